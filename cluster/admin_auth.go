@@ -0,0 +1,126 @@
+package cluster
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/zhexuany/influxcloud/tlv"
+)
+
+// Roles admin tokens grant.
+const (
+	// RoleAdmin may send every RPC, including those that mutate the cluster.
+	RoleAdmin = "admin"
+
+	// RoleReadOnly may only send the RPCs other nodes send without a token,
+	// e.g. status RPCs, so that monitoring tools can inspect the cluster
+	// without being able to change it.
+	RoleReadOnly = "read-only"
+)
+
+// ErrInvalidAdminToken is returned for requests following a token that is
+// not configured on the node.
+var ErrInvalidAdminToken = errors.New("invalid admin token")
+
+// adminRPCs are the requests that mutate the cluster, delete its data or
+// export it, which need a token with RoleAdmin once admin tokens are
+// configured. Nodes sending them on their own, e.g. to drop expired shards,
+// copy a shard or execute a DROP statement on every node, authorize them
+// with the first admin token they are configured with, see adminTokens.admin.
+var adminRPCs = map[byte]bool{
+	tlv.ExecuteStatementRequestMessage:      true,
+	tlv.BackupShardRequestMessage:           true,
+	tlv.RemoveShardRequestMessage:           true,
+	tlv.TruncateShardsRequestMessage:        true,
+	tlv.RemoveDataNodeRequestMessage:        true,
+	tlv.UpdateDataNodeRequestMessage:        true,
+	tlv.ReplaceDataNodeRequestMessage:       true,
+	tlv.RestoreShardRequestMessage:          true,
+	tlv.SetMaintenanceRequestMessage:        true,
+	tlv.SetReadOnlyRequestMessage:           true,
+	tlv.QuarantineRequestMessage:            true,
+	tlv.DropShardsRequestMessage:            true,
+	tlv.CopyShardRequestMessage:             true,
+	tlv.PauseReplicationRequestMessage:      true,
+	tlv.RedirectHintedHandoffRequestMessage: true,
+	tlv.UploadShardSnapshotRequestMessage:   true,
+	tlv.ExportMetaDataRequestMessage:        true,
+}
+
+// adminTokens are the admin tokens configured on a node and the role each
+// grants.
+type adminTokens struct {
+	mu     sync.RWMutex
+	tokens []AdminTokenConfig
+}
+
+// newAdminTokens returns the admin tokens of c.
+func newAdminTokens(c Config) *adminTokens {
+	t := &adminTokens{}
+	t.set(c.AdminTokens)
+	return t
+}
+
+// set replaces the tokens, e.g. when the configuration is reloaded.
+func (t *adminTokens) set(tokens []AdminTokenConfig) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.tokens = append([]AdminTokenConfig(nil), tokens...)
+}
+
+// admin returns the first token with RoleAdmin, which the node authorizes
+// the admin RPCs it sends on its own with, or an empty string if none is
+// configured. The nodes of a cluster are expected to share their tokens.
+func (t *adminTokens) admin() string {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	for _, tc := range t.tokens {
+		if tc.Role == RoleAdmin {
+			return tc.Token
+		}
+	}
+	return ""
+}
+
+// role returns the role token grants, or false if it is not configured.
+// Every token is compared in constant time, so that the time taken does
+// not tell how much of a token was guessed.
+func (t *adminTokens) role(token string) (string, bool) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	var role string
+	var ok bool
+	for _, tc := range t.tokens {
+		if subtle.ConstantTimeCompare([]byte(tc.Token), []byte(token)) == 1 {
+			role, ok = tc.Role, true
+		}
+	}
+	return role, ok
+}
+
+// authorize returns an error unless a request of type typ following token,
+// or no token if empty, is allowed. Every request is allowed if no tokens
+// are configured.
+func (t *adminTokens) authorize(typ byte, token string) error {
+	t.mu.RLock()
+	enabled := len(t.tokens) > 0
+	t.mu.RUnlock()
+	if !enabled {
+		return nil
+	}
+
+	role := ""
+	if token != "" {
+		var ok bool
+		if role, ok = t.role(token); !ok {
+			return ErrInvalidAdminToken
+		}
+	}
+	if adminRPCs[typ] && role != RoleAdmin {
+		return fmt.Errorf("%s requires an admin token", rpcName(typ))
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package cluster
+
+import (
+	"testing"
+
+	"github.com/zhexuany/influxcloud/tlv"
+)
+
+// Ensure every request the service processes is either an admin RPC or
+// known not to need an admin token, so that a new RPC cannot be left
+// ungated by accident.
+func TestAdminRPCs(t *testing.T) {
+	// The requests that neither mutate the cluster nor export its data
+	// beyond what queries return.
+	open := map[byte]bool{
+		tlv.WriteShardRequestMessage:      true,
+		tlv.WritePointsRequestMessage:     true,
+		tlv.PingRequestMessage:            true,
+		tlv.CreateIteratorRequestMessage:  true,
+		tlv.FieldDimensionsRequestMessage: true,
+		tlv.ShowShardsRequestMessage:      true,
+		tlv.AuthStateRequestMessage:       true,
+		tlv.ShardStatusRequestMessage:     true,
+		tlv.ShardBoundsRequestMessage:     true,
+		tlv.AcquireLeaseRequestMessage:    true,
+		tlv.CardinalityRequestMessage:     true,
+	}
+
+	s := NewService(Config{})
+	for i := 0; i < 256; i++ {
+		typ := byte(i)
+		if _, ok := s.handler(typ); !ok {
+			if adminRPCs[typ] {
+				t.Errorf("admin RPC %s has no handler", rpcName(typ))
+			}
+			continue
+		}
+
+		if adminRPCs[typ] == open[typ] {
+			t.Errorf("%s (type %d) must be either an admin RPC or open", rpcName(typ), typ)
+		} else if err := s.adminTokens.authorize(typ, ""); err != nil {
+			t.Errorf("%s refused without admin tokens: %s", rpcName(typ), err)
+		}
+	}
+
+	s.adminTokens.set([]AdminTokenConfig{{Token: "monitoring", Role: RoleReadOnly}, {Token: "s3cr3t", Role: RoleAdmin}})
+	for _, typ := range []byte{tlv.ExecuteStatementRequestMessage, tlv.BackupShardRequestMessage} {
+		if !adminRPCs[typ] {
+			t.Errorf("%s must be an admin RPC", rpcName(typ))
+		}
+	}
+	for typ := range adminRPCs {
+		if err := s.adminTokens.authorize(typ, ""); err == nil {
+			t.Errorf("%s allowed without a token", rpcName(typ))
+		} else if err := s.adminTokens.authorize(typ, "monitoring"); err == nil {
+			t.Errorf("%s allowed with a read-only token", rpcName(typ))
+		} else if err := s.adminTokens.authorize(typ, "s3cr3t"); err != nil {
+			t.Errorf("%s refused with an admin token: %s", rpcName(typ), err)
+		}
+	}
+	if token := s.adminTokens.admin(); token != "s3cr3t" {
+		t.Fatalf("unexpected admin token: %q", token)
+	}
+}
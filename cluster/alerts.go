@@ -0,0 +1,257 @@
+package cluster
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/uber-go/zap"
+)
+
+// Names of the alerts raised by an Alerter.
+const (
+	// AlertNodeUnreachable is raised for a data node that has not answered
+	// pings for longer than the unreachable threshold.
+	AlertNodeUnreachable = "nodeUnreachable"
+
+	// AlertHHBacklog is raised for a data node whose hinted handoff queue
+	// on this node exceeds the backlog threshold.
+	AlertHHBacklog = "hhBacklog"
+
+	// AlertPartialWrites is raised while the partial shard writes within
+	// the partial write window reach the partial write threshold.
+	AlertPartialWrites = "partialWrites"
+)
+
+// Statuses of the alerts posted by an Alerter.
+const (
+	AlertFiring   = "firing"
+	AlertResolved = "resolved"
+)
+
+// Alert is a sustained condition of the cluster. It is posted once when the
+// condition starts, and once more with a resolved status when it clears.
+type Alert struct {
+	Name    string    `json:"name"`
+	Status  string    `json:"status"`
+	NodeID  uint64    `json:"nodeID,omitempty"`
+	Message string    `json:"message"`
+	Since   time.Time `json:"since"`
+	Time    time.Time `json:"time"`
+}
+
+// alertKey identifies an alert across checks, so that it is only posted
+// when it starts and when it clears.
+type alertKey struct {
+	name   string
+	nodeID uint64
+}
+
+// Alerter raises alerts from the events of an EventBus and from the hinted
+// handoff backlog, and posts them to each of URLs. Nodes are only known to
+// be unreachable if the service checks nodes.
+type Alerter struct {
+	URLs   []string
+	Client *http.Client
+	Logger zap.Logger
+
+	CheckInterval        time.Duration
+	NodeUnreachableAfter time.Duration
+	MaxHHBacklog         int64
+	MaxPartialWrites     int
+	PartialWriteWindow   time.Duration
+
+	// HintedHandoff reports the backlog queued for each node. The backlog
+	// is not checked if nil.
+	HintedHandoff interface {
+		QueueSizes() map[uint64]int64
+	}
+
+	mu          sync.Mutex
+	unreachable map[uint64]time.Time // The time each dead node stopped answering.
+	partials    []time.Time          // The times of recent partial writes, oldest first.
+	active      map[alertKey]Alert
+
+	wg      sync.WaitGroup
+	closing chan struct{}
+	cancel  func()
+}
+
+// NewAlerter returns an Alerter configured by c, posting each alert within
+// the event webhook timeout.
+func NewAlerter(c Config) *Alerter {
+	return &Alerter{
+		URLs:                 c.AlertWebhookURLs,
+		Client:               &http.Client{Timeout: time.Duration(c.EventWebhookTimeout)},
+		Logger:               zap.New(zap.NullEncoder()),
+		CheckInterval:        time.Duration(c.AlertCheckInterval),
+		NodeUnreachableAfter: time.Duration(c.AlertNodeUnreachableAfter),
+		MaxHHBacklog:         int64(c.AlertMaxHHBacklog),
+		MaxPartialWrites:     c.AlertMaxPartialWrites,
+		PartialWriteWindow:   time.Duration(c.AlertPartialWriteWindow),
+		unreachable:          make(map[uint64]time.Time),
+		active:               make(map[alertKey]Alert),
+		closing:              make(chan struct{}),
+	}
+}
+
+// Open starts handling the events published on bus, and checking the alert
+// conditions every CheckInterval.
+func (a *Alerter) Open(bus *EventBus) {
+	events, cancel := bus.Subscribe(0)
+	a.cancel = cancel
+	a.wg.Add(1)
+	go a.run(events)
+}
+
+// Close stops raising alerts.
+func (a *Alerter) Close() {
+	close(a.closing)
+	a.cancel()
+	a.wg.Wait()
+}
+
+// run handles events and checks the alert conditions until a is closed.
+func (a *Alerter) run(events <-chan Event) {
+	defer a.wg.Done()
+
+	t := time.NewTicker(a.CheckInterval)
+	defer t.Stop()
+	for {
+		select {
+		case e, ok := <-events:
+			if !ok {
+				return
+			}
+			a.Handle(e)
+		case <-t.C:
+			a.Check(time.Now().UTC())
+		case <-a.closing:
+			return
+		}
+	}
+}
+
+// Handle records the node reachability and partial writes reported by e.
+func (a *Alerter) Handle(e Event) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	switch e.Type {
+	case EventNodeDead:
+		if _, ok := a.unreachable[e.NodeID]; !ok {
+			a.unreachable[e.NodeID] = e.Time
+		}
+	case EventNodeAlive, EventNodeLeft:
+		delete(a.unreachable, e.NodeID)
+	case EventWritePartial:
+		a.partials = append(a.partials, e.Time)
+	}
+}
+
+// Check evaluates the alert conditions at now, posting the alerts that
+// started and those that cleared since the last check.
+func (a *Alerter) Check(now time.Time) {
+	firing := make(map[alertKey]Alert)
+
+	a.mu.Lock()
+	for id, since := range a.unreachable {
+		if now.Sub(since) >= a.NodeUnreachableAfter {
+			firing[alertKey{AlertNodeUnreachable, id}] = Alert{
+				NodeID:  id,
+				Message: fmt.Sprintf("node %d unreachable since %s", id, since.Format(time.RFC3339)),
+				Since:   since,
+			}
+		}
+	}
+
+	// Partial writes outside the window are forgotten.
+	i := 0
+	for i < len(a.partials) && now.Sub(a.partials[i]) > a.PartialWriteWindow {
+		i++
+	}
+	a.partials = a.partials[i:]
+	if n := len(a.partials); a.MaxPartialWrites > 0 && n >= a.MaxPartialWrites {
+		firing[alertKey{AlertPartialWrites, 0}] = Alert{
+			Message: fmt.Sprintf("%d partial writes in the last %s", n, a.PartialWriteWindow),
+			Since:   a.partials[0],
+		}
+	}
+	a.mu.Unlock()
+
+	if a.HintedHandoff != nil && a.MaxHHBacklog > 0 {
+		for id, size := range a.HintedHandoff.QueueSizes() {
+			if size > a.MaxHHBacklog {
+				firing[alertKey{AlertHHBacklog, id}] = Alert{
+					NodeID:  id,
+					Message: fmt.Sprintf("hinted handoff backlog of %d bytes for node %d exceeds %d", size, id, a.MaxHHBacklog),
+					Since:   now,
+				}
+			}
+		}
+	}
+
+	// Alerts already firing are not posted again.
+	a.mu.Lock()
+	var alerts []Alert
+	for k, alert := range firing {
+		if _, ok := a.active[k]; ok {
+			continue
+		}
+		alert.Name, alert.Status, alert.Time = k.name, AlertFiring, now
+		a.active[k] = alert
+		alerts = append(alerts, alert)
+	}
+	for k, alert := range a.active {
+		if _, ok := firing[k]; ok {
+			continue
+		}
+		delete(a.active, k)
+		alert.Status, alert.Time = AlertResolved, now
+		alerts = append(alerts, alert)
+	}
+	a.mu.Unlock()
+
+	sort.Sort(alertList(alerts))
+	for _, alert := range alerts {
+		a.post(alert)
+	}
+}
+
+// post sends alert to each of the URLs, logging those that fail.
+func (a *Alerter) post(alert Alert) {
+	a.Logger.Warn("alert "+alert.Status, zap.String("name", alert.Name), zap.Uint64("nodeID", alert.NodeID), zap.String("message", alert.Message))
+
+	buf, err := json.Marshal(&alert)
+	if err != nil {
+		a.Logger.Info("unable to encode alert", zap.Error(err))
+		return
+	}
+	for _, url := range a.URLs {
+		resp, err := a.Client.Post(url, "application/json", bytes.NewReader(buf))
+		if err == nil {
+			resp.Body.Close()
+			if resp.StatusCode/100 != 2 {
+				err = fmt.Errorf("unexpected status: %s", resp.Status)
+			}
+		}
+		if err != nil {
+			a.Logger.Info("unable to post alert", zap.String("name", alert.Name), zap.String("url", url), zap.Error(err))
+		}
+	}
+}
+
+// alertList sorts alerts by name, then node ID.
+type alertList []Alert
+
+func (a alertList) Len() int { return len(a) }
+func (a alertList) Less(i, j int) bool {
+	if a[i].Name != a[j].Name {
+		return a[i].Name < a[j].Name
+	}
+	return a[i].NodeID < a[j].NodeID
+}
+func (a alertList) Swap(i, j int) { a[i], a[j] = a[j], a[i] }
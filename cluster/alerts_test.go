@@ -0,0 +1,116 @@
+package cluster_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/influxdata/influxdb/toml"
+	"github.com/zhexuany/influxcloud/cluster"
+)
+
+// Ensure alerts are posted to every URL once when their condition starts,
+// and once more when it clears.
+func TestAlerter_Check(t *testing.T) {
+	var mu sync.Mutex
+	var posted []cluster.Alert
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var a cluster.Alert
+		if err := json.NewDecoder(r.Body).Decode(&a); err != nil {
+			t.Errorf("unable to decode alert: %s", err)
+		}
+		mu.Lock()
+		posted = append(posted, a)
+		mu.Unlock()
+	})
+	ts0, ts1 := httptest.NewServer(handler), httptest.NewServer(handler)
+	defer ts0.Close()
+	defer ts1.Close()
+
+	a := cluster.NewAlerter(cluster.Config{
+		AlertWebhookURLs:          []string{ts0.URL, ts1.URL},
+		EventWebhookTimeout:       toml.Duration(time.Second),
+		AlertNodeUnreachableAfter: toml.Duration(5 * time.Minute),
+		AlertMaxHHBacklog:         100,
+		AlertMaxPartialWrites:     2,
+		AlertPartialWriteWindow:   toml.Duration(time.Minute),
+	})
+	hh := queueSizes{3: 200}
+	a.HintedHandoff = hh
+
+	// check returns the name, status and node of the alerts posted at now.
+	type alert struct {
+		name, status string
+		nodeID       uint64
+	}
+	check := func(now time.Time) []alert {
+		mu.Lock()
+		posted = nil
+		mu.Unlock()
+		a.Check(now)
+		mu.Lock()
+		defer mu.Unlock()
+		var got []alert
+		for i, p := range posted {
+			// Each alert is posted to both URLs in turn.
+			if i%2 == 1 {
+				if p != posted[i-1] {
+					t.Fatalf("unexpected alert: %+v", p)
+				}
+				continue
+			}
+			got = append(got, alert{p.Name, p.Status, p.NodeID})
+		}
+		return got
+	}
+	equal := func(got []alert, exp ...alert) bool {
+		if len(got) != len(exp) {
+			return false
+		}
+		for i := range got {
+			if got[i] != exp[i] {
+				return false
+			}
+		}
+		return true
+	}
+
+	t0 := time.Unix(0, 0).UTC()
+	a.Handle(cluster.Event{Type: cluster.EventNodeDead, NodeID: 2, Time: t0})
+	a.Handle(cluster.Event{Type: cluster.EventWritePartial, Time: t0})
+	a.Handle(cluster.Event{Type: cluster.EventWritePartial, Time: t0.Add(10 * time.Second)})
+
+	if got := check(t0.Add(time.Minute)); !equal(got,
+		alert{cluster.AlertHHBacklog, cluster.AlertFiring, 3},
+		alert{cluster.AlertPartialWrites, cluster.AlertFiring, 0},
+	) {
+		t.Fatalf("unexpected alerts: %+v", got)
+	}
+
+	// Alerts still firing are not posted again.
+	if got := check(t0.Add(2 * time.Minute)); !equal(got,
+		alert{cluster.AlertPartialWrites, cluster.AlertResolved, 0},
+	) {
+		t.Fatalf("unexpected alerts: %+v", got)
+	}
+
+	delete(hh, 3)
+	if got := check(t0.Add(6 * time.Minute)); !equal(got,
+		alert{cluster.AlertHHBacklog, cluster.AlertResolved, 3},
+		alert{cluster.AlertNodeUnreachable, cluster.AlertFiring, 2},
+	) {
+		t.Fatalf("unexpected alerts: %+v", got)
+	}
+
+	a.Handle(cluster.Event{Type: cluster.EventNodeAlive, NodeID: 2, Time: t0.Add(7 * time.Minute)})
+	if got := check(t0.Add(8 * time.Minute)); !equal(got,
+		alert{cluster.AlertNodeUnreachable, cluster.AlertResolved, 2},
+	) {
+		t.Fatalf("unexpected alerts: %+v", got)
+	} else if got := check(t0.Add(9 * time.Minute)); len(got) != 0 {
+		t.Fatalf("unexpected alerts: %+v", got)
+	}
+}
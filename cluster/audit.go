@@ -0,0 +1,79 @@
+package cluster
+
+import (
+	"io"
+	"net"
+	"os"
+
+	"github.com/influxdata/influxdb/influxql"
+	"github.com/uber-go/zap"
+)
+
+// newAuditLogger returns a logger writing audit records to w as JSON, one
+// record per line, with the time each operation was processed.
+func newAuditLogger(w io.Writer) zap.Logger {
+	return zap.New(
+		zap.NewJSONEncoder(zap.RFC3339Formatter("time")),
+		zap.Output(zap.AddSync(w)),
+	)
+}
+
+// openAuditLog opens the audit log file at path, appending to it if it
+// exists, and points AuditLogger at it.
+func (s *Service) openAuditLog(path string) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return err
+	}
+	s.auditFile = f
+	s.AuditLogger = newAuditLogger(f)
+	return nil
+}
+
+// audit records a destructive operation requested by the node at peer, and
+// its outcome. nodeID is the ID of the node the operation originates from,
+// or zero if it is only known by its address, e.g. when requested by
+// influxcloud-ctl.
+func (s *Service) audit(op string, peer net.Addr, nodeID uint64, err error, fields ...zap.Field) {
+	fields = append(fields, zap.Stringer("peer", peer))
+	if nodeID != 0 {
+		fields = append(fields, zap.Uint64("originNodeID", nodeID))
+	}
+	if s.Node != nil {
+		fields = append(fields, zap.Uint64("nodeID", s.Node.ID))
+	}
+	if err != nil {
+		fields = append(fields, zap.String("result", "failed"), zap.Error(err))
+	} else {
+		fields = append(fields, zap.String("result", "ok"))
+	}
+	s.AuditLogger.Info(op, fields...)
+}
+
+// AuditReload records a setting changed from old to new by reloading the
+// configuration of this node.
+func (s *Service) AuditReload(setting, old, new string) {
+	fields := []zap.Field{
+		zap.String("setting", setting),
+		zap.String("old", old),
+		zap.String("new", new),
+	}
+	if s.Node != nil {
+		fields = append(fields, zap.Uint64("nodeID", s.Node.ID))
+	}
+	s.AuditLogger.Info("reload config", fields...)
+}
+
+// destructive returns true if stmt drops or deletes data.
+func destructive(stmt influxql.Statement) bool {
+	switch stmt.(type) {
+	case *influxql.DropDatabaseStatement,
+		*influxql.DropMeasurementStatement,
+		*influxql.DropSeriesStatement,
+		*influxql.DeleteSeriesStatement,
+		*influxql.DropRetentionPolicyStatement,
+		*influxql.DropShardStatement:
+		return true
+	}
+	return false
+}
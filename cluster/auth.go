@@ -0,0 +1,127 @@
+package cluster
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/influxdata/influxdb/services/meta"
+	"github.com/zhexuany/influxcloud/rpc"
+	"github.com/zhexuany/influxcloud/tlv"
+)
+
+// UsersHash returns a digest of a user list. Two nodes with the same users,
+// password hashes and privileges produce the same digest regardless of order.
+func UsersHash(users []meta.UserInfo) string {
+	names := make([]string, 0, len(users))
+	byName := make(map[string]meta.UserInfo, len(users))
+	for _, u := range users {
+		names = append(names, u.Name)
+		byName[u.Name] = u
+	}
+	sort.Strings(names)
+
+	h := sha256.New()
+	for _, name := range names {
+		u := byName[name]
+		fmt.Fprintf(h, "%q %q %t\n", u.Name, u.Hash, u.Admin)
+
+		dbs := make([]string, 0, len(u.Privileges))
+		for db := range u.Privileges {
+			dbs = append(dbs, db)
+		}
+		sort.Strings(dbs)
+		for _, db := range dbs {
+			fmt.Fprintf(h, "\t%q %d\n", db, u.Privileges[db])
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// AuthState is the cached auth state reported by a single data node.
+type AuthState struct {
+	NodeID uint64
+	Hash   string
+	UserN  int
+
+	// Stale is true when the node's users differ from the meta store.
+	Stale bool
+
+	// Err is set if the node could not be reached.
+	Err error
+}
+
+// VerifyAuthState asks every data node for a digest of its cached users and
+// compares it with the digest of users, the authoritative list from meta.
+// Nodes with stale caches could still accept requests from revoked users.
+func (m *MetaExecutor) VerifyAuthState(users []meta.UserInfo) ([]AuthState, error) {
+	nodes, err := m.MetaClient.DataNodes()
+	if err != nil {
+		return nil, err
+	}
+
+	exp := UsersHash(users)
+	states := make([]AuthState, len(nodes))
+
+	var wg sync.WaitGroup
+	for i := range nodes {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			state := AuthState{NodeID: nodes[i].ID}
+			resp, err := m.authStateOnNode(nodes[i].ID)
+			if err != nil {
+				state.Err = err
+			} else {
+				state.Hash, state.UserN = resp.Hash, resp.UserN
+				state.Stale = resp.Hash != exp
+			}
+			states[i] = state
+		}(i)
+	}
+	wg.Wait()
+
+	sort.Sort(authStates(states))
+	return states, nil
+}
+
+// authStateOnNode requests the auth state from a single node.
+func (m *MetaExecutor) authStateOnNode(nodeID uint64) (*rpc.AuthStateResponse, error) {
+	c, err := m.dial(nodeID)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, ok := c.(*pooledConn)
+	if !ok {
+		panic("wrong connection type in MetaExecutor")
+	}
+	// Return connection to pool by "closing" it.
+	defer conn.Close()
+
+	conn.SetWriteDeadline(time.Now().Add(m.timeout))
+	if err := tlv.EncodeTLV(conn, tlv.AuthStateRequestMessage, &rpc.AuthStateRequest{}); err != nil {
+		conn.MarkUnusable()
+		return nil, err
+	}
+
+	var resp rpc.AuthStateResponse
+	conn.SetReadDeadline(time.Now().Add(m.timeout))
+	if _, err := tlv.DecodeTLV(conn, &resp); err != nil {
+		conn.MarkUnusable()
+		return nil, err
+	} else if resp.Err != "" {
+		return nil, fmt.Errorf("auth state on node %d: %s", nodeID, resp.Err)
+	}
+	return &resp, nil
+}
+
+type authStates []AuthState
+
+func (a authStates) Len() int           { return len(a) }
+func (a authStates) Less(i, j int) bool { return a[i].NodeID < a[j].NodeID }
+func (a authStates) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
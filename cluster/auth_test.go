@@ -0,0 +1,61 @@
+package cluster_test
+
+import (
+	"testing"
+
+	"github.com/influxdata/influxdb/influxql"
+	"github.com/influxdata/influxdb/services/meta"
+	"github.com/zhexuany/influxcloud/cluster"
+)
+
+// Ensure the users hash does not depend on the order of users.
+func TestUsersHash(t *testing.T) {
+	a := []meta.UserInfo{
+		{Name: "alice", Hash: "x", Admin: true},
+		{Name: "bob", Hash: "y", Privileges: map[string]influxql.Privilege{"db0": influxql.ReadPrivilege, "db1": influxql.AllPrivileges}},
+	}
+	b := []meta.UserInfo{a[1], a[0]}
+
+	if cluster.UsersHash(a) != cluster.UsersHash(b) {
+		t.Fatal("expected hashes to match")
+	}
+
+	// Revoking a privilege changes the hash.
+	c := []meta.UserInfo{a[0], {Name: "bob", Hash: "y", Privileges: map[string]influxql.Privilege{"db0": influxql.ReadPrivilege}}}
+	if cluster.UsersHash(a) == cluster.UsersHash(c) {
+		t.Fatal("expected hashes to differ")
+	}
+}
+
+// Ensure nodes whose cached users differ from meta are reported as stale.
+func TestMetaExecutor_VerifyAuthState(t *testing.T) {
+	users := []meta.UserInfo{{Name: "alice", Hash: "x", Admin: true}}
+
+	s := MustOpenService()
+	defer s.Close()
+	s.MetaClient.UsersFn = func() []meta.UserInfo { return users }
+
+	e := cluster.NewMetaExecutor()
+	e.MetaClient = &metaClient{host: s.Addr().String()}
+
+	states, err := e.VerifyAuthState(users)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(states) != 1 {
+		t.Fatalf("unexpected state count: %d", len(states))
+	} else if states[0].Err != nil {
+		t.Fatal(states[0].Err)
+	} else if states[0].Stale {
+		t.Fatal("expected node to be up to date")
+	} else if states[0].UserN != 1 {
+		t.Fatalf("unexpected user count: %d", states[0].UserN)
+	}
+
+	// Meta has revoked alice but the node still has her cached.
+	states, err = e.VerifyAuthState(nil)
+	if err != nil {
+		t.Fatal(err)
+	} else if !states[0].Stale {
+		t.Fatal("expected node to be stale")
+	}
+}
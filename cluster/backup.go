@@ -0,0 +1,401 @@
+package cluster
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"path"
+	"time"
+
+	"github.com/influxdata/influxdb/services/meta"
+	"github.com/uber-go/zap"
+	"github.com/zhexuany/influxcloud"
+	"github.com/zhexuany/influxcloud/rpc"
+	"github.com/zhexuany/influxcloud/tlv"
+)
+
+// BackupSink receives the shard backups streamed by a BackupCoordinator.
+type BackupSink interface {
+	// BackupShard stores the backup of shard read from r. The backup must
+	// be read until EOF. It ends with the checksums of its files. If the
+	// backup does not match them, BackupShard is called again for the shard
+	// and the new backup replaces the previous one.
+	BackupShard(shard BackupShardInfo, r io.Reader) error
+}
+
+// BackupManifest describes a cluster backup of a retention policy.
+type BackupManifest struct {
+	Database        string    `json:"database"`
+	RetentionPolicy string    `json:"retentionPolicy"`
+	StartTime       time.Time `json:"startTime"`
+	EndTime         time.Time `json:"endTime"`
+
+	// The settings of the retention policy, so that it can be recreated.
+	ReplicaN           int           `json:"replicaN"`
+	Duration           time.Duration `json:"duration"`
+	ShardGroupDuration time.Duration `json:"shardGroupDuration"`
+
+	Shards []BackupShardInfo `json:"shards"`
+}
+
+// BackupShardInfo describes the backup of one shard, and which owner it was
+// taken from.
+type BackupShardInfo struct {
+	ID           uint64    `json:"id"`
+	ShardGroupID uint64    `json:"shardGroupID"`
+	StartTime    time.Time `json:"startTime"`
+	EndTime      time.Time `json:"endTime"`
+	NodeID       uint64    `json:"nodeID"`
+	Size         int64     `json:"size"`
+
+	// The ID of the snapshot taken on the node, and of the snapshot it was
+	// taken since if the backup is incremental.
+	SnapshotID     uint64 `json:"snapshotID,omitempty"`
+	BaseSnapshotID uint64 `json:"baseSnapshotID,omitempty"`
+
+	// The key of the snapshot in the SnapshotSink it was uploaded to, if
+	// the shard was uploaded rather than backed up.
+	Key string `json:"key,omitempty"`
+}
+
+// Incremental reports whether the backup only holds the files of the shard
+// changed since a previous backup.
+func (si *BackupShardInfo) Incremental() bool { return si.BaseSnapshotID != 0 }
+
+// BackupCoordinator backs up the shards of a retention policy across the
+// cluster. Each shard is backed up from one of its owners, which snapshots
+// the shard and streams the snapshot back.
+type BackupCoordinator struct {
+	timeout time.Duration
+
+	// Dialer connects to the data nodes. Backing up a shard is an admin
+	// RPC, so it should send the node's admin token if tokens are
+	// configured, see Service.Dialer.
+	Dialer *Dialer
+
+	MetaClient interface {
+		DataNode(id uint64) (*meta.NodeInfo, error)
+		RetentionPolicy(database, name string) (*meta.RetentionPolicyInfo, error)
+		ShardGroupsByTimeRange(database, policy string, min, max time.Time) ([]meta.ShardGroupInfo, error)
+	}
+}
+
+// NewBackupCoordinator returns a new instance of BackupCoordinator.
+func NewBackupCoordinator(timeout time.Duration) *BackupCoordinator {
+	return &BackupCoordinator{timeout: timeout, Dialer: DefaultDialer}
+}
+
+// Backup streams a backup of every shard of database and policy overlapping
+// the time range [min, max] to sink, and returns the manifest of the backup.
+// Shards are spread across their owners. If an owner cannot be reached, or
+// fails before streaming its snapshot, the shard is backed up from the next
+// owner. The backup fails if no owner of a shard can back it up.
+func (c *BackupCoordinator) Backup(database, policy string, min, max time.Time, sink BackupSink) (*BackupManifest, error) {
+	return c.eachShard(database, policy, min, max, func(info *BackupShardInfo, owners []meta.ShardOwner, load map[uint64]int) error {
+		return c.backupShard(info, owners, load, nil, sink)
+	})
+}
+
+// IncrementalBackup streams a backup of the shards of the database and
+// policy of base overlapping the time range [min, max] to sink, holding only
+// the files changed since base where possible. Each shard in base is backed
+// up from the node its base backup was taken from, which streams the files
+// changed since its snapshot if it still knows the snapshot. Other shards,
+// and shards backed up from another owner, are backed up in full. The
+// manifest reports which shards are incremental.
+func (c *BackupCoordinator) IncrementalBackup(base *BackupManifest, min, max time.Time, sink BackupSink) (*BackupManifest, error) {
+	prev := make(map[uint64]*BackupShardInfo, len(base.Shards))
+	for i := range base.Shards {
+		prev[base.Shards[i].ID] = &base.Shards[i]
+	}
+
+	return c.eachShard(base.Database, base.RetentionPolicy, min, max, func(info *BackupShardInfo, owners []meta.ShardOwner, load map[uint64]int) error {
+		return c.backupShard(info, owners, load, prev[info.ID], sink)
+	})
+}
+
+// Upload asks the owners of every shard of database and policy overlapping
+// the time range [min, max] to upload a snapshot of the shard to their
+// SnapshotSink, and returns the manifest of the uploaded snapshots. The
+// snapshot of each shard is stored under prefix, as the key set in the
+// manifest. Shards are spread across their owners as with Backup, and a
+// shard is uploaded by the next owner if an owner fails to upload it.
+func (c *BackupCoordinator) Upload(database, policy string, min, max time.Time, prefix string) (*BackupManifest, error) {
+	return c.eachShard(database, policy, min, max, func(info *BackupShardInfo, owners []meta.ShardOwner, load map[uint64]int) error {
+		info.Key = path.Join(prefix, shardPath(database, policy, info.ID)+".tar")
+		return c.uploadShard(info, owners, load)
+	})
+}
+
+// eachShard calls fn with each shard of database and policy overlapping the
+// time range [min, max], and returns the manifest of the shards. fn sets the
+// node the shard was taken from, and the size of the snapshot.
+func (c *BackupCoordinator) eachShard(database, policy string, min, max time.Time, fn func(info *BackupShardInfo, owners []meta.ShardOwner, load map[uint64]int) error) (*BackupManifest, error) {
+	rpi, err := c.MetaClient.RetentionPolicy(database, policy)
+	if err != nil {
+		return nil, err
+	} else if rpi == nil {
+		return nil, influxcloud.ErrRetentionPolicyNotFound(policy)
+	}
+
+	groups, err := c.MetaClient.ShardGroupsByTimeRange(database, policy, min, max)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &BackupManifest{
+		Database:           database,
+		RetentionPolicy:    policy,
+		StartTime:          min.UTC(),
+		EndTime:            max.UTC(),
+		ReplicaN:           rpi.ReplicaN,
+		Duration:           rpi.Duration,
+		ShardGroupDuration: rpi.ShardGroupDuration,
+	}
+
+	// Count the shards backed up from each node, so that owners backing
+	// up fewer shards are asked first.
+	load := make(map[uint64]int)
+	for _, g := range groups {
+		for _, sh := range g.Shards {
+			info := BackupShardInfo{
+				ID:           sh.ID,
+				ShardGroupID: g.ID,
+				StartTime:    g.StartTime,
+				EndTime:      g.EndTime,
+			}
+			if len(sh.Owners) == 0 {
+				return nil, fmt.Errorf("backup shard %d: shard has no owners", sh.ID)
+			} else if err := fn(&info, sh.Owners, load); err != nil {
+				return nil, err
+			}
+			load[info.NodeID]++
+			m.Shards = append(m.Shards, info)
+		}
+	}
+	return m, nil
+}
+
+// ownerOrder returns the owners of a shard in the order they are asked for
+// it: the least loaded owner first, then the others in turn.
+func ownerOrder(owners []meta.ShardOwner, load map[uint64]int) []uint64 {
+	first := 0
+	for i, o := range owners {
+		if load[o.NodeID] < load[owners[first].NodeID] {
+			first = i
+		}
+	}
+
+	ids := make([]uint64, len(owners))
+	for i := range owners {
+		ids[i] = owners[(first+i)%len(owners)].NodeID
+	}
+	return ids
+}
+
+// backupShard backs up the shard described by info from one of owners, and
+// sets the node, snapshot and size of the backup in info. If prev is set, the
+// node prev was taken from is asked first for the changes since prev.
+func (c *BackupCoordinator) backupShard(info *BackupShardInfo, owners []meta.ShardOwner, load map[uint64]int, prev *BackupShardInfo, sink BackupSink) error {
+	nodeIDs := ownerOrder(owners, load)
+	if prev != nil && prev.SnapshotID != 0 {
+		for i, nodeID := range nodeIDs {
+			if nodeID == prev.NodeID {
+				copy(nodeIDs[1:i+1], nodeIDs[:i])
+				nodeIDs[0] = nodeID
+				break
+			}
+		}
+	}
+
+	var err error
+	for _, nodeID := range nodeIDs {
+		var baseID uint64
+		if prev != nil && nodeID == prev.NodeID {
+			baseID = prev.SnapshotID
+		}
+
+		// Corrupted backups are taken again, then from the next owner.
+		for attempt := 1; attempt <= maxShardStreamAttempts; attempt++ {
+			var conn net.Conn
+			var resp *rpc.BackupShardResponse
+			if conn, resp, err = c.requestBackup(nodeID, info.ID, baseID); err != nil {
+				// Ask the next owner.
+				break
+			}
+
+			info.NodeID = nodeID
+			info.SnapshotID, info.BaseSnapshotID = resp.SnapshotID, resp.BaseSnapshotID
+			r := &countingReader{r: conn}
+			err = verifiedBackup(*info, r, sink)
+			conn.Close()
+			if err == nil {
+				info.Size = r.n
+				return nil
+			} else if !isChecksumError(err) {
+				return fmt.Errorf("backup shard %d from node %d: %s", info.ID, nodeID, err)
+			}
+		}
+	}
+	return fmt.Errorf("backup shard %d: %s", info.ID, err)
+}
+
+// verifiedBackup passes the backup of shard read from r to sink, verifying it
+// against its checksums as it is read.
+func verifiedBackup(shard BackupShardInfo, r io.Reader, sink BackupSink) error {
+	pr, pw := io.Pipe()
+	done := make(chan error, 1)
+	go func() {
+		err := verifyShardChecksums(ioutil.Discard, pr, shard.ID, true)
+		if err == nil {
+			_, err = io.Copy(ioutil.Discard, pr)
+		}
+		pr.CloseWithError(err)
+		done <- err
+	}()
+
+	err := sink.BackupShard(shard, io.TeeReader(r, pw))
+	pw.CloseWithError(err)
+	if verr := <-done; isChecksumError(verr) || err == nil {
+		return verr
+	}
+	return err
+}
+
+// uploadShard asks one of owners to upload the shard described by info to
+// info.Key, and sets the node and size of the upload in info.
+func (c *BackupCoordinator) uploadShard(info *BackupShardInfo, owners []meta.ShardOwner, load map[uint64]int) error {
+	var err error
+	for _, nodeID := range ownerOrder(owners, load) {
+		var size int64
+		if size, err = c.requestUpload(nodeID, info.ID, info.Key); err != nil {
+			// Ask the next owner.
+			continue
+		}
+		info.NodeID, info.Size = nodeID, size
+		return nil
+	}
+	return fmt.Errorf("upload shard %d: %s", info.ID, err)
+}
+
+// requestUpload asks the node nodeID to upload a snapshot of shardID to key,
+// and returns the size of the snapshot.
+func (c *BackupCoordinator) requestUpload(nodeID, shardID uint64, key string) (int64, error) {
+	n, err := c.MetaClient.DataNode(nodeID)
+	if err != nil {
+		return 0, err
+	} else if n == nil {
+		return 0, fmt.Errorf("node %d does not exist", nodeID)
+	}
+
+	// The response is sent once the upload is done, for as long as it takes.
+	var resp rpc.UploadShardSnapshotResponse
+	if err := c.Dialer.dialRPC(n.TCPHost, c.timeout, tlv.UploadShardSnapshotRequestMessage, &rpc.UploadShardSnapshotRequest{
+		ShardID: shardID,
+		Key:     key,
+	}, &resp); err != nil {
+		return 0, err
+	} else if resp.Err != "" {
+		return 0, errors.New(resp.Err)
+	}
+	return resp.Size, nil
+}
+
+// requestBackup asks the node nodeID to back up shardID, incrementally since
+// the snapshot baseID if non-zero, and returns the connection the backup is
+// streamed over along with the node's response.
+func (c *BackupCoordinator) requestBackup(nodeID, shardID, baseID uint64) (net.Conn, *rpc.BackupShardResponse, error) {
+	n, err := c.MetaClient.DataNode(nodeID)
+	if err != nil {
+		return nil, nil, err
+	} else if n == nil {
+		return nil, nil, fmt.Errorf("node %d does not exist", nodeID)
+	}
+
+	conn, err := c.Dialer.Dial(n.TCPHost, c.timeout)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var resp rpc.BackupShardResponse
+
+	if err := func() error {
+		conn.SetDeadline(time.Now().Add(c.timeout))
+
+		if err := tlv.EncodeTLV(conn, tlv.BackupShardRequestMessage, &rpc.BackupShardRequest{
+			ShardID:        shardID,
+			BaseSnapshotID: baseID,
+			Checksums:      true,
+		}); err != nil {
+			return err
+		}
+
+		if _, err := tlv.DecodeTLV(conn, &resp); err != nil {
+			return err
+		} else if resp.Err != "" {
+			return errors.New(resp.Err)
+		}
+
+		// The snapshot is streamed for as long as it takes.
+		return conn.SetDeadline(time.Time{})
+	}(); err != nil {
+		conn.Close()
+		return nil, nil, err
+	}
+	return conn, &resp, nil
+}
+
+// countingReader counts the bytes read from r.
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.n += int64(n)
+	return n, err
+}
+
+// processUploadShardSnapshotRequest uploads a snapshot of a local shard to the
+// SnapshotSink of the service.
+func (s *Service) processUploadShardSnapshotRequest(conn net.Conn) error {
+	var req rpc.UploadShardSnapshotRequest
+	if err := tlv.DecodeLV(conn, &req); err != nil {
+		return err
+	}
+
+	var resp rpc.UploadShardSnapshotResponse
+	if size, err := s.uploadShardSnapshot(req.ShardID, req.Key); err != nil {
+		resp.Err = err.Error()
+	} else {
+		resp.Size = size
+	}
+
+	return tlv.EncodeTLV(conn, tlv.UploadShardSnapshotResponseMessage, &resp)
+}
+
+// uploadShardSnapshot streams a snapshot of the shard id to the SnapshotSink
+// as key, without staging it locally.
+func (s *Service) uploadShardSnapshot(id uint64, key string) (int64, error) {
+	if s.SnapshotSink == nil {
+		return 0, errors.New("no snapshot sink configured")
+	}
+
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(s.backupShard(id, time.Time{}, pw, true))
+	}()
+
+	size, err := s.SnapshotSink.PutSnapshot(key, pr)
+	pr.CloseWithError(err)
+	if err != nil {
+		return 0, fmt.Errorf("upload shard %d: %s", id, err)
+	}
+
+	s.Logger.Info("uploaded shard snapshot", zap.Uint64("shardID", id), zap.String("key", key))
+
+	return size, nil
+}
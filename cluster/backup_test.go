@@ -0,0 +1,296 @@
+package cluster_test
+
+import (
+	"archive/tar"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/influxdata/influxdb/services/meta"
+	"github.com/zhexuany/influxcloud/cluster"
+	"github.com/zhexuany/influxcloud/rpc"
+	"github.com/zhexuany/influxcloud/tlv"
+)
+
+// Ensure every shard is backed up from one of its owners, spread across the
+// owners and failing over to another owner if one is unreachable.
+func TestBackupCoordinator_Backup(t *testing.T) {
+	s0, s1 := MustOpenService(), MustOpenService()
+	defer s0.Close()
+	defer s1.Close()
+	for i, s := range []*Service{s0, s1} {
+		nodeID := i + 1
+		s.TSDBStore.BackupShardFn = func(id uint64, since time.Time, w io.Writer) error {
+			return WriteShardBackup(w, id, uint64(nodeID))
+		}
+	}
+
+	start := time.Unix(0, 0).UTC()
+	mc := &backupMetaClient{
+		hosts: map[uint64]string{1: s0.Addr().String(), 2: s1.Addr().String(), 3: "127.0.0.1:0"},
+		groups: []meta.ShardGroupInfo{{
+			ID:        1,
+			StartTime: start,
+			EndTime:   start.Add(time.Hour),
+			Shards: []meta.ShardInfo{
+				{ID: 10, Owners: []meta.ShardOwner{{NodeID: 1}, {NodeID: 2}}},
+				{ID: 11, Owners: []meta.ShardOwner{{NodeID: 1}, {NodeID: 2}}},
+				{ID: 12, Owners: []meta.ShardOwner{{NodeID: 3}, {NodeID: 1}}},
+			},
+		}},
+	}
+	c := cluster.NewBackupCoordinator(time.Second)
+	c.MetaClient = mc
+
+	sink := make(backupSink)
+	m, err := c.Backup("db0", "rp0", start, start.Add(time.Hour), sink)
+	if err != nil {
+		t.Fatal(err)
+	} else if mc.database != "db0" || mc.policy != "rp0" {
+		t.Fatalf("unexpected shard groups requested: %s.%s", mc.database, mc.policy)
+	}
+
+	var shards []cluster.BackupShardInfo
+	for i, sh := range []struct{ id, nodeID uint64 }{{10, 1}, {11, 2}, {12, 1}} {
+		data := fmt.Sprintf("shard %d from node %d", sh.id, sh.nodeID)
+		if got := MustReadShardBackup(sink[sh.id]); got != data {
+			t.Fatalf("unexpected backup of shard %d: %q", sh.id, got)
+		} else if len(m.Shards) > i && m.Shards[i].SnapshotID == 0 {
+			t.Fatalf("expected snapshot id for shard %d", sh.id)
+		}
+		shards = append(shards, cluster.BackupShardInfo{
+			ID:           sh.id,
+			ShardGroupID: 1,
+			StartTime:    start,
+			EndTime:      start.Add(time.Hour),
+			NodeID:       sh.nodeID,
+			Size:         int64(len(sink[sh.id])),
+		})
+		if len(m.Shards) > i {
+			shards[i].SnapshotID = m.Shards[i].SnapshotID
+		}
+	}
+	exp := &cluster.BackupManifest{
+		Database:           "db0",
+		RetentionPolicy:    "rp0",
+		StartTime:          start,
+		EndTime:            start.Add(time.Hour),
+		ReplicaN:           2,
+		ShardGroupDuration: time.Hour,
+		Shards:             shards,
+	}
+	if !reflect.DeepEqual(m, exp) {
+		t.Fatalf("unexpected manifest:\n\ngot=%+v\n\nexp=%+v", m, exp)
+	}
+
+	// The backup fails if no owner of a shard can be reached.
+	mc.groups[0].Shards = []meta.ShardInfo{{ID: 13, Owners: []meta.ShardOwner{{NodeID: 3}}}}
+	if _, err := c.Backup("db0", "rp0", start, start.Add(time.Hour), sink); err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+// Ensure shards are backed up since their previous backup by the node that
+// took it, and in full by nodes that did not.
+func TestBackupCoordinator_IncrementalBackup(t *testing.T) {
+	s0, s1 := MustOpenService(), MustOpenService()
+	defer s0.Close()
+	defer s1.Close()
+	var mu sync.Mutex
+	since := make(map[uint64]time.Time)
+	for i, s := range []*Service{s0, s1} {
+		nodeID := i + 1
+		s.TSDBStore.BackupShardFn = func(id uint64, t time.Time, w io.Writer) error {
+			mu.Lock()
+			since[id] = t
+			mu.Unlock()
+			return WriteShardBackup(w, id, uint64(nodeID))
+		}
+	}
+
+	start := time.Unix(0, 0).UTC()
+	mc := &backupMetaClient{
+		hosts: map[uint64]string{1: s0.Addr().String(), 2: s1.Addr().String()},
+		groups: []meta.ShardGroupInfo{{
+			ID:        1,
+			StartTime: start,
+			EndTime:   start.Add(time.Hour),
+			Shards: []meta.ShardInfo{
+				{ID: 10, Owners: []meta.ShardOwner{{NodeID: 1}, {NodeID: 2}}},
+				{ID: 11, Owners: []meta.ShardOwner{{NodeID: 1}, {NodeID: 2}}},
+			},
+		}},
+	}
+	c := cluster.NewBackupCoordinator(time.Second)
+	c.MetaClient = mc
+
+	base, err := c.Backup("db0", "rp0", start, start.Add(time.Hour), make(backupSink))
+	if err != nil {
+		t.Fatal(err)
+	} else if base.Shards[0].Incremental() || base.Shards[1].Incremental() {
+		t.Fatalf("unexpected incremental backup: %+v", base.Shards)
+	}
+
+	// Shard 11 was backed up from node 2, which no longer owns it, so it
+	// is backed up in full from node 1.
+	mc.groups[0].Shards[1].Owners = []meta.ShardOwner{{NodeID: 1}}
+	m, err := c.IncrementalBackup(base, start, start.Add(time.Hour), make(backupSink))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if sh := m.Shards[0]; sh.NodeID != 1 || sh.BaseSnapshotID != base.Shards[0].SnapshotID || sh.SnapshotID <= sh.BaseSnapshotID {
+		t.Fatalf("unexpected incremental backup of shard 10: %+v", sh)
+	} else if exp := time.Unix(0, int64(sh.BaseSnapshotID)); !since[10].Equal(exp) {
+		t.Fatalf("unexpected since: %s", since[10])
+	}
+	if sh := m.Shards[1]; sh.NodeID != 1 || sh.Incremental() {
+		t.Fatalf("unexpected backup of shard 11: %+v", sh)
+	} else if since[11].After(start) {
+		t.Fatalf("unexpected since: %s", since[11])
+	}
+
+	// A snapshot the node did not take is not used as a base.
+	var resp rpc.BackupShardResponse
+	if err := s1.Request(tlv.BackupShardRequestMessage, &rpc.BackupShardRequest{ShardID: 10, BaseSnapshotID: 1}, &resp); err != nil {
+		t.Fatal(err)
+	} else if resp.BaseSnapshotID != 0 || resp.SnapshotID == 0 {
+		t.Fatalf("unexpected response: %+v", resp)
+	}
+}
+
+// Ensure every shard is uploaded by one of its owners to its snapshot sink,
+// failing over to another owner if one cannot upload it.
+func TestBackupCoordinator_Upload(t *testing.T) {
+	s0, s1 := MustOpenService(), MustOpenService()
+	defer s0.Close()
+	defer s1.Close()
+	sinks := []snapshotSink{make(snapshotSink), make(snapshotSink)}
+	for i, s := range []*Service{s0, s1} {
+		nodeID := i + 1
+		s.TSDBStore.BackupShardFn = func(id uint64, since time.Time, w io.Writer) error {
+			return WriteShardBackup(w, id, uint64(nodeID))
+		}
+		s.SnapshotSink = sinks[i]
+	}
+	// Node 1 fails to snapshot shard 10.
+	s0.TSDBStore.BackupShardFn = func(id uint64, since time.Time, w io.Writer) error {
+		if id == 10 {
+			return errors.New("marker")
+		}
+		return WriteShardBackup(w, id, 1)
+	}
+
+	start := time.Unix(0, 0).UTC()
+	mc := &backupMetaClient{
+		hosts: map[uint64]string{1: s0.Addr().String(), 2: s1.Addr().String()},
+		groups: []meta.ShardGroupInfo{{
+			ID:        1,
+			StartTime: start,
+			EndTime:   start.Add(time.Hour),
+			Shards: []meta.ShardInfo{
+				{ID: 10, Owners: []meta.ShardOwner{{NodeID: 1}, {NodeID: 2}}},
+				{ID: 11, Owners: []meta.ShardOwner{{NodeID: 1}, {NodeID: 2}}},
+			},
+		}},
+	}
+	c := cluster.NewBackupCoordinator(time.Second)
+	c.MetaClient = mc
+
+	m, err := c.Upload("db0", "rp0", start, start.Add(time.Hour), "backups")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i, sh := range []struct{ id, nodeID uint64 }{{10, 2}, {11, 1}} {
+		key := fmt.Sprintf("backups/db0/rp0/%d.tar", sh.id)
+		data := fmt.Sprintf("shard %d from node %d", sh.id, sh.nodeID)
+		buf := sinks[sh.nodeID-1][key]
+		if got := MustReadShardBackup(buf); got != data {
+			t.Fatalf("unexpected snapshot %s: %q", key, got)
+		} else if info := m.Shards[i]; info.ID != sh.id || info.NodeID != sh.nodeID || info.Key != key || info.Size != int64(len(buf)) {
+			t.Fatalf("unexpected shard %d in manifest: %+v", i, info)
+		}
+	}
+
+	// The upload fails if no owner has a snapshot sink.
+	s1.SnapshotSink = nil
+	if _, err := c.Upload("db0", "rp0", start, start.Add(time.Hour), "backups"); err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+// WriteShardBackup writes a backup of shard id taken on node nodeID to w.
+func WriteShardBackup(w io.Writer, id, nodeID uint64) error {
+	_, err := w.Write(MustTarShardBackup(fmt.Sprintf("db0/rp0/%d/000000001-000000001.tsm", id), fmt.Sprintf("shard %d from node %d", id, nodeID)))
+	return err
+}
+
+// MustReadShardBackup returns the data of the files in a shard backup
+// archive, leaving out the checksums of the files. Panic on error.
+func MustReadShardBackup(buf string) string {
+	var data string
+	tr := tar.NewReader(strings.NewReader(buf))
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return data
+		} else if err != nil {
+			panic(err)
+		}
+		b, err := ioutil.ReadAll(tr)
+		if err != nil {
+			panic(err)
+		} else if hdr.Name != ".influxcloud-checksums" {
+			data += string(b)
+		}
+	}
+}
+
+// backupMetaClient serves the shard groups and data nodes of a backup.
+type backupMetaClient struct {
+	hosts  map[uint64]string
+	groups []meta.ShardGroupInfo
+
+	database, policy string
+}
+
+func (c *backupMetaClient) DataNode(id uint64) (*meta.NodeInfo, error) {
+	return &meta.NodeInfo{ID: id, TCPHost: c.hosts[id]}, nil
+}
+
+func (c *backupMetaClient) RetentionPolicy(database, name string) (*meta.RetentionPolicyInfo, error) {
+	return &meta.RetentionPolicyInfo{Name: name, ReplicaN: 2, ShardGroupDuration: time.Hour}, nil
+}
+
+func (c *backupMetaClient) ShardGroupsByTimeRange(database, policy string, min, max time.Time) ([]meta.ShardGroupInfo, error) {
+	c.database, c.policy = database, policy
+	return c.groups, nil
+}
+
+// backupSink keeps the backup of each shard by shard ID.
+type backupSink map[uint64]string
+
+func (s backupSink) BackupShard(shard cluster.BackupShardInfo, r io.Reader) error {
+	buf, err := ioutil.ReadAll(r)
+	s[shard.ID] = string(buf)
+	return err
+}
+
+// snapshotSink keeps the snapshots uploaded to it by key.
+type snapshotSink map[string]string
+
+func (s snapshotSink) PutSnapshot(key string, r io.Reader) (int64, error) {
+	buf, err := ioutil.ReadAll(r)
+	if err != nil {
+		return 0, err
+	}
+	s[key] = string(buf)
+	return int64(len(buf)), nil
+}
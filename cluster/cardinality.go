@@ -0,0 +1,328 @@
+package cluster
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/influxdata/influxdb/services/meta"
+	"github.com/retailnext/hllpp"
+	"github.com/zhexuany/influxcloud/rpc"
+	"github.com/zhexuany/influxcloud/tlv"
+)
+
+// Cardinality is the number of series and measurements of a database across
+// the cluster. Series stored by several shards or nodes are counted once.
+type Cardinality struct {
+	Database     string `json:"database"`
+	SeriesN      int64  `json:"seriesN"`
+	MeasurementN int64  `json:"measurementN"`
+
+	// Exact is set if the series were counted from their keys, rather than
+	// estimated from the merged sketches of every node.
+	Exact bool `json:"exact"`
+
+	// Shards are the number of series in each copy of the shards of the
+	// database, in order of shard ID, then node ID.
+	Shards []ShardCardinality `json:"shards"`
+
+	// Unreachable are the data nodes that could not be queried, so that the
+	// series only they store are missing from the counts.
+	Unreachable []uint64 `json:"unreachable,omitempty"`
+}
+
+// ShardCardinality is the number of series in the copy of a shard stored by
+// a data node.
+type ShardCardinality struct {
+	ID      uint64 `json:"id"`
+	NodeID  uint64 `json:"nodeID"`
+	SeriesN int64  `json:"seriesN"`
+}
+
+// CardinalityClient collects the series cardinality from every data node in
+// the cluster, and merges it per database.
+type CardinalityClient struct {
+	timeout time.Duration
+
+	// Dialer connects to the data nodes.
+	Dialer *Dialer
+
+	MetaClient interface {
+		DataNodes() ([]meta.NodeInfo, error)
+	}
+}
+
+// NewCardinalityClient returns a new instance of CardinalityClient.
+func NewCardinalityClient(timeout time.Duration) *CardinalityClient {
+	return &CardinalityClient{timeout: timeout, Dialer: DefaultDialer}
+}
+
+// Cardinality returns the cardinality of database, or of every database if
+// empty, in order of name. Series are counted exactly if exact is set, which
+// sends every series key to this node, and estimated otherwise. Nodes are
+// queried concurrently; those that cannot be reached are reported in each
+// database's Unreachable instead of failing the whole request.
+func (c *CardinalityClient) Cardinality(database string, exact bool) ([]Cardinality, error) {
+	nodes, err := c.MetaClient.DataNodes()
+	if err != nil {
+		return nil, err
+	}
+
+	resps := make([]rpc.CardinalityResponse, len(nodes))
+	errs := make([]error, len(nodes))
+	var wg sync.WaitGroup
+	for i, n := range nodes {
+		wg.Add(1)
+		go func(i int, n meta.NodeInfo) {
+			defer wg.Done()
+			resps[i], errs[i] = c.nodeCardinality(n.TCPHost, database, exact)
+		}(i, n)
+	}
+	wg.Wait()
+
+	var unreachable []uint64
+	merged := make(map[string]*cardinalityMerger)
+	for i, n := range nodes {
+		if errs[i] != nil {
+			unreachable = append(unreachable, n.ID)
+			continue
+		}
+		for _, dc := range resps[i].Databases {
+			m := merged[dc.Name]
+			if m == nil {
+				m = newCardinalityMerger(exact)
+				merged[dc.Name] = m
+			}
+			if err := m.add(dc); err != nil {
+				return nil, fmt.Errorf("node %d: %s", n.ID, err)
+			}
+		}
+		for _, sc := range resps[i].Shards {
+			if m := merged[sc.Database]; m != nil {
+				m.shards = append(m.shards, ShardCardinality{ID: sc.ID, NodeID: n.ID, SeriesN: sc.SeriesN})
+			}
+		}
+	}
+	sort.Sort(uint64Slice(unreachable))
+
+	a := make([]Cardinality, 0, len(merged))
+	for name, m := range merged {
+		card := m.cardinality()
+		card.Database, card.Unreachable = name, unreachable
+		a = append(a, card)
+	}
+	sort.Sort(cardinalities(a))
+	return a, nil
+}
+
+// SeriesN returns the estimated number of series of database across the
+// cluster, so that a CardinalityClient can limit the series of a database
+// written by the PointsWriter.
+func (c *CardinalityClient) SeriesN(database string) (int64, error) {
+	a, err := c.Cardinality(database, false)
+	if err != nil {
+		return 0, err
+	}
+	for _, card := range a {
+		if card.Database == database {
+			return card.SeriesN, nil
+		}
+	}
+	return 0, nil
+}
+
+// nodeCardinality requests the cardinality of the local shards of database
+// from the node at addr.
+func (c *CardinalityClient) nodeCardinality(addr, database string, exact bool) (rpc.CardinalityResponse, error) {
+	var resp rpc.CardinalityResponse
+	if err := c.Dialer.dialRPC(addr, c.timeout, tlv.CardinalityRequestMessage, &rpc.CardinalityRequest{
+		Database: database,
+		Exact:    exact,
+	}, &resp); err != nil {
+		return rpc.CardinalityResponse{}, err
+	} else if resp.Err != "" {
+		return rpc.CardinalityResponse{}, errors.New(resp.Err)
+	}
+	return resp, nil
+}
+
+// cardinalityMerger merges the cardinality of a database reported by each
+// node, counting the series and measurements of several nodes once.
+type cardinalityMerger struct {
+	measurements map[string]struct{}
+	keys         map[string]struct{} // nil unless exact
+	sketch       *hllpp.HLLPP        // nil if exact
+	shards       []ShardCardinality
+}
+
+func newCardinalityMerger(exact bool) *cardinalityMerger {
+	m := &cardinalityMerger{measurements: make(map[string]struct{})}
+	if exact {
+		m.keys = make(map[string]struct{})
+	} else {
+		m.sketch = hllpp.New()
+	}
+	return m
+}
+
+// add merges the cardinality dc reported by a node.
+func (m *cardinalityMerger) add(dc rpc.DatabaseCardinality) error {
+	for _, name := range dc.Measurements {
+		m.measurements[name] = struct{}{}
+	}
+	if m.keys != nil {
+		for _, key := range dc.SeriesKeys {
+			m.keys[key] = struct{}{}
+		}
+		return nil
+	}
+
+	if len(dc.Sketch) == 0 {
+		return nil
+	}
+	sketch, err := hllpp.Unmarshal(dc.Sketch)
+	if err != nil {
+		return fmt.Errorf("invalid sketch of database %q: %s", dc.Name, err)
+	}
+	return m.sketch.Merge(sketch)
+}
+
+// cardinality returns the merged counts.
+func (m *cardinalityMerger) cardinality() Cardinality {
+	card := Cardinality{
+		MeasurementN: int64(len(m.measurements)),
+		Exact:        m.keys != nil,
+		Shards:       m.shards,
+	}
+	if m.keys != nil {
+		card.SeriesN = int64(len(m.keys))
+	} else {
+		card.SeriesN = int64(m.sketch.Count())
+	}
+	sort.Sort(shardCardinalities(card.Shards))
+	return card
+}
+
+// processCardinalityRequest responds with the cardinality of the local
+// shards of the requested databases.
+func (s *Service) processCardinalityRequest(conn net.Conn) error {
+	var req rpc.CardinalityRequest
+	if err := tlv.DecodeLV(conn, &req); err != nil {
+		return err
+	}
+
+	var resp rpc.CardinalityResponse
+	if dbs, shards, err := s.localCardinality(req.Database, req.Exact); err != nil {
+		resp.Err = err.Error()
+	} else {
+		resp.Databases, resp.Shards = dbs, shards
+	}
+
+	return tlv.EncodeTLV(conn, tlv.CardinalityResponseMessage, &resp)
+}
+
+// localCardinality returns the cardinality of database, or of every database
+// if empty, in the series index of this node, and the number of series of
+// each local shard of those databases. Databases without local series are
+// left out.
+func (s *Service) localCardinality(database string, exact bool) ([]rpc.DatabaseCardinality, []rpc.ShardCardinality, error) {
+	idx, ok := s.TSDBStore.(seriesIndex)
+	if !ok {
+		return nil, nil, fmt.Errorf("series index not available")
+	}
+
+	infos, err := s.shardInfos()
+	if err != nil {
+		return nil, nil, err
+	}
+	local := make(map[uint64]bool)
+	if s.ShardStore != nil {
+		for _, id := range s.ShardStore.ShardIDs() {
+			local[id] = true
+		}
+	}
+
+	// The databases and their local shards.
+	var names []string
+	shardIDs := make(map[string][]uint64)
+	if database != "" {
+		names = append(names, database)
+	}
+	for _, si := range infos {
+		if database != "" && si.Database != database {
+			continue
+		}
+		ids, ok := shardIDs[si.Database]
+		if !ok && database == "" {
+			names = append(names, si.Database)
+		}
+		if local[si.ID] {
+			ids = append(ids, si.ID)
+		}
+		shardIDs[si.Database] = ids
+	}
+	sort.Strings(names)
+
+	var dbs []rpc.DatabaseCardinality
+	var shards []rpc.ShardCardinality
+	for _, name := range names {
+		d := idx.DatabaseIndex(name)
+		if d == nil {
+			continue
+		}
+
+		dc := rpc.DatabaseCardinality{Name: name}
+		var sketch *hllpp.HLLPP
+		if !exact {
+			sketch = hllpp.New()
+		}
+		for _, m := range d.Measurements() {
+			dc.Measurements = append(dc.Measurements, m.Name)
+			for _, key := range m.SeriesKeys() {
+				dc.SeriesN++
+				if exact {
+					dc.SeriesKeys = append(dc.SeriesKeys, key)
+				} else {
+					sketch.Add([]byte(key))
+				}
+			}
+		}
+		if dc.SeriesN == 0 && len(dc.Measurements) == 0 {
+			continue
+		}
+		sort.Strings(dc.Measurements)
+		if exact {
+			sort.Strings(dc.SeriesKeys)
+		} else {
+			dc.Sketch = sketch.Marshal()
+		}
+		dbs = append(dbs, dc)
+
+		ids := shardIDs[name]
+		sort.Sort(uint64Slice(ids))
+		for _, id := range ids {
+			shards = append(shards, rpc.ShardCardinality{ID: id, Database: name, SeriesN: int64(d.SeriesShardN(id))})
+		}
+	}
+	return dbs, shards, nil
+}
+
+type cardinalities []Cardinality
+
+func (a cardinalities) Len() int           { return len(a) }
+func (a cardinalities) Less(i, j int) bool { return a[i].Database < a[j].Database }
+func (a cardinalities) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
+
+type shardCardinalities []ShardCardinality
+
+func (a shardCardinalities) Len() int { return len(a) }
+func (a shardCardinalities) Less(i, j int) bool {
+	if a[i].ID != a[j].ID {
+		return a[i].ID < a[j].ID
+	}
+	return a[i].NodeID < a[j].NodeID
+}
+func (a shardCardinalities) Swap(i, j int) { a[i], a[j] = a[j], a[i] }
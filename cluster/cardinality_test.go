@@ -0,0 +1,100 @@
+package cluster_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxdb/services/meta"
+	"github.com/influxdata/influxdb/tsdb"
+	"github.com/zhexuany/influxcloud/cluster"
+)
+
+// Ensure the cardinality of a database is merged across nodes, counting the
+// series stored by both nodes once.
+func TestCardinalityClient_Cardinality(t *testing.T) {
+	dbs := []meta.DatabaseInfo{{
+		Name: "db0",
+		RetentionPolicies: []meta.RetentionPolicyInfo{{
+			Name: "rp0",
+			ShardGroups: []meta.ShardGroupInfo{{
+				ID:     1,
+				Shards: []meta.ShardInfo{{ID: 10}, {ID: 11}},
+			}},
+		}},
+	}}
+
+	// newNode returns a service storing keys in shard id of db0.
+	newNode := func(id uint64, keys ...string) *Service {
+		idx := tsdb.NewDatabaseIndex("db0")
+		for _, key := range keys {
+			name, tags, err := models.ParseKey([]byte(key))
+			if err != nil {
+				t.Fatal(err)
+			}
+			idx.CreateSeriesIndexIfNotExists(name, tsdb.NewSeries(key, tags), false).AssignShard(id)
+		}
+
+		s := MustOpenService()
+		s.MetaClient.DatabasesFn = func() ([]meta.DatabaseInfo, error) { return dbs, nil }
+		s.TSDBStore.DatabaseIndexFn = func(name string) *tsdb.DatabaseIndex {
+			if name != "db0" {
+				return nil
+			}
+			return idx
+		}
+		s.Service.ShardStore = shardIDs{id}
+		return s
+	}
+	s0 := newNode(10, "cpu,host=a", "cpu,host=b", "mem,host=a")
+	defer s0.Close()
+	s1 := newNode(11, "cpu,host=b", "disk,host=c")
+	defer s1.Close()
+
+	c := cluster.NewCardinalityClient(time.Second)
+	c.MetaClient = &ServiceMetaClient{
+		DataNodesFn: func() ([]meta.NodeInfo, error) {
+			return []meta.NodeInfo{
+				{ID: 1, TCPHost: s0.Addr().String()},
+				{ID: 2, TCPHost: s1.Addr().String()},
+				{ID: 3, TCPHost: "127.0.0.1:0"},
+			}, nil
+		},
+	}
+
+	for _, exact := range []bool{true, false} {
+		a, err := c.Cardinality("", exact)
+		if err != nil {
+			t.Fatal(err)
+		} else if len(a) != 1 {
+			t.Fatalf("unexpected databases: %+v", a)
+		}
+
+		card := a[0]
+		if card.Database != "db0" || card.SeriesN != 4 || card.MeasurementN != 3 || card.Exact != exact {
+			t.Fatalf("unexpected cardinality (exact=%v): %+v", exact, card)
+		} else if !reflect.DeepEqual(card.Unreachable, []uint64{3}) {
+			t.Fatalf("unexpected unreachable nodes: %v", card.Unreachable)
+		} else if !reflect.DeepEqual(card.Shards, []cluster.ShardCardinality{
+			{ID: 10, NodeID: 1, SeriesN: 3},
+			{ID: 11, NodeID: 2, SeriesN: 2},
+		}) {
+			t.Fatalf("unexpected shards: %+v", card.Shards)
+		}
+	}
+
+	if n, err := c.SeriesN("db0"); err != nil {
+		t.Fatal(err)
+	} else if n != 4 {
+		t.Fatalf("unexpected series: %d", n)
+	} else if n, err := c.SeriesN("db1"); err != nil || n != 0 {
+		t.Fatalf("unexpected series of unknown database: %d, %v", n, err)
+	}
+}
+
+// shardIDs is a ShardStore storing shards without data.
+type shardIDs []uint64
+
+func (a shardIDs) ShardIDs() []uint64          { return a }
+func (a shardIDs) Shard(id uint64) *tsdb.Shard { return nil }
@@ -0,0 +1,179 @@
+package cluster
+
+import (
+	"archive/tar"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"io/ioutil"
+	"time"
+
+	"github.com/zhexuany/influxcloud/rpc"
+)
+
+// shardChecksumsName is the name of the entry a checksummed shard archive
+// ends with. It lists the checksums of the files before it.
+const shardChecksumsName = ".influxcloud-checksums"
+
+// maxShardStreamAttempts is the number of times a shard stream is sent if
+// the receiver finds that it does not match its checksums.
+const maxShardStreamAttempts = 3
+
+// shardChecksums lists the SHA-256 of each file of a shard archive, and the
+// SHA-256 of the list itself so that files cannot go missing unnoticed.
+type shardChecksums struct {
+	Files  []fileChecksum `json:"files"`
+	SHA256 string         `json:"sha256"`
+}
+
+// fileChecksum is the checksum of one file of a shard archive.
+type fileChecksum struct {
+	Name   string `json:"name"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// add adds the checksum of a file, as computed by h.
+func (c *shardChecksums) add(name string, size int64, h hash.Hash) {
+	c.Files = append(c.Files, fileChecksum{Name: name, Size: size, SHA256: hex.EncodeToString(h.Sum(nil))})
+}
+
+// sum returns the checksum of the list of files.
+func (c *shardChecksums) sum() string {
+	h := sha256.New()
+	for _, f := range c.Files {
+		fmt.Fprintf(h, "%s\x00%d\x00%s\n", f.Name, f.Size, f.SHA256)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// addShardChecksums copies the shard archive read from r to w, followed by
+// an entry with the checksums of its files.
+func addShardChecksums(w io.Writer, r io.Reader) error {
+	var sums shardChecksums
+	tr, tw := tar.NewReader(r), tar.NewWriter(w)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return err
+		}
+
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		h := sha256.New()
+		n, err := io.Copy(io.MultiWriter(tw, h), tr)
+		if err != nil {
+			return err
+		}
+		sums.add(hdr.Name, n, h)
+	}
+	sums.SHA256 = sums.sum()
+
+	buf, err := json.Marshal(&sums)
+	if err != nil {
+		return err
+	}
+	if err := tw.WriteHeader(&tar.Header{Name: shardChecksumsName, Mode: 0600, Size: int64(len(buf))}); err != nil {
+		return err
+	} else if _, err := tw.Write(buf); err != nil {
+		return err
+	}
+	return tw.Close()
+}
+
+// verifyShardChecksums copies the checksummed shard archive read from r to w,
+// without the checksum entry, and returns a *rpc.ChecksumError if a file does
+// not match its checksum. The end of the archive is only written once every
+// file is verified, so that a restore from w fails if any file does not
+// match. Archives without checksums are copied as is unless required is set.
+func verifyShardChecksums(w io.Writer, r io.Reader, shardID uint64, required bool) error {
+	var got shardChecksums
+	var exp *shardChecksums
+	tr, tw := tar.NewReader(r), tar.NewWriter(w)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return err
+		} else if exp != nil {
+			return &rpc.ChecksumError{ShardID: shardID, File: hdr.Name}
+		}
+
+		if hdr.Name == shardChecksumsName {
+			buf, err := ioutil.ReadAll(tr)
+			if err != nil {
+				return err
+			}
+			exp = &shardChecksums{}
+			if err := json.Unmarshal(buf, exp); err != nil {
+				return &rpc.ChecksumError{ShardID: shardID, File: shardChecksumsName}
+			}
+			continue
+		}
+
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		h := sha256.New()
+		n, err := io.Copy(io.MultiWriter(tw, h), tr)
+		if err != nil {
+			return err
+		}
+		got.add(hdr.Name, n, h)
+	}
+
+	if exp == nil {
+		if required {
+			return &rpc.ChecksumError{ShardID: shardID, File: shardChecksumsName}
+		}
+		return tw.Close()
+	}
+
+	// Checking the list catches corruption of the checksums themselves.
+	if exp.SHA256 != exp.sum() {
+		return &rpc.ChecksumError{ShardID: shardID, File: shardChecksumsName}
+	}
+	for i, f := range got.Files {
+		if i >= len(exp.Files) || exp.Files[i] != f {
+			return &rpc.ChecksumError{ShardID: shardID, File: f.Name}
+		}
+	}
+	if len(exp.Files) > len(got.Files) {
+		return &rpc.ChecksumError{ShardID: shardID, File: exp.Files[len(got.Files)].Name}
+	}
+	return tw.Close()
+}
+
+// backupShard writes a backup of the shard id to w, followed by the checksums
+// of its files if checksums is set.
+func (s *Service) backupShard(id uint64, since time.Time, w io.Writer, checksums bool) error {
+	if !checksums {
+		return s.TSDBStore.BackupShard(id, since, w)
+	}
+
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(s.TSDBStore.BackupShard(id, since, pw))
+	}()
+
+	err := addShardChecksums(w, pr)
+	if err == nil {
+		_, err = io.Copy(ioutil.Discard, pr)
+	}
+	pr.CloseWithError(err)
+	return err
+}
+
+// isChecksumError returns true if err is caused by a stream that did not
+// match its checksums, and may succeed if sent again.
+func isChecksumError(err error) bool {
+	_, ok := err.(*rpc.ChecksumError)
+	return ok
+}
@@ -0,0 +1,72 @@
+package cluster
+
+import (
+	"archive/tar"
+	"bytes"
+	"io/ioutil"
+	"testing"
+
+	"github.com/zhexuany/influxcloud/rpc"
+)
+
+// Ensure shard archives are verified against the checksums sent with them.
+func TestVerifyShardChecksums(t *testing.T) {
+	var archive bytes.Buffer
+	if err := addShardChecksums(&archive, bytes.NewReader(tarFiles(t, "db0/rp0/1/a.tsm", "aaaa", "db0/rp0/1/b.tsm", "bbbb"))); err != nil {
+		t.Fatal(err)
+	}
+
+	// A matching archive is copied without its checksums.
+	var buf bytes.Buffer
+	if err := verifyShardChecksums(&buf, bytes.NewReader(archive.Bytes()), 1, true); err != nil {
+		t.Fatal(err)
+	} else if names := tarNames(t, buf.Bytes()); len(names) != 2 || names[0] != "db0/rp0/1/a.tsm" || names[1] != "db0/rp0/1/b.tsm" {
+		t.Fatalf("unexpected files: %v", names)
+	}
+
+	// A corrupted file is reported.
+	corrupt := bytes.Replace(archive.Bytes(), []byte("bbbb"), []byte("bbbc"), 1)
+	if err := verifyShardChecksums(ioutil.Discard, bytes.NewReader(corrupt), 1, true); !isChecksumError(err) {
+		t.Fatalf("unexpected error: %v", err)
+	} else if e := err.(*rpc.ChecksumError); e.ShardID != 1 || e.File != "db0/rp0/1/b.tsm" {
+		t.Fatalf("unexpected error: %s", e)
+	}
+
+	// Archives without checksums are only accepted unless required.
+	plain := tarFiles(t, "db0/rp0/1/a.tsm", "aaaa")
+	if err := verifyShardChecksums(ioutil.Discard, bytes.NewReader(plain), 1, false); err != nil {
+		t.Fatal(err)
+	} else if err := verifyShardChecksums(ioutil.Discard, bytes.NewReader(plain), 1, true); !isChecksumError(err) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+// tarFiles returns an archive of the files given as name and data pairs.
+func tarFiles(t *testing.T, files ...string) []byte {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for i := 0; i < len(files); i += 2 {
+		if err := tw.WriteHeader(&tar.Header{Name: files[i], Mode: 0666, Size: int64(len(files[i+1]))}); err != nil {
+			t.Fatal(err)
+		} else if _, err := tw.Write([]byte(files[i+1])); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// tarNames returns the names of the files in an archive.
+func tarNames(t *testing.T, archive []byte) []string {
+	var names []string
+	tr := tar.NewReader(bytes.NewReader(archive))
+	for {
+		hdr, err := tr.Next()
+		if err != nil {
+			return names
+		}
+		names = append(names, hdr.Name)
+	}
+}
@@ -31,6 +31,27 @@ func (c *clientPool) getPool(nodeID uint64) (pool.Pool, bool) {
 	return p, ok
 }
 
+// remove closes and removes the pool for nodeID.
+func (c *clientPool) remove(nodeID uint64) {
+	c.mu.Lock()
+	if p, ok := c.pool[nodeID]; ok {
+		p.Close()
+		delete(c.pool, nodeID)
+	}
+	c.mu.Unlock()
+}
+
+// nodeIDs returns the IDs of the nodes that have a pool.
+func (c *clientPool) nodeIDs() []uint64 {
+	c.mu.RLock()
+	ids := make([]uint64, 0, len(c.pool))
+	for id := range c.pool {
+		ids = append(ids, id)
+	}
+	c.mu.RUnlock()
+	return ids
+}
+
 func (c *clientPool) size() int {
 	c.mu.RLock()
 	var size int
@@ -43,9 +64,12 @@ func (c *clientPool) size() int {
 
 func (c *clientPool) conn(nodeID uint64) (net.Conn, error) {
 	c.mu.RLock()
-	conn, err := c.pool[nodeID].Get()
-	c.mu.RUnlock()
-	return conn, err
+	defer c.mu.RUnlock()
+	p, ok := c.pool[nodeID]
+	if !ok {
+		return nil, pool.ErrClosed
+	}
+	return p.Get()
 }
 
 func (c *clientPool) close() {
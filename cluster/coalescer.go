@@ -0,0 +1,69 @@
+package cluster
+
+import (
+	"sync"
+	"time"
+
+	"github.com/influxdata/influxdb/models"
+)
+
+// writeCoalescer batches concurrent writes to the same shard into a single
+// write to the store. The first write to a shard opens a batch that is
+// flushed once the window has elapsed; writes to that shard arriving before
+// then are appended to the batch and wait for it to be flushed.
+//
+// Every write in a batch receives the error of the batch, so a point rejected
+// by the store fails all the requests it was batched with. Field type
+// conflicts are the exception: the service checks which points of each
+// request were rejected, and the requests without any succeed.
+type writeCoalescer struct {
+	window time.Duration
+	write  func(shardID uint64, points []models.Point) error
+
+	mu      sync.Mutex
+	batches map[uint64]*writeBatch
+}
+
+// writeBatch is the set of points waiting to be written to a shard.
+type writeBatch struct {
+	points []models.Point
+	done   chan struct{}
+	err    error
+}
+
+// newWriteCoalescer returns a writeCoalescer that batches writes for window
+// before passing them to write.
+func newWriteCoalescer(window time.Duration, write func(shardID uint64, points []models.Point) error) *writeCoalescer {
+	return &writeCoalescer{
+		window:  window,
+		write:   write,
+		batches: make(map[uint64]*writeBatch),
+	}
+}
+
+// WriteToShard adds points to the open batch for shardID and waits for the
+// batch to be written.
+func (c *writeCoalescer) WriteToShard(shardID uint64, points []models.Point) error {
+	c.mu.Lock()
+	b := c.batches[shardID]
+	if b == nil {
+		b = &writeBatch{done: make(chan struct{})}
+		c.batches[shardID] = b
+		time.AfterFunc(c.window, func() { c.flush(shardID, b) })
+	}
+	b.points = append(b.points, points...)
+	c.mu.Unlock()
+
+	<-b.done
+	return b.err
+}
+
+// flush closes the batch b for shardID and writes it to the store.
+func (c *writeCoalescer) flush(shardID uint64, b *writeBatch) {
+	c.mu.Lock()
+	delete(c.batches, shardID)
+	c.mu.Unlock()
+
+	b.err = c.write(shardID, b.points)
+	close(b.done)
+}
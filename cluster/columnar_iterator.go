@@ -0,0 +1,881 @@
+package cluster
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/influxdata/influxdb/influxql"
+	"github.com/zhexuany/influxcloud/rpc"
+)
+
+// A columnar stream encodes the points of a remote iterator as record
+// batches, in the manner of Arrow, rather than as one protobuf message per
+// point, so that the querying node decodes dense numeric series without
+// unmarshaling each point. Each batch is prefixed with its size as a big
+// endian uint32, and holds:
+//
+//	version     byte
+//	type        byte, the influxql.DataType of the values
+//	stats       uvarint SeriesN and PointN of the iterator
+//	rows        uvarint
+//	dictionary  uvarint count of series added, then the name of each and
+//	            its tags, as a uvarint count of keys followed by the keys
+//	            and values
+//	series      uvarint per row, the index of its series in the dictionary
+//	times       zigzag varint per row, the delta from the previous row
+//	validity    bitmap of the rows that are not nil
+//	aggregated  uvarint per row
+//	values      of the valid rows: a little endian float64 or int64 each, a
+//	            bitmap of the booleans, or a uvarint length each followed by
+//	            the data of the strings
+//	aux         uvarint count of columns, then for each a type byte per row
+//	            followed by the values of the rows that are not nil
+//
+// Strings are a uvarint length followed by their bytes, and bitmaps hold a
+// bit per row, least significant first. The dictionary lasts for the whole
+// stream, so each series is only sent once. The columns only follow the row
+// count of batches with rows; a batch without rows only carries statistics,
+// and one is sent first, last and every stats interval.
+
+// columnarBatchVersion is the version of the layout of columnar batches.
+const columnarBatchVersion = 1
+
+// columnarBatchSize is the number of rows of a full columnar batch.
+const columnarBatchSize = 1024
+
+// maxColumnarBatchSize is the size of the largest columnar batch read, so
+// that a corrupt size cannot make the querying node allocate it.
+const maxColumnarBatchSize = 1 << 28
+
+// Types of the auxiliary values of columnar batches. The nil types are the
+// typed nils of auxiliary fields missing from a point.
+const (
+	columnarAuxNil byte = iota
+	columnarAuxFloat
+	columnarAuxInteger
+	columnarAuxString
+	columnarAuxBoolean
+	columnarAuxFloatNil
+	columnarAuxIntegerNil
+	columnarAuxStringNil
+	columnarAuxBooleanNil
+)
+
+// errColumnarBatchTruncated is returned for a columnar batch that ends
+// before its last column.
+var errColumnarBatchTruncated = errors.New("columnar batch truncated")
+
+// columnarSeries identifies a series of the dictionary of a columnar stream.
+type columnarSeries struct {
+	name string
+	id   string
+}
+
+// columnarAuxColumn is an auxiliary column of a columnar batch.
+type columnarAuxColumn struct {
+	types  []byte
+	values []byte
+}
+
+// columnarEncoder encodes the points of an iterator as columnar batches.
+type columnarEncoder struct {
+	w     io.Writer
+	typ   influxql.DataType
+	stats func() influxql.IteratorStats
+
+	// series are the indexes of the series of the dictionary, and dict the
+	// encoded series added to it since the last batch.
+	series map[columnarSeries]int
+	dict   []byte
+	added  int
+
+	n      int   // The number of rows of the batch.
+	validN int   // The number of rows that are not nil.
+	prev   int64 // The time of the previous row.
+	index  []byte
+	times  []byte
+	valid  []byte
+	agg    []byte
+	values []byte
+	data   []byte // The data of the strings, whose lengths are in values.
+	aux    []columnarAuxColumn
+
+	buf []byte
+}
+
+// encodeColumnarIterator encodes the points of itr to w as columnar batches,
+// sending the statistics of itr every stats interval.
+func encodeColumnarIterator(w io.Writer, itr influxql.Iterator, interval time.Duration) error {
+	e := &columnarEncoder{w: w, stats: itr.Stats, series: make(map[columnarSeries]int)}
+
+	// next appends the next point of itr, and returns false once there
+	// are no more.
+	var next func() (bool, error)
+	switch itr := itr.(type) {
+	case influxql.FloatIterator:
+		e.typ = influxql.Float
+		next = func() (bool, error) {
+			p, err := itr.Next()
+			if err != nil || p == nil {
+				return false, err
+			} else if err := e.row(p.Name, p.Tags, p.Time, p.Nil, p.Aggregated, p.Aux); err != nil {
+				return false, err
+			}
+			if !p.Nil {
+				e.values = appendUint64LE(e.values, math.Float64bits(p.Value))
+			}
+			return true, nil
+		}
+	case influxql.IntegerIterator:
+		e.typ = influxql.Integer
+		next = func() (bool, error) {
+			p, err := itr.Next()
+			if err != nil || p == nil {
+				return false, err
+			} else if err := e.row(p.Name, p.Tags, p.Time, p.Nil, p.Aggregated, p.Aux); err != nil {
+				return false, err
+			}
+			if !p.Nil {
+				e.values = appendUint64LE(e.values, uint64(p.Value))
+			}
+			return true, nil
+		}
+	case influxql.StringIterator:
+		e.typ = influxql.String
+		next = func() (bool, error) {
+			p, err := itr.Next()
+			if err != nil || p == nil {
+				return false, err
+			} else if err := e.row(p.Name, p.Tags, p.Time, p.Nil, p.Aggregated, p.Aux); err != nil {
+				return false, err
+			}
+			if !p.Nil {
+				e.values = appendUvarint(e.values, uint64(len(p.Value)))
+				e.data = append(e.data, p.Value...)
+			}
+			return true, nil
+		}
+	case influxql.BooleanIterator:
+		e.typ = influxql.Boolean
+		next = func() (bool, error) {
+			p, err := itr.Next()
+			if err != nil || p == nil {
+				return false, err
+			} else if err := e.row(p.Name, p.Tags, p.Time, p.Nil, p.Aggregated, p.Aux); err != nil {
+				return false, err
+			}
+			if !p.Nil {
+				e.values = appendBit(e.values, e.validN-1, p.Value)
+			}
+			return true, nil
+		}
+	default:
+		return fmt.Errorf("unsupported iterator for columnar encoding: %T", itr)
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	// Emit initial stats.
+	if err := e.flush(); err != nil {
+		return err
+	}
+	for {
+		// Emit the rows read so far, with the stats, periodically.
+		select {
+		case <-ticker.C:
+			if err := e.flush(); err != nil {
+				return err
+			}
+		default:
+		}
+
+		if ok, err := next(); err != nil {
+			return err
+		} else if !ok {
+			break
+		}
+		if e.n == columnarBatchSize {
+			if err := e.flush(); err != nil {
+				return err
+			}
+		}
+	}
+
+	// Emit the last rows and the final stats.
+	return e.flush()
+}
+
+// row appends the columns of a point, other than its value, to the batch.
+// Rows of a batch have the same number of auxiliary values, so the batch is
+// sent first if aux has another number.
+func (e *columnarEncoder) row(name string, tags influxql.Tags, t int64, isNil bool, aggregated uint32, aux []interface{}) error {
+	if e.n > 0 && len(aux) != len(e.aux) {
+		if err := e.flush(); err != nil {
+			return err
+		}
+	}
+	if e.n == 0 && len(aux) != len(e.aux) {
+		e.aux = make([]columnarAuxColumn, len(aux))
+	}
+
+	key := columnarSeries{name: name, id: tags.ID()}
+	idx, ok := e.series[key]
+	if !ok {
+		idx = len(e.series)
+		e.series[key] = idx
+		e.dict = appendColumnarSeries(e.dict, name, tags)
+		e.added++
+	}
+	e.index = appendUvarint(e.index, uint64(idx))
+	e.times = appendVarint(e.times, t-e.prev)
+	e.prev = t
+	e.valid = appendBit(e.valid, e.n, !isNil)
+	e.agg = appendUvarint(e.agg, uint64(aggregated))
+	for i, v := range aux {
+		e.aux[i].append(v)
+	}
+
+	e.n++
+	if !isNil {
+		e.validN++
+	}
+	return nil
+}
+
+// flush sends the batch with the current stats of the iterator, and starts
+// the next batch.
+func (e *columnarEncoder) flush() error {
+	stats := e.stats()
+	b := append(e.buf[:0], 0, 0, 0, 0, columnarBatchVersion, byte(e.typ))
+	b = appendUvarint(b, uint64(stats.SeriesN))
+	b = appendUvarint(b, uint64(stats.PointN))
+	b = appendUvarint(b, uint64(e.n))
+	if e.n > 0 {
+		b = appendUvarint(b, uint64(e.added))
+		b = append(b, e.dict...)
+		b = append(b, e.index...)
+		b = append(b, e.times...)
+		b = append(b, e.valid...)
+		b = append(b, e.agg...)
+		b = append(b, e.values...)
+		b = append(b, e.data...)
+		b = appendUvarint(b, uint64(len(e.aux)))
+		for _, col := range e.aux {
+			b = append(b, col.types...)
+			b = append(b, col.values...)
+		}
+	}
+	binary.BigEndian.PutUint32(b, uint32(len(b)-4))
+	e.buf = b
+
+	e.n, e.validN, e.prev, e.added = 0, 0, 0, 0
+	e.dict, e.index, e.times, e.valid = e.dict[:0], e.index[:0], e.times[:0], e.valid[:0]
+	e.agg, e.values, e.data = e.agg[:0], e.values[:0], e.data[:0]
+	for i := range e.aux {
+		e.aux[i] = columnarAuxColumn{types: e.aux[i].types[:0], values: e.aux[i].values[:0]}
+	}
+
+	_, err := e.w.Write(b)
+	return err
+}
+
+// append appends the auxiliary value of a row to the column.
+func (c *columnarAuxColumn) append(v interface{}) {
+	switch v := v.(type) {
+	case float64:
+		c.types = append(c.types, columnarAuxFloat)
+		c.values = appendUint64LE(c.values, math.Float64bits(v))
+	case int64:
+		c.types = append(c.types, columnarAuxInteger)
+		c.values = appendUint64LE(c.values, uint64(v))
+	case string:
+		c.types = append(c.types, columnarAuxString)
+		c.values = appendColumnarString(c.values, v)
+	case bool:
+		c.types = append(c.types, columnarAuxBoolean)
+		if v {
+			c.values = append(c.values, 1)
+		} else {
+			c.values = append(c.values, 0)
+		}
+	case *float64:
+		c.types = append(c.types, columnarAuxFloatNil)
+	case *int64:
+		c.types = append(c.types, columnarAuxIntegerNil)
+	case *string:
+		c.types = append(c.types, columnarAuxStringNil)
+	case *bool:
+		c.types = append(c.types, columnarAuxBooleanNil)
+	default:
+		c.types = append(c.types, columnarAuxNil)
+	}
+}
+
+// appendColumnarSeries appends the dictionary entry of a series to b.
+func appendColumnarSeries(b []byte, name string, tags influxql.Tags) []byte {
+	b = appendColumnarString(b, name)
+	m := tags.KeyValues()
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	b = appendUvarint(b, uint64(len(keys)))
+	for _, k := range keys {
+		b = appendColumnarString(b, k)
+		b = appendColumnarString(b, m[k])
+	}
+	return b
+}
+
+// appendColumnarString appends s, prefixed with its length, to b.
+func appendColumnarString(b []byte, s string) []byte {
+	b = appendUvarint(b, uint64(len(s)))
+	return append(b, s...)
+}
+
+func appendUvarint(b []byte, v uint64) []byte {
+	var buf [binary.MaxVarintLen64]byte
+	return append(b, buf[:binary.PutUvarint(buf[:], v)]...)
+}
+
+func appendVarint(b []byte, v int64) []byte {
+	var buf [binary.MaxVarintLen64]byte
+	return append(b, buf[:binary.PutVarint(buf[:], v)]...)
+}
+
+// appendUint64LE appends v to b as little endian.
+func appendUint64LE(b []byte, v uint64) []byte {
+	var buf [8]byte
+	binary.LittleEndian.PutUint64(buf[:], v)
+	return append(b, buf[:]...)
+}
+
+// appendBit sets bit i of the bitmap b to v, growing b as needed.
+func appendBit(b []byte, i int, v bool) []byte {
+	if i%8 == 0 {
+		b = append(b, 0)
+	}
+	if v {
+		b[i/8] |= 1 << uint(i%8)
+	}
+	return b
+}
+
+// columnarBatch is the decoded columns of a columnar batch, by row.
+type columnarBatch struct {
+	n      int
+	series []int
+	times  []int64
+	nils   []bool
+	agg    []uint32
+	floats []float64
+	ints   []int64
+	strs   []string
+	bools  []bool
+	aux    [][]interface{}
+}
+
+// columnarDecoder decodes the columnar batches of a stream of points.
+type columnarDecoder struct {
+	r     io.Reader
+	typ   influxql.DataType
+	stats influxql.IteratorStats
+
+	// The dictionary of the stream.
+	names []string
+	tags  []influxql.Tags
+
+	batch columnarBatch
+	i     int // The next row of the batch.
+	buf   []byte
+	err   error
+}
+
+// newColumnarReaderIterator returns an iterator of type typ over the
+// columnar stream of points read from r.
+func newColumnarReaderIterator(r io.Reader, typ influxql.DataType, stats influxql.IteratorStats) influxql.Iterator {
+	dec := &columnarDecoder{r: r, typ: typ, stats: stats}
+	switch typ {
+	case influxql.Float:
+		return &columnarFloatIterator{dec: dec}
+	case influxql.Integer:
+		return &columnarIntegerIterator{dec: dec}
+	case influxql.String:
+		return &columnarStringIterator{dec: dec}
+	case influxql.Boolean:
+		return &columnarBooleanIterator{dec: dec}
+	default:
+		return influxql.NewReaderIterator(r, typ, stats)
+	}
+}
+
+// newStreamReaderIterator returns an iterator of type typ over the stream
+// of points read from r, encoded as encoding.
+func newStreamReaderIterator(r io.Reader, encoding string, typ influxql.DataType) influxql.Iterator {
+	if encoding == rpc.IteratorEncodingColumnar {
+		return newColumnarReaderIterator(r, typ, influxql.IteratorStats{})
+	}
+	return influxql.NewReaderIterator(r, typ, influxql.IteratorStats{})
+}
+
+// next returns the next row of the stream, and false once the stream ends
+// or fails with the error it failed with.
+func (d *columnarDecoder) next() (int, bool, error) {
+	for d.i >= d.batch.n {
+		if d.err == io.EOF {
+			return 0, false, nil
+		} else if d.err != nil {
+			return 0, false, d.err
+		}
+		d.err = d.readBatch()
+	}
+	d.i++
+	return d.i - 1, true, nil
+}
+
+// readBatch reads the next batch, returning io.EOF at the end of the stream.
+func (d *columnarDecoder) readBatch() error {
+	var sz [4]byte
+	if _, err := io.ReadFull(d.r, sz[:]); err != nil {
+		return err
+	}
+	n := binary.BigEndian.Uint32(sz[:])
+	if n > maxColumnarBatchSize {
+		return fmt.Errorf("columnar batch too large: %d bytes", n)
+	}
+	if uint32(cap(d.buf)) < n {
+		d.buf = make([]byte, n)
+	}
+	d.buf = d.buf[:n]
+	if _, err := io.ReadFull(d.r, d.buf); err == io.EOF {
+		return io.ErrUnexpectedEOF
+	} else if err != nil {
+		return err
+	}
+	return d.decodeBatch(d.buf)
+}
+
+// decodeBatch decodes the batch b, updating the stats and the dictionary.
+// Strings are copied, so that b can be reused.
+func (d *columnarDecoder) decodeBatch(b []byte) error {
+	r := columnarBatchReader{b: b}
+	version, typ := r.byte(), influxql.DataType(r.byte())
+	seriesN, pointN := r.uvarint(), r.uvarint()
+	n := r.count()
+	if r.err != nil {
+		return r.err
+	} else if version != columnarBatchVersion {
+		return fmt.Errorf("unsupported columnar batch version: %d", version)
+	} else if typ != d.typ {
+		return fmt.Errorf("columnar stream of %s points read as %s", typ, d.typ)
+	}
+	d.stats = influxql.IteratorStats{SeriesN: int(seriesN), PointN: int(pointN)}
+
+	batch := &d.batch
+	batch.reset(n)
+	d.i = 0
+	if n == 0 {
+		return nil
+	}
+
+	for i, added := 0, r.count(); i < added && r.err == nil; i++ {
+		name := string(r.bytes())
+		var m map[string]string
+		if tagN := r.count(); tagN > 0 {
+			m = make(map[string]string, tagN)
+			for j := 0; j < tagN && r.err == nil; j++ {
+				k := string(r.bytes())
+				m[k] = string(r.bytes())
+			}
+		}
+		d.names, d.tags = append(d.names, name), append(d.tags, influxql.NewTags(m))
+	}
+	for i := 0; i < n; i++ {
+		idx := r.uvarint()
+		if r.err == nil && idx >= uint64(len(d.names)) {
+			return fmt.Errorf("columnar batch series %d not in dictionary", idx)
+		}
+		batch.series[i] = int(idx)
+	}
+	var t int64
+	for i := 0; i < n; i++ {
+		t += r.varint()
+		batch.times[i] = t
+	}
+	valid := r.next((n + 7) / 8)
+	if r.err != nil {
+		return r.err
+	}
+	var validN int
+	for i := 0; i < n; i++ {
+		batch.nils[i] = valid[i/8]&(1<<uint(i%8)) == 0
+		if !batch.nils[i] {
+			validN++
+		}
+	}
+	for i := 0; i < n; i++ {
+		batch.agg[i] = uint32(r.uvarint())
+	}
+
+	switch d.typ {
+	case influxql.Float:
+		for i := 0; i < n; i++ {
+			if !batch.nils[i] {
+				batch.floats[i] = math.Float64frombits(r.uint64())
+			}
+		}
+	case influxql.Integer:
+		for i := 0; i < n; i++ {
+			if !batch.nils[i] {
+				batch.ints[i] = int64(r.uint64())
+			}
+		}
+	case influxql.String:
+		lens := make([]int, n)
+		for i := 0; i < n; i++ {
+			if !batch.nils[i] {
+				lens[i] = r.count()
+			}
+		}
+		for i := 0; i < n; i++ {
+			if !batch.nils[i] {
+				batch.strs[i] = string(r.next(lens[i]))
+			}
+		}
+	case influxql.Boolean:
+		bits := r.next((validN + 7) / 8)
+		for i, j := 0, 0; i < n && r.err == nil; i++ {
+			if !batch.nils[i] {
+				batch.bools[i] = bits[j/8]&(1<<uint(j%8)) != 0
+				j++
+			}
+		}
+	}
+
+	if auxN := r.count(); auxN > 0 && r.err == nil {
+		values := make([]interface{}, n*auxN)
+		for i := 0; i < n; i++ {
+			batch.aux[i] = values[i*auxN : (i+1)*auxN : (i+1)*auxN]
+		}
+		for j := 0; j < auxN && r.err == nil; j++ {
+			types := r.next(n)
+			for i := 0; i < len(types) && r.err == nil; i++ {
+				v, err := r.aux(types[i])
+				if err != nil {
+					return err
+				}
+				batch.aux[i][j] = v
+			}
+		}
+	}
+	if r.err == nil && len(r.b) > 0 {
+		return fmt.Errorf("columnar batch has %d unexpected trailing bytes", len(r.b))
+	}
+	return r.err
+}
+
+// reset makes the columns of b hold n rows.
+func (b *columnarBatch) reset(n int) {
+	if cap(b.series) < n {
+		b.series = make([]int, n)
+		b.times = make([]int64, n)
+		b.nils = make([]bool, n)
+		b.agg = make([]uint32, n)
+		b.aux = make([][]interface{}, n)
+	}
+	b.n = n
+	b.series, b.times, b.nils, b.agg = b.series[:n], b.times[:n], b.nils[:n], b.agg[:n]
+
+	// The auxiliary values of returned points are not reused.
+	b.aux = b.aux[:n]
+	for i := range b.aux {
+		b.aux[i] = nil
+	}
+
+	// Only the values of one type are read. Their columns are cleared, so
+	// that nil rows have zero values.
+	b.floats = resetFloats(b.floats, n)
+	b.ints = resetInts(b.ints, n)
+	b.strs = resetStrings(b.strs, n)
+	b.bools = resetBools(b.bools, n)
+}
+
+func resetFloats(a []float64, n int) []float64 {
+	if cap(a) < n {
+		return make([]float64, n)
+	}
+	a = a[:n]
+	for i := range a {
+		a[i] = 0
+	}
+	return a
+}
+
+func resetInts(a []int64, n int) []int64 {
+	if cap(a) < n {
+		return make([]int64, n)
+	}
+	a = a[:n]
+	for i := range a {
+		a[i] = 0
+	}
+	return a
+}
+
+func resetStrings(a []string, n int) []string {
+	if cap(a) < n {
+		return make([]string, n)
+	}
+	a = a[:n]
+	for i := range a {
+		a[i] = ""
+	}
+	return a
+}
+
+func resetBools(a []bool, n int) []bool {
+	if cap(a) < n {
+		return make([]bool, n)
+	}
+	a = a[:n]
+	for i := range a {
+		a[i] = false
+	}
+	return a
+}
+
+// close closes the reader of the stream, if applicable.
+func (d *columnarDecoder) close() error {
+	if r, ok := d.r.(io.ReadCloser); ok {
+		return r.Close()
+	}
+	return nil
+}
+
+// columnarBatchReader reads the columns of a columnar batch. Once a read
+// fails, err is set and later reads return zero values.
+type columnarBatchReader struct {
+	b   []byte
+	err error
+}
+
+func (r *columnarBatchReader) fail() {
+	if r.err == nil {
+		r.err = errColumnarBatchTruncated
+	}
+	r.b = nil
+}
+
+func (r *columnarBatchReader) byte() byte {
+	if len(r.b) < 1 {
+		r.fail()
+		return 0
+	}
+	v := r.b[0]
+	r.b = r.b[1:]
+	return v
+}
+
+// next returns the next n bytes.
+func (r *columnarBatchReader) next(n int) []byte {
+	if n < 0 || len(r.b) < n {
+		r.fail()
+		return nil
+	}
+	v := r.b[:n]
+	r.b = r.b[n:]
+	return v
+}
+
+func (r *columnarBatchReader) bytes() []byte {
+	return r.next(r.count())
+}
+
+func (r *columnarBatchReader) uint64() uint64 {
+	b := r.next(8)
+	if b == nil {
+		return 0
+	}
+	return binary.LittleEndian.Uint64(b)
+}
+
+func (r *columnarBatchReader) uvarint() uint64 {
+	v, n := binary.Uvarint(r.b)
+	if n <= 0 {
+		r.fail()
+		return 0
+	}
+	r.b = r.b[n:]
+	return v
+}
+
+func (r *columnarBatchReader) varint() int64 {
+	v, n := binary.Varint(r.b)
+	if n <= 0 {
+		r.fail()
+		return 0
+	}
+	r.b = r.b[n:]
+	return v
+}
+
+// count returns a uvarint count of items of at least a byte each, failing
+// if there are fewer bytes left.
+func (r *columnarBatchReader) count() int {
+	v := r.uvarint()
+	if v > uint64(len(r.b)) {
+		r.fail()
+		return 0
+	}
+	return int(v)
+}
+
+// aux returns an auxiliary value of type typ.
+func (r *columnarBatchReader) aux(typ byte) (interface{}, error) {
+	switch typ {
+	case columnarAuxNil:
+		return nil, nil
+	case columnarAuxFloat:
+		return math.Float64frombits(r.uint64()), nil
+	case columnarAuxInteger:
+		return int64(r.uint64()), nil
+	case columnarAuxString:
+		return string(r.bytes()), nil
+	case columnarAuxBoolean:
+		return r.byte() != 0, nil
+	case columnarAuxFloatNil:
+		return (*float64)(nil), nil
+	case columnarAuxIntegerNil:
+		return (*int64)(nil), nil
+	case columnarAuxStringNil:
+		return (*string)(nil), nil
+	case columnarAuxBooleanNil:
+		return (*bool)(nil), nil
+	default:
+		return nil, fmt.Errorf("unknown columnar auxiliary type: %d", typ)
+	}
+}
+
+// columnarFloatIterator reads float points from a columnar stream.
+type columnarFloatIterator struct {
+	dec *columnarDecoder
+}
+
+// Stats returns the last statistics sent with the stream.
+func (itr *columnarFloatIterator) Stats() influxql.IteratorStats { return itr.dec.stats }
+
+// Close closes the reader of the stream, if applicable.
+func (itr *columnarFloatIterator) Close() error { return itr.dec.close() }
+
+// Next returns the next point of the stream.
+func (itr *columnarFloatIterator) Next() (*influxql.FloatPoint, error) {
+	i, ok, err := itr.dec.next()
+	if !ok {
+		return nil, err
+	}
+	d, b := itr.dec, &itr.dec.batch
+	return &influxql.FloatPoint{
+		Name:       d.names[b.series[i]],
+		Tags:       d.tags[b.series[i]],
+		Time:       b.times[i],
+		Nil:        b.nils[i],
+		Value:      b.floats[i],
+		Aux:        b.aux[i],
+		Aggregated: b.agg[i],
+	}, nil
+}
+
+// columnarIntegerIterator reads integer points from a columnar stream.
+type columnarIntegerIterator struct {
+	dec *columnarDecoder
+}
+
+// Stats returns the last statistics sent with the stream.
+func (itr *columnarIntegerIterator) Stats() influxql.IteratorStats { return itr.dec.stats }
+
+// Close closes the reader of the stream, if applicable.
+func (itr *columnarIntegerIterator) Close() error { return itr.dec.close() }
+
+// Next returns the next point of the stream.
+func (itr *columnarIntegerIterator) Next() (*influxql.IntegerPoint, error) {
+	i, ok, err := itr.dec.next()
+	if !ok {
+		return nil, err
+	}
+	d, b := itr.dec, &itr.dec.batch
+	return &influxql.IntegerPoint{
+		Name:       d.names[b.series[i]],
+		Tags:       d.tags[b.series[i]],
+		Time:       b.times[i],
+		Nil:        b.nils[i],
+		Value:      b.ints[i],
+		Aux:        b.aux[i],
+		Aggregated: b.agg[i],
+	}, nil
+}
+
+// columnarStringIterator reads string points from a columnar stream.
+type columnarStringIterator struct {
+	dec *columnarDecoder
+}
+
+// Stats returns the last statistics sent with the stream.
+func (itr *columnarStringIterator) Stats() influxql.IteratorStats { return itr.dec.stats }
+
+// Close closes the reader of the stream, if applicable.
+func (itr *columnarStringIterator) Close() error { return itr.dec.close() }
+
+// Next returns the next point of the stream.
+func (itr *columnarStringIterator) Next() (*influxql.StringPoint, error) {
+	i, ok, err := itr.dec.next()
+	if !ok {
+		return nil, err
+	}
+	d, b := itr.dec, &itr.dec.batch
+	return &influxql.StringPoint{
+		Name:       d.names[b.series[i]],
+		Tags:       d.tags[b.series[i]],
+		Time:       b.times[i],
+		Nil:        b.nils[i],
+		Value:      b.strs[i],
+		Aux:        b.aux[i],
+		Aggregated: b.agg[i],
+	}, nil
+}
+
+// columnarBooleanIterator reads boolean points from a columnar stream.
+type columnarBooleanIterator struct {
+	dec *columnarDecoder
+}
+
+// Stats returns the last statistics sent with the stream.
+func (itr *columnarBooleanIterator) Stats() influxql.IteratorStats { return itr.dec.stats }
+
+// Close closes the reader of the stream, if applicable.
+func (itr *columnarBooleanIterator) Close() error { return itr.dec.close() }
+
+// Next returns the next point of the stream.
+func (itr *columnarBooleanIterator) Next() (*influxql.BooleanPoint, error) {
+	i, ok, err := itr.dec.next()
+	if !ok {
+		return nil, err
+	}
+	d, b := itr.dec, &itr.dec.batch
+	return &influxql.BooleanPoint{
+		Name:       d.names[b.series[i]],
+		Tags:       d.tags[b.series[i]],
+		Time:       b.times[i],
+		Nil:        b.nils[i],
+		Value:      b.bools[i],
+		Aux:        b.aux[i],
+		Aggregated: b.agg[i],
+	}, nil
+}
@@ -0,0 +1,171 @@
+package cluster
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/influxdata/influxdb/influxql"
+)
+
+// floatPointIterator returns a slice of float points.
+type floatPointIterator struct {
+	points []influxql.FloatPoint
+	stats  influxql.IteratorStats
+}
+
+func (itr *floatPointIterator) Stats() influxql.IteratorStats { return itr.stats }
+func (itr *floatPointIterator) Close() error                  { return nil }
+
+func (itr *floatPointIterator) Next() (*influxql.FloatPoint, error) {
+	if len(itr.points) == 0 {
+		return nil, nil
+	}
+	p := &itr.points[0]
+	itr.points = itr.points[1:]
+	itr.stats.PointN++
+	return p, nil
+}
+
+// stringPointIterator returns a slice of string points.
+type stringPointIterator struct {
+	points []influxql.StringPoint
+}
+
+func (itr *stringPointIterator) Stats() influxql.IteratorStats { return influxql.IteratorStats{} }
+func (itr *stringPointIterator) Close() error                  { return nil }
+
+func (itr *stringPointIterator) Next() (*influxql.StringPoint, error) {
+	if len(itr.points) == 0 {
+		return nil, nil
+	}
+	p := &itr.points[0]
+	itr.points = itr.points[1:]
+	return p, nil
+}
+
+// Ensure points survive a columnar stream, over several batches.
+func TestColumnarIterator_Float(t *testing.T) {
+	cpuA := influxql.NewTags(map[string]string{"host": "a", "region": "west"})
+	cpuB := influxql.NewTags(map[string]string{"host": "b"})
+
+	var points []influxql.FloatPoint
+	for i := 0; i < 3*columnarBatchSize+10; i++ {
+		p := influxql.FloatPoint{Name: "cpu", Tags: cpuA, Time: int64(i) * int64(time.Second), Value: float64(i) / 4}
+		switch i % 4 {
+		case 1:
+			p.Tags = cpuB
+			p.Aggregated = 3
+		case 2:
+			p.Name, p.Tags, p.Nil, p.Value = "mem", influxql.Tags{}, true, 0
+		case 3:
+			p.Time = -p.Time
+		}
+		points = append(points, p)
+	}
+
+	var buf bytes.Buffer
+	itr := &floatPointIterator{points: points, stats: influxql.IteratorStats{SeriesN: 3}}
+	if err := encodeColumnarIterator(&buf, itr, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+
+	dec := newColumnarReaderIterator(&buf, influxql.Float, influxql.IteratorStats{}).(influxql.FloatIterator)
+	for i := range points {
+		p, err := dec.Next()
+		if err != nil {
+			t.Fatal(err)
+		} else if p == nil {
+			t.Fatalf("stream ended after %d points", i)
+		} else if p.Name != points[i].Name || p.Tags.ID() != points[i].Tags.ID() || p.Time != points[i].Time ||
+			p.Nil != points[i].Nil || p.Value != points[i].Value || p.Aggregated != points[i].Aggregated || p.Aux != nil {
+			t.Fatalf("point %d: got %+v, exp %+v", i, p, points[i])
+		} else if !reflect.DeepEqual(p.Tags.KeyValues(), points[i].Tags.KeyValues()) {
+			t.Fatalf("point %d tags: got %v, exp %v", i, p.Tags.KeyValues(), points[i].Tags.KeyValues())
+		}
+	}
+	if p, err := dec.Next(); err != nil || p != nil {
+		t.Fatalf("unexpected point at end of stream: %v, %v", p, err)
+	} else if p, err := dec.Next(); err != nil || p != nil {
+		t.Fatalf("unexpected point after end of stream: %v, %v", p, err)
+	}
+	if stats := dec.Stats(); stats.SeriesN != 3 || stats.PointN != len(points) {
+		t.Fatalf("unexpected stats: %+v", stats)
+	}
+}
+
+// Ensure strings and auxiliary values survive a columnar stream.
+func TestColumnarIterator_StringAux(t *testing.T) {
+	points := []influxql.StringPoint{
+		{Name: "log", Time: 1, Value: "hello", Aux: []interface{}{1.5, int64(2), "x", true}},
+		{Name: "log", Time: 2, Nil: true, Aux: []interface{}{(*float64)(nil), (*int64)(nil), (*string)(nil), (*bool)(nil)}},
+		{Name: "log", Time: 3, Value: "", Aux: []interface{}{nil, int64(-1), "", false}},
+		{Name: "log", Time: 4, Value: "world", Aux: []interface{}{2.5}},
+		{Name: "log", Time: 5, Value: "!"},
+	}
+
+	var buf bytes.Buffer
+	if err := encodeColumnarIterator(&buf, &stringPointIterator{points: append([]influxql.StringPoint(nil), points...)}, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	b := buf.Bytes()
+
+	dec := newColumnarReaderIterator(bytes.NewReader(b), influxql.String, influxql.IteratorStats{}).(influxql.StringIterator)
+	for i := range points {
+		p, err := dec.Next()
+		if err != nil {
+			t.Fatal(err)
+		} else if p == nil {
+			t.Fatalf("stream ended after %d points", i)
+		} else if p.Time != points[i].Time || p.Nil != points[i].Nil || p.Value != points[i].Value {
+			t.Fatalf("point %d: got %+v, exp %+v", i, p, points[i])
+		} else if !reflect.DeepEqual(p.Aux, points[i].Aux) {
+			t.Fatalf("point %d aux: got %#v, exp %#v", i, p.Aux, points[i].Aux)
+		}
+	}
+
+	// Truncated streams fail rather than end early.
+	for i := 1; i < len(b); i++ {
+		dec := newColumnarReaderIterator(bytes.NewReader(b[:i]), influxql.String, influxql.IteratorStats{}).(influxql.StringIterator)
+		var err error
+		for {
+			var p *influxql.StringPoint
+			if p, err = dec.Next(); err != nil || p == nil {
+				break
+			}
+		}
+		if err == nil && !isBatchBoundary(b, i) {
+			t.Fatalf("expected error reading %d of %d bytes", i, len(b))
+		}
+	}
+}
+
+// isBatchBoundary returns true if the first n bytes of the columnar stream
+// b end with a whole batch.
+func isBatchBoundary(b []byte, n int) bool {
+	for i := 0; i < n; {
+		if len(b)-i < 4 {
+			return false
+		}
+		i += 4 + int(binary.BigEndian.Uint32(b[i:]))
+		if i == n {
+			return true
+		}
+	}
+	return false
+}
+
+// Ensure a columnar stream read with another type fails.
+func TestColumnarIterator_TypeMismatch(t *testing.T) {
+	var buf bytes.Buffer
+	if err := encodeColumnarIterator(&buf, &stringPointIterator{points: []influxql.StringPoint{{Name: "log", Value: "x"}}}, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	dec := newColumnarReaderIterator(&buf, influxql.Float, influxql.IteratorStats{}).(influxql.FloatIterator)
+	if _, err := dec.Next(); err == nil || err == io.EOF {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
@@ -1,10 +1,15 @@
 package cluster
 
 import (
+	"crypto/tls"
+	"fmt"
 	"time"
 
 	"github.com/influxdata/influxdb/influxql"
 	"github.com/influxdata/influxdb/toml"
+	cloudMeta "github.com/zhexuany/influxcloud/meta"
+	"github.com/zhexuany/influxcloud/rpc"
+	"github.com/zhexuany/influxcloud/tlv"
 )
 
 const (
@@ -27,6 +32,11 @@ const (
 	// DefaultWriteTimeout is the default timeout for a complete write to succeed.
 	DefaultWriteTimeout = 5 * time.Second
 
+	// DefaultOwnerWriteTimeout is the default timeout for a single attempt to
+	// write to a shard owner. Zero lets an attempt use what is left of the
+	// write timeout.
+	DefaultOwnerWriteTimeout = 0
+
 	// DefaultMaxConcurrentQueries is the maximum number of running queries.
 	// A value of zero will make the maximum query limit unlimited.
 	DefaultMaxConcurrentQueries = 0
@@ -42,6 +52,261 @@ const (
 	// DefaultMaxSelectSeriesN is the maximum number of series a SELECT can run.
 	// A value of zero will make the maximum series count unlimited.
 	DefaultMaxSelectBucketsN = 0
+
+	// DefaultHTTPBindAddress is the default address the status HTTP listener binds to.
+	DefaultHTTPBindAddress = ":8090"
+
+	// DefaultDrainTimeout is the default time to wait for in-flight requests
+	// to finish when a node is drained.
+	DefaultDrainTimeout = 30 * time.Second
+
+	// DefaultWriteCoalesceWindow is the default time inbound writes to the
+	// same shard are batched for. A value of zero disables batching.
+	DefaultWriteCoalesceWindow = 0
+
+	// DefaultDNSTTL is the default time the IP addresses a node hostname
+	// resolves to are cached for. A value of zero resolves hostnames on
+	// every dial.
+	DefaultDNSTTL = 30 * time.Second
+
+	// DefaultKeepAliveInterval is the default interval at which idle
+	// connections to other nodes are pinged. A value of zero disables pings.
+	DefaultKeepAliveInterval = 30 * time.Second
+
+	// DefaultMaxFutureWrite is the default limit on how far in the future
+	// points may be written. A value of zero disables the limit.
+	DefaultMaxFutureWrite = 0
+
+	// DefaultMaxPastWrite is the default limit on how far in the past points
+	// may be written. A value of zero disables the limit.
+	DefaultMaxPastWrite = 0
+
+	// DefaultShardAssignment is the default mode points are assigned to the
+	// shards of new shard groups with.
+	DefaultShardAssignment = cloudMeta.ShardAssignmentHash
+
+	// DefaultWriteForwardThreshold is the default share of a write's points
+	// that must belong to a single remote node for the whole write to be
+	// forwarded to it. Zero disables forwarding.
+	DefaultWriteForwardThreshold = 0
+
+	// DefaultIntoWriteConsistency is the default consistency level the
+	// results of SELECT ... INTO statements are written with.
+	DefaultIntoWriteConsistency = "one"
+
+	// DefaultMaxConcurrentRemoteIterators is the maximum number of iterators
+	// other nodes may have open on this node at once. A value of zero will
+	// make the maximum unlimited.
+	DefaultMaxConcurrentRemoteIterators = 0
+
+	// DefaultMaxRemoteQueryBytes is the maximum number of bytes of points a
+	// single remote iterator may stream to the querying node. A value of zero
+	// will make the maximum unlimited.
+	DefaultMaxRemoteQueryBytes = 0
+
+	// DefaultMaxRemoteSeriesN is the maximum number of series a single remote
+	// iterator may read. A value of zero will make the maximum unlimited.
+	DefaultMaxRemoteSeriesN = 0
+
+	// DefaultMaxQueryNodeConnections is the maximum number of nodes a query
+	// requests remote iterators from at once. A value of zero will make the
+	// maximum unlimited.
+	DefaultMaxQueryNodeConnections = 0
+
+	// DefaultIteratorStallTimeout is the default time a querying node may
+	// stop reading the stream of a remote iterator for before the stream is
+	// aborted. A value of zero lets it stall indefinitely.
+	DefaultIteratorStallTimeout = 5 * time.Minute
+
+	// DefaultLeaseDuration is the default time a lease granted to a data
+	// node, e.g. to run continuous queries, is held for unless renewed.
+	DefaultLeaseDuration = 60 * time.Second
+
+	// DefaultWriteHedgeDelay is the default time writes at consistency ONE
+	// wait for the first owner of a shard before also writing to the others.
+	// A value of zero writes to every owner at once.
+	DefaultWriteHedgeDelay = 0
+
+	// DefaultLateWriteWindow is the default time a remote write that timed
+	// out is waited on to succeed late before it is queued in hinted
+	// handoff. A value of zero queues it right away.
+	DefaultLateWriteWindow = time.Second
+
+	// DefaultIteratorCacheTTL is the default time the points of a remote
+	// iterator are cached for.
+	DefaultIteratorCacheTTL = 10 * time.Minute
+
+	// DefaultIteratorCacheMinAge is the default time before now the range
+	// of a remote iterator must end by for its points to be cached.
+	DefaultIteratorCacheMinAge = 10 * time.Minute
+
+	// DefaultShardCopyRateLimit is the default limit, in bytes per second,
+	// on the rate each shard copied or restored to a node is received at. A
+	// value of zero does not limit it.
+	DefaultShardCopyRateLimit = 0
+
+	// DefaultShardCopyNodeRateLimit is the default limit, in bytes per
+	// second, on the rate all shards copied or restored to a node at once
+	// are received at. A value of zero does not limit it.
+	DefaultShardCopyNodeRateLimit = 0
+
+	// DefaultMaxWALBacklog is the default size of the WAL segments not yet
+	// compacted above which a node rejects writes as overloaded. A value of
+	// zero disables the limit.
+	DefaultMaxWALBacklog = 0
+
+	// DefaultMaxCacheSize is the default size of the in-memory caches above
+	// which a node rejects writes as overloaded. A value of zero disables
+	// the limit.
+	DefaultMaxCacheSize = 0
+
+	// DefaultMaxCompactionDebt is the default number of cache snapshots and
+	// TSM compactions in progress above which a node rejects writes as
+	// overloaded. A value of zero disables the limit.
+	DefaultMaxCompactionDebt = 0
+
+	// DefaultMaxConcurrentWritesPerDatabase is the maximum number of shard
+	// writes of a single database other nodes may have in flight on this
+	// node. A value of zero will make the maximum unlimited.
+	DefaultMaxConcurrentWritesPerDatabase = 0
+
+	// DefaultMaxConcurrentIteratorsPerDatabase is the maximum number of
+	// iterators over a single database other nodes may have open on this
+	// node. A value of zero will make the maximum unlimited.
+	DefaultMaxConcurrentIteratorsPerDatabase = 0
+
+	// DefaultDatabaseBandwidthLimit is the default limit, in bytes per
+	// second, on the rate each database's shard writes are received and
+	// iterators are streamed at. A value of zero does not limit it.
+	DefaultDatabaseBandwidthLimit = 0
+
+	// DefaultMaxTagsPerPoint is the default maximum number of tags a point
+	// may have. A value of zero will make the maximum unlimited.
+	DefaultMaxTagsPerPoint = 0
+
+	// DefaultMaxFieldValueSize is the default maximum size, in bytes, of the
+	// string field values of a point. A value of zero will make the maximum
+	// unlimited.
+	DefaultMaxFieldValueSize = 0
+
+	// DefaultMaxSeriesPerDatabase is the default number of series of a
+	// database above which writes to it are rejected. A value of zero will
+	// make the maximum unlimited.
+	DefaultMaxSeriesPerDatabase = 0
+
+	// DefaultOrphanShardCheckInterval is the default interval at which the
+	// local shards are checked for shards no longer assigned to this node.
+	// A value of zero disables the check.
+	DefaultOrphanShardCheckInterval = 10 * time.Minute
+
+	// DefaultOrphanShardGracePeriod is the default time a shard must stay
+	// orphaned for before it is acted on, so that shards being copied to
+	// this node are not removed.
+	DefaultOrphanShardGracePeriod = 24 * time.Hour
+
+	// DefaultOrphanShardAction is the default action taken on orphan
+	// shards once their grace period has passed.
+	DefaultOrphanShardAction = OrphanShardReport
+
+	// DefaultStartupShardCheck is whether the shards this node owns in the
+	// meta store are checked against its local shards when it starts.
+	DefaultStartupShardCheck = true
+
+	// DefaultStartupShardRepair is whether the owned shards missing locally
+	// at startup are copied from their other owners.
+	DefaultStartupShardRepair = false
+
+	// DefaultShardScrubInterval is the default interval at which the TSM
+	// files of the local shards are verified against their checksums. A
+	// value of zero disables scrubbing.
+	DefaultShardScrubInterval = 0
+
+	// DefaultShardScrubRateLimit is the default limit, in bytes per second,
+	// on the rate TSM files are read at while scrubbing, so that scrubbing
+	// does not compete with queries. A value of zero will make the rate
+	// unlimited.
+	DefaultShardScrubRateLimit = 8 * 1024 * 1024
+
+	// DefaultShardScrubRepair is whether corrupt shards found by scrubbing
+	// are replaced by a copy from another owner.
+	DefaultShardScrubRepair = false
+
+	// DefaultNodeCheckInterval is the default interval at which the data
+	// nodes and shards in the meta store are checked for changes to publish
+	// as events. A value of zero disables the check.
+	DefaultNodeCheckInterval = 10 * time.Second
+
+	// DefaultEventWebhookTimeout is the default time an event may take to
+	// be posted to the event webhook.
+	DefaultEventWebhookTimeout = 5 * time.Second
+
+	// DefaultAlertCheckInterval is the default interval at which the alert
+	// conditions are evaluated.
+	DefaultAlertCheckInterval = 30 * time.Second
+
+	// DefaultAlertNodeUnreachableAfter is the default time a data node must
+	// not answer pings for before an alert is raised.
+	DefaultAlertNodeUnreachableAfter = 5 * time.Minute
+
+	// DefaultAlertMaxHHBacklog is the default size of the hinted handoff
+	// queue for a node above which an alert is raised. A value of zero
+	// disables the alert.
+	DefaultAlertMaxHHBacklog = 10 * 1024 * 1024 * 1024
+
+	// DefaultAlertMaxPartialWrites is the default number of partial shard
+	// writes within the partial write window at which an alert is raised. A
+	// value of zero disables the alert.
+	DefaultAlertMaxPartialWrites = 10
+
+	// DefaultAlertPartialWriteWindow is the default window partial shard
+	// writes are counted over.
+	DefaultAlertPartialWriteWindow = 5 * time.Minute
+
+	// DefaultRetentionCheckInterval is the default interval at which the
+	// cluster retention service deletes expired shard groups.
+	DefaultRetentionCheckInterval = 30 * time.Minute
+
+	// DefaultMaxMessageSize is the default size of the largest request a
+	// node accepts from other nodes. It cannot be raised above
+	// tlv.MaxMessageSize.
+	DefaultMaxMessageSize = tlv.MaxMessageSize
+
+	// DefaultMaxConnectionRequests is the default number of requests
+	// received on a single connection that are processed concurrently.
+	// Requests are processed one at a time if it is 1 or less.
+	DefaultMaxConnectionRequests = 8
+
+	// DefaultMaxMuxConnectionRequests is the default number of writes
+	// received on a single multiplexed connection that are processed
+	// concurrently. Further writes are not read until one completes.
+	DefaultMaxMuxConnectionRequests = 64
+
+	// DefaultWriteRetryPolicy is the default policy deciding whether failed
+	// writes to remote nodes are retried, queued in hinted handoff, or
+	// returned to the client.
+	DefaultWriteRetryPolicy = RetryPolicyDefault
+
+	// DefaultReadyMaxHHBacklog is the default size of the hinted handoff
+	// queues above which a node reports itself as not ready on /ready. A
+	// value of zero disables the check.
+	DefaultReadyMaxHHBacklog = 1024 * 1024 * 1024
+
+	// DefaultWritePointCodec is the default codec the points of the writes
+	// sent to other nodes are encoded with, if the node written to supports
+	// it.
+	DefaultWritePointCodec = "binary"
+
+	// DefaultS3Region is the default region shard snapshots are uploaded
+	// to object storage in.
+	DefaultS3Region = "us-east-1"
+
+	// DefaultS3PartSize is the default size of the parts shard snapshots
+	// are uploaded to object storage in.
+	DefaultS3PartSize = 8 * 1024 * 1024
+
+	// DefaultS3Concurrency is the default number of parts of a shard
+	// snapshot uploaded to object storage at once.
+	DefaultS3Concurrency = 4
 )
 
 // Config represents the configuration for the clustering service.
@@ -52,12 +317,188 @@ type Config struct {
 	MaxRemoteWriteConnections int           `toml:"max-remote-write-connections"`
 	ClusterTracing            bool          `toml:"cluster-tracing`
 	WriteTimeout              toml.Duration `toml:"write-timeout"`
+	OwnerWriteTimeout         toml.Duration `toml:"owner-write-timeout"`
 	MaxConcurrentQueries      int           `toml:"max-concurrent-queries"`
 	QueryTimeout              toml.Duration `toml:"query-timeout"`
 	LogQueriesAfter           toml.Duration `toml:"log-queries-after"`
 	MaxSelectPointN           int           `toml:"max-select-point"`
 	MaxSelectSeriesN          int           `toml:"max-select-series"`
 	MaxSelectBucketsN         int           `toml:"max-select-buckets"`
+	HTTPEnabled               bool          `toml:"http-enabled"`
+	HTTPBindAddress           string        `toml:"http-bind-address"`
+	DrainTimeout              toml.Duration `toml:"drain-timeout"`
+	ReadOnly                  bool          `toml:"read-only"`
+	WriteCoalesceWindow       toml.Duration `toml:"write-coalesce-window"`
+	KeepAliveInterval         toml.Duration `toml:"keep-alive-interval"`
+	DNSTTL                    toml.Duration `toml:"dns-ttl"`
+	MaxFutureWrite            toml.Duration `toml:"max-future-write"`
+	MaxPastWrite              toml.Duration `toml:"max-past-write"`
+	RejectOutOfBoundsWrites   bool          `toml:"reject-out-of-bounds-writes"`
+	ErrorOnDroppedPoints      bool          `toml:"error-on-dropped-points"`
+	ShardAssignment           string        `toml:"shard-assignment"`
+	WriteForwardThreshold     float64       `toml:"write-forward-threshold"`
+	IntoWriteConsistency      string        `toml:"into-write-consistency"`
+
+	MaxConcurrentRemoteIterators int           `toml:"max-concurrent-remote-iterators"`
+	MaxRemoteQueryBytes          int64         `toml:"max-remote-query-bytes"`
+	MaxRemoteSeriesN             int           `toml:"max-remote-series"`
+	IteratorStallTimeout         toml.Duration `toml:"iterator-stall-timeout"`
+
+	// MaxQueryNodeConnections sets the MaxNodeConnections option of the
+	// RemoteIteratorClient.
+	MaxQueryNodeConnections int `toml:"max-query-node-connections"`
+
+	// CompressIteratorStreams sets the Compress option of the
+	// RemoteIteratorClient, so that data nodes compress the points they
+	// stream to this node.
+	CompressIteratorStreams bool `toml:"compress-iterator-streams"`
+
+	// ColumnarIteratorStreams sets the Columnar option of the
+	// RemoteIteratorClient, so that data nodes stream points to this node as
+	// columnar record batches.
+	ColumnarIteratorStreams bool `toml:"columnar-iterator-streams"`
+
+	// IteratorPrefetchSize sets the PrefetchSize option of the
+	// RemoteIteratorClient.
+	IteratorPrefetchSize toml.Size `toml:"iterator-prefetch-size"`
+
+	// ReplanLostNodes sets the ReplanLostNodes option of the
+	// RemoteIteratorClient, so that queries read the shards of a node lost
+	// during the query from their other owners rather than fail.
+	ReplanLostNodes bool `toml:"replan-lost-nodes"`
+
+	// DisableAggregatePushdown sets the RemoteIteratorClient option of the
+	// same name, so that aggregates are computed on the querying node only.
+	DisableAggregatePushdown bool `toml:"disable-aggregate-pushdown"`
+
+	// IteratorCacheSize is the number of bytes of remote iterator points
+	// cached by the IteratorCache of the querying node. Nothing is cached
+	// if zero.
+	IteratorCacheSize   toml.Size     `toml:"iterator-cache-size"`
+	IteratorCacheTTL    toml.Duration `toml:"iterator-cache-ttl"`
+	IteratorCacheMinAge toml.Duration `toml:"iterator-cache-min-age"`
+
+	LeaseDuration   toml.Duration `toml:"lease-duration"`
+	LateWriteWindow toml.Duration `toml:"late-write-window"`
+
+	ShardCopyRateLimit     int64 `toml:"shard-copy-rate-limit"`
+	ShardCopyNodeRateLimit int64 `toml:"shard-copy-node-rate-limit"`
+
+	MaxWALBacklog     toml.Size `toml:"max-wal-backlog"`
+	MaxCacheSize      toml.Size `toml:"max-cache-size"`
+	MaxCompactionDebt int       `toml:"max-compaction-debt"`
+
+	MaxConcurrentWritesPerDatabase    int   `toml:"max-concurrent-writes-per-database"`
+	MaxConcurrentIteratorsPerDatabase int   `toml:"max-concurrent-remote-iterators-per-database"`
+	DatabaseBandwidthLimit            int64 `toml:"database-bandwidth-limit"`
+
+	MaxTagsPerPoint      int      `toml:"max-tags-per-point"`
+	MaxFieldValueSize    int      `toml:"max-field-value-size"`
+	BannedMeasurements   []string `toml:"banned-measurements"`
+	BannedTags           []string `toml:"banned-tags"`
+	MaxSeriesPerDatabase int64    `toml:"max-series-per-database"`
+
+	// PartialWritePolicy is how writes are handled when some of their points
+	// fail validation or are rejected by the store, e.g. "reject-all",
+	// unless overridden for their database.
+	PartialWritePolicy string `toml:"partial-write-policy"`
+
+	OrphanShardCheckInterval toml.Duration `toml:"orphan-shard-check-interval"`
+	OrphanShardGracePeriod   toml.Duration `toml:"orphan-shard-grace-period"`
+	OrphanShardAction        string        `toml:"orphan-shard-action"`
+
+	StartupShardCheck  bool `toml:"startup-shard-check"`
+	StartupShardRepair bool `toml:"startup-shard-repair"`
+
+	ShardScrubInterval  toml.Duration `toml:"shard-scrub-interval"`
+	ShardScrubRateLimit int64         `toml:"shard-scrub-rate-limit"`
+	ShardScrubRepair    bool          `toml:"shard-scrub-repair"`
+
+	NodeCheckInterval toml.Duration `toml:"node-check-interval"`
+
+	// EventWebhookURL is the URL the cluster events seen by this node are
+	// posted to as JSON. Events are not posted if empty.
+	EventWebhookURL     string        `toml:"event-webhook-url"`
+	EventWebhookTimeout toml.Duration `toml:"event-webhook-timeout"`
+
+	// AlertWebhookURLs are the URLs alerts raised by this node are posted
+	// to as JSON. Alerts are disabled if empty.
+	AlertWebhookURLs          []string      `toml:"alert-webhook-urls"`
+	AlertCheckInterval        toml.Duration `toml:"alert-check-interval"`
+	AlertNodeUnreachableAfter toml.Duration `toml:"alert-node-unreachable-after"`
+	AlertMaxHHBacklog         toml.Size     `toml:"alert-max-hh-backlog"`
+	AlertMaxPartialWrites     int           `toml:"alert-max-partial-writes"`
+	AlertPartialWriteWindow   toml.Duration `toml:"alert-partial-write-window"`
+
+	RetentionCheckInterval toml.Duration `toml:"retention-check-interval"`
+
+	MaxConnectionRequests    int       `toml:"max-connection-requests"`
+	MaxMuxConnectionRequests int       `toml:"max-mux-connection-requests"`
+	MaxMessageSize           toml.Size `toml:"max-message-size"`
+
+	// AuditLogPath is the file the destructive operations requested by
+	// other nodes are recorded to. Nothing is recorded if empty.
+	AuditLogPath string `toml:"audit-log-path"`
+
+	// AdminTokens authorize the admin RPCs that mutate the cluster, delete
+	// its data or export it, e.g. removing shards or data nodes, executing
+	// DROP statements or backing up shards. Once any are set, those RPCs are
+	// rejected unless they follow a token with the admin role. Nodes send
+	// their first admin token along the admin RPCs they request from each
+	// other, so that the nodes of a cluster must share it.
+	AdminTokens []AdminTokenConfig `toml:"admin-token"`
+
+	ReadyMaxHHBacklog toml.Size `toml:"ready-max-hh-backlog"`
+
+	WriteRetryPolicy string `toml:"write-retry-policy"`
+	MaxWriteRetries  int    `toml:"max-write-retries"`
+
+	WriteHedgeDelay toml.Duration `toml:"write-hedge-delay"`
+
+	// WritePointCodec is the name of the codec the points of writes sent to
+	// other nodes over multiplexed connections are encoded with, e.g.
+	// "columnar". Writes to nodes that do not support it are sent as binary.
+	WritePointCodec string `toml:"write-point-codec"`
+
+	// TLS is the policy inter-node TLS connections are negotiated with.
+	TLS TLSConfig `toml:"tls"`
+
+	// SnapshotS3 is the object store shard snapshots are uploaded to.
+	SnapshotS3 S3Config `toml:"snapshot-s3"`
+
+	// Databases overrides settings for individual databases.
+	Databases []DatabaseConfig `toml:"database"`
+}
+
+// DatabaseConfig overrides the settings of the database with the given name.
+// Settings that are not set are taken from the Config.
+type DatabaseConfig struct {
+	Name               string `toml:"name"`
+	PartialWritePolicy string `toml:"partial-write-policy"`
+}
+
+// AdminTokenConfig grants Role to the clients presenting Token.
+type AdminTokenConfig struct {
+	Token string `toml:"token"`
+	Role  string `toml:"role"`
+}
+
+// String returns the role of t, leaving the token out so that it is not
+// logged, e.g. when the configuration is reloaded.
+func (t AdminTokenConfig) String() string { return t.Role }
+
+// S3Config represents the configuration of an S3-compatible object store.
+// Uploads are disabled unless Bucket is set. If no credentials are set, the
+// AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY environment variables are used.
+type S3Config struct {
+	Endpoint        string    `toml:"endpoint"`
+	Region          string    `toml:"region"`
+	Bucket          string    `toml:"bucket"`
+	Prefix          string    `toml:"prefix"`
+	AccessKeyID     string    `toml:"access-key-id"`
+	SecretAccessKey string    `toml:"secret-access-key"`
+	PartSize        toml.Size `toml:"part-size"`
+	Concurrency     int       `toml:"concurrency"`
 }
 
 // NewConfig returns an instance of Config with defaults.
@@ -69,10 +510,119 @@ func NewConfig() Config {
 		MaxRemoteWriteConnections: DefaultMaxRemoteWriteConnections,
 		ClusterTracing:            DefaultClusterTracing,
 		WriteTimeout:              toml.Duration(DefaultWriteTimeout),
+		OwnerWriteTimeout:         toml.Duration(DefaultOwnerWriteTimeout),
 		MaxConcurrentQueries:      DefaultMaxConcurrentQueries,
 		QueryTimeout:              toml.Duration(influxql.DefaultQueryTimeout),
 		MaxSelectPointN:           DefaultMaxSelectPointN,
 		MaxSelectSeriesN:          DefaultMaxSelectSeriesN,
 		MaxSelectBucketsN:         DefaultMaxSelectBucketsN,
+		HTTPBindAddress:           DefaultHTTPBindAddress,
+		DrainTimeout:              toml.Duration(DefaultDrainTimeout),
+		WriteCoalesceWindow:       toml.Duration(DefaultWriteCoalesceWindow),
+		KeepAliveInterval:         toml.Duration(DefaultKeepAliveInterval),
+		DNSTTL:                    toml.Duration(DefaultDNSTTL),
+		MaxFutureWrite:            toml.Duration(DefaultMaxFutureWrite),
+		MaxPastWrite:              toml.Duration(DefaultMaxPastWrite),
+		ShardAssignment:           DefaultShardAssignment,
+		WriteForwardThreshold:     DefaultWriteForwardThreshold,
+		IntoWriteConsistency:      DefaultIntoWriteConsistency,
+
+		MaxConcurrentRemoteIterators: DefaultMaxConcurrentRemoteIterators,
+		MaxRemoteQueryBytes:          DefaultMaxRemoteQueryBytes,
+		MaxRemoteSeriesN:             DefaultMaxRemoteSeriesN,
+		IteratorStallTimeout:         toml.Duration(DefaultIteratorStallTimeout),
+		MaxQueryNodeConnections:      DefaultMaxQueryNodeConnections,
+
+		IteratorCacheTTL:    toml.Duration(DefaultIteratorCacheTTL),
+		IteratorCacheMinAge: toml.Duration(DefaultIteratorCacheMinAge),
+
+		LeaseDuration:   toml.Duration(DefaultLeaseDuration),
+		LateWriteWindow: toml.Duration(DefaultLateWriteWindow),
+
+		ShardCopyRateLimit:     DefaultShardCopyRateLimit,
+		ShardCopyNodeRateLimit: DefaultShardCopyNodeRateLimit,
+
+		MaxWALBacklog:     DefaultMaxWALBacklog,
+		MaxCacheSize:      DefaultMaxCacheSize,
+		MaxCompactionDebt: DefaultMaxCompactionDebt,
+
+		MaxConcurrentWritesPerDatabase:    DefaultMaxConcurrentWritesPerDatabase,
+		MaxConcurrentIteratorsPerDatabase: DefaultMaxConcurrentIteratorsPerDatabase,
+		DatabaseBandwidthLimit:            DefaultDatabaseBandwidthLimit,
+
+		MaxTagsPerPoint:      DefaultMaxTagsPerPoint,
+		MaxFieldValueSize:    DefaultMaxFieldValueSize,
+		MaxSeriesPerDatabase: DefaultMaxSeriesPerDatabase,
+
+		OrphanShardCheckInterval: toml.Duration(DefaultOrphanShardCheckInterval),
+		OrphanShardGracePeriod:   toml.Duration(DefaultOrphanShardGracePeriod),
+		OrphanShardAction:        DefaultOrphanShardAction,
+
+		StartupShardCheck:  DefaultStartupShardCheck,
+		StartupShardRepair: DefaultStartupShardRepair,
+
+		ShardScrubInterval:  toml.Duration(DefaultShardScrubInterval),
+		ShardScrubRateLimit: DefaultShardScrubRateLimit,
+		ShardScrubRepair:    DefaultShardScrubRepair,
+
+		NodeCheckInterval:   toml.Duration(DefaultNodeCheckInterval),
+		EventWebhookTimeout: toml.Duration(DefaultEventWebhookTimeout),
+
+		AlertCheckInterval:        toml.Duration(DefaultAlertCheckInterval),
+		AlertNodeUnreachableAfter: toml.Duration(DefaultAlertNodeUnreachableAfter),
+		AlertMaxHHBacklog:         DefaultAlertMaxHHBacklog,
+		AlertMaxPartialWrites:     DefaultAlertMaxPartialWrites,
+		AlertPartialWriteWindow:   toml.Duration(DefaultAlertPartialWriteWindow),
+
+		RetentionCheckInterval: toml.Duration(DefaultRetentionCheckInterval),
+
+		MaxConnectionRequests:    DefaultMaxConnectionRequests,
+		MaxMuxConnectionRequests: DefaultMaxMuxConnectionRequests,
+		MaxMessageSize:           DefaultMaxMessageSize,
+
+		ReadyMaxHHBacklog: DefaultReadyMaxHHBacklog,
+
+		WriteRetryPolicy: DefaultWriteRetryPolicy,
+		MaxWriteRetries:  DefaultMaxWriteRetries,
+
+		WriteHedgeDelay: toml.Duration(DefaultWriteHedgeDelay),
+		WritePointCodec: DefaultWritePointCodec,
+
+		SnapshotS3: S3Config{
+			Region:      DefaultS3Region,
+			PartSize:    DefaultS3PartSize,
+			Concurrency: DefaultS3Concurrency,
+		},
+	}
+}
+
+// Validate returns an error if the config is invalid.
+func (c Config) Validate() error {
+	if _, err := NewRetryPolicy(c.WriteRetryPolicy, c.MaxWriteRetries); err != nil {
+		return err
+	}
+	if err := validatePartialWritePolicy(c.PartialWritePolicy); err != nil {
+		return err
+	}
+	if _, ok := rpc.PointCodecByName(c.WritePointCodec); !ok && c.WritePointCodec != "" {
+		return fmt.Errorf("unknown write point codec: %q", c.WritePointCodec)
+	}
+	for _, db := range c.Databases {
+		if err := validatePartialWritePolicy(db.PartialWritePolicy); err != nil {
+			return fmt.Errorf("database %q: %s", db.Name, err)
+		}
+	}
+	for i, t := range c.AdminTokens {
+		if t.Token == "" {
+			return fmt.Errorf("admin token %d: token must not be empty", i)
+		} else if t.Role != RoleAdmin && t.Role != RoleReadOnly {
+			return fmt.Errorf("admin token %d: unknown role: %q", i, t.Role)
+		}
+	}
+	if err := c.TLS.Apply(&tls.Config{}); err != nil {
+		return err
+	} else if c.TLS.Enabled && (c.TLS.Certificate == "" || c.TLS.PrivateKey == "") {
+		return fmt.Errorf("tls certificate and private-key must be set when tls is enabled")
 	}
+	return nil
 }
@@ -1,6 +1,7 @@
 package cluster_test
 
 import (
+	"crypto/tls"
 	"testing"
 	"time"
 
@@ -14,6 +15,103 @@ func TestConfig_Parse(t *testing.T) {
 	if _, err := toml.Decode(`
 shard-writer-timeout = "10s"
 write-timeout = "20s"
+owner-write-timeout = "2s"
+http-enabled = true
+http-bind-address = ":9090"
+drain-timeout = "1m"
+read-only = true
+write-coalesce-window = "5ms"
+keep-alive-interval = "10s"
+dns-ttl = "5s"
+max-future-write = "1h"
+max-past-write = "168h"
+reject-out-of-bounds-writes = true
+error-on-dropped-points = true
+shard-assignment = "jump"
+write-forward-threshold = 0.75
+into-write-consistency = "quorum"
+max-concurrent-remote-iterators = 8
+max-remote-query-bytes = 1048576
+max-remote-series = 1000
+max-query-node-connections = 4
+compress-iterator-streams = true
+columnar-iterator-streams = true
+replan-lost-nodes = true
+iterator-prefetch-size = "1m"
+iterator-stall-timeout = "30s"
+disable-aggregate-pushdown = true
+iterator-cache-size = "64m"
+iterator-cache-ttl = "5m"
+iterator-cache-min-age = "1h"
+lease-duration = "10s"
+late-write-window = "2s"
+shard-copy-rate-limit = 1048576
+shard-copy-node-rate-limit = 4194304
+max-wal-backlog = "512m"
+max-cache-size = "1g"
+max-compaction-debt = 16
+max-concurrent-writes-per-database = 32
+max-concurrent-remote-iterators-per-database = 4
+database-bandwidth-limit = 8388608
+max-tags-per-point = 64
+max-field-value-size = 65536
+banned-measurements = ["debug"]
+banned-tags = ["request_id", "session_id"]
+max-series-per-database = 1000000
+partial-write-policy = "accept-partial"
+orphan-shard-check-interval = "5m"
+orphan-shard-grace-period = "48h"
+orphan-shard-action = "archive"
+startup-shard-check = false
+startup-shard-repair = true
+shard-scrub-interval = "24h"
+shard-scrub-rate-limit = 1048576
+shard-scrub-repair = true
+node-check-interval = "30s"
+event-webhook-url = "http://localhost:8080/events"
+event-webhook-timeout = "2s"
+alert-webhook-urls = ["http://localhost:8080/alerts", "http://localhost:8081/alerts"]
+alert-check-interval = "1m"
+alert-node-unreachable-after = "10m"
+alert-max-hh-backlog = "1g"
+alert-max-partial-writes = 20
+alert-partial-write-window = "1m"
+retention-check-interval = "1h"
+max-connection-requests = 16
+max-mux-connection-requests = 128
+max-message-size = "64m"
+audit-log-path = "/var/log/influxcloud/audit.log"
+ready-max-hh-backlog = "512m"
+write-retry-policy = "strict"
+max-write-retries = 5
+write-hedge-delay = "10ms"
+write-point-codec = "columnar"
+
+[snapshot-s3]
+endpoint = "http://localhost:9000"
+bucket = "backups"
+part-size = "16m"
+concurrency = 2
+
+[tls]
+preset = "fips"
+ciphers = ["TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384"]
+
+[[admin-token]]
+token = "s3cr3t"
+role = "admin"
+
+[[admin-token]]
+token = "monitoring"
+role = "read-only"
+
+[[database]]
+name = "telemetry"
+partial-write-policy = "accept-and-log"
+
+[[database]]
+name = "billing"
+partial-write-policy = "reject-all"
 `, &c); err != nil {
 		t.Fatal(err)
 	}
@@ -23,5 +121,177 @@ write-timeout = "20s"
 		t.Fatalf("unexpected shard-writer timeout: %s", c.ShardWriterTimeout)
 	} else if time.Duration(c.WriteTimeout) != 20*time.Second {
 		t.Fatalf("unexpected write timeout s: %s", c.WriteTimeout)
+	} else if time.Duration(c.OwnerWriteTimeout) != 2*time.Second {
+		t.Fatalf("unexpected owner write timeout: %s", c.OwnerWriteTimeout)
+	} else if !c.HTTPEnabled {
+		t.Fatal("expected http to be enabled")
+	} else if c.HTTPBindAddress != ":9090" {
+		t.Fatalf("unexpected http bind address: %s", c.HTTPBindAddress)
+	} else if time.Duration(c.DrainTimeout) != time.Minute {
+		t.Fatalf("unexpected drain timeout: %s", c.DrainTimeout)
+	} else if !c.ReadOnly {
+		t.Fatal("expected read-only")
+	} else if time.Duration(c.WriteCoalesceWindow) != 5*time.Millisecond {
+		t.Fatalf("unexpected write coalesce window: %s", c.WriteCoalesceWindow)
+	} else if time.Duration(c.KeepAliveInterval) != 10*time.Second {
+		t.Fatalf("unexpected keep-alive interval: %s", c.KeepAliveInterval)
+	} else if time.Duration(c.DNSTTL) != 5*time.Second {
+		t.Fatalf("unexpected dns ttl: %s", c.DNSTTL)
+	} else if time.Duration(c.MaxFutureWrite) != time.Hour {
+		t.Fatalf("unexpected max future write: %s", c.MaxFutureWrite)
+	} else if time.Duration(c.MaxPastWrite) != 7*24*time.Hour {
+		t.Fatalf("unexpected max past write: %s", c.MaxPastWrite)
+	} else if !c.RejectOutOfBoundsWrites {
+		t.Fatal("expected out of bounds writes to be rejected")
+	} else if !c.ErrorOnDroppedPoints {
+		t.Fatal("expected an error on dropped points")
+	} else if c.ShardAssignment != "jump" {
+		t.Fatalf("unexpected shard assignment: %s", c.ShardAssignment)
+	} else if c.WriteForwardThreshold != 0.75 {
+		t.Fatalf("unexpected write forward threshold: %v", c.WriteForwardThreshold)
+	} else if c.IntoWriteConsistency != "quorum" {
+		t.Fatalf("unexpected into write consistency: %s", c.IntoWriteConsistency)
+	} else if c.MaxConcurrentRemoteIterators != 8 {
+		t.Fatalf("unexpected max concurrent remote iterators: %d", c.MaxConcurrentRemoteIterators)
+	} else if c.MaxRemoteQueryBytes != 1048576 {
+		t.Fatalf("unexpected max remote query bytes: %d", c.MaxRemoteQueryBytes)
+	} else if c.MaxRemoteSeriesN != 1000 {
+		t.Fatalf("unexpected max remote series: %d", c.MaxRemoteSeriesN)
+	} else if time.Duration(c.IteratorStallTimeout) != 30*time.Second {
+		t.Fatalf("unexpected iterator stall timeout: %s", c.IteratorStallTimeout)
+	} else if c.MaxQueryNodeConnections != 4 {
+		t.Fatalf("unexpected max query node connections: %d", c.MaxQueryNodeConnections)
+	} else if !c.CompressIteratorStreams {
+		t.Fatal("expected iterator streams to be compressed")
+	} else if !c.ColumnarIteratorStreams {
+		t.Fatal("expected columnar iterator streams")
+	} else if !c.ReplanLostNodes {
+		t.Fatal("expected lost nodes to be re-planned")
+	} else if c.IteratorPrefetchSize != 1<<20 {
+		t.Fatalf("unexpected iterator prefetch size: %d", c.IteratorPrefetchSize)
+	} else if !c.DisableAggregatePushdown {
+		t.Fatal("expected aggregate pushdown to be disabled")
+	} else if c.IteratorCacheSize != 64<<20 {
+		t.Fatalf("unexpected iterator cache size: %d", c.IteratorCacheSize)
+	} else if time.Duration(c.IteratorCacheTTL) != 5*time.Minute {
+		t.Fatalf("unexpected iterator cache ttl: %s", c.IteratorCacheTTL)
+	} else if time.Duration(c.IteratorCacheMinAge) != time.Hour {
+		t.Fatalf("unexpected iterator cache min age: %s", c.IteratorCacheMinAge)
+	} else if time.Duration(c.LeaseDuration) != 10*time.Second {
+		t.Fatalf("unexpected lease duration: %s", c.LeaseDuration)
+	} else if time.Duration(c.LateWriteWindow) != 2*time.Second {
+		t.Fatalf("unexpected late write window: %s", c.LateWriteWindow)
+	} else if c.ShardCopyRateLimit != 1048576 || c.ShardCopyNodeRateLimit != 4194304 {
+		t.Fatalf("unexpected shard copy rate limits: %d, %d", c.ShardCopyRateLimit, c.ShardCopyNodeRateLimit)
+	} else if c.MaxWALBacklog != 512*1024*1024 || c.MaxCacheSize != 1024*1024*1024 || c.MaxCompactionDebt != 16 {
+		t.Fatalf("unexpected load thresholds: %d, %d, %d", c.MaxWALBacklog, c.MaxCacheSize, c.MaxCompactionDebt)
+	} else if c.MaxConcurrentWritesPerDatabase != 32 || c.MaxConcurrentIteratorsPerDatabase != 4 || c.DatabaseBandwidthLimit != 8388608 {
+		t.Fatalf("unexpected database quotas: %d, %d, %d", c.MaxConcurrentWritesPerDatabase, c.MaxConcurrentIteratorsPerDatabase, c.DatabaseBandwidthLimit)
+	} else if c.MaxTagsPerPoint != 64 || c.MaxFieldValueSize != 65536 || c.MaxSeriesPerDatabase != 1000000 {
+		t.Fatalf("unexpected point validation limits: %d, %d, %d", c.MaxTagsPerPoint, c.MaxFieldValueSize, c.MaxSeriesPerDatabase)
+	} else if len(c.BannedMeasurements) != 1 || c.BannedMeasurements[0] != "debug" || len(c.BannedTags) != 2 || c.BannedTags[1] != "session_id" {
+		t.Fatalf("unexpected banned measurements and tags: %v, %v", c.BannedMeasurements, c.BannedTags)
+	} else if time.Duration(c.OrphanShardCheckInterval) != 5*time.Minute || time.Duration(c.OrphanShardGracePeriod) != 48*time.Hour || c.OrphanShardAction != "archive" {
+		t.Fatalf("unexpected orphan shard settings: %s, %s, %s", c.OrphanShardCheckInterval, c.OrphanShardGracePeriod, c.OrphanShardAction)
+	} else if c.StartupShardCheck || !c.StartupShardRepair {
+		t.Fatalf("unexpected startup shard check settings: %v, %v", c.StartupShardCheck, c.StartupShardRepair)
+	} else if time.Duration(c.ShardScrubInterval) != 24*time.Hour || c.ShardScrubRateLimit != 1048576 || !c.ShardScrubRepair {
+		t.Fatalf("unexpected shard scrub settings: %s, %d, %v", c.ShardScrubInterval, c.ShardScrubRateLimit, c.ShardScrubRepair)
+	} else if time.Duration(c.NodeCheckInterval) != 30*time.Second {
+		t.Fatalf("unexpected node check interval: %s", c.NodeCheckInterval)
+	} else if c.EventWebhookURL != "http://localhost:8080/events" || time.Duration(c.EventWebhookTimeout) != 2*time.Second {
+		t.Fatalf("unexpected event webhook: %s, %s", c.EventWebhookURL, c.EventWebhookTimeout)
+	} else if len(c.AlertWebhookURLs) != 2 || c.AlertWebhookURLs[1] != "http://localhost:8081/alerts" {
+		t.Fatalf("unexpected alert webhook urls: %v", c.AlertWebhookURLs)
+	} else if time.Duration(c.AlertCheckInterval) != time.Minute || time.Duration(c.AlertNodeUnreachableAfter) != 10*time.Minute {
+		t.Fatalf("unexpected alert intervals: %s, %s", c.AlertCheckInterval, c.AlertNodeUnreachableAfter)
+	} else if c.AlertMaxHHBacklog != 1024*1024*1024 || c.AlertMaxPartialWrites != 20 || time.Duration(c.AlertPartialWriteWindow) != time.Minute {
+		t.Fatalf("unexpected alert thresholds: %d, %d, %s", c.AlertMaxHHBacklog, c.AlertMaxPartialWrites, c.AlertPartialWriteWindow)
+	} else if time.Duration(c.RetentionCheckInterval) != time.Hour {
+		t.Fatalf("unexpected retention check interval: %s", c.RetentionCheckInterval)
+	} else if c.MaxConnectionRequests != 16 || c.MaxMuxConnectionRequests != 128 {
+		t.Fatalf("unexpected max connection requests: %d, %d", c.MaxConnectionRequests, c.MaxMuxConnectionRequests)
+	} else if c.MaxMessageSize != 64*1024*1024 {
+		t.Fatalf("unexpected max message size: %d", c.MaxMessageSize)
+	} else if c.AuditLogPath != "/var/log/influxcloud/audit.log" {
+		t.Fatalf("unexpected audit log path: %s", c.AuditLogPath)
+	} else if c.ReadyMaxHHBacklog != 512*1024*1024 {
+		t.Fatalf("unexpected ready max hh backlog: %d", c.ReadyMaxHHBacklog)
+	} else if c.WriteRetryPolicy != cluster.RetryPolicyStrict || c.MaxWriteRetries != 5 {
+		t.Fatalf("unexpected write retry policy: %s, %d", c.WriteRetryPolicy, c.MaxWriteRetries)
+	} else if time.Duration(c.WriteHedgeDelay) != 10*time.Millisecond {
+		t.Fatalf("unexpected write hedge delay: %s", c.WriteHedgeDelay)
+	} else if c.WritePointCodec != "columnar" {
+		t.Fatalf("unexpected write point codec: %s", c.WritePointCodec)
+	} else if c.SnapshotS3.Endpoint != "http://localhost:9000" || c.SnapshotS3.Bucket != "backups" {
+		t.Fatalf("unexpected snapshot object store: %+v", c.SnapshotS3)
+	} else if c.SnapshotS3.PartSize != 16*1024*1024 || c.SnapshotS3.Concurrency != 2 {
+		t.Fatalf("unexpected snapshot upload settings: %+v", c.SnapshotS3)
+	} else if c.TLS.Preset != cluster.TLSPresetFIPS || len(c.TLS.Ciphers) != 1 {
+		t.Fatalf("unexpected tls policy: %+v", c.TLS)
+	} else if a := c.AdminTokens; len(a) != 2 || a[0] != (cluster.AdminTokenConfig{Token: "s3cr3t", Role: cluster.RoleAdmin}) || a[1] != (cluster.AdminTokenConfig{Token: "monitoring", Role: cluster.RoleReadOnly}) {
+		t.Fatalf("unexpected admin tokens: %#v", a)
+	} else if c.PartialWritePolicy != cluster.PartialWriteAcceptPartial {
+		t.Fatalf("unexpected partial write policy: %s", c.PartialWritePolicy)
+	} else if m := c.PartialWritePolicies(); len(m) != 2 || m["telemetry"] != cluster.PartialWriteAcceptAndLog || m["billing"] != cluster.PartialWriteRejectAll {
+		t.Fatalf("unexpected database partial write policies: %v", m)
+	} else if err := c.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	c.AdminTokens = append(c.AdminTokens, cluster.AdminTokenConfig{Token: "x", Role: "root"})
+	if err := c.Validate(); err == nil || err.Error() != `admin token 2: unknown role: "root"` {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	c.AdminTokens = c.AdminTokens[:2]
+
+	c.TLS.Enabled = true
+	if err := c.Validate(); err == nil || err.Error() != `tls certificate and private-key must be set when tls is enabled` {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	c.TLS.Enabled = false
+
+	c.Databases = append(c.Databases, cluster.DatabaseConfig{Name: "db0", PartialWritePolicy: "drop"})
+	if err := c.Validate(); err == nil || err.Error() != `database "db0": unknown partial write policy: "drop"` {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+// Ensure the fips TLS preset is applied, and may only be narrowed.
+func TestTLSConfig_Apply(t *testing.T) {
+	var tc tls.Config
+	if err := (cluster.TLSConfig{Preset: cluster.TLSPresetFIPS, Curves: []string{"P384"}}).Apply(&tc); err != nil {
+		t.Fatal(err)
+	} else if tc.MinVersion != tls.VersionTLS12 || tc.MaxVersion != tls.VersionTLS12 {
+		t.Fatalf("unexpected versions: %x-%x", tc.MinVersion, tc.MaxVersion)
+	} else if len(tc.CipherSuites) != 4 || tc.CipherSuites[0] != tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256 {
+		t.Fatalf("unexpected ciphers: %x", tc.CipherSuites)
+	} else if len(tc.CurvePreferences) != 1 || tc.CurvePreferences[0] != tls.CurveP384 {
+		t.Fatalf("unexpected curves: %v", tc.CurvePreferences)
+	}
+
+	tc = tls.Config{}
+	if err := (cluster.TLSConfig{MinVersion: "tls1.3", Curves: []string{"X25519"}}).Apply(&tc); err != nil {
+		t.Fatal(err)
+	} else if tc.MinVersion != tls.VersionTLS13 || tc.MaxVersion != 0 || tc.CipherSuites != nil {
+		t.Fatalf("unexpected policy: %x-%x, %x", tc.MinVersion, tc.MaxVersion, tc.CipherSuites)
+	}
+
+	for _, tt := range []struct {
+		c   cluster.TLSConfig
+		err string
+	}{
+		{cluster.TLSConfig{Preset: "nsa"}, `unknown tls preset: "nsa"`},
+		{cluster.TLSConfig{MinVersion: "ssl3"}, `unknown tls version: "ssl3"`},
+		{cluster.TLSConfig{MinVersion: "tls1.3", MaxVersion: "tls1.2"}, `tls min-version tls1.3 is above max-version tls1.2`},
+		{cluster.TLSConfig{Ciphers: []string{"TLS_NULL"}}, `unknown tls cipher: "TLS_NULL"`},
+		{cluster.TLSConfig{Curves: []string{"P192"}}, `unknown tls curve: "P192"`},
+		{cluster.TLSConfig{Preset: cluster.TLSPresetFIPS, MaxVersion: "tls1.3"}, `tls preset "fips" only allows tls1.2`},
+		{cluster.TLSConfig{Preset: cluster.TLSPresetFIPS, Ciphers: []string{"TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305_SHA256"}}, `tls cipher TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305_SHA256 not allowed by preset "fips"`},
+		{cluster.TLSConfig{Preset: cluster.TLSPresetFIPS, Curves: []string{"X25519"}}, `tls curve X25519 not allowed by preset "fips"`},
+	} {
+		if err := tt.c.Apply(&tls.Config{}); err == nil || err.Error() != tt.err {
+			t.Fatalf("unexpected error for %+v: %v", tt.c, err)
+		}
 	}
 }
@@ -0,0 +1,165 @@
+package cluster
+
+import (
+	"crypto/tls"
+	"encoding"
+	"net"
+	"time"
+
+	"github.com/zhexuany/influxcloud/rpc"
+	"github.com/zhexuany/influxcloud/tlv"
+)
+
+// DefaultDialer is the Dialer the clients of this package connect to other
+// nodes with unless given another one.
+var DefaultDialer = &Dialer{Resolver: DefaultResolver}
+
+// Dialer connects to the cluster service of other nodes. Every connection a
+// node opens to another one is dialed through its Dialer, so that they are
+// all set up the same way.
+type Dialer struct {
+	// Resolver resolves the hostnames of the nodes dialed. Addresses are
+	// dialed as is if nil.
+	Resolver *Resolver
+
+	// TLS is the configuration connections are secured with once the
+	// multiplexing header is written, or nil to dial in plaintext. The
+	// server name defaults to the host of the address dialed.
+	TLS *tls.Config
+
+	// Hello returns the hello message said on the connections dialed, or
+	// nil to not say hello. It is only said once its cluster ID is known, as
+	// the nodes that require a hello refuse one without. Nodes older than
+	// the hello message are dialed again without it.
+	Hello func() *rpc.HelloRequest
+
+	// AdminToken returns the admin token sent on the connections dialed,
+	// authorizing the admin RPCs sent over them, or an empty string to send
+	// none.
+	AdminToken func() string
+}
+
+// longRPCs are the requests answered once they are done, for as long as it
+// takes, so that their responses are read without a deadline.
+var longRPCs = map[byte]bool{
+	tlv.CopyShardRequestMessage:             true,
+	tlv.ExportMetaDataRequestMessage:        true,
+	tlv.ReplaceDataNodeRequestMessage:       true,
+	tlv.RedirectHintedHandoffRequestMessage: true,
+	tlv.UploadShardSnapshotRequestMessage:   true,
+}
+
+// Dial connects to the cluster service of the node at addr within timeout,
+// writes the multiplexing header, completes the TLS handshake, says hello
+// and sends the admin token, if any. A nil Dialer dials addr as is.
+func (d *Dialer) Dial(addr string, timeout time.Duration) (net.Conn, error) {
+	conn, err := d.connect(addr, timeout)
+	if err != nil || d == nil {
+		return conn, err
+	}
+
+	if hello := d.hello(); hello != nil {
+		conn.SetDeadline(time.Now().Add(timeout))
+		if _, err := sayHello(conn, hello); err != nil && isLegacyHelloErr(err) {
+			conn.Close()
+			if conn, err = d.connect(addr, timeout); err != nil {
+				return nil, err
+			}
+		} else if err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+
+	if d.AdminToken != nil {
+		if token := d.AdminToken(); token != "" {
+			conn.SetDeadline(time.Now().Add(timeout))
+			if err := tlv.EncodeTLV(conn, tlv.AdminTokenMessage, &rpc.AdminToken{Token: token}); err != nil {
+				conn.Close()
+				return nil, err
+			}
+		}
+	}
+
+	conn.SetDeadline(time.Time{})
+	return conn, nil
+}
+
+// hello returns the hello message to say, or nil if none is, or if its
+// cluster ID is unknown.
+func (d *Dialer) hello() *rpc.HelloRequest {
+	if d.Hello == nil {
+		return nil
+	} else if hello := d.Hello(); hello != nil && hello.ClusterID != 0 {
+		return hello
+	}
+	return nil
+}
+
+// connect connects to the node at addr like Dial, without saying hello.
+func (d *Dialer) connect(addr string, timeout time.Duration) (net.Conn, error) {
+	var r *Resolver
+	if d != nil {
+		r = d.Resolver
+	}
+	conn, err := r.Dial(addr, timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	conn.SetDeadline(time.Now().Add(timeout))
+	if _, err := conn.Write([]byte{MuxHeader}); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	if d != nil && d.TLS != nil {
+		tc := d.TLS
+		if tc.ServerName == "" {
+			tc = tc.Clone()
+			if tc.ServerName, _, err = net.SplitHostPort(addr); err != nil {
+				tc.ServerName = addr
+			}
+		}
+		tlsConn := tls.Client(conn, tc)
+		if err := tlsConn.Handshake(); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		conn = tlsConn
+	}
+
+	conn.SetDeadline(time.Time{})
+	return conn, nil
+}
+
+// dialRPC sends the request req of type typ to the node at addr over a new
+// connection, and decodes the response into resp. Each step times out after
+// timeout, except reading the response to one of longRPCs.
+func (d *Dialer) dialRPC(addr string, timeout time.Duration, typ byte, req encoding.BinaryMarshaler, resp encoding.BinaryUnmarshaler) error {
+	conn, err := d.Dial(addr, timeout)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	conn.SetDeadline(time.Now().Add(timeout))
+	if err := tlv.EncodeTLV(conn, typ, req); err != nil {
+		return err
+	}
+
+	if longRPCs[typ] {
+		conn.SetReadDeadline(time.Time{})
+	}
+	_, err = tlv.DecodeTLV(conn, resp)
+	return err
+}
+
+// moved returns true if the hostname of addr no longer resolves to the IP
+// of remote. See Resolver.moved.
+func (d *Dialer) moved(addr string, remote net.Addr) bool {
+	if d == nil {
+		return false
+	}
+	return d.Resolver.moved(addr, remote)
+}
@@ -0,0 +1,67 @@
+package cluster
+
+import (
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zhexuany/influxcloud/rpc"
+	"github.com/zhexuany/influxcloud/tlv"
+)
+
+// Ensure requests are sent after the multiplexing header, and that only the
+// responses to long requests are read past the timeout.
+func TestDialer_dialRPC(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	const delay = 200 * time.Millisecond
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func(conn net.Conn) {
+				defer conn.Close()
+
+				header := make([]byte, 1)
+				if _, err := conn.Read(header); err != nil || header[0] != MuxHeader {
+					return
+				}
+
+				typ, _, err := tlv.ReadTLV(conn)
+				if err != nil {
+					return
+				}
+				time.Sleep(delay)
+				switch typ {
+				case tlv.ShardStatusRequestMessage:
+					tlv.EncodeTLV(conn, tlv.ShardStatusResponseMessage, &rpc.ShardStatusResponse{})
+				case tlv.UploadShardSnapshotRequestMessage:
+					tlv.EncodeTLV(conn, tlv.UploadShardSnapshotResponseMessage, &rpc.UploadShardSnapshotResponse{Size: 10})
+				}
+			}(conn)
+		}
+	}()
+
+	d := &Dialer{Resolver: NewResolver(time.Hour)}
+	addr := ln.Addr().String()
+
+	var resp rpc.UploadShardSnapshotResponse
+	if err := d.dialRPC(addr, delay/4, tlv.UploadShardSnapshotRequestMessage, &rpc.UploadShardSnapshotRequest{ShardID: 1}, &resp); err != nil {
+		t.Fatal(err)
+	} else if resp.Size != 10 {
+		t.Fatalf("unexpected size: %d", resp.Size)
+	}
+
+	if err := d.dialRPC(addr, delay/4, tlv.ShardStatusRequestMessage, &rpc.ShardStatusRequest{}, &rpc.ShardStatusResponse{}); err == nil {
+		t.Fatal("expected the response to time out")
+	} else if !strings.Contains(err.Error(), "i/o timeout") {
+		t.Fatalf("unexpected error: %s", err)
+	}
+}
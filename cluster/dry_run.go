@@ -0,0 +1,156 @@
+package cluster
+
+import (
+	"sort"
+	"time"
+
+	"github.com/influxdata/influxdb/models"
+	"github.com/zhexuany/influxcloud"
+)
+
+// DryRun is how a write would be distributed across the cluster, as computed
+// by WritePointsDryRun.
+type DryRun struct {
+	Database         string
+	RetentionPolicy  string
+	ConsistencyLevel models.ConsistencyLevel
+
+	// Shards are the shards the points would be written to, sorted by ID.
+	Shards []DryRunShard
+
+	// Nodes is the number of points each node would be sent, keyed by
+	// node ID.
+	Nodes map[uint64]int
+
+	// ForwardTo is the node the whole write would be forwarded to, or zero
+	// if it would be split into shard writes here.
+	ForwardTo uint64
+
+	// DroppedN is the number of points that would be dropped for being
+	// older than the retention policy. PendingN is the number of points
+	// whose shard group would be created by the write.
+	DroppedN int
+	PendingN int
+}
+
+// DryRunShard is how the points of a write mapped to a shard would be
+// written.
+type DryRunShard struct {
+	ShardID uint64
+	PointN  int
+	Owners  []uint64
+
+	// Required is the number of owners that must acknowledge the write for
+	// it to meet its consistency level.
+	Required int
+
+	// Paused are the owners that writes are paused to, whose points would
+	// be queued in hinted handoff.
+	Paused []uint64
+}
+
+// WritePointsDryRun passes a write through the write middleware, validates
+// its points and maps them to shards, then returns how it would be
+// distributed without writing it. Shard groups are not created; see Plan.
+// Middleware can tell dry runs apart by Write.DryRun.
+func (w *PointsWriter) WritePointsDryRun(database, retentionPolicy string, consistencyLevel models.ConsistencyLevel, points []models.Point) (*DryRun, error) {
+	var result *DryRun
+	fn := w.chain(func(wr *Write) error {
+		var err error
+		result, err = w.dryRun(wr)
+		return err
+	})
+	if err := fn(&Write{
+		RequestID:        NewRequestID(),
+		Deadline:         time.Now().Add(w.WriteTimeout),
+		Database:         database,
+		RetentionPolicy:  retentionPolicy,
+		ConsistencyLevel: consistencyLevel,
+		Points:           points,
+		DryRun:           true,
+	}); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// dryRun returns how wr would be distributed once it has been through the
+// write middleware.
+func (w *PointsWriter) dryRun(wr *Write) (*DryRun, error) {
+	retentionPolicy := wr.RetentionPolicy
+	if retentionPolicy == "" {
+		db := w.MetaClient.Database(wr.Database)
+		if db == nil {
+			return nil, influxcloud.ErrDatabaseNotFound(wr.Database)
+		}
+		retentionPolicy = db.DefaultRetentionPolicy
+	}
+
+	if w.validationEnabled() {
+		if err := w.checkPoints(wr.Database, wr.Points); err != nil {
+			return nil, err
+		}
+	}
+
+	mapping, err := w.Plan(&WritePointsRequest{Database: wr.Database, RetentionPolicy: retentionPolicy, Points: wr.Points})
+	if err != nil {
+		return nil, err
+	}
+
+	result := &DryRun{
+		Database:         wr.Database,
+		RetentionPolicy:  retentionPolicy,
+		ConsistencyLevel: wr.ConsistencyLevel,
+		Nodes:            make(map[uint64]int),
+		DroppedN:         len(mapping.Dropped),
+		PendingN:         len(mapping.Pending),
+	}
+
+	var localID uint64
+	if w.Node != nil {
+		localID = w.Node.ID
+	}
+	if w.Forwarder != nil && w.ForwardThreshold > 0 {
+		if nodeID, ok := forwardTarget(mapping, localID, w.ForwardThreshold, w.replicationPaused); ok {
+			result.ForwardTo = nodeID
+		}
+	}
+
+	for shardID, points := range mapping.Points {
+		sh := DryRunShard{
+			ShardID:  shardID,
+			PointN:   len(points),
+			Owners:   mapping.Owners[shardID],
+			Required: requiredOwners(len(mapping.Owners[shardID]), wr.ConsistencyLevel),
+		}
+		for _, id := range sh.Owners {
+			result.Nodes[id] += len(points)
+			if id != localID && w.replicationPaused(id) {
+				sh.Paused = append(sh.Paused, id)
+			}
+		}
+		result.Shards = append(result.Shards, sh)
+	}
+	sort.Sort(dryRunShards(result.Shards))
+	return result, nil
+}
+
+// requiredOwners returns the number of the n owners of a shard that must
+// acknowledge a write of consistency.
+func requiredOwners(n int, consistency models.ConsistencyLevel) int {
+	switch consistency {
+	case models.ConsistencyLevelAny, models.ConsistencyLevelOne:
+		return 1
+	case models.ConsistencyLevelQuorum:
+		return n/2 + 1
+	default:
+		return n
+	}
+}
+
+// dryRunShards sorts the shards of a dry run by ID.
+type dryRunShards []DryRunShard
+
+func (a dryRunShards) Len() int           { return len(a) }
+func (a dryRunShards) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
+func (a dryRunShards) Less(i, j int) bool { return a[i].ShardID < a[j].ShardID }
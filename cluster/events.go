@@ -0,0 +1,322 @@
+package cluster
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/uber-go/zap"
+	cloudMeta "github.com/zhexuany/influxcloud/meta"
+)
+
+// EventType is the kind of cluster state change an Event reports.
+type EventType string
+
+// Types of the events published on an EventBus.
+const (
+	// EventNodeJoined and EventNodeLeft are published when a data node is
+	// added to or removed from the meta store.
+	EventNodeJoined EventType = "nodeJoined"
+	EventNodeLeft   EventType = "nodeLeft"
+
+	// EventNodeDead is published when a data node stops answering pings,
+	// and EventNodeAlive when it answers again.
+	EventNodeDead  EventType = "nodeDead"
+	EventNodeAlive EventType = "nodeAlive"
+
+	// EventShardCreated is published when a shard is added to the meta
+	// store. EventShardCopied and EventShardDropped are published when a
+	// shard is copied to or dropped from this node.
+	EventShardCreated EventType = "shardCreated"
+	EventShardCopied  EventType = "shardCopied"
+	EventShardDropped EventType = "shardDropped"
+
+	// EventShardCorrupt is published when scrubbing finds a TSM file of a
+	// shard of this node that does not match its checksums.
+	EventShardCorrupt EventType = "shardCorrupt"
+
+	// EventWritePartial is published when a shard write reaches some of
+	// the owners of the shard, but fewer than its consistency level needs.
+	EventWritePartial EventType = "writePartial"
+
+	// EventHHQueueFull is published when the hinted handoff queue of a node
+	// fills up, e.g. by hooking hh.Service.OnQueueFull to the bus.
+	EventHHQueueFull EventType = "hhQueueFull"
+
+	// EventPeerIdentityMismatch is published when a node connecting over
+	// mutual TLS presents a certificate that is not valid for the address
+	// of the node it claims to be, and is refused.
+	EventPeerIdentityMismatch EventType = "peerIdentityMismatch"
+
+	// EventClusterMismatch is published when a node of another cluster
+	// connects, e.g. one pointed at the wrong meta nodes, and is refused.
+	EventClusterMismatch EventType = "clusterMismatch"
+
+	// EventBreakerOpened is published when writes to a node are cut off
+	// after failing repeatedly. Nothing in this package publishes it yet.
+	EventBreakerOpened EventType = "breakerOpened"
+)
+
+// DefaultEventBufferN is the number of events buffered for a subscriber that
+// does not ask for a buffer size.
+const DefaultEventBufferN = 64
+
+// Event is a change of the state of the cluster. NodeID and ShardID are set
+// to the node and shard the event is about, if any.
+type Event struct {
+	Type    EventType `json:"type"`
+	Time    time.Time `json:"time"`
+	NodeID  uint64    `json:"nodeID,omitempty"`
+	ShardID uint64    `json:"shardID,omitempty"`
+	Message string    `json:"message,omitempty"`
+}
+
+// EventBus publishes events to its subscribers. Publishing never blocks:
+// events are dropped for subscribers whose buffer is full, so that a slow
+// subscriber cannot hold up the cluster. A nil EventBus drops every event.
+type EventBus struct {
+	mu   sync.RWMutex
+	subs map[chan Event]struct{}
+
+	dropped int64
+}
+
+// NewEventBus returns a new instance of EventBus.
+func NewEventBus() *EventBus {
+	return &EventBus{subs: make(map[chan Event]struct{})}
+}
+
+// Subscribe returns a channel receiving the events published from now on,
+// buffering up to n of them, and a function ending the subscription. The
+// channel is closed once the subscription ends.
+func (b *EventBus) Subscribe(n int) (<-chan Event, func()) {
+	if n <= 0 {
+		n = DefaultEventBufferN
+	}
+	ch := make(chan Event, n)
+	b.mu.Lock()
+	b.subs[ch] = struct{}{}
+	b.mu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			b.mu.Lock()
+			delete(b.subs, ch)
+			b.mu.Unlock()
+			close(ch)
+		})
+	}
+}
+
+// Publish sends e to every subscriber, setting its time to now if unset.
+func (b *EventBus) Publish(e Event) {
+	if b == nil {
+		return
+	}
+	if e.Time.IsZero() {
+		e.Time = time.Now().UTC()
+	}
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for ch := range b.subs {
+		select {
+		case ch <- e:
+		default:
+			atomic.AddInt64(&b.dropped, 1)
+		}
+	}
+}
+
+// Dropped returns the number of events dropped for subscribers whose buffer
+// was full.
+func (b *EventBus) Dropped() int64 {
+	return atomic.LoadInt64(&b.dropped)
+}
+
+// WebhookSink posts the events of an EventBus to a URL, each as a JSON
+// object in its own request. Events that fail to post are logged and lost.
+type WebhookSink struct {
+	URL    string
+	Client *http.Client
+	Logger zap.Logger
+
+	wg     sync.WaitGroup
+	cancel func()
+}
+
+// NewWebhookSink returns a WebhookSink posting to url, giving up on each
+// request after timeout.
+func NewWebhookSink(url string, timeout time.Duration) *WebhookSink {
+	return &WebhookSink{
+		URL:    url,
+		Client: &http.Client{Timeout: timeout},
+		Logger: zap.New(zap.NullEncoder()),
+	}
+}
+
+// Open starts posting the events published on bus.
+func (w *WebhookSink) Open(bus *EventBus) {
+	events, cancel := bus.Subscribe(0)
+	w.cancel = cancel
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+		for e := range events {
+			if err := w.post(e); err != nil {
+				w.Logger.Info("unable to post event", zap.String("type", string(e.Type)), zap.String("url", w.URL), zap.Error(err))
+			}
+		}
+	}()
+}
+
+// Close stops posting events, once the event being posted is done.
+func (w *WebhookSink) Close() {
+	if w.cancel != nil {
+		w.cancel()
+	}
+	w.wg.Wait()
+}
+
+// post sends e to the webhook.
+func (w *WebhookSink) post(e Event) error {
+	buf, err := json.Marshal(&e)
+	if err != nil {
+		return err
+	}
+	resp, err := w.Client.Post(w.URL, "application/json", bytes.NewReader(buf))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("unexpected status: %s", resp.Status)
+	}
+	return nil
+}
+
+// nodeWatch is the state of the data nodes and shards last seen in the meta
+// store, against which the next check detects changes.
+type nodeWatch struct {
+	interval time.Duration
+
+	mu     sync.Mutex
+	seen   bool            // Unset until the first check, which only publishes dead nodes.
+	nodes  map[uint64]bool // Data nodes, set to whether they are dead.
+	shards map[uint64]bool
+}
+
+// runNodeCheck checks the data nodes and shards in the meta store every
+// interval until the service is closed. If the meta client reports what
+// changed, they are also checked as soon as data nodes or shard groups change.
+func (s *Service) runNodeCheck() {
+	defer s.wg.Done()
+
+	var changes <-chan cloudMeta.Change
+	if mc, ok := s.MetaClient.(changeWatcher); ok {
+		var cancel func()
+		changes, cancel = mc.Watch(0)
+		defer cancel()
+	}
+
+	t := time.NewTicker(s.nodes.interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+		case c, ok := <-changes:
+			if !ok {
+				changes = nil
+				continue
+			}
+			switch c.Type {
+			case cloudMeta.ChangeReset,
+				cloudMeta.ChangeDataNodeAdded, cloudMeta.ChangeDataNodeRemoved, cloudMeta.ChangeDataNodeUpdated,
+				cloudMeta.ChangeShardGroupCreated, cloudMeta.ChangeShardGroupDeleted, cloudMeta.ChangeShardGroupUpdated:
+			default:
+				continue
+			}
+		case <-s.closing:
+			return
+		}
+		if err := s.checkNodes(); err != nil {
+			s.Logger.Info("node check failed", zap.Error(err))
+		}
+	}
+}
+
+// checkNodes publishes the data nodes that joined, left, or stopped or
+// started answering pings, and the shards created, since the last check.
+func (s *Service) checkNodes() error {
+	nodes, err := s.MetaClient.DataNodes()
+	if err != nil {
+		return err
+	}
+	infos, err := s.shardInfos()
+	if err != nil {
+		return err
+	}
+
+	// Nodes are pinged without holding the lock, as they may not answer
+	// before the dial timeout.
+	dead := make(map[uint64]bool, len(nodes))
+	for _, n := range nodes {
+		if s.Node != nil && n.ID == s.Node.ID {
+			dead[n.ID] = false
+			continue
+		}
+		dead[n.ID] = s.pingNode(n.TCPHost) != nil
+	}
+
+	s.nodes.mu.Lock()
+	defer s.nodes.mu.Unlock()
+	for _, n := range nodes {
+		wasDead, ok := s.nodes.nodes[n.ID]
+		if !ok && s.nodes.seen {
+			s.Events.Publish(Event{Type: EventNodeJoined, NodeID: n.ID, Message: n.TCPHost})
+		}
+		if dead[n.ID] && !wasDead {
+			s.Events.Publish(Event{Type: EventNodeDead, NodeID: n.ID, Message: n.TCPHost})
+		} else if !dead[n.ID] && wasDead {
+			s.Events.Publish(Event{Type: EventNodeAlive, NodeID: n.ID, Message: n.TCPHost})
+		}
+	}
+	for id := range s.nodes.nodes {
+		if _, ok := dead[id]; !ok {
+			s.Events.Publish(Event{Type: EventNodeLeft, NodeID: id})
+		}
+	}
+
+	shards := make(map[uint64]bool, len(infos))
+	for _, si := range infos {
+		shards[si.ID] = true
+		if s.nodes.seen && !s.nodes.shards[si.ID] {
+			s.Events.Publish(Event{Type: EventShardCreated, ShardID: si.ID, Message: si.Database + "." + si.Policy})
+		}
+	}
+
+	s.nodes.nodes, s.nodes.shards, s.nodes.seen = dead, shards, true
+	return nil
+}
+
+// pingNode checks that the node at addr answers a ping within the dial
+// timeout.
+func (s *Service) pingNode(addr string) error {
+	timeout := s.dialTimeout
+	if timeout <= 0 {
+		timeout = DefaultDialTimeout
+	}
+	conn, err := s.dialer.Dial(addr, timeout)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	conn.SetDeadline(time.Now().Add(timeout))
+	return ping(conn, timeout)
+}
@@ -0,0 +1,171 @@
+package cluster_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/influxdata/influxdb/services/meta"
+	"github.com/influxdata/influxdb/toml"
+	"github.com/zhexuany/influxcloud"
+	"github.com/zhexuany/influxcloud/cluster"
+)
+
+// Ensure events reach every subscriber, that events are dropped rather than
+// block once a subscriber's buffer is full, and that unsubscribing closes
+// the channel.
+func TestEventBus_Publish(t *testing.T) {
+	bus := cluster.NewEventBus()
+	a, cancelA := bus.Subscribe(1)
+	b, cancelB := bus.Subscribe(2)
+	defer cancelB()
+
+	bus.Publish(cluster.Event{Type: cluster.EventNodeJoined, NodeID: 2})
+	bus.Publish(cluster.Event{Type: cluster.EventNodeLeft, NodeID: 2})
+
+	if e := <-a; e.Type != cluster.EventNodeJoined || e.NodeID != 2 || e.Time.IsZero() {
+		t.Fatalf("unexpected event: %+v", e)
+	} else if n := bus.Dropped(); n != 1 {
+		t.Fatalf("unexpected dropped events: %d", n)
+	}
+	if e := <-b; e.Type != cluster.EventNodeJoined {
+		t.Fatalf("unexpected event: %+v", e)
+	} else if e := <-b; e.Type != cluster.EventNodeLeft {
+		t.Fatalf("unexpected event: %+v", e)
+	}
+
+	cancelA()
+	cancelA()
+	if _, ok := <-a; ok {
+		t.Fatal("expected channel to be closed")
+	}
+	bus.Publish(cluster.Event{Type: cluster.EventShardCreated, ShardID: 10})
+	if e := <-b; e.Type != cluster.EventShardCreated || e.ShardID != 10 {
+		t.Fatalf("unexpected event: %+v", e)
+	}
+}
+
+// Ensure the webhook sink posts each event as JSON.
+func TestWebhookSink(t *testing.T) {
+	posted := make(chan cluster.Event, 1)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var e cluster.Event
+		if err := json.NewDecoder(r.Body).Decode(&e); err != nil {
+			t.Errorf("unable to decode event: %s", err)
+		}
+		posted <- e
+	}))
+	defer ts.Close()
+
+	bus := cluster.NewEventBus()
+	sink := cluster.NewWebhookSink(ts.URL, time.Second)
+	sink.Open(bus)
+	defer sink.Close()
+
+	bus.Publish(cluster.Event{Type: cluster.EventHHQueueFull, NodeID: 3})
+	select {
+	case e := <-posted:
+		if e.Type != cluster.EventHHQueueFull || e.NodeID != 3 {
+			t.Fatalf("unexpected event: %+v", e)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for event")
+	}
+}
+
+// Ensure the service publishes the data nodes that join, stop answering and
+// leave, and the shards created in the meta store.
+func TestService_NodeCheck(t *testing.T) {
+	peer := MustOpenService()
+	defer peer.Close()
+
+	// An address nothing listens on.
+	ln := MustListen("tcp", "127.0.0.1:0")
+	deadAddr := ln.Addr().String()
+	ln.Close()
+
+	var mu sync.Mutex
+	nodes := []meta.NodeInfo{{ID: 1, TCPHost: "127.0.0.1:1"}, {ID: 2, TCPHost: peer.Addr().String()}}
+	var shards []meta.ShardInfo
+
+	// Closed once the second check starts, after the first one saw the
+	// initial state.
+	var checks int
+	checked := make(chan struct{})
+
+	s := NewService()
+	s.Service = cluster.NewService(cluster.Config{
+		DialTimeout:       toml.Duration(time.Second),
+		NodeCheckInterval: toml.Duration(10 * time.Millisecond),
+	})
+	s.Service.Node = &influxcloud.Node{ID: 1}
+	s.Service.MetaClient = &s.MetaClient
+	s.MetaClient.DataNodesFn = func() ([]meta.NodeInfo, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if checks++; checks == 2 {
+			close(checked)
+		}
+		return append([]meta.NodeInfo(nil), nodes...), nil
+	}
+	s.MetaClient.DatabasesFn = func() ([]meta.DatabaseInfo, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		return []meta.DatabaseInfo{{
+			Name: "db0",
+			RetentionPolicies: []meta.RetentionPolicyInfo{{
+				Name:        "rp0",
+				ShardGroups: []meta.ShardGroupInfo{{ID: 1, Shards: append([]meta.ShardInfo(nil), shards...)}},
+			}},
+		}}, nil
+	}
+	events, cancel := s.Events.Subscribe(0)
+	defer cancel()
+
+	s.ln = MustListen("tcp", "127.0.0.1:0")
+	s.Listener = &muxListener{s.ln}
+	if err := s.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	next := func() cluster.Event {
+		select {
+		case e := <-events:
+			return e
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for event")
+		}
+		return cluster.Event{}
+	}
+
+	<-checked
+	mu.Lock()
+	nodes = append(nodes, meta.NodeInfo{ID: 3, TCPHost: deadAddr})
+	shards = append(shards, meta.ShardInfo{ID: 10, Owners: []meta.ShardOwner{{NodeID: 2}}})
+	mu.Unlock()
+
+	// The shard may be seen by the check before the node is.
+	got := make(map[cluster.EventType]cluster.Event)
+	for i := 0; i < 3; i++ {
+		e := next()
+		got[e.Type] = e
+	}
+	if e := got[cluster.EventNodeJoined]; e.NodeID != 3 || e.Message != deadAddr {
+		t.Fatalf("unexpected node joined event: %+v", e)
+	} else if e := got[cluster.EventNodeDead]; e.NodeID != 3 {
+		t.Fatalf("unexpected node dead event: %+v", e)
+	} else if e := got[cluster.EventShardCreated]; e.ShardID != 10 || e.Message != "db0.rp0" {
+		t.Fatalf("unexpected shard created event: %+v", e)
+	}
+
+	mu.Lock()
+	nodes = nodes[:2]
+	mu.Unlock()
+	if e := next(); e.Type != cluster.EventNodeLeft || e.NodeID != 3 {
+		t.Fatalf("unexpected event: %+v", e)
+	}
+}
@@ -0,0 +1,271 @@
+package cluster
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/influxdata/influxdb/influxql"
+	"github.com/influxdata/influxdb/models"
+)
+
+// Pushdown decisions of the calls of a query plan.
+const (
+	// PushdownAggregate means the data nodes compute the partial aggregates
+	// of the call, which are merged by the querying node.
+	PushdownAggregate = "aggregate"
+
+	// PushdownCandidates means the data nodes select the candidate points
+	// of a top() or bottom() call, which the querying node selects among.
+	PushdownCandidates = "candidates"
+
+	// PushdownNone means the call is computed by the querying node, from
+	// the raw points of the data nodes or from the result of another call.
+	PushdownNone = "none"
+)
+
+// QueryPlan is how a SELECT statement would be read across the cluster, as
+// computed by StatementExecutor.Explain.
+type QueryPlan struct {
+	Statement string
+
+	// MinTime and MaxTime are the time range of the statement.
+	MinTime time.Time
+	MaxTime time.Time
+
+	// Shards are the shards selected by the sources and time range of the
+	// statement, sorted by ID.
+	Shards []QueryPlanShard
+
+	// Nodes are the nodes the shards would be read from, sorted by ID.
+	Nodes []QueryPlanNode
+
+	// Calls are the pushdown decisions for the calls of the statement, in
+	// the order they are selected.
+	Calls []QueryPlanCall
+
+	// RemoteStreamN is the estimated number of iterator streams that would
+	// be read from other nodes. Wildcards and regexes are counted as a
+	// single field, as the fields they match are not known before the
+	// statement is executed.
+	RemoteStreamN int
+}
+
+// QueryPlanShard is a shard selected by a query plan.
+type QueryPlanShard struct {
+	ID     uint64
+	Owners []uint64
+
+	// NodeID is the owner the shard would be read from, as chosen by
+	// ShardsByNode. It is zero if the shard has no owners.
+	NodeID uint64
+}
+
+// QueryPlanNode is a node a query plan reads shards from.
+type QueryPlanNode struct {
+	ID       uint64
+	ShardIDs []uint64
+	Local    bool
+
+	// StreamN is the estimated number of iterator streams read from the
+	// node.
+	StreamN int
+}
+
+// QueryPlanCall is the pushdown decision for a call of a query plan.
+type QueryPlanCall struct {
+	Call     string
+	Pushdown string
+}
+
+// Explain returns how stmt would be read across the cluster, without
+// reading it. Sources without a database are read from database, and those
+// without a retention policy from its default retention policy.
+func (e *StatementExecutor) Explain(stmt *influxql.SelectStatement, database string) (*QueryPlan, error) {
+	now := time.Now().UTC()
+	stmt = stmt.Reduce(&influxql.NowValuer{Now: now})
+
+	min, max, err := influxql.TimeRange(stmt.Condition)
+	if err != nil {
+		return nil, err
+	}
+	if max.IsZero() {
+		max = time.Unix(0, influxql.MaxTime).UTC()
+	}
+	if min.IsZero() {
+		min = time.Unix(0, influxql.MinTime).UTC()
+	}
+
+	sources, err := e.explainSources(stmt.Sources, database)
+	if err != nil {
+		return nil, err
+	}
+	shards, err := e.MetaClient.ShardsByTimeRange(sources, min, max)
+	if err != nil {
+		return nil, err
+	}
+
+	var maintenance []uint64
+	if m, ok := e.MetaClient.(maintenanceMetaClient); ok {
+		maintenance = m.MaintenanceNodes()
+	}
+	var localID uint64
+	if e.Node != nil {
+		localID = e.Node.ID
+	}
+
+	plan := &QueryPlan{Statement: stmt.String(), MinTime: min, MaxTime: max}
+	nodes := make(map[uint64]*QueryPlanNode)
+	for _, si := range shards {
+		sh := QueryPlanShard{ID: si.ID, NodeID: queryNode(si, localID, maintenance)}
+		for _, o := range si.Owners {
+			sh.Owners = append(sh.Owners, o.NodeID)
+		}
+		plan.Shards = append(plan.Shards, sh)
+
+		if sh.NodeID == 0 {
+			continue
+		}
+		n := nodes[sh.NodeID]
+		if n == nil {
+			n = &QueryPlanNode{ID: sh.NodeID, Local: sh.NodeID == localID}
+			nodes[sh.NodeID] = n
+		}
+		n.ShardIDs = append(n.ShardIDs, sh.ID)
+	}
+	sort.Sort(queryPlanShards(plan.Shards))
+
+	var disabled bool
+	if e.RemoteIteratorClient != nil {
+		disabled = e.RemoteIteratorClient.DisableAggregatePushdown
+	}
+	streamN := 0
+	for _, call := range stmt.FunctionCalls() {
+		// Calls over other calls read from the inner call.
+		for call != nil {
+			plan.Calls = append(plan.Calls, QueryPlanCall{Call: call.String(), Pushdown: callPushdown(call, disabled)})
+			if len(call.Args) == 0 {
+				break
+			}
+			inner, ok := call.Args[0].(*influxql.Call)
+			if !ok {
+				streamN++
+			}
+			call = inner
+		}
+	}
+	if stmt.IsRawQuery {
+		streamN = 1
+	}
+
+	for _, n := range nodes {
+		sort.Sort(uint64Slice(n.ShardIDs))
+		n.StreamN = streamN
+		if !n.Local {
+			plan.RemoteStreamN += streamN
+		}
+		plan.Nodes = append(plan.Nodes, *n)
+	}
+	sort.Sort(queryPlanNodes(plan.Nodes))
+	return plan, nil
+}
+
+// ExecuteExplain sends the plan of stmt as the result of the statement
+// executed with ctx. The parser has no EXPLAIN statement, so callers choose
+// which statements are explained rather than executed.
+func (e *StatementExecutor) ExecuteExplain(stmt *influxql.SelectStatement, ctx influxql.ExecutionContext) error {
+	plan, err := e.Explain(stmt, ctx.Database)
+	if err != nil {
+		return err
+	}
+	return ctx.Send(&influxql.Result{
+		StatementID: ctx.StatementID,
+		Series:      plan.Rows(),
+	})
+}
+
+// explainSources returns the measurements of sources with their database
+// and retention policy set.
+func (e *StatementExecutor) explainSources(sources influxql.Sources, database string) (influxql.Sources, error) {
+	a := make(influxql.Sources, 0, len(sources))
+	for _, src := range sources {
+		m, ok := src.(*influxql.Measurement)
+		if !ok {
+			return nil, fmt.Errorf("invalid source type: %T", src)
+		}
+		other := *m
+		if other.Database == "" {
+			other.Database = database
+		}
+		if other.RetentionPolicy == "" {
+			di, err := e.MetaClient.Database(other.Database)
+			if err != nil {
+				return nil, err
+			} else if di == nil {
+				return nil, fmt.Errorf("database not found: %s", other.Database)
+			}
+			other.RetentionPolicy = di.DefaultRetentionPolicy
+		}
+		a = append(a, &other)
+	}
+	return a, nil
+}
+
+// callPushdown returns the pushdown decision for call, mirroring
+// RemoteIteratorClient.CreateIterator and Service.createIterator.
+func callPushdown(call *influxql.Call, disabled bool) string {
+	if len(call.Args) == 0 {
+		return PushdownNone
+	} else if _, ok := call.Args[0].(*influxql.Call); ok {
+		return PushdownNone
+	}
+
+	switch call.Name {
+	case "top", "bottom":
+		if disabled {
+			return PushdownNone
+		}
+		return PushdownCandidates
+	case "count", "min", "max", "sum", "first", "last", "mean":
+		// Fields are typed once the statement is executed, so their raw
+		// points are read if the pushdown is disabled.
+		if _, ok := call.Args[0].(*influxql.VarRef); ok && disabled {
+			return PushdownNone
+		}
+		return PushdownAggregate
+	default:
+		return PushdownNone
+	}
+}
+
+// Rows returns the plan as the rows of a query result: the shards, the
+// nodes and the calls of the plan.
+func (p *QueryPlan) Rows() models.Rows {
+	shards := &models.Row{Name: "shards", Columns: []string{"id", "owners", "node_id"}}
+	for _, sh := range p.Shards {
+		shards.Values = append(shards.Values, []interface{}{sh.ID, fmt.Sprint(sh.Owners), sh.NodeID})
+	}
+
+	nodes := &models.Row{Name: "nodes", Columns: []string{"id", "shards", "local", "streams"}}
+	for _, n := range p.Nodes {
+		nodes.Values = append(nodes.Values, []interface{}{n.ID, fmt.Sprint(n.ShardIDs), n.Local, n.StreamN})
+	}
+
+	calls := &models.Row{Name: "calls", Columns: []string{"call", "pushdown"}}
+	for _, c := range p.Calls {
+		calls.Values = append(calls.Values, []interface{}{c.Call, c.Pushdown})
+	}
+	return models.Rows{shards, nodes, calls}
+}
+
+type queryPlanShards []QueryPlanShard
+
+func (a queryPlanShards) Len() int           { return len(a) }
+func (a queryPlanShards) Less(i, j int) bool { return a[i].ID < a[j].ID }
+func (a queryPlanShards) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
+
+type queryPlanNodes []QueryPlanNode
+
+func (a queryPlanNodes) Len() int           { return len(a) }
+func (a queryPlanNodes) Less(i, j int) bool { return a[i].ID < a[j].ID }
+func (a queryPlanNodes) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
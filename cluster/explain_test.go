@@ -0,0 +1,113 @@
+package cluster_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/influxdata/influxdb/influxql"
+	"github.com/influxdata/influxdb/services/meta"
+	"github.com/zhexuany/influxcloud"
+	"github.com/zhexuany/influxcloud/cluster"
+)
+
+// Ensure a plan reads local shards locally, prefers owners not in
+// maintenance for the others, and reports the pushdown of each call.
+func TestStatementExecutor_Explain(t *testing.T) {
+	shards := []meta.ShardInfo{
+		{ID: 12, Owners: []meta.ShardOwner{{NodeID: 2}, {NodeID: 3}}},
+		{ID: 10, Owners: []meta.ShardOwner{{NodeID: 3}, {NodeID: 1}}},
+		{ID: 11, Owners: []meta.ShardOwner{{NodeID: 3}, {NodeID: 2}}},
+	}
+	mc := &explainMetaClient{maintenance: []uint64{3}}
+	mc.ShardsByTimeRangeFn = func(sources influxql.Sources, tmin, tmax time.Time) ([]meta.ShardInfo, error) {
+		if m := sources[0].(*influxql.Measurement); m.Database != "db0" || m.RetentionPolicy != "rp0" {
+			t.Fatalf("unexpected source: %s", m)
+		} else if !tmin.Equal(time.Unix(10, 0)) || tmax.Unix() != time.Unix(0, influxql.MaxTime).Unix() {
+			t.Fatalf("unexpected time range: %s-%s", tmin, tmax)
+		}
+		return shards, nil
+	}
+
+	e := &cluster.StatementExecutor{
+		Node:                 &influxcloud.Node{ID: 1},
+		MetaClient:           mc,
+		RemoteIteratorClient: cluster.NewRemoteIteratorClient(time.Second),
+	}
+
+	stmt := MustParseSelectStatement(`SELECT mean(value), top(value, 2), derivative(max(value)) FROM cpu WHERE time >= 10s GROUP BY time(1m)`)
+	plan, err := e.Explain(stmt, "db0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(plan.Shards, []cluster.QueryPlanShard{
+		{ID: 10, Owners: []uint64{3, 1}, NodeID: 1},
+		{ID: 11, Owners: []uint64{3, 2}, NodeID: 2},
+		{ID: 12, Owners: []uint64{2, 3}, NodeID: 2},
+	}) {
+		t.Fatalf("unexpected shards: %+v", plan.Shards)
+	} else if !reflect.DeepEqual(plan.Nodes, []cluster.QueryPlanNode{
+		{ID: 1, ShardIDs: []uint64{10}, Local: true, StreamN: 3},
+		{ID: 2, ShardIDs: []uint64{11, 12}, StreamN: 3},
+	}) {
+		t.Fatalf("unexpected nodes: %+v", plan.Nodes)
+	} else if plan.RemoteStreamN != 3 {
+		t.Fatalf("unexpected remote streams: %d", plan.RemoteStreamN)
+	} else if !reflect.DeepEqual(plan.Calls, []cluster.QueryPlanCall{
+		{Call: "mean(value)", Pushdown: cluster.PushdownAggregate},
+		{Call: "top(value, 2)", Pushdown: cluster.PushdownCandidates},
+		{Call: "derivative(max(value))", Pushdown: cluster.PushdownNone},
+		{Call: "max(value)", Pushdown: cluster.PushdownAggregate},
+	}) {
+		t.Fatalf("unexpected calls: %+v", plan.Calls)
+	}
+
+	// Fields are read raw if the pushdown is disabled.
+	e.RemoteIteratorClient.DisableAggregatePushdown = true
+	if plan, err := e.Explain(stmt, "db0"); err != nil {
+		t.Fatal(err)
+	} else if plan.Calls[0].Pushdown != cluster.PushdownNone || plan.Calls[1].Pushdown != cluster.PushdownNone {
+		t.Fatalf("unexpected calls: %+v", plan.Calls)
+	}
+
+	// Raw queries read a single stream from each node.
+	if plan, err := e.Explain(MustParseSelectStatement(`SELECT value, host FROM cpu WHERE time >= 10s`), "db0"); err != nil {
+		t.Fatal(err)
+	} else if plan.RemoteStreamN != 1 || len(plan.Calls) != 0 {
+		t.Fatalf("unexpected raw plan: %+v", plan)
+	} else if rows := plan.Rows(); len(rows) != 3 || len(rows[0].Values) != 3 || len(rows[1].Values) != 2 {
+		t.Fatalf("unexpected rows: %+v", rows)
+	}
+}
+
+// MustParseSelectStatement parses s as a SELECT statement. Panic on error.
+func MustParseSelectStatement(s string) *influxql.SelectStatement {
+	stmt, err := influxql.ParseStatement(s)
+	if err != nil {
+		panic(err)
+	}
+	return stmt.(*influxql.SelectStatement)
+}
+
+// explainMetaClient is the meta client of a StatementExecutor explaining
+// statements over db0.
+type explainMetaClient struct {
+	ShardsByTimeRangeFn func(sources influxql.Sources, tmin, tmax time.Time) ([]meta.ShardInfo, error)
+	maintenance         []uint64
+}
+
+func (m *explainMetaClient) DataNodes() (meta.NodeInfos, error) { return nil, nil }
+
+func (m *explainMetaClient) Database(name string) (*meta.DatabaseInfo, error) {
+	if name != "db0" {
+		return nil, nil
+	}
+	return &meta.DatabaseInfo{Name: name, DefaultRetentionPolicy: "rp0"}, nil
+}
+
+func (m *explainMetaClient) ShardsByTimeRange(sources influxql.Sources, tmin, tmax time.Time) ([]meta.ShardInfo, error) {
+	return m.ShardsByTimeRangeFn(sources, tmin, tmax)
+}
+
+func (m *explainMetaClient) MaintenanceNodes() []uint64 { return m.maintenance }
@@ -0,0 +1,242 @@
+package cluster
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"time"
+)
+
+// handler serves the cluster service status endpoints over HTTP.
+type handler struct {
+	s *Service
+}
+
+// newHandler returns a new instance of handler for s.
+func newHandler(s *Service) *handler {
+	return &handler{s: s}
+}
+
+// ServeHTTP responds to HTTP request to the handler.
+func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, "", http.StatusMethodNotAllowed)
+		return
+	}
+
+	switch r.URL.Path {
+	case "/status":
+		h.serveStatus(w, r)
+	case "/hh":
+		h.serveHH(w, r)
+	case "/connections":
+		h.serveConnections(w, r)
+	case "/metrics":
+		h.serveMetrics(w, r)
+	case "/debug/writes":
+		h.serveInflightWrites(w, r)
+	case "/debug/traces":
+		h.serveWriteTraces(w, r)
+	case "/shards/orphans":
+		h.serveOrphanShards(w, r)
+	case "/shards/corrupt":
+		h.serveCorruptShards(w, r)
+	case "/cardinality":
+		h.serveCardinality(w, r)
+	case "/ready":
+		h.serveReady(w, r)
+	case "/healthz":
+		h.serveHealthz(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// Status is the response body of the /status endpoint.
+type Status struct {
+	NodeID uint64       `json:"nodeID"`
+	Peers  []StatusPeer `json:"peers"`
+
+	// ShardCheck is the result of the check of the local shards made when
+	// the node started, if any.
+	ShardCheck *ShardCheck `json:"shardCheck,omitempty"`
+}
+
+// StatusPeer describes a data node and the number of shards it owns.
+type StatusPeer struct {
+	ID      uint64 `json:"id"`
+	Host    string `json:"host"`
+	TCPHost string `json:"tcpHost"`
+	ShardN  int    `json:"shardN"`
+}
+
+// serveStatus returns the local node ID, the shard ownership of each peer,
+// and the result of the startup shard check.
+func (h *handler) serveStatus(w http.ResponseWriter, r *http.Request) {
+	status := Status{ShardCheck: h.s.startupCheck.report()}
+	if h.s.Node != nil {
+		status.NodeID = h.s.Node.ID
+	}
+
+	nodes, err := h.s.MetaClient.DataNodes()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	shards, err := h.s.shardInfos()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	shardN := make(map[uint64]int)
+	for _, si := range shards {
+		for _, id := range si.Owners {
+			shardN[id]++
+		}
+	}
+
+	status.Peers = make([]StatusPeer, 0, len(nodes))
+	for _, n := range nodes {
+		status.Peers = append(status.Peers, StatusPeer{
+			ID:      n.ID,
+			Host:    n.Host,
+			TCPHost: n.TCPHost,
+			ShardN:  shardN[n.ID],
+		})
+	}
+
+	writeJSON(w, status)
+}
+
+// serveHH returns the hinted-handoff queue size in bytes for each node.
+func (h *handler) serveHH(w http.ResponseWriter, r *http.Request) {
+	sizes := make(map[uint64]int64)
+	if h.s.HintedHandoff != nil {
+		sizes = h.s.HintedHandoff.QueueSizes()
+	}
+	writeJSON(w, sizes)
+}
+
+// Connection describes an open inbound connection to the cluster service.
+type Connection struct {
+	Remote string    `json:"remote"`
+	Local  string    `json:"local"`
+	Opened time.Time `json:"opened"`
+}
+
+// serveConnections returns the open inbound connections, oldest first.
+func (h *handler) serveConnections(w http.ResponseWriter, r *http.Request) {
+	h.s.mu.RLock()
+	conns := make([]Connection, 0, len(h.s.conns))
+	for conn, opened := range h.s.conns {
+		conns = append(conns, Connection{
+			Remote: conn.RemoteAddr().String(),
+			Local:  conn.LocalAddr().String(),
+			Opened: opened,
+		})
+	}
+	h.s.mu.RUnlock()
+
+	sort.Sort(connections(conns))
+
+	writeJSON(w, conns)
+}
+
+// serveMetrics returns the cluster metrics in the Prometheus text format.
+func (h *handler) serveMetrics(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	h.s.Metrics.WriteTo(w)
+}
+
+// serveInflightWrites returns the shard writes that have been in flight for
+// longer than the threshold query parameter, e.g. ?threshold=5s.
+func (h *handler) serveInflightWrites(w http.ResponseWriter, r *http.Request) {
+	threshold := DefaultInflightWriteThreshold
+	if s := r.URL.Query().Get("threshold"); s != "" {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		threshold = d
+	}
+
+	writes := []InflightWrite{}
+	if h.s.Writes != nil {
+		writes = h.s.Writes.InflightWrites(threshold)
+	}
+	writeJSON(w, writes)
+}
+
+// serveWriteTraces returns the slowest traced writes, slowest first.
+func (h *handler) serveWriteTraces(w http.ResponseWriter, r *http.Request) {
+	traces := []WriteTrace{}
+	if h.s.Writes != nil {
+		if t := h.s.Writes.WriteTraces(); t != nil {
+			traces = t
+		}
+	}
+	writeJSON(w, traces)
+}
+
+// serveOrphanShards returns the local shards no longer assigned to this node,
+// followed by the orphan shards last deleted or archived.
+func (h *handler) serveOrphanShards(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, h.s.orphans.report())
+}
+
+// serveCorruptShards returns the local shards found corrupt by scrubbing.
+func (h *handler) serveCorruptShards(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, h.s.scrubber.report())
+}
+
+// serveCardinality returns the cluster-wide cardinality of the db query
+// parameter, or of every database if unset. Series are estimated unless
+// exact=true is set.
+func (h *handler) serveCardinality(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	c := NewCardinalityClient(h.s.dialTimeout)
+	c.Dialer = h.s.dialer
+	c.MetaClient = h.s.MetaClient
+	a, err := c.Cardinality(q.Get("db"), q.Get("exact") == "true")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, a)
+}
+
+type connections []Connection
+
+func (a connections) Len() int           { return len(a) }
+func (a connections) Less(i, j int) bool { return a[i].Opened.Before(a[j].Opened) }
+func (a connections) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
+
+// serveReady returns the readiness checks of the node, with a 503 status
+// code if any of them failed so that load balancers and orchestrators stop
+// sending it traffic.
+func (h *handler) serveReady(w http.ResponseWriter, r *http.Request) {
+	readiness := h.s.readiness()
+	if !readiness.Ready {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusServiceUnavailable)
+		json.NewEncoder(w).Encode(readiness)
+		return
+	}
+	writeJSON(w, readiness)
+}
+
+// serveHealthz reports that the node is alive. It succeeds as long as the
+// service can serve HTTP requests.
+func (h *handler) serveHealthz(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, map[string]string{"status": "ok"})
+}
+
+// writeJSON encodes v as the JSON response body.
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
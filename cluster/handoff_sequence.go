@@ -0,0 +1,56 @@
+package cluster
+
+import (
+	"sync"
+
+	"github.com/zhexuany/influxcloud/rpc"
+)
+
+// handoffStream is the stream of hinted handoff writes replayed to a shard
+// from the queue of node queueID on node originNodeID. The writes of a
+// stream are sent in order, each with a higher sequence number.
+type handoffStream struct {
+	originNodeID uint64
+	queueID      uint64
+	shardID      uint64
+}
+
+// handoffStreamOf returns the hinted handoff stream and sequence number of
+// req, or a zero sequence number if req is not a sequenced replay.
+func handoffStreamOf(req *rpc.WriteShardRequest) (handoffStream, uint64) {
+	queueID, seq := req.HandoffSequence()
+	if req.OriginNodeID() == 0 {
+		return handoffStream{}, 0
+	}
+	return handoffStream{originNodeID: req.OriginNodeID(), queueID: queueID, shardID: req.ShardID()}, seq
+}
+
+// handoffSequences are the high-water marks of the hinted handoff streams
+// replayed to a node: the highest sequence number of each stream that was
+// written. They are kept in memory, so a write replayed again after this
+// node restarts is written again, which only overwrites the same points.
+type handoffSequences struct {
+	mu   sync.Mutex
+	seqs map[handoffStream]uint64
+}
+
+// newHandoffSequences returns an empty set of high-water marks.
+func newHandoffSequences() *handoffSequences {
+	return &handoffSequences{seqs: make(map[handoffStream]uint64)}
+}
+
+// applied returns true if the writes of stream up to seq were written.
+func (h *handoffSequences) applied(stream handoffStream, seq uint64) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return seq <= h.seqs[stream]
+}
+
+// advance records that the writes of stream up to seq were written.
+func (h *handoffSequences) advance(stream handoffStream, seq uint64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if seq > h.seqs[stream] {
+		h.seqs[stream] = seq
+	}
+}
@@ -0,0 +1,171 @@
+package cluster
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/uber-go/zap"
+	"github.com/zhexuany/influxcloud/rpc"
+	"github.com/zhexuany/influxcloud/tlv"
+)
+
+// IncompatiblePeerError is returned when a node refuses to talk to another
+// node because their protocol versions are incompatible.
+type IncompatiblePeerError struct {
+	NodeID uint64
+	Reason string
+}
+
+func (e *IncompatiblePeerError) Error() string {
+	return fmt.Sprintf("incompatible peer %d: %s", e.NodeID, e.Reason)
+}
+
+// ClusterMismatchError is returned when a node refuses to talk to a node of
+// another cluster, e.g. one pointed at the meta nodes of the wrong cluster,
+// so that writes and shards never cross from one cluster to the other.
+type ClusterMismatchError struct {
+	NodeID uint64
+	Local  uint64
+	Remote uint64
+}
+
+func (e *ClusterMismatchError) Error() string {
+	if e.Remote == 0 {
+		return fmt.Sprintf("node %d did not tell its cluster, cluster %d required", e.NodeID, e.Local)
+	}
+	return fmt.Sprintf("node %d belongs to cluster %d, not to cluster %d", e.NodeID, e.Remote, e.Local)
+}
+
+// newHelloRequest returns the hello message sent by the node nodeID of the
+// cluster clusterID running the build version.
+func newHelloRequest(nodeID, clusterID uint64, version string) *rpc.HelloRequest {
+	return &rpc.HelloRequest{
+		NodeID:          nodeID,
+		Version:         version,
+		ProtocolVersion: rpc.ProtocolVersion,
+		Features:        rpc.SupportedFeatures,
+		PointCodecs:     rpc.SupportedPointCodecs(),
+		ClusterID:       clusterID,
+	}
+}
+
+// clusterIDOf returns the ID of the cluster of the meta client mc, or zero
+// if mc does not tell it.
+func clusterIDOf(mc interface{}) uint64 {
+	if c, ok := mc.(interface {
+		ClusterID() uint64
+	}); ok {
+		return c.ClusterID()
+	}
+	return 0
+}
+
+// checkClusterID returns a *ClusterMismatchError if the node nodeID belongs
+// to the cluster remote rather than local. Cluster IDs are only compared once
+// both are known, so that nodes that do not send theirs, e.g. older ones, can
+// still connect.
+func checkClusterID(nodeID, local, remote uint64) error {
+	if local != 0 && remote != 0 && local != remote {
+		return &ClusterMismatchError{NodeID: nodeID, Local: local, Remote: remote}
+	}
+	return nil
+}
+
+// checkProtocolVersion returns an error if a peer speaking version of the
+// cluster protocol cannot be talked to.
+func checkProtocolVersion(nodeID uint64, version uint32) error {
+	if version < rpc.MinProtocolVersion {
+		return &IncompatiblePeerError{
+			NodeID: nodeID,
+			Reason: fmt.Sprintf("protocol version %d is older than the minimum supported version %d", version, rpc.MinProtocolVersion),
+		}
+	}
+	return nil
+}
+
+// helloRequest returns the hello message the service says on the connections
+// it dials.
+func (s *Service) helloRequest() *rpc.HelloRequest {
+	var nodeID uint64
+	if s.Node != nil {
+		nodeID = s.Node.ID
+	}
+	return newHelloRequest(nodeID, clusterIDOf(s.MetaClient), s.Version)
+}
+
+// processHelloRequest answers the hello message of a connecting node with the
+// features and point codecs both nodes support, and returns the features. Nodes with an incompatible
+// protocol version, of another or an unknown cluster once the local one is
+// known, or whose mutual TLS certificate does not match the node they claim
+// to be, are told why and the connection is closed.
+func (s *Service) processHelloRequest(conn net.Conn) (rpc.Feature, error) {
+	var req rpc.HelloRequest
+	if err := s.decodeRequest(conn, &req); err != nil {
+		return 0, err
+	}
+
+	resp := rpc.HelloResponse{
+		Version:         s.Version,
+		ProtocolVersion: rpc.ProtocolVersion,
+		Features:        req.Features & rpc.SupportedFeatures,
+		PointCodecs:     rpc.CommonPointCodecs(req.PointCodecs),
+		ClusterID:       clusterIDOf(s.MetaClient),
+	}
+	if s.Node != nil {
+		resp.NodeID = s.Node.ID
+	}
+
+	refused := checkProtocolVersion(req.NodeID, req.ProtocolVersion)
+	if refused == nil {
+		// Once the local cluster is known, connecting nodes must tell theirs.
+		if resp.ClusterID != 0 && req.ClusterID == 0 {
+			refused = &ClusterMismatchError{NodeID: req.NodeID, Local: resp.ClusterID}
+		} else {
+			refused = checkClusterID(req.NodeID, resp.ClusterID, req.ClusterID)
+		}
+		if refused != nil {
+			s.Events.Publish(Event{Type: EventClusterMismatch, NodeID: req.NodeID, Message: fmt.Sprintf("%s from %s", refused, conn.RemoteAddr())})
+		}
+	}
+	if refused == nil {
+		refused = s.verifyPeerIdentity(conn, req.NodeID)
+	}
+	if refused != nil {
+		resp.Err = refused.Error()
+		resp.Features, resp.PointCodecs = 0, nil
+	} else if req.Version != s.Version {
+		s.Logger.Warn("version skew with remote node",
+			zap.Uint64("nodeID", req.NodeID),
+			zap.Stringer("peer", conn.RemoteAddr()),
+			zap.String("localVersion", s.Version),
+			zap.String("remoteVersion", req.Version),
+		)
+	}
+
+	if err := tlv.EncodeTLV(conn, tlv.HelloResponseMessage, &resp); err != nil {
+		return 0, err
+	}
+	return resp.Features, refused
+}
+
+// sayHello sends req on conn, after the multiplexing header has been written,
+// and returns the remote node's answer. An *IncompatiblePeerError is returned
+// if either node refuses the other, and a *ClusterMismatchError if the remote
+// node belongs to another cluster.
+func sayHello(conn net.Conn, req *rpc.HelloRequest) (*rpc.HelloResponse, error) {
+	if err := tlv.EncodeTLV(conn, tlv.HelloRequestMessage, req); err != nil {
+		return nil, err
+	}
+
+	var resp rpc.HelloResponse
+	if _, err := tlv.DecodeTLV(conn, &resp); err != nil {
+		return nil, err
+	} else if resp.Err != "" {
+		return nil, &IncompatiblePeerError{NodeID: resp.NodeID, Reason: resp.Err}
+	} else if err := checkProtocolVersion(resp.NodeID, resp.ProtocolVersion); err != nil {
+		return nil, err
+	} else if err := checkClusterID(resp.NodeID, req.ClusterID, resp.ClusterID); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
@@ -0,0 +1,108 @@
+package cluster
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// DefaultInflightWriteThreshold is the default minimum age of the writes
+// reported on the /debug/writes endpoint.
+const DefaultInflightWriteThreshold = time.Second
+
+// InflightWrite describes a write to a shard that has not yet reached its
+// required consistency level.
+type InflightWrite struct {
+	RequestID string        `json:"requestID"`
+	ShardID   uint64        `json:"shardID"`
+	Owners    []uint64      `json:"owners"`
+	Required  int           `json:"required"`
+	Acked     []uint64      `json:"acked"`
+	Failed    []uint64      `json:"failed"`
+	Started   time.Time     `json:"started"`
+	Elapsed   time.Duration `json:"elapsed"`
+}
+
+// inflightWrite is the live state of a single writeToShard call.
+type inflightWrite struct {
+	mu sync.Mutex
+	InflightWrite
+}
+
+// ack records the result of the write to nodeID.
+func (w *inflightWrite) ack(nodeID uint64, err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if err != nil {
+		w.Failed = append(w.Failed, nodeID)
+	} else {
+		w.Acked = append(w.Acked, nodeID)
+	}
+}
+
+// snapshot returns a copy of the write's state as of now.
+func (w *inflightWrite) snapshot(now time.Time) InflightWrite {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	other := w.InflightWrite
+	other.Acked = append([]uint64(nil), w.Acked...)
+	other.Failed = append([]uint64(nil), w.Failed...)
+	other.Elapsed = now.Sub(w.Started)
+	return other
+}
+
+// inflightWrites is the set of writes in progress on a PointsWriter.
+type inflightWrites struct {
+	mu     sync.Mutex
+	writes map[*inflightWrite]struct{}
+}
+
+// add registers a new write and returns it.
+func (a *inflightWrites) add(requestID string, shardID uint64, owners []uint64, required int) *inflightWrite {
+	w := &inflightWrite{InflightWrite: InflightWrite{
+		RequestID: requestID,
+		ShardID:   shardID,
+		Owners:    owners,
+		Required:  required,
+		Started:   time.Now(),
+	}}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.writes == nil {
+		a.writes = make(map[*inflightWrite]struct{})
+	}
+	a.writes[w] = struct{}{}
+	return w
+}
+
+// remove unregisters a completed write.
+func (a *inflightWrites) remove(w *inflightWrite) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	delete(a.writes, w)
+}
+
+// olderThan returns the writes that started more than threshold ago, oldest first.
+func (a *inflightWrites) olderThan(threshold time.Duration) []InflightWrite {
+	now := time.Now()
+
+	a.mu.Lock()
+	writes := make([]InflightWrite, 0, len(a.writes))
+	for w := range a.writes {
+		if s := w.snapshot(now); s.Elapsed >= threshold {
+			writes = append(writes, s)
+		}
+	}
+	a.mu.Unlock()
+
+	sort.Sort(inflightWritesByAge(writes))
+	return writes
+}
+
+type inflightWritesByAge []InflightWrite
+
+func (a inflightWritesByAge) Len() int           { return len(a) }
+func (a inflightWritesByAge) Less(i, j int) bool { return a[i].Started.Before(a[j].Started) }
+func (a inflightWritesByAge) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
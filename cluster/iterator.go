@@ -58,6 +58,7 @@ func (ric *remoteIteratorCreator) createNodeIterator(id uint64, shardIDs uint64S
 		req := rpc.CreateIteratorRequest{}
 		req.ShardIDs = []uint64(shardIDs)
 		req.Opt = opt
+		req.RequestID = NewRequestID()
 
 		if err := tlv.EncodeTLV(conn, tlv.CreateIteratorRequestMessage, &req); err != nil {
 
@@ -127,7 +128,12 @@ func (mic *metaIteratorCreator) ExpandSources(sources influxql.Sources) (influxq
 }
 
 type NodeDialer struct {
-	timeout    time.Duration
+	timeout time.Duration
+
+	// Dialer connects to the nodes dialed. Addresses are dialed as is if
+	// nil.
+	Dialer *Dialer
+
 	MetaClient interface {
 		DataNode(id uint64) (*meta.NodeInfo, error)
 	}
@@ -139,7 +145,7 @@ func (nd *NodeDialer) DialNode(id uint64) (net.Conn, error) {
 		return nil, err
 	}
 
-	conn, err := net.DialTimeout("tcp", node.TCPHost, nd.timeout)
+	conn, err := nd.Dialer.Dial(node.TCPHost, nd.timeout)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,288 @@
+package cluster
+
+import (
+	"bytes"
+	"container/list"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/influxdata/influxdb/influxql"
+	"github.com/influxdata/influxdb/models"
+	"github.com/zhexuany/influxcloud/rpc"
+)
+
+// Statistics of an IteratorCache.
+const (
+	statIteratorCacheHits      = "hits"
+	statIteratorCacheMisses    = "misses"
+	statIteratorCacheEvictions = "evictions"
+	statIteratorCacheEntries   = "entries"
+	statIteratorCacheSize      = "size"
+)
+
+// IteratorCache is an LRU cache of the points streamed by remote iterators
+// over time ranges that have passed, so that dashboards refreshing the same
+// historical panels do not read them from the data nodes again. Iterators
+// are identified by their node, shards, type and options, which hold the
+// time range and everything else the statement they were planned from
+// selects.
+//
+// Entries are invalidated when a shard they read is copied or dropped, or
+// written by the PointsWriter within their time range. Writes made through
+// other nodes are not seen, so entries also expire after TTL.
+type IteratorCache struct {
+	// MaxSize is the number of bytes of encoded points cached.
+	MaxSize int64
+
+	// TTL is the time an entry is cached for.
+	TTL time.Duration
+
+	// MinAge is the time before now the range of an iterator must end by
+	// for its points to be cached, so that points still being written to
+	// the range are not.
+	MinAge time.Duration
+
+	mu      sync.Mutex
+	lru     *list.List // Most recently used first.
+	entries map[string]*list.Element
+	shards  map[uint64]map[string]struct{} // The keys of the entries reading each shard.
+	size    int64
+
+	hits, misses, evictions int64
+
+	cancel func()
+	now    func() time.Time
+}
+
+// iteratorCacheEntry is the encoded points of a remote iterator, as streamed
+// with encoding.
+type iteratorCacheEntry struct {
+	key                string
+	shardIDs           []uint64
+	startTime, endTime int64
+	encoding           string
+	data               []byte
+	expires            time.Time
+}
+
+// NewIteratorCache returns an IteratorCache configured by c.
+func NewIteratorCache(c Config) *IteratorCache {
+	return &IteratorCache{
+		MaxSize: int64(c.IteratorCacheSize),
+		TTL:     time.Duration(c.IteratorCacheTTL),
+		MinAge:  time.Duration(c.IteratorCacheMinAge),
+		lru:     list.New(),
+		entries: make(map[string]*list.Element),
+		shards:  make(map[uint64]map[string]struct{}),
+		now:     time.Now,
+	}
+}
+
+// Open starts invalidating the shards copied or dropped on bus.
+func (c *IteratorCache) Open(bus *EventBus) {
+	events, cancel := bus.Subscribe(0)
+	c.cancel = cancel
+	go func() {
+		for e := range events {
+			switch e.Type {
+			case EventShardCopied, EventShardDropped:
+				c.Invalidate(e.ShardID)
+			}
+		}
+	}()
+}
+
+// Close stops invalidating shards.
+func (c *IteratorCache) Close() {
+	if c.cancel != nil {
+		c.cancel()
+	}
+}
+
+// key returns the key of the iterator of type typ over shardIDs of node
+// nodeID, and false if its points are not cached because its time range has
+// not passed. A nil cache caches nothing.
+func (c *IteratorCache) key(nodeID uint64, shardIDs []uint64, typ influxql.DataType, opt influxql.IteratorOptions) (string, bool) {
+	if c == nil || c.MaxSize <= 0 {
+		return "", false
+	} else if opt.EndTime >= c.now().Add(-c.MinAge).UnixNano() {
+		return "", false
+	}
+
+	ids := append([]uint64(nil), shardIDs...)
+	sort.Sort(uint64Slice(ids))
+	buf, err := (&rpc.CreateIteratorRequest{ShardIDs: ids, Opt: opt}).MarshalBinary()
+	if err != nil {
+		return "", false
+	}
+	return fmt.Sprintf("%d\x00%d\x00%s", nodeID, typ, buf), true
+}
+
+// get returns the encoded points cached for key, and their encoding.
+func (c *IteratorCache) get(key string) ([]byte, string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[key]
+	if ok && c.now().After(elem.Value.(*iteratorCacheEntry).expires) {
+		c.remove(elem)
+		ok = false
+	}
+	if !ok {
+		c.misses++
+		return nil, "", false
+	}
+	c.hits++
+	c.lru.MoveToFront(elem)
+	e := elem.Value.(*iteratorCacheEntry)
+	return e.data, e.encoding, true
+}
+
+// put caches the points of the iterator with opt over shardIDs, encoded as
+// encoding, evicting the least recently used entries to make room for them.
+func (c *IteratorCache) put(key string, shardIDs []uint64, opt influxql.IteratorOptions, encoding string, data []byte) {
+	if int64(len(data)) > c.MaxSize {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.entries[key]; ok {
+		c.remove(elem)
+	}
+	for c.size+int64(len(data)) > c.MaxSize {
+		c.remove(c.lru.Back())
+		c.evictions++
+	}
+
+	e := &iteratorCacheEntry{
+		key:       key,
+		shardIDs:  append([]uint64(nil), shardIDs...),
+		startTime: opt.StartTime,
+		endTime:   opt.EndTime,
+		encoding:  encoding,
+		data:      data,
+		expires:   c.now().Add(c.TTL),
+	}
+	c.entries[key] = c.lru.PushFront(e)
+	c.size += int64(len(data))
+	for _, id := range shardIDs {
+		keys := c.shards[id]
+		if keys == nil {
+			keys = make(map[string]struct{})
+			c.shards[id] = keys
+		}
+		keys[key] = struct{}{}
+	}
+}
+
+// remove removes the entry of elem.
+func (c *IteratorCache) remove(elem *list.Element) {
+	e := c.lru.Remove(elem).(*iteratorCacheEntry)
+	delete(c.entries, e.key)
+	c.size -= int64(len(e.data))
+	for _, id := range e.shardIDs {
+		if keys := c.shards[id]; keys != nil {
+			delete(keys, e.key)
+			if len(keys) == 0 {
+				delete(c.shards, id)
+			}
+		}
+	}
+}
+
+// Invalidate removes the entries reading the shard with the given ID.
+func (c *IteratorCache) Invalidate(shardID uint64) {
+	c.invalidate(shardID, influxql.MinTime, influxql.MaxTime)
+}
+
+// invalidate removes the entries reading the shard with the given ID whose
+// time range overlaps min to max. A nil cache has nothing to remove.
+func (c *IteratorCache) invalidate(shardID uint64, min, max int64) {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key := range c.shards[shardID] {
+		elem := c.entries[key]
+		if e := elem.Value.(*iteratorCacheEntry); e.startTime <= max && e.endTime >= min {
+			c.remove(elem)
+		}
+	}
+}
+
+// invalidatePoints removes the entries reading the shard with the given ID
+// whose time range holds any of points.
+func (c *IteratorCache) invalidatePoints(shardID uint64, points []models.Point) {
+	if c == nil || len(points) == 0 {
+		return
+	}
+
+	min, max := points[0].UnixNano(), points[0].UnixNano()
+	for _, p := range points[1:] {
+		if t := p.UnixNano(); t < min {
+			min = t
+		} else if t > max {
+			max = t
+		}
+	}
+	c.invalidate(shardID, min, max)
+}
+
+// Statistics returns the hits, misses and evictions of the cache, and the
+// number and size of its entries.
+func (c *IteratorCache) Statistics(tags map[string]string) []models.Statistic {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return []models.Statistic{{
+		Name: "iteratorCache",
+		Tags: tags,
+		Values: map[string]interface{}{
+			statIteratorCacheHits:      c.hits,
+			statIteratorCacheMisses:    c.misses,
+			statIteratorCacheEvictions: c.evictions,
+			statIteratorCacheEntries:   int64(c.lru.Len()),
+			statIteratorCacheSize:      c.size,
+		},
+	}}
+}
+
+// cachingReader copies the encoded points read from a remote iterator, and
+// caches them once the stream ends successfully. Streams larger than the
+// cache are not cached.
+type cachingReader struct {
+	r        io.ReadCloser
+	cache    *IteratorCache
+	key      string
+	shardIDs []uint64
+	opt      influxql.IteratorOptions
+	encoding string
+
+	buf  bytes.Buffer
+	skip bool
+}
+
+// Read reads from the remote iterator.
+func (r *cachingReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	if !r.skip {
+		r.buf.Write(p[:n])
+		r.skip = int64(r.buf.Len()) > r.cache.MaxSize
+	}
+	if err == io.EOF && !r.skip {
+		r.cache.put(r.key, r.shardIDs, r.opt, r.encoding, r.buf.Bytes())
+		r.skip = true
+	}
+	return n, err
+}
+
+// Close closes the remote iterator.
+func (r *cachingReader) Close() error {
+	return r.r.Close()
+}
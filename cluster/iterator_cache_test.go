@@ -0,0 +1,120 @@
+package cluster_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/influxdb/influxql"
+	"github.com/influxdata/influxdb/toml"
+	"github.com/zhexuany/influxcloud/cluster"
+)
+
+// Ensure the points of remote iterators over past time ranges are cached
+// until their shards are invalidated, and those of current ranges are not.
+func TestRemoteIteratorClient_CreateIterator_Cache(t *testing.T) {
+	store := MustOpenIteratorStore()
+	defer store.Close()
+	s := MustOpenIteratorService(cluster.Config{}, store)
+
+	c := cluster.NewRemoteIteratorClient(time.Second)
+	c.MetaClient = &metaClient{host: s.Addr().String()}
+	c.Cache = cluster.NewIteratorCache(cluster.Config{
+		IteratorCacheSize: 1 << 20,
+		IteratorCacheTTL:  toml.Duration(time.Minute),
+	})
+
+	opt := newIteratorOptions()
+	opt.EndTime = time.Unix(10, 0).UnixNano()
+
+	// count returns the points of an iterator over shards 10 and 11.
+	count := func(opt influxql.IteratorOptions) int {
+		itr, err := c.CreateIterator(1, []uint64{11, 10}, influxql.Float, opt)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer itr.Close()
+
+		var n int
+		for {
+			p, err := itr.(influxql.FloatIterator).Next()
+			if err != nil {
+				t.Fatal(err)
+			} else if p == nil {
+				return n
+			}
+			n++
+		}
+	}
+	if n := count(opt); n != 8 {
+		t.Fatalf("unexpected points: %d", n)
+	}
+
+	// The points are read from the cache once the node is gone.
+	s.Close()
+	if n := count(opt); n != 8 {
+		t.Fatalf("unexpected cached points: %d", n)
+	}
+	values := c.Cache.Statistics(nil)[0].Values
+	if values["hits"] != int64(1) || values["misses"] != int64(1) || values["entries"] != int64(1) {
+		t.Fatalf("unexpected statistics: %v", values)
+	}
+
+	// Invalidated entries and current ranges are read from the node.
+	c.Cache.Invalidate(10)
+	if _, err := c.CreateIterator(1, []uint64{10, 11}, influxql.Float, opt); err == nil {
+		t.Fatal("expected error reading invalidated entry")
+	}
+	opt.EndTime = influxql.MaxTime
+	if _, err := c.CreateIterator(1, []uint64{10, 11}, influxql.Float, opt); err == nil {
+		t.Fatal("expected error reading current range")
+	}
+}
+
+// Ensure cached points are read with the encoding they were streamed with.
+func TestRemoteIteratorClient_CreateIterator_CacheColumnar(t *testing.T) {
+	store := MustOpenIteratorStore()
+	defer store.Close()
+	s := MustOpenIteratorService(cluster.Config{}, store)
+
+	c := cluster.NewRemoteIteratorClient(time.Second)
+	c.MetaClient = &metaClient{host: s.Addr().String()}
+	c.Columnar = true
+	c.Cache = cluster.NewIteratorCache(cluster.Config{
+		IteratorCacheSize: 1 << 20,
+		IteratorCacheTTL:  toml.Duration(time.Minute),
+	})
+
+	opt := newIteratorOptions()
+	opt.EndTime = time.Unix(10, 0).UnixNano()
+
+	// values returns the values of the points of an iterator over shards
+	// 10 and 11.
+	values := func() []float64 {
+		itr, err := c.CreateIterator(1, []uint64{10, 11}, influxql.Float, opt)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer itr.Close()
+
+		var a []float64
+		for {
+			p, err := itr.(influxql.FloatIterator).Next()
+			if err != nil {
+				t.Fatal(err)
+			} else if p == nil {
+				return a
+			}
+			a = append(a, p.Value)
+		}
+	}
+	if a := values(); len(a) != 8 {
+		t.Fatalf("unexpected points: %v", a)
+	}
+
+	// The columnar stream is cached as is, whatever the client asks for later.
+	s.Close()
+	c.Columnar = false
+	if a := values(); len(a) != 8 || a[0] != 1 {
+		t.Fatalf("unexpected cached points: %v", a)
+	}
+}
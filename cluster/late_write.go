@@ -0,0 +1,87 @@
+package cluster
+
+import (
+	"sync"
+	"time"
+
+	"github.com/zhexuany/influxcloud/rpc"
+)
+
+// lateWriteKey identifies the write of a batch to one of its shard's owners.
+// The encoded points of a batch are shared by the writes to every owner, and
+// by the retries of each write, so they identify the batch.
+type lateWriteKey struct {
+	nodeID uint64
+	points *rpc.EncodedPoints
+}
+
+// lateWrites tracks the remote writes in progress, so that a write which
+// succeeds after it timed out can be told apart from one that failed.
+type lateWrites struct {
+	mu sync.Mutex
+	m  map[lateWriteKey]chan struct{}
+}
+
+// track starts tracking the write of points to nodeID. The returned channel
+// is closed if the write is reported to have succeeded late.
+func (l *lateWrites) track(nodeID uint64, points *rpc.EncodedPoints) <-chan struct{} {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.m == nil {
+		l.m = make(map[lateWriteKey]chan struct{})
+	}
+	ch := make(chan struct{})
+	l.m[lateWriteKey{nodeID, points}] = ch
+	return ch
+}
+
+// untrack stops tracking the write of points to nodeID.
+func (l *lateWrites) untrack(nodeID uint64, points *rpc.EncodedPoints) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.m, lateWriteKey{nodeID, points})
+}
+
+// succeeded closes the channel of the write of points to nodeID, if tracked.
+func (l *lateWrites) succeeded(nodeID uint64, points *rpc.EncodedPoints) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	key := lateWriteKey{nodeID, points}
+	if ch, ok := l.m[key]; ok {
+		close(ch)
+		delete(l.m, key)
+	}
+}
+
+// LateWriteSucceeded reports that a write of points to nodeID, which the
+// ShardWriter returned ErrTimeout for, has since succeeded. If the write is
+// still waiting to be queued in hinted handoff, it is not queued.
+func (w *PointsWriter) LateWriteSucceeded(nodeID uint64, points *rpc.EncodedPoints) {
+	w.lateWrites.succeeded(nodeID, points)
+}
+
+// awaitLateWrite returns true if a remote write that failed with err has
+// since been reported to have succeeded through late. Writes that timed out
+// are waited on for up to LateWriteWindow.
+func (w *PointsWriter) awaitLateWrite(err error, late <-chan struct{}) bool {
+	select {
+	case <-late:
+		return true
+	default:
+	}
+	if err != ErrTimeout || w.LateWriteWindow <= 0 {
+		return false
+	}
+
+	timer := time.NewTimer(w.LateWriteWindow)
+	defer timer.Stop()
+
+	select {
+	case <-late:
+		return true
+	case <-timer.C:
+		return false
+	case <-w.closing:
+		return false
+	}
+}
@@ -0,0 +1,106 @@
+package cluster
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"time"
+
+	"github.com/influxdata/influxdb/services/meta"
+	"github.com/zhexuany/influxcloud/rpc"
+	"github.com/zhexuany/influxcloud/tlv"
+)
+
+// ErrNoLeaseAuthority is returned if no data node could be asked for a lease.
+var ErrNoLeaseAuthority = errors.New("no data node available to grant leases")
+
+// processAcquireLeaseRequest acquires the requested lease for the requesting
+// node if it is free, expired or already held by that node.
+func (s *Service) processAcquireLeaseRequest(conn net.Conn) error {
+	var req rpc.AcquireLeaseRequest
+	if err := tlv.DecodeLV(conn, &req); err != nil {
+		return err
+	}
+
+	// The lease is returned whether or not it was acquired; the requesting
+	// node holds it if it is the owner.
+	l, _ := s.leases.Acquire(req.Name, req.NodeID)
+
+	return tlv.EncodeTLV(conn, tlv.AcquireLeaseResponseMessage, &rpc.AcquireLeaseResponse{
+		Owner:      l.Owner,
+		Expiration: l.Expiration,
+	})
+}
+
+// LeaseClient acquires leases from the data nodes, e.g. so that each
+// continuous query is run by only one data node per interval. The data node
+// with the lowest ID that can be reached grants the leases, and the next node
+// takes over if it dies. A lease held by a node that dies can be acquired by
+// another node once it expires.
+//
+// Leases are not strongly consistent: during a failover, or if nodes disagree
+// on which node is reachable, two nodes may briefly hold the same lease. Work
+// done under a lease should tolerate being repeated.
+type LeaseClient struct {
+	timeout time.Duration
+
+	// Dialer connects to the data nodes.
+	Dialer *Dialer
+
+	// NodeID is the ID of the node leases are acquired for.
+	NodeID uint64
+
+	MetaClient interface {
+		DataNodes() ([]meta.NodeInfo, error)
+	}
+}
+
+// NewLeaseClient returns a new instance of LeaseClient acquiring leases for
+// the node nodeID.
+func NewLeaseClient(nodeID uint64, timeout time.Duration) *LeaseClient {
+	return &LeaseClient{
+		timeout: timeout,
+		Dialer:  DefaultDialer,
+		NodeID:  nodeID,
+	}
+}
+
+// AcquireLease acquires the lease name, or renews it if already held by this
+// node. An error is returned if another node holds the lease.
+func (c *LeaseClient) AcquireLease(name string) (*meta.Lease, error) {
+	nodes, err := c.MetaClient.DataNodes()
+	if err != nil {
+		return nil, err
+	}
+	sort.Sort(meta.NodeInfos(nodes))
+
+	for _, n := range nodes {
+		resp, err := c.acquireLease(n.TCPHost, name)
+		if err != nil {
+			// Ask the next node if this one cannot be reached.
+			continue
+		} else if resp.Err != "" {
+			return nil, errors.New(resp.Err)
+		}
+
+		l := &meta.Lease{Name: name, Owner: resp.Owner, Expiration: resp.Expiration}
+		if l.Owner != c.NodeID {
+			return l, fmt.Errorf("lease %s held by node %d", name, l.Owner)
+		}
+		return l, nil
+	}
+	return nil, ErrNoLeaseAuthority
+}
+
+// acquireLease requests the lease name from the node at addr.
+func (c *LeaseClient) acquireLease(addr, name string) (*rpc.AcquireLeaseResponse, error) {
+	var resp rpc.AcquireLeaseResponse
+	if err := c.Dialer.dialRPC(addr, c.timeout, tlv.AcquireLeaseRequestMessage, &rpc.AcquireLeaseRequest{
+		Name:   name,
+		NodeID: c.NodeID,
+	}, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
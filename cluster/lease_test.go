@@ -0,0 +1,73 @@
+package cluster_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/influxdb/services/meta"
+	"github.com/influxdata/influxdb/toml"
+	"github.com/zhexuany/influxcloud/cluster"
+)
+
+// Ensure leases are granted by the lowest reachable data node, and can be
+// acquired by another node once they expire or the granting node dies.
+func TestLeaseClient_AcquireLease(t *testing.T) {
+	c := cluster.Config{LeaseDuration: toml.Duration(100 * time.Millisecond)}
+	s0, s1 := MustOpenLeaseService(c), MustOpenLeaseService(c)
+	defer s1.Close()
+
+	dataNodes := &ServiceMetaClient{DataNodesFn: func() ([]meta.NodeInfo, error) {
+		return []meta.NodeInfo{
+			{ID: 2, TCPHost: s1.Addr().String()},
+			{ID: 1, TCPHost: s0.Addr().String()},
+		}, nil
+	}}
+	c0, c1 := cluster.NewLeaseClient(1, time.Second), cluster.NewLeaseClient(2, time.Second)
+	c0.MetaClient, c1.MetaClient = dataNodes, dataNodes
+
+	if l, err := c0.AcquireLease("cq"); err != nil {
+		t.Fatal(err)
+	} else if l.Owner != 1 {
+		t.Fatalf("unexpected owner: %d", l.Owner)
+	}
+	if l, err := c1.AcquireLease("cq"); err == nil {
+		t.Fatal("expected lease to be held by another node")
+	} else if l.Owner != 1 {
+		t.Fatalf("unexpected owner: %d", l.Owner)
+	}
+
+	// The lease can be renewed by its owner, and acquired by another node
+	// once it expires.
+	if _, err := c0.AcquireLease("cq"); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(150 * time.Millisecond)
+	if _, err := c1.AcquireLease("cq"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Leases are granted by the next node once the lowest node dies.
+	s0.Close()
+	if l, err := c1.AcquireLease("cq"); err != nil {
+		t.Fatal(err)
+	} else if l.Owner != 2 {
+		t.Fatalf("unexpected owner: %d", l.Owner)
+	}
+	if _, err := c0.AcquireLease("cq"); err == nil {
+		t.Fatal("expected lease to be held by another node")
+	}
+}
+
+// MustOpenLeaseService returns a new, open service with config c. Panic on
+// error.
+func MustOpenLeaseService(c cluster.Config) *Service {
+	s := NewService()
+	s.Service = cluster.NewService(c)
+	s.Service.MetaClient = &s.MetaClient
+	s.ln = MustListen("tcp", "127.0.0.1:0")
+	s.Listener = &muxListener{s.ln}
+	if err := s.Open(); err != nil {
+		panic(err)
+	}
+	return s
+}
@@ -0,0 +1,102 @@
+package cluster
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/influxdata/influxdb/models"
+	"github.com/zhexuany/influxcloud/rpc"
+)
+
+// loadSampleInterval is how long a sample of the load on the local store is
+// used for. Sampling reads the statistics of every shard, so it is not done
+// for every write.
+const loadSampleInterval = time.Second
+
+// loadLimits are the thresholds on the load of the local store above which a
+// node rejects writes. A threshold of zero is not enforced.
+type loadLimits struct {
+	walBytes       int64
+	cacheBytes     int64
+	compactionDebt int64
+}
+
+// enabled returns true if any threshold is enforced.
+func (l loadLimits) enabled() bool {
+	return l.walBytes > 0 || l.cacheBytes > 0 || l.compactionDebt > 0
+}
+
+// exceeded returns the threshold load exceeds, or an empty string if none.
+func (l loadLimits) exceeded(load rpc.NodeLoad) string {
+	switch {
+	case l.walBytes > 0 && load.WALBytes > l.walBytes:
+		return fmt.Sprintf("wal backlog of %d bytes exceeds %d", load.WALBytes, l.walBytes)
+	case l.cacheBytes > 0 && load.CacheBytes > l.cacheBytes:
+		return fmt.Sprintf("cache size of %d bytes exceeds %d", load.CacheBytes, l.cacheBytes)
+	case l.compactionDebt > 0 && load.CompactionDebt > l.compactionDebt:
+		return fmt.Sprintf("compaction debt of %d exceeds %d", load.CompactionDebt, l.compactionDebt)
+	default:
+		return ""
+	}
+}
+
+// nodeLoad returns the load on the local store, sampled at most once per
+// loadSampleInterval.
+func (s *Service) nodeLoad() rpc.NodeLoad {
+	if s.ShardStore == nil {
+		return rpc.NodeLoad{}
+	}
+
+	s.loadMu.Lock()
+	defer s.loadMu.Unlock()
+	if !s.loadAt.IsZero() && time.Since(s.loadAt) < loadSampleInterval {
+		return s.load
+	}
+
+	var load rpc.NodeLoad
+	for _, id := range s.ShardStore.ShardIDs() {
+		if sh := s.ShardStore.Shard(id); sh != nil {
+			addShardLoad(&load, sh.Statistics(nil))
+		}
+	}
+	load.Overloaded = s.loadLimits.exceeded(load)
+
+	s.load, s.loadAt = load, time.Now()
+	return load
+}
+
+// addShardLoad adds the load of a shard, as reported by its statistics, to
+// load.
+func addShardLoad(load *rpc.NodeLoad, stats []models.Statistic) {
+	for _, stat := range stats {
+		switch stat.Name {
+		case "tsm1_wal":
+			load.WALBytes += statInt(stat.Values["oldSegmentsDiskBytes"]) + statInt(stat.Values["currentSegmentDiskBytes"])
+		case "tsm1_cache":
+			load.CacheBytes += statInt(stat.Values["memBytes"])
+			load.CompactionDebt += statInt(stat.Values["snapshotCount"])
+		case "tsm1_engine":
+			for k, v := range stat.Values {
+				if strings.HasSuffix(k, "CompactionsActive") {
+					load.CompactionDebt += statInt(v)
+				}
+			}
+		}
+	}
+}
+
+// statInt returns the integer value of a statistic, or zero if it is not an
+// integer.
+func statInt(v interface{}) int64 {
+	switch v := v.(type) {
+	case int64:
+		return v
+	case int:
+		return int64(v)
+	case uint64:
+		return int64(v)
+	default:
+		return 0
+	}
+}
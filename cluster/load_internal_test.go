@@ -0,0 +1,62 @@
+package cluster
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxdb/tsdb"
+	"github.com/zhexuany/influxcloud/rpc"
+)
+
+// Ensure the load of a shard is read from its WAL, cache and engine statistics.
+func TestAddShardLoad(t *testing.T) {
+	var load rpc.NodeLoad
+	for i := 0; i < 2; i++ {
+		addShardLoad(&load, []models.Statistic{
+			{Name: "shard", Values: map[string]interface{}{"diskBytes": int64(1000)}},
+			{Name: "tsm1_wal", Values: map[string]interface{}{"oldSegmentsDiskBytes": int64(30), "currentSegmentDiskBytes": int64(10)}},
+			{Name: "tsm1_cache", Values: map[string]interface{}{"memBytes": int64(50), "snapshotCount": int64(1)}},
+			{Name: "tsm1_engine", Values: map[string]interface{}{"cacheCompactionsActive": int64(1), "tsmLevel1CompactionsActive": int64(2), "tsmLevel1Compactions": int64(9)}},
+		})
+	}
+
+	if exp := (rpc.NodeLoad{WALBytes: 80, CacheBytes: 100, CompactionDebt: 8}); load != exp {
+		t.Fatalf("got %+v, expected %+v", load, exp)
+	}
+
+	limits := loadLimits{cacheBytes: 100, compactionDebt: 4}
+	if reason := limits.exceeded(load); reason != "compaction debt of 8 exceeds 4" {
+		t.Fatalf("unexpected reason: %q", reason)
+	} else if reason := (loadLimits{}).exceeded(load); reason != "" {
+		t.Fatalf("unexpected reason: %q", reason)
+	}
+}
+
+// Ensure shard writes are rejected as overloaded while the local store is
+// above a load threshold.
+func TestService_WriteShard_Overloaded(t *testing.T) {
+	s := NewService(Config{MaxCacheSize: 100})
+	s.ShardStore = emptyShardStore{}
+	s.load = rpc.NodeLoad{CacheBytes: 200, Overloaded: s.loadLimits.exceeded(rpc.NodeLoad{CacheBytes: 200})}
+	s.loadAt = time.Now()
+
+	var req rpc.WriteShardRequest
+	req.SetShardID(1)
+	buf, err := req.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.serveWriteShard(buf); err == nil {
+		t.Fatal("expected error")
+	} else if e, ok := err.(*rpc.WriteShardError); !ok || e.Code != rpc.CodeOverloaded || !e.Retryable() {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+// emptyShardStore is a shard store without shards.
+type emptyShardStore struct{}
+
+func (emptyShardStore) ShardIDs() []uint64          { return nil }
+func (emptyShardStore) Shard(id uint64) *tsdb.Shard { return nil }
@@ -0,0 +1,76 @@
+package cluster
+
+import (
+	"net"
+
+	"github.com/influxdata/influxdb/services/meta"
+	"github.com/uber-go/zap"
+	"github.com/zhexuany/influxcloud/rpc"
+	"github.com/zhexuany/influxcloud/tlv"
+)
+
+// maintenanceMetaClient is implemented by meta clients that know which data
+// nodes are in maintenance mode.
+type maintenanceMetaClient interface {
+	MaintenanceNodes() []uint64
+}
+
+// processSetMaintenanceRequest puts a data node into maintenance mode, or
+// takes it out of it, in the meta store.
+func (s *Service) processSetMaintenanceRequest(conn net.Conn) error {
+	var req rpc.SetMaintenanceRequest
+	if err := tlv.DecodeLV(conn, &req); err != nil {
+		return err
+	}
+
+	var resp rpc.SetMaintenanceResponse
+	if n, err := s.dataNodeByTCPHost(req.TCPHost); err != nil {
+		resp.Err = err.Error()
+	} else if err := s.MetaClient.SetDataNodeMaintenance(n.ID, req.Maintenance); err != nil {
+		resp.Err = err.Error()
+	} else if req.Maintenance {
+		s.Logger.Info("data node entered maintenance", zap.Uint64("nodeID", n.ID))
+	} else {
+		s.Logger.Info("data node left maintenance", zap.Uint64("nodeID", n.ID))
+	}
+
+	return tlv.EncodeTLV(conn, tlv.SetMaintenanceResponseMessage, &resp)
+}
+
+// QueryOwners returns the IDs of the owners a query may read a shard from,
+// in order of preference. Owners in maintenance mode come last, so that they
+// are only read from if no other owner can be.
+func QueryOwners(owners []meta.ShardOwner, maintenance []uint64) []uint64 {
+	down := make(map[uint64]bool, len(maintenance))
+	for _, id := range maintenance {
+		down[id] = true
+	}
+
+	ids := make([]uint64, 0, len(owners))
+	for _, o := range owners {
+		if !down[o.NodeID] {
+			ids = append(ids, o.NodeID)
+		}
+	}
+	for _, o := range owners {
+		if down[o.NodeID] {
+			ids = append(ids, o.NodeID)
+		}
+	}
+	return ids
+}
+
+// inMaintenance returns true if the meta client reports nodeID to be in
+// maintenance mode.
+func (w *PointsWriter) inMaintenance(nodeID uint64) bool {
+	m, ok := w.MetaClient.(maintenanceMetaClient)
+	if !ok {
+		return false
+	}
+	for _, id := range m.MaintenanceNodes() {
+		if id == nodeID {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,83 @@
+package cluster_test
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxdb/services/meta"
+	"github.com/zhexuany/influxcloud"
+	"github.com/zhexuany/influxcloud/cluster"
+)
+
+// Ensure writes to a node in maintenance are queued in hinted handoff without
+// being sent to it.
+func TestPointsWriter_WritePoints_Maintenance(t *testing.T) {
+	var mu sync.Mutex
+	var sent, queued []uint64
+
+	c := cluster.NewPointsWriter()
+	c.MetaClient = &maintenanceMetaClient{
+		PointsWriterMetaClient: NewPointsWriterMetaClient(),
+		maintenance:            []uint64{2},
+	}
+	c.ShardWriter = &fakeShardWriter{
+		ShardWriteFn: func(shardID, nodeID uint64, points []models.Point) error {
+			mu.Lock()
+			defer mu.Unlock()
+			sent = append(sent, nodeID)
+			return nil
+		},
+	}
+	c.HintedHandoff = &fakeHintedHandoff{
+		ShardWriteFn: func(shardID, nodeID uint64, points []models.Point) error {
+			mu.Lock()
+			defer mu.Unlock()
+			queued = append(queued, nodeID)
+			return nil
+		},
+	}
+	c.TSDBStore = &fakeStore{
+		WriteFn: func(shardID uint64, points []models.Point) error { return nil },
+	}
+	c.Node = &influxcloud.Node{ID: 1}
+	c.Open()
+	defer c.Close()
+
+	pr := &cluster.WritePointsRequest{Database: "mydb", RetentionPolicy: "myrp"}
+	pr.AddPoint("cpu", 1.0, time.Now(), nil)
+
+	// The write to node 2 fails at consistency ALL but is queued.
+	if err := c.WritePoints(pr.Database, pr.RetentionPolicy, models.ConsistencyLevelAll, pr.Points); err == nil {
+		t.Fatal("expected an error at consistency all")
+	}
+	c.Drain()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if !reflect.DeepEqual(sent, []uint64{3}) {
+		t.Fatalf("unexpected remote writes: %v", sent)
+	} else if !reflect.DeepEqual(queued, []uint64{2}) {
+		t.Fatalf("unexpected hinted handoff writes: %v", queued)
+	}
+}
+
+// Ensure queries prefer the owners of a shard that are not in maintenance.
+func TestQueryOwners(t *testing.T) {
+	owners := []meta.ShardOwner{{NodeID: 1}, {NodeID: 2}, {NodeID: 3}}
+	if got := cluster.QueryOwners(owners, nil); !reflect.DeepEqual(got, []uint64{1, 2, 3}) {
+		t.Fatalf("unexpected owners: %v", got)
+	} else if got := cluster.QueryOwners(owners, []uint64{1, 4}); !reflect.DeepEqual(got, []uint64{2, 3, 1}) {
+		t.Fatalf("unexpected owners: %v", got)
+	}
+}
+
+// maintenanceMetaClient reports the nodes in maintenance mode.
+type maintenanceMetaClient struct {
+	*PointsWriterMetaClient
+	maintenance []uint64
+}
+
+func (m *maintenanceMetaClient) MaintenanceNodes() []uint64 { return m.maintenance }
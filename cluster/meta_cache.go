@@ -0,0 +1,103 @@
+package cluster
+
+import (
+	"sync"
+	"time"
+
+	"github.com/influxdata/influxdb/services/meta"
+)
+
+// DefaultMetaCacheTTL is the default time retention policies and shard
+// groups looked up when mapping points to shards are cached for.
+const DefaultMetaCacheTTL = 10 * time.Second
+
+// metaCache caches the retention policy and the shard groups already used for
+// writes to each retention policy, so that MapShards does not need to ask the
+// meta client for them on every write. The zero value is an empty cache.
+//
+// Entries expire after a TTL and are removed when the meta data changes: only
+// those of the database changed if the meta client reports which, otherwise
+// all of them. Each removal starts a new generation; entries looked up in an
+// earlier generation are not stored, as they may predate the change.
+type metaCache struct {
+	mu      sync.Mutex
+	gen     uint64
+	entries map[metaCacheKey]*metaCacheEntry
+}
+
+type metaCacheKey struct {
+	database, policy string
+}
+
+type metaCacheEntry struct {
+	rp      *meta.RetentionPolicyInfo
+	groups  sgList
+	expires time.Time
+}
+
+// get returns the cached retention policy and shard groups of database and
+// policy, and the current generation of the cache. rp is nil if the entry is
+// missing or has expired.
+func (c *metaCache) get(database, policy string) (rp *meta.RetentionPolicyInfo, groups sgList, gen uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := metaCacheKey{database, policy}
+	e := c.entries[key]
+	if e == nil {
+		return nil, nil, c.gen
+	} else if time.Now().After(e.expires) {
+		delete(c.entries, key)
+		return nil, nil, c.gen
+	}
+	return e.rp, append(sgList(nil), e.groups...), c.gen
+}
+
+// put caches rp and groups for ttl if the cache is still at generation gen.
+// The shard groups of an existing entry are kept.
+func (c *metaCache) put(database, policy string, rp *meta.RetentionPolicyInfo, groups sgList, ttl time.Duration, gen uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if gen != c.gen {
+		return
+	}
+
+	key := metaCacheKey{database, policy}
+	e := c.entries[key]
+	if e == nil {
+		if c.entries == nil {
+			c.entries = make(map[metaCacheKey]*metaCacheEntry)
+		}
+		e = &metaCacheEntry{rp: rp, expires: time.Now().Add(ttl)}
+		c.entries[key] = e
+	}
+groups:
+	for _, sg := range groups {
+		for _, other := range e.groups {
+			if other.ID == sg.ID {
+				continue groups
+			}
+		}
+		e.groups = e.groups.Append(sg)
+	}
+}
+
+// clear removes all entries and starts a new generation.
+func (c *metaCache) clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.gen++
+	c.entries = nil
+}
+
+// invalidate removes the entries of database and starts a new generation.
+func (c *metaCache) invalidate(database string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.gen++
+	for key := range c.entries {
+		if key.database == database {
+			delete(c.entries, key)
+		}
+	}
+}
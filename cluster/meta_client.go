@@ -0,0 +1,359 @@
+package cluster
+
+import (
+	"errors"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/influxdata/influxdb/services/meta"
+	"github.com/influxdata/influxdb/tsdb"
+	cloudMeta "github.com/zhexuany/influxcloud/meta"
+)
+
+// MetaClient is the meta store the cluster layer runs against: the
+// PointsWriter maps points to shards with it, and the Service and the
+// ShardWriter answer and send the requests of other nodes with it. Embedders
+// may implement it on top of another store than the meta nodes, and
+// MemoryMetaClient implements it in memory.
+//
+// A MetaClient may also have the following methods, which are used if it
+// does:
+//
+//	ClusterID() uint64                               nodes of other clusters are refused
+//	MaintenanceNodes() []uint64                      nodes in maintenance are not written to or queried
+//	ShardGroupAssignment(id uint64) string           points are assigned to shards with the mode returned
+//	Ping(checkAllMetaServers bool) error             the readiness check pings the meta store
+//	WaitForDataChanged() chan struct{}               cached meta data is dropped once changed
+//	Watch(n int) (<-chan cloudMeta.Change, func())   cached meta data is dropped once changed, and nodes are checked
+type MetaClient interface {
+	Database(name string) *meta.DatabaseInfo
+	Databases() ([]meta.DatabaseInfo, error)
+	RetentionPolicy(database, policy string) (*meta.RetentionPolicyInfo, error)
+	CreateShardGroup(database, policy string, timestamp time.Time) (*meta.ShardGroupInfo, error)
+	TruncateShardGroups(t time.Time) error
+
+	ShardOwner(shardID uint64) (database, policy string, si meta.ShardInfo)
+	AddShardOwner(shardID, nodeID uint64) error
+	RemoveShardOwner(shardID, nodeID uint64) error
+
+	DataNode(id uint64) (*meta.NodeInfo, error)
+	DataNodes() ([]meta.NodeInfo, error)
+	UpdateDataNode(id uint64, host, tcpHost string) error
+	DeleteDataNode(id uint64) error
+	ReplaceDataNode(oldID, newID uint64) error
+	SetDataNodeMaintenance(id uint64, maintenance bool) error
+
+	Users() []meta.UserInfo
+}
+
+// MemoryMetaClient is a MetaClient keeping the meta data in memory, e.g. for
+// tests or for nodes running without meta nodes. Each change is applied to a
+// copy of the data like the meta store applies commands, advancing the index
+// of the data and closing the channel returned by WaitForDataChanged.
+//
+// It also implements the optional methods of MetaClient other than Ping and
+// Watch, and the MetaStore of the Service.
+type MemoryMetaClient struct {
+	// ShardAssignment is the mode points are assigned to the shards of the
+	// shard groups created with, or cloudMeta.ShardAssignmentHash if empty.
+	ShardAssignment string
+
+	mu      sync.RWMutex
+	data    *cloudMeta.Data
+	changed chan struct{}
+}
+
+// NewMemoryMetaClient returns a MemoryMetaClient without data nodes or
+// databases, of a new cluster.
+func NewMemoryMetaClient() *MemoryMetaClient {
+	return &MemoryMetaClient{
+		data:    &cloudMeta.Data{Data: &meta.Data{ClusterID: uint64(rand.Int63())}},
+		changed: make(chan struct{}),
+	}
+}
+
+// snapshot returns the current data, which is never modified.
+func (c *MemoryMetaClient) snapshot() *cloudMeta.Data {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.data
+}
+
+// apply applies fn to a copy of the data, which replaces the data unless fn
+// returns an error.
+func (c *MemoryMetaClient) apply(fn func(data *cloudMeta.Data) error) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	other := c.data.Clone()
+	if err := fn(other); err != nil {
+		return err
+	}
+	other.Data.Index++
+	c.data = other
+
+	close(c.changed)
+	c.changed = make(chan struct{})
+	return nil
+}
+
+// Data returns a copy of the meta data.
+func (c *MemoryMetaClient) Data() *cloudMeta.Data {
+	return c.snapshot().Clone()
+}
+
+// SetData replaces the meta data with a copy of data.
+func (c *MemoryMetaClient) SetData(data *cloudMeta.Data) error {
+	return c.apply(func(other *cloudMeta.Data) error {
+		*other = *data.Clone()
+		return nil
+	})
+}
+
+// WaitForDataChanged returns a channel closed once the meta data changes.
+func (c *MemoryMetaClient) WaitForDataChanged() chan struct{} {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.changed
+}
+
+// ClusterID returns the ID of the cluster.
+func (c *MemoryMetaClient) ClusterID() uint64 {
+	return c.snapshot().Data.ClusterID
+}
+
+// CreateDatabase creates the database name, without retention policies, if
+// it does not exist.
+func (c *MemoryMetaClient) CreateDatabase(name string) (*meta.DatabaseInfo, error) {
+	if db := c.Database(name); db != nil {
+		return db, nil
+	}
+	if err := c.apply(func(data *cloudMeta.Data) error {
+		return data.Data.CreateDatabase(name)
+	}); err != nil {
+		return nil, err
+	}
+	return c.Database(name), nil
+}
+
+// Database returns the database name, or nil if it does not exist.
+func (c *MemoryMetaClient) Database(name string) *meta.DatabaseInfo {
+	return c.snapshot().Data.Database(name)
+}
+
+// Databases returns the databases.
+func (c *MemoryMetaClient) Databases() ([]meta.DatabaseInfo, error) {
+	return c.snapshot().Data.Databases, nil
+}
+
+// CreateRetentionPolicy creates the retention policy of spec on database,
+// and makes it the default of database if makeDefault is set.
+func (c *MemoryMetaClient) CreateRetentionPolicy(database string, spec *meta.RetentionPolicySpec, makeDefault bool) (*meta.RetentionPolicyInfo, error) {
+	if err := c.apply(func(data *cloudMeta.Data) error {
+		return data.Data.CreateRetentionPolicy(database, spec.NewRetentionPolicyInfo(), makeDefault)
+	}); err != nil {
+		return nil, err
+	}
+	return c.RetentionPolicy(database, spec.Name)
+}
+
+// RetentionPolicy returns the retention policy of database named policy, or
+// nil if it does not exist.
+func (c *MemoryMetaClient) RetentionPolicy(database, policy string) (*meta.RetentionPolicyInfo, error) {
+	return c.snapshot().Data.RetentionPolicy(database, policy)
+}
+
+// CreateShardGroup returns the shard group of database and policy for
+// timestamp, created with a shard per replica set of the data nodes if it
+// does not exist.
+func (c *MemoryMetaClient) CreateShardGroup(database, policy string, timestamp time.Time) (*meta.ShardGroupInfo, error) {
+	if sg, _ := c.snapshot().Data.ShardGroupByTimestamp(database, policy, timestamp); sg != nil {
+		return sg, nil
+	}
+	if err := c.apply(func(data *cloudMeta.Data) error {
+		if len(data.DataNodes) == 0 {
+			return errors.New("no data nodes to create shard group on")
+		}
+		return data.CreateShardGroup(database, policy, timestamp, c.ShardAssignment)
+	}); err != nil {
+		return nil, err
+	}
+	return c.snapshot().Data.ShardGroupByTimestamp(database, policy, timestamp)
+}
+
+// ShardGroupAssignment returns the mode points are assigned to the shards of
+// a shard group with.
+func (c *MemoryMetaClient) ShardGroupAssignment(shardGroupID uint64) string {
+	return c.snapshot().ShardAssignment(shardGroupID)
+}
+
+// TruncateShardGroups ends the shard groups that are still open at t.
+func (c *MemoryMetaClient) TruncateShardGroups(t time.Time) error {
+	return c.apply(func(data *cloudMeta.Data) error {
+		data.TruncateShardGroups(t)
+		return nil
+	})
+}
+
+// ShardOwner returns the database and retention policy of the shard
+// shardID, and the shard. The shard has a zero ID if it does not exist, or
+// its shard group was deleted.
+func (c *MemoryMetaClient) ShardOwner(shardID uint64) (database, policy string, si meta.ShardInfo) {
+	if sh := shardByID(c.snapshot(), shardID, false); sh != nil {
+		return sh.database, sh.policy, *sh.ShardInfo
+	}
+	return "", "", meta.ShardInfo{}
+}
+
+// AddShardOwner makes the data node nodeID an owner of the shard shardID.
+func (c *MemoryMetaClient) AddShardOwner(shardID, nodeID uint64) error {
+	return c.apply(func(data *cloudMeta.Data) error {
+		sh := shardByID(data, shardID, true)
+		if sh == nil {
+			return tsdb.ErrShardNotFound
+		} else if data.DataNode(nodeID) == nil {
+			return cloudMeta.ErrNodeNotFound
+		} else if !sh.OwnedBy(nodeID) {
+			sh.Owners = append(sh.Owners, meta.ShardOwner{NodeID: nodeID})
+		}
+		return nil
+	})
+}
+
+// RemoveShardOwner removes the data node nodeID from the owners of the shard
+// shardID. The last owner of a shard is never removed.
+func (c *MemoryMetaClient) RemoveShardOwner(shardID, nodeID uint64) error {
+	return c.apply(func(data *cloudMeta.Data) error {
+		sh := shardByID(data, shardID, true)
+		if sh == nil {
+			return tsdb.ErrShardNotFound
+		} else if !sh.OwnedBy(nodeID) {
+			return nil
+		} else if len(sh.Owners) == 1 {
+			return cloudMeta.ErrShardNotReplicated
+		}
+		owners := sh.Owners[:0]
+		for _, o := range sh.Owners {
+			if o.NodeID != nodeID {
+				owners = append(owners, o)
+			}
+		}
+		sh.Owners = owners
+		return nil
+	})
+}
+
+// locatedShard is a shard of the meta data, and where it is.
+type locatedShard struct {
+	*meta.ShardInfo
+	database, policy string
+}
+
+// shardByID returns the shard shardID of data, or nil if it does not exist.
+// The shards of deleted shard groups are only returned if deleted is set.
+func shardByID(data *cloudMeta.Data, shardID uint64, deleted bool) *locatedShard {
+	for i := range data.Data.Databases {
+		db := &data.Data.Databases[i]
+		for j := range db.RetentionPolicies {
+			rp := &db.RetentionPolicies[j]
+			for k := range rp.ShardGroups {
+				sg := &rp.ShardGroups[k]
+				if sg.Deleted() && !deleted {
+					continue
+				}
+				for l := range sg.Shards {
+					if sg.Shards[l].ID == shardID {
+						return &locatedShard{ShardInfo: &sg.Shards[l], database: db.Name, policy: rp.Name}
+					}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// CreateDataNode adds a data node with the given addresses.
+func (c *MemoryMetaClient) CreateDataNode(host, tcpHost string) (*meta.NodeInfo, error) {
+	if err := c.apply(func(data *cloudMeta.Data) error {
+		return data.CreateDataNode(host, tcpHost)
+	}); err != nil {
+		return nil, err
+	}
+	for _, n := range c.snapshot().DataNodes {
+		if n.TCPHost == tcpHost {
+			return &meta.NodeInfo{ID: n.ID, Host: n.Host, TCPHost: n.TCPHost}, nil
+		}
+	}
+	return nil, cloudMeta.ErrNodeNotFound
+}
+
+// DataNode returns the data node id.
+func (c *MemoryMetaClient) DataNode(id uint64) (*meta.NodeInfo, error) {
+	n := c.snapshot().DataNode(id)
+	if n == nil {
+		return nil, cloudMeta.ErrNodeNotFound
+	}
+	return &meta.NodeInfo{ID: n.ID, Host: n.Host, TCPHost: n.TCPHost}, nil
+}
+
+// DataNodes returns the data nodes.
+func (c *MemoryMetaClient) DataNodes() ([]meta.NodeInfo, error) {
+	var nodes []meta.NodeInfo
+	for _, n := range c.snapshot().DataNodes {
+		nodes = append(nodes, meta.NodeInfo{ID: n.ID, Host: n.Host, TCPHost: n.TCPHost})
+	}
+	return nodes, nil
+}
+
+// UpdateDataNode changes the addresses of the data node id.
+func (c *MemoryMetaClient) UpdateDataNode(id uint64, host, tcpHost string) error {
+	return c.apply(func(data *cloudMeta.Data) error {
+		n := data.DataNode(id)
+		if n == nil {
+			return cloudMeta.ErrNodeNotFound
+		}
+		n.Host, n.TCPHost = host, tcpHost
+		return nil
+	})
+}
+
+// DeleteDataNode removes the data node id, handing the shards only it owned
+// over to other data nodes.
+func (c *MemoryMetaClient) DeleteDataNode(id uint64) error {
+	return c.apply(func(data *cloudMeta.Data) error {
+		return data.DeleteDataNode(id)
+	})
+}
+
+// ReplaceDataNode hands the shards owned by the data node oldID over to the
+// data node newID, and removes oldID.
+func (c *MemoryMetaClient) ReplaceDataNode(oldID, newID uint64) error {
+	return c.apply(func(data *cloudMeta.Data) error {
+		return data.ReplaceDataNode(oldID, newID)
+	})
+}
+
+// SetDataNodeMaintenance puts the data node id into maintenance mode, or
+// takes it out of it.
+func (c *MemoryMetaClient) SetDataNodeMaintenance(id uint64, maintenance bool) error {
+	return c.apply(func(data *cloudMeta.Data) error {
+		return data.SetDataNodeMaintenance(id, maintenance)
+	})
+}
+
+// MaintenanceNodes returns the IDs of the data nodes in maintenance mode.
+func (c *MemoryMetaClient) MaintenanceNodes() []uint64 {
+	var ids []uint64
+	for _, n := range c.snapshot().DataNodes {
+		if n.Maintenance {
+			ids = append(ids, n.ID)
+		}
+	}
+	return ids
+}
+
+// Users returns the users.
+func (c *MemoryMetaClient) Users() []meta.UserInfo {
+	return c.snapshot().Data.Users
+}
@@ -0,0 +1,364 @@
+package cluster_test
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/influxdata/influxdb/services/meta"
+	"github.com/influxdata/influxdb/tcp"
+	"github.com/influxdata/influxdb/tsdb"
+	"github.com/zhexuany/influxcloud"
+	"github.com/zhexuany/influxcloud/cluster"
+	cloudMeta "github.com/zhexuany/influxcloud/meta"
+	"github.com/zhexuany/influxcloud/rpc"
+	"github.com/zhexuany/influxcloud/tlv"
+)
+
+// Ensure the points writer maps points to shards of the data nodes of an
+// in-memory meta client, without a meta service.
+func TestMemoryMetaClient_PointsWriter(t *testing.T) {
+	mc := MustNewMemoryMetaClient(t, 2)
+
+	c := cluster.NewPointsWriter()
+	c.MetaClient = mc
+	if err := c.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	pr := &cluster.WritePointsRequest{Database: "db0", RetentionPolicy: "rp0"}
+	pr.AddPoint("cpu", 1.0, time.Unix(0, 0), nil)
+	pr.AddPoint("cpu", 2.0, time.Unix(0, 0).Add(2*time.Hour), nil)
+
+	mapping, err := c.MapShards(pr)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(mapping.Points) != 2 {
+		t.Fatalf("unexpected shards: %d", len(mapping.Points))
+	}
+	for shardID, owners := range mapping.Owners {
+		if db, rp, si := mc.ShardOwner(shardID); db != "db0" || rp != "rp0" || si.ID != shardID {
+			t.Fatalf("unexpected shard owner of %d: %s %s %+v", shardID, db, rp, si)
+		} else if len(owners) != 2 {
+			t.Fatalf("unexpected owners of %d: %v", shardID, owners)
+		}
+	}
+
+	// Shard groups are only created once.
+	rp, _ := mc.RetentionPolicy("db0", "rp0")
+	if len(rp.ShardGroups) != 2 {
+		t.Fatalf("unexpected shard groups: %d", len(rp.ShardGroups))
+	} else if _, err := c.MapShards(pr); err != nil {
+		t.Fatal(err)
+	} else if rp, _ := mc.RetentionPolicy("db0", "rp0"); len(rp.ShardGroups) != 2 {
+		t.Fatalf("unexpected shard groups: %d", len(rp.ShardGroups))
+	}
+}
+
+// Ensure changes to the data nodes and shard owners of an in-memory meta
+// client are reported, and seen by later reads.
+func TestMemoryMetaClient_Nodes(t *testing.T) {
+	mc := MustNewMemoryMetaClient(t, 3)
+	if mc.ClusterID() == 0 {
+		t.Fatal("expected a cluster ID")
+	}
+
+	sg, err := mc.CreateShardGroup("db0", "rp0", time.Unix(0, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	shardID := sg.Shards[0].ID
+
+	changed := mc.WaitForDataChanged()
+	if err := mc.RemoveShardOwner(shardID, 3); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-changed:
+	default:
+		t.Fatal("change not reported")
+	}
+	if _, _, si := mc.ShardOwner(shardID); si.OwnedBy(3) {
+		t.Fatalf("unexpected owners: %+v", si.Owners)
+	}
+
+	if err := mc.AddShardOwner(shardID, 3); err != nil {
+		t.Fatal(err)
+	} else if _, _, si := mc.ShardOwner(shardID); !si.OwnedBy(3) {
+		t.Fatalf("unexpected owners: %+v", si.Owners)
+	} else if err := mc.AddShardOwner(shardID, 4); err != cloudMeta.ErrNodeNotFound {
+		t.Fatalf("unexpected error: %v", err)
+	} else if err := mc.AddShardOwner(100, 1); err != tsdb.ErrShardNotFound {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := mc.SetDataNodeMaintenance(2, true); err != nil {
+		t.Fatal(err)
+	} else if got := mc.MaintenanceNodes(); !reflect.DeepEqual(got, []uint64{2}) {
+		t.Fatalf("unexpected maintenance nodes: %v", got)
+	}
+
+	if err := mc.UpdateDataNode(1, "host1b:8086", "host1b:8088"); err != nil {
+		t.Fatal(err)
+	} else if n, err := mc.DataNode(1); err != nil {
+		t.Fatal(err)
+	} else if n.TCPHost != "host1b:8088" {
+		t.Fatalf("unexpected data node: %+v", n)
+	}
+
+	if err := mc.DeleteDataNode(3); err != nil {
+		t.Fatal(err)
+	} else if _, err := mc.DataNode(3); err != cloudMeta.ErrNodeNotFound {
+		t.Fatalf("unexpected error: %v", err)
+	} else if nodes, _ := mc.DataNodes(); len(nodes) != 2 {
+		t.Fatalf("unexpected data nodes: %+v", nodes)
+	}
+
+	// The data exported by the client is imported by another one as is.
+	other := cluster.NewMemoryMetaClient()
+	if err := other.SetData(mc.Data()); err != nil {
+		t.Fatal(err)
+	} else if other.ClusterID() != mc.ClusterID() {
+		t.Fatalf("unexpected cluster ID: %d", other.ClusterID())
+	} else if got, exp := other.MaintenanceNodes(), mc.MaintenanceNodes(); !reflect.DeepEqual(got, exp) {
+		t.Fatalf("unexpected maintenance nodes: %v", got)
+	}
+}
+
+// Ensure the shard and data node RPCs of the service update a real meta store.
+func TestService_MetaStore(t *testing.T) {
+	mc := MustOpenMetaStoreClient(t)
+	defer mc.Close()
+
+	n1, err := mc.CreateDataNode("host1:8086", "host1:8088")
+	if err != nil {
+		t.Fatal(err)
+	}
+	n2, err := mc.CreateDataNode("host2:8086", "host2:8088")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := mc.Client.CreateDatabase("db0"); err != nil {
+		t.Fatal(err)
+	}
+	sg, err := mc.CreateShardGroup("db0", "default", time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	shardID := sg.Shards[0].ID
+	n3, err := mc.CreateDataNode("host3:8086", "host3:8088")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	src := MustOpenService()
+	defer src.Close()
+	src.TSDBStore.BackupShardFn = func(id uint64, since time.Time, w io.Writer) error {
+		_, err := w.Write(MustTarShardBackup(fmt.Sprintf("db0/default/%d/000000001-000000001.tsm", id), "shard data"))
+		return err
+	}
+
+	// Each node of the shard is served by a service sharing the meta store.
+	services := make(map[uint64]*Service)
+	for _, n := range []*cloudMeta.NodeInfo{n1, n2, n3} {
+		s := NewService()
+		s.Node.ID = n.ID
+		s.Service.MetaClient = mc
+		s.TSDBStore.CreateShardFn = func(database, policy string, shardID uint64, enabled bool) error { return nil }
+		s.TSDBStore.RestoreShardFn = func(id uint64, r io.Reader) error {
+			_, err := ioutil.ReadAll(r)
+			return err
+		}
+		s.TSDBStore.DeleteShardFn = func(id uint64) error { return nil }
+		s.ln = MustListen("tcp", "127.0.0.1:0")
+		s.Listener = &muxListener{s.ln}
+		if err := s.Open(); err != nil {
+			t.Fatal(err)
+		}
+		defer s.Close()
+		services[n.ID] = s
+	}
+
+	owners := func() []uint64 {
+		_, _, si := mc.ShardOwner(shardID)
+		var ids []uint64
+		for _, o := range si.Owners {
+			ids = append(ids, o.NodeID)
+		}
+		return ids
+	}
+
+	var copyResp rpc.CopyShardResponse
+	if err := services[n3.ID].Request(tlv.CopyShardRequestMessage, &rpc.CopyShardRequest{
+		Source:   src.Addr().String(),
+		Dest:     services[n3.ID].Addr().String(),
+		ShardID:  shardID,
+		Database: "db0",
+		Policy:   "default",
+	}, &copyResp); err != nil {
+		t.Fatal(err)
+	} else if copyResp.Err != "" {
+		t.Fatal(copyResp.Err)
+	} else if got, exp := owners(), []uint64{n1.ID, n2.ID, n3.ID}; !reflect.DeepEqual(got, exp) {
+		t.Fatalf("unexpected owners: %v", got)
+	}
+
+	for _, id := range []uint64{n1.ID, n3.ID} {
+		var resp rpc.RemoveShardResponse
+		if err := services[id].Request(tlv.RemoveShardRequestMessage, &rpc.RemoveShardRequest{ShardID: shardID}, &resp); err != nil {
+			t.Fatal(err)
+		} else if resp.Err != "" {
+			t.Fatal(resp.Err)
+		}
+	}
+	if got, exp := owners(), []uint64{n2.ID}; !reflect.DeepEqual(got, exp) {
+		t.Fatalf("unexpected owners: %v", got)
+	}
+
+	// The last owner of the shard keeps it.
+	var removeResp rpc.RemoveShardResponse
+	if err := services[n2.ID].Request(tlv.RemoveShardRequestMessage, &rpc.RemoveShardRequest{ShardID: shardID}, &removeResp); err != nil {
+		t.Fatal(err)
+	} else if removeResp.Err == "" {
+		t.Fatal("expected error removing the last owner of a shard")
+	} else if got, exp := owners(), []uint64{n2.ID}; !reflect.DeepEqual(got, exp) {
+		t.Fatalf("unexpected owners: %v", got)
+	}
+
+	var updateResp rpc.UpdateDataNodeResponse
+	if err := services[n1.ID].Request(tlv.UpdateDataNodeRequestMessage, &rpc.UpdateDataNodeRequest{
+		OldTCPHost: "host2:8088",
+		NewTCPHost: "host2b:8088",
+	}, &updateResp); err != nil {
+		t.Fatal(err)
+	} else if updateResp.Err != "" {
+		t.Fatal(updateResp.Err)
+	} else if n, err := mc.DataNode(n2.ID); err != nil {
+		t.Fatal(err)
+	} else if n.Host != "host2:8086" || n.TCPHost != "host2b:8088" {
+		t.Fatalf("unexpected data node: %+v", n)
+	}
+}
+
+// MetaStoreClient is a cluster meta client backed by the client of a meta
+// service running on a temporary directory.
+type MetaStoreClient struct {
+	*cloudMeta.Client
+	service *cloudMeta.Service
+	ln      net.Listener
+	dir     string
+}
+
+// MustOpenMetaStoreClient returns a client of a new, open meta service.
+func MustOpenMetaStoreClient(t *testing.T) *MetaStoreClient {
+	dir, err := ioutil.TempDir("", "cluster-meta-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := &MetaStoreClient{dir: dir, ln: MustListen("tcp", "127.0.0.1:0")}
+
+	cfg := cloudMeta.NewConfig()
+	cfg.BindAddress = c.ln.Addr().String()
+	cfg.HTTPBindAddress = "127.0.0.1:0"
+	cfg.Dir = dir
+	cfg.LoggingEnabled = testing.Verbose()
+
+	mux := tcp.NewMux()
+	c.service = cloudMeta.NewService(cfg)
+	c.service.Node = influxcloud.NewNode(dir)
+	c.service.RaftListener = mux.Listen(cloudMeta.MuxHeader)
+	go mux.Serve(c.ln)
+	if err := c.service.Open(); err != nil {
+		t.Fatal(err)
+	}
+
+	c.Client = cloudMeta.NewClient(cfg)
+	c.Client.SetMetaServers([]string{c.service.HTTPAddr()})
+	if !testing.Verbose() {
+		c.Client.SetLogger(log.New(ioutil.Discard, "", 0))
+	}
+	if err := c.Client.Open(); err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+// Close closes the client and the meta service, and removes its data.
+func (c *MetaStoreClient) Close() error {
+	c.Client.Close()
+	c.service.Close()
+	c.ln.Close()
+	return os.RemoveAll(c.dir)
+}
+
+// Database returns the database name, or nil if it does not exist.
+func (c *MetaStoreClient) Database(name string) *meta.DatabaseInfo {
+	db, _ := c.Client.Database(name)
+	return db
+}
+
+// ShardOwner returns the shard shardID and where it is.
+func (c *MetaStoreClient) ShardOwner(shardID uint64) (database, policy string, si meta.ShardInfo) {
+	database, policy, sh := c.Client.ShardOwner(shardID)
+	if sh != nil {
+		si = *sh
+	}
+	return database, policy, si
+}
+
+// DataNode returns the data node id.
+func (c *MetaStoreClient) DataNode(id uint64) (*meta.NodeInfo, error) {
+	n, err := c.Client.DataNode(id)
+	if err != nil {
+		return nil, err
+	}
+	return &meta.NodeInfo{ID: n.ID, Host: n.Host, TCPHost: n.TCPHost}, nil
+}
+
+// DataNodes returns the data nodes of the cluster.
+func (c *MetaStoreClient) DataNodes() ([]meta.NodeInfo, error) {
+	nodes, err := c.Client.DataNodes()
+	if err != nil {
+		return nil, err
+	}
+	other := make([]meta.NodeInfo, len(nodes))
+	for i, n := range nodes {
+		other[i] = meta.NodeInfo{ID: n.ID, Host: n.Host, TCPHost: n.TCPHost}
+	}
+	return other, nil
+}
+
+// Users returns no users.
+func (c *MetaStoreClient) Users() []meta.UserInfo { return nil }
+
+// MustNewMemoryMetaClient returns an in-memory meta client with nodeN data
+// nodes, fully replicating the hourly retention policy rp0 of db0.
+func MustNewMemoryMetaClient(t *testing.T, nodeN int) *cluster.MemoryMetaClient {
+	mc := cluster.NewMemoryMetaClient()
+	for i := 1; i <= nodeN; i++ {
+		if _, err := mc.CreateDataNode(fmt.Sprintf("host%d:8086", i), fmt.Sprintf("host%d:8088", i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := mc.CreateDatabase("db0"); err != nil {
+		t.Fatal(err)
+	}
+	duration := time.Duration(0)
+	replicaN := nodeN
+	if _, err := mc.CreateRetentionPolicy("db0", &meta.RetentionPolicySpec{
+		Name:               "rp0",
+		Duration:           &duration,
+		ReplicaN:           &replicaN,
+		ShardGroupDuration: time.Hour,
+	}, true); err != nil {
+		t.Fatal(err)
+	}
+	return mc
+}
@@ -31,8 +31,13 @@ type MetaExecutor struct {
 	Logger         zap.Logger
 	Node           *influxdb.Node
 
+	// Dialer connects to the data nodes. The statements are admin RPCs, so
+	// it should send the node's admin token if tokens are configured, see
+	// Service.Dialer.
+	Dialer *Dialer
+
 	nodeExecutor interface {
-		executeOnNode(stmt influxql.Statement, database string, node *meta.NodeInfo) error
+		executeOnNode(requestID string, stmt influxql.Statement, database string, node *meta.NodeInfo) (int64, error)
 	}
 
 	MetaClient interface {
@@ -60,6 +65,7 @@ func NewMetaExecutor() *MetaExecutor {
 		pool:           newClientPool(),
 		maxConnections: metaExecutorMaxWriteConnections,
 		Logger:         zap.New(zap.NullEncoder()),
+		Dialer:         DefaultDialer,
 	}
 	m.nodeExecutor = m
 
@@ -79,44 +85,62 @@ func (e remoteNodeError) Error() string {
 
 // ExecuteStatement executes a single InfluxQL statement on all nodes in the cluster concurrently.
 func (m *MetaExecutor) ExecuteStatement(stmt influxql.Statement, database string) error {
+	_, err := m.executeOnNodes(stmt, database)
+	return err
+}
+
+// executeOnNodes executes stmt on all nodes in the cluster concurrently and
+// returns the number of series deleted on each node that succeeded.
+func (m *MetaExecutor) executeOnNodes(stmt influxql.Statement, database string) (map[uint64]int64, error) {
 	// Get a list of all nodes the query needs to be executed on.
 	nodes, err := m.MetaClient.DataNodes()
 	if err != nil {
-		return err
+		return nil, err
 	} else if len(nodes) < 1 {
-		return nil
+		return nil, nil
 	}
 
+	requestID := NewRequestID()
+
 	// Start a goroutine to execute the statement on each of the remote nodes.
 	var wg sync.WaitGroup
-	errs := make(chan error, len(nodes)-1)
+	var mu sync.Mutex
+	seriesN := make(map[uint64]int64, len(nodes))
+	errs := make(chan error, len(nodes))
 	for _, node := range nodes {
 		wg.Add(1)
 		go func(node meta.NodeInfo) {
 			defer wg.Done()
-			if err := m.nodeExecutor.executeOnNode(stmt, database, &node); err != nil {
+			n, err := m.nodeExecutor.executeOnNode(requestID, stmt, database, &node)
+			if err != nil {
+				m.Logger.Info("execute statement failed", zap.String("requestID", requestID), zap.Uint64("node", node.ID), zap.Error(err))
 				errs <- remoteNodeError{id: node.ID, err: err}
+				return
 			}
+			mu.Lock()
+			seriesN[node.ID] = n
+			mu.Unlock()
 		}(node)
 	}
 
-	// Wait on n-1 nodes to execute the statement and respond.
+	// Wait on all nodes to execute the statement and respond.
 	wg.Wait()
 
 	select {
 	case err = <-errs:
-		return err
+		return seriesN, err
 	default:
-		return nil
+		return seriesN, nil
 	}
 }
 
-// executeOnNode executes a single InfluxQL statement on a single node.
-func (m *MetaExecutor) executeOnNode(stmt influxql.Statement, database string, node *meta.NodeInfo) error {
+// executeOnNode executes a single InfluxQL statement on a single node and
+// returns the number of series it deleted.
+func (m *MetaExecutor) executeOnNode(requestID string, stmt influxql.Statement, database string, node *meta.NodeInfo) (int64, error) {
 	// We're executing on a remote node so establish a connection.
 	c, err := m.dial(node.ID)
 	if err != nil {
-		return err
+		return 0, err
 	}
 
 	conn, ok := c.(*pooledConn)
@@ -130,18 +154,22 @@ func (m *MetaExecutor) executeOnNode(stmt influxql.Statement, database string, n
 	var request rpc.ExecuteStatementRequest
 	request.SetStatement(stmt.String())
 	request.SetDatabase(database)
+	request.SetRequestID(requestID)
+	if m.Node != nil {
+		request.SetNodeID(m.Node.ID)
+	}
 
 	// Marshal into protocol buffer.
 	buf, err := request.MarshalBinary()
 	if err != nil {
-		return err
+		return 0, err
 	}
 
 	// Send request.
 	conn.SetWriteDeadline(time.Now().Add(m.timeout))
 	if err := tlv.WriteTLV(conn, tlv.ExecuteStatementRequestMessage, buf); err != nil {
 		conn.MarkUnusable()
-		return err
+		return 0, err
 	}
 
 	// Read the response.
@@ -151,20 +179,20 @@ func (m *MetaExecutor) executeOnNode(stmt influxql.Statement, database string, n
 	_, buf, err = tlv.ReadTLV(conn)
 	if err != nil {
 		conn.MarkUnusable()
-		return err
+		return 0, err
 	}
 
 	// Unmarshal response.
 	var response rpc.ExecuteStatementResponse
 	if err := response.UnmarshalBinary(buf); err != nil {
-		return err
+		return 0, err
 	}
 
 	if response.Code() != 0 {
-		return fmt.Errorf("error code %d: %s", response.Code(), response.Message())
+		return 0, fmt.Errorf("error code %d: %s", response.Code(), response.Message())
 	}
 
-	return nil
+	return response.SeriesN(), nil
 }
 
 // dial returns a connection to a single node in the cluster.
@@ -172,7 +200,7 @@ func (m *MetaExecutor) dial(nodeID uint64) (net.Conn, error) {
 	// If we don't have a connection pool for that addr yet, create one
 	_, ok := m.pool.getPool(nodeID)
 	if !ok {
-		factory := &connFactory{nodeID: nodeID, clientPool: m.pool, timeout: m.timeout}
+		factory := &connFactory{nodeID: nodeID, clientPool: m.pool, timeout: m.timeout, dialer: m.Dialer, hello: m.helloRequest}
 		factory.metaClient = m.MetaClient
 
 		p, err := NewBoundedPool(1, m.maxConnections, m.timeout, factory.dial)
@@ -184,6 +212,15 @@ func (m *MetaExecutor) dial(nodeID uint64) (net.Conn, error) {
 	return m.pool.conn(nodeID)
 }
 
+// helloRequest returns the hello message said on the connections dialed.
+func (m *MetaExecutor) helloRequest() *rpc.HelloRequest {
+	var nodeID uint64
+	if m.Node != nil {
+		nodeID = m.Node.ID
+	}
+	return newHelloRequest(nodeID, clusterIDOf(m.MetaClient), "")
+}
+
 // CreateShard will create Shard on serveral data nodes
 func (m *MetaExecutor) CreateShard(db, policy string, shardID uint64, enabled bool) error {
 	return m.TSDBStore.CreateShard(db, policy, shardID, enabled)
@@ -215,13 +252,26 @@ func (m *MetaExecutor) DeleteRetentionPolicy(stmt influxql.Statement) error {
 	return m.ExecuteStatement(stmt, db)
 }
 
-// DeleteSeries removes series data from cluster
-func (m *MetaExecutor) DeleteSeries(stmt influxql.Statement) error {
-	db := ""
-	// if st, ok := stmt.(*influxql.DropSeriesStatement); ok {
-
-	// }
-	return m.ExecuteStatement(stmt, db)
+// DeleteSeries removes the series data matching stmt, a DROP SERIES or
+// DELETE statement, from every data node so that all owners of a shard
+// apply the same delete. It returns the number of series deleted on each
+// node. now() in the statement's condition is evaluated once, here, so that
+// every node deletes the same time range.
+func (m *MetaExecutor) DeleteSeries(stmt influxql.Statement, database string) (map[uint64]int64, error) {
+	valuer := &influxql.NowValuer{Now: time.Now().UTC()}
+	switch st := stmt.(type) {
+	case *influxql.DropSeriesStatement:
+		other := *st
+		other.Condition = influxql.Reduce(st.Condition, valuer)
+		stmt = &other
+	case *influxql.DeleteSeriesStatement:
+		other := *st
+		other.Condition = influxql.Reduce(st.Condition, valuer)
+		stmt = &other
+	default:
+		return nil, fmt.Errorf("%q does not delete series", stmt.String())
+	}
+	return m.executeOnNodes(stmt, database)
 }
 
 // DeleteShard removes a Shard from cluster
@@ -0,0 +1,498 @@
+package cluster
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/influxdata/influxdb/models"
+	"github.com/zhexuany/influxcloud/tlv"
+)
+
+// MetricsNamespace prefixes every exported metric name.
+const MetricsNamespace = "influxcloud"
+
+// DefaultLatencyBuckets are the upper bounds, in seconds, of the RPC latency histogram.
+var DefaultLatencyBuckets = []float64{.001, .005, .01, .05, .1, .5, 1, 5, 10}
+
+// rpcNames maps request types to the label used for RPC latency metrics.
+var rpcNames = map[byte]string{
+	tlv.WriteShardRequestMessage:            "writeShard",
+	tlv.ExecuteStatementRequestMessage:      "executeStatement",
+	tlv.CreateIteratorRequestMessage:        "createIterator",
+	tlv.FieldDimensionsRequestMessage:       "fieldDimensions",
+	tlv.CopyShardRequestMessage:             "copyShard",
+	tlv.RemoveShardRequestMessage:           "removeShard",
+	tlv.ShowShardsRequestMessage:            "showShards",
+	tlv.BackupShardRequestMessage:           "backupShard",
+	tlv.TruncateShardsRequestMessage:        "truncateShards",
+	tlv.RemoveDataNodeRequestMessage:        "removeDataNode",
+	tlv.UpdateDataNodeRequestMessage:        "updateDataNode",
+	tlv.AuthStateRequestMessage:             "authState",
+	tlv.PauseReplicationRequestMessage:      "pauseReplication",
+	tlv.ExportMetaDataRequestMessage:        "exportMetaData",
+	tlv.ShardStatusRequestMessage:           "shardStatus",
+	tlv.MultiplexRequestMessage:             "multiplex",
+	tlv.PingRequestMessage:                  "ping",
+	tlv.WritePointsRequestMessage:           "writePoints",
+	tlv.ShardBoundsRequestMessage:           "shardBounds",
+	tlv.AcquireLeaseRequestMessage:          "acquireLease",
+	tlv.RestoreShardRequestMessage:          "restoreShard",
+	tlv.UploadShardSnapshotRequestMessage:   "uploadShardSnapshot",
+	tlv.ReplaceDataNodeRequestMessage:       "replaceDataNode",
+	tlv.RedirectHintedHandoffRequestMessage: "redirectHintedHandoff",
+	tlv.HelloRequestMessage:                 "hello",
+	tlv.DropShardsRequestMessage:            "dropShards",
+	tlv.SetMaintenanceRequestMessage:        "setMaintenance",
+	tlv.SetReadOnlyRequestMessage:           "setReadOnly",
+	tlv.CardinalityRequestMessage:           "cardinality",
+	tlv.QuarantineRequestMessage:            "quarantine",
+}
+
+// rpcName returns the label of request type typ, or "unknown".
+func rpcName(typ byte) string {
+	if name, ok := rpcNames[typ]; ok {
+		return name
+	}
+	return "unknown"
+}
+
+// StatisticsSource is implemented by anything that reports models.Statistic
+// values for the monitor service, such as a PointsWriter or the hinted
+// handoff service.
+type StatisticsSource interface {
+	Statistics(tags map[string]string) []models.Statistic
+}
+
+// QueueSizer is implemented by the hinted handoff service, which reports the
+// number of bytes queued for each node.
+type QueueSizer interface {
+	QueueSizes() map[uint64]int64
+}
+
+// Metrics exports cluster statistics in the Prometheus text format.
+//
+// Statistics from registered sources are mirrored as untyped metrics named
+// after the statistic and its field, e.g. the "writeOk" field of the "write"
+// statistic becomes influxcloud_write_write_ok. RPC latencies and iterator
+// streams are tracked by the cluster service itself, and the sizes of the
+// hinted handoff queues are exported as a gauge once registered.
+type Metrics struct {
+	mu      sync.Mutex
+	sources []StatisticsSource
+	queues  QueueSizer
+	rpcs    map[string]*histogram
+
+	iteratorStreams         int64
+	iteratorStreamsTotal    int64
+	iteratorStreamsOrphaned int64
+	iteratorStreamsEvicted  int64
+
+	// Requests rejected by a per-database quota, by database and quota.
+	quotaHits map[quotaKey]int64
+
+	// Requests whose handler panicked, by RPC name.
+	rpcPanics map[string]int64
+}
+
+// quotaKey identifies a per-database quota.
+type quotaKey struct {
+	database string
+	quota    string
+}
+
+// NewMetrics returns a new, empty instance of Metrics.
+func NewMetrics() *Metrics {
+	return &Metrics{
+		rpcs:      make(map[string]*histogram),
+		quotaHits: make(map[quotaKey]int64),
+		rpcPanics: make(map[string]int64),
+	}
+}
+
+// Register adds src to the statistics exported on every scrape.
+func (m *Metrics) Register(src StatisticsSource) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sources = append(m.sources, src)
+}
+
+// RegisterQueues exports the queue sizes q reports on every scrape, replacing
+// any registered before.
+func (m *Metrics) RegisterQueues(q QueueSizer) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.queues = q
+}
+
+// ObserveRPC records the time taken to process a request of type typ.
+func (m *Metrics) ObserveRPC(typ byte, d time.Duration) {
+	m.observeRPC(rpcName(typ), d)
+}
+
+// observeRPC records the time taken to process a request named name.
+func (m *Metrics) observeRPC(name string, d time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	h := m.rpcs[name]
+	if h == nil {
+		h = newHistogram(DefaultLatencyBuckets)
+		m.rpcs[name] = h
+	}
+	h.observe(d.Seconds())
+}
+
+// openIteratorStream records the start of a remote iterator stream.
+func (m *Metrics) openIteratorStream() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.iteratorStreams++
+	m.iteratorStreamsTotal++
+}
+
+// closeIteratorStream records the end of a remote iterator stream.
+func (m *Metrics) closeIteratorStream() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.iteratorStreams--
+}
+
+// orphanIteratorStream records a remote iterator stream closed because the
+// querying node disconnected before the end of the stream.
+func (m *Metrics) orphanIteratorStream() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.iteratorStreamsOrphaned++
+}
+
+// evictIteratorStream records a remote iterator stream aborted because the
+// querying node stopped reading it.
+func (m *Metrics) evictIteratorStream() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.iteratorStreamsEvicted++
+}
+
+// quotaHit records a request of database rejected by quota.
+func (m *Metrics) quotaHit(database, quota string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.quotaHits[quotaKey{database: database, quota: quota}]++
+}
+
+// rpcPanic records a request named name whose handler panicked.
+func (m *Metrics) rpcPanic(name string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.rpcPanics[name]++
+}
+
+// WriteTo writes all metrics to w in the Prometheus text format.
+func (m *Metrics) WriteTo(w io.Writer) (int64, error) {
+	m.mu.Lock()
+	sources := make([]StatisticsSource, len(m.sources))
+	copy(sources, m.sources)
+	queues := m.queues
+	m.mu.Unlock()
+
+	// Group samples by metric name, since all samples of a metric must be
+	// written together.
+	families := make(map[string][]string)
+	for _, src := range sources {
+		for _, stat := range src.Statistics(nil) {
+			labels := formatLabels(stat.Tags)
+			for field, v := range stat.Values {
+				value, ok := formatValue(v)
+				if !ok {
+					continue
+				}
+				name := metricName(statisticName(stat.Name), field)
+				families[name] = append(families[name], name+labels+" "+value)
+			}
+		}
+	}
+
+	bw := bufio.NewWriter(w)
+	cw := &countingWriter{w: bw}
+
+	names := make([]string, 0, len(families))
+	for name := range families {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		samples := families[name]
+		sort.Strings(samples)
+		fmt.Fprintf(cw, "# TYPE %s untyped\n", name)
+		for _, s := range samples {
+			fmt.Fprintln(cw, s)
+		}
+	}
+
+	if queues != nil {
+		writeQueueSizes(cw, queues.QueueSizes())
+	}
+
+	m.mu.Lock()
+	m.writeRPCs(cw)
+	m.writeIteratorStreams(cw)
+	m.writeQuotaHits(cw)
+	m.writeRPCPanics(cw)
+	m.mu.Unlock()
+
+	if err := bw.Flush(); err != nil {
+		return cw.n, err
+	}
+	return cw.n, cw.err
+}
+
+// writeQueueSizes writes the number of bytes queued for each node by the
+// hinted handoff service.
+func writeQueueSizes(w io.Writer, sizes map[uint64]int64) {
+	name := MetricsNamespace + "_hh_queue_bytes"
+	fmt.Fprintf(w, "# HELP %s Bytes queued by hinted handoff for each node.\n", name)
+	fmt.Fprintf(w, "# TYPE %s gauge\n", name)
+
+	ids := make([]uint64, 0, len(sizes))
+	for id := range sizes {
+		ids = append(ids, id)
+	}
+	sort.Sort(uint64Slice(ids))
+	for _, id := range ids {
+		fmt.Fprintf(w, "%s{node=\"%d\"} %d\n", name, id, sizes[id])
+	}
+}
+
+// writeRPCs writes the RPC latency histograms. Must be called with the lock held.
+func (m *Metrics) writeRPCs(w io.Writer) {
+	if len(m.rpcs) == 0 {
+		return
+	}
+
+	name := MetricsNamespace + "_rpc_duration_seconds"
+	fmt.Fprintf(w, "# HELP %s Time taken to process cluster RPCs on this node.\n", name)
+	fmt.Fprintf(w, "# TYPE %s histogram\n", name)
+
+	types := make([]string, 0, len(m.rpcs))
+	for typ := range m.rpcs {
+		types = append(types, typ)
+	}
+	sort.Strings(types)
+	for _, typ := range types {
+		h := m.rpcs[typ]
+		for i, le := range h.buckets {
+			fmt.Fprintf(w, "%s_bucket{type=%q,le=%q} %d\n", name, typ, formatFloat(le), h.counts[i])
+		}
+		fmt.Fprintf(w, "%s_bucket{type=%q,le=\"+Inf\"} %d\n", name, typ, h.count)
+		fmt.Fprintf(w, "%s_sum{type=%q} %s\n", name, typ, formatFloat(h.sum))
+		fmt.Fprintf(w, "%s_count{type=%q} %d\n", name, typ, h.count)
+	}
+}
+
+// writeIteratorStreams writes the iterator stream counts. Must be called with the lock held.
+func (m *Metrics) writeIteratorStreams(w io.Writer) {
+	name := MetricsNamespace + "_iterator_streams"
+	fmt.Fprintf(w, "# HELP %s Remote iterator streams currently open.\n", name)
+	fmt.Fprintf(w, "# TYPE %s gauge\n", name)
+	fmt.Fprintf(w, "%s %d\n", name, m.iteratorStreams)
+
+	fmt.Fprintf(w, "# HELP %s_total Remote iterator streams opened.\n", name)
+	fmt.Fprintf(w, "# TYPE %s_total counter\n", name)
+	fmt.Fprintf(w, "%s_total %d\n", name, m.iteratorStreamsTotal)
+
+	fmt.Fprintf(w, "# HELP %s_orphaned_total Remote iterator streams closed after the querying node disconnected.\n", name)
+	fmt.Fprintf(w, "# TYPE %s_orphaned_total counter\n", name)
+	fmt.Fprintf(w, "%s_orphaned_total %d\n", name, m.iteratorStreamsOrphaned)
+
+	fmt.Fprintf(w, "# HELP %s_evicted_total Remote iterator streams aborted after the querying node stopped reading.\n", name)
+	fmt.Fprintf(w, "# TYPE %s_evicted_total counter\n", name)
+	fmt.Fprintf(w, "%s_evicted_total %d\n", name, m.iteratorStreamsEvicted)
+}
+
+// writeQuotaHits writes the counts of requests rejected by per-database
+// quotas. Must be called with the lock held.
+func (m *Metrics) writeQuotaHits(w io.Writer) {
+	if len(m.quotaHits) == 0 {
+		return
+	}
+
+	name := MetricsNamespace + "_database_quota_hits_total"
+	fmt.Fprintf(w, "# HELP %s Requests rejected by a per-database quota.\n", name)
+	fmt.Fprintf(w, "# TYPE %s counter\n", name)
+
+	keys := make([]quotaKey, 0, len(m.quotaHits))
+	for k := range m.quotaHits {
+		keys = append(keys, k)
+	}
+	sort.Sort(quotaKeys(keys))
+	for _, k := range keys {
+		fmt.Fprintf(w, "%s{database=%q,quota=%q} %d\n", name, k.database, k.quota, m.quotaHits[k])
+	}
+}
+
+// writeRPCPanics writes the counts of requests whose handler panicked. Must
+// be called with the lock held.
+func (m *Metrics) writeRPCPanics(w io.Writer) {
+	if len(m.rpcPanics) == 0 {
+		return
+	}
+
+	name := MetricsNamespace + "_rpc_panics_total"
+	fmt.Fprintf(w, "# HELP %s Cluster RPCs whose handler panicked on this node.\n", name)
+	fmt.Fprintf(w, "# TYPE %s counter\n", name)
+
+	types := make([]string, 0, len(m.rpcPanics))
+	for typ := range m.rpcPanics {
+		types = append(types, typ)
+	}
+	sort.Strings(types)
+	for _, typ := range types {
+		fmt.Fprintf(w, "%s{type=%q} %d\n", name, typ, m.rpcPanics[typ])
+	}
+}
+
+type quotaKeys []quotaKey
+
+func (a quotaKeys) Len() int      { return len(a) }
+func (a quotaKeys) Swap(i, j int) { a[i], a[j] = a[j], a[i] }
+func (a quotaKeys) Less(i, j int) bool {
+	if a[i].database != a[j].database {
+		return a[i].database < a[j].database
+	}
+	return a[i].quota < a[j].quota
+}
+
+// histogram is a cumulative histogram of observed values.
+type histogram struct {
+	buckets []float64
+	counts  []uint64
+	count   uint64
+	sum     float64
+}
+
+func newHistogram(buckets []float64) *histogram {
+	return &histogram{
+		buckets: buckets,
+		counts:  make([]uint64, len(buckets)),
+	}
+}
+
+func (h *histogram) observe(v float64) {
+	for i, le := range h.buckets {
+		if v <= le {
+			h.counts[i]++
+		}
+	}
+	h.count++
+	h.sum += v
+}
+
+// statisticName strips any instance suffix from a statistic name, so that
+// "hh_processor:/var/lib/hh/2" is exported as "hh_processor".
+func statisticName(name string) string {
+	if i := strings.Index(name, ":"); i >= 0 {
+		return name[:i]
+	}
+	return name
+}
+
+// metricName returns the Prometheus name of field in the named statistic.
+func metricName(stat, field string) string {
+	return MetricsNamespace + "_" + sanitizeName(snakeCase(stat)) + "_" + sanitizeName(snakeCase(field))
+}
+
+// snakeCase converts a camel case name such as "pointReqHH" to "point_req_hh".
+func snakeCase(s string) string {
+	runes := []rune(s)
+	var buf []rune
+	for i, r := range runes {
+		if unicode.IsUpper(r) && i > 0 {
+			prev := runes[i-1]
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) ||
+				(unicode.IsUpper(prev) && i+1 < len(runes) && unicode.IsLower(runes[i+1])) {
+				buf = append(buf, '_')
+			}
+		}
+		buf = append(buf, unicode.ToLower(r))
+	}
+	return string(buf)
+}
+
+// sanitizeName replaces characters that are invalid in metric and label names.
+func sanitizeName(s string) string {
+	b := []byte(s)
+	for i, c := range b {
+		if !(c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9' && i > 0)) {
+			b[i] = '_'
+		}
+	}
+	return string(b)
+}
+
+// formatLabels returns tags as a sorted Prometheus label set.
+func formatLabels(tags map[string]string) string {
+	if len(tags) == 0 {
+		return ""
+	}
+
+	keys := make([]string, 0, len(tags))
+	for k := range tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, len(keys))
+	for i, k := range keys {
+		pairs[i] = sanitizeName(k) + "=" + strconv.Quote(tags[k])
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+// formatValue returns v as a sample value. Non-numeric values are skipped.
+func formatValue(v interface{}) (string, bool) {
+	switch v := v.(type) {
+	case int:
+		return strconv.Itoa(v), true
+	case int64:
+		return strconv.FormatInt(v, 10), true
+	case uint64:
+		return strconv.FormatUint(v, 10), true
+	case float64:
+		return formatFloat(v), true
+	case bool:
+		if v {
+			return "1", true
+		}
+		return "0", true
+	default:
+		return "", false
+	}
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+// countingWriter counts the bytes written to w and keeps the first error.
+type countingWriter struct {
+	w   io.Writer
+	n   int64
+	err error
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	if w.err != nil {
+		return 0, w.err
+	}
+	n, err := w.w.Write(p)
+	w.n += int64(n)
+	w.err = err
+	return n, err
+}
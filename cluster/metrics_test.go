@@ -0,0 +1,57 @@
+package cluster_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/influxdata/influxdb/models"
+	"github.com/zhexuany/influxcloud/cluster"
+	"github.com/zhexuany/influxcloud/tlv"
+)
+
+// Ensure statistics and RPC latencies are written in the Prometheus text format.
+func TestMetrics_WriteTo(t *testing.T) {
+	m := cluster.NewMetrics()
+	m.Register(statistics{
+		{Name: "write", Values: map[string]interface{}{"pointReqHH": int64(3), "name": "skipped"}},
+		{Name: "hh_processor:/tmp/hh/2", Tags: map[string]string{"id": "2"}, Values: map[string]interface{}{"diskBytes": int64(100)}},
+	})
+	m.RegisterQueues(queueSizes{3: 50, 2: 100})
+	m.ObserveRPC(tlv.WriteShardRequestMessage, 20*time.Millisecond)
+	m.ObserveRPC(tlv.WriteShardRequestMessage, 2*time.Second)
+
+	var buf bytes.Buffer
+	if _, err := m.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+
+	for _, line := range []string{
+		"# TYPE influxcloud_write_point_req_hh untyped",
+		"influxcloud_write_point_req_hh 3",
+		`influxcloud_hh_processor_disk_bytes{id="2"} 100`,
+		"# TYPE influxcloud_hh_queue_bytes gauge",
+		`influxcloud_hh_queue_bytes{node="2"} 100` + "\n" + `influxcloud_hh_queue_bytes{node="3"} 50`,
+		"# TYPE influxcloud_rpc_duration_seconds histogram",
+		`influxcloud_rpc_duration_seconds_bucket{type="writeShard",le="0.01"} 0`,
+		`influxcloud_rpc_duration_seconds_bucket{type="writeShard",le="0.05"} 1`,
+		`influxcloud_rpc_duration_seconds_bucket{type="writeShard",le="+Inf"} 2`,
+		`influxcloud_rpc_duration_seconds_count{type="writeShard"} 2`,
+		"influxcloud_iterator_streams 0",
+		"influxcloud_iterator_streams_orphaned_total 0",
+	} {
+		if !strings.Contains(out, line+"\n") {
+			t.Errorf("missing line %q in:\n%s", line, out)
+		}
+	}
+	if strings.Contains(out, "skipped") {
+		t.Errorf("unexpected non-numeric value in:\n%s", out)
+	}
+}
+
+// statistics is a static implementation of cluster.StatisticsSource.
+type statistics []models.Statistic
+
+func (s statistics) Statistics(tags map[string]string) []models.Statistic { return s }
@@ -0,0 +1,261 @@
+package cluster
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/zhexuany/influxcloud/rpc"
+	"github.com/zhexuany/influxcloud/tlv"
+)
+
+// errMuxConnClosed is returned for requests on a multiplexed connection that
+// has been closed.
+var errMuxConnClosed = errors.New("multiplexed connection closed")
+
+// muxConn is a client connection that carries several requests at once.
+// Each request is sent with a new tag and the response with the same tag is
+// returned to it, in whatever order the remote node answers.
+type muxConn struct {
+	conn    net.Conn
+	timeout time.Duration
+
+	// checksum is set if both nodes negotiated checksummed records.
+	checksum bool
+
+	// pointCodecs are the codecs the remote node decodes the points of
+	// writes with, other than the binary codec every node decodes.
+	pointCodecs []rpc.PointCodecID
+
+	// wmu serializes writes to conn.
+	wmu sync.Mutex
+
+	mu      sync.Mutex
+	nextTag uint64
+	pending map[uint64]chan muxResponse
+	late    map[uint64]func(typ byte, buf []byte)
+	err     error // set once the connection has failed or been closed
+}
+
+// muxResponse is a response read from a multiplexed connection.
+type muxResponse struct {
+	typ byte
+	buf []byte
+}
+
+// errNoPipelining is returned when dialing a multiplexed connection to a node
+// that does not support carrying several requests at once.
+var errNoPipelining = errors.New("node does not support pipelining")
+
+// dialMuxConn opens a multiplexed connection to addr, dialed by d. If hello is not nil it
+// is sent first, and errNoPipelining is returned if the remote node does not
+// support multiplexed connections. Records are checksummed if both nodes
+// support it.
+func dialMuxConn(d *Dialer, addr string, timeout time.Duration, hello *rpc.HelloRequest) (*muxConn, error) {
+	conn, err := d.connect(addr, timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	var features rpc.Feature
+	var codecs []rpc.PointCodecID
+
+	if err := func() error {
+		conn.SetDeadline(time.Now().Add(timeout))
+
+		if hello != nil {
+			resp, err := sayHello(conn, hello)
+			if err != nil {
+				return err
+			} else if !resp.Features.Has(rpc.FeaturePipelining) {
+				return errNoPipelining
+			}
+			features, codecs = resp.Features, resp.PointCodecs
+		}
+
+		if err := tlv.WriteTLV(conn, tlv.MultiplexRequestMessage, nil); err != nil {
+			return err
+		}
+
+		if typ, _, err := tlv.ReadTLV(conn); err != nil {
+			return err
+		} else if typ != tlv.MultiplexResponseMessage {
+			return fmt.Errorf("unexpected multiplex response type: %d", typ)
+		}
+
+		return conn.SetDeadline(time.Time{})
+	}(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	c := &muxConn{
+		conn:        conn,
+		timeout:     timeout,
+		checksum:    features.Has(rpc.FeatureChecksum),
+		pointCodecs: codecs,
+		pending:     make(map[uint64]chan muxResponse),
+		late:        make(map[uint64]func(typ byte, buf []byte)),
+	}
+	go c.readLoop()
+	return c, nil
+}
+
+// supportsPointCodec returns true if the remote node decodes the points of
+// writes encoded with the codec id.
+func (c *muxConn) supportsPointCodec(id rpc.PointCodecID) bool {
+	if id == rpc.BinaryPointCodec {
+		return true
+	}
+	for _, other := range c.pointCodecs {
+		if other == id {
+			return true
+		}
+	}
+	return false
+}
+
+// Request sends a request of type typ and waits up to timeout for its
+// response, or up to the timeout of the connection if timeout is zero. The
+// context of span is sent ahead of the request. If the request times out and
+// late is not nil, late is called with the response if it arrives before the
+// connection fails.
+func (c *muxConn) Request(span Span, typ byte, buf []byte, timeout time.Duration, late func(typ byte, buf []byte)) (byte, []byte, error) {
+	ch := make(chan muxResponse, 1)
+
+	c.mu.Lock()
+	if c.err != nil {
+		c.mu.Unlock()
+		return 0, nil, c.err
+	}
+	c.nextTag++
+	tag := c.nextTag
+	c.pending[tag] = ch
+	c.mu.Unlock()
+
+	if err := c.write(span, tag, typ, buf); err != nil {
+		c.fail(err)
+		return 0, nil, err
+	}
+
+	if timeout <= 0 {
+		timeout = c.timeout
+	}
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case resp, ok := <-ch:
+		if !ok {
+			return 0, nil, c.error()
+		}
+		return resp.typ, resp.buf, nil
+	case <-timer.C:
+		// A response arriving after this is passed to late by the read
+		// loop, or dropped if late is nil.
+		c.mu.Lock()
+		_, ok := c.pending[tag]
+		if ok {
+			delete(c.pending, tag)
+			if late != nil {
+				c.late[tag] = late
+			}
+		}
+		c.mu.Unlock()
+		if !ok {
+			// The response arrived, or the connection failed, as the
+			// request timed out.
+			if resp, ok := <-ch; ok {
+				return resp.typ, resp.buf, nil
+			}
+			return 0, nil, c.error()
+		}
+		return 0, nil, ErrTimeout
+	}
+}
+
+// write sends the span context and the request tagged with tag.
+func (c *muxConn) write(span Span, tag uint64, typ byte, buf []byte) error {
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+	c.conn.SetWriteDeadline(time.Now().Add(c.timeout))
+
+	carrier := make(map[string]string)
+	span.Inject(carrier)
+	if len(carrier) > 0 {
+		b, err := (&rpc.SpanContext{Carrier: carrier}).MarshalBinary()
+		if err != nil {
+			return err
+		}
+		if err := c.writeTagged(tlv.SpanContextMessage, tag, b); err != nil {
+			return err
+		}
+	}
+
+	return c.writeTagged(typ, tag, buf)
+}
+
+// writeTagged writes a tagged record, checksummed if negotiated.
+func (c *muxConn) writeTagged(typ byte, tag uint64, buf []byte) error {
+	if c.checksum {
+		return tlv.WriteTaggedTLVChecksum(c.conn, typ, tag, buf)
+	}
+	return tlv.WriteTaggedTLV(c.conn, typ, tag, buf)
+}
+
+// readLoop delivers responses to the requests waiting for them until the
+// connection fails. A corrupted response fails the connection, as its tag
+// cannot be trusted.
+func (c *muxConn) readLoop() {
+	for {
+		typ, tag, buf, err := tlv.ReadTaggedTLV(c.conn)
+		if err != nil {
+			c.fail(err)
+			return
+		}
+
+		c.mu.Lock()
+		ch, late := c.pending[tag], c.late[tag]
+		delete(c.pending, tag)
+		delete(c.late, tag)
+		c.mu.Unlock()
+
+		if ch != nil {
+			ch <- muxResponse{typ: typ, buf: buf}
+		} else if late != nil {
+			late(typ, buf)
+		}
+	}
+}
+
+// fail closes the connection and fails every pending request with err.
+func (c *muxConn) fail(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.err != nil {
+		return
+	}
+
+	c.err = err
+	c.conn.Close()
+	for tag, ch := range c.pending {
+		close(ch)
+		delete(c.pending, tag)
+	}
+	c.late = nil
+}
+
+// error returns the error the connection failed with, if any.
+func (c *muxConn) error() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.err
+}
+
+// Close closes the connection. Pending requests fail.
+func (c *muxConn) Close() error {
+	c.fail(errMuxConnClosed)
+	return nil
+}
@@ -0,0 +1,324 @@
+package cluster
+
+import (
+	"fmt"
+	"sort"
+	"sync/atomic"
+	"time"
+
+	"github.com/influxdata/influxdb/influxql"
+	"github.com/influxdata/influxdb/services/meta"
+)
+
+// NodeLostError is returned by a remote iterator whose node is lost before
+// the end of its stream, if its shards are not re-planned on other owners.
+type NodeLostError struct {
+	NodeID uint64
+	Err    error
+}
+
+// Error returns a string representation of the error.
+func (e *NodeLostError) Error() string {
+	return fmt.Sprintf("node %d lost during query: %s", e.NodeID, e.Err)
+}
+
+// shardOwnerMetaClient is implemented by meta clients that know the owners
+// of shards.
+type shardOwnerMetaClient interface {
+	ShardOwner(shardID uint64) (string, string, meta.ShardInfo)
+}
+
+// nodeLost returns true if err, returned while reading the stream of a
+// remote iterator, means the connection to the node was lost, rather than
+// the iterator failing on the node.
+func nodeLost(err error) bool {
+	return err == errIteratorStreamTruncated
+}
+
+// failover re-plans the shards of a remote iterator on other owners once
+// its node is lost, and records the statistics of its stream.
+type failover struct {
+	client   *RemoteIteratorClient
+	nodeID   uint64
+	shardIDs []uint64
+	typ      influxql.DataType
+	opt      influxql.IteratorOptions
+
+	// lost are the nodes lost earlier in the query, which the shards
+	// cannot be re-planned on.
+	lost []uint64
+
+	// stream is the stream of the iterator, opened at start. Its
+	// statistics are recorded once it is closed, or lost and re-planned.
+	stream   *iteratorStreamReader
+	start    time.Time
+	recorded bool
+}
+
+// record records the statistics of the stream of f once.
+func (f *failover) record(stats influxql.IteratorStats, retried bool) {
+	if f.recorded {
+		return
+	}
+	f.recorded = true
+	f.client.stats.add(f.nodeID, stats, atomic.LoadInt64(&f.stream.n), time.Since(f.start), retried)
+}
+
+// newFailoverIterator returns input, a remote iterator of f, failing over
+// to the other owners of its shards as f allows.
+func newFailoverIterator(input influxql.Iterator, f *failover) influxql.Iterator {
+	switch input := input.(type) {
+	case influxql.FloatIterator:
+		return &floatFailoverIterator{input: input, f: f}
+	case influxql.IntegerIterator:
+		return &integerFailoverIterator{input: input, f: f}
+	case influxql.StringIterator:
+		return &stringFailoverIterator{input: input, f: f}
+	case influxql.BooleanIterator:
+		return &booleanFailoverIterator{input: input, f: f}
+	default:
+		return input
+	}
+}
+
+// replan returns an iterator over the shards of f on their surviving owners
+// if err means the node of f was lost after n points were read, with stats.
+// The points are read again from the new iterator, so they can only be
+// skipped if the stream is sorted; a *NodeLostError is returned otherwise,
+// or if re-planning is disabled or fails. Other errors are returned as is.
+func (f *failover) replan(err error, n int, stats influxql.IteratorStats) (influxql.Iterator, error) {
+	if !nodeLost(err) {
+		return nil, err
+	}
+	lostErr := &NodeLostError{NodeID: f.nodeID, Err: err}
+	if !f.client.ReplanLostNodes || (n > 0 && !f.opt.Ordered) {
+		return nil, lostErr
+	}
+
+	lost := append(append([]uint64(nil), f.lost...), f.nodeID)
+	shards, err := f.client.survivingOwners(f.shardIDs, lost)
+	if err != nil {
+		lostErr.Err = fmt.Errorf("%s, unable to re-plan shards: %s", lostErr.Err, err)
+		return nil, lostErr
+	}
+
+	nodeIDs := make([]uint64, 0, len(shards))
+	for id := range shards {
+		nodeIDs = append(nodeIDs, id)
+	}
+	sort.Sort(uint64Slice(nodeIDs))
+
+	var itrs influxql.Iterators
+	for _, id := range nodeIDs {
+		itr, err := f.client.createNodeIterator(id, shards[id], f.typ, f.opt, lost)
+		if err != nil {
+			itrs.Close()
+			lostErr.Err = fmt.Errorf("%s, unable to re-plan shards on node %d: %s", lostErr.Err, id, err)
+			return nil, lostErr
+		}
+		itrs = append(itrs, itr)
+	}
+	f.record(stats, true)
+	if len(itrs) == 1 {
+		return itrs[0], nil
+	} else if f.opt.Ordered {
+		return influxql.NewSortedMergeIterator(itrs, f.opt), nil
+	}
+	return influxql.NewMergeIterator(itrs, f.opt), nil
+}
+
+// survivingOwners returns shardIDs keyed by the owner each is read from
+// instead of the lost nodes, in QueryOwners order.
+func (c *RemoteIteratorClient) survivingOwners(shardIDs []uint64, lost []uint64) (map[uint64][]uint64, error) {
+	m, ok := c.MetaClient.(shardOwnerMetaClient)
+	if !ok {
+		return nil, fmt.Errorf("shard owners unknown")
+	}
+	var maintenance []uint64
+	if mm, ok := c.MetaClient.(maintenanceMetaClient); ok {
+		maintenance = mm.MaintenanceNodes()
+	}
+
+	shards := make(map[uint64][]uint64)
+	for _, id := range shardIDs {
+		_, _, si := m.ShardOwner(id)
+		var owner uint64
+		for _, nodeID := range QueryOwners(si.Owners, maintenance) {
+			if !containsUint64(lost, nodeID) {
+				owner = nodeID
+				break
+			}
+		}
+		if owner == 0 {
+			return nil, fmt.Errorf("no surviving owner of shard %d", id)
+		}
+		shards[owner] = append(shards[owner], id)
+	}
+	return shards, nil
+}
+
+// containsUint64 returns true if a contains v.
+func containsUint64(a []uint64, v uint64) bool {
+	for _, x := range a {
+		if x == v {
+			return true
+		}
+	}
+	return false
+}
+
+// floatFailoverIterator reads a remote float iterator, failing over once
+// its node is lost.
+type floatFailoverIterator struct {
+	input influxql.FloatIterator
+	f     *failover
+	n     int // The number of points read.
+}
+
+// Stats returns stats from the input iterator.
+func (itr *floatFailoverIterator) Stats() influxql.IteratorStats { return itr.input.Stats() }
+
+// Close records the statistics of the stream and closes the input iterator.
+func (itr *floatFailoverIterator) Close() error {
+	itr.f.record(itr.input.Stats(), false)
+	return itr.input.Close()
+}
+
+// Next returns the next point of the input iterator.
+func (itr *floatFailoverIterator) Next() (*influxql.FloatPoint, error) {
+	p, err := itr.input.Next()
+	if err != nil {
+		input, err := itr.f.replan(err, itr.n, itr.input.Stats())
+		if err != nil {
+			return nil, err
+		}
+		itr.input.Close()
+		itr.input = input.(influxql.FloatIterator)
+		for i := 0; i < itr.n; i++ {
+			if p, err := itr.input.Next(); err != nil || p == nil {
+				return nil, err
+			}
+		}
+		return itr.Next()
+	} else if p != nil {
+		itr.n++
+	}
+	return p, nil
+}
+
+// integerFailoverIterator reads a remote integer iterator, failing over
+// once its node is lost.
+type integerFailoverIterator struct {
+	input influxql.IntegerIterator
+	f     *failover
+	n     int // The number of points read.
+}
+
+// Stats returns stats from the input iterator.
+func (itr *integerFailoverIterator) Stats() influxql.IteratorStats { return itr.input.Stats() }
+
+// Close records the statistics of the stream and closes the input iterator.
+func (itr *integerFailoverIterator) Close() error {
+	itr.f.record(itr.input.Stats(), false)
+	return itr.input.Close()
+}
+
+// Next returns the next point of the input iterator.
+func (itr *integerFailoverIterator) Next() (*influxql.IntegerPoint, error) {
+	p, err := itr.input.Next()
+	if err != nil {
+		input, err := itr.f.replan(err, itr.n, itr.input.Stats())
+		if err != nil {
+			return nil, err
+		}
+		itr.input.Close()
+		itr.input = input.(influxql.IntegerIterator)
+		for i := 0; i < itr.n; i++ {
+			if p, err := itr.input.Next(); err != nil || p == nil {
+				return nil, err
+			}
+		}
+		return itr.Next()
+	} else if p != nil {
+		itr.n++
+	}
+	return p, nil
+}
+
+// stringFailoverIterator reads a remote string iterator, failing over once
+// its node is lost.
+type stringFailoverIterator struct {
+	input influxql.StringIterator
+	f     *failover
+	n     int // The number of points read.
+}
+
+// Stats returns stats from the input iterator.
+func (itr *stringFailoverIterator) Stats() influxql.IteratorStats { return itr.input.Stats() }
+
+// Close records the statistics of the stream and closes the input iterator.
+func (itr *stringFailoverIterator) Close() error {
+	itr.f.record(itr.input.Stats(), false)
+	return itr.input.Close()
+}
+
+// Next returns the next point of the input iterator.
+func (itr *stringFailoverIterator) Next() (*influxql.StringPoint, error) {
+	p, err := itr.input.Next()
+	if err != nil {
+		input, err := itr.f.replan(err, itr.n, itr.input.Stats())
+		if err != nil {
+			return nil, err
+		}
+		itr.input.Close()
+		itr.input = input.(influxql.StringIterator)
+		for i := 0; i < itr.n; i++ {
+			if p, err := itr.input.Next(); err != nil || p == nil {
+				return nil, err
+			}
+		}
+		return itr.Next()
+	} else if p != nil {
+		itr.n++
+	}
+	return p, nil
+}
+
+// booleanFailoverIterator reads a remote boolean iterator, failing over
+// once its node is lost.
+type booleanFailoverIterator struct {
+	input influxql.BooleanIterator
+	f     *failover
+	n     int // The number of points read.
+}
+
+// Stats returns stats from the input iterator.
+func (itr *booleanFailoverIterator) Stats() influxql.IteratorStats { return itr.input.Stats() }
+
+// Close records the statistics of the stream and closes the input iterator.
+func (itr *booleanFailoverIterator) Close() error {
+	itr.f.record(itr.input.Stats(), false)
+	return itr.input.Close()
+}
+
+// Next returns the next point of the input iterator.
+func (itr *booleanFailoverIterator) Next() (*influxql.BooleanPoint, error) {
+	p, err := itr.input.Next()
+	if err != nil {
+		input, err := itr.f.replan(err, itr.n, itr.input.Stats())
+		if err != nil {
+			return nil, err
+		}
+		itr.input.Close()
+		itr.input = input.(influxql.BooleanIterator)
+		for i := 0; i < itr.n; i++ {
+			if p, err := itr.input.Next(); err != nil || p == nil {
+				return nil, err
+			}
+		}
+		return itr.Next()
+	} else if p != nil {
+		itr.n++
+	}
+	return p, nil
+}
@@ -0,0 +1,161 @@
+package cluster_test
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/influxdata/influxdb/influxql"
+	"github.com/influxdata/influxdb/services/meta"
+	"github.com/zhexuany/influxcloud/cluster"
+	"github.com/zhexuany/influxcloud/tlv"
+)
+
+// Ensure the shards of a node lost mid-stream are re-planned on their other
+// owners, or the query fails with the node lost.
+func TestRemoteIteratorClient_CreateIterator_NodeLost(t *testing.T) {
+	store := MustOpenIteratorStore()
+	defer store.Close()
+	s := MustOpenIteratorService(cluster.Config{}, store)
+	defer s.Close()
+
+	// Node 1 streams the first points of node 2 before it is lost.
+	ln := MustListen("tcp", "127.0.0.1:0")
+	defer ln.Close()
+	go serveLostNode(ln, s.Addr().String(), 3)
+
+	mc := &ownersMetaClient{
+		hosts: nodeHosts{1: ln.Addr().String(), 2: s.Addr().String()},
+		owners: map[uint64][]meta.ShardOwner{
+			10: {{NodeID: 1}, {NodeID: 2}},
+			11: {{NodeID: 1}, {NodeID: 2}},
+		},
+	}
+	c := cluster.NewRemoteIteratorClient(time.Second)
+	c.MetaClient = mc
+
+	// read returns the points of an iterator over both shards of node id.
+	read := func(id uint64, opt influxql.IteratorOptions) ([]string, error) {
+		itr, err := c.CreateIterator(id, []uint64{10, 11}, influxql.Float, opt)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer itr.Close()
+
+		var a []string
+		for {
+			p, err := itr.(influxql.FloatIterator).Next()
+			if err != nil {
+				return a, err
+			} else if p == nil {
+				return a, nil
+			}
+			a = append(a, fmt.Sprintf("%s %d", p.Tags.ID(), p.Time))
+		}
+	}
+	exp, err := read(2, newIteratorOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := read(1, newIteratorOptions()); err == nil {
+		t.Fatal("expected error")
+	} else if e, ok := err.(*cluster.NodeLostError); !ok || e.NodeID != 1 {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	c.ReplanLostNodes = true
+	stats := cluster.NewRemoteQueryStats()
+	c = c.WithStats(stats)
+	if got, err := read(1, newIteratorOptions()); err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(got, exp) {
+		t.Fatalf("unexpected points: %v, expected %v", got, exp)
+	}
+	if a := stats.Nodes(); len(a) != 2 || a[0].NodeID != 1 || a[0].RetryN != 1 || a[1].NodeID != 2 || a[1].StreamN != 1 || a[1].RetryN != 0 {
+		t.Fatalf("unexpected statistics: %s", stats)
+	}
+
+	// Points read from unsorted streams cannot be skipped on other owners.
+	opt := newIteratorOptions()
+	opt.Ordered = false
+	if _, err := read(1, opt); err == nil {
+		t.Fatal("expected error")
+	} else if _, ok := err.(*cluster.NodeLostError); !ok {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Shards without surviving owners cannot be re-planned.
+	mc.owners[11] = []meta.ShardOwner{{NodeID: 1}}
+	if _, err := read(1, newIteratorOptions()); err == nil {
+		t.Fatal("expected error")
+	} else if _, ok := err.(*cluster.NodeLostError); !ok {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+// serveLostNode relays the iterator requests accepted on ln to the node at
+// host, and is lost after streaming back the first n points of each.
+func serveLostNode(ln net.Listener, host string, n int) {
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		go func(conn net.Conn) {
+			defer conn.Close()
+			upstream, err := net.Dial("tcp", host)
+			if err != nil {
+				return
+			}
+			defer upstream.Close()
+
+			var header [1]byte
+			if _, err := conn.Read(header[:]); err != nil {
+				return
+			} else if _, err := upstream.Write(header[:]); err != nil {
+				return
+			}
+			if typ, buf, err := tlv.ReadTLV(conn); err != nil {
+				return
+			} else if err := tlv.WriteTLV(upstream, typ, buf); err != nil {
+				return
+			}
+			if typ, buf, err := tlv.ReadTLV(upstream); err != nil {
+				return
+			} else if err := tlv.WriteTLV(conn, typ, buf); err != nil {
+				return
+			}
+
+			// Send the encoded points up to the end of the nth point.
+			_, buf, err := tlv.ReadTLV(upstream)
+			if err != nil {
+				return
+			}
+			r := bytes.NewReader(buf)
+			dec := influxql.NewFloatPointDecoder(r)
+			for i := 0; i < n; i++ {
+				if err := dec.DecodeFloatPoint(&influxql.FloatPoint{}); err != nil {
+					return
+				}
+			}
+			tlv.WriteTLV(conn, tlv.IteratorPointsMessage, buf[:len(buf)-r.Len()])
+		}(conn)
+	}
+}
+
+// ownersMetaClient is a meta client of the TCP hosts of data nodes and the
+// owners of shards.
+type ownersMetaClient struct {
+	hosts  nodeHosts
+	owners map[uint64][]meta.ShardOwner
+}
+
+func (m *ownersMetaClient) DataNode(id uint64) (*meta.NodeInfo, error) { return m.hosts.DataNode(id) }
+
+func (m *ownersMetaClient) ShardOwner(shardID uint64) (string, string, meta.ShardInfo) {
+	return "db0", "rp0", meta.ShardInfo{ID: shardID, Owners: m.owners[shardID]}
+}
@@ -0,0 +1,210 @@
+package cluster
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/uber-go/zap"
+)
+
+// Actions taken on orphan shards once their grace period has passed.
+const (
+	// OrphanShardReport only reports orphan shards.
+	OrphanShardReport = "report"
+
+	// OrphanShardDelete deletes orphan shards.
+	OrphanShardDelete = "delete"
+
+	// OrphanShardArchive uploads a snapshot of orphan shards to the
+	// SnapshotSink, then deletes them. Shards that fail to upload are kept.
+	OrphanShardArchive = "archive"
+)
+
+// orphanReportN is the number of handled orphan shards kept for the report.
+const orphanReportN = 100
+
+// OrphanShard is a shard stored on this node that the meta store does not
+// assign to it, e.g. after the shard was moved to another node or its
+// retention policy was dropped.
+type OrphanShard struct {
+	ID              uint64    `json:"id"`
+	Database        string    `json:"database"`
+	RetentionPolicy string    `json:"retentionPolicy"`
+	Size            int64     `json:"size"`
+	Detected        time.Time `json:"detected"`
+
+	// Action is the action taken once the grace period passed, or empty if
+	// it has not passed yet.
+	Action  string    `json:"action,omitempty"`
+	Handled time.Time `json:"handled,omitempty"`
+	Err     string    `json:"error,omitempty"`
+}
+
+// orphanShards detects the orphan shards of a node, and acts on them once
+// they have been orphaned for longer than grace.
+type orphanShards struct {
+	interval time.Duration
+	grace    time.Duration
+	action   string
+
+	mu      sync.Mutex
+	pending map[uint64]*OrphanShard
+	handled []OrphanShard // The last orphanReportN, oldest first.
+}
+
+// newOrphanShards returns orphan shard detection configured by c.
+func newOrphanShards(c Config) *orphanShards {
+	// Shards are only reported unless a known action is configured.
+	action := c.OrphanShardAction
+	if action != OrphanShardDelete && action != OrphanShardArchive {
+		action = OrphanShardReport
+	}
+	return &orphanShards{
+		interval: time.Duration(c.OrphanShardCheckInterval),
+		grace:    time.Duration(c.OrphanShardGracePeriod),
+		action:   action,
+		pending:  make(map[uint64]*OrphanShard),
+	}
+}
+
+// report returns the shards currently orphaned, in order of ID, followed by
+// the orphan shards last acted on.
+func (o *orphanShards) report() []OrphanShard {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	a := make([]OrphanShard, 0, len(o.pending)+len(o.handled))
+	for _, sh := range o.pending {
+		a = append(a, *sh)
+	}
+	sort.Sort(orphanShardList(a))
+	return append(a, o.handled...)
+}
+
+// runOrphanShardCheck checks for orphan shards every interval until the
+// service is closed.
+func (s *Service) runOrphanShardCheck() {
+	defer s.wg.Done()
+
+	t := time.NewTicker(s.orphans.interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+			if err := s.checkOrphanShards(time.Now()); err != nil {
+				s.Logger.Info("orphan shard check failed", zap.Error(err))
+			}
+		case <-s.closing:
+			return
+		}
+	}
+}
+
+// checkOrphanShards records the local shards not assigned to this node in the
+// meta store, and acts on those orphaned since before now minus the grace
+// period. Shards assigned to this node again are no longer orphans.
+func (s *Service) checkOrphanShards(now time.Time) error {
+	if s.ShardStore == nil || s.Node == nil {
+		return nil
+	}
+
+	// Never act on shards without a view of the meta store.
+	infos, err := s.shardInfos()
+	if err != nil {
+		return err
+	}
+	owned := make(map[uint64]bool)
+	for _, si := range infos {
+		for _, id := range si.Owners {
+			if id == s.Node.ID {
+				owned[si.ID] = true
+			}
+		}
+	}
+
+	local := make(map[uint64]bool)
+	var expired []*OrphanShard
+	s.orphans.mu.Lock()
+	for _, id := range s.ShardStore.ShardIDs() {
+		local[id] = true
+		if owned[id] {
+			continue
+		}
+
+		orphan := s.orphans.pending[id]
+		if orphan == nil {
+			sh := s.ShardStore.Shard(id)
+			if sh == nil {
+				continue
+			}
+			orphan = &OrphanShard{ID: id, Detected: now}
+			orphan.Size, _ = sh.DiskSize()
+			orphan.RetentionPolicy = filepath.Base(filepath.Dir(sh.Path()))
+			orphan.Database = filepath.Base(filepath.Dir(filepath.Dir(sh.Path())))
+			s.orphans.pending[id] = orphan
+			s.Logger.Info("detected orphan shard", zap.Uint64("shardID", id), zap.String("database", orphan.Database), zap.String("retentionPolicy", orphan.RetentionPolicy))
+		}
+		if s.orphans.action != OrphanShardReport && now.Sub(orphan.Detected) >= s.orphans.grace {
+			expired = append(expired, orphan)
+		}
+	}
+	for id := range s.orphans.pending {
+		if owned[id] || !local[id] {
+			delete(s.orphans.pending, id)
+		}
+	}
+	s.orphans.mu.Unlock()
+
+	for _, orphan := range expired {
+		s.handleOrphanShard(orphan, now)
+	}
+	return nil
+}
+
+// handleOrphanShard archives and deletes, or deletes, an orphan shard. The
+// shard stays pending if that fails, so that it is retried on the next
+// check.
+func (s *Service) handleOrphanShard(orphan *OrphanShard, now time.Time) {
+	err := s.removeOrphanShard(orphan.ID)
+
+	s.orphans.mu.Lock()
+	defer s.orphans.mu.Unlock()
+	if err != nil {
+		orphan.Err = err.Error()
+		s.Logger.Info("orphan shard action failed", zap.String("action", s.orphans.action), zap.Uint64("shardID", orphan.ID), zap.Error(err))
+		return
+	}
+
+	handled := *orphan
+	handled.Action, handled.Handled, handled.Err = s.orphans.action, now, ""
+	delete(s.orphans.pending, orphan.ID)
+	s.orphans.handled = append(s.orphans.handled, handled)
+	if n := len(s.orphans.handled); n > orphanReportN {
+		s.orphans.handled = s.orphans.handled[n-orphanReportN:]
+	}
+	s.Logger.Info("handled orphan shard", zap.String("action", s.orphans.action), zap.Uint64("shardID", orphan.ID))
+}
+
+// removeOrphanShard removes the orphan shard id as configured.
+func (s *Service) removeOrphanShard(id uint64) error {
+	if s.orphans.action == OrphanShardArchive {
+		key := fmt.Sprintf("orphans/%d/%d.tar", s.Node.ID, id)
+		if _, err := s.uploadShardSnapshot(id, key); err != nil {
+			return err
+		}
+	}
+	if err := s.TSDBStore.DeleteShard(id); err != nil {
+		return err
+	}
+	s.Events.Publish(Event{Type: EventShardDropped, NodeID: s.Node.ID, ShardID: id, Message: "orphan"})
+	return nil
+}
+
+type orphanShardList []OrphanShard
+
+func (a orphanShardList) Len() int           { return len(a) }
+func (a orphanShardList) Less(i, j int) bool { return a[i].ID < a[j].ID }
+func (a orphanShardList) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
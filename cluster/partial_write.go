@@ -0,0 +1,165 @@
+package cluster
+
+import (
+	"fmt"
+
+	"github.com/influxdata/influxdb/influxql"
+	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxdb/tsdb"
+	"github.com/uber-go/zap"
+)
+
+// Partial write policies, deciding how a write is handled when some of its
+// points fail validation or are rejected by the store. If no policy is set,
+// writes breaking a validation rule are rejected whole, and the points the
+// store rejects are dropped as with PartialWriteAcceptPartial.
+const (
+	// PartialWriteRejectAll writes none of the points of a shard if any are
+	// rejected, and none of the points of the write if any fail validation
+	// or are beyond the retention policy. The other shards of a write may
+	// still be written, as the points of the shards of other nodes are only
+	// checked once they are sent.
+	PartialWriteRejectAll = "reject-all"
+
+	// PartialWriteAcceptPartial writes the valid points, and returns a
+	// tsdb.PartialWriteError counting the others.
+	PartialWriteAcceptPartial = "accept-partial"
+
+	// PartialWriteAcceptAndLog writes the valid points and logs the others,
+	// reporting the write as successful.
+	PartialWriteAcceptAndLog = "accept-and-log"
+)
+
+// PartialWritePolicies returns the partial write policies of the databases
+// overriding PartialWritePolicy, by database.
+func (c Config) PartialWritePolicies() map[string]string {
+	m := make(map[string]string)
+	for _, db := range c.Databases {
+		if db.PartialWritePolicy != "" {
+			m[db.Name] = db.PartialWritePolicy
+		}
+	}
+	return m
+}
+
+// validatePartialWritePolicy returns an error if policy is not a partial
+// write policy.
+func validatePartialWritePolicy(policy string) error {
+	switch policy {
+	case "", PartialWriteRejectAll, PartialWriteAcceptPartial, PartialWriteAcceptAndLog:
+		return nil
+	}
+	return fmt.Errorf("unknown partial write policy: %q", policy)
+}
+
+// partialWritePolicies are the partial write policies of the databases of
+// a data node.
+type partialWritePolicies struct {
+	policy    string
+	databases map[string]string
+}
+
+// newPartialWritePolicies returns the partial write policies of c.
+func newPartialWritePolicies(c Config) partialWritePolicies {
+	return partialWritePolicies{policy: c.PartialWritePolicy, databases: c.PartialWritePolicies()}
+}
+
+// get returns the partial write policy of database.
+func (p partialWritePolicies) get(database string) string {
+	if policy, ok := p.databases[database]; ok {
+		return policy
+	}
+	return p.policy
+}
+
+// partialWritePolicy returns the partial write policy of database.
+func (w *PointsWriter) partialWritePolicy(database string) string {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return partialWritePolicies{policy: w.PartialWritePolicy, databases: w.DatabasePartialWritePolicies}.get(database)
+}
+
+// acceptPartialWrite returns err, the result of a write to database, as the
+// partial write policy of the database reports it: the points dropped by a
+// tsdb.PartialWriteError are logged rather than returned if the policy is
+// PartialWriteAcceptAndLog.
+func (w *PointsWriter) acceptPartialWrite(requestID, database string, err error) error {
+	e, ok := err.(tsdb.PartialWriteError)
+	if !ok || w.partialWritePolicy(database) != PartialWriteAcceptAndLog {
+		return err
+	}
+	w.Logger.Warn("points of write dropped", zap.String("requestID", requestID), zap.String("database", database), zap.Int("dropped", e.Dropped), zap.String("reason", e.Reason))
+	return nil
+}
+
+// addPartialWrite adds the points dropped by partial, a partial write error,
+// to err, the result of writing the other points.
+func addPartialWrite(err, partial error) error {
+	p, ok := partial.(tsdb.PartialWriteError)
+	if !ok {
+		return err
+	}
+	switch e := err.(type) {
+	case nil:
+		return p
+	case tsdb.PartialWriteError:
+		e.Reason = fmt.Sprintf("%s; %s", e.Reason, p.Reason)
+		e.Dropped += p.Dropped
+		return e
+	}
+	return err
+}
+
+// rejectedWrite returns the error of a write of n points rejected whole by
+// the PartialWriteRejectAll policy because of err.
+func rejectedWrite(err error, n int) error {
+	return tsdb.PartialWriteError{Reason: fmt.Sprintf("write rejected by %s partial write policy: %s", PartialWriteRejectAll, err), Dropped: n}
+}
+
+// shardFieldsStore is implemented by stores that expose their shards, so that
+// the fields of a shard can be checked before points are written to it.
+type shardFieldsStore interface {
+	Shard(id uint64) *tsdb.Shard
+}
+
+// conflictingPoints returns the indices of the points with a field of
+// another type than the field of the shard shardID of store, and why the
+// first conflicts. ok is false if the fields of the shard are unknown, e.g.
+// because store does not expose its shards or the shard does not exist.
+func conflictingPoints(store interface{}, shardID uint64, points []models.Point) (a []int, reason string, ok bool) {
+	s, ok := store.(shardFieldsStore)
+	if !ok {
+		return nil, "", false
+	}
+	sh := s.Shard(shardID)
+	if sh == nil {
+		return nil, "", false
+	}
+
+	fieldsByName := make(map[string]map[string]influxql.DataType)
+	for i, p := range points {
+		fields, ok := fieldsByName[p.Name()]
+		if !ok {
+			var err error
+			if fields, _, err = sh.FieldDimensions([]string{p.Name()}); err != nil {
+				return nil, "", false
+			}
+			fieldsByName[p.Name()] = fields
+		}
+
+		values, err := p.Fields()
+		if err != nil {
+			return nil, "", false
+		}
+		for k, v := range values {
+			if typ, ok := fields[k]; ok && typ != influxql.InspectDataType(v) {
+				if a == nil {
+					reason = fmt.Sprintf("%s: input field %q on measurement %q is type %s, already exists as type %s", tsdb.ErrFieldTypeConflict, k, p.Name(), influxql.InspectDataType(v), typ)
+				}
+				a = append(a, i)
+				break
+			}
+		}
+	}
+	return a, reason, true
+}
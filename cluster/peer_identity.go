@@ -0,0 +1,130 @@
+package cluster
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+
+	"github.com/influxdata/influxdb/services/meta"
+)
+
+// errNoPeerRegistry is returned for mutual TLS connections to a service
+// without a meta client, as the data nodes their certificates must be valid
+// for are not known.
+var errNoPeerRegistry = errors.New("no meta client to verify peer identity against")
+
+// PeerIdentityError is returned when the certificate a node presents over
+// mutual TLS does not match the node ID it claims to be, or any registered
+// data node if NodeID is zero.
+type PeerIdentityError struct {
+	NodeID uint64
+	Reason string
+}
+
+func (e *PeerIdentityError) Error() string {
+	if e.NodeID == 0 {
+		return fmt.Sprintf("peer identity mismatch: %s", e.Reason)
+	}
+	return fmt.Sprintf("peer identity mismatch for node %d: %s", e.NodeID, e.Reason)
+}
+
+// peerCertificate returns the certificate the peer of conn presented, or nil
+// if conn is not a mutual TLS connection.
+func peerCertificate(conn net.Conn) *x509.Certificate {
+	c, ok := conn.(interface {
+		ConnectionState() tls.ConnectionState
+	})
+	if !ok {
+		return nil
+	}
+	if certs := c.ConnectionState().PeerCertificates; len(certs) > 0 {
+		return certs[0]
+	}
+	return nil
+}
+
+// checkPeerIdentity returns a *PeerIdentityError unless cert is valid for
+// the host nodeID is registered with in nodes, so that a node holding a
+// certificate of the cluster cannot pass itself off as another node.
+func checkPeerIdentity(cert *x509.Certificate, nodeID uint64, nodes []meta.NodeInfo) error {
+	var ni *meta.NodeInfo
+	for i := range nodes {
+		if nodes[i].ID == nodeID {
+			ni = &nodes[i]
+			break
+		}
+	}
+	if ni == nil {
+		return &PeerIdentityError{NodeID: nodeID, Reason: "node not registered in meta"}
+	}
+
+	host, _, err := net.SplitHostPort(ni.TCPHost)
+	if err != nil {
+		host = ni.TCPHost
+	}
+	if err := cert.VerifyHostname(host); err != nil {
+		return &PeerIdentityError{
+			NodeID: nodeID,
+			Reason: fmt.Sprintf("certificate of %q is not valid for registered address %s", cert.Subject.CommonName, ni.TCPHost),
+		}
+	}
+	return nil
+}
+
+// identifyPeer checks the certificate the peer of conn presented, if any, is
+// valid for a registered data node, so that every mutual TLS connection is
+// from a node of the cluster before any message is read from it. Mismatches
+// are published on the event bus as security events.
+func (s *Service) identifyPeer(conn net.Conn) error {
+	cert := peerCertificate(conn)
+	if cert == nil {
+		return nil
+	} else if s.MetaClient == nil {
+		return errNoPeerRegistry
+	}
+
+	nodes, err := s.MetaClient.DataNodes()
+	if err != nil {
+		return err
+	}
+	for _, n := range nodes {
+		if checkPeerIdentity(cert, n.ID, nodes) == nil {
+			return nil
+		}
+	}
+
+	err = &PeerIdentityError{Reason: fmt.Sprintf("certificate of %q is not valid for any registered data node", cert.Subject.CommonName)}
+	s.Events.Publish(Event{Type: EventPeerIdentityMismatch, Message: fmt.Sprintf("%s from %s", err, conn.RemoteAddr())})
+	return err
+}
+
+// verifyPeerIdentity checks the certificate the peer of conn presented, if
+// any, against the node nodeID it claims to be in its hello message. A
+// mutual TLS peer must claim to be a node. Mismatches are published on the
+// event bus as security events.
+func (s *Service) verifyPeerIdentity(conn net.Conn, nodeID uint64) error {
+	cert := peerCertificate(conn)
+	if cert == nil {
+		return nil
+	} else if s.MetaClient == nil {
+		return errNoPeerRegistry
+	}
+
+	var err error
+	if nodeID == 0 {
+		err = &PeerIdentityError{Reason: fmt.Sprintf("certificate of %q presented without a node ID", cert.Subject.CommonName)}
+	} else {
+		var nodes []meta.NodeInfo
+		if nodes, err = s.MetaClient.DataNodes(); err != nil {
+			return err
+		}
+		err = checkPeerIdentity(cert, nodeID, nodes)
+	}
+	if err != nil {
+		s.Events.Publish(Event{Type: EventPeerIdentityMismatch, NodeID: nodeID, Message: fmt.Sprintf("%s from %s", err, conn.RemoteAddr())})
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package cluster
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/influxdata/influxdb/services/meta"
+)
+
+// mustCreateCertificate returns a self-signed certificate valid for hosts.
+func mustCreateCertificate(t *testing.T, cn string, hosts ...string) *x509.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth, x509.ExtKeyUsageServerAuth},
+	}
+	for _, h := range hosts {
+		if ip := net.ParseIP(h); ip != nil {
+			tmpl.IPAddresses = append(tmpl.IPAddresses, ip)
+		} else {
+			tmpl.DNSNames = append(tmpl.DNSNames, h)
+		}
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert
+}
+
+// Ensure peer certificates are only accepted for the address registered for
+// the node they claim to be.
+func TestCheckPeerIdentity(t *testing.T) {
+	nodes := []meta.NodeInfo{
+		{ID: 1, TCPHost: "data1.example.com:8088"},
+		{ID: 2, TCPHost: "10.0.0.2:8088"},
+	}
+	data1 := mustCreateCertificate(t, "data1", "data1.example.com")
+	data2 := mustCreateCertificate(t, "data2", "10.0.0.2")
+
+	if err := checkPeerIdentity(data1, 1, nodes); err != nil {
+		t.Fatal(err)
+	} else if err := checkPeerIdentity(data2, 2, nodes); err != nil {
+		t.Fatal(err)
+	}
+
+	// A node cannot pass itself off as another node, or an unknown one.
+	for _, tt := range []struct {
+		cert   *x509.Certificate
+		nodeID uint64
+	}{
+		{data1, 2},
+		{data2, 1},
+		{data1, 3},
+	} {
+		err := checkPeerIdentity(tt.cert, tt.nodeID, nodes)
+		if e, ok := err.(*PeerIdentityError); !ok {
+			t.Fatalf("expected identity error for %s as node %d, got %v", tt.cert.Subject.CommonName, tt.nodeID, err)
+		} else if e.NodeID != tt.nodeID {
+			t.Fatalf("unexpected node: %d", e.NodeID)
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package cluster
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"time"
+
+	"github.com/uber-go/zap"
+	"github.com/zhexuany/influxcloud/rpc"
+	"github.com/zhexuany/influxcloud/tlv"
+)
+
+// pipeline processes requests received on a connection concurrently, and
+// writes their responses in the order the requests were received, as the
+// protocol has no way to match them otherwise. A slow request, such as an
+// iterator, does not hold up the requests received after it, only their
+// responses.
+type pipeline struct {
+	conn   net.Conn
+	logger zap.Logger
+
+	// The responses of the requests in flight, in the order the requests
+	// were received. Its capacity bounds the requests in flight.
+	responses chan chan pipelineResponse
+	done      chan struct{}
+}
+
+// pipelineResponse is the response of a request processed by a pipeline.
+type pipelineResponse struct {
+	name string
+	buf  []byte
+	err  error
+
+	// flushed is closed once the responses before it are written, if set.
+	flushed chan struct{}
+}
+
+// newPipeline returns a pipeline writing responses to conn, with up to n
+// requests in flight.
+func newPipeline(conn net.Conn, n int, logger zap.Logger) *pipeline {
+	if n < 1 {
+		n = 1
+	}
+	p := &pipeline{
+		conn:      conn,
+		logger:    logger,
+		responses: make(chan chan pipelineResponse, n),
+		done:      make(chan struct{}),
+	}
+	go p.run()
+	return p
+}
+
+// run writes responses until the pipeline is closed. The connection is
+// closed once a request fails, and later responses are discarded.
+func (p *pipeline) run() {
+	defer close(p.done)
+
+	failed := false
+	for ch := range p.responses {
+		resp := <-ch
+		if resp.flushed != nil {
+			close(resp.flushed)
+			continue
+		} else if failed {
+			continue
+		}
+
+		if _, err := p.conn.Write(resp.buf); err != nil {
+			p.logger.Warn("unable to write response", zap.String("type", resp.name), zap.Error(err))
+			failed = true
+		} else if resp.err != nil {
+			p.logger.Warn("request failed", zap.String("type", resp.name), zap.Error(resp.err))
+			failed = true
+		}
+		if failed {
+			p.conn.Close()
+		}
+	}
+}
+
+// process processes the request buf with h in its own goroutine. It blocks
+// while the maximum number of requests are in flight.
+func (p *pipeline) process(s *Service, h registeredHandler, buf []byte, carrier map[string]string) {
+	ch := make(chan pipelineResponse, 1)
+	p.responses <- ch
+
+	go func() {
+		span := startRemoteSpan(s.SpanTracer, h.name, carrier)
+		defer span.Finish()
+
+		start := time.Now()
+		c := &pipelineConn{Conn: p.conn, r: bytes.NewReader(buf)}
+		err := s.call(h, c)
+		if e, ok := err.(*panicError); ok {
+			// The request was read in full, so the connection can still
+			// be used once the partial response is replaced by the error.
+			c.w.Reset()
+			err = writeErrorResponse(&c.w, rpc.CodeInternal, e)
+		} else if err == nil {
+			s.Metrics.observeRPC(h.name, time.Since(start))
+		}
+		ch <- pipelineResponse{name: h.name, buf: c.w.Bytes(), err: err}
+	}()
+}
+
+// flush waits for the responses of the requests in flight to be written.
+func (p *pipeline) flush() {
+	flushed := make(chan struct{})
+	ch := make(chan pipelineResponse, 1)
+	ch <- pipelineResponse{flushed: flushed}
+	p.responses <- ch
+	<-flushed
+}
+
+// close waits for the requests in flight to be answered, and stops the
+// pipeline.
+func (p *pipeline) close() {
+	close(p.responses)
+	<-p.done
+}
+
+// pipelineConn is the connection a request processed by a pipeline is
+// handled with. It reads the request read ahead from the connection, and
+// buffers the response until it can be written.
+type pipelineConn struct {
+	net.Conn
+	r io.Reader
+	w bytes.Buffer
+}
+
+func (c *pipelineConn) Read(p []byte) (int, error)  { return c.r.Read(p) }
+func (c *pipelineConn) Write(p []byte) (int, error) { return c.w.Write(p) }
+
+// readRequest reads the length-value of a request from conn, so that it can
+// be processed by a pipeline. A *tlv.FrameTooLargeError is returned if the
+// request is larger than max.
+func readRequest(conn net.Conn, max int64) ([]byte, error) {
+	buf, err := tlv.ReadLVMax(conn, max)
+	if err != nil {
+		return nil, err
+	}
+
+	var b bytes.Buffer
+	if err := tlv.WriteLV(&b, buf); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
@@ -0,0 +1,169 @@
+package cluster
+
+import (
+	"fmt"
+	"sync/atomic"
+
+	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxdb/tsdb"
+)
+
+// Names of the point validation rules, as reported in a PointValidationError.
+const (
+	ruleMaxTagsPerPoint      = "max-tags-per-point"
+	ruleMaxFieldValueSize    = "max-field-value-size"
+	ruleBannedMeasurement    = "banned-measurement"
+	ruleBannedTag            = "banned-tag"
+	ruleMaxSeriesPerDatabase = "max-series-per-database"
+)
+
+// PointValidationError is returned for writes rejected by the point
+// validation of a PointsWriter. None of the points of a rejected write are
+// written.
+type PointValidationError struct {
+	Rule        string // The rule broken, e.g. "max-tags-per-point".
+	Database    string
+	Measurement string // Empty if the rule applies to the whole write.
+	Reason      string
+}
+
+// Error returns a string representation of the error.
+func (e *PointValidationError) Error() string {
+	if e.Measurement == "" {
+		return fmt.Sprintf("write to database %q rejected by %s: %s", e.Database, e.Rule, e.Reason)
+	}
+	return fmt.Sprintf("point of measurement %q in database %q rejected by %s: %s", e.Measurement, e.Database, e.Rule, e.Reason)
+}
+
+// SeriesCardinality reports the number of series of a database across the
+// cluster.
+type SeriesCardinality interface {
+	SeriesN(database string) (int64, error)
+}
+
+// validationEnabled returns true if points are validated before they are
+// written.
+func (w *PointsWriter) validationEnabled() bool {
+	return w.MaxTagsPerPoint > 0 || w.MaxFieldValueSize > 0 ||
+		len(w.BannedMeasurements) > 0 || len(w.BannedTags) > 0 ||
+		(w.MaxSeriesPerDatabase > 0 && w.Cardinality != nil)
+}
+
+// validatePoints returns the points of a write to database to be written,
+// and a *PointValidationError for the first that breaks a validation rule.
+// The whole write is rejected, unless the partial write policy of database
+// accepts partial writes: the points breaking a rule are then dropped, and
+// counted by a tsdb.PartialWriteError returned with the others. A write is
+// still rejected if none of its points are valid.
+func (w *PointsWriter) validatePoints(database string, points []models.Point) ([]models.Point, error) {
+	if !w.validationEnabled() {
+		return points, nil
+	}
+
+	var err error
+	switch w.partialWritePolicy(database) {
+	case PartialWriteAcceptPartial, PartialWriteAcceptAndLog:
+		var valid []models.Point
+		if valid, err = w.dropInvalidPoints(database, points); err == nil || len(valid) > 0 {
+			if dropped := len(points) - len(valid); dropped > 0 {
+				atomic.AddInt64(&w.stats.PointWriteRejected, int64(dropped))
+				err = tsdb.PartialWriteError{Reason: err.Error(), Dropped: dropped}
+			}
+			return valid, err
+		}
+	default:
+		err = w.checkPoints(database, points)
+	}
+	if err != nil {
+		atomic.AddInt64(&w.stats.WriteRejected, 1)
+		atomic.AddInt64(&w.stats.PointWriteRejected, int64(len(points)))
+	}
+	return points, err
+}
+
+// checkPoints checks points of database against each validation rule.
+func (w *PointsWriter) checkPoints(database string, points []models.Point) error {
+	if err := w.checkSeriesLimit(database); err != nil {
+		return err
+	}
+
+	for _, p := range points {
+		if reason, rule := w.checkPoint(p); rule != "" {
+			return &PointValidationError{Rule: rule, Database: database, Measurement: p.Name(), Reason: reason}
+		}
+	}
+	return nil
+}
+
+// dropInvalidPoints returns the points of database that break no validation
+// rule, and a *PointValidationError for the first that does. None are valid
+// if the database breaks the series limit.
+func (w *PointsWriter) dropInvalidPoints(database string, points []models.Point) ([]models.Point, error) {
+	if err := w.checkSeriesLimit(database); err != nil {
+		return nil, err
+	}
+
+	var first error
+	valid := make([]models.Point, 0, len(points))
+	for _, p := range points {
+		if reason, rule := w.checkPoint(p); rule != "" {
+			if first == nil {
+				first = &PointValidationError{Rule: rule, Database: database, Measurement: p.Name(), Reason: reason}
+			}
+			continue
+		}
+		valid = append(valid, p)
+	}
+	return valid, first
+}
+
+// checkSeriesLimit returns a *PointValidationError if database already has
+// MaxSeriesPerDatabase series.
+func (w *PointsWriter) checkSeriesLimit(database string) error {
+	if w.MaxSeriesPerDatabase <= 0 || w.Cardinality == nil {
+		return nil
+	}
+	n, err := w.Cardinality.SeriesN(database)
+	if err != nil {
+		return err
+	} else if n >= w.MaxSeriesPerDatabase {
+		return &PointValidationError{
+			Rule:     ruleMaxSeriesPerDatabase,
+			Database: database,
+			Reason:   fmt.Sprintf("database has %d series, limit is %d", n, w.MaxSeriesPerDatabase),
+		}
+	}
+	return nil
+}
+
+// checkPoint returns the rule p breaks and why, or empty strings if none.
+func (w *PointsWriter) checkPoint(p models.Point) (reason, rule string) {
+	for _, name := range w.BannedMeasurements {
+		if p.Name() == name {
+			return "measurement is banned", ruleBannedMeasurement
+		}
+	}
+
+	tags := p.Tags()
+	if w.MaxTagsPerPoint > 0 && len(tags) > w.MaxTagsPerPoint {
+		return fmt.Sprintf("%d tags exceed the limit of %d", len(tags), w.MaxTagsPerPoint), ruleMaxTagsPerPoint
+	}
+	for _, key := range w.BannedTags {
+		if tags.Get([]byte(key)) != nil {
+			return fmt.Sprintf("tag %q is banned", key), ruleBannedTag
+		}
+	}
+
+	if w.MaxFieldValueSize > 0 {
+		iter := p.FieldIterator()
+		for iter.Next() {
+			if iter.Type() != models.String {
+				continue
+			}
+			if n := len(iter.StringValue()); n > w.MaxFieldValueSize {
+				return fmt.Sprintf("field %q of %d bytes exceeds the limit of %d", iter.FieldKey(), n, w.MaxFieldValueSize), ruleMaxFieldValueSize
+			}
+		}
+	}
+	return "", ""
+}
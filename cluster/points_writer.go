@@ -6,13 +6,17 @@ import (
 	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/influxdata/influxdb/coordinator"
 	"github.com/influxdata/influxdb/models"
 	"github.com/influxdata/influxdb/services/meta"
+	"github.com/influxdata/influxdb/tsdb"
 	"github.com/uber-go/zap"
 	"github.com/zhexuany/influxcloud"
+	cloudMeta "github.com/zhexuany/influxcloud/meta"
+	"github.com/zhexuany/influxcloud/rpc"
 )
 
 var (
@@ -25,35 +29,205 @@ var (
 
 	// ErrWriteFailed is returned when no writes succeeded.
 	ErrWriteFailed = errors.New("write failed")
+
+	// ErrWriteCanceled is returned for a remote write at consistency ANY that
+	// was no longer needed, since another owner of the shard was written to
+	// first. The write is queued in hinted handoff instead.
+	ErrWriteCanceled = errors.New("write canceled")
+
+	// ErrReplicationPaused is returned when writes to a node have been paused
+	// by an operator. The write is queued in hinted handoff instead.
+	ErrReplicationPaused = errors.New("replication paused")
+
+	// ErrNodeMaintenance is returned for writes to a node in maintenance
+	// mode. The write is queued in hinted handoff instead.
+	ErrNodeMaintenance = errors.New("node in maintenance")
+
+	// ErrDraining is returned when a node is draining and no longer accepts
+	// new writes.
+	ErrDraining = errors.New("node is draining")
+
+	// ErrReadOnly is returned when a node is read-only and rejects the
+	// writes other nodes send it.
+	ErrReadOnly = errors.New("node is read-only")
+
+	// ErrWriteOutOfBounds is returned when a write contains points outside
+	// the accepted write window and such writes are rejected.
+	ErrWriteOutOfBounds = errors.New("point time outside write window")
+)
+
+// The statistics generated by the "write" module.
+const (
+	statWriteReq            = "req"
+	statPointWriteReq       = "pointReq"
+	statPointWriteReqLocal  = "pointReqLocal"
+	statPointWriteReqRemote = "pointReqRemote"
+	statPointWriteReqHH     = "pointReqHH"
+	statWriteOK             = "writeOk"
+	statWriteDrop           = "writeDrop"
+	statWriteTimeout        = "writeTimeout"
+	statWritePartial        = "writePartial"
+	statWriteErr            = "writeError"
+	statSubWriteOK          = "subWriteOk"
+	statSubWriteDrop        = "subWriteDrop"
+	statWriteRetry          = "writeRetry"
+	statWriteOutOfBounds    = "writeOutOfBounds"
+	statWriteForward        = "writeForward"
+	statWriteForwardReq     = "writeForwardReq"
+	statWriteForwardFailed  = "writeForwardFail"
+	statPointWriteReqFwd    = "pointReqForward"
+	statWriteLate           = "writeLate"
+	statWriteRejected       = "writeRejected"
+	statPointWriteRejected  = "pointWriteRejected"
+	statWriteHedge          = "writeHedge"
+	statWriteCancel         = "writeCancel"
 )
 
 // PointsWriter handles writes across multiple local and remote data nodes.
 type PointsWriter struct {
-	mu           sync.RWMutex
-	closing      chan struct{}
-	WriteTimeout time.Duration
-	Logger       zap.Logger
+	mu      sync.RWMutex
+	closing chan struct{}
+
+	// WriteTimeout bounds a whole write request, however many shards and
+	// owners it spans. OwnerWriteTimeout bounds each attempt to write to a
+	// shard owner within what is left of the request; zero leaves attempts
+	// bounded by the request alone.
+	WriteTimeout      time.Duration
+	OwnerWriteTimeout time.Duration
+
+	// WriteHedgeDelay is how long writes at consistency ONE or ANY wait for
+	// the first owner of a shard, the local one if any, before also writing
+	// to the other owners. The other owners are written to right away if the
+	// first one fails, and in the background once it succeeded, so that the
+	// caller only waits on one owner in the common case. Every owner is
+	// written to at once if zero.
+	WriteHedgeDelay time.Duration
+
+	Logger zap.Logger
+
+	// Events receives an EventWritePartial for each partial shard write, if
+	// set.
+	Events *EventBus
+
+	// IteratorCache has the entries of the time ranges written to removed,
+	// if set.
+	IteratorCache *IteratorCache
 
 	Node *influxcloud.Node
 
-	MetaClient interface {
-		Database(name string) (di *meta.DatabaseInfo)
-		RetentionPolicy(database, policy string) (*meta.RetentionPolicyInfo, error)
-		CreateShardGroup(database, policy string, timestamp time.Time) (*meta.ShardGroupInfo, error)
-	}
+	MetaClient MetaClient
 
 	TSDBStore interface {
 		CreateShard(database, retentionPolicy string, shardID uint64) error
 		WriteToShard(shardID uint64, points []models.Point) error
 	}
 
+	// ShardWriter and HintedHandoff receive points encoded once per shard,
+	// which are shared by every remote owner of the shard.
 	ShardWriter interface {
-		WriteShard(shardID, ownerID uint64, points []models.Point) error
+		WriteEncodedShard(span Span, requestID string, deadline time.Time, shardID, ownerID uint64, points *rpc.EncodedPoints) error
 	}
 
 	HintedHandoff interface {
-		WriteShard(shardID, ownerID uint64, points []models.Point) error
+		WriteEncodedShard(shardID, ownerID uint64, points *rpc.EncodedPoints) error
 	}
+
+	// RetryPolicy decides whether failed remote writes are retried, queued
+	// in hinted handoff, or returned to the caller.
+	RetryPolicy RetryPolicy
+
+	// Tracer records the time spent in each stage of a write. Tracing is
+	// disabled if nil.
+	Tracer *WriteTracer
+
+	// SpanTracer creates spans for writes that are propagated to the remote
+	// nodes written to. Spans are not created if nil.
+	SpanTracer SpanTracer
+
+	// MetaCacheTTL is how long retention policies and shard groups are
+	// cached for when mapping points to shards. Entries are also removed
+	// whenever the meta data changes, if the meta client reports changes:
+	// only those of the database changed if it reports which, through
+	// Watch.
+	// Caching is disabled if zero.
+	MetaCacheTTL time.Duration
+	metaCache    metaCache
+
+	// MaxFutureWrite and MaxPastWrite bound how far ahead of and behind the
+	// current time points may be, so that clients with skewed clocks do not
+	// create shard groups far from the present. Points outside the window
+	// are dropped, or the whole write is rejected with ErrWriteOutOfBounds if
+	// RejectOutOfBoundsWrites is set. A zero bound is not checked.
+	MaxFutureWrite          time.Duration
+	MaxPastWrite            time.Duration
+	RejectOutOfBoundsWrites bool
+
+	// ErrorOnDroppedPoints returns a tsdb.PartialWriteError for writes that
+	// contain points older than the retention policy instead of silently
+	// dropping them. The rest of the write is still applied.
+	ErrorOnDroppedPoints bool
+
+	// Points are validated against these rules once a write has been
+	// through the write middleware, and the whole write is rejected with a
+	// *PointValidationError if any point breaks one. MaxFieldValueSize is
+	// the size in bytes of string field values. A write is also rejected
+	// if its database already has MaxSeriesPerDatabase series, as
+	// reported by Cardinality. Zero limits are not checked.
+	MaxTagsPerPoint      int
+	MaxFieldValueSize    int
+	BannedMeasurements   []string
+	BannedTags           []string
+	MaxSeriesPerDatabase int64
+	Cardinality          SeriesCardinality
+
+	// PartialWritePolicy is how writes are handled when some of their
+	// points fail validation or are rejected by the store, e.g.
+	// PartialWriteRejectAll. DatabasePartialWritePolicies overrides it for
+	// the databases it has a policy for.
+	PartialWritePolicy           string
+	DatabasePartialWritePolicies map[string]string
+
+	// Forwarder sends a whole write to another data node, which writes it
+	// as if it had received it from a client.
+	Forwarder interface {
+		ForwardPoints(span Span, requestID string, deadline time.Time, nodeID uint64, database, retentionPolicy string, consistencyLevel models.ConsistencyLevel, points *rpc.EncodedPoints) error
+	}
+
+	// ForwardThreshold is the share of a write's points that must belong to
+	// the shards of a single remote node for the write to be forwarded to
+	// that node in one hop, instead of being split into a write per shard
+	// owner here. This reduces fan-out when clients partition their writes
+	// by series. A write is split here if forwarding fails before the node
+	// wrote it. Forwarding is disabled if zero or if Forwarder is nil.
+	ForwardThreshold float64
+
+	// IntoConsistencyLevel is the consistency level the results of
+	// SELECT ... INTO statements are written with.
+	IntoConsistencyLevel models.ConsistencyLevel
+
+	// LateWriteWindow is how long a remote write that timed out is waited
+	// on before it is queued in hinted handoff, in case the ShardWriter
+	// reports that it succeeded after all through LateWriteSucceeded. Writes
+	// are queued right away if zero.
+	LateWriteWindow time.Duration
+	lateWrites      lateWrites
+
+	stats *WriteStatistics
+
+	// after waits for the backoff between retries of a remote write.
+	after func(time.Duration) <-chan time.Time
+
+	// Nodes that writes are not sent to, keyed by node ID.
+	paused map[uint64]struct{}
+
+	inflight inflightWrites
+
+	// Set once Drain is called; writes tracks writes still in progress.
+	draining bool
+	writes   sync.WaitGroup
+
+	// Wraps writes before their points are mapped to shards; see Use.
+	middleware []WriteMiddleware
 }
 
 // WritePointsRequest represents a request to write point data to the cluster.
@@ -80,14 +254,24 @@ func NewPointsWriter() *PointsWriter {
 		closing:      make(chan struct{}),
 		WriteTimeout: DefaultWriteTimeout,
 		Logger:       zap.New(zap.NullEncoder()),
+		RetryPolicy:  DefaultRetryPolicy{},
+		MetaCacheTTL: DefaultMetaCacheTTL,
+		stats:        &WriteStatistics{},
+		after:        time.After,
+
+		IntoConsistencyLevel: models.ConsistencyLevelOne,
+		LateWriteWindow:      DefaultLateWriteWindow,
 	}
 }
 
 // ShardMapping contains a mapping of a shards to a points.
 type ShardMapping struct {
-	n      int
-	Points map[uint64][]models.Point  // The points associated with a shard ID
-	Shards map[uint64]*meta.ShardInfo // The shards that have been mapped, keyed by shard ID
+	n       int
+	Points  map[uint64][]models.Point  // The points associated with a shard ID
+	Shards  map[uint64]*meta.ShardInfo // The shards that have been mapped, keyed by shard ID
+	Dropped []models.Point             // Points older than the retention policy
+	Owners  map[uint64][]uint64        // The IDs of the nodes owning each shard, keyed by shard ID
+	Pending []models.Point             // Points without a shard group yet; only set by Plan
 }
 
 // NewShardMapping creates an empty ShardMapping
@@ -96,6 +280,7 @@ func NewShardMapping(n int) *ShardMapping {
 		n:      n,
 		Points: map[uint64][]models.Point{},
 		Shards: map[uint64]*meta.ShardInfo{},
+		Owners: map[uint64][]uint64{},
 	}
 }
 
@@ -105,17 +290,103 @@ func (s *ShardMapping) MapPoint(shardInfo *meta.ShardInfo, p models.Point) {
 		s.Points[shardInfo.ID] = make([]models.Point, 0, s.n)
 	}
 	s.Points[shardInfo.ID] = append(s.Points[shardInfo.ID], p)
+	if _, ok := s.Shards[shardInfo.ID]; !ok && s.Owners != nil {
+		owners := make([]uint64, len(shardInfo.Owners))
+		for i, o := range shardInfo.Owners {
+			owners[i] = o.NodeID
+		}
+		s.Owners[shardInfo.ID] = owners
+	}
 	s.Shards[shardInfo.ID] = shardInfo
 }
 
+// NodeIDs returns the sorted IDs of the nodes owning any of the shards
+// mapped.
+func (s *ShardMapping) NodeIDs() []uint64 {
+	seen := make(map[uint64]bool)
+	var ids []uint64
+	for _, owners := range s.Owners {
+		for _, id := range owners {
+			if !seen[id] {
+				seen[id] = true
+				ids = append(ids, id)
+			}
+		}
+	}
+	sort.Sort(uint64Slice(ids))
+	return ids
+}
+
 // Open opens the communication channel with the point writer
 func (w *PointsWriter) Open() error {
 	w.mu.Lock()
 	defer w.mu.Unlock()
 	w.closing = make(chan struct{})
+
+	if w.MetaCacheTTL > 0 {
+		if mc, ok := w.MetaClient.(changeWatcher); ok {
+			changes, cancel := mc.Watch(0)
+			go w.invalidateMetaCacheOnChange(changes, cancel, w.closing)
+		} else if mc, ok := w.MetaClient.(dataChangeNotifier); ok {
+			go w.clearMetaCacheOnChange(mc, mc.WaitForDataChanged(), w.closing)
+		}
+	}
 	return nil
 }
 
+// dataChangeNotifier is implemented by meta clients that report changes to
+// the meta data.
+type dataChangeNotifier interface {
+	WaitForDataChanged() chan struct{}
+}
+
+// changeWatcher is implemented by meta clients that report what changed in
+// the meta data.
+type changeWatcher interface {
+	Watch(n int) (<-chan cloudMeta.Change, func())
+}
+
+// invalidateMetaCacheOnChange removes the entries of the meta cache a change
+// received on changes may have made stale, until closing is closed. cancel
+// ends the watch once done.
+func (w *PointsWriter) invalidateMetaCacheOnChange(changes <-chan cloudMeta.Change, cancel func(), closing chan struct{}) {
+	defer cancel()
+	for {
+		select {
+		case <-closing:
+			return
+		case c, ok := <-changes:
+			if !ok {
+				return
+			}
+			switch c.Type {
+			case cloudMeta.ChangeDataNodeAdded, cloudMeta.ChangeDataNodeRemoved, cloudMeta.ChangeDataNodeUpdated:
+				// Shard owners are reported as shard group updates.
+			case cloudMeta.ChangeReset:
+				w.metaCache.clear()
+			default:
+				w.metaCache.invalidate(c.Database)
+			}
+		}
+	}
+}
+
+// clearMetaCacheOnChange clears the meta cache each time the meta data
+// changes, starting with the change that closes changed, until closing is
+// closed.
+func (w *PointsWriter) clearMetaCacheOnChange(mc dataChangeNotifier, changed, closing chan struct{}) {
+	for {
+		select {
+		case <-closing:
+			return
+		case <-changed:
+			// Wait on the next change before clearing so that none is missed.
+			changed = mc.WaitForDataChanged()
+			w.metaCache.clear()
+		}
+	}
+}
+
 // Close closes the communication channel with the point writer
 func (w *PointsWriter) Close() error {
 	w.mu.Lock()
@@ -131,6 +402,123 @@ func (w *PointsWriter) WithLogger(log zap.Logger) {
 	w.Logger = log.With(zap.String("service", "write"))
 }
 
+// Reload applies the write timeouts and retry policy of c while the writer
+// is open. Writes in flight finish with the settings they started with.
+func (w *PointsWriter) Reload(c Config) error {
+	policy, err := NewRetryPolicy(c.WriteRetryPolicy, c.MaxWriteRetries)
+	if err != nil {
+		return err
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.WriteTimeout = time.Duration(c.WriteTimeout)
+	w.OwnerWriteTimeout = time.Duration(c.OwnerWriteTimeout)
+	w.WriteHedgeDelay = time.Duration(c.WriteHedgeDelay)
+	w.RetryPolicy = policy
+	return nil
+}
+
+// writeTimeout returns WriteTimeout.
+func (w *PointsWriter) writeTimeout() time.Duration {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.WriteTimeout
+}
+
+// ownerDeadline returns the deadline of an attempt to write to a shard owner
+// that starts now, within the request deadline.
+func (w *PointsWriter) ownerDeadline(deadline time.Time) time.Time {
+	w.mu.RLock()
+	timeout := w.OwnerWriteTimeout
+	w.mu.RUnlock()
+	if timeout <= 0 {
+		return deadline
+	}
+	if d := time.Now().Add(timeout); d.Before(deadline) {
+		return d
+	}
+	return deadline
+}
+
+// writeHedgeDelay returns WriteHedgeDelay.
+func (w *PointsWriter) writeHedgeDelay() time.Duration {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.WriteHedgeDelay
+}
+
+// retryPolicy returns RetryPolicy.
+func (w *PointsWriter) retryPolicy() RetryPolicy {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.RetryPolicy
+}
+
+// PauseReplication stops writes from this node to nodeID. Until resumed,
+// writes owned by nodeID go straight to hinted handoff.
+func (w *PointsWriter) PauseReplication(nodeID uint64) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.paused == nil {
+		w.paused = make(map[uint64]struct{})
+	}
+	w.paused[nodeID] = struct{}{}
+}
+
+// ResumeReplication restarts writes from this node to nodeID.
+func (w *PointsWriter) ResumeReplication(nodeID uint64) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	delete(w.paused, nodeID)
+}
+
+// Drain stops accepting new writes and waits for in-flight writes to finish,
+// including writes to hinted handoff for owners that failed. WritePoints
+// returns ErrDraining once Drain has been called.
+func (w *PointsWriter) Drain() error {
+	w.mu.Lock()
+	w.draining = true
+	w.mu.Unlock()
+
+	w.writes.Wait()
+	return nil
+}
+
+// startWrite registers a new write with the writer. It returns false if the
+// writer is draining.
+func (w *PointsWriter) startWrite() bool {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	if w.draining {
+		return false
+	}
+	w.writes.Add(1)
+	return true
+}
+
+// InflightWrites returns the shard writes that have been waiting for their
+// consistency level for at least threshold.
+func (w *PointsWriter) InflightWrites(threshold time.Duration) []InflightWrite {
+	return w.inflight.olderThan(threshold)
+}
+
+// WriteTraces returns the slowest traced writes, or nil if tracing is disabled.
+func (w *PointsWriter) WriteTraces() []WriteTrace {
+	if w.Tracer == nil {
+		return nil
+	}
+	return w.Tracer.Traces()
+}
+
+// replicationPaused returns true if writes to nodeID are paused.
+func (w *PointsWriter) replicationPaused(nodeID uint64) bool {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	_, ok := w.paused[nodeID]
+	return ok
+}
+
 // WriteStatistics keeps statistics related to the PointsWriter.
 type WriteStatistics struct {
 	WriteReq            int64
@@ -145,30 +533,124 @@ type WriteStatistics struct {
 	WriteErr            int64
 	SubWriteOK          int64
 	SubWriteDrop        int64
+	WriteRetry          int64
+	WriteOutOfBounds    int64
+	WriteForward        int64
+	WriteForwardReq     int64
+	WriteForwardFailed  int64
+	PointWriteReqFwd    int64
+	WriteLate           int64
+	WriteRejected       int64
+	PointWriteRejected  int64
+	WriteHedge          int64
+	WriteCancel         int64
+}
+
+// Statistics returns statistics for periodic monitoring.
+func (w *PointsWriter) Statistics(tags map[string]string) []models.Statistic {
+	return []models.Statistic{{
+		Name: "write",
+		Tags: tags,
+		Values: map[string]interface{}{
+			statWriteReq:            atomic.LoadInt64(&w.stats.WriteReq),
+			statPointWriteReq:       atomic.LoadInt64(&w.stats.PointWriteReq),
+			statPointWriteReqLocal:  atomic.LoadInt64(&w.stats.PointWriteReqLocal),
+			statPointWriteReqRemote: atomic.LoadInt64(&w.stats.PointWriteReqRemote),
+			statPointWriteReqHH:     atomic.LoadInt64(&w.stats.PointWriteReqHH),
+			statWriteOK:             atomic.LoadInt64(&w.stats.WriteOK),
+			statWriteDrop:           atomic.LoadInt64(&w.stats.WriteDropped),
+			statWriteTimeout:        atomic.LoadInt64(&w.stats.WriteTimeout),
+			statWritePartial:        atomic.LoadInt64(&w.stats.WritePartial),
+			statWriteErr:            atomic.LoadInt64(&w.stats.WriteErr),
+			statSubWriteOK:          atomic.LoadInt64(&w.stats.SubWriteOK),
+			statSubWriteDrop:        atomic.LoadInt64(&w.stats.SubWriteDrop),
+			statWriteRetry:          atomic.LoadInt64(&w.stats.WriteRetry),
+			statWriteOutOfBounds:    atomic.LoadInt64(&w.stats.WriteOutOfBounds),
+			statWriteForward:        atomic.LoadInt64(&w.stats.WriteForward),
+			statWriteForwardReq:     atomic.LoadInt64(&w.stats.WriteForwardReq),
+			statWriteForwardFailed:  atomic.LoadInt64(&w.stats.WriteForwardFailed),
+			statPointWriteReqFwd:    atomic.LoadInt64(&w.stats.PointWriteReqFwd),
+			statWriteLate:           atomic.LoadInt64(&w.stats.WriteLate),
+			statWriteRejected:       atomic.LoadInt64(&w.stats.WriteRejected),
+			statPointWriteRejected:  atomic.LoadInt64(&w.stats.PointWriteRejected),
+			statWriteHedge:          atomic.LoadInt64(&w.stats.WriteHedge),
+			statWriteCancel:         atomic.LoadInt64(&w.stats.WriteCancel),
+		},
+	}}
 }
 
 // MapShards maps the points contained in wp to a ShardMapping.  If a point
 // maps to a shard group or shard that does not currently exist, it will be
 // created before returning the mapping.
 func (w *PointsWriter) MapShards(wp *WritePointsRequest) (*ShardMapping, error) {
-	rp, err := w.MetaClient.RetentionPolicy(wp.Database, wp.RetentionPolicy)
-	if err != nil {
-		return nil, err
-	} else if rp == nil {
-		return nil, influxcloud.ErrRetentionPolicyNotFound(wp.RetentionPolicy)
+	return w.mapShards(wp, false)
+}
+
+// Plan maps the points contained in wp to a ShardMapping like MapShards, but
+// without side effects: shard groups are not created, and the points that
+// would need one are returned in Pending instead. Nothing is cached or
+// counted in the write statistics, so Plan can be used to find the nodes a
+// write would be sent to without writing it.
+func (w *PointsWriter) Plan(wp *WritePointsRequest) (*ShardMapping, error) {
+	return w.mapShards(wp, true)
+}
+
+// mapShards maps the points of wp to shards, creating the shard groups
+// missing unless plan is set.
+func (w *PointsWriter) mapShards(wp *WritePointsRequest, plan bool) (*ShardMapping, error) {
+	now := time.Now()
+	if w.RejectOutOfBoundsWrites {
+		for _, p := range wp.Points {
+			if !w.inWriteWindow(p.Time(), now) {
+				if !plan {
+					atomic.AddInt64(&w.stats.WriteOutOfBounds, int64(len(wp.Points)))
+				}
+				return nil, ErrWriteOutOfBounds
+			}
+		}
+	}
+
+	// Start from the retention policy and shard groups cached by earlier
+	// writes, if any.
+	var rp *meta.RetentionPolicyInfo
+	var list sgList
+	var gen uint64
+	if w.MetaCacheTTL > 0 && !plan {
+		rp, list, gen = w.metaCache.get(wp.Database, wp.RetentionPolicy)
+	}
+
+	if rp == nil {
+		var err error
+		rp, err = w.MetaClient.RetentionPolicy(wp.Database, wp.RetentionPolicy)
+		if err != nil {
+			return nil, err
+		} else if rp == nil {
+			return nil, influxcloud.ErrRetentionPolicyNotFound(wp.RetentionPolicy)
+		}
 	}
 
 	// Holds all the shard groups and shards that are required for writes.
-	list := make(sgList, 0, 8)
+	if list == nil {
+		list = make(sgList, 0, 8)
+	}
 	min := time.Unix(0, models.MinNanoTime)
 	if rp.Duration > 0 {
-		min = time.Now().Add(-rp.Duration)
+		min = now.Add(-rp.Duration)
+	}
+
+	// Plans only use the shard groups that already exist.
+	if plan {
+		for _, sg := range rp.ShardGroups {
+			if !sg.Deleted() {
+				list = list.Append(sg)
+			}
+		}
 	}
 
 	for _, p := range wp.Points {
-		// Either the point is outside the scope of the RP, or we already have
-		// a suitable shard group for the point.
-		if p.Time().Before(min) || list.Covers(p.Time()) {
+		// Either the point is outside the scope of the RP or the write
+		// window, or we already have a suitable shard group for the point.
+		if p.Time().Before(min) || !w.inWriteWindow(p.Time(), now) || list.Covers(p.Time()) || plan {
 			continue
 		}
 
@@ -185,21 +667,62 @@ func (w *PointsWriter) MapShards(wp *WritePointsRequest) (*ShardMapping, error)
 		list = list.Append(*sg)
 	}
 
+	if w.MetaCacheTTL > 0 && !plan {
+		w.metaCache.put(wp.Database, wp.RetentionPolicy, rp, list, w.MetaCacheTTL, gen)
+	}
+
+	// The assignment mode of each shard group, if the meta client stores one.
+	assigner, _ := w.MetaClient.(shardAssigner)
+	modes := make(map[uint64]string)
+
 	mapping := NewShardMapping(len(wp.Points))
 	for _, p := range wp.Points {
+		if !w.inWriteWindow(p.Time(), now) {
+			if !plan {
+				atomic.AddInt64(&w.stats.WriteOutOfBounds, 1)
+			}
+			continue
+		}
+
 		sg := list.ShardGroupAt(p.Time())
-		if sg == nil {
+		if sg == nil && plan && !p.Time().Before(min) {
+			// The shard group of the point would be created by a write.
+			mapping.Pending = append(mapping.Pending, p)
+			continue
+		} else if sg == nil {
 			// We didn't create a shard group because the point was outside the
 			// scope of the RP.
+			mapping.Dropped = append(mapping.Dropped, p)
+			if !plan {
+				atomic.AddInt64(&w.stats.WriteDropped, 1)
+			}
 			continue
 		}
 
-		sh := sg.ShardFor(p.HashID())
+		mode, ok := modes[sg.ID]
+		if !ok && assigner != nil {
+			mode = assigner.ShardGroupAssignment(sg.ID)
+			modes[sg.ID] = mode
+		}
+
+		sh := shardFor(sg, mode, p.HashID())
 		mapping.MapPoint(&sh, p)
 	}
 	return mapping, nil
 }
 
+// inWriteWindow returns true if t is within MaxPastWrite and MaxFutureWrite
+// of now.
+func (w *PointsWriter) inWriteWindow(t, now time.Time) bool {
+	if w.MaxFutureWrite > 0 && t.After(now.Add(w.MaxFutureWrite)) {
+		return false
+	}
+	if w.MaxPastWrite > 0 && t.Before(now.Add(-w.MaxPastWrite)) {
+		return false
+	}
+	return true
+}
+
 // sgList is a wrapper around a meta.ShardGroupInfos where we can also check
 // if a given time is covered by any of the shard groups in the list.
 type sgList meta.ShardGroupInfos
@@ -239,12 +762,102 @@ func (l sgList) Append(sgi meta.ShardGroupInfo) sgList {
 
 // WritePointsInto is a copy of WritePoints that uses a tsdb structure instead of
 // a cluster structure for information. This is to avoid a circular dependency
+//
+// Points are written with IntoConsistencyLevel. Unlike WritePoints, every
+// shard is written even if some fail, and a tsdb.PartialWriteError counting
+// the points of the failed shards is returned if others succeeded, so that
+// the statement reports how much of its result was not written.
 func (w *PointsWriter) WritePointsInto(p *coordinator.IntoWriteRequest) error {
-	return w.WritePoints(p.Database, p.RetentionPolicy, models.ConsistencyLevelOne, p.Points)
+	return w.write(NewRequestID(), time.Time{}, writePartial, p.Database, p.RetentionPolicy, w.IntoConsistencyLevel, p.Points)
 }
 
 // WritePoints writes across multiple local and remote data nodes according the consistency level.
 func (w *PointsWriter) WritePoints(database, retentionPolicy string, consistencyLevel models.ConsistencyLevel, points []models.Point) error {
+	return w.WritePointsWithDeadline(time.Time{}, database, retentionPolicy, consistencyLevel, points)
+}
+
+// WritePointsWithDeadline writes points like WritePoints but gives up at
+// deadline instead of after WriteTimeout, e.g. when the client that sent the
+// points has its own timeout. The time left is sent to the remote nodes
+// written to so that they do not write points the client has given up on.
+// A zero deadline uses WriteTimeout.
+func (w *PointsWriter) WritePointsWithDeadline(deadline time.Time, database, retentionPolicy string, consistencyLevel models.ConsistencyLevel, points []models.Point) error {
+	return w.write(NewRequestID(), deadline, writeForward, database, retentionPolicy, consistencyLevel, points)
+}
+
+// WriteForwardedPoints writes points forwarded by another node's
+// PointsWriter as part of the client request identified by requestID. The
+// write is not forwarded again.
+func (w *PointsWriter) WriteForwardedPoints(requestID string, deadline time.Time, database, retentionPolicy string, consistencyLevel models.ConsistencyLevel, points []models.Point) error {
+	atomic.AddInt64(&w.stats.WriteForwardReq, 1)
+	return w.write(requestID, deadline, writeForwarded, database, retentionPolicy, consistencyLevel, points)
+}
+
+// writeMode controls how a write is sent to the shards of its points.
+type writeMode int
+
+const (
+	// writeForward allows the write to be forwarded whole to another node.
+	writeForward writeMode = 1 << iota
+
+	// writePartial writes every shard even if some fail, and reports the
+	// points of the failed shards in a tsdb.PartialWriteError.
+	writePartial
+
+	// writeForwarded marks a write forwarded by another node, which has
+	// already been through the write middleware.
+	writeForwarded
+)
+
+// write passes points written on behalf of the client request identified by
+// requestID through the write middleware, then writes them as controlled by
+// mode.
+func (w *PointsWriter) write(requestID string, deadline time.Time, mode writeMode, database, retentionPolicy string, consistencyLevel models.ConsistencyLevel, points []models.Point) error {
+	if deadline.IsZero() {
+		deadline = time.Now().Add(w.writeTimeout())
+	}
+
+	atomic.AddInt64(&w.stats.WriteReq, 1)
+	atomic.AddInt64(&w.stats.PointWriteReq, int64(len(points)))
+
+	if !w.startWrite() {
+		return ErrDraining
+	}
+	defer w.writes.Done()
+
+	fn := func(wr *Write) error { return w.writeMapped(wr, mode) }
+	if mode&writeForwarded == 0 {
+		fn = w.chain(fn)
+	}
+	return fn(&Write{
+		RequestID:        requestID,
+		Deadline:         deadline,
+		Database:         database,
+		RetentionPolicy:  retentionPolicy,
+		ConsistencyLevel: consistencyLevel,
+		Points:           points,
+	})
+}
+
+// writeMapped writes wr once it has been through the write middleware.
+func (w *PointsWriter) writeMapped(wr *Write, mode writeMode) error {
+	requestID, deadline := wr.RequestID, wr.Deadline
+	database, retentionPolicy := wr.Database, wr.RetentionPolicy
+	consistencyLevel, points := wr.ConsistencyLevel, wr.Points
+
+	// Forwarded writes were validated by the node they were forwarded by.
+	// Invalid points may be dropped from the write as its partial write
+	// policy allows, and are reported with the result of the write.
+	var invalid error
+	if mode&writeForwarded == 0 {
+		valid, err := w.validatePoints(database, points)
+		if _, ok := err.(tsdb.PartialWriteError); ok {
+			invalid = err
+		} else if err != nil {
+			return err
+		}
+		points = valid
+	}
 
 	if retentionPolicy == "" {
 		db := w.MetaClient.Database(database)
@@ -254,43 +867,175 @@ func (w *PointsWriter) WritePoints(database, retentionPolicy string, consistency
 		retentionPolicy = db.DefaultRetentionPolicy
 	}
 
+	trace := w.Tracer.start(requestID, database, retentionPolicy, len(points))
+	span := startSpan(w.SpanTracer, "cluster.writePoints", nil)
+	span.SetTag("requestID", requestID)
+	span.SetTag("database", database)
+	span.SetTag("retentionPolicy", retentionPolicy)
+	defer span.Finish()
+
+	err := w.writePoints(requestID, deadline, mode, trace, span, database, retentionPolicy, consistencyLevel, points)
+	err = addPartialWrite(err, invalid)
+	if err != nil {
+		span.SetTag("error", err.Error())
+	}
+	if trace != nil {
+		t := w.Tracer.finish(trace, err)
+		w.Logger.Debug("write trace", zap.String("traceID", t.TraceID), zap.Duration("duration", t.Duration), zap.Object("stages", t.Stages))
+	}
+	return w.acceptPartialWrite(requestID, database, err)
+}
+
+// writePoints maps points to shards and writes each shard concurrently. If
+// mode allows forwarding and most points belong to a single remote node, the
+// write is forwarded to that node instead.
+func (w *PointsWriter) writePoints(requestID string, deadline time.Time, mode writeMode, trace *writeTrace, span Span, database, retentionPolicy string, consistencyLevel models.ConsistencyLevel, points []models.Point) error {
+	start := time.Now()
 	shardMappings, err := w.MapShards(&WritePointsRequest{Database: database, RetentionPolicy: retentionPolicy, Points: points})
+	trace.stage(StageMapShards, 0, 0, start, err)
 	if err != nil {
 		return err
 	}
+	if n := len(shardMappings.Dropped); n > 0 && w.partialWritePolicy(database) == PartialWriteRejectAll {
+		return rejectedWrite(fmt.Errorf("%d points beyond retention policy", n), len(points))
+	}
+
+	if mode&writeForward != 0 && w.Forwarder != nil && w.ForwardThreshold > 0 {
+		if nodeID, ok := forwardTarget(shardMappings, w.Node.ID, w.ForwardThreshold, w.replicationPaused); ok {
+			if forwarded, err := w.forwardPoints(requestID, deadline, trace, span, nodeID, database, retentionPolicy, consistencyLevel, shardMappings); forwarded {
+				return w.droppedPointsError(err, len(shardMappings.Dropped))
+			}
+		}
+	}
 
 	// Write each shard in it's own goroutine and return as soon
-	// as one fails.
-	ch := make(chan error, len(shardMappings.Points))
+	// as one fails, unless the write is partial.
+	type shardResult struct {
+		shardID uint64
+		err     error
+	}
+	ch := make(chan shardResult, len(shardMappings.Points))
 	for shardID, points := range shardMappings.Points {
+		w.writes.Add(1)
 		go func(shard *meta.ShardInfo, database, retentionPolicy string, points []models.Point) {
-			ch <- w.writeToShard(shard, database, retentionPolicy, consistencyLevel, points)
+			defer w.writes.Done()
+			ch <- shardResult{shard.ID, w.writeToShard(requestID, deadline, trace, span, shard, database, retentionPolicy, consistencyLevel, points)}
 		}(shardMappings.Shards[shardID], database, retentionPolicy, points)
 	}
 
+	var firstErr error
+	var failedShards, failedPoints int
 	for range shardMappings.Points {
 		select {
 		case <-w.closing:
 			return ErrWriteFailed
-		case err := <-ch:
-			if err != nil {
-				return err
+		case r := <-ch:
+			if r.err == nil {
+				continue
+			}
+			w.Logger.Info("write failed", zap.String("requestID", requestID), zap.Error(r.err))
+			if mode&writePartial == 0 {
+				return w.droppedPointsError(r.err, len(shardMappings.Dropped))
+			}
+
+			if firstErr == nil {
+				firstErr = r.err
+			}
+			failedShards++
+			if e, ok := r.err.(tsdb.PartialWriteError); ok {
+				failedPoints += e.Dropped
+			} else {
+				failedPoints += len(shardMappings.Points[r.shardID])
 			}
 		}
 	}
-	return nil
+
+	// A write that failed for every shard did not write anything.
+	if failedShards > 0 && failedShards < len(shardMappings.Points) {
+		firstErr = tsdb.PartialWriteError{
+			Reason:  fmt.Sprintf("write failed for %d of %d shards: %v", failedShards, len(shardMappings.Points), firstErr),
+			Dropped: failedPoints,
+		}
+	}
+	return w.droppedPointsError(firstErr, len(shardMappings.Dropped))
+}
+
+// forwardPoints forwards the points mapped to shards to nodeID. forwarded is
+// false if the write failed before nodeID wrote it, in which case it should
+// be split and written to the shard owners instead.
+func (w *PointsWriter) forwardPoints(requestID string, deadline time.Time, trace *writeTrace, parent Span, nodeID uint64, database, retentionPolicy string,
+	consistencyLevel models.ConsistencyLevel, m *ShardMapping) (forwarded bool, err error) {
+	var points []models.Point
+	for _, p := range m.Points {
+		points = append(points, p...)
+	}
+	encoded, err := rpc.EncodePoints(points)
+	if err != nil {
+		return true, err
+	}
+
+	span := startSpan(w.SpanTracer, "cluster.forwardPoints", parent)
+	span.SetTag("nodeID", nodeID)
+	defer span.Finish()
+
+	start := time.Now()
+	err = w.Forwarder.ForwardPoints(span, requestID, deadline, nodeID, database, retentionPolicy, consistencyLevel, encoded)
+	trace.stage(StageForward, 0, nodeID, start, err)
+	if err != nil {
+		span.SetTag("error", err.Error())
+		if forwardFailed(err) {
+			atomic.AddInt64(&w.stats.WriteForwardFailed, 1)
+			w.Logger.Info("write forward failed, writing to shard owners", zap.String("requestID", requestID), zap.Uint64("nodeID", nodeID), zap.Error(err))
+			return false, err
+		}
+	}
+
+	atomic.AddInt64(&w.stats.WriteForward, 1)
+	atomic.AddInt64(&w.stats.PointWriteReqFwd, int64(len(points)))
+	if err == ErrTimeout {
+		atomic.AddInt64(&w.stats.WriteTimeout, 1)
+		return true, err
+	} else if err != nil {
+		atomic.AddInt64(&w.stats.WriteErr, 1)
+		w.Logger.Info("forwarded write failed", zap.String("requestID", requestID), zap.Uint64("nodeID", nodeID), zap.Error(err))
+		return true, fmt.Errorf("write failed on node %d: %v", nodeID, err)
+	}
+	return true, nil
+}
+
+// droppedPointsError adds the number of points dropped for being older than
+// the retention policy to the error returned for a write. A partial write
+// error from a shard includes them, and if the write otherwise succeeded an
+// error is only returned if ErrorOnDroppedPoints is set.
+func (w *PointsWriter) droppedPointsError(err error, dropped int) error {
+	if dropped == 0 {
+		return err
+	}
+
+	const reason = "points beyond retention policy"
+	switch e := err.(type) {
+	case nil:
+		if !w.ErrorOnDroppedPoints {
+			return nil
+		}
+		return tsdb.PartialWriteError{Reason: reason, Dropped: dropped}
+	case tsdb.PartialWriteError:
+		e.Reason = fmt.Sprintf("%s; %d %s", e.Reason, dropped, reason)
+		e.Dropped += dropped
+		return e
+	}
+	return err
 }
 
 // writeToShards writes points to a shard.
-func (w *PointsWriter) writeToShard(shard *meta.ShardInfo, database, retentionPolicy string,
+func (w *PointsWriter) writeToShard(requestID string, deadline time.Time, trace *writeTrace, span Span, shard *meta.ShardInfo, database, retentionPolicy string,
 	consistency models.ConsistencyLevel, points []models.Point) error {
-	required := len(shard.Owners)
-	switch consistency {
-	case models.ConsistencyLevelAny, models.ConsistencyLevelOne:
-		required = 1
-	case models.ConsistencyLevelQuorum:
-		required = required/2 + 1
-	}
+	required := requiredOwners(len(shard.Owners), consistency)
+	rejectAll := w.partialWritePolicy(database) == PartialWriteRejectAll
+
+	// Cached points of the shard may be stale once the write has reached
+	// any owner, whether or not it succeeds.
+	defer w.IteratorCache.invalidatePoints(shard.ID, points)
 
 	// AsyncWriteResult is a struct that can be used
 	// to determine the status of each PointWriteRequest
@@ -302,29 +1047,90 @@ func (w *PointsWriter) writeToShard(shard *meta.ShardInfo, database, retentionPo
 	// response channel for each shard writer go routine
 	ch := make(chan *AsyncWriteResult, len(shard.Owners))
 
+	// At consistency ANY, the remote writes still to be made once the write
+	// succeeded are canceled by closing cancel, and queued in hinted handoff
+	// instead of being sent.
+	var cancel chan struct{}
+	if consistency == models.ConsistencyLevelAny {
+		cancel = make(chan struct{})
+	}
+
+	// Track the write until it completes for the /debug/writes endpoint.
+	owners := make([]uint64, len(shard.Owners))
+	for i, owner := range shard.Owners {
+		owners[i] = owner.NodeID
+	}
+	inflight := w.inflight.add(requestID, shard.ID, owners, required)
+	defer w.inflight.remove(inflight)
+
+	// Encode the points once for all remote owners and hinted handoff.
+	var encoded *rpc.EncodedPoints
 	for _, owner := range shard.Owners {
+		if w.Node.ID != owner.NodeID {
+			e, err := rpc.EncodePoints(points)
+			if err != nil {
+				return err
+			}
+			encoded = e
+			break
+		}
+	}
+
+	// write starts writing points to owner.
+	write := func(owner meta.ShardOwner) {
+		w.writes.Add(2)
 		go func(shardID uint64, owner meta.ShardOwner, points []models.Point) {
+			defer w.writes.Done()
 			if w.Node.ID != owner.NodeID {
 				w.Logger.Info("Remote Write")
 				return
 			}
+			atomic.AddInt64(&w.stats.PointWriteReqLocal, int64(len(points)))
+
 			// not actually created this shard, tell it to create it and retry the write
-			err := w.TSDBStore.WriteToShard(shardID, points)
+			start := time.Now()
+			var err error
+			if rejectAll {
+				// The store writes the points it does not reject, so they
+				// are checked first.
+				if rejected, reason, _ := conflictingPoints(w.TSDBStore, shardID, points); len(rejected) > 0 {
+					err = tsdb.PartialWriteError{Reason: reason, Dropped: len(rejected)}
+				}
+			}
+			if err == nil {
+				err = w.TSDBStore.WriteToShard(shardID, points)
+			}
+			trace.stage(StageLocalWrite, shardID, owner.NodeID, start, err)
 			if err != nil {
-				w.Logger.Info("failed to write point to shard locally:", zap.Error(err))
+				w.Logger.Info("failed to write point to shard locally:", zap.String("requestID", requestID), zap.Error(err))
 			}
 			ch <- &AsyncWriteResult{owner, err}
 			return
 		}(shard.ID, owner, points)
 
 		// Start to write Shard into remote nodes
-		go func(shardID uint64, owner meta.ShardOwner, points []models.Point) {
+		go func(shardID uint64, owner meta.ShardOwner, points *rpc.EncodedPoints) {
+			defer w.writes.Done()
 			if w.Node.ID != owner.NodeID {
+				late := w.lateWrites.track(owner.NodeID, points)
+				defer w.lateWrites.untrack(owner.NodeID, points)
 
-				err := w.ShardWriter.WriteShard(shardID, owner.NodeID, points)
-				if err != nil && isRetryable(err) {
+				start := time.Now()
+				decision, err := w.writeToRemote(requestID, deadline, cancel, span, shardID, owner.NodeID, points)
+				trace.stage(StageRemoteWrite, shardID, owner.NodeID, start, err)
+				if err != nil && decision == RetryDecisionHintedHandoff && w.awaitLateWrite(err, late) {
+					// An attempt succeeded after timing out, so queueing the
+					// write would write the points twice.
+					atomic.AddInt64(&w.stats.WriteLate, 1)
+					ch <- &AsyncWriteResult{owner, nil}
+					return
+				}
+				if err != nil && decision == RetryDecisionHintedHandoff {
 					// The remote write failed so queue it via hinted handoff
-					hherr := w.HintedHandoff.WriteShard(shardID, owner.NodeID, points)
+					atomic.AddInt64(&w.stats.PointWriteReqHH, int64(points.Len()))
+					start := time.Now()
+					hherr := w.HintedHandoff.WriteEncodedShard(shardID, owner.NodeID, points)
+					trace.stage(StageHintedHandoff, shardID, owner.NodeID, start, hherr)
 					if hherr != nil {
 						ch <- &AsyncWriteResult{owner, hherr}
 						return
@@ -340,21 +1146,52 @@ func (w *PointsWriter) writeToShard(shard *meta.ShardInfo, database, retentionPo
 				}
 				ch <- &AsyncWriteResult{owner, err}
 			}
-		}(shard.ID, owner, points)
+		}(shard.ID, owner, encoded)
+
+	}
 
+	// When hedging, the writes to all but the first owner are held back
+	// until it fails, the hedge delay passes, or it succeeds.
+	ordered := orderOwners(shard.ID, w.Node.ID, shard.Owners)
+	var pending []meta.ShardOwner
+	var hedge <-chan time.Time
+	if delay := w.writeHedgeDelay(); delay > 0 && required == 1 && len(ordered) > 1 {
+		ordered, pending = ordered[:1], ordered[1:]
+		timer := time.NewTimer(delay)
+		defer timer.Stop()
+		hedge = timer.C
+	}
+	for _, owner := range ordered {
+		write(owner)
+	}
+
+	// fanOut starts the writes held back by hedging.
+	fanOut := func() {
+		for _, owner := range pending {
+			write(owner)
+		}
+		pending, hedge = nil, nil
 	}
 
 	var wrote int
-	timeout := time.After(w.WriteTimeout)
+	timeout := time.After(time.Until(deadline))
 	var writeError error
-	for range shard.Owners {
+	for n := 0; n < len(shard.Owners); {
 		select {
 		case <-w.closing:
 			return ErrWriteFailed
+		case <-hedge:
+			atomic.AddInt64(&w.stats.WriteHedge, 1)
+			fanOut()
 		case <-timeout:
+			fanOut()
+			atomic.AddInt64(&w.stats.WriteTimeout, 1)
 			// return timeout error to caller
 			return ErrTimeout
 		case result := <-ch:
+			n++
+			inflight.ack(result.Owner.NodeID, result.Err)
+
 			// If the write returned an error, continue to the next response
 			if result.Err != nil {
 				// w.Logger.Error("write failed for shard %d on node %d: %v", shard.ID, result.Owner.NodeID, result.Err)
@@ -363,6 +1200,7 @@ func (w *PointsWriter) writeToShard(shard *meta.ShardInfo, database, retentionPo
 				if writeError == nil {
 					writeError = result.Err
 				}
+				fanOut()
 				continue
 			}
 
@@ -370,29 +1208,165 @@ func (w *PointsWriter) writeToShard(shard *meta.ShardInfo, database, retentionPo
 
 			// We wrote the required consistency level
 			if wrote >= required {
+				// The owners held back are written to without the caller
+				// waiting on them.
+				if cancel != nil {
+					close(cancel)
+				}
+				fanOut()
+				atomic.AddInt64(&w.stats.WriteOK, 1)
 				return nil
 			}
 		}
 	}
 
 	if wrote > 0 {
+		atomic.AddInt64(&w.stats.WritePartial, 1)
+		w.Events.Publish(Event{Type: EventWritePartial, ShardID: shard.ID, Message: fmt.Sprintf("wrote %d of %d required owners", wrote, required)})
 		return ErrPartialWrite
 	}
 
 	if writeError != nil {
+		atomic.AddInt64(&w.stats.WriteErr, 1)
+		switch e := writeError.(type) {
+		case tsdb.PartialWriteError:
+			// The local store wrote the points it did not reject, unless
+			// they were all rejected.
+			if rejectAll {
+				return rejectedWrite(e, len(points))
+			}
+			e.Reason = "write failed: " + e.Reason
+			return e
+		case *rpc.WriteShardError:
+			if len(e.Rejected) == 0 {
+				break
+			}
+			// The rejected points are listed from the encoded points, as a
+			// local write may have removed the points the local store
+			// dropped from points.
+			reason := fmt.Sprintf("write failed: %v; rejected %s", writeError, rejectedPoints(encoded, e.Rejected))
+			if rejectAll {
+				return tsdb.PartialWriteError{Reason: reason, Dropped: len(points)}
+			}
+			// The owner wrote the points it did not reject.
+			return tsdb.PartialWriteError{Reason: reason, Dropped: len(e.Rejected)}
+		}
 		return fmt.Errorf("write failed: %v", writeError)
 	}
 
 	return ErrWriteFailed
 }
 
-func isRetryable(err error) bool {
-	if err == nil {
-		return true
+// maxRejectedPoints is the maximum number of rejected points listed in the
+// error of a write.
+const maxRejectedPoints = 10
+
+// rejectedPoints returns the points at the indices rejected by a shard owner,
+// as a list of up to maxRejectedPoints point keys.
+func rejectedPoints(encoded *rpc.EncodedPoints, rejected []int) string {
+	points, err := encoded.Points()
+	if err != nil {
+		return fmt.Sprintf("%d points", len(rejected))
 	}
+	var keys []string
+	for _, i := range rejected {
+		if len(keys) == maxRejectedPoints {
+			keys = append(keys, fmt.Sprintf("and %d more", len(rejected)-maxRejectedPoints))
+			break
+		} else if i < 0 || i >= len(points) {
+			continue
+		}
+		keys = append(keys, fmt.Sprintf("%s %d", points[i].Key(), points[i].UnixNano()))
+	}
+	return fmt.Sprintf("points: [%s]", strings.Join(keys, ", "))
+}
 
-	if strings.Contains(err.Error(), "field type conflict") {
-		return false
+// orderOwners returns owners in the order a shard is written to: the owner
+// localID first, then the remote owners by node ID, starting from one picked
+// by shardID so that the first remote owner differs between shards.
+func orderOwners(shardID, localID uint64, owners []meta.ShardOwner) []meta.ShardOwner {
+	ordered := make([]meta.ShardOwner, 0, len(owners))
+	var remote []meta.ShardOwner
+	for _, o := range owners {
+		if o.NodeID == localID {
+			ordered = append(ordered, o)
+		} else {
+			remote = append(remote, o)
+		}
+	}
+	if len(remote) == 0 {
+		return ordered
+	}
+
+	sort.Sort(shardOwnersByID(remote))
+	i := int(shardID % uint64(len(remote)))
+	ordered = append(ordered, remote[i:]...)
+	return append(ordered, remote[:i]...)
+}
+
+// shardOwnersByID sorts shard owners by node ID.
+type shardOwnersByID []meta.ShardOwner
+
+func (a shardOwnersByID) Len() int           { return len(a) }
+func (a shardOwnersByID) Less(i, j int) bool { return a[i].NodeID < a[j].NodeID }
+func (a shardOwnersByID) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
+
+// writeToRemote writes points to a remote node, retrying for as long as the
+// retry policy allows and deadline has not passed, after the backoff the
+// policy waits for. Each attempt is bounded by OwnerWriteTimeout, so that an
+// owner that is slow to respond leaves time to retry it. It returns the last
+// error and the policy's decision for it, if any. A write that would be
+// retried after the deadline, or that is canceled by closing cancel before an
+// attempt or while backing off, is queued in hinted handoff instead. An
+// attempt already sent is not canceled.
+func (w *PointsWriter) writeToRemote(requestID string, deadline time.Time, cancel <-chan struct{}, parent Span, shardID, nodeID uint64, points *rpc.EncodedPoints) (RetryDecision, error) {
+	policy := w.retryPolicy()
+	for attempt := 1; ; attempt++ {
+		select {
+		case <-cancel:
+			atomic.AddInt64(&w.stats.WriteCancel, 1)
+			return RetryDecisionHintedHandoff, ErrWriteCanceled
+		default:
+		}
+
+		var err error
+		if w.replicationPaused(nodeID) {
+			err = ErrReplicationPaused
+		} else if w.inMaintenance(nodeID) {
+			err = ErrNodeMaintenance
+		} else {
+			atomic.AddInt64(&w.stats.PointWriteReqRemote, int64(points.Len()))
+			span := startSpan(w.SpanTracer, "cluster.writeShard", parent)
+			span.SetTag("shardID", shardID)
+			span.SetTag("nodeID", nodeID)
+			span.SetTag("attempt", attempt)
+			err = w.ShardWriter.WriteEncodedShard(span, requestID, w.ownerDeadline(deadline), shardID, nodeID, points)
+			if err != nil {
+				span.SetTag("error", err.Error())
+			}
+			span.Finish()
+		}
+		if err == nil {
+			return RetryDecisionFail, nil
+		}
+
+		decision := policy.Decide(err, attempt)
+		backoff := policy.Backoff(attempt)
+		if decision != RetryDecisionRetry {
+			return decision, err
+		} else if !time.Now().Add(backoff).Before(deadline) {
+			return RetryDecisionHintedHandoff, err
+		}
+		atomic.AddInt64(&w.stats.WriteRetry, 1)
+		w.Logger.Info("retrying remote write", zap.String("requestID", requestID), zap.Uint64("shardID", shardID), zap.Uint64("nodeID", nodeID), zap.Duration("backoff", backoff), zap.Error(err))
+
+		if backoff > 0 {
+			select {
+			case <-cancel:
+				atomic.AddInt64(&w.stats.WriteCancel, 1)
+				return RetryDecisionHintedHandoff, ErrWriteCanceled
+			case <-w.after(backoff):
+			}
+		}
 	}
-	return true
 }
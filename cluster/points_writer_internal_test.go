@@ -1,8 +1,12 @@
 package cluster
 
 import (
+	"reflect"
 	"testing"
 	"time"
+
+	"github.com/influxdata/influxdb/services/meta"
+	"github.com/zhexuany/influxcloud/rpc"
 )
 
 func TestSgList_ShardGroupAt(t *testing.T) {
@@ -44,3 +48,101 @@ func TestSgList_ShardGroupAt(t *testing.T) {
 		}
 	}
 }
+
+// Ensure the local owner is written to first, and the first remote owner is
+// picked by shard ID.
+func TestOrderOwners(t *testing.T) {
+	owners := []meta.ShardOwner{{NodeID: 3}, {NodeID: 1}, {NodeID: 4}, {NodeID: 2}}
+	for _, tt := range []struct {
+		shardID, localID uint64
+		exp              []uint64
+	}{
+		{shardID: 0, localID: 1, exp: []uint64{1, 2, 3, 4}},
+		{shardID: 1, localID: 1, exp: []uint64{1, 3, 4, 2}},
+		{shardID: 5, localID: 4, exp: []uint64{4, 3, 1, 2}},
+		{shardID: 2, localID: 9, exp: []uint64{3, 4, 1, 2}},
+	} {
+		var got []uint64
+		for _, o := range orderOwners(tt.shardID, tt.localID, owners) {
+			got = append(got, o.NodeID)
+		}
+		if !reflect.DeepEqual(got, tt.exp) {
+			t.Errorf("shard %d, local %d: unexpected order: %v", tt.shardID, tt.localID, got)
+		}
+	}
+}
+
+// Ensure retries of a remote write wait for the backoff of the retry policy,
+// and that writes canceled while backing off, or that would be retried after
+// their deadline, are queued in hinted handoff instead.
+func TestPointsWriter_writeToRemote_Backoff(t *testing.T) {
+	var attempts int
+	w := NewPointsWriter()
+	w.ShardWriter = shardWriterFunc(func(shardID, nodeID uint64) error {
+		if attempts++; attempts < 3 {
+			return &rpc.WriteShardError{Code: rpc.CodeOverloaded}
+		}
+		return nil
+	})
+	policy := &StrictRetryPolicy{MaxRetries: 5, MinBackoff: time.Second, MaxBackoff: 10 * time.Second}
+	w.RetryPolicy = policy
+
+	// The fake clock hands over every wait, and fires once told to.
+	waits, fire := make(chan time.Duration), make(chan time.Time)
+	w.after = func(d time.Duration) <-chan time.Time {
+		waits <- d
+		return fire
+	}
+
+	points := rpc.NewEncodedPoints(nil)
+	errs := make(chan error, 1)
+	write := func(deadline time.Time, cancel <-chan struct{}) {
+		go func() {
+			_, err := w.writeToRemote("", deadline, cancel, nil, 1, 2, points)
+			errs <- err
+		}()
+	}
+
+	write(time.Now().Add(time.Hour), nil)
+	for i, max := range []time.Duration{time.Second, 2 * time.Second} {
+		if d := <-waits; d < max/2 || d > max {
+			t.Fatalf("%d. unexpected backoff: %s", i, d)
+		} else if attempts != i+1 {
+			t.Fatalf("%d. retried before backing off: %d attempts", i, attempts)
+		}
+		fire <- time.Time{}
+	}
+	if err := <-errs; err != nil {
+		t.Fatal(err)
+	} else if attempts != 3 {
+		t.Fatalf("unexpected attempts: %d", attempts)
+	}
+
+	// A write canceled while backing off is not retried.
+	attempts = 0
+	cancel := make(chan struct{})
+	write(time.Now().Add(time.Hour), cancel)
+	<-waits
+	close(cancel)
+	if err := <-errs; err != ErrWriteCanceled {
+		t.Fatalf("unexpected error: %v", err)
+	} else if attempts != 1 {
+		t.Fatalf("unexpected attempts: %d", attempts)
+	}
+
+	// Nor is a write that would be retried after its deadline.
+	attempts = 0
+	write(time.Now().Add(policy.MinBackoff/4), nil)
+	if err := <-errs; err == nil {
+		t.Fatal("expected error")
+	} else if attempts != 1 {
+		t.Fatalf("unexpected attempts: %d", attempts)
+	}
+}
+
+// shardWriterFunc is a ShardWriter calling itself for every write.
+type shardWriterFunc func(shardID, nodeID uint64) error
+
+func (fn shardWriterFunc) WriteEncodedShard(span Span, requestID string, deadline time.Time, shardID, ownerID uint64, points *rpc.EncodedPoints) error {
+	return fn(shardID, ownerID)
+}
@@ -1,16 +1,23 @@
 package cluster_test
 
 import (
+	"errors"
 	"fmt"
+	"reflect"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/influxdata/influxdb/coordinator"
 	"github.com/influxdata/influxdb/models"
 	"github.com/influxdata/influxdb/services/meta"
+	"github.com/influxdata/influxdb/tsdb"
 	"github.com/zhexuany/influxcloud"
 	"github.com/zhexuany/influxcloud/cluster"
+	cloudMeta "github.com/zhexuany/influxcloud/meta"
+	"github.com/zhexuany/influxcloud/rpc"
 )
 
 // Ensures the points writer maps a single point to a single shard.
@@ -121,6 +128,251 @@ func TestPointsWriter_MapShards_Multiple(t *testing.T) {
 	}
 }
 
+// Ensures the points writer caches the retention policy and shard groups
+// until the meta data changes.
+func TestPointsWriter_MapShards_Cache(t *testing.T) {
+	rp := NewRetentionPolicy("myp", time.Hour, 3)
+
+	var rpN, sgN int64
+	ms := &notifyingMetaClient{changed: make(chan struct{})}
+	ms.RetentionPolicyFn = func(db, retentionPolicy string) (*meta.RetentionPolicyInfo, error) {
+		atomic.AddInt64(&rpN, 1)
+		return rp, nil
+	}
+	ms.CreateShardGroupIfNotExistsFn = func(database, policy string, timestamp time.Time) (*meta.ShardGroupInfo, error) {
+		atomic.AddInt64(&sgN, 1)
+		return &rp.ShardGroups[0], nil
+	}
+
+	c := cluster.NewPointsWriter()
+	c.MetaClient = ms
+	if err := c.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	pr := &cluster.WritePointsRequest{Database: "mydb", RetentionPolicy: "myrp"}
+	pr.AddPoint("cpu", 1.0, time.Now(), nil)
+	for i := 0; i < 3; i++ {
+		if _, err := c.MapShards(pr); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if rpN != 1 || sgN != 1 {
+		t.Fatalf("unexpected meta lookups: rp=%d sg=%d", rpN, sgN)
+	}
+
+	// The cache is cleared once the meta data changes.
+	ms.Change()
+	for i := 0; ; i++ {
+		if _, err := c.MapShards(pr); err != nil {
+			t.Fatal(err)
+		} else if atomic.LoadInt64(&rpN) == 2 {
+			break
+		} else if i == 100 {
+			t.Fatal("cache not cleared after meta data change")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// Ensures the points writer only drops the cached meta data of the database
+// a watched change is about.
+func TestPointsWriter_MapShards_CacheWatch(t *testing.T) {
+	rp := NewRetentionPolicy("myp", time.Hour, 3)
+
+	var mu sync.Mutex
+	lookups := make(map[string]int)
+	ms := &watchingMetaClient{changes: make(chan cloudMeta.Change, 1)}
+	ms.RetentionPolicyFn = func(db, retentionPolicy string) (*meta.RetentionPolicyInfo, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		lookups[db]++
+		return rp, nil
+	}
+	ms.CreateShardGroupIfNotExistsFn = func(database, policy string, timestamp time.Time) (*meta.ShardGroupInfo, error) {
+		return &rp.ShardGroups[0], nil
+	}
+	lookupN := func(db string) int {
+		mu.Lock()
+		defer mu.Unlock()
+		return lookups[db]
+	}
+
+	c := cluster.NewPointsWriter()
+	c.MetaClient = ms
+	if err := c.Open(); err != nil {
+		t.Fatal(err)
+	}
+
+	mapShards := func(db string) {
+		pr := &cluster.WritePointsRequest{Database: db, RetentionPolicy: "myrp"}
+		pr.AddPoint("cpu", 1.0, time.Now(), nil)
+		if _, err := c.MapShards(pr); err != nil {
+			t.Fatal(err)
+		}
+	}
+	mapShards("db0")
+	mapShards("db1")
+
+	ms.changes <- cloudMeta.Change{Type: cloudMeta.ChangeShardGroupCreated, Index: 2, Database: "db0"}
+	for i := 0; ; i++ {
+		mapShards("db0")
+		if lookupN("db0") == 2 {
+			break
+		} else if i == 100 {
+			t.Fatal("cache not invalidated after meta data change")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	mapShards("db1")
+	if n := lookupN("db1"); n != 1 {
+		t.Fatalf("unexpected lookups of db1: %d", n)
+	}
+
+	// The watch ends with the points writer.
+	c.Close()
+	select {
+	case <-ms.cancelled:
+	case <-time.After(5 * time.Second):
+		t.Fatal("watch not cancelled")
+	}
+}
+
+// watchingMetaClient is a PointsWriterMetaClient that reports what changed.
+type watchingMetaClient struct {
+	PointsWriterMetaClient
+	changes   chan cloudMeta.Change
+	cancelled chan struct{}
+}
+
+func (m *watchingMetaClient) Watch(n int) (<-chan cloudMeta.Change, func()) {
+	m.cancelled = make(chan struct{})
+	return m.changes, func() { close(m.cancelled) }
+}
+
+// Ensures the points writer drops or rejects points outside the write window.
+func TestPointsWriter_MapShards_WriteWindow(t *testing.T) {
+	rp := NewRetentionPolicy("myp", 0, 3)
+
+	var sgN int64
+	ms := PointsWriterMetaClient{}
+	ms.RetentionPolicyFn = func(db, retentionPolicy string) (*meta.RetentionPolicyInfo, error) {
+		return rp, nil
+	}
+	ms.CreateShardGroupIfNotExistsFn = func(database, policy string, timestamp time.Time) (*meta.ShardGroupInfo, error) {
+		atomic.AddInt64(&sgN, 1)
+		return &meta.ShardGroupInfo{
+			ID:        uint64(sgN),
+			StartTime: timestamp.Truncate(time.Hour),
+			EndTime:   timestamp.Truncate(time.Hour).Add(time.Hour),
+			Shards:    []meta.ShardInfo{{ID: uint64(sgN)}},
+		}, nil
+	}
+
+	c := cluster.NewPointsWriter()
+	c.MetaClient = ms
+	c.MetaCacheTTL = 0
+	c.MaxFutureWrite = time.Hour
+	c.MaxPastWrite = time.Hour
+
+	now := time.Now()
+	pr := &cluster.WritePointsRequest{Database: "mydb", RetentionPolicy: "myrp"}
+	pr.AddPoint("cpu", 1.0, now, nil)
+	pr.AddPoint("cpu", 2.0, now.Add(2*time.Hour), nil)
+	pr.AddPoint("cpu", 3.0, now.Add(-2*time.Hour), nil)
+
+	mapping, err := c.MapShards(pr)
+	if err != nil {
+		t.Fatal(err)
+	} else if sgN != 1 {
+		t.Fatalf("unexpected shard groups created: %d", sgN)
+	}
+	var n int
+	for _, points := range mapping.Points {
+		n += len(points)
+	}
+	if n != 1 {
+		t.Fatalf("unexpected mapped points: %d", n)
+	}
+
+	c.RejectOutOfBoundsWrites = true
+	if _, err := c.MapShards(pr); err != cluster.ErrWriteOutOfBounds {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	stats := c.Statistics(nil)
+	if got := stats[0].Values["writeOutOfBounds"]; got != int64(5) {
+		t.Fatalf("unexpected out of bounds count: %v", got)
+	}
+}
+
+// notifyingMetaClient is a PointsWriterMetaClient that reports changes.
+type notifyingMetaClient struct {
+	PointsWriterMetaClient
+	mu      sync.Mutex
+	changed chan struct{}
+}
+
+func (m *notifyingMetaClient) WaitForDataChanged() chan struct{} {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.changed
+}
+
+// Change signals a change to the meta data.
+func (m *notifyingMetaClient) Change() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	close(m.changed)
+	m.changed = make(chan struct{})
+}
+
+// Ensures planning maps points to the shard groups that exist without
+// creating any, and reports the nodes owning the shards mapped.
+func TestPointsWriter_Plan(t *testing.T) {
+	ms := PointsWriterMetaClient{}
+	rp := NewRetentionPolicy("myp", time.Hour, 3)
+	AttachShardGroupInfo(rp, []meta.ShardOwner{{NodeID: 4}})
+
+	ms.RetentionPolicyFn = func(db, retentionPolicy string) (*meta.RetentionPolicyInfo, error) {
+		return rp, nil
+	}
+	ms.CreateShardGroupIfNotExistsFn = func(database, policy string, timestamp time.Time) (*meta.ShardGroupInfo, error) {
+		t.Fatal("unexpected shard group creation")
+		return nil, nil
+	}
+
+	c := cluster.NewPointsWriter()
+	c.MetaClient = ms
+	pr := &cluster.WritePointsRequest{Database: "mydb", RetentionPolicy: "myrp"}
+	start := rp.ShardGroups[0].StartTime
+	pr.AddPoint("cpu", 1.0, start, nil)
+	pr.AddPoint("cpu", 2.0, start.Add(90*time.Minute), nil)
+	pr.AddPoint("cpu", 3.0, start.Add(3*time.Hour), nil)
+	pr.AddPoint("cpu", 4.0, start.Add(-2*time.Hour), nil)
+
+	mapping, err := c.Plan(pr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(mapping.Points) != 2 {
+		t.Fatalf("unexpected shards: %v", mapping.Points)
+	} else if len(mapping.Pending) != 1 || mapping.Pending[0] != pr.Points[2] {
+		t.Fatalf("unexpected pending points: %v", mapping.Pending)
+	} else if len(mapping.Dropped) != 1 || mapping.Dropped[0] != pr.Points[3] {
+		t.Fatalf("unexpected dropped points: %v", mapping.Dropped)
+	} else if exp := []uint64{4}; !reflect.DeepEqual(mapping.Owners[rp.ShardGroups[1].Shards[0].ID], exp) {
+		t.Fatalf("unexpected owners: %v", mapping.Owners)
+	} else if exp := []uint64{1, 2, 3, 4}; !reflect.DeepEqual(mapping.NodeIDs(), exp) {
+		t.Fatalf("unexpected node ids: %v", mapping.NodeIDs())
+	}
+	if got := c.Statistics(nil)[0].Values["writeDrop"]; got != int64(0) {
+		t.Fatalf("unexpected dropped points count: %v", got)
+	}
+}
+
 // TestPointsWriter_WritePoints is correct if TestPointsWriter_MapShards_Multiple/One also right.
 func TestPointsWriter_WritePoints(t *testing.T) {
 	tests := []struct {
@@ -207,7 +459,7 @@ func TestPointsWriter_WritePoints(t *testing.T) {
 			},
 		}
 
-		hh := &fakeShardWriter{
+		hh := &fakeHintedHandoff{
 			ShardWriteFn: func(shardID, nodeID uint64, points []models.Point) error {
 				return nil
 			},
@@ -260,6 +512,1027 @@ func TestPointsWriter_WritePoints(t *testing.T) {
 	}
 }
 
+// Ensure writes to a node with paused replication are queued in hinted handoff.
+func TestPointsWriter_WritePoints_ReplicationPaused(t *testing.T) {
+	var mu sync.Mutex
+	written := make(map[uint64]int)
+	queued := make(map[uint64]int)
+
+	c := cluster.NewPointsWriter()
+	c.MetaClient = NewPointsWriterMetaClient()
+	c.ShardWriter = &fakeShardWriter{
+		ShardWriteFn: func(shardID, nodeID uint64, points []models.Point) error {
+			mu.Lock()
+			defer mu.Unlock()
+			written[nodeID]++
+			return nil
+		},
+	}
+	c.HintedHandoff = &fakeHintedHandoff{
+		ShardWriteFn: func(shardID, nodeID uint64, points []models.Point) error {
+			mu.Lock()
+			defer mu.Unlock()
+			queued[nodeID]++
+			return nil
+		},
+	}
+	c.TSDBStore = &fakeStore{
+		WriteFn: func(shardID uint64, points []models.Point) error { return nil },
+	}
+	c.Node = &influxcloud.Node{ID: 1}
+	c.Events = cluster.NewEventBus()
+	events, cancel := c.Events.Subscribe(1)
+	defer cancel()
+	c.Open()
+	defer c.Close()
+
+	c.PauseReplication(2)
+
+	pr := &cluster.WritePointsRequest{Database: "mydb", RetentionPolicy: "myrp"}
+	pr.AddPoint("cpu", 1.0, time.Now(), nil)
+
+	// Node 2 only received the write through hinted handoff.
+	if err := c.WritePoints(pr.Database, pr.RetentionPolicy, models.ConsistencyLevelAll, pr.Points); err != cluster.ErrPartialWrite {
+		t.Fatalf("unexpected error: %v", err)
+	} else if written[2] != 0 || queued[2] != 1 {
+		t.Fatalf("unexpected writes to node 2: written=%d queued=%d", written[2], queued[2])
+	} else if written[3] != 1 || queued[3] != 0 {
+		t.Fatalf("unexpected writes to node 3: written=%d queued=%d", written[3], queued[3])
+	} else if e := <-events; e.Type != cluster.EventWritePartial || e.ShardID == 0 {
+		t.Fatalf("unexpected event: %+v", e)
+	}
+
+	c.ResumeReplication(2)
+	if err := c.WritePoints(pr.Database, pr.RetentionPolicy, models.ConsistencyLevelAll, pr.Points); err != nil {
+		t.Fatal(err)
+	} else if written[2] != 1 {
+		t.Fatalf("unexpected writes to node 2: %d", written[2])
+	}
+}
+
+// Ensure the retry policy is consulted when a remote write fails.
+func TestPointsWriter_WritePoints_RetryPolicy(t *testing.T) {
+	var mu sync.Mutex
+	attempts := make(map[uint64]int)
+	var queued int
+
+	c := cluster.NewPointsWriter()
+	c.MetaClient = NewPointsWriterMetaClient()
+	c.ShardWriter = &fakeShardWriter{
+		ShardWriteFn: func(shardID, nodeID uint64, points []models.Point) error {
+			mu.Lock()
+			defer mu.Unlock()
+			attempts[nodeID]++
+			if nodeID == 2 && attempts[nodeID] < 3 {
+				return &rpc.WriteShardError{Code: rpc.CodeOverloaded, Message: "cache-max-memory-size exceeded"}
+			}
+			return nil
+		},
+	}
+	c.HintedHandoff = &fakeHintedHandoff{
+		ShardWriteFn: func(shardID, nodeID uint64, points []models.Point) error {
+			mu.Lock()
+			defer mu.Unlock()
+			queued++
+			return nil
+		},
+	}
+	c.TSDBStore = &fakeStore{
+		WriteFn: func(shardID uint64, points []models.Point) error { return nil },
+	}
+	c.RetryPolicy = cluster.NewStrictRetryPolicy()
+	c.Node = &influxcloud.Node{ID: 1}
+	c.Open()
+	defer c.Close()
+
+	pr := &cluster.WritePointsRequest{Database: "mydb", RetentionPolicy: "myrp"}
+	pr.AddPoint("cpu", 1.0, time.Now(), nil)
+
+	if err := c.WritePoints(pr.Database, pr.RetentionPolicy, models.ConsistencyLevelAll, pr.Points); err != nil {
+		t.Fatal(err)
+	} else if attempts[2] != 3 {
+		t.Fatalf("unexpected attempts to node 2: %d", attempts[2])
+	} else if queued != 0 {
+		t.Fatalf("unexpected hinted handoff writes: %d", queued)
+	}
+}
+
+// Ensure draining waits for in-flight writes and rejects new ones.
+func TestPointsWriter_Drain(t *testing.T) {
+	release := make(chan struct{})
+
+	c := cluster.NewPointsWriter()
+	c.MetaClient = NewPointsWriterMetaClient()
+	c.ShardWriter = &fakeShardWriter{
+		ShardWriteFn: func(shardID, nodeID uint64, points []models.Point) error {
+			<-release
+			return nil
+		},
+	}
+	c.TSDBStore = &fakeStore{
+		WriteFn: func(shardID uint64, points []models.Point) error { return nil },
+	}
+	c.Node = &influxcloud.Node{ID: 1}
+	c.Open()
+	defer c.Close()
+
+	pr := &cluster.WritePointsRequest{Database: "mydb", RetentionPolicy: "myrp"}
+	pr.AddPoint("cpu", 1.0, time.Now(), nil)
+
+	// The local write satisfies consistency level ONE; the remote writes are
+	// still in flight when WritePoints returns.
+	if err := c.WritePoints(pr.Database, pr.RetentionPolicy, models.ConsistencyLevelOne, pr.Points); err != nil {
+		t.Fatal(err)
+	}
+
+	drained := make(chan error)
+	go func() { drained <- c.Drain() }()
+
+	select {
+	case <-drained:
+		t.Fatal("drain returned before in-flight writes finished")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(release)
+	if err := <-drained; err != nil {
+		t.Fatal(err)
+	} else if err := c.WritePoints(pr.Database, pr.RetentionPolicy, models.ConsistencyLevelOne, pr.Points); err != cluster.ErrDraining {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+// Ensure points older than the retention policy are reported when requested.
+func TestPointsWriter_WritePoints_DroppedPoints(t *testing.T) {
+	c := cluster.NewPointsWriter()
+	c.MetaClient = NewPointsWriterMetaClient()
+	c.ShardWriter = &fakeShardWriter{
+		ShardWriteFn: func(shardID, nodeID uint64, points []models.Point) error { return nil },
+	}
+	c.TSDBStore = &fakeStore{
+		WriteFn: func(shardID uint64, points []models.Point) error { return nil },
+	}
+	c.Node = &influxcloud.Node{ID: 1}
+	c.Open()
+	defer c.Close()
+
+	pr := &cluster.WritePointsRequest{Database: "mydb", RetentionPolicy: "myrp"}
+	pr.AddPoint("cpu", 1.0, time.Now(), nil)
+	pr.AddPoint("cpu", 2.0, time.Now().Add(-2*time.Hour), nil)
+
+	// Dropped points are only counted by default.
+	if err := c.WritePoints(pr.Database, pr.RetentionPolicy, models.ConsistencyLevelOne, pr.Points); err != nil {
+		t.Fatal(err)
+	}
+
+	c.ErrorOnDroppedPoints = true
+	err := c.WritePoints(pr.Database, pr.RetentionPolicy, models.ConsistencyLevelOne, pr.Points)
+	if e, ok := err.(tsdb.PartialWriteError); !ok || e.Dropped != 1 {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got := c.Statistics(nil)[0].Values["writeDrop"]; got != int64(2) {
+		t.Fatalf("unexpected dropped count: %v", got)
+	}
+}
+
+// Ensure a write gives up at its own deadline rather than the write timeout.
+func TestPointsWriter_WritePointsWithDeadline(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+
+	c := cluster.NewPointsWriter()
+	c.WriteTimeout = time.Minute
+	c.MetaClient = NewPointsWriterMetaClient()
+	c.ShardWriter = &fakeShardWriter{
+		ShardWriteFn: func(shardID, nodeID uint64, points []models.Point) error {
+			<-release
+			return nil
+		},
+	}
+	c.TSDBStore = &fakeStore{
+		WriteFn: func(shardID uint64, points []models.Point) error { return nil },
+	}
+	c.Node = &influxcloud.Node{ID: 1}
+	c.Open()
+	defer c.Close()
+
+	pr := &cluster.WritePointsRequest{Database: "mydb", RetentionPolicy: "myrp"}
+	pr.AddPoint("cpu", 1.0, time.Now(), nil)
+
+	start := time.Now()
+	err := c.WritePointsWithDeadline(start.Add(50*time.Millisecond), pr.Database, pr.RetentionPolicy, models.ConsistencyLevelAll, pr.Points)
+	if err != cluster.ErrTimeout {
+		t.Fatalf("unexpected error: %v", err)
+	} else if d := time.Since(start); d > 5*time.Second {
+		t.Fatalf("write took %s", d)
+	}
+}
+
+// Ensure each attempt to write to an owner is bounded by the owner write
+// timeout, so that a slow owner is queued in hinted handoff long before the
+// request times out.
+func TestPointsWriter_WritePoints_OwnerWriteTimeout(t *testing.T) {
+	queued := make(chan uint64, 2)
+
+	c := cluster.NewPointsWriter()
+	c.WriteTimeout = time.Minute
+	c.OwnerWriteTimeout = 50 * time.Millisecond
+	c.MetaClient = NewPointsWriterMetaClient()
+	c.ShardWriter = deadlineShardWriter(func(deadline time.Time, nodeID uint64) error {
+		if nodeID != 2 {
+			return nil
+		}
+		// Node 2 does not respond before the write gives up on it.
+		time.Sleep(time.Until(deadline))
+		return cluster.ErrTimeout
+	})
+	c.HintedHandoff = &fakeHintedHandoff{
+		ShardWriteFn: func(shardID, nodeID uint64, points []models.Point) error {
+			queued <- nodeID
+			return nil
+		},
+	}
+	c.TSDBStore = &fakeStore{
+		WriteFn: func(shardID uint64, points []models.Point) error { return nil },
+	}
+	c.Node = &influxcloud.Node{ID: 1}
+	c.Open()
+	defer c.Close()
+
+	pr := &cluster.WritePointsRequest{Database: "mydb", RetentionPolicy: "myrp"}
+	pr.AddPoint("cpu", 1.0, time.Now(), nil)
+
+	if err := c.WritePoints(pr.Database, pr.RetentionPolicy, models.ConsistencyLevelOne, pr.Points); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case nodeID := <-queued:
+		if nodeID != 2 {
+			t.Fatalf("unexpected hinted handoff write to node %d", nodeID)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("write to node 2 was not bounded by the owner write timeout")
+	}
+}
+
+// Ensure hedged writes at consistency ONE wait on the local owner alone, and
+// write to the other owners once it succeeded.
+func TestPointsWriter_WritePoints_Hedge(t *testing.T) {
+	release := make(chan struct{})
+	remote := make(chan uint64, 2)
+
+	c := cluster.NewPointsWriter()
+	c.WriteHedgeDelay = time.Minute
+	c.MetaClient = NewPointsWriterMetaClient()
+	c.ShardWriter = &fakeShardWriter{
+		ShardWriteFn: func(shardID, nodeID uint64, points []models.Point) error {
+			remote <- nodeID
+			return nil
+		},
+	}
+	c.TSDBStore = &fakeStore{
+		WriteFn: func(shardID uint64, points []models.Point) error {
+			<-release
+			return nil
+		},
+	}
+	c.Node = &influxcloud.Node{ID: 1}
+	c.Open()
+	defer c.Close()
+
+	pr := &cluster.WritePointsRequest{Database: "mydb", RetentionPolicy: "myrp"}
+	pr.AddPoint("cpu", 1.0, time.Now(), nil)
+
+	done := make(chan error)
+	go func() { done <- c.WritePoints(pr.Database, pr.RetentionPolicy, models.ConsistencyLevelOne, pr.Points) }()
+
+	select {
+	case nodeID := <-remote:
+		t.Fatalf("unexpected write to node %d before the local write", nodeID)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(release)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		select {
+		case <-remote:
+		case <-time.After(5 * time.Second):
+			t.Fatal("remote owners were not written to")
+		}
+	}
+	if got := c.Statistics(nil)[0].Values["writeHedge"]; got != int64(0) {
+		t.Fatalf("unexpected hedged writes: %v", got)
+	}
+}
+
+// Ensure hedged writes fan out to the other owners once the local owner is
+// slower than the hedge delay.
+func TestPointsWriter_WritePoints_HedgeDelay(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+
+	c := cluster.NewPointsWriter()
+	c.WriteHedgeDelay = 20 * time.Millisecond
+	c.MetaClient = NewPointsWriterMetaClient()
+	c.ShardWriter = &fakeShardWriter{
+		ShardWriteFn: func(shardID, nodeID uint64, points []models.Point) error { return nil },
+	}
+	c.TSDBStore = &fakeStore{
+		WriteFn: func(shardID uint64, points []models.Point) error {
+			<-release
+			return nil
+		},
+	}
+	c.Node = &influxcloud.Node{ID: 1}
+	c.Open()
+	defer c.Close()
+
+	pr := &cluster.WritePointsRequest{Database: "mydb", RetentionPolicy: "myrp"}
+	pr.AddPoint("cpu", 1.0, time.Now(), nil)
+
+	if err := c.WritePoints(pr.Database, pr.RetentionPolicy, models.ConsistencyLevelOne, pr.Points); err != nil {
+		t.Fatal(err)
+	} else if got := c.Statistics(nil)[0].Values["writeHedge"]; got != int64(1) {
+		t.Fatalf("unexpected hedged writes: %v", got)
+	}
+}
+
+// Ensure remote writes at consistency ANY are not retried once another owner
+// was written to, and are queued in hinted handoff instead.
+func TestPointsWriter_WritePoints_AnyCancel(t *testing.T) {
+	started := make(chan struct{}, 2)
+	release := make(chan struct{})
+	queued := make(chan uint64, 2)
+	var attempts int64
+
+	c := cluster.NewPointsWriter()
+	c.MetaClient = NewPointsWriterMetaClient()
+	c.ShardWriter = &fakeShardWriter{
+		ShardWriteFn: func(shardID, nodeID uint64, points []models.Point) error {
+			atomic.AddInt64(&attempts, 1)
+			started <- struct{}{}
+			<-release
+			return &rpc.WriteShardError{Code: rpc.CodeOverloaded, Message: "cache-max-memory-size exceeded"}
+		},
+	}
+	c.HintedHandoff = &fakeHintedHandoff{
+		ShardWriteFn: func(shardID, nodeID uint64, points []models.Point) error {
+			queued <- nodeID
+			return nil
+		},
+	}
+	c.TSDBStore = &fakeStore{
+		WriteFn: func(shardID uint64, points []models.Point) error {
+			// Succeed once the remote writes were sent.
+			<-started
+			<-started
+			return nil
+		},
+	}
+	c.RetryPolicy = cluster.NewStrictRetryPolicy()
+	c.Node = &influxcloud.Node{ID: 1}
+	c.Open()
+	defer c.Close()
+
+	pr := &cluster.WritePointsRequest{Database: "mydb", RetentionPolicy: "myrp"}
+	pr.AddPoint("cpu", 1.0, time.Now(), nil)
+
+	if err := c.WritePoints(pr.Database, pr.RetentionPolicy, models.ConsistencyLevelAny, pr.Points); err != nil {
+		t.Fatal(err)
+	}
+
+	// The remote owners are overloaded, which is retried unless canceled.
+	close(release)
+	for i := 0; i < 2; i++ {
+		select {
+		case <-queued:
+		case <-time.After(5 * time.Second):
+			t.Fatal("remote writes were not queued in hinted handoff")
+		}
+	}
+	if n := atomic.LoadInt64(&attempts); n != 2 {
+		t.Fatalf("unexpected remote attempts: %d", n)
+	} else if got := c.Statistics(nil)[0].Values["writeCancel"]; got != int64(2) {
+		t.Fatalf("unexpected canceled writes: %v", got)
+	}
+}
+
+// Ensure writes mostly owned by a remote node are forwarded to it as a whole,
+// and split here if forwarding fails before the node wrote them.
+func TestPointsWriter_WritePoints_Forward(t *testing.T) {
+	for _, tt := range []struct {
+		name       string
+		threshold  float64
+		forwardErr error
+		forwarded  bool
+		split      bool
+		err        bool
+	}{
+		{name: "forwarded", threshold: 0.5, forwarded: true},
+		{name: "disabled", threshold: 0, split: true},
+		{name: "below threshold", threshold: 1.5, split: true},
+		{name: "fallback", threshold: 0.5, forwardErr: fmt.Errorf("EOF"), forwarded: true, split: true},
+		{name: "draining", threshold: 0.5, forwardErr: &rpc.WriteShardError{Code: rpc.CodeDraining}, forwarded: true, split: true},
+		{name: "remote error", threshold: 0.5, forwardErr: &rpc.WriteShardError{Code: rpc.CodeFieldTypeConflict}, forwarded: true, err: true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			var forwardedTo uint64
+			var forwardedN, shardWrites int64
+
+			c := cluster.NewPointsWriter()
+			c.MetaClient = NewPointsWriterMetaClient()
+			c.ShardWriter = &fakeShardWriter{
+				ShardWriteFn: func(shardID, nodeID uint64, points []models.Point) error {
+					atomic.AddInt64(&shardWrites, 1)
+					return nil
+				},
+			}
+			c.Forwarder = &fakeForwarder{
+				ForwardFn: func(nodeID uint64, database, retentionPolicy string, points []models.Point) error {
+					forwardedTo = nodeID
+					forwardedN = int64(len(points))
+					return tt.forwardErr
+				},
+			}
+			c.ForwardThreshold = tt.threshold
+			// The node owns none of the shards written to.
+			c.Node = &influxcloud.Node{ID: 4}
+			c.Open()
+			defer c.Close()
+
+			pr := &cluster.WritePointsRequest{Database: "mydb", RetentionPolicy: "myrp"}
+			pr.AddPoint("cpu", 1.0, time.Now(), nil)
+			pr.AddPoint("cpu", 2.0, time.Now().Add(time.Hour), nil)
+
+			err := c.WritePoints(pr.Database, pr.RetentionPolicy, models.ConsistencyLevelOne, pr.Points)
+			if tt.err && err == nil {
+				t.Fatal("expected error")
+			} else if !tt.err && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if tt.forwarded {
+				// Every node owns both shards, so the lowest node ID wins.
+				if forwardedTo != 1 || forwardedN != 2 {
+					t.Fatalf("forwarded %d points to node %d", forwardedN, forwardedTo)
+				}
+			} else if forwardedTo != 0 {
+				t.Fatalf("unexpected forward to node %d", forwardedTo)
+			}
+			if got := atomic.LoadInt64(&shardWrites) > 0; got != tt.split {
+				t.Fatalf("got shard writes %v, expected %v", got, tt.split)
+			}
+
+			var forwards, fallbacks int64
+			if tt.forwarded && tt.split {
+				fallbacks = 1
+			} else if tt.forwarded {
+				forwards = 1
+			}
+			stats := c.Statistics(nil)[0].Values
+			if got := stats["writeForward"]; got != forwards {
+				t.Fatalf("got writeForward %v, expected %d", got, forwards)
+			} else if got := stats["writeForwardFail"]; got != fallbacks {
+				t.Fatalf("got writeForwardFail %v, expected %d", got, fallbacks)
+			}
+		})
+	}
+}
+
+// Ensure forwarded writes are written here and not forwarded again.
+func TestPointsWriter_WriteForwardedPoints(t *testing.T) {
+	var shardWrites int64
+	c := cluster.NewPointsWriter()
+	c.MetaClient = NewPointsWriterMetaClient()
+	c.ShardWriter = &fakeShardWriter{
+		ShardWriteFn: func(shardID, nodeID uint64, points []models.Point) error {
+			atomic.AddInt64(&shardWrites, 1)
+			return nil
+		},
+	}
+	c.Forwarder = &fakeForwarder{
+		ForwardFn: func(nodeID uint64, database, retentionPolicy string, points []models.Point) error {
+			t.Fatalf("unexpected forward to node %d", nodeID)
+			return nil
+		},
+	}
+	c.ForwardThreshold = 0.5
+	c.Node = &influxcloud.Node{ID: 4}
+	c.Open()
+	defer c.Close()
+
+	pr := &cluster.WritePointsRequest{Database: "mydb", RetentionPolicy: "myrp"}
+	pr.AddPoint("cpu", 1.0, time.Now(), nil)
+
+	if err := c.WriteForwardedPoints("req", time.Time{}, pr.Database, pr.RetentionPolicy, models.ConsistencyLevelOne, pr.Points); err != nil {
+		t.Fatal(err)
+	} else if atomic.LoadInt64(&shardWrites) == 0 {
+		t.Fatal("expected shard writes")
+	} else if got := c.Statistics(nil)[0].Values["writeForwardReq"]; got != int64(1) {
+		t.Fatalf("unexpected writeForwardReq stat: %v", got)
+	}
+}
+
+// Ensure writes pass through the middleware in the order it was added before
+// being written, and that forwarded writes do not pass through it again.
+func TestPointsWriter_Use(t *testing.T) {
+	var mu sync.Mutex
+	var written []models.Point
+	write := func(shardID uint64, points []models.Point) error {
+		mu.Lock()
+		defer mu.Unlock()
+		written = append(written, points...)
+		return nil
+	}
+	c := cluster.NewPointsWriter()
+	c.MetaClient = NewPointsWriterMetaClient()
+	c.ShardWriter = &fakeShardWriter{
+		ShardWriteFn: func(shardID, nodeID uint64, points []models.Point) error { return write(shardID, points) },
+	}
+	c.TSDBStore = &fakeStore{WriteFn: write}
+	c.Node = &influxcloud.Node{ID: 1}
+	c.Open()
+	defer c.Close()
+
+	var calls []string
+	errBlocked := errors.New("blocked")
+	c.Use(func(next cluster.WriteFunc) cluster.WriteFunc {
+		return func(w *cluster.Write) error {
+			calls = append(calls, "validate")
+			if w.Database == "blocked" {
+				return errBlocked
+			}
+			return next(w)
+		}
+	}, func(next cluster.WriteFunc) cluster.WriteFunc {
+		return func(w *cluster.Write) error {
+			calls = append(calls, "enrich")
+			for _, p := range w.Points {
+				p.AddTag("region", "west")
+			}
+			return next(w)
+		}
+	})
+
+	pr := &cluster.WritePointsRequest{Database: "mydb", RetentionPolicy: "myrp"}
+	pr.AddPoint("cpu", 1.0, time.Now(), nil)
+
+	if err := c.WritePoints("blocked", "myrp", models.ConsistencyLevelOne, pr.Points); err != errBlocked {
+		t.Fatalf("unexpected error: %v", err)
+	} else if err := c.WritePoints(pr.Database, pr.RetentionPolicy, models.ConsistencyLevelOne, pr.Points); err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(calls, []string{"validate", "validate", "enrich"}) {
+		t.Fatalf("unexpected middleware calls: %v", calls)
+	}
+
+	mu.Lock()
+	if len(written) == 0 || written[0].Tags().GetString("region") != "west" {
+		t.Fatalf("unexpected points written: %v", written)
+	}
+	mu.Unlock()
+
+	calls = nil
+	if err := c.WriteForwardedPoints("req", time.Time{}, pr.Database, pr.RetentionPolicy, models.ConsistencyLevelOne, pr.Points); err != nil {
+		t.Fatal(err)
+	} else if len(calls) != 0 {
+		t.Fatalf("unexpected middleware calls: %v", calls)
+	}
+}
+
+// Ensure dry runs report how a write would be distributed without writing it.
+func TestPointsWriter_WritePointsDryRun(t *testing.T) {
+	write := func(shardID uint64, points []models.Point) error {
+		t.Fatalf("unexpected write to shard %d", shardID)
+		return nil
+	}
+	c := cluster.NewPointsWriter()
+	c.MetaClient = NewPointsWriterMetaClient()
+	c.ShardWriter = &fakeShardWriter{
+		ShardWriteFn: func(shardID, nodeID uint64, points []models.Point) error { return write(shardID, points) },
+	}
+	c.TSDBStore = &fakeStore{WriteFn: write}
+	c.Node = &influxcloud.Node{ID: 1}
+	c.BannedMeasurements = []string{"debug"}
+	c.PauseReplication(3)
+	c.Open()
+	defer c.Close()
+
+	var dryRun bool
+	c.Use(func(next cluster.WriteFunc) cluster.WriteFunc {
+		return func(w *cluster.Write) error {
+			dryRun = w.DryRun
+			return next(w)
+		}
+	})
+
+	pr := &cluster.WritePointsRequest{Database: "mydb", RetentionPolicy: "myrp"}
+	pr.AddPoint("cpu", 1.0, time.Now(), nil)
+	pr.AddPoint("cpu", 2.0, time.Now(), map[string]string{"host": "a"})
+	pr.AddPoint("cpu", 3.0, time.Now().Add(2*time.Hour), nil)
+
+	result, err := c.WritePointsDryRun(pr.Database, pr.RetentionPolicy, models.ConsistencyLevelQuorum, pr.Points)
+	if err != nil {
+		t.Fatal(err)
+	} else if !dryRun {
+		t.Fatal("expected middleware to see a dry run")
+	}
+
+	if len(result.Shards) != 2 {
+		t.Fatalf("unexpected shards: %+v", result.Shards)
+	} else if sh := result.Shards[0]; sh.PointN != 2 || sh.Required != 2 || !reflect.DeepEqual(sh.Owners, []uint64{1, 2, 3}) || !reflect.DeepEqual(sh.Paused, []uint64{3}) {
+		t.Fatalf("unexpected shard: %+v", sh)
+	} else if exp := map[uint64]int{1: 3, 2: 3, 3: 3}; !reflect.DeepEqual(result.Nodes, exp) {
+		t.Fatalf("unexpected nodes: %v", result.Nodes)
+	}
+	if got := c.Statistics(nil)[0].Values["req"]; got != int64(0) {
+		t.Fatalf("unexpected write requests: %v", got)
+	}
+
+	pr.AddPoint("debug", 1.0, time.Now(), nil)
+	if _, err := c.WritePointsDryRun(pr.Database, pr.RetentionPolicy, models.ConsistencyLevelOne, pr.Points); err == nil {
+		t.Fatal("expected validation error")
+	}
+}
+
+// Ensure writes with points breaking a validation rule are rejected whole,
+// and that the rejected writes are counted.
+func TestPointsWriter_WritePoints_Validation(t *testing.T) {
+	c := cluster.NewPointsWriter()
+	c.MetaClient = NewPointsWriterMetaClient()
+	c.ShardWriter = &fakeShardWriter{
+		ShardWriteFn: func(shardID, nodeID uint64, points []models.Point) error { return nil },
+	}
+	c.TSDBStore = &fakeStore{
+		WriteFn: func(shardID uint64, points []models.Point) error { return nil },
+	}
+	c.Node = &influxcloud.Node{ID: 1}
+	c.MaxTagsPerPoint = 2
+	c.MaxFieldValueSize = 4
+	c.BannedMeasurements = []string{"debug"}
+	c.BannedTags = []string{"session"}
+	c.MaxSeriesPerDatabase = 10
+	c.Cardinality = seriesCardinality{"mydb": 5, "full": 10}
+	c.Open()
+	defer c.Close()
+
+	for _, tt := range []struct {
+		database string
+		name     string
+		value    interface{}
+		tags     map[string]string
+		rule     string
+	}{
+		{database: "mydb", name: "cpu", value: 1.0, tags: map[string]string{"host": "a", "region": "west"}},
+		{database: "mydb", name: "cpu", value: 1.0, tags: map[string]string{"host": "a", "region": "west", "dc": "1"}, rule: "max-tags-per-point"},
+		{database: "mydb", name: "cpu", value: "abcde", rule: "max-field-value-size"},
+		{database: "mydb", name: "debug", value: 1.0, rule: "banned-measurement"},
+		{database: "mydb", name: "cpu", value: 1.0, tags: map[string]string{"session": "x"}, rule: "banned-tag"},
+		{database: "full", name: "cpu", value: 1.0, rule: "max-series-per-database"},
+	} {
+		pr := &cluster.WritePointsRequest{Database: tt.database, RetentionPolicy: "myrp"}
+		pr.AddPoint("cpu", 1.0, time.Now(), nil)
+		pr.AddPoint(tt.name, tt.value, time.Now(), tt.tags)
+
+		err := c.WritePoints(pr.Database, pr.RetentionPolicy, models.ConsistencyLevelOne, pr.Points)
+		if tt.rule == "" {
+			if err != nil {
+				t.Fatalf("%s: unexpected error: %v", tt.name, err)
+			}
+			continue
+		}
+		if e, ok := err.(*cluster.PointValidationError); !ok || e.Rule != tt.rule || e.Database != tt.database {
+			t.Fatalf("%s: unexpected error: %v", tt.rule, err)
+		}
+	}
+
+	stats := c.Statistics(nil)[0].Values
+	if got := stats["writeRejected"]; got != int64(5) {
+		t.Fatalf("unexpected rejected writes: %v", got)
+	} else if got := stats["pointWriteRejected"]; got != int64(10) {
+		t.Fatalf("unexpected rejected points: %v", got)
+	}
+}
+
+// Ensure points breaking a validation rule are dropped, logged or reject the
+// whole write as the partial write policy of their database decides.
+func TestPointsWriter_WritePoints_PartialWritePolicy(t *testing.T) {
+	var written int64
+	c := cluster.NewPointsWriter()
+	c.MetaClient = NewPointsWriterMetaClient()
+	c.ShardWriter = &fakeShardWriter{
+		ShardWriteFn: func(shardID, nodeID uint64, points []models.Point) error { return nil },
+	}
+	c.TSDBStore = &fakeStore{
+		WriteFn: func(shardID uint64, points []models.Point) error {
+			atomic.AddInt64(&written, int64(len(points)))
+			return nil
+		},
+	}
+	c.Node = &influxcloud.Node{ID: 1}
+	c.BannedMeasurements = []string{"debug"}
+	c.PartialWritePolicy = cluster.PartialWriteAcceptPartial
+	c.DatabasePartialWritePolicies = map[string]string{
+		"logged": cluster.PartialWriteAcceptAndLog,
+		"strict": cluster.PartialWriteRejectAll,
+	}
+	c.Open()
+	defer c.Close()
+
+	// write writes a valid and an invalid point to database.
+	write := func(database string) error {
+		atomic.StoreInt64(&written, 0)
+		pr := &cluster.WritePointsRequest{Database: database, RetentionPolicy: "myrp"}
+		pr.AddPoint("cpu", 1.0, time.Now(), nil)
+		pr.AddPoint("debug", 1.0, time.Now(), nil)
+		return c.WritePoints(pr.Database, pr.RetentionPolicy, models.ConsistencyLevelAll, pr.Points)
+	}
+
+	if err := write("mydb"); err == nil {
+		t.Fatal("expected error")
+	} else if e, ok := err.(tsdb.PartialWriteError); !ok || e.Dropped != 1 {
+		t.Fatalf("unexpected error: %#v", err)
+	} else if n := atomic.LoadInt64(&written); n != 1 {
+		t.Fatalf("unexpected points written: %d", n)
+	}
+
+	if err := write("logged"); err != nil {
+		t.Fatal(err)
+	} else if n := atomic.LoadInt64(&written); n != 1 {
+		t.Fatalf("unexpected points written: %d", n)
+	}
+
+	if err := write("strict"); err == nil {
+		t.Fatal("expected error")
+	} else if e, ok := err.(*cluster.PointValidationError); !ok || e.Rule != "banned-measurement" {
+		t.Fatalf("unexpected error: %#v", err)
+	} else if n := atomic.LoadInt64(&written); n != 0 {
+		t.Fatalf("unexpected points written: %d", n)
+	}
+}
+
+// seriesCardinality reports the number of series of each database.
+type seriesCardinality map[string]int64
+
+func (c seriesCardinality) SeriesN(database string) (int64, error) { return c[database], nil }
+
+// Ensure SELECT ... INTO writes use their own consistency level and report
+// the points of the shards that failed.
+func TestPointsWriter_WritePointsInto(t *testing.T) {
+	c := cluster.NewPointsWriter()
+	c.MetaClient = NewPointsWriterMetaClient()
+	c.ShardWriter = &fakeShardWriter{
+		ShardWriteFn: func(shardID, nodeID uint64, points []models.Point) error { return nil },
+	}
+	// The shard of the next hour fails locally.
+	c.TSDBStore = &fakeStore{
+		WriteFn: func(shardID uint64, points []models.Point) error {
+			if points[0].Time().After(time.Now().Add(30 * time.Minute)) {
+				return errors.New("disk full")
+			}
+			return nil
+		},
+	}
+	c.Node = &influxcloud.Node{ID: 1}
+	c.Open()
+	defer c.Close()
+
+	pr := &coordinator.IntoWriteRequest{Database: "mydb", RetentionPolicy: "myrp"}
+	for _, ts := range []time.Time{time.Now(), time.Now(), time.Now().Add(time.Hour)} {
+		pr.Points = append(pr.Points, models.MustNewPoint("cpu", nil, models.Fields{"value": 1.0}, ts))
+	}
+
+	// The remote owners are enough for consistency level one.
+	if err := c.WritePointsInto(pr); err != nil {
+		t.Fatal(err)
+	}
+
+	c.IntoConsistencyLevel = models.ConsistencyLevelAll
+	err := c.WritePointsInto(pr)
+	if e, ok := err.(tsdb.PartialWriteError); !ok || e.Dropped != 1 {
+		t.Fatalf("unexpected error: %v", err)
+	} else if !strings.Contains(e.Reason, "1 of 2 shards") {
+		t.Fatalf("unexpected reason: %s", e.Reason)
+	}
+
+	// A write that fails for every shard is not partial.
+	pr.Points = pr.Points[2:]
+	if err := c.WritePointsInto(pr); err == nil {
+		t.Fatal("expected error")
+	} else if _, ok := err.(tsdb.PartialWriteError); ok {
+		t.Fatalf("unexpected partial write error: %v", err)
+	}
+}
+
+// Ensure remote writes that succeed after timing out are not queued in
+// hinted handoff.
+func TestPointsWriter_WritePoints_LateWrite(t *testing.T) {
+	var mu sync.Mutex
+	queued := make(map[uint64]int)
+
+	c := cluster.NewPointsWriter()
+	c.MetaClient = NewPointsWriterMetaClient()
+	c.LateWriteWindow = time.Second
+	// Both remote writes time out, but the write to node 2 succeeds after.
+	c.ShardWriter = encodedShardWriter(func(nodeID uint64, points *rpc.EncodedPoints) error {
+		if nodeID == 2 {
+			go func() {
+				time.Sleep(10 * time.Millisecond)
+				c.LateWriteSucceeded(nodeID, points)
+			}()
+		}
+		return cluster.ErrTimeout
+	})
+	c.HintedHandoff = &fakeHintedHandoff{
+		ShardWriteFn: func(shardID, nodeID uint64, points []models.Point) error {
+			mu.Lock()
+			defer mu.Unlock()
+			queued[nodeID]++
+			return nil
+		},
+	}
+	c.TSDBStore = &fakeStore{
+		WriteFn: func(shardID uint64, points []models.Point) error { return nil },
+	}
+	c.Node = &influxcloud.Node{ID: 1}
+	c.Open()
+	defer c.Close()
+
+	pr := &cluster.WritePointsRequest{Database: "mydb", RetentionPolicy: "myrp"}
+	pr.AddPoint("cpu", 1.0, time.Now(), nil)
+
+	if err := c.WritePoints(pr.Database, pr.RetentionPolicy, models.ConsistencyLevelOne, pr.Points); err != nil {
+		t.Fatal(err)
+	}
+	c.Drain()
+
+	if !reflect.DeepEqual(queued, map[uint64]int{3: 1}) {
+		t.Fatalf("unexpected hinted handoff writes: %v", queued)
+	}
+	if v := c.Statistics(nil)[0].Values["writeLate"]; v != int64(1) {
+		t.Fatalf("unexpected late writes: %v", v)
+	}
+}
+
+// Ensure the points rejected by the shard owners are reported as a partial
+// write of the other points.
+func TestPointsWriter_WritePoints_Rejected(t *testing.T) {
+	written := make(chan struct{}, 2)
+
+	c := cluster.NewPointsWriter()
+	c.MetaClient = NewPointsWriterMetaClient()
+	c.ShardWriter = encodedShardWriter(func(nodeID uint64, points *rpc.EncodedPoints) error {
+		defer func() { written <- struct{}{} }()
+		return &rpc.WriteShardError{Code: rpc.CodeFieldTypeConflict, Message: "field type conflict", Rejected: []int{1}}
+	})
+	c.TSDBStore = &fakeStore{
+		WriteFn: func(shardID uint64, points []models.Point) error {
+			// Fail once the remote owners rejected the point.
+			<-written
+			<-written
+			return tsdb.PartialWriteError{Reason: tsdb.ErrFieldTypeConflict.Error(), Dropped: 1}
+		},
+	}
+	c.Node = &influxcloud.Node{ID: 1}
+	c.Open()
+	defer c.Close()
+
+	now := time.Now()
+	pr := &cluster.WritePointsRequest{Database: "mydb", RetentionPolicy: "myrp"}
+	pr.AddPoint("cpu", 1.0, now, nil)
+	pr.AddPoint("cpu", "bad", now.Add(time.Nanosecond), nil)
+
+	err := c.WritePoints(pr.Database, pr.RetentionPolicy, models.ConsistencyLevelOne, pr.Points)
+	if e, ok := err.(tsdb.PartialWriteError); !ok || e.Dropped != 1 {
+		t.Fatalf("unexpected error: %#v", err)
+	} else if exp := fmt.Sprintf("rejected points: [cpu %d]", now.Add(time.Nanosecond).UnixNano()); !strings.HasSuffix(e.Reason, exp) {
+		t.Fatalf("unexpected reason: %s", e.Reason)
+	}
+
+	// The owners write none of the points if the policy rejects them all.
+	c.PartialWritePolicy = cluster.PartialWriteRejectAll
+	err = c.WritePoints(pr.Database, pr.RetentionPolicy, models.ConsistencyLevelOne, pr.Points)
+	if e, ok := err.(tsdb.PartialWriteError); !ok || e.Dropped != 2 {
+		t.Fatalf("unexpected error: %#v", err)
+	}
+}
+
+// Ensure writes waiting on their consistency level are reported as in flight.
+func TestPointsWriter_InflightWrites(t *testing.T) {
+	release := make(chan struct{})
+
+	c := cluster.NewPointsWriter()
+	c.MetaClient = NewPointsWriterMetaClient()
+	c.ShardWriter = &fakeShardWriter{
+		ShardWriteFn: func(shardID, nodeID uint64, points []models.Point) error {
+			if nodeID == 3 {
+				<-release
+			}
+			return nil
+		},
+	}
+	c.HintedHandoff = &fakeHintedHandoff{
+		ShardWriteFn: func(shardID, nodeID uint64, points []models.Point) error { return nil },
+	}
+	c.TSDBStore = &fakeStore{
+		WriteFn: func(shardID uint64, points []models.Point) error { return nil },
+	}
+	c.Node = &influxcloud.Node{ID: 1}
+	c.Open()
+	defer c.Close()
+
+	pr := &cluster.WritePointsRequest{Database: "mydb", RetentionPolicy: "myrp"}
+	pr.AddPoint("cpu", 1.0, time.Now(), nil)
+
+	errC := make(chan error, 1)
+	go func() {
+		errC <- c.WritePoints(pr.Database, pr.RetentionPolicy, models.ConsistencyLevelAll, pr.Points)
+	}()
+
+	// Wait until every owner except node 3 has acknowledged the write.
+	var writes []cluster.InflightWrite
+	for i := 0; ; i++ {
+		writes = c.InflightWrites(0)
+		if len(writes) == 1 && len(writes[0].Acked) == 2 {
+			break
+		} else if i == 100 {
+			t.Fatalf("unexpected in-flight writes: %+v", writes)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if w := writes[0]; w.RequestID == "" || w.Required != 3 || len(w.Owners) != 3 || len(w.Failed) != 0 {
+		t.Fatalf("unexpected in-flight write: %+v", w)
+	} else if writes := c.InflightWrites(time.Hour); len(writes) != 0 {
+		t.Fatalf("unexpected writes above threshold: %+v", writes)
+	}
+
+	close(release)
+	if err := <-errC; err != nil {
+		t.Fatal(err)
+	} else if writes := c.InflightWrites(0); len(writes) != 0 {
+		t.Fatalf("unexpected in-flight writes after completion: %+v", writes)
+	}
+}
+
+func TestPointsWriter_WriteTraces(t *testing.T) {
+	c := cluster.NewPointsWriter()
+	c.MetaClient = NewPointsWriterMetaClient()
+	c.ShardWriter = &fakeShardWriter{
+		ShardWriteFn: func(shardID, nodeID uint64, points []models.Point) error { return nil },
+	}
+	c.TSDBStore = &fakeStore{
+		WriteFn: func(shardID uint64, points []models.Point) error { return nil },
+	}
+	c.Node = &influxcloud.Node{ID: 1}
+	c.Open()
+	defer c.Close()
+
+	// Tracing is disabled by default.
+	pr := &cluster.WritePointsRequest{Database: "mydb", RetentionPolicy: "myrp"}
+	pr.AddPoint("cpu", 1.0, time.Now(), nil)
+	if err := c.WritePoints(pr.Database, pr.RetentionPolicy, models.ConsistencyLevelAll, pr.Points); err != nil {
+		t.Fatal(err)
+	} else if traces := c.WriteTraces(); traces != nil {
+		t.Fatalf("unexpected traces: %+v", traces)
+	}
+
+	// Only the slowest trace is kept.
+	c.Tracer = cluster.NewWriteTracer(1)
+	for i := 0; i < 3; i++ {
+		if err := c.WritePoints(pr.Database, pr.RetentionPolicy, models.ConsistencyLevelAll, pr.Points); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	traces := c.WriteTraces()
+	if len(traces) != 1 {
+		t.Fatalf("unexpected traces: %+v", traces)
+	}
+
+	tr := traces[0]
+	if tr.TraceID == "" || tr.Database != "mydb" || tr.RetentionPolicy != "myrp" || tr.PointN != 1 || tr.Err != "" {
+		t.Fatalf("unexpected trace: %+v", tr)
+	}
+
+	stages := make(map[string][]uint64)
+	for _, s := range tr.Stages {
+		stages[s.Name] = append(stages[s.Name], s.NodeID)
+	}
+	if got := len(stages[cluster.StageMapShards]); got != 1 {
+		t.Fatalf("got %d shard mapping stages, expected 1", got)
+	} else if got, exp := stages[cluster.StageLocalWrite], []uint64{1}; !reflect.DeepEqual(got, exp) {
+		t.Fatalf("got local writes to %v, expected %v", got, exp)
+	} else if got := len(stages[cluster.StageRemoteWrite]); got != 2 {
+		t.Fatalf("got %d remote write stages, expected 2", got)
+	} else if got := len(stages[cluster.StageHintedHandoff]); got != 0 {
+		t.Fatalf("got %d hinted handoff stages, expected 0", got)
+	}
+}
+
 var shardID uint64
 
 type fakeShardWriter struct {
@@ -270,6 +1543,53 @@ func (f *fakeShardWriter) WriteShard(shardID, nodeID uint64, points []models.Poi
 	return f.ShardWriteFn(shardID, nodeID, points)
 }
 
+func (f *fakeShardWriter) WriteEncodedShard(span cluster.Span, requestID string, deadline time.Time, shardID, nodeID uint64, e *rpc.EncodedPoints) error {
+	points, err := e.Points()
+	if err != nil {
+		return err
+	}
+	return f.ShardWriteFn(shardID, nodeID, points)
+}
+
+// encodedShardWriter is a ShardWriter that is passed the encoded points.
+type encodedShardWriter func(nodeID uint64, points *rpc.EncodedPoints) error
+
+func (f encodedShardWriter) WriteEncodedShard(span cluster.Span, requestID string, deadline time.Time, shardID, nodeID uint64, e *rpc.EncodedPoints) error {
+	return f(nodeID, e)
+}
+
+// deadlineShardWriter is a ShardWriter that is passed the deadline of each
+// write.
+type deadlineShardWriter func(deadline time.Time, nodeID uint64) error
+
+func (f deadlineShardWriter) WriteEncodedShard(span cluster.Span, requestID string, deadline time.Time, shardID, nodeID uint64, e *rpc.EncodedPoints) error {
+	return f(deadline, nodeID)
+}
+
+type fakeForwarder struct {
+	ForwardFn func(nodeID uint64, database, retentionPolicy string, points []models.Point) error
+}
+
+func (f *fakeForwarder) ForwardPoints(span cluster.Span, requestID string, deadline time.Time, nodeID uint64, database, retentionPolicy string, consistencyLevel models.ConsistencyLevel, e *rpc.EncodedPoints) error {
+	points, err := e.Points()
+	if err != nil {
+		return err
+	}
+	return f.ForwardFn(nodeID, database, retentionPolicy, points)
+}
+
+type fakeHintedHandoff struct {
+	ShardWriteFn func(shardID, nodeID uint64, points []models.Point) error
+}
+
+func (f *fakeHintedHandoff) WriteEncodedShard(shardID, nodeID uint64, e *rpc.EncodedPoints) error {
+	points, err := e.Points()
+	if err != nil {
+		return err
+	}
+	return f.ShardWriteFn(shardID, nodeID, points)
+}
+
 type fakeStore struct {
 	WriteFn       func(shardID uint64, points []models.Point) error
 	CreateShardfn func(database, retentionPolicy string, shardID uint64) error
@@ -312,12 +1632,15 @@ func NewPointsWriterMetaClient() *PointsWriterMetaClient {
 	return ms
 }
 
+// PointsWriterMetaClient is a mockable implementation of
+// cluster.PointsWriter.MetaClient. The methods it does not mock panic.
 type PointsWriterMetaClient struct {
+	cluster.MetaClient
 	NodeIDFn                      func() uint64
 	RetentionPolicyFn             func(database, name string) (*meta.RetentionPolicyInfo, error)
 	CreateShardGroupIfNotExistsFn func(database, policy string, timestamp time.Time) (*meta.ShardGroupInfo, error)
 	DatabaseFn                    func(database string) *meta.DatabaseInfo
-	ShardOwnerFn                  func(shardID uint64) (string, string, *meta.ShardGroupInfo)
+	ShardOwnerFn                  func(shardID uint64) (string, string, meta.ShardInfo)
 }
 
 func (m PointsWriterMetaClient) NodeID() uint64 { return m.NodeIDFn() }
@@ -334,7 +1657,7 @@ func (m PointsWriterMetaClient) Database(database string) *meta.DatabaseInfo {
 	return m.DatabaseFn(database)
 }
 
-func (m PointsWriterMetaClient) ShardOwner(shardID uint64) (string, string, *meta.ShardGroupInfo) {
+func (m PointsWriterMetaClient) ShardOwner(shardID uint64) (string, string, meta.ShardInfo) {
 	return m.ShardOwnerFn(shardID)
 }
 
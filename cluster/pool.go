@@ -156,6 +156,34 @@ func (c *boundedPool) Close() {
 
 func (c *boundedPool) Len() int { return len(c.getConns()) }
 
+// checkIdle runs check on each idle connection in the pool. Connections
+// that fail the check are closed so that the next Get dials a new one.
+func (c *boundedPool) checkIdle(check func(net.Conn) error) {
+	conns := c.getConns()
+	if conns == nil {
+		return
+	}
+
+	for i, n := 0, len(conns); i < n; i++ {
+		var conn net.Conn
+		select {
+		case conn = <-conns:
+		default:
+			return
+		}
+		if conn == nil {
+			return
+		}
+
+		if err := check(conn); err != nil {
+			atomic.AddInt32(&c.total, -1)
+			conn.Close()
+			continue
+		}
+		c.put(conn)
+	}
+}
+
 // newConn wraps a standard net.Conn to a poolConn net.Conn.
 func (c *boundedPool) wrapConn(conn net.Conn) net.Conn {
 	p := &pooledConn{c: c}
@@ -0,0 +1,112 @@
+package cluster
+
+import (
+	"errors"
+	"sync"
+)
+
+// errPrefetchClosed is returned by a prefetchReader read after it is closed.
+var errPrefetchClosed = errors.New("remote iterator stream closed")
+
+// chunkReader reads the chunks of points of a remote iterator's stream.
+type chunkReader interface {
+	// readChunk returns the next chunk, or the error ending the stream.
+	readChunk() ([]byte, error)
+	Close() error
+}
+
+// prefetchReader reads the chunks of a remote iterator's stream ahead of the
+// iterator, so that the round trips to the node overlap with the merging of
+// the points already read. At most max bytes of chunks are buffered, but a
+// single larger chunk is always read.
+type prefetchReader struct {
+	r   chunkReader
+	max int
+
+	mu     sync.Mutex
+	cond   *sync.Cond
+	chunks [][]byte
+	size   int
+	err    error // The error ending the stream, once read.
+	closed bool
+
+	buf []byte // The rest of the chunk being read.
+}
+
+// newPrefetchReader returns a reader of r prefetching up to max bytes.
+func newPrefetchReader(r chunkReader, max int) *prefetchReader {
+	pr := &prefetchReader{r: r, max: max}
+	pr.cond = sync.NewCond(&pr.mu)
+	go pr.prefetch()
+	return pr
+}
+
+// prefetch reads the chunks of the stream while there is room for them.
+func (r *prefetchReader) prefetch() {
+	for {
+		buf, err := r.r.readChunk()
+		if err == nil && len(buf) == 0 {
+			continue
+		}
+
+		r.mu.Lock()
+		for !r.closed && r.size > 0 && r.size+len(buf) > r.max {
+			r.cond.Wait()
+		}
+		if r.closed {
+			r.mu.Unlock()
+			return
+		}
+		if err != nil {
+			r.err = err
+		} else {
+			r.chunks = append(r.chunks, buf)
+			r.size += len(buf)
+		}
+		r.cond.Broadcast()
+		r.mu.Unlock()
+
+		if err != nil {
+			return
+		}
+	}
+}
+
+// Read reads the points of the prefetched chunks, waiting for the next one
+// if none is buffered. It returns the error ending the stream once all the
+// chunks before it are read.
+func (r *prefetchReader) Read(p []byte) (int, error) {
+	if len(r.buf) == 0 {
+		r.mu.Lock()
+		for !r.closed && len(r.chunks) == 0 && r.err == nil {
+			r.cond.Wait()
+		}
+		if r.closed {
+			r.mu.Unlock()
+			return 0, errPrefetchClosed
+		} else if len(r.chunks) == 0 {
+			err := r.err
+			r.mu.Unlock()
+			return 0, err
+		}
+		r.buf = r.chunks[0]
+		r.chunks[0] = nil
+		r.chunks = r.chunks[1:]
+		r.size -= len(r.buf)
+		r.cond.Broadcast()
+		r.mu.Unlock()
+	}
+
+	n := copy(p, r.buf)
+	r.buf = r.buf[n:]
+	return n, nil
+}
+
+// Close stops prefetching and closes the stream.
+func (r *prefetchReader) Close() error {
+	r.mu.Lock()
+	r.closed = true
+	r.cond.Broadcast()
+	r.mu.Unlock()
+	return r.r.Close()
+}
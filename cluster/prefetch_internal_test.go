@@ -0,0 +1,90 @@
+package cluster
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Ensure chunks are read ahead of the reader up to the budget of the
+// prefetch reader, and read back in order.
+func TestPrefetchReader(t *testing.T) {
+	src := &chunkSource{chunks: [][]byte{[]byte("aaaa"), []byte("bb"), []byte("cccc"), []byte("dddddddd")}}
+	r := newPrefetchReader(src, 6)
+	defer r.Close()
+
+	// The first two chunks fit the budget, so the third waits for room
+	// once read.
+	waitForChunks(t, src, 3)
+	time.Sleep(10 * time.Millisecond)
+	if n := src.n(); n != 3 {
+		t.Fatalf("unexpected chunks read: %d", n)
+	}
+
+	// Reading the first chunk makes room for the third one; the fourth is
+	// larger than the budget, so it is read once the buffer is empty.
+	buf, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	} else if string(buf) != "aaaabbccccdddddddd" {
+		t.Fatalf("unexpected stream: %q", buf)
+	} else if _, err := r.Read(make([]byte, 1)); err != io.EOF {
+		t.Fatalf("unexpected error at end of stream: %v", err)
+	}
+
+	// Reads fail once the reader is closed.
+	src = &chunkSource{chunks: [][]byte{bytes.Repeat([]byte("a"), 10)}}
+	r = newPrefetchReader(src, 1)
+	r.Close()
+	if _, err := r.Read(make([]byte, 1)); err != errPrefetchClosed {
+		t.Fatalf("unexpected error: %v", err)
+	} else if !src.closed {
+		t.Fatal("expected stream to be closed")
+	}
+}
+
+// waitForChunks waits for n chunks of src to be read.
+func waitForChunks(t *testing.T, src *chunkSource, n int) {
+	for i := 0; i < 100 && src.n() < n; i++ {
+		time.Sleep(time.Millisecond)
+	}
+	if src.n() < n {
+		t.Fatalf("timed out waiting for %d chunks to be read", n)
+	}
+}
+
+// chunkSource is a chunkReader of chunks, ending with io.EOF. The nth read
+// starts once n-1 chunks are read.
+type chunkSource struct {
+	mu     sync.Mutex
+	chunks [][]byte
+	read   int
+	closed bool
+}
+
+func (s *chunkSource) readChunk() ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.read++
+	if s.read > len(s.chunks) {
+		return nil, io.EOF
+	}
+	return s.chunks[s.read-1], nil
+}
+
+// n returns the number of reads started.
+func (s *chunkSource) n() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.read
+}
+
+func (s *chunkSource) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	return nil
+}
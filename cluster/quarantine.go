@@ -0,0 +1,64 @@
+package cluster
+
+import (
+	"errors"
+	"fmt"
+	"net"
+
+	"github.com/zhexuany/influxcloud/rpc"
+	"github.com/zhexuany/influxcloud/tlv"
+)
+
+// hintedHandoffQuarantine is implemented by a HintedHandoff that moves the
+// writes a node repeatedly fails to store out of their queue.
+type hintedHandoffQuarantine interface {
+	QuarantinedWrites() ([]rpc.QuarantinedWrite, error)
+	RequeueQuarantined(nodeID uint64, id string) error
+	DropQuarantined(nodeID uint64, id string) error
+}
+
+// processQuarantineRequest lists, requeues or drops the hinted handoff
+// writes quarantined on this node.
+func (s *Service) processQuarantineRequest(conn net.Conn) error {
+	var req rpc.QuarantineRequest
+	if err := tlv.DecodeLV(conn, &req); err != nil {
+		return err
+	}
+
+	var resp rpc.QuarantineResponse
+	if err := s.quarantine(&req, &resp); err != nil {
+		resp.Err = err.Error()
+	}
+	return tlv.EncodeTLV(conn, tlv.QuarantineResponseMessage, &resp)
+}
+
+// quarantine applies req to the quarantined writes, listing them in resp.
+func (s *Service) quarantine(req *rpc.QuarantineRequest, resp *rpc.QuarantineResponse) error {
+	// Without hinted handoff, no writes are queued, so none are quarantined.
+	if s.HintedHandoff == nil {
+		if req.Action == rpc.QuarantineList {
+			return nil
+		}
+		return errors.New("hinted handoff disabled")
+	}
+	q, ok := s.HintedHandoff.(hintedHandoffQuarantine)
+	if !ok {
+		return errors.New("hinted handoff quarantine not supported")
+	}
+
+	switch req.Action {
+	case rpc.QuarantineList:
+		writes, err := q.QuarantinedWrites()
+		if err != nil {
+			return err
+		}
+		resp.Writes = writes
+		return nil
+	case rpc.QuarantineRequeue:
+		return q.RequeueQuarantined(req.NodeID, req.ID)
+	case rpc.QuarantineDrop:
+		return q.DropQuarantined(req.NodeID, req.ID)
+	default:
+		return fmt.Errorf("unknown quarantine action: %q", req.Action)
+	}
+}
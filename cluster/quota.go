@@ -0,0 +1,175 @@
+package cluster
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/uber-go/zap"
+	"github.com/zhexuany/influxcloud/rpc"
+)
+
+// Names of the per-database quotas, as reported when they are hit.
+const (
+	quotaWrites    = "max-concurrent-writes-per-database"
+	quotaIterators = "max-concurrent-remote-iterators-per-database"
+	quotaBandwidth = "database-bandwidth-limit"
+)
+
+// databaseQuotas limits the resources each database may use on this node, so
+// that the traffic of one database cannot starve the others. A limit of zero
+// is not enforced.
+type databaseQuotas struct {
+	maxWrites    int
+	maxIterators int
+	rate         int64 // bytes per second of writes and iterator streams
+
+	mu        sync.Mutex
+	writes    map[string]int
+	iterators map[string]int
+	limiters  map[string]*rateLimiter
+}
+
+// newDatabaseQuotas returns quotas enforcing the limits in c.
+func newDatabaseQuotas(c Config) *databaseQuotas {
+	return &databaseQuotas{
+		maxWrites:    c.MaxConcurrentWritesPerDatabase,
+		maxIterators: c.MaxConcurrentIteratorsPerDatabase,
+		rate:         c.DatabaseBandwidthLimit,
+		writes:       make(map[string]int),
+		iterators:    make(map[string]int),
+		limiters:     make(map[string]*rateLimiter),
+	}
+}
+
+// enabled returns true if any limit is enforced.
+func (q *databaseQuotas) enabled() bool {
+	return q.maxWrites > 0 || q.maxIterators > 0 || q.rate > 0
+}
+
+// acquire reserves one of max slots of db in inUse. It returns false without
+// blocking if they are all in use.
+func (q *databaseQuotas) acquire(inUse map[string]int, max int, db string) bool {
+	if max <= 0 {
+		return true
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if inUse[db] >= max {
+		return false
+	}
+	inUse[db]++
+	return true
+}
+
+// release releases a slot of db reserved by acquire.
+func (q *databaseQuotas) release(inUse map[string]int, max int, db string) {
+	if max <= 0 {
+		return
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if inUse[db]--; inUse[db] <= 0 {
+		delete(inUse, db)
+	}
+}
+
+// limiter returns the limiter shared by the writes and iterator streams of
+// db, or nil if bandwidth is not limited.
+func (q *databaseQuotas) limiter(db string) *rateLimiter {
+	if q.rate <= 0 {
+		return nil
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	l := q.limiters[db]
+	if l == nil {
+		l = newRateLimiter(q.rate)
+		q.limiters[db] = l
+	}
+	return l
+}
+
+// shardDatabase returns the database of the shard shardID, or an empty
+// string if it is not known.
+func (s *Service) shardDatabase(shardID uint64) string {
+	if s.MetaClient == nil {
+		return ""
+	}
+	db, _, _ := s.MetaClient.ShardOwner(shardID)
+	return db
+}
+
+// acquireWrite reserves one of the concurrent writes db may have on this
+// node, and waits until the bandwidth quota of db allows n more bytes. A
+// write that would have to wait past deadline is rejected. release must be
+// called once the write is done if err is nil.
+func (s *Service) acquireWrite(db string, n int, deadline time.Time) (release func(), err error) {
+	if !s.quotas.acquire(s.quotas.writes, s.quotas.maxWrites, db) {
+		s.quotaHit(db, quotaWrites, int64(s.quotas.maxWrites))
+		return nil, errQuota(db, quotaWrites, int64(s.quotas.maxWrites))
+	}
+	release = func() { s.quotas.release(s.quotas.writes, s.quotas.maxWrites, db) }
+
+	if wait := s.quotas.limiter(db).reserve(n); wait > 0 {
+		if !deadline.IsZero() && time.Now().Add(wait).After(deadline) {
+			release()
+			s.quotaHit(db, quotaBandwidth, s.quotas.rate)
+			return nil, errQuota(db, quotaBandwidth, s.quotas.rate)
+		}
+
+		t := time.NewTimer(wait)
+		defer t.Stop()
+		select {
+		case <-t.C:
+		case <-s.closing:
+			release()
+			return nil, &rpc.WriteShardError{Code: rpc.CodeDraining, Message: errThrottleClosed.Error()}
+		}
+	}
+	return release, nil
+}
+
+// quotaHit records that a request of db was rejected by quota.
+func (s *Service) quotaHit(db, quota string, max int64) {
+	s.Metrics.quotaHit(db, quota)
+	s.Logger.Info("database quota hit", zap.String("database", db), zap.String("quota", quota), zap.Int64("max", max))
+}
+
+// errQuota returns the error a write of db rejected by quota fails with.
+// Writes are rejected as overloaded so that the sender may retry them or
+// queue them in hinted handoff.
+func errQuota(db, quota string, max int64) error {
+	return &rpc.WriteShardError{Code: rpc.CodeOverloaded, Message: fmt.Sprintf("database %q exceeded %s (%d)", db, quota, max)}
+}
+
+// throttledWriter writes to w no faster than each of limiters allows.
+type throttledWriter struct {
+	w        io.Writer
+	limiters []*rateLimiter
+	closing  <-chan struct{}
+}
+
+// Write waits until the limiters allow p, then writes it to w.
+func (w *throttledWriter) Write(p []byte) (int, error) {
+	var wait time.Duration
+	for _, l := range w.limiters {
+		if d := l.reserve(len(p)); d > wait {
+			wait = d
+		}
+	}
+	if wait > 0 {
+		t := time.NewTimer(wait)
+		defer t.Stop()
+		select {
+		case <-t.C:
+		case <-w.closing:
+			return 0, errThrottleClosed
+		}
+	}
+	return w.w.Write(p)
+}
@@ -0,0 +1,59 @@
+package cluster
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zhexuany/influxcloud/rpc"
+)
+
+// Ensure the writes of one database are limited without limiting the others,
+// and that rejected writes are counted.
+func TestService_AcquireWrite(t *testing.T) {
+	s := NewService(Config{MaxConcurrentWritesPerDatabase: 1, DatabaseBandwidthLimit: 1000})
+
+	release, err := s.acquireWrite("db0", 10, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.acquireWrite("db0", 10, time.Time{}); !isOverloaded(err) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	release1, err := s.acquireWrite("db1", 10, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	release1()
+	release()
+
+	// A write that would wait past its deadline for bandwidth is rejected.
+	release, err = s.acquireWrite("db1", 1000, time.Now().Add(100*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	release()
+	if _, err := s.acquireWrite("db1", 1000, time.Now().Add(100*time.Millisecond)); !isOverloaded(err) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if _, err := s.Metrics.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	for _, exp := range []string{
+		`influxcloud_database_quota_hits_total{database="db0",quota="max-concurrent-writes-per-database"} 1`,
+		`influxcloud_database_quota_hits_total{database="db1",quota="database-bandwidth-limit"} 1`,
+	} {
+		if !strings.Contains(buf.String(), exp) {
+			t.Fatalf("missing %s in:\n%s", exp, buf.String())
+		}
+	}
+}
+
+// isOverloaded returns true if err is a write rejected as overloaded.
+func isOverloaded(err error) bool {
+	e, ok := err.(*rpc.WriteShardError)
+	return ok && e.Code == rpc.CodeOverloaded
+}
@@ -0,0 +1,46 @@
+package cluster
+
+import (
+	"net"
+
+	"github.com/zhexuany/influxcloud/rpc"
+	"github.com/zhexuany/influxcloud/tlv"
+)
+
+// ReadOnly returns true if the service rejects the writes other nodes send
+// it. Queries and other requests are served as usual.
+func (s *Service) ReadOnly() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.readOnly
+}
+
+// SetReadOnly makes the service reject the writes other nodes send it with a
+// retryable code, so that they are queued in hinted handoff for this node,
+// or accept them again if v is false.
+func (s *Service) SetReadOnly(v bool) {
+	s.mu.Lock()
+	changed := s.readOnly != v
+	s.readOnly = v
+	s.mu.Unlock()
+
+	if !changed {
+		return
+	} else if v {
+		s.Logger.Info("cluster service is read-only")
+	} else {
+		s.Logger.Info("cluster service accepts writes again")
+	}
+}
+
+// processSetReadOnlyRequest makes this node read-only, or accept writes
+// again.
+func (s *Service) processSetReadOnlyRequest(conn net.Conn) error {
+	var req rpc.SetReadOnlyRequest
+	if err := tlv.DecodeLV(conn, &req); err != nil {
+		return err
+	}
+
+	s.SetReadOnly(req.ReadOnly)
+	return tlv.EncodeTLV(conn, tlv.SetReadOnlyResponseMessage, &rpc.SetReadOnlyResponse{})
+}
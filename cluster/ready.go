@@ -0,0 +1,245 @@
+package cluster
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/zhexuany/influxcloud/rpc"
+)
+
+// Readiness is the response body of the /ready endpoint. The node is ready
+// to take traffic if every check passed.
+type Readiness struct {
+	Ready  bool             `json:"ready"`
+	Checks []ReadinessCheck `json:"checks"`
+}
+
+// ReadinessCheck is the outcome of a single readiness check. Err is empty
+// if the check passed.
+type ReadinessCheck struct {
+	Name string `json:"name"`
+	Err  string `json:"error,omitempty"`
+}
+
+// readiness checks that the meta store can be reached, that the service
+// accepts connections and is not draining, that hinted handoff is not
+// backlogged past readyMaxHHBacklog, and that every shard the meta store
+// assigns to this node is stored locally.
+func (s *Service) readiness() Readiness {
+	r := Readiness{Ready: true}
+	for _, c := range []struct {
+		name  string
+		check func() error
+	}{
+		{"meta", s.checkMeta},
+		{"listener", s.checkListener},
+		{"hintedHandoff", s.checkHintedHandoff},
+		{"shards", s.checkLocalShards},
+	} {
+		check := ReadinessCheck{Name: c.name}
+		if err := c.check(); err != nil {
+			check.Err = err.Error()
+			r.Ready = false
+		}
+		r.Checks = append(r.Checks, check)
+	}
+	return r
+}
+
+// checkMeta returns an error if the meta store cannot be reached.
+func (s *Service) checkMeta() error {
+	if p, ok := s.MetaClient.(interface {
+		Ping(checkAllMetaServers bool) error
+	}); ok {
+		return p.Ping(false)
+	}
+	_, err := s.MetaClient.DataNodes()
+	return err
+}
+
+// checkListener returns an error unless the service accepts connections
+// from other nodes and is not draining.
+func (s *Service) checkListener() error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.draining {
+		return ErrDraining
+	} else if !s.accepting {
+		return fmt.Errorf("not accepting connections")
+	}
+	return nil
+}
+
+// checkHintedHandoff returns an error if the writes queued for other nodes
+// exceed readyMaxHHBacklog bytes.
+func (s *Service) checkHintedHandoff() error {
+	s.mu.RLock()
+	max := s.readyMaxHHBacklog
+	s.mu.RUnlock()
+	if s.HintedHandoff == nil || max <= 0 {
+		return nil
+	}
+
+	var n int64
+	for _, size := range s.HintedHandoff.QueueSizes() {
+		n += size
+	}
+	if n > max {
+		return fmt.Errorf("hinted handoff backlog of %d bytes exceeds %d", n, max)
+	}
+	return nil
+}
+
+// checkLocalShards returns an error if shards the meta store assigns to this
+// node are not stored locally.
+func (s *Service) checkLocalShards() error {
+	if s.ShardStore == nil || s.Node == nil {
+		return nil
+	}
+
+	_, missing, err := s.missingLocalShards(time.Now())
+	if err != nil {
+		return err
+	}
+	if len(missing) > 0 {
+		ids := make([]uint64, len(missing))
+		for i, sh := range missing {
+			ids[i] = sh.ID
+		}
+		return fmt.Errorf("missing shards: %v", ids)
+	}
+	return nil
+}
+
+// shardGracePeriod is how long after its start a shard group may go without
+// its shards stored locally. Shards are only created on their first write.
+const shardGracePeriod = 10 * time.Minute
+
+// missingShard is a shard owned by this node that is not stored locally,
+// with the addresses of the other owners storing it.
+type missingShard struct {
+	rpc.ShardInfo
+	sources []string
+}
+
+// missingLocalShards returns the number of shards the meta store assigns to
+// this node, and those not stored locally that should be, in order of ID.
+// Shards of groups that started less than shardGracePeriod before now, such
+// as precreated groups, are not expected yet. Nor are shards no other owner
+// stores, as they have not been written to; shards without other owners, or
+// whose other owners could not all be asked, are expected regardless.
+func (s *Service) missingLocalShards(now time.Time) (int, []missingShard, error) {
+	infos, err := s.shardInfos()
+	if err != nil {
+		return 0, nil, err
+	}
+	local := make(map[uint64]bool)
+	for _, id := range s.ShardStore.ShardIDs() {
+		local[id] = true
+	}
+
+	var ownedN int
+	var candidates []rpc.ShardInfo
+	for _, si := range infos {
+		if !containsUint64(si.Owners, s.Node.ID) {
+			continue
+		}
+		ownedN++
+		if !local[si.ID] && !si.StartTime.After(now.Add(-shardGracePeriod)) {
+			candidates = append(candidates, si)
+		}
+	}
+	if len(candidates) == 0 {
+		return ownedN, nil, nil
+	}
+
+	holders, answered := s.shardHolders(candidates)
+	var missing []missingShard
+	for _, si := range candidates {
+		if sources := holders[si.ID]; len(sources) > 0 {
+			missing = append(missing, missingShard{ShardInfo: si, sources: sources})
+			continue
+		}
+
+		// Every other owner answered without it: not written to yet.
+		var otherN, answeredN int
+		for _, id := range si.Owners {
+			if id != s.Node.ID {
+				otherN++
+				if answered[id] {
+					answeredN++
+				}
+			}
+		}
+		if otherN == 0 || answeredN < otherN {
+			missing = append(missing, missingShard{ShardInfo: si})
+		}
+	}
+	sort.Sort(missingShardInfos(missing))
+	return ownedN, missing, nil
+}
+
+// shardHolders asks the other owners of shards which of them they store. It
+// returns the addresses of the owners storing each shard, and the IDs of the
+// owners that answered.
+func (s *Service) shardHolders(shards []rpc.ShardInfo) (map[uint64][]string, map[uint64]bool) {
+	holders := make(map[uint64][]string)
+	answered := make(map[uint64]bool)
+
+	nodes, err := s.MetaClient.DataNodes()
+	if err != nil {
+		return holders, answered
+	}
+	hosts := make(map[uint64]string)
+	for _, n := range nodes {
+		hosts[n.ID] = n.TCPHost
+	}
+
+	// Each owner is asked once about all of the shards it owns.
+	owned := make(map[uint64][]uint64)
+	for _, si := range shards {
+		for _, id := range si.Owners {
+			if id != s.Node.ID && hosts[id] != "" {
+				owned[id] = append(owned[id], si.ID)
+			}
+		}
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	c := NewShardStatusClient(s.dialTimeout)
+	c.Dialer = s.dialer
+	for id, shardIDs := range owned {
+		wg.Add(1)
+		go func(id uint64, shardIDs []uint64) {
+			defer wg.Done()
+			resp, err := c.nodeShardStatus(hosts[id], shardIDs)
+			if err != nil {
+				return
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			answered[id] = true
+			for _, st := range resp.Shards {
+				holders[st.ID] = append(holders[st.ID], hosts[id])
+			}
+		}(id, shardIDs)
+	}
+	wg.Wait()
+	return holders, answered
+}
+
+type missingShardInfos []missingShard
+
+func (a missingShardInfos) Len() int           { return len(a) }
+func (a missingShardInfos) Less(i, j int) bool { return a[i].ID < a[j].ID }
+func (a missingShardInfos) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
+
+// setAccepting records whether serve accepts connections from other nodes.
+func (s *Service) setAccepting(v bool) {
+	s.mu.Lock()
+	s.accepting = v
+	s.mu.Unlock()
+}
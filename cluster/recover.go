@@ -0,0 +1,72 @@
+package cluster
+
+import (
+	"fmt"
+	"io"
+	"net"
+
+	"github.com/uber-go/zap"
+	"github.com/zhexuany/influxcloud/rpc"
+	"github.com/zhexuany/influxcloud/tlv"
+)
+
+// panicError is returned for a request whose handler panicked.
+type panicError struct {
+	name  string
+	value interface{}
+}
+
+// Error returns the request and the value it panicked with.
+func (e *panicError) Error() string {
+	return fmt.Sprintf("%s request panicked: %v", e.name, e.value)
+}
+
+// call processes a request with h. A panic processing the request is
+// recovered and returned as a *panicError, so that a single malformed
+// request cannot crash the node. The panic is logged with the remote
+// address of conn and the stack of the handler.
+func (s *Service) call(h registeredHandler, conn net.Conn) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = s.recovered(h.name, conn.RemoteAddr(), r)
+		}
+	}()
+	return h.fn(conn)
+}
+
+// recovered logs and counts the panic r of a request named name received
+// from peer, and returns it as a *panicError. It must be called by the
+// deferred function that recovered r, so that the stack logged is the
+// stack of the panic.
+func (s *Service) recovered(name string, peer net.Addr, r interface{}) error {
+	s.Logger.Error("panic processing cluster request",
+		zap.String("type", name),
+		zap.Stringer("peer", peer),
+		zap.String("panic", fmt.Sprint(r)),
+		zap.Stack(),
+	)
+	s.Metrics.rpcPanic(name)
+	return &panicError{name: name, value: r}
+}
+
+// serveMuxWriteShard serves a shard write received on a multiplexed
+// connection from peer. A panic serving it is answered like any failed
+// write, with the code of an internal error, so that the other writes on
+// the connection are unaffected.
+func (s *Service) serveMuxWriteShard(buf []byte, peer net.Addr) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = &rpc.WriteShardError{Code: rpc.CodeInternal, Message: s.recovered(rpcName(tlv.WriteShardRequestMessage), peer, r).Error()}
+		}
+	}()
+	return s.serveWriteShard(buf)
+}
+
+// writeErrorResponse writes err to w with code, in place of the response to
+// a request.
+func writeErrorResponse(w io.Writer, code rpc.ErrorCode, err error) error {
+	return tlv.EncodeTLV(w, tlv.ErrorResponseMessage, &rpc.ErrorResponse{
+		Code:    code,
+		Message: err.Error(),
+	})
+}
@@ -0,0 +1,187 @@
+package cluster
+
+import (
+	"encoding"
+	"errors"
+	"fmt"
+	"net"
+
+	"github.com/uber-go/zap"
+	"github.com/zhexuany/influxcloud/rpc"
+	"github.com/zhexuany/influxcloud/tlv"
+)
+
+// ErrHijacked is returned by a Handler that has taken over its connection,
+// e.g. to stream a response until the remote node closes it. The service
+// stops reading requests from the connection without reporting an error.
+var ErrHijacked = errors.New("connection hijacked by handler")
+
+// Handler processes a request received by the cluster service. The type of
+// the request has been read from conn; the handler reads the rest of the
+// request and writes its response. The connection is closed if it returns
+// an error, and further requests are read from it otherwise.
+type Handler func(conn net.Conn) error
+
+// registeredHandler is a Handler and the name its requests are reported as
+// in metrics and spans. Requests of serial handlers are processed once the
+// requests received before them on the connection have been answered, with
+// the connection itself; the others are processed concurrently, and only
+// see their own request and response.
+type registeredHandler struct {
+	name   string
+	fn     Handler
+	serial bool
+}
+
+// RegisterHandler registers h for requests of message type typ, reported as
+// name in metrics and spans, so that extensions can add their own RPCs to
+// the cluster service. It returns an error if a handler is already
+// registered for typ, or if typ is reserved for the connection protocol.
+// Requests are processed one at a time, so that h may stream more than its
+// response over the connection or take it over.
+func (s *Service) RegisterHandler(typ byte, name string, h Handler) error {
+	switch typ {
+	case tlv.SpanContextMessage, tlv.MultiplexRequestMessage, tlv.HelloRequestMessage, tlv.AdminTokenMessage:
+		return fmt.Errorf("message type %d is reserved", typ)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.handlers[typ]; ok {
+		return fmt.Errorf("handler already registered for message type %d", typ)
+	}
+	s.handlers[typ] = registeredHandler{name: name, fn: h, serial: true}
+	return nil
+}
+
+// handler returns the handler registered for typ, if any.
+func (s *Service) handler(typ byte) (registeredHandler, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	h, ok := s.handlers[typ]
+	return h, ok
+}
+
+// registerHandlers registers the handlers of the requests the service
+// processes itself.
+func (s *Service) registerHandlers() {
+	for typ, fn := range map[byte]Handler{
+		tlv.WriteShardRequestMessage:            s.handleWriteShardRequest,
+		tlv.WritePointsRequestMessage:           s.processWritePointsRequest,
+		tlv.ExecuteStatementRequestMessage:      s.handleExecuteStatementRequest,
+		tlv.PingRequestMessage:                  s.handlePingRequest,
+		tlv.CreateIteratorRequestMessage:        hijack(s.processCreateIteratorRequest),
+		tlv.FieldDimensionsRequestMessage:       hijack(s.processFieldDimensionsRequest),
+		tlv.BackupShardRequestMessage:           hijack(s.processBackupShardRequest),
+		tlv.ShowShardsRequestMessage:            s.processShowShardsRequest,
+		tlv.CopyShardRequestMessage:             s.processCopyShardRequest,
+		tlv.RemoveShardRequestMessage:           s.processRemoveShardRequest,
+		tlv.TruncateShardsRequestMessage:        s.processTruncateShardsRequest,
+		tlv.RemoveDataNodeRequestMessage:        s.processRemoveDataNodeRequest,
+		tlv.UpdateDataNodeRequestMessage:        s.processUpdateDataNodeRequest,
+		tlv.AuthStateRequestMessage:             s.processAuthStateRequest,
+		tlv.PauseReplicationRequestMessage:      s.processPauseReplicationRequest,
+		tlv.ShardStatusRequestMessage:           s.processShardStatusRequest,
+		tlv.ShardBoundsRequestMessage:           s.processShardBoundsRequest,
+		tlv.AcquireLeaseRequestMessage:          s.processAcquireLeaseRequest,
+		tlv.RestoreShardRequestMessage:          s.processRestoreShardRequest,
+		tlv.UploadShardSnapshotRequestMessage:   s.processUploadShardSnapshotRequest,
+		tlv.ReplaceDataNodeRequestMessage:       s.processReplaceDataNodeRequest,
+		tlv.RedirectHintedHandoffRequestMessage: s.processRedirectHintedHandoffRequest,
+		tlv.DropShardsRequestMessage:            s.processDropShardsRequest,
+		tlv.ExportMetaDataRequestMessage:        s.processExportMetaDataRequest,
+		tlv.SetMaintenanceRequestMessage:        s.processSetMaintenanceRequest,
+		tlv.SetReadOnlyRequestMessage:           s.processSetReadOnlyRequest,
+		tlv.CardinalityRequestMessage:           s.processCardinalityRequest,
+		tlv.QuarantineRequestMessage:            s.processQuarantineRequest,
+	} {
+		name := rpcNames[typ]
+		if name == "" {
+			name = fmt.Sprintf("type%d", typ)
+		}
+		s.handlers[typ] = registeredHandler{name: name, fn: fn}
+	}
+
+	// Requests streaming data over the connection are processed one at a
+	// time.
+	for _, typ := range []byte{
+		tlv.CreateIteratorRequestMessage,
+		tlv.FieldDimensionsRequestMessage,
+		tlv.BackupShardRequestMessage,
+		tlv.RestoreShardRequestMessage,
+	} {
+		h := s.handlers[typ]
+		h.serial = true
+		s.handlers[typ] = h
+	}
+}
+
+// hijack returns a Handler running fn, which takes over the connection.
+func hijack(fn func(conn net.Conn)) Handler {
+	return func(conn net.Conn) error {
+		fn(conn)
+		return ErrHijacked
+	}
+}
+
+// decodeRequest reads the request of a serial handler from conn into v. A
+// request larger than the maximum message size is left unread and answered
+// with rpc.CodeFrameTooLarge, and its *tlv.FrameTooLargeError is returned so
+// that the handler closes the connection without any other response.
+func (s *Service) decodeRequest(conn net.Conn, v encoding.BinaryUnmarshaler) error {
+	err := tlv.DecodeLVMax(conn, v, s.maxMessageSize)
+	if e, ok := err.(*tlv.FrameTooLargeError); ok {
+		if err := writeErrorResponse(conn, rpc.CodeFrameTooLarge, e); err != nil {
+			s.Logger.Warn("unable to write error response", zap.Stringer("peer", conn.RemoteAddr()), zap.Error(err))
+		}
+	}
+	return err
+}
+
+// isFrameTooLarge returns true if err is caused by a request larger than the
+// maximum message size, which has already been answered.
+func isFrameTooLarge(err error) bool {
+	_, ok := err.(*tlv.FrameTooLargeError)
+	return ok
+}
+
+// handleWriteShardRequest writes the points of a shard write to the local
+// store. Failed writes are reported to the sender, not returned.
+func (s *Service) handleWriteShardRequest(conn net.Conn) error {
+	buf, err := tlv.ReadLV(conn)
+	if err != nil {
+		return fmt.Errorf("unable to read length-value: %s", err)
+	}
+
+	s.writeShardResponse(conn, s.serveWriteShard(buf))
+	return nil
+}
+
+// handleExecuteStatementRequest executes a statement on the local store.
+// Failed statements are reported to the sender, not returned.
+func (s *Service) handleExecuteStatementRequest(conn net.Conn) error {
+	buf, err := tlv.ReadLV(conn)
+	if err != nil {
+		return fmt.Errorf("unable to read length-value: %s", err)
+	}
+
+	if !s.startRequest() {
+		s.executeStatementResponse(conn, 0, &rpc.WriteShardError{Code: rpc.CodeDraining, Message: ErrDraining.Error()})
+		return nil
+	}
+	seriesN, err := s.processExecuteStatementRequest(buf, conn.RemoteAddr())
+	s.active.Done()
+	if err != nil {
+		s.Logger.Warn("execute statement failed", zap.Stringer("peer", conn.RemoteAddr()), zap.Error(err))
+	}
+	s.executeStatementResponse(conn, seriesN, err)
+	return nil
+}
+
+// handlePingRequest responds to a check that the connection is alive.
+func (s *Service) handlePingRequest(conn net.Conn) error {
+	if _, err := tlv.ReadLV(conn); err != nil {
+		return fmt.Errorf("unable to read length-value: %s", err)
+	}
+	return tlv.WriteTLV(conn, tlv.PingResponseMessage, nil)
+}
@@ -0,0 +1,27 @@
+package cluster
+
+// Reload applies the tunables of c to the running service and its
+// Reloaders: the shard copy rate limits, the hinted handoff backlog above
+// which the node is not ready, the admin tokens, and whatever the Reloaders
+// apply. Copies in progress keep the rate limits they started with. Nothing
+// is applied if c is invalid. Other settings take effect once the node is
+// restarted.
+func (s *Service) Reload(c Config) error {
+	if err := c.Validate(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.copyRateLimit = c.ShardCopyRateLimit
+	s.copyLimiter = newRateLimiter(c.ShardCopyNodeRateLimit)
+	s.readyMaxHHBacklog = int64(c.ReadyMaxHHBacklog)
+	s.mu.Unlock()
+	s.adminTokens.set(c.AdminTokens)
+
+	for _, r := range s.Reloaders {
+		if err := r.Reload(c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
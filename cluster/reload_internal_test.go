@@ -0,0 +1,49 @@
+package cluster
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/influxdb/toml"
+)
+
+// Ensure reloading applies the tunables of the service and of its
+// reloaders, and applies nothing if the config is invalid.
+func TestService_Reload(t *testing.T) {
+	w := NewPointsWriter()
+	sw := NewShardWriter(time.Second, 1)
+	s := NewService(NewConfig())
+	s.Reloaders = []Reloader{w, sw}
+
+	c := NewConfig()
+	c.ShardCopyRateLimit = 1000
+	c.ShardCopyNodeRateLimit = 5000
+	c.ReadyMaxHHBacklog = 100
+	c.WriteTimeout = toml.Duration(10 * time.Second)
+	c.ShardWriterTimeout = toml.Duration(3 * time.Second)
+	c.WriteRetryPolicy = RetryPolicyStrict
+	c.MaxWriteRetries = 5
+	if err := s.Reload(c); err != nil {
+		t.Fatal(err)
+	}
+
+	if s.copyRateLimit != 1000 || s.copyLimiter == nil || s.copyLimiter.rate != 5000 {
+		t.Fatalf("unexpected copy limits: %d, %+v", s.copyRateLimit, s.copyLimiter)
+	} else if s.readyMaxHHBacklog != 100 {
+		t.Fatalf("unexpected ready hh backlog: %d", s.readyMaxHHBacklog)
+	} else if w.WriteTimeout != 10*time.Second {
+		t.Fatalf("unexpected write timeout: %s", w.WriteTimeout)
+	} else if p, ok := w.RetryPolicy.(*StrictRetryPolicy); !ok || p.MaxRetries != 5 {
+		t.Fatalf("unexpected retry policy: %#v", w.RetryPolicy)
+	} else if d := sw.writeTimeout(); d != 3*time.Second {
+		t.Fatalf("unexpected shard writer timeout: %s", d)
+	}
+
+	c.ShardCopyRateLimit = 0
+	c.WriteRetryPolicy = "sometimes"
+	if err := s.Reload(c); err == nil {
+		t.Fatal("expected error")
+	} else if s.copyRateLimit != 1000 {
+		t.Fatalf("unexpected copy rate limit: %d", s.copyRateLimit)
+	}
+}
@@ -0,0 +1,411 @@
+package cluster
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/golang/snappy"
+	"github.com/influxdata/influxdb/influxql"
+	"github.com/influxdata/influxdb/services/meta"
+	"github.com/zhexuany/influxcloud/rpc"
+	"github.com/zhexuany/influxcloud/tlv"
+)
+
+// RemoteIteratorClient creates iterators over shards stored on other data
+// nodes.
+type RemoteIteratorClient struct {
+	timeout time.Duration
+
+	// Dialer connects to the nodes the iterators are created on.
+	Dialer *Dialer
+
+	// DisableAggregatePushdown makes the nodes stream the raw points of
+	// aggregate queries, which are then aggregated by this node, rather than
+	// their partial aggregates. It is meant for debugging the pushdown.
+	DisableAggregatePushdown bool
+
+	// Cache caches the points of iterators over time ranges that have
+	// passed. Nothing is cached if nil.
+	Cache *IteratorCache
+
+	// Compress asks the nodes to compress the streams of points with
+	// snappy. Nodes that do not support it stream the points uncompressed.
+	Compress bool
+
+	// Columnar asks the nodes to stream points as columnar record batches,
+	// which are faster to decode than influxql encoded points for dense
+	// series. Nodes that do not support it stream influxql encoded points.
+	Columnar bool
+
+	// PrefetchSize is the number of bytes of points read ahead of each
+	// iterator, so that reading from the nodes overlaps with merging the
+	// points read. Points are only read as they are needed if zero.
+	PrefetchSize int
+
+	// ReplanLostNodes makes the iterators of a node lost before the end of
+	// their streams read its shards from their other owners, which the
+	// MetaClient must know, rather than fail with a *NodeLostError. Once
+	// points have been read, only sorted streams are re-planned.
+	ReplanLostNodes bool
+
+	// MaxNodeConnections is the maximum number of nodes CreateIterators
+	// requests iterators from at once for a query. A value of zero will
+	// make the maximum unlimited.
+	MaxNodeConnections int
+
+	MetaClient interface {
+		DataNode(id uint64) (*meta.NodeInfo, error)
+	}
+
+	// stats are the statistics of the query the iterators are created for.
+	stats *RemoteQueryStats
+}
+
+// NewRemoteIteratorClient returns a new instance of RemoteIteratorClient.
+func NewRemoteIteratorClient(timeout time.Duration) *RemoteIteratorClient {
+	return &RemoteIteratorClient{timeout: timeout, Dialer: DefaultDialer}
+}
+
+// WithStats returns a copy of c recording the statistics of the streams of
+// the iterators it creates in stats, once each is closed. It is meant to be
+// used for the iterators of a single query.
+func (c *RemoteIteratorClient) WithStats(stats *RemoteQueryStats) *RemoteIteratorClient {
+	other := *c
+	other.stats = stats
+	return &other
+}
+
+// CreateIterator creates an iterator of type typ over the given shards on the
+// node nodeID. If the node rejects the iterator, or ends its stream early,
+// because the query exceeds one of its limits, a *rpc.QueryLimitError is
+// returned from CreateIterator or from the iterator's Next. If the node is
+// lost before the end of the stream, Next returns a *NodeLostError unless
+// the shards are re-planned; see ReplanLostNodes.
+//
+// The node computes the partial aggregates of an opt.Expr call locally, for
+// each GROUP BY interval and tag set, unless the pushdown is disabled. For a
+// top() or bottom() call, the iterator returns the candidate points of the
+// node, which NewSelectorIterator merges with those of the other nodes; the
+// raw points are returned as candidates if the pushdown is disabled.
+func (c *RemoteIteratorClient) CreateIterator(nodeID uint64, shardIDs []uint64, typ influxql.DataType, opt influxql.IteratorOptions) (influxql.Iterator, error) {
+	if call, ok := selectorCall(opt); ok && c.DisableAggregatePushdown {
+		rawOpt, err := selectorRawOptions(call, opt)
+		if err != nil {
+			return nil, err
+		}
+		return c.createIterator(nodeID, shardIDs, typ, rawOpt)
+	}
+	if call, ok := opt.Expr.(*influxql.Call); ok && c.DisableAggregatePushdown {
+		// Calls over other calls, or fields of unknown type, cannot be
+		// read raw and are still pushed down.
+		if ref, ok := call.Args[0].(*influxql.VarRef); ok && ref.Type != influxql.Unknown {
+			rawOpt := opt
+			rawOpt.Expr = &influxql.VarRef{Val: ref.Val, Type: ref.Type}
+			rawOpt.Limit, rawOpt.Offset = 0, 0
+
+			itr, err := c.createIterator(nodeID, shardIDs, ref.Type, rawOpt)
+			if err != nil {
+				return nil, err
+			}
+			callItr, err := influxql.NewCallIterator(itr, opt)
+			if err != nil {
+				itr.Close()
+				return nil, err
+			}
+			return callItr, nil
+		}
+	}
+	return c.createIterator(nodeID, shardIDs, typ, opt)
+}
+
+// CreateIterators creates an iterator of type typ over the shards of each
+// node in shards, keyed by node ID, as returned by ShardsByNode. The shards
+// of a node are read with a single request, and at most MaxNodeConnections
+// requests are made at once. The iterators are returned in node ID order;
+// if any node fails, the iterators created are closed.
+func (c *RemoteIteratorClient) CreateIterators(shards map[uint64][]uint64, typ influxql.DataType, opt influxql.IteratorOptions) (influxql.Iterators, error) {
+	nodeIDs := make([]uint64, 0, len(shards))
+	for id := range shards {
+		nodeIDs = append(nodeIDs, id)
+	}
+	sort.Sort(uint64Slice(nodeIDs))
+
+	n := c.MaxNodeConnections
+	if n <= 0 || n > len(nodeIDs) {
+		n = len(nodeIDs)
+	}
+	sem := make(chan struct{}, n)
+
+	itrs := make(influxql.Iterators, len(nodeIDs))
+	errs := make([]error, len(nodeIDs))
+	var wg sync.WaitGroup
+	for i, id := range nodeIDs {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, id uint64) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			itrs[i], errs[i] = c.CreateIterator(id, shards[id], typ, opt)
+		}(i, id)
+	}
+	wg.Wait()
+
+	var a influxql.Iterators
+	var err error
+	for i, itr := range itrs {
+		if errs[i] != nil && err == nil {
+			err = fmt.Errorf("node %d: %s", nodeIDs[i], errs[i])
+		} else if itr != nil {
+			a = append(a, itr)
+		}
+	}
+	if err != nil {
+		a.Close()
+		return nil, err
+	}
+	return a, nil
+}
+
+// ShardsByNode returns the IDs of shards keyed by the node each is read
+// from: the local node localID if it owns the shard, or else its preferred
+// owner in QueryOwners order. Shards without owners are left out.
+func ShardsByNode(shards []meta.ShardInfo, localID uint64, maintenance []uint64) map[uint64][]uint64 {
+	m := make(map[uint64][]uint64)
+	for _, si := range shards {
+		if id := queryNode(si, localID, maintenance); id != 0 {
+			m[id] = append(m[id], si.ID)
+		}
+	}
+	for _, ids := range m {
+		sort.Sort(uint64Slice(ids))
+	}
+	return m
+}
+
+// queryNode returns the node the shard si is read from, or zero if it has
+// no owners.
+func queryNode(si meta.ShardInfo, localID uint64, maintenance []uint64) uint64 {
+	if si.OwnedBy(localID) {
+		return localID
+	} else if ids := QueryOwners(si.Owners, maintenance); len(ids) > 0 {
+		return ids[0]
+	}
+	return 0
+}
+
+// createIterator requests an iterator with opt from the node nodeID.
+func (c *RemoteIteratorClient) createIterator(nodeID uint64, shardIDs []uint64, typ influxql.DataType, opt influxql.IteratorOptions) (influxql.Iterator, error) {
+	return c.createNodeIterator(nodeID, shardIDs, typ, opt, nil)
+}
+
+// createNodeIterator requests an iterator with opt from the node nodeID,
+// which fails over to the other owners of shardIDs, other than the nodes
+// lost earlier in the query, if the node is lost.
+func (c *RemoteIteratorClient) createNodeIterator(nodeID uint64, shardIDs []uint64, typ influxql.DataType, opt influxql.IteratorOptions, lost []uint64) (influxql.Iterator, error) {
+	start := time.Now()
+	key, cached := c.Cache.key(nodeID, shardIDs, typ, opt)
+	if cached {
+		if data, encoding, ok := c.Cache.get(key); ok {
+			return newStreamReaderIterator(bytes.NewReader(data), encoding, typ), nil
+		}
+	}
+
+	n, err := c.MetaClient.DataNode(nodeID)
+	if err != nil {
+		return nil, err
+	} else if n == nil {
+		return nil, fmt.Errorf("node %d does not exist", nodeID)
+	}
+
+	conn, err := c.Dialer.Dial(n.TCPHost, c.timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	var compressed bool
+	var encoding string
+	if err := func() error {
+		conn.SetDeadline(time.Now().Add(c.timeout))
+
+		req := &rpc.CreateIteratorRequest{
+			ShardIDs:  shardIDs,
+			Opt:       opt,
+			RequestID: NewRequestID(),
+		}
+		if c.Compress {
+			req.Compression = rpc.CompressionSnappy
+		}
+		if c.Columnar {
+			req.Encoding = rpc.IteratorEncodingColumnar
+		}
+		if err := tlv.EncodeTLV(conn, tlv.CreateIteratorRequestMessage, req); err != nil {
+			return err
+		}
+
+		var resp rpc.CreateIteratorResponse
+		if _, err := tlv.DecodeTLV(conn, &resp); err != nil {
+			return err
+		} else if resp.Err != nil {
+			return resp.Err
+		}
+		compressed = resp.Compression == rpc.CompressionSnappy
+		encoding = resp.Encoding
+
+		// Points are read for as long as the query runs.
+		return conn.SetDeadline(time.Time{})
+	}(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	sr := &iteratorStreamReader{conn: conn, compressed: compressed}
+	var r io.ReadCloser = sr
+	if c.PrefetchSize > 0 {
+		r = newPrefetchReader(sr, c.PrefetchSize)
+	}
+	if cached {
+		r = &cachingReader{r: r, cache: c.Cache, key: key, shardIDs: shardIDs, opt: opt, encoding: encoding}
+	}
+	return newFailoverIterator(newStreamReaderIterator(r, encoding, typ), &failover{
+		client:   c,
+		nodeID:   nodeID,
+		shardIDs: shardIDs,
+		typ:      typ,
+		opt:      opt,
+		lost:     lost,
+		stream:   sr,
+		start:    start,
+	}), nil
+}
+
+// iteratorStreamWriter sends the encoded points of a remote iterator in
+// chunks, so that the stream can be ended with an error after some points
+// have been sent. Writes fail with a *rpc.QueryLimitError once more than max
+// bytes have been sent, unless max is zero. Chunks are compressed with
+// snappy if compress is set; max applies to the uncompressed points.
+type iteratorStreamWriter struct {
+	w        io.Writer
+	max      int64
+	n        int64
+	compress bool
+
+	// err is the error writing to w, if any.
+	err error
+}
+
+// Write sends p as a single chunk.
+func (w *iteratorStreamWriter) Write(p []byte) (int, error) {
+	if w.max > 0 && w.n+int64(len(p)) > w.max {
+		return 0, &rpc.QueryLimitError{Limit: "max-remote-query-bytes", Max: w.max}
+	}
+	buf := p
+	if w.compress {
+		buf = snappy.Encode(nil, p)
+	}
+	if err := tlv.WriteTLV(w.w, tlv.IteratorPointsMessage, buf); err != nil {
+		w.err = err
+		return 0, err
+	}
+	w.n += int64(len(p))
+	return len(p), nil
+}
+
+// stallWriter writes to conn, failing writes that do not complete within
+// timeout. A write that times out means the remote node stopped reading.
+type stallWriter struct {
+	conn    net.Conn
+	timeout time.Duration
+
+	// stalled is set once a write timed out.
+	stalled bool
+}
+
+// Write writes p to conn within the timeout, if any.
+func (w *stallWriter) Write(p []byte) (int, error) {
+	if w.timeout <= 0 {
+		return w.conn.Write(p)
+	}
+
+	w.conn.SetWriteDeadline(time.Now().Add(w.timeout))
+	n, err := w.conn.Write(p)
+	if e, ok := err.(net.Error); ok && e.Timeout() {
+		w.stalled = true
+	}
+	return n, err
+}
+
+// errIteratorStreamTruncated is returned if a remote iterator's connection
+// closes before the end of its stream.
+var errIteratorStreamTruncated = errors.New("remote iterator stream truncated")
+
+// iteratorStreamReader reads the encoded points sent by an
+// iteratorStreamWriter, decompressing its chunks if compressed is set. Read
+// returns the error the stream was ended with, or io.EOF if it ended
+// successfully.
+type iteratorStreamReader struct {
+	conn       net.Conn
+	compressed bool
+	buf        []byte
+	err        error
+
+	n int64 // The number of bytes of chunks read, updated atomically.
+}
+
+// Read reads the points of the current chunk, reading the next chunk once it
+// has been consumed.
+func (r *iteratorStreamReader) Read(p []byte) (int, error) {
+	for len(r.buf) == 0 {
+		if r.err != nil {
+			return 0, r.err
+		}
+		r.buf, r.err = r.readChunk()
+	}
+
+	n := copy(p, r.buf)
+	r.buf = r.buf[n:]
+	return n, nil
+}
+
+// readChunk returns the next chunk of points, or the error ending the stream.
+func (r *iteratorStreamReader) readChunk() ([]byte, error) {
+	typ, buf, err := tlv.ReadTLV(r.conn)
+	switch err.(type) {
+	case nil:
+	case *tlv.ChecksumError, *tlv.FrameTooLargeError:
+		return nil, err
+	default:
+		// The connection failed or closed before the end of the stream.
+		return nil, errIteratorStreamTruncated
+	}
+
+	switch typ {
+	case tlv.IteratorPointsMessage:
+		atomic.AddInt64(&r.n, int64(len(buf)))
+		if r.compressed {
+			return snappy.Decode(nil, buf)
+		}
+		return buf, nil
+	case tlv.IteratorEndMessage:
+		var end rpc.IteratorEnd
+		if err := end.UnmarshalBinary(buf); err != nil {
+			return nil, err
+		} else if end.Err != nil {
+			return nil, end.Err
+		}
+		return nil, io.EOF
+	default:
+		return nil, fmt.Errorf("unexpected iterator stream message type: %d", typ)
+	}
+}
+
+// Close closes the connection to the remote node.
+func (r *iteratorStreamReader) Close() error {
+	return r.conn.Close()
+}
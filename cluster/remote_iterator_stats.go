@@ -0,0 +1,104 @@
+package cluster
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/influxdata/influxdb/influxql"
+)
+
+// RemoteQueryStats are the statistics of the remote iterators of a single
+// query, kept by node, e.g. to be logged with slow queries. See
+// RemoteIteratorClient.WithStats.
+type RemoteQueryStats struct {
+	mu    sync.Mutex
+	nodes map[uint64]*RemoteNodeStats
+}
+
+// RemoteNodeStats are the statistics of the remote iterators of a query
+// read from a single node.
+type RemoteNodeStats struct {
+	NodeID  uint64
+	StreamN int
+
+	// SeriesN and PointN are the series and points scanned by the node, as
+	// reported at the end of its streams.
+	SeriesN int
+	PointN  int
+
+	// Bytes is the number of bytes of points streamed from the node, as
+	// sent over the network.
+	Bytes int64
+
+	// Duration is the total time the streams of the node were open for.
+	Duration time.Duration
+
+	// RetryN is the number of times streams of the node were lost and their
+	// shards read from other owners.
+	RetryN int
+}
+
+// NewRemoteQueryStats returns the statistics of a query with no remote
+// iterators.
+func NewRemoteQueryStats() *RemoteQueryStats {
+	return &RemoteQueryStats{nodes: make(map[uint64]*RemoteNodeStats)}
+}
+
+// add records a stream from the node nodeID. A nil RemoteQueryStats records
+// nothing.
+func (s *RemoteQueryStats) add(nodeID uint64, stats influxql.IteratorStats, bytes int64, d time.Duration, retried bool) {
+	if s == nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := s.nodes[nodeID]
+	if n == nil {
+		n = &RemoteNodeStats{NodeID: nodeID}
+		s.nodes[nodeID] = n
+	}
+	n.StreamN++
+	n.SeriesN += stats.SeriesN
+	n.PointN += stats.PointN
+	n.Bytes += bytes
+	n.Duration += d
+	if retried {
+		n.RetryN++
+	}
+}
+
+// Nodes returns the statistics of each node, sorted by node ID.
+func (s *RemoteQueryStats) Nodes() []RemoteNodeStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	a := make([]RemoteNodeStats, 0, len(s.nodes))
+	for _, n := range s.nodes {
+		a = append(a, *n)
+	}
+	sort.Sort(remoteNodeStats(a))
+	return a
+}
+
+// String returns the statistics of each node on a single line.
+func (s *RemoteQueryStats) String() string {
+	var buf bytes.Buffer
+	for i, n := range s.Nodes() {
+		if i > 0 {
+			buf.WriteString(", ")
+		}
+		fmt.Fprintf(&buf, "node %d: streams=%d series=%d points=%d bytes=%d duration=%s retries=%d",
+			n.NodeID, n.StreamN, n.SeriesN, n.PointN, n.Bytes, n.Duration, n.RetryN)
+	}
+	return buf.String()
+}
+
+type remoteNodeStats []RemoteNodeStats
+
+func (a remoteNodeStats) Len() int           { return len(a) }
+func (a remoteNodeStats) Less(i, j int) bool { return a[i].NodeID < a[j].NodeID }
+func (a remoteNodeStats) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
@@ -0,0 +1,571 @@
+package cluster_test
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/influxdata/influxdb/influxql"
+	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxdb/services/meta"
+	"github.com/influxdata/influxdb/toml"
+	"github.com/zhexuany/influxcloud"
+	"github.com/zhexuany/influxcloud/cluster"
+	"github.com/zhexuany/influxcloud/rpc"
+	"github.com/zhexuany/influxcloud/tlv"
+)
+
+// Ensure points of local shards are streamed to remote iterators.
+func TestRemoteIteratorClient_CreateIterator(t *testing.T) {
+	store := MustOpenIteratorStore()
+	defer store.Close()
+
+	s := MustOpenIteratorService(cluster.Config{}, store)
+	defer s.Close()
+
+	stats := cluster.NewRemoteQueryStats()
+	c := cluster.NewRemoteIteratorClient(time.Second).WithStats(stats)
+	c.MetaClient = &metaClient{host: s.Addr().String()}
+
+	itr, err := c.CreateIterator(1, []uint64{10, 11, 12}, influxql.Float, newIteratorOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var times []int64
+	for {
+		p, err := itr.(influxql.FloatIterator).Next()
+		if err != nil {
+			t.Fatal(err)
+		} else if p == nil {
+			break
+		}
+		times = append(times, p.Time/int64(time.Second))
+	}
+	if exp := []int64{0, 0, 1, 1, 2, 2, 3, 3}; !reflect.DeepEqual(times, exp) {
+		t.Fatalf("unexpected point times: %v, expected %v", times, exp)
+	}
+
+	// The statistics of the stream are recorded once it is closed.
+	if a := stats.Nodes(); len(a) != 0 {
+		t.Fatalf("unexpected statistics of open stream: %+v", a)
+	}
+	itr.Close()
+	if a := stats.Nodes(); len(a) != 1 {
+		t.Fatalf("unexpected statistics: %+v", a)
+	} else if n := a[0]; n.NodeID != 1 || n.StreamN != 1 || n.SeriesN != 4 || n.PointN != 8 || n.Bytes == 0 || n.Duration == 0 || n.RetryN != 0 {
+		t.Fatalf("unexpected statistics: %+v", n)
+	}
+}
+
+// Ensure compressed streams of points are decompressed by the querying node.
+func TestRemoteIteratorClient_CreateIterator_Compress(t *testing.T) {
+	store := MustOpenIteratorStore()
+	defer store.Close()
+
+	s := MustOpenIteratorService(cluster.Config{}, store)
+	defer s.Close()
+
+	c := cluster.NewRemoteIteratorClient(time.Second)
+	c.MetaClient = &metaClient{host: s.Addr().String()}
+	c.Compress = true
+
+	itr, err := c.CreateIterator(1, []uint64{10, 11}, influxql.Float, newIteratorOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer itr.Close()
+
+	var n int
+	for {
+		p, err := itr.(influxql.FloatIterator).Next()
+		if err != nil {
+			t.Fatal(err)
+		} else if p == nil {
+			break
+		} else if p.Value != 1 {
+			t.Fatalf("unexpected point: %v", p)
+		}
+		n++
+	}
+	if n != 8 {
+		t.Fatalf("unexpected points: %d", n)
+	}
+
+	// The node accepts the compression in its response, and only for
+	// known compressions.
+	for compression, exp := range map[string]string{rpc.CompressionSnappy: rpc.CompressionSnappy, "lz4": ""} {
+		conn, err := net.Dial("tcp", s.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+		if _, err := conn.Write([]byte{cluster.MuxHeader}); err != nil {
+			t.Fatal(err)
+		} else if err := tlv.EncodeTLV(conn, tlv.CreateIteratorRequestMessage, &rpc.CreateIteratorRequest{
+			ShardIDs:    []uint64{10},
+			Opt:         newIteratorOptions(),
+			Compression: compression,
+		}); err != nil {
+			t.Fatal(err)
+		}
+
+		var resp rpc.CreateIteratorResponse
+		if _, err := tlv.DecodeTLV(conn, &resp); err != nil {
+			t.Fatal(err)
+		} else if resp.Compression != exp {
+			t.Fatalf("unexpected compression for %q: %q", compression, resp.Compression)
+		}
+	}
+}
+
+// Ensure columnar streams of points are decoded by the querying node.
+func TestRemoteIteratorClient_CreateIterator_Columnar(t *testing.T) {
+	store := MustOpenIteratorStore()
+	defer store.Close()
+
+	s := MustOpenIteratorService(cluster.Config{}, store)
+	defer s.Close()
+
+	stats := cluster.NewRemoteQueryStats()
+	c := cluster.NewRemoteIteratorClient(time.Second).WithStats(stats)
+	c.MetaClient = &metaClient{host: s.Addr().String()}
+	c.Columnar = true
+	c.Compress = true
+
+	itr, err := c.CreateIterator(1, []uint64{10, 11, 12}, influxql.Float, newIteratorOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var times []int64
+	for {
+		p, err := itr.(influxql.FloatIterator).Next()
+		if err != nil {
+			t.Fatal(err)
+		} else if p == nil {
+			break
+		} else if p.Value != 1 || p.Name != "cpu" {
+			t.Fatalf("unexpected point: %v", p)
+		}
+		times = append(times, p.Time/int64(time.Second))
+	}
+	if exp := []int64{0, 0, 1, 1, 2, 2, 3, 3}; !reflect.DeepEqual(times, exp) {
+		t.Fatalf("unexpected point times: %v, expected %v", times, exp)
+	}
+	itr.Close()
+	if a := stats.Nodes(); len(a) != 1 {
+		t.Fatalf("unexpected statistics: %+v", a)
+	} else if n := a[0]; n.SeriesN != 4 || n.PointN != 8 {
+		t.Fatalf("unexpected statistics: %+v", n)
+	}
+
+	// The node accepts the encoding in its response, and only for known
+	// encodings.
+	for encoding, exp := range map[string]string{rpc.IteratorEncodingColumnar: rpc.IteratorEncodingColumnar, "arrow": ""} {
+		conn, err := net.Dial("tcp", s.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+		if _, err := conn.Write([]byte{cluster.MuxHeader}); err != nil {
+			t.Fatal(err)
+		} else if err := tlv.EncodeTLV(conn, tlv.CreateIteratorRequestMessage, &rpc.CreateIteratorRequest{
+			ShardIDs: []uint64{10},
+			Opt:      newIteratorOptions(),
+			Encoding: encoding,
+		}); err != nil {
+			t.Fatal(err)
+		}
+
+		var resp rpc.CreateIteratorResponse
+		if _, err := tlv.DecodeTLV(conn, &resp); err != nil {
+			t.Fatal(err)
+		} else if resp.Encoding != exp {
+			t.Fatalf("unexpected encoding for %q: %q", encoding, resp.Encoding)
+		}
+	}
+}
+
+// Ensure prefetched streams of points are read in order.
+func TestRemoteIteratorClient_CreateIterator_Prefetch(t *testing.T) {
+	store := MustOpenIteratorStore()
+	defer store.Close()
+
+	s := MustOpenIteratorService(cluster.Config{}, store)
+	defer s.Close()
+
+	c := cluster.NewRemoteIteratorClient(time.Second)
+	c.MetaClient = &metaClient{host: s.Addr().String()}
+	c.PrefetchSize = 1
+
+	itr, err := c.CreateIterator(1, []uint64{10, 11}, influxql.Float, newIteratorOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer itr.Close()
+
+	var times []int64
+	for {
+		p, err := itr.(influxql.FloatIterator).Next()
+		if err != nil {
+			t.Fatal(err)
+		} else if p == nil {
+			break
+		}
+		times = append(times, p.Time/int64(time.Second))
+	}
+	if exp := []int64{0, 0, 1, 1, 2, 2, 3, 3}; !reflect.DeepEqual(times, exp) {
+		t.Fatalf("unexpected point times: %v, expected %v", times, exp)
+	}
+}
+
+// Ensure aggregates are computed for each interval and tag set, whether the
+// node computes the partial aggregates or streams the raw points.
+func TestRemoteIteratorClient_CreateIterator_Aggregate(t *testing.T) {
+	store := MustOpenIteratorStore()
+	defer store.Close()
+
+	s := MustOpenIteratorService(cluster.Config{}, store)
+	defer s.Close()
+
+	for _, disabled := range []bool{false, true} {
+		c := cluster.NewRemoteIteratorClient(time.Second)
+		c.MetaClient = &metaClient{host: s.Addr().String()}
+		c.DisableAggregatePushdown = disabled
+
+		opt := newIteratorOptions()
+		opt.Expr = &influxql.Call{Name: "count", Args: []influxql.Expr{&influxql.VarRef{Val: "value", Type: influxql.Float}}}
+		opt.Interval = influxql.Interval{Duration: 2 * time.Second}
+		opt.Dimensions = []string{"host"}
+		opt.GroupBy = map[string]struct{}{"host": struct{}{}}
+		opt.Ordered = false
+
+		itr, err := c.CreateIterator(1, []uint64{10, 11}, influxql.Integer, opt)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var got []string
+		for {
+			p, err := itr.(influxql.IntegerIterator).Next()
+			if err != nil {
+				t.Fatal(err)
+			} else if p == nil {
+				break
+			}
+			got = append(got, fmt.Sprintf("%s %d %d", p.Tags.Value("host"), p.Time/int64(time.Second), p.Value))
+		}
+		itr.Close()
+
+		if exp := []string{"server0 0 2", "server0 2 2", "server1 0 2", "server1 2 2"}; !reflect.DeepEqual(got, exp) {
+			t.Fatalf("unexpected counts (pushdown disabled=%v): %v, expected %v", disabled, got, exp)
+		}
+	}
+}
+
+// Ensure remote iterators exceeding the limits of a node fail with a limit
+// error.
+func TestRemoteIteratorClient_CreateIterator_Limits(t *testing.T) {
+	store := MustOpenIteratorStore()
+	defer store.Close()
+
+	for _, tt := range []struct {
+		name   string
+		config cluster.Config
+		limit  string
+		onNext bool
+	}{
+		{name: "series", config: cluster.Config{MaxRemoteSeriesN: 1}, limit: "max-remote-series"},
+		{name: "bytes", config: cluster.Config{MaxRemoteQueryBytes: 64}, limit: "max-remote-query-bytes", onNext: true},
+	} {
+		s := MustOpenIteratorService(tt.config, store)
+
+		c := cluster.NewRemoteIteratorClient(time.Second)
+		c.MetaClient = &metaClient{host: s.Addr().String()}
+
+		itr, err := c.CreateIterator(1, []uint64{10, 11}, influxql.Float, newIteratorOptions())
+		if tt.onNext && err == nil {
+			err = drainFloatIterator(itr)
+			itr.Close()
+		}
+		if e, ok := err.(*rpc.QueryLimitError); !ok || e.Limit != tt.limit {
+			t.Errorf("%s: unexpected error: %v", tt.name, err)
+		}
+		s.Close()
+	}
+}
+
+// Ensure iterators beyond the node's concurrency limit are rejected.
+func TestRemoteIteratorClient_CreateIterator_ConcurrencyLimit(t *testing.T) {
+	store := MustOpenIteratorStore()
+	defer store.Close()
+
+	s := MustOpenIteratorService(cluster.Config{MaxConcurrentRemoteIterators: 1}, store)
+	defer s.Close()
+
+	// Hold the only iterator with a request that is never completed.
+	conn, err := net.Dial("tcp", s.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if _, err := conn.Write([]byte{cluster.MuxHeader, tlv.CreateIteratorRequestMessage}); err != nil {
+		t.Fatal(err)
+	}
+
+	waitForMetric(t, s, "influxcloud_iterator_streams 1")
+
+	c := cluster.NewRemoteIteratorClient(time.Second)
+	c.MetaClient = &metaClient{host: s.Addr().String()}
+
+	_, err2 := c.CreateIterator(1, []uint64{10}, influxql.Float, newIteratorOptions())
+	if e, ok := err2.(*rpc.QueryLimitError); !ok || e.Limit != "max-concurrent-remote-iterators" || e.Max != 1 {
+		t.Fatalf("unexpected error: %v", err2)
+	}
+
+	// The iterator is released once the held request fails.
+	conn.Close()
+	for i := 0; i < 100; i++ {
+		var itr influxql.Iterator
+		if itr, err2 = c.CreateIterator(1, []uint64{10}, influxql.Float, newIteratorOptions()); err2 == nil {
+			itr.Close()
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err2 != nil {
+		t.Fatal(err2)
+	}
+}
+
+// Ensure the shards of each node are read with a single request per node,
+// and that the iterators created are closed if a node fails.
+func TestRemoteIteratorClient_CreateIterators(t *testing.T) {
+	store := MustOpenIteratorStore()
+	defer store.Close()
+
+	s := MustOpenIteratorService(cluster.Config{}, store)
+	defer s.Close()
+
+	c := cluster.NewRemoteIteratorClient(time.Second)
+	c.MaxNodeConnections = 1
+	c.MetaClient = nodeHosts{1: s.Addr().String(), 2: s.Addr().String(), 3: "127.0.0.1:0"}
+
+	itrs, err := c.CreateIterators(map[uint64][]uint64{1: {10, 11}, 2: {11}}, influxql.Float, newIteratorOptions())
+	if err != nil {
+		t.Fatal(err)
+	} else if len(itrs) != 2 {
+		t.Fatalf("unexpected iterators: %d", len(itrs))
+	}
+	for i, exp := range []int{8, 4} {
+		var n int
+		for {
+			p, err := itrs[i].(influxql.FloatIterator).Next()
+			if err != nil {
+				t.Fatal(err)
+			} else if p == nil {
+				break
+			}
+			n++
+		}
+		if n != exp {
+			t.Fatalf("unexpected points of iterator %d: %d, expected %d", i, n, exp)
+		}
+	}
+	itrs.Close()
+	waitForMetric(t, s, "influxcloud_iterator_streams 0")
+
+	if _, err := c.CreateIterators(map[uint64][]uint64{1: {10}, 3: {11}}, influxql.Float, newIteratorOptions()); err == nil || !strings.HasPrefix(err.Error(), "node 3: ") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	waitForMetric(t, s, "influxcloud_iterator_streams 0")
+}
+
+// Ensure shards are read from the local node if it owns them, and from their
+// preferred owner otherwise.
+func TestShardsByNode(t *testing.T) {
+	shards := []meta.ShardInfo{
+		{ID: 12, Owners: []meta.ShardOwner{{NodeID: 3}, {NodeID: 2}}},
+		{ID: 10, Owners: []meta.ShardOwner{{NodeID: 2}, {NodeID: 1}}},
+		{ID: 11, Owners: []meta.ShardOwner{{NodeID: 2}, {NodeID: 3}}},
+		{ID: 13},
+	}
+	if m := cluster.ShardsByNode(shards, 1, []uint64{3}); !reflect.DeepEqual(m, map[uint64][]uint64{
+		1: {10},
+		2: {11, 12},
+	}) {
+		t.Fatalf("unexpected shards by node: %v", m)
+	}
+}
+
+// nodeHosts is a meta client of the TCP hosts of data nodes, keyed by ID.
+type nodeHosts map[uint64]string
+
+func (m nodeHosts) DataNode(id uint64) (*meta.NodeInfo, error) {
+	return &meta.NodeInfo{ID: id, TCPHost: m[id]}, nil
+}
+
+// MustOpenIteratorStore returns a store with two series of cpu points in
+// shard 10 and 11. Panic on error.
+func MustOpenIteratorStore() *Store {
+	s := MustOpenStore()
+	for i, id := range []uint64{10, 11} {
+		if err := s.CreateShard("db0", "rp0", id, true); err != nil {
+			panic(err)
+		}
+
+		var points []models.Point
+		for _, host := range []string{"server0", "server1"} {
+			for j := 0; j < 2; j++ {
+				points = append(points, models.MustNewPoint("cpu", models.NewTags(map[string]string{"host": host}), map[string]interface{}{"value": 1.0}, time.Unix(int64(2*i+j), 0)))
+			}
+		}
+		if err := s.WriteToShard(id, points); err != nil {
+			panic(err)
+		}
+	}
+	return s
+}
+
+// MustOpenIteratorService returns a new, open service with config c
+// serving iterators over store. Panic on error.
+func MustOpenIteratorService(c cluster.Config, store *Store) *Service {
+	s := NewService()
+	s.Service = cluster.NewService(c)
+	s.Service.Node = &influxcloud.Node{ID: 1}
+	s.Service.MetaClient = &s.MetaClient
+	s.Service.ShardStore = store
+	s.ln = MustListen("tcp", "127.0.0.1:0")
+	s.Listener = &muxListener{s.ln}
+	if err := s.Open(); err != nil {
+		panic(err)
+	}
+	return s
+}
+
+// newIteratorOptions returns options for an ordered raw query on cpu.
+func newIteratorOptions() influxql.IteratorOptions {
+	return influxql.IteratorOptions{
+		Expr:      &influxql.VarRef{Val: "value", Type: influxql.Float},
+		Sources:   []influxql.Source{&influxql.Measurement{Name: "cpu"}},
+		StartTime: influxql.MinTime,
+		EndTime:   influxql.MaxTime,
+		Ascending: true,
+		Ordered:   true,
+	}
+}
+
+// drainFloatIterator reads all points from itr and returns the first error.
+func drainFloatIterator(itr influxql.Iterator) error {
+	for {
+		if p, err := itr.(influxql.FloatIterator).Next(); err != nil {
+			return err
+		} else if p == nil {
+			return nil
+		}
+	}
+}
+
+// Ensure local iterators are closed if the querying node disconnects before
+// the end of the stream.
+func TestRemoteIteratorClient_CreateIterator_Disconnect(t *testing.T) {
+	store := MustOpenStore()
+	defer store.Close()
+	if err := store.CreateShard("db0", "rp0", 10, true); err != nil {
+		t.Fatal(err)
+	}
+
+	// Write enough points for the stream not to fit in the socket buffers.
+	points := make([]models.Point, 0, 100000)
+	for i := 0; i < cap(points); i++ {
+		points = append(points, models.MustNewPoint("cpu", models.NewTags(map[string]string{"host": "server0"}), map[string]interface{}{"value": 1.0}, time.Unix(0, int64(i))))
+	}
+	if err := store.WriteToShard(10, points); err != nil {
+		t.Fatal(err)
+	}
+
+	s := MustOpenIteratorService(cluster.Config{}, store)
+	defer s.Close()
+
+	c := cluster.NewRemoteIteratorClient(time.Second)
+	c.MetaClient = &metaClient{host: s.Addr().String()}
+
+	itr, err := c.CreateIterator(1, []uint64{10}, influxql.Float, newIteratorOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p, err := itr.(influxql.FloatIterator).Next(); err != nil || p == nil {
+		t.Fatalf("unexpected point: %v (%v)", p, err)
+	}
+	itr.Close()
+
+	waitForMetric(t, s, "influxcloud_iterator_streams_orphaned_total 1")
+	waitForMetric(t, s, "influxcloud_iterator_streams 0")
+}
+
+// Ensure iterator streams are aborted if the querying node stops reading them
+// for longer than the stall timeout.
+func TestRemoteIteratorClient_CreateIterator_Stalled(t *testing.T) {
+	store := MustOpenStore()
+	defer store.Close()
+	if err := store.CreateShard("db0", "rp0", 10, true); err != nil {
+		t.Fatal(err)
+	}
+
+	// Write enough points for the stream not to fit in the socket buffers.
+	value := strings.Repeat("x", 1024)
+	points := make([]models.Point, 0, 20000)
+	for i := 0; i < cap(points); i++ {
+		points = append(points, models.MustNewPoint("cpu", models.NewTags(map[string]string{"host": "server0"}), map[string]interface{}{"value": value}, time.Unix(0, int64(i))))
+	}
+	if err := store.WriteToShard(10, points); err != nil {
+		t.Fatal(err)
+	}
+
+	s := MustOpenIteratorService(cluster.Config{IteratorStallTimeout: toml.Duration(50 * time.Millisecond)}, store)
+	defer s.Close()
+
+	// The stream is left unread once the iterator is created.
+	conn, err := net.Dial("tcp", s.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.(*net.TCPConn).SetReadBuffer(4096)
+	if _, err := conn.Write([]byte{cluster.MuxHeader}); err != nil {
+		t.Fatal(err)
+	}
+	opt := newIteratorOptions()
+	opt.Expr = &influxql.VarRef{Val: "value", Type: influxql.String}
+	if err := tlv.EncodeTLV(conn, tlv.CreateIteratorRequestMessage, &rpc.CreateIteratorRequest{
+		ShardIDs: []uint64{10},
+		Opt:      opt,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	waitForMetric(t, s, "influxcloud_iterator_streams_evicted_total 1")
+	waitForMetric(t, s, "influxcloud_iterator_streams 0")
+}
+
+// waitForMetric waits for line to appear in the metrics of s.
+func waitForMetric(t *testing.T, s *Service, line string) {
+	var out string
+	for i := 0; i < 100; i++ {
+		var buf bytes.Buffer
+		if _, err := s.Metrics.WriteTo(&buf); err != nil {
+			t.Fatal(err)
+		}
+		out = buf.String()
+		if strings.Contains(out, line+"\n") {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("missing line %q in:\n%s", line, out)
+}
@@ -0,0 +1,173 @@
+package cluster
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/uber-go/zap"
+	"github.com/zhexuany/influxcloud/rpc"
+	"github.com/zhexuany/influxcloud/tlv"
+)
+
+// processReplaceDataNodeRequest hands the shards of a data node over to a new
+// data node, and removes the old node from the cluster.
+func (s *Service) processReplaceDataNodeRequest(conn net.Conn) error {
+	var req rpc.ReplaceDataNodeRequest
+	if err := tlv.DecodeLV(conn, &req); err != nil {
+		return err
+	}
+
+	var resp rpc.ReplaceDataNodeResponse
+	ids, err := s.replaceDataNode(req.OldTCPHost, req.NewTCPHost)
+	resp.ShardIDs = ids
+	if err != nil {
+		resp.Err = err.Error()
+	}
+
+	return tlv.EncodeTLV(conn, tlv.ReplaceDataNodeResponseMessage, &resp)
+}
+
+// replaceDataNode replaces the data node at oldHost, which may be dead, with
+// the data node at newHost. Every shard owned by the old node is copied to the
+// new node from its surviving owners, the writes other nodes queued for the old
+// node are sent to the new node, and the old node's ownership is then moved to
+// the new node in a single meta command. It returns the IDs of the shards
+// copied, and may be run again if it fails part way.
+func (s *Service) replaceDataNode(oldHost, newHost string) ([]uint64, error) {
+	old, err := s.dataNodeByTCPHost(oldHost)
+	if err != nil {
+		return nil, err
+	}
+	n, err := s.dataNodeByTCPHost(newHost)
+	if err != nil {
+		return nil, err
+	} else if n.ID == old.ID {
+		return nil, fmt.Errorf("data node %d cannot replace itself", n.ID)
+	}
+
+	nodes, err := s.MetaClient.DataNodes()
+	if err != nil {
+		return nil, err
+	}
+	hosts := make(map[uint64]string, len(nodes))
+	for _, ni := range nodes {
+		hosts[ni.ID] = ni.TCPHost
+	}
+
+	shards, err := s.shardInfos()
+	if err != nil {
+		return nil, err
+	}
+
+	var ids []uint64
+	for _, si := range shards {
+		if !ownedBy(si.Owners, old.ID) || ownedBy(si.Owners, n.ID) {
+			continue
+		}
+		if err := s.copyReplica(si, old.ID, hosts, newHost); err != nil {
+			return ids, err
+		}
+		ids = append(ids, si.ID)
+	}
+
+	// The new node owns the copied shards, so the queued writes to them can
+	// be sent to it.
+	for _, ni := range nodes {
+		if ni.ID == old.ID {
+			continue
+		}
+		if err := s.redirectHintedHandoff(ni.TCPHost, old.ID, n.ID); err != nil {
+			return ids, fmt.Errorf("redirect hinted handoff on node %d: %s", ni.ID, err)
+		}
+	}
+
+	if err := s.MetaClient.ReplaceDataNode(old.ID, n.ID); err != nil {
+		return ids, err
+	}
+	s.Logger.Info("replaced data node", zap.Uint64("oldNodeID", old.ID), zap.Uint64("newNodeID", n.ID))
+	return ids, nil
+}
+
+// copyReplica asks the data node at dest to copy the shard si from one of its
+// owners other than the node skip, trying each in turn.
+func (s *Service) copyReplica(si rpc.ShardInfo, skip uint64, hosts map[uint64]string, dest string) error {
+	err := fmt.Errorf("shard %d has no other owner to copy from", si.ID)
+	for _, id := range si.Owners {
+		if id == skip || hosts[id] == "" {
+			continue
+		}
+		if err = s.copyShardTo(dest, &rpc.CopyShardRequest{
+			Source:   hosts[id],
+			Dest:     dest,
+			ShardID:  si.ID,
+			Database: si.Database,
+			Policy:   si.Policy,
+		}); err == nil {
+			return nil
+		}
+		s.Logger.Warn("unable to copy shard", zap.Uint64("shardID", si.ID), zap.Uint64("nodeID", id), zap.Error(err))
+	}
+	return err
+}
+
+// copyShardTo sends req to the data node at addr, which pulls the shard from
+// its source. There is no deadline, as copying a shard may take a long time.
+func (s *Service) copyShardTo(addr string, req *rpc.CopyShardRequest) error {
+	var resp rpc.CopyShardResponse
+	if err := s.dialer.dialRPC(addr, s.dialTimeout, tlv.CopyShardRequestMessage, req, &resp); err != nil {
+		return err
+	} else if resp.Err != "" {
+		return errors.New(resp.Err)
+	}
+	return nil
+}
+
+// redirectHintedHandoff asks the data node at addr to send the writes it
+// queued for the node from to the node to.
+func (s *Service) redirectHintedHandoff(addr string, from, to uint64) error {
+	// The queued writes are flushed before the node responds.
+	var resp rpc.RedirectHintedHandoffResponse
+	if err := s.dialer.dialRPC(addr, s.dialTimeout, tlv.RedirectHintedHandoffRequestMessage, &rpc.RedirectHintedHandoffRequest{
+		FromNodeID: from,
+		ToNodeID:   to,
+	}, &resp); err != nil {
+		return err
+	} else if resp.Err != "" {
+		return errors.New(resp.Err)
+	}
+	return nil
+}
+
+// processRedirectHintedHandoffRequest sends the writes this node queued for
+// one node to another node.
+func (s *Service) processRedirectHintedHandoffRequest(conn net.Conn) error {
+	var req rpc.RedirectHintedHandoffRequest
+	if err := tlv.DecodeLV(conn, &req); err != nil {
+		return err
+	}
+
+	// Without hinted handoff, no writes are queued for other nodes.
+	var resp rpc.RedirectHintedHandoffResponse
+	if s.HintedHandoff != nil {
+		start := time.Now()
+		if err := s.HintedHandoff.RedirectQueue(req.FromNodeID, req.ToNodeID); err != nil {
+			resp.Err = err.Error()
+		} else {
+			s.Logger.Info("redirected hinted handoff queue", zap.Uint64("fromNodeID", req.FromNodeID), zap.Uint64("toNodeID", req.ToNodeID), zap.Duration("duration", time.Since(start)))
+		}
+	}
+
+	return tlv.EncodeTLV(conn, tlv.RedirectHintedHandoffResponseMessage, &resp)
+}
+
+// ownedBy returns true if nodeID is one of owners.
+func ownedBy(owners []uint64, nodeID uint64) bool {
+	for _, id := range owners {
+		if id == nodeID {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,10 @@
+package cluster
+
+import "github.com/influxdata/influxdb/uuid"
+
+// NewRequestID returns a new identifier for a client request. The ID is sent
+// with every RPC made on behalf of the request and included in the log
+// entries on each node, so a single request can be traced across the cluster.
+func NewRequestID() string {
+	return uuid.TimeUUID().String()
+}
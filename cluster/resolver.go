@@ -0,0 +1,141 @@
+package cluster
+
+import (
+	"net"
+	"sync"
+	"time"
+)
+
+// DefaultResolver is the Resolver the clients of this package dial other
+// nodes with unless given another one.
+var DefaultResolver = NewResolver(DefaultDNSTTL)
+
+// Resolver resolves the hostnames of node addresses when dialing other
+// nodes, so that the addresses stored in the meta store may be DNS names,
+// e.g. of Kubernetes pods that get a new IP when they are rescheduled.
+// Each hostname is resolved at most once per TTL. A hostname is resolved
+// again as soon as the addresses it resolved to cannot be dialed, so that
+// a node that moved is reached at its new IP without waiting for the TTL.
+type Resolver struct {
+	ttl time.Duration
+
+	mu    sync.Mutex
+	hosts map[string]resolvedHost
+
+	// lookupHost returns the addresses of a hostname.
+	lookupHost func(host string) ([]string, error)
+}
+
+// resolvedHost is the resolution of a hostname and the time it expires.
+type resolvedHost struct {
+	addrs   []string
+	expires time.Time
+}
+
+// NewResolver returns a Resolver caching each resolution for ttl. A ttl of
+// zero resolves hostnames on every dial.
+func NewResolver(ttl time.Duration) *Resolver {
+	return &Resolver{
+		ttl:        ttl,
+		hosts:      make(map[string]resolvedHost),
+		lookupHost: net.LookupHost,
+	}
+}
+
+// Dial connects to the TCP address addr within timeout, trying each address
+// its hostname resolves to in turn. Addresses with an IP are dialed as is,
+// as is every address if r is nil.
+func (r *Resolver) Dial(addr string, timeout time.Duration) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if r == nil || err != nil || host == "" || net.ParseIP(host) != nil {
+		return net.DialTimeout("tcp", addr, timeout)
+	}
+
+	addrs, cached, err := r.resolve(host)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := dialAny(addrs, port, timeout)
+	if err != nil && cached {
+		// The node may have moved since host was resolved.
+		r.forget(host)
+		if addrs, _, err = r.resolve(host); err != nil {
+			return nil, err
+		}
+		conn, err = dialAny(addrs, port, timeout)
+	}
+	if err != nil {
+		r.forget(host)
+		return nil, err
+	}
+	return conn, nil
+}
+
+// moved returns true if the hostname of addr no longer resolves to the IP
+// of remote, the address a connection to addr was dialed at. Hostnames are
+// only resolved again once their resolution expires.
+func (r *Resolver) moved(addr string, remote net.Addr) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if r == nil || err != nil || host == "" || net.ParseIP(host) != nil {
+		return false
+	}
+	ip, _, err := net.SplitHostPort(remote.String())
+	if err != nil {
+		return false
+	}
+
+	addrs, _, err := r.resolve(host)
+	if err != nil {
+		return false
+	}
+	for _, a := range addrs {
+		if a == ip {
+			return false
+		}
+	}
+	return true
+}
+
+// resolve returns the addresses host resolves to, and whether they were
+// cached rather than just looked up.
+func (r *Resolver) resolve(host string) ([]string, bool, error) {
+	now := time.Now()
+	r.mu.Lock()
+	h, ok := r.hosts[host]
+	r.mu.Unlock()
+	if ok && now.Before(h.expires) {
+		return h.addrs, true, nil
+	}
+
+	addrs, err := r.lookupHost(host)
+	if err != nil {
+		return nil, false, err
+	}
+	if r.ttl > 0 {
+		r.mu.Lock()
+		r.hosts[host] = resolvedHost{addrs: addrs, expires: now.Add(r.ttl)}
+		r.mu.Unlock()
+	}
+	return addrs, false, nil
+}
+
+// forget drops the resolution of host, so that it is resolved again on the
+// next dial.
+func (r *Resolver) forget(host string) {
+	r.mu.Lock()
+	delete(r.hosts, host)
+	r.mu.Unlock()
+}
+
+// dialAny connects to the first of addrs that accepts a connection on port.
+func dialAny(addrs []string, port string, timeout time.Duration) (net.Conn, error) {
+	var err error = &net.AddrError{Err: "no addresses", Addr: port}
+	for _, a := range addrs {
+		conn, e := net.DialTimeout("tcp", net.JoinHostPort(a, port), timeout)
+		if e == nil {
+			return conn, nil
+		}
+		err = e
+	}
+	return nil, err
+}
@@ -0,0 +1,90 @@
+package cluster
+
+import (
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Ensure hostnames are resolved once per TTL, and resolved again right away
+// once the node at the cached IP can no longer be dialed.
+func TestResolver_Dial(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, port, _ := net.SplitHostPort(ln.Addr().String())
+
+	var mu sync.Mutex
+	ip, lookups := "127.0.0.1", 0
+	r := NewResolver(time.Hour)
+	r.lookupHost = func(host string) ([]string, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if host != "data0.example.com" {
+			t.Fatalf("unexpected host: %s", host)
+		}
+		lookups++
+		return []string{ip}, nil
+	}
+
+	addr := net.JoinHostPort("data0.example.com", port)
+	for i := 0; i < 2; i++ {
+		conn, err := r.Dial(addr, time.Second)
+		if err != nil {
+			t.Fatal(err)
+		}
+		conn.Close()
+	}
+	if lookups != 1 {
+		t.Fatalf("unexpected lookups: %d", lookups)
+	}
+
+	// The node moves to another IP.
+	ln.Close()
+	ln, err = net.Listen("tcp", net.JoinHostPort("127.0.0.2", port))
+	if err != nil {
+		t.Skipf("unable to listen on another loopback address: %s", err)
+	}
+	defer ln.Close()
+	mu.Lock()
+	ip = "127.0.0.2"
+	mu.Unlock()
+
+	conn, err := r.Dial(addr, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if lookups != 2 {
+		t.Fatalf("unexpected lookups: %d", lookups)
+	} else if !r.moved(addr, &net.TCPAddr{IP: net.ParseIP("127.0.0.1")}) {
+		t.Fatal("expected connections to the old IP to have moved")
+	} else if r.moved(addr, conn.RemoteAddr()) {
+		t.Fatal("unexpected move of the connection to the new IP")
+	}
+}
+
+// Ensure addresses with an IP are dialed without being resolved.
+func TestResolver_Dial_IP(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	r := NewResolver(time.Hour)
+	r.lookupHost = func(host string) ([]string, error) {
+		t.Fatalf("unexpected lookup of %s", host)
+		return nil, nil
+	}
+	conn, err := r.Dial(ln.Addr().String(), time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.Close()
+	if r.moved(ln.Addr().String(), conn.RemoteAddr()) {
+		t.Fatal("unexpected move")
+	}
+}
@@ -0,0 +1,371 @@
+package cluster
+
+import (
+	"archive/tar"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/influxdata/influxdb/services/meta"
+	"github.com/uber-go/zap"
+	"github.com/zhexuany/influxcloud/rpc"
+	"github.com/zhexuany/influxcloud/tlv"
+)
+
+// RestoreSource provides the shard backups of a BackupManifest to a
+// RestoreCoordinator.
+type RestoreSource interface {
+	// OpenShard opens the backup of shard, as received by a BackupSink.
+	OpenShard(shard BackupShardInfo) (io.ReadCloser, error)
+}
+
+// RestoreCoordinator restores a backup taken by a BackupCoordinator. The
+// shards are placed on the nodes of the cluster they are restored to, which
+// may have a different number of nodes than the cluster they came from.
+type RestoreCoordinator struct {
+	timeout time.Duration
+
+	// Dialer connects to the data nodes.
+	Dialer *Dialer
+
+	// AdminToken authorizes the restores on the data nodes, if they have
+	// admin tokens configured.
+	AdminToken string
+
+	MetaClient interface {
+		DataNode(id uint64) (*meta.NodeInfo, error)
+		CreateDatabase(name string) (*meta.DatabaseInfo, error)
+		CreateRetentionPolicy(database string, spec *meta.RetentionPolicySpec) (*meta.RetentionPolicyInfo, error)
+		CreateShardGroupWithShards(database, policy string, timestamp time.Time, shardN int) (*meta.ShardGroupInfo, error)
+	}
+}
+
+// NewRestoreCoordinator returns a new instance of RestoreCoordinator.
+func NewRestoreCoordinator(timeout time.Duration) *RestoreCoordinator {
+	return &RestoreCoordinator{timeout: timeout, Dialer: DefaultDialer}
+}
+
+// Restore restores the backup described by m from src. The database and
+// retention policy are created if they do not exist, and a shard group with
+// as many shards as the backup is created for each backed up shard group. The
+// new shards are owned by the nodes meta places them on, and each backed up
+// shard is streamed to every owner of its new shard. It returns the IDs of the
+// new shards by the IDs of the backed up shards.
+func (c *RestoreCoordinator) Restore(m *BackupManifest, src RestoreSource) (map[uint64]uint64, error) {
+	if _, err := c.MetaClient.CreateDatabase(m.Database); err != nil {
+		return nil, err
+	}
+
+	replicaN, duration := m.ReplicaN, m.Duration
+	if _, err := c.MetaClient.CreateRetentionPolicy(m.Database, &meta.RetentionPolicySpec{
+		Name:               m.RetentionPolicy,
+		ReplicaN:           &replicaN,
+		Duration:           &duration,
+		ShardGroupDuration: m.ShardGroupDuration,
+	}); err != nil {
+		return nil, err
+	}
+
+	ids := make(map[uint64]uint64, len(m.Shards))
+	for _, shards := range groupBackupShards(m.Shards) {
+		g, err := c.MetaClient.CreateShardGroupWithShards(m.Database, m.RetentionPolicy, shards[0].StartTime, len(shards))
+		if err != nil {
+			return nil, err
+		} else if g == nil {
+			return nil, fmt.Errorf("no data nodes to restore shard group %d to", shards[0].ShardGroupID)
+		} else if len(g.Shards) != len(shards) {
+			return nil, fmt.Errorf("shard group %d already exists with %d shards, expected %d", g.ID, len(g.Shards), len(shards))
+		}
+
+		for i, sh := range shards {
+			if err := c.restoreShard(m, sh, g.Shards[i], src); err != nil {
+				return nil, err
+			}
+			ids[sh.ID] = g.Shards[i].ID
+		}
+	}
+	return ids, nil
+}
+
+// groupBackupShards returns the shards of a backup by shard group, in the
+// order the shard groups appear in the backup.
+func groupBackupShards(shards []BackupShardInfo) [][]BackupShardInfo {
+	var groups [][]BackupShardInfo
+	index := make(map[uint64]int)
+	for _, sh := range shards {
+		i, ok := index[sh.ShardGroupID]
+		if !ok {
+			i = len(groups)
+			index[sh.ShardGroupID] = i
+			groups = append(groups, nil)
+		}
+		groups[i] = append(groups[i], sh)
+	}
+	return groups
+}
+
+// restoreShard streams the backup of shard from src to every owner of the new
+// shard si. The backup is sent again if an owner finds that it does not match
+// its checksums.
+func (c *RestoreCoordinator) restoreShard(m *BackupManifest, shard BackupShardInfo, si meta.ShardInfo, src RestoreSource) error {
+	for _, o := range si.Owners {
+		var err error
+		for attempt := 1; attempt <= maxShardStreamAttempts; attempt++ {
+			if err = c.sendShard(m, shard, si.ID, o.NodeID, src); !isChecksumError(err) {
+				break
+			}
+		}
+		if err != nil {
+			return fmt.Errorf("restore shard %d to shard %d on node %d: %s", shard.ID, si.ID, o.NodeID, err)
+		}
+	}
+	return nil
+}
+
+// sendShard streams the backup of shard from src to the new shard id on the
+// node nodeID.
+func (c *RestoreCoordinator) sendShard(m *BackupManifest, shard BackupShardInfo, id, nodeID uint64, src RestoreSource) error {
+	r, err := src.OpenShard(shard)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	return c.restoreShardTo(nodeID, &rpc.RestoreShardRequest{
+		Size:          uint64(shard.Size),
+		ShardID:       id,
+		Database:      m.Database,
+		Policy:        m.RetentionPolicy,
+		SourceShardID: shard.ID,
+	}, r)
+}
+
+// restoreShardTo sends req to the node nodeID, followed by the backup read
+// from r.
+func (c *RestoreCoordinator) restoreShardTo(nodeID uint64, req *rpc.RestoreShardRequest, r io.Reader) error {
+	n, err := c.MetaClient.DataNode(nodeID)
+	if err != nil {
+		return err
+	} else if n == nil {
+		return fmt.Errorf("node %d does not exist", nodeID)
+	}
+
+	conn, err := c.Dialer.Dial(n.TCPHost, c.timeout)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(c.timeout))
+
+	if c.AdminToken != "" {
+		if err := tlv.EncodeTLV(conn, tlv.AdminTokenMessage, &rpc.AdminToken{Token: c.AdminToken}); err != nil {
+			return err
+		}
+	}
+	if err := tlv.EncodeTLV(conn, tlv.RestoreShardRequestMessage, req); err != nil {
+		return err
+	}
+
+	// Stream the backup, which is restored as it is received.
+	w := &restoreStreamWriter{conn: conn, timeout: c.timeout}
+	if _, err := io.Copy(w, r); err != nil {
+		return err
+	} else if err := w.Close(); err != nil {
+		return err
+	}
+
+	conn.SetDeadline(time.Now().Add(c.timeout))
+	var resp rpc.RestoreShardResponse
+	if _, err := tlv.DecodeTLV(conn, &resp); err != nil {
+		return err
+	} else if resp.Code == rpc.CodeChecksumMismatch {
+		return &rpc.ChecksumError{ShardID: req.SourceShardID}
+	} else if resp.Err != "" {
+		return errors.New(resp.Err)
+	}
+	return nil
+}
+
+// processRestoreShardRequest creates a local shard and restores the backup
+// streamed after the request into it.
+func (s *Service) processRestoreShardRequest(conn net.Conn) error {
+	var req rpc.RestoreShardRequest
+	if err := s.decodeRequest(conn, &req); err != nil {
+		return err
+	}
+
+	r := &restoreStreamReader{r: conn}
+	var resp rpc.RestoreShardResponse
+	if err := s.restoreShard(&req, s.throttleCopy(r)); err != nil {
+		resp.Err = err.Error()
+		if isChecksumError(err) {
+			resp.Code = rpc.CodeChecksumMismatch
+		}
+	}
+
+	// Read the rest of the backup if the restore failed, so that the
+	// response follows the end of the stream.
+	if _, err := io.Copy(ioutil.Discard, r); err != nil {
+		return err
+	}
+
+	return tlv.EncodeTLV(conn, tlv.RestoreShardResponseMessage, &resp)
+}
+
+// restoreShard creates the requested shard and restores the backup read from
+// r into it.
+func (s *Service) restoreShard(req *rpc.RestoreShardRequest, r io.Reader) error {
+	if err := s.TSDBStore.CreateShard(req.Database, req.Policy, req.ShardID, true); err != nil {
+		return fmt.Errorf("create shard %d: %s", req.ShardID, err)
+	}
+	s.snapshots.remove(req.ShardID)
+
+	// The backup is verified if it was taken with checksums. Its files are
+	// named after the shard it was taken from, so they are renamed after the
+	// new shard.
+	from, to := shardPath(req.Database, req.Policy, req.SourceShardID), shardPath(req.Database, req.Policy, req.ShardID)
+	if err := s.restoreArchive(req.ShardID, r, func(w io.Writer, r io.Reader) error {
+		return verifyShardChecksums(w, r, req.SourceShardID, false)
+	}, func(w io.Writer, r io.Reader) error {
+		return renameShardBackup(w, r, from, to)
+	}); err != nil {
+		if isChecksumError(err) {
+			// Remove the corrupted files so the backup can be sent again.
+			s.TSDBStore.DeleteShard(req.ShardID)
+			return err
+		}
+		return fmt.Errorf("restore shard %d: %s", req.ShardID, err)
+	}
+
+	s.Logger.Info("restored shard", zap.Uint64("shardID", req.ShardID), zap.Uint64("sourceShardID", req.SourceShardID))
+
+	return nil
+}
+
+// archiveFilter copies the shard archive read from r to w, rewriting it.
+type archiveFilter func(w io.Writer, r io.Reader) error
+
+// restoreArchive restores the shard id from the archive read from r, as
+// rewritten by each of filters in turn. A checksum mismatch found by a filter
+// is returned in preference to the error it causes restoring the shard.
+func (s *Service) restoreArchive(id uint64, r io.Reader, filters ...archiveFilter) error {
+	errs := make(chan error, len(filters))
+	var pipes []*io.PipeReader
+	for _, fn := range filters {
+		pr, pw := io.Pipe()
+		go func(fn archiveFilter, r io.Reader) {
+			err := fn(pw, r)
+			pw.CloseWithError(err)
+			errs <- err
+		}(fn, r)
+		pipes = append(pipes, pr)
+		r = pr
+	}
+
+	err := s.TSDBStore.RestoreShard(id, r)
+	if err == nil {
+		io.Copy(ioutil.Discard, r)
+	}
+	// Stop the filters still writing if the restore failed.
+	for _, pr := range pipes {
+		pr.Close()
+	}
+
+	var ferr error
+	for range filters {
+		if e := <-errs; e != nil && (ferr == nil || isChecksumError(e)) {
+			ferr = e
+		}
+	}
+	if isChecksumError(ferr) || err == nil {
+		return ferr
+	}
+	return err
+}
+
+// shardPath returns the path of a shard's files relative to the store, as
+// used by the names of the files in its backups.
+func shardPath(database, policy string, id uint64) string {
+	return path.Join(database, policy, strconv.FormatUint(id, 10))
+}
+
+// renameShardBackup copies the shard backup archive read from r to w,
+// replacing the shard path from with to in the names of its files.
+func renameShardBackup(w io.Writer, r io.Reader, from, to string) error {
+	tr, tw := tar.NewReader(r), tar.NewWriter(w)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return err
+		}
+
+		if strings.HasPrefix(hdr.Name, from+"/") {
+			hdr.Name = to + strings.TrimPrefix(hdr.Name, from)
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		} else if _, err := io.Copy(tw, tr); err != nil {
+			return err
+		}
+	}
+	return tw.Close()
+}
+
+// restoreStreamWriter sends a shard backup as RestoreShardDataMessage
+// records. Close ends the stream with an empty record.
+type restoreStreamWriter struct {
+	conn    net.Conn
+	timeout time.Duration
+}
+
+// Write sends p as a single record.
+func (w *restoreStreamWriter) Write(p []byte) (int, error) {
+	w.conn.SetWriteDeadline(time.Now().Add(w.timeout))
+	if err := tlv.WriteTLV(w.conn, tlv.RestoreShardDataMessage, p); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Close ends the stream.
+func (w *restoreStreamWriter) Close() error {
+	return tlv.WriteTLV(w.conn, tlv.RestoreShardDataMessage, nil)
+}
+
+// restoreStreamReader reads the shard backup sent by a restoreStreamWriter.
+// Read returns io.EOF once the stream has ended.
+type restoreStreamReader struct {
+	r   io.Reader
+	buf []byte
+	eof bool
+}
+
+// Read reads the current record, reading the next record once it has been
+// consumed.
+func (r *restoreStreamReader) Read(p []byte) (int, error) {
+	for len(r.buf) == 0 {
+		if r.eof {
+			return 0, io.EOF
+		}
+
+		typ, buf, err := tlv.ReadTLV(r.r)
+		if err != nil {
+			return 0, err
+		} else if typ != tlv.RestoreShardDataMessage {
+			return 0, fmt.Errorf("unexpected restore stream message type: %d", typ)
+		}
+		r.buf, r.eof = buf, len(buf) == 0
+	}
+
+	n := copy(p, r.buf)
+	r.buf = r.buf[n:]
+	return n, nil
+}
@@ -0,0 +1,151 @@
+package cluster_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"io"
+	"io/ioutil"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/influxdata/influxdb/services/meta"
+	"github.com/zhexuany/influxcloud/cluster"
+)
+
+// Ensure a backup is restored onto the owners of newly created shards, with
+// the files of each backed up shard renamed after its new shard.
+func TestRestoreCoordinator_Restore(t *testing.T) {
+	var mu sync.Mutex
+	restored := make(map[uint64][]string)
+
+	s0, s1 := MustOpenService(), MustOpenService()
+	defer s0.Close()
+	defer s1.Close()
+	for i, s := range []*Service{s0, s1} {
+		nodeID := uint64(i + 1)
+		s.TSDBStore.RestoreShardFn = func(id uint64, r io.Reader) error {
+			tr := tar.NewReader(r)
+			for {
+				hdr, err := tr.Next()
+				if err == io.EOF {
+					return nil
+				} else if err != nil {
+					return err
+				}
+				buf, err := ioutil.ReadAll(tr)
+				if err != nil {
+					return err
+				}
+				mu.Lock()
+				restored[nodeID] = append(restored[nodeID], hdr.Name+"="+string(buf))
+				mu.Unlock()
+			}
+		}
+	}
+
+	// The backed up shard group of two shards is restored to two shards
+	// replicated on both nodes.
+	start := time.Unix(0, 0).UTC()
+	mc := &restoreMetaClient{
+		hosts: map[uint64]string{1: s0.Addr().String(), 2: s1.Addr().String()},
+		group: &meta.ShardGroupInfo{
+			ID: 5,
+			Shards: []meta.ShardInfo{
+				{ID: 20, Owners: []meta.ShardOwner{{NodeID: 1}, {NodeID: 2}}},
+				{ID: 21, Owners: []meta.ShardOwner{{NodeID: 2}, {NodeID: 1}}},
+			},
+		},
+	}
+	c := cluster.NewRestoreCoordinator(time.Second)
+	c.MetaClient = mc
+
+	m := &cluster.BackupManifest{
+		Database:           "db0",
+		RetentionPolicy:    "rp0",
+		ReplicaN:           2,
+		ShardGroupDuration: time.Hour,
+		Shards: []cluster.BackupShardInfo{
+			{ID: 10, ShardGroupID: 1, StartTime: start, NodeID: 3},
+			{ID: 11, ShardGroupID: 1, StartTime: start, NodeID: 4},
+		},
+	}
+	src := restoreSource{
+		10: MustTarShardBackup("db0/rp0/10/000000001-000000001.tsm", "a"),
+		11: MustTarShardBackup("db0/rp0/11/000000001-000000001.tsm", "b"),
+	}
+
+	ids, err := c.Restore(m, src)
+	if err != nil {
+		t.Fatal(err)
+	} else if exp := map[uint64]uint64{10: 20, 11: 21}; !reflect.DeepEqual(ids, exp) {
+		t.Fatalf("unexpected shard ids: %v", ids)
+	}
+
+	if mc.rp == nil || mc.rp.Name != "rp0" || *mc.rp.ReplicaN != 2 || mc.rp.ShardGroupDuration != time.Hour {
+		t.Fatalf("unexpected retention policy: %+v", mc.rp)
+	} else if mc.shardN != 2 {
+		t.Fatalf("unexpected shard count: %d", mc.shardN)
+	}
+
+	files := []string{"db0/rp0/20/000000001-000000001.tsm=a", "db0/rp0/21/000000001-000000001.tsm=b"}
+	if exp := map[uint64][]string{1: files, 2: files}; !reflect.DeepEqual(restored, exp) {
+		t.Fatalf("unexpected restored files: %v", restored)
+	}
+
+	// A restore into a shard group with a different shard count fails.
+	mc.group.Shards = mc.group.Shards[:1]
+	if _, err := c.Restore(m, src); err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+// MustTarShardBackup returns a shard backup archive with a single file.
+// Panic on error.
+func MustTarShardBackup(name, data string) []byte {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0666, Size: int64(len(data))}); err != nil {
+		panic(err)
+	} else if _, err := tw.Write([]byte(data)); err != nil {
+		panic(err)
+	} else if err := tw.Close(); err != nil {
+		panic(err)
+	}
+	return buf.Bytes()
+}
+
+// restoreMetaClient creates a single shard group for a restore.
+type restoreMetaClient struct {
+	hosts map[uint64]string
+	group *meta.ShardGroupInfo
+
+	rp     *meta.RetentionPolicySpec
+	shardN int
+}
+
+func (c *restoreMetaClient) DataNode(id uint64) (*meta.NodeInfo, error) {
+	return &meta.NodeInfo{ID: id, TCPHost: c.hosts[id]}, nil
+}
+
+func (c *restoreMetaClient) CreateDatabase(name string) (*meta.DatabaseInfo, error) {
+	return &meta.DatabaseInfo{Name: name}, nil
+}
+
+func (c *restoreMetaClient) CreateRetentionPolicy(database string, spec *meta.RetentionPolicySpec) (*meta.RetentionPolicyInfo, error) {
+	c.rp = spec
+	return spec.NewRetentionPolicyInfo(), nil
+}
+
+func (c *restoreMetaClient) CreateShardGroupWithShards(database, policy string, timestamp time.Time, shardN int) (*meta.ShardGroupInfo, error) {
+	c.shardN = shardN
+	return c.group, nil
+}
+
+// restoreSource serves shard backups by shard ID.
+type restoreSource map[uint64][]byte
+
+func (s restoreSource) OpenShard(shard cluster.BackupShardInfo) (io.ReadCloser, error) {
+	return ioutil.NopCloser(bytes.NewReader(s[shard.ID])), nil
+}
@@ -0,0 +1,263 @@
+package cluster
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/influxdata/influxdb/services/meta"
+	"github.com/uber-go/zap"
+	"github.com/zhexuany/influxcloud/rpc"
+	"github.com/zhexuany/influxcloud/tlv"
+)
+
+// retentionLease is the lease held by the data node enforcing retention.
+const retentionLease = "retention"
+
+// RetentionService enforces retention policies for the whole cluster. It
+// deletes expired shard groups from the meta store, then asks every owner of
+// their shards to drop its local copy. Owners that cannot be reached are
+// asked again on each check until they drop their copy, so that nodes that
+// were down do not keep expired shards. Deleted shard groups are pruned from
+// the meta store once every owner has dropped its shards.
+type RetentionService struct {
+	checkInterval time.Duration
+	timeout       time.Duration
+
+	// Dialer connects to the owners of expired shards.
+	Dialer *Dialer
+
+	wg      sync.WaitGroup
+	closing chan struct{}
+
+	mu      sync.Mutex
+	dropped map[uint64]map[uint64]bool // Owners that dropped each shard, keyed by shard ID.
+
+	MetaClient interface {
+		Databases() ([]meta.DatabaseInfo, error)
+		DataNodes() ([]meta.NodeInfo, error)
+		DeleteShardGroup(database, policy string, id uint64) error
+		PruneShardGroups() error
+	}
+
+	// Leases ensures only one data node enforces retention at a time.
+	// Retention is enforced by every node it runs on if nil.
+	Leases interface {
+		AcquireLease(name string) (*meta.Lease, error)
+	}
+
+	Logger zap.Logger
+}
+
+// NewRetentionService returns a new instance of RetentionService checking for
+// expired shard groups every checkInterval.
+func NewRetentionService(checkInterval, timeout time.Duration) *RetentionService {
+	return &RetentionService{
+		checkInterval: checkInterval,
+		timeout:       timeout,
+		Dialer:        DefaultDialer,
+		closing:       make(chan struct{}),
+		dropped:       make(map[uint64]map[uint64]bool),
+		Logger:        zap.New(zap.NullEncoder()),
+	}
+}
+
+// WithLogger sets the internal logger to the logger passed in
+func (s *RetentionService) WithLogger(log zap.Logger) {
+	s.Logger = log.With(zap.String("service", "cluster-retention"))
+}
+
+// Open starts enforcing retention policies.
+func (s *RetentionService) Open() error {
+	s.Logger.Info("Starting cluster retention service", zap.Duration("checkInterval", s.checkInterval))
+	s.wg.Add(1)
+	go s.run()
+	return nil
+}
+
+// Close stops enforcing retention policies.
+func (s *RetentionService) Close() error {
+	close(s.closing)
+	s.wg.Wait()
+	return nil
+}
+
+// run enforces retention every checkInterval until the service is closed.
+func (s *RetentionService) run() {
+	defer s.wg.Done()
+
+	t := time.NewTicker(s.checkInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+			if err := s.Enforce(time.Now().UTC()); err != nil {
+				s.Logger.Info("retention enforcement failed", zap.Error(err))
+			}
+		case <-s.closing:
+			return
+		}
+	}
+}
+
+// Enforce deletes the shard groups expired at now, and asks the owners of the
+// shards of deleted shard groups to drop them. It does nothing if another
+// node holds the retention lease.
+func (s *RetentionService) Enforce(now time.Time) error {
+	if s.Leases != nil {
+		if _, err := s.Leases.AcquireLease(retentionLease); err != nil {
+			return nil
+		}
+	}
+
+	dbs, err := s.MetaClient.Databases()
+	if err != nil {
+		return err
+	}
+	deleted := 0
+	for _, db := range dbs {
+		for _, rp := range db.RetentionPolicies {
+			for _, sg := range rp.ExpiredShardGroups(now) {
+				if err := s.MetaClient.DeleteShardGroup(db.Name, rp.Name, sg.ID); err != nil {
+					s.Logger.Info("unable to delete shard group", zap.Uint64("shardGroupID", sg.ID), zap.String("database", db.Name), zap.String("retentionPolicy", rp.Name), zap.Error(err))
+					continue
+				}
+				s.Logger.Info("deleted shard group", zap.Uint64("shardGroupID", sg.ID), zap.String("database", db.Name), zap.String("retentionPolicy", rp.Name))
+				deleted++
+			}
+		}
+	}
+	if deleted > 0 {
+		if dbs, err = s.MetaClient.Databases(); err != nil {
+			return err
+		}
+	}
+
+	nodes, err := s.MetaClient.DataNodes()
+	if err != nil {
+		return err
+	}
+	if pending := s.dropShards(dbs, nodes); pending > 0 {
+		s.Logger.Info("expired shard copies left to drop", zap.Int("n", pending))
+		return nil
+	}
+
+	// Every owner dropped the shards of the deleted shard groups.
+	return s.MetaClient.PruneShardGroups()
+}
+
+// dropShards asks the owners of the shards of the deleted shard groups in dbs
+// to drop them, if they have not yet. It returns the number of copies still
+// to be dropped. Owners that are no longer data nodes have nothing to drop.
+func (s *RetentionService) dropShards(dbs []meta.DatabaseInfo, nodes []meta.NodeInfo) int {
+	hosts := make(map[uint64]string, len(nodes))
+	for _, n := range nodes {
+		hosts[n.ID] = n.TCPHost
+	}
+
+	// The shards each node has yet to drop, keyed by node ID. Shards pruned
+	// from the meta store are forgotten.
+	s.mu.Lock()
+	drops := make(map[uint64][]uint64)
+	expired := make(map[uint64]bool)
+	for _, db := range dbs {
+		for _, rp := range db.RetentionPolicies {
+			for _, sg := range rp.DeletedShardGroups() {
+				for _, sh := range sg.Shards {
+					expired[sh.ID] = true
+					for _, o := range sh.Owners {
+						if _, ok := hosts[o.NodeID]; ok && !s.dropped[sh.ID][o.NodeID] {
+							drops[o.NodeID] = append(drops[o.NodeID], sh.ID)
+						}
+					}
+				}
+			}
+		}
+	}
+	for id := range s.dropped {
+		if !expired[id] {
+			delete(s.dropped, id)
+		}
+	}
+	s.mu.Unlock()
+
+	ids := make([]uint64, 0, len(drops))
+	for id := range drops {
+		ids = append(ids, id)
+	}
+	sort.Sort(uint64Slice(ids))
+
+	pending := 0
+	for _, nodeID := range ids {
+		shardIDs := drops[nodeID]
+		if err := s.requestDropShards(hosts[nodeID], shardIDs); err != nil {
+			s.Logger.Info("unable to drop expired shards", zap.Uint64("nodeID", nodeID), zap.Int("n", len(shardIDs)), zap.Error(err))
+			pending += len(shardIDs)
+			continue
+		}
+
+		s.mu.Lock()
+		for _, id := range shardIDs {
+			if s.dropped[id] == nil {
+				s.dropped[id] = make(map[uint64]bool)
+			}
+			s.dropped[id][nodeID] = true
+		}
+		s.mu.Unlock()
+	}
+	return pending
+}
+
+// requestDropShards asks the node at addr to drop its copies of shardIDs.
+func (s *RetentionService) requestDropShards(addr string, shardIDs []uint64) error {
+	var resp rpc.DropShardsResponse
+	if err := s.Dialer.dialRPC(addr, s.timeout, tlv.DropShardsRequestMessage, &rpc.DropShardsRequest{
+		ShardIDs: shardIDs,
+	}, &resp); err != nil {
+		return err
+	} else if resp.Err != "" {
+		return errors.New(resp.Err)
+	}
+	return nil
+}
+
+// processDropShardsRequest deletes the requested local shards. Requests for
+// shards that still belong to a live shard group in the meta store of this
+// node are refused, so that they cannot delete live data.
+func (s *Service) processDropShardsRequest(conn net.Conn) error {
+	var req rpc.DropShardsRequest
+	if err := tlv.DecodeLV(conn, &req); err != nil {
+		return err
+	}
+
+	var resp rpc.DropShardsResponse
+	err := s.dropShards(req.ShardIDs)
+	s.audit("drop shards", conn.RemoteAddr(), 0, err, zap.Object("shardIDs", req.ShardIDs))
+	if err != nil {
+		resp.Err = err.Error()
+	}
+
+	return tlv.EncodeTLV(conn, tlv.DropShardsResponseMessage, &resp)
+}
+
+// dropShards deletes the local shards ids of deleted shard groups.
+func (s *Service) dropShards(ids []uint64) error {
+	for _, id := range ids {
+		if db, _, _ := s.MetaClient.ShardOwner(id); db != "" {
+			return fmt.Errorf("shard %d belongs to a live shard group", id)
+		}
+	}
+
+	for _, id := range ids {
+		s.snapshots.remove(id)
+		if err := s.TSDBStore.DeleteShard(id); err != nil {
+			return fmt.Errorf("drop shard %d: %s", id, err)
+		}
+		s.Logger.Info("dropped expired shard", zap.Uint64("shardID", id))
+		s.Events.Publish(Event{Type: EventShardDropped, NodeID: s.Node.ID, ShardID: id, Message: "expired"})
+	}
+	return nil
+}
@@ -0,0 +1,142 @@
+package cluster_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/influxdata/influxdb/services/meta"
+	"github.com/zhexuany/influxcloud/cluster"
+)
+
+// Ensure expired shard groups are deleted, that every owner is asked to drop
+// their shards until it does, and that the shard groups are then pruned.
+func TestRetentionService_Enforce(t *testing.T) {
+	s := MustOpenService()
+	defer s.Close()
+
+	var dropped []uint64
+	s.TSDBStore.DeleteShardFn = func(id uint64) error {
+		dropped = append(dropped, id)
+		return nil
+	}
+	s.MetaClient.ShardOwnerFn = func(shardID uint64) (string, string, meta.ShardInfo) {
+		return "", "", meta.ShardInfo{}
+	}
+
+	now := time.Now().UTC()
+	mc := &RetentionMetaClient{
+		dbs: []meta.DatabaseInfo{{
+			Name: "db0",
+			RetentionPolicies: []meta.RetentionPolicyInfo{{
+				Name:     "rp0",
+				Duration: time.Hour,
+				ShardGroups: []meta.ShardGroupInfo{{
+					ID:        1,
+					StartTime: now.Add(-3 * time.Hour),
+					EndTime:   now.Add(-2 * time.Hour),
+					Shards:    []meta.ShardInfo{{ID: 10, Owners: []meta.ShardOwner{{NodeID: 1}, {NodeID: 2}}}},
+				}},
+			}},
+		}},
+		nodes: []meta.NodeInfo{
+			{ID: 1, TCPHost: s.Addr().String()},
+			{ID: 2, TCPHost: "127.0.0.1:0"},
+		},
+	}
+
+	r := cluster.NewRetentionService(time.Hour, time.Second)
+	r.MetaClient = mc
+
+	// Node 2 is down, so the shard group is not pruned.
+	if err := r.Enforce(now); err != nil {
+		t.Fatal(err)
+	} else if !mc.dbs[0].RetentionPolicies[0].ShardGroups[0].Deleted() {
+		t.Fatal("expected shard group to be deleted")
+	} else if !reflect.DeepEqual(dropped, []uint64{10}) {
+		t.Fatalf("unexpected dropped shards: %v", dropped)
+	} else if mc.pruned {
+		t.Fatal("unexpected prune")
+	}
+
+	// Node 1 is not asked again once it dropped the shard.
+	if err := r.Enforce(now); err != nil {
+		t.Fatal(err)
+	} else if len(dropped) != 1 || mc.pruned {
+		t.Fatalf("unexpected dropped shards: %v", dropped)
+	}
+
+	// Node 2 drops the shard once it is back.
+	mc.nodes[1].TCPHost = s.Addr().String()
+	if err := r.Enforce(now); err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(dropped, []uint64{10, 10}) {
+		t.Fatalf("unexpected dropped shards: %v", dropped)
+	} else if !mc.pruned {
+		t.Fatal("expected deleted shard groups to be pruned")
+	}
+}
+
+// Ensure nodes refuse to drop shards of live shard groups.
+func TestService_DropShards_Live(t *testing.T) {
+	s := MustOpenService()
+	defer s.Close()
+
+	s.TSDBStore.DeleteShardFn = func(id uint64) error {
+		t.Fatalf("unexpected drop of shard %d", id)
+		return nil
+	}
+	s.MetaClient.ShardOwnerFn = func(shardID uint64) (string, string, meta.ShardInfo) {
+		return "db0", "rp0", meta.ShardInfo{ID: shardID}
+	}
+
+	r := cluster.NewRetentionService(time.Hour, time.Second)
+	r.MetaClient = &RetentionMetaClient{
+		dbs: []meta.DatabaseInfo{{
+			Name: "db0",
+			RetentionPolicies: []meta.RetentionPolicyInfo{{
+				Name: "rp0",
+				ShardGroups: []meta.ShardGroupInfo{{
+					ID:        1,
+					DeletedAt: time.Now(),
+					Shards:    []meta.ShardInfo{{ID: 10, Owners: []meta.ShardOwner{{NodeID: 1}}}},
+				}},
+			}},
+		}},
+		nodes: []meta.NodeInfo{{ID: 1, TCPHost: s.Addr().String()}},
+	}
+	if err := r.Enforce(time.Now()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// RetentionMetaClient is an in-memory implementation of
+// cluster.RetentionService.MetaClient.
+type RetentionMetaClient struct {
+	dbs    []meta.DatabaseInfo
+	nodes  []meta.NodeInfo
+	pruned bool
+}
+
+func (m *RetentionMetaClient) Databases() ([]meta.DatabaseInfo, error) { return m.dbs, nil }
+
+func (m *RetentionMetaClient) DataNodes() ([]meta.NodeInfo, error) { return m.nodes, nil }
+
+func (m *RetentionMetaClient) DeleteShardGroup(database, policy string, id uint64) error {
+	for i := range m.dbs {
+		for j := range m.dbs[i].RetentionPolicies {
+			rp := &m.dbs[i].RetentionPolicies[j]
+			for k := range rp.ShardGroups {
+				if rp.ShardGroups[k].ID == id {
+					rp.ShardGroups[k].DeletedAt = time.Now()
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func (m *RetentionMetaClient) PruneShardGroups() error {
+	m.pruned = true
+	return nil
+}
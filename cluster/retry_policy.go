@@ -0,0 +1,160 @@
+package cluster
+
+import (
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/zhexuany/influxcloud/rpc"
+)
+
+// DefaultMaxWriteRetries is the number of times StrictRetryPolicy retries a
+// write to an overloaded node before giving up.
+const DefaultMaxWriteRetries = 3
+
+const (
+	// DefaultWriteRetryMinBackoff is the wait StrictRetryPolicy backs off
+	// for before the first retry, which doubles on every further retry.
+	DefaultWriteRetryMinBackoff = 10 * time.Millisecond
+
+	// DefaultWriteRetryMaxBackoff is the longest wait StrictRetryPolicy
+	// backs off for between retries.
+	DefaultWriteRetryMaxBackoff = time.Second
+)
+
+// Names of the retry policies that can be configured.
+const (
+	// RetryPolicyDefault selects DefaultRetryPolicy.
+	RetryPolicyDefault = "default"
+
+	// RetryPolicyStrict selects StrictRetryPolicy.
+	RetryPolicyStrict = "strict"
+)
+
+// NewRetryPolicy returns the retry policy called name. A strict policy
+// retries writes to overloaded nodes up to maxRetries times, or
+// DefaultMaxWriteRetries times if maxRetries is not positive. An empty name
+// selects the default policy.
+func NewRetryPolicy(name string, maxRetries int) (RetryPolicy, error) {
+	switch name {
+	case "", RetryPolicyDefault:
+		return DefaultRetryPolicy{}, nil
+	case RetryPolicyStrict:
+		p := NewStrictRetryPolicy()
+		if maxRetries > 0 {
+			p.MaxRetries = maxRetries
+		}
+		return p, nil
+	}
+	return nil, fmt.Errorf("unknown write retry policy: %q", name)
+}
+
+// RetryDecision is the action taken after a failed write to a remote node.
+type RetryDecision int
+
+const (
+	// RetryDecisionFail returns the error to the caller without retrying.
+	RetryDecisionFail RetryDecision = iota
+
+	// RetryDecisionRetry sends the write to the remote node again.
+	RetryDecisionRetry
+
+	// RetryDecisionHintedHandoff queues the write in hinted handoff so that
+	// it is delivered when the remote node is available again.
+	RetryDecisionHintedHandoff
+)
+
+// String returns the name of the decision.
+func (d RetryDecision) String() string {
+	switch d {
+	case RetryDecisionFail:
+		return "fail"
+	case RetryDecisionRetry:
+		return "retry"
+	case RetryDecisionHintedHandoff:
+		return "hinted handoff"
+	default:
+		return "unknown"
+	}
+}
+
+// RetryPolicy decides how the PointsWriter handles a failed write to a remote
+// node. attempt is the number of writes already made, starting at 1. Backoff
+// is the wait before the write retried after attempt is sent again.
+type RetryPolicy interface {
+	Decide(err error, attempt int) RetryDecision
+	Backoff(attempt int) time.Duration
+}
+
+// DefaultRetryPolicy queues every failed write in hinted handoff unless the
+// remote node reported an error that will fail again on every attempt, such as
+// a field type conflict.
+type DefaultRetryPolicy struct{}
+
+// Decide implements RetryPolicy.
+func (DefaultRetryPolicy) Decide(err error, attempt int) RetryDecision {
+	if e, ok := err.(*rpc.WriteShardError); ok && !e.Retryable() {
+		return RetryDecisionFail
+	}
+	return RetryDecisionHintedHandoff
+}
+
+// Backoff implements RetryPolicy. No write is retried, so there is no wait.
+func (DefaultRetryPolicy) Backoff(attempt int) time.Duration { return 0 }
+
+// StrictRetryPolicy only queues writes in hinted handoff when the remote node
+// could not be reached, is draining or read-only, dropped the write because
+// its deadline had passed, or replication to it is paused. Writes rejected by
+// an overloaded node are retried up to MaxRetries times; all other errors
+// reported by the remote node are returned to the caller.
+//
+// Retries back off exponentially from MinBackoff up to MaxBackoff, and each
+// wait is jittered between half and all of it, so that the writers an
+// overloaded node rejected at once do not all retry at once.
+type StrictRetryPolicy struct {
+	MaxRetries int
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+}
+
+// NewStrictRetryPolicy returns a StrictRetryPolicy with the default retry
+// limit and backoff.
+func NewStrictRetryPolicy() *StrictRetryPolicy {
+	return &StrictRetryPolicy{
+		MaxRetries: DefaultMaxWriteRetries,
+		MinBackoff: DefaultWriteRetryMinBackoff,
+		MaxBackoff: DefaultWriteRetryMaxBackoff,
+	}
+}
+
+// Decide implements RetryPolicy.
+func (p *StrictRetryPolicy) Decide(err error, attempt int) RetryDecision {
+	e, ok := err.(*rpc.WriteShardError)
+	if !ok {
+		return RetryDecisionHintedHandoff
+	}
+
+	switch {
+	case e.Code == rpc.CodeDraining, e.Code == rpc.CodeReadOnly, e.Code == rpc.CodeDeadlineExceeded:
+		return RetryDecisionHintedHandoff
+	case e.Code == rpc.CodeOverloaded && attempt <= p.MaxRetries:
+		return RetryDecisionRetry
+	default:
+		return RetryDecisionFail
+	}
+}
+
+// Backoff implements RetryPolicy.
+func (p *StrictRetryPolicy) Backoff(attempt int) time.Duration {
+	d := p.MinBackoff
+	for i := 1; i < attempt && d < p.MaxBackoff; i++ {
+		d *= 2
+	}
+	if d > p.MaxBackoff {
+		d = p.MaxBackoff
+	}
+	if d <= 0 {
+		return 0
+	}
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
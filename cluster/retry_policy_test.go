@@ -0,0 +1,87 @@
+package cluster_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/zhexuany/influxcloud/cluster"
+	"github.com/zhexuany/influxcloud/rpc"
+)
+
+// Ensure the retry policies classify remote write errors.
+func TestRetryPolicy_Decide(t *testing.T) {
+	strict := cluster.NewStrictRetryPolicy()
+
+	for i, tt := range []struct {
+		policy  cluster.RetryPolicy
+		err     error
+		attempt int
+		exp     cluster.RetryDecision
+	}{
+		{cluster.DefaultRetryPolicy{}, errors.New("connection refused"), 1, cluster.RetryDecisionHintedHandoff},
+		{cluster.DefaultRetryPolicy{}, cluster.ErrReplicationPaused, 1, cluster.RetryDecisionHintedHandoff},
+		{cluster.DefaultRetryPolicy{}, &rpc.WriteShardError{Code: rpc.CodeOverloaded}, 1, cluster.RetryDecisionHintedHandoff},
+		{cluster.DefaultRetryPolicy{}, &rpc.WriteShardError{Code: rpc.CodeReadOnly}, 1, cluster.RetryDecisionHintedHandoff},
+		{cluster.DefaultRetryPolicy{}, &rpc.WriteShardError{Code: rpc.CodeFieldTypeConflict}, 1, cluster.RetryDecisionFail},
+		{cluster.DefaultRetryPolicy{}, &rpc.WriteShardError{Code: rpc.CodeAuthFailed}, 1, cluster.RetryDecisionFail},
+
+		{strict, errors.New("connection refused"), 1, cluster.RetryDecisionHintedHandoff},
+		{strict, cluster.ErrReplicationPaused, 1, cluster.RetryDecisionHintedHandoff},
+		{strict, &rpc.WriteShardError{Code: rpc.CodeDraining}, 1, cluster.RetryDecisionHintedHandoff},
+		{strict, &rpc.WriteShardError{Code: rpc.CodeReadOnly}, 1, cluster.RetryDecisionHintedHandoff},
+		{strict, &rpc.WriteShardError{Code: rpc.CodeOverloaded}, 1, cluster.RetryDecisionRetry},
+		{strict, &rpc.WriteShardError{Code: rpc.CodeOverloaded}, cluster.DefaultMaxWriteRetries + 1, cluster.RetryDecisionFail},
+		{strict, &rpc.WriteShardError{Code: rpc.CodeShardNotFound}, 1, cluster.RetryDecisionFail},
+		{strict, &rpc.WriteShardError{Code: rpc.CodeFieldTypeConflict}, 1, cluster.RetryDecisionFail},
+	} {
+		if got := tt.policy.Decide(tt.err, tt.attempt); got != tt.exp {
+			t.Errorf("%d. %T(%v, %d): got %s, exp %s", i, tt.policy, tt.err, tt.attempt, got, tt.exp)
+		}
+	}
+}
+
+// Ensure the strict retry policy backs off exponentially, with jitter, up to
+// its maximum backoff.
+func TestStrictRetryPolicy_Backoff(t *testing.T) {
+	p := &cluster.StrictRetryPolicy{MinBackoff: 10 * time.Millisecond, MaxBackoff: 50 * time.Millisecond}
+	for attempt, max := range []time.Duration{
+		1: 10 * time.Millisecond,
+		2: 20 * time.Millisecond,
+		3: 40 * time.Millisecond,
+		4: 50 * time.Millisecond,
+		5: 50 * time.Millisecond,
+	} {
+		if attempt == 0 {
+			continue
+		}
+		for i := 0; i < 100; i++ {
+			if d := p.Backoff(attempt); d < max/2 || d > max {
+				t.Fatalf("attempt %d: backoff %s out of [%s, %s]", attempt, d, max/2, max)
+			}
+		}
+	}
+
+	if d := (cluster.DefaultRetryPolicy{}).Backoff(1); d != 0 {
+		t.Fatalf("unexpected default backoff: %s", d)
+	}
+}
+
+// Ensure retry policies are looked up by their configured name.
+func TestNewRetryPolicy(t *testing.T) {
+	if p, err := cluster.NewRetryPolicy("", 0); err != nil {
+		t.Fatal(err)
+	} else if _, ok := p.(cluster.DefaultRetryPolicy); !ok {
+		t.Fatalf("unexpected policy: %#v", p)
+	}
+
+	if p, err := cluster.NewRetryPolicy(cluster.RetryPolicyStrict, 0); err != nil {
+		t.Fatal(err)
+	} else if p, ok := p.(*cluster.StrictRetryPolicy); !ok || p.MaxRetries != cluster.DefaultMaxWriteRetries {
+		t.Fatalf("unexpected policy: %#v", p)
+	}
+
+	if _, err := cluster.NewRetryPolicy("sometimes", 0); err == nil {
+		t.Fatal("expected error")
+	}
+}
@@ -0,0 +1,357 @@
+package cluster
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SnapshotSink stores shard snapshots outside of the cluster, so that data
+// nodes can upload them without copying them through the backup client.
+type SnapshotSink interface {
+	// PutSnapshot stores the snapshot read from r under key, and returns
+	// the number of bytes stored.
+	PutSnapshot(key string, r io.Reader) (int64, error)
+}
+
+// S3SnapshotSink uploads shard snapshots to an S3-compatible object store.
+// Snapshots are uploaded in parts of PartSize bytes, Concurrency parts at a
+// time. Objects are addressed by path, i.e. as <endpoint>/<bucket>/<key>.
+type S3SnapshotSink struct {
+	Endpoint        string
+	Region          string
+	Bucket          string
+	Prefix          string
+	AccessKeyID     string
+	SecretAccessKey string
+	PartSize        int
+	Concurrency     int
+
+	Client *http.Client
+}
+
+// NewS3SnapshotSink returns a new instance of S3SnapshotSink with config c.
+func NewS3SnapshotSink(c S3Config) *S3SnapshotSink {
+	s := &S3SnapshotSink{
+		Endpoint:        c.Endpoint,
+		Region:          c.Region,
+		Bucket:          c.Bucket,
+		Prefix:          c.Prefix,
+		AccessKeyID:     c.AccessKeyID,
+		SecretAccessKey: c.SecretAccessKey,
+		PartSize:        int(c.PartSize),
+		Concurrency:     c.Concurrency,
+		Client:          http.DefaultClient,
+	}
+	if s.Endpoint == "" {
+		s.Endpoint = "https://s3.amazonaws.com"
+	}
+	if s.Region == "" {
+		s.Region = DefaultS3Region
+	}
+	if s.PartSize <= 0 {
+		s.PartSize = DefaultS3PartSize
+	}
+	if s.Concurrency <= 0 {
+		s.Concurrency = DefaultS3Concurrency
+	}
+	if s.AccessKeyID == "" && s.SecretAccessKey == "" {
+		s.AccessKeyID = os.Getenv("AWS_ACCESS_KEY_ID")
+		s.SecretAccessKey = os.Getenv("AWS_SECRET_ACCESS_KEY")
+	}
+	return s
+}
+
+// PutSnapshot uploads the snapshot read from r as the object key under
+// Prefix. Snapshots that fit in a single part are uploaded with one request.
+func (s *S3SnapshotSink) PutSnapshot(key string, r io.Reader) (int64, error) {
+	key = path.Join(s.Prefix, key)
+
+	part, err := readPart(r, s.PartSize)
+	if err != nil {
+		return 0, err
+	} else if len(part) < s.PartSize {
+		if _, err := s.do("PUT", key, nil, part); err != nil {
+			return 0, fmt.Errorf("put snapshot %s: %s", key, err)
+		}
+		return int64(len(part)), nil
+	}
+
+	uploadID, err := s.createMultipartUpload(key)
+	if err != nil {
+		return 0, fmt.Errorf("create upload of snapshot %s: %s", key, err)
+	}
+
+	n, etags, err := s.uploadParts(key, uploadID, part, r)
+	if err == nil {
+		err = s.completeMultipartUpload(key, uploadID, etags)
+	}
+	if err != nil {
+		s.do("DELETE", key, url.Values{"uploadId": {uploadID}}, nil)
+		return 0, fmt.Errorf("upload snapshot %s: %s", key, err)
+	}
+	return n, nil
+}
+
+// uploadParts uploads first and the rest of r as the parts of an upload, and
+// returns the number of bytes uploaded and the ETag of each part.
+func (s *S3SnapshotSink) uploadParts(key, uploadID string, first []byte, r io.Reader) (int64, []string, error) {
+	var (
+		mu    sync.Mutex
+		wg    sync.WaitGroup
+		etags []string
+		err   error
+		n     int64
+	)
+	sem := make(chan struct{}, s.Concurrency)
+	fail := func(e error) {
+		mu.Lock()
+		if err == nil {
+			err = e
+		}
+		mu.Unlock()
+	}
+	failed := func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return err != nil
+	}
+
+	part := first
+	for i := 1; len(part) > 0 && !failed(); i++ {
+		mu.Lock()
+		etags = append(etags, "")
+		mu.Unlock()
+		n += int64(len(part))
+
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(i int, part []byte) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			h, e := s.do("PUT", key, url.Values{"partNumber": {fmt.Sprint(i)}, "uploadId": {uploadID}}, part)
+			if e != nil {
+				fail(fmt.Errorf("part %d: %s", i, e))
+				return
+			}
+			mu.Lock()
+			etags[i-1] = h.Get("ETag")
+			mu.Unlock()
+		}(i, part)
+
+		var e error
+		if part, e = readPart(r, s.PartSize); e != nil {
+			fail(e)
+		}
+	}
+	wg.Wait()
+
+	return n, etags, err
+}
+
+// createMultipartUpload starts an upload of key and returns its ID.
+func (s *S3SnapshotSink) createMultipartUpload(key string) (string, error) {
+	buf, err := s.doBody("POST", key, url.Values{"uploads": {""}}, nil)
+	if err != nil {
+		return "", err
+	}
+
+	var result struct {
+		UploadID string `xml:"UploadId"`
+	}
+	if err := xml.Unmarshal(buf, &result); err != nil {
+		return "", err
+	}
+	return result.UploadID, nil
+}
+
+// completeMultipartUpload assembles the uploaded parts into the object key.
+func (s *S3SnapshotSink) completeMultipartUpload(key, uploadID string, etags []string) error {
+	type part struct {
+		PartNumber int
+		ETag       string
+	}
+	var req struct {
+		XMLName xml.Name `xml:"CompleteMultipartUpload"`
+		Parts   []part   `xml:"Part"`
+	}
+	for i, etag := range etags {
+		req.Parts = append(req.Parts, part{PartNumber: i + 1, ETag: etag})
+	}
+	body, err := xml.Marshal(&req)
+	if err != nil {
+		return err
+	}
+
+	// Errors completing an upload may be returned with a 200 status.
+	buf, err := s.doBody("POST", key, url.Values{"uploadId": {uploadID}}, body)
+	if err != nil {
+		return err
+	} else if bytes.Contains(buf, []byte("<Error>")) {
+		return fmt.Errorf("complete upload: %s", buf)
+	}
+	return nil
+}
+
+// do sends a signed request for the object key and returns the response
+// headers.
+func (s *S3SnapshotSink) do(method, key string, query url.Values, body []byte) (http.Header, error) {
+	resp, err := s.request(method, key, query, body)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	io.Copy(ioutil.Discard, resp.Body)
+	return resp.Header, nil
+}
+
+// doBody sends a signed request for the object key and returns the response
+// body.
+func (s *S3SnapshotSink) doBody(method, key string, query url.Values, body []byte) ([]byte, error) {
+	resp, err := s.request(method, key, query, body)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	return ioutil.ReadAll(resp.Body)
+}
+
+// request sends a signed request and returns the response if it succeeded.
+func (s *S3SnapshotSink) request(method, key string, query url.Values, body []byte) (*http.Response, error) {
+	u, err := url.Parse(strings.TrimSuffix(s.Endpoint, "/"))
+	if err != nil {
+		return nil, err
+	}
+	u.Path = "/" + s.Bucket + "/" + key
+	u.RawPath = "/" + s3Escape(s.Bucket, false) + "/" + s3Escape(key, false)
+	u.RawQuery = s3Query(query)
+
+	req, err := http.NewRequest(method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.ContentLength = int64(len(body))
+	s.sign(req, body)
+
+	resp, err := s.Client.Do(req)
+	if err != nil {
+		return nil, err
+	} else if resp.StatusCode/100 != 2 {
+		defer resp.Body.Close()
+		buf, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 4096))
+		return nil, fmt.Errorf("%s %s: %s: %s", method, key, resp.Status, bytes.TrimSpace(buf))
+	}
+	return resp, nil
+}
+
+// sign signs req with AWS Signature Version 4.
+func (s *S3SnapshotSink) sign(req *http.Request, body []byte) {
+	now := time.Now().UTC()
+	date := now.Format("20060102")
+	payload := sha256.Sum256(body)
+	payloadHash := hex.EncodeToString(payload[:])
+
+	req.Header.Set("X-Amz-Date", now.Format("20060102T150405Z"))
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	// Sign the host and the headers set above, in sorted order.
+	names := []string{"host", "x-amz-content-sha256", "x-amz-date"}
+	var headers bytes.Buffer
+	for _, name := range names {
+		v := req.Header.Get(name)
+		if name == "host" {
+			v = req.URL.Host
+		}
+		fmt.Fprintf(&headers, "%s:%s\n", name, strings.TrimSpace(v))
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonical := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		headers.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := date + "/" + s.Region + "/s3/aws4_request"
+	hash := sha256.Sum256([]byte(canonical))
+	toSign := "AWS4-HMAC-SHA256\n" + now.Format("20060102T150405Z") + "\n" + scope + "\n" + hex.EncodeToString(hash[:])
+
+	key := hmacSHA256([]byte("AWS4"+s.SecretAccessKey), date)
+	key = hmacSHA256(key, s.Region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, toSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.AccessKeyID, scope, signedHeaders, signature))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+// s3Query encodes query in the canonical form requests are signed with.
+func s3Query(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var parts []string
+	for _, k := range keys {
+		for _, v := range query[k] {
+			parts = append(parts, s3Escape(k, true)+"="+s3Escape(v, true))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// s3Escape percent-encodes s as required by AWS Signature Version 4. Slashes
+// are left as is unless encodeSlash is set.
+func s3Escape(s string, encodeSlash bool) string {
+	var buf bytes.Buffer
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9',
+			c == '-', c == '_', c == '.', c == '~':
+			buf.WriteByte(c)
+		case c == '/' && !encodeSlash:
+			buf.WriteByte(c)
+		default:
+			fmt.Fprintf(&buf, "%%%02X", c)
+		}
+	}
+	return buf.String()
+}
+
+// readPart reads up to size bytes from r. A short part is only returned at
+// the end of r.
+func readPart(r io.Reader, size int) ([]byte, error) {
+	buf := make([]byte, size)
+	n, err := io.ReadFull(r, buf)
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		err = nil
+	}
+	return buf[:n], err
+}
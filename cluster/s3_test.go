@@ -0,0 +1,123 @@
+package cluster_test
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/zhexuany/influxcloud/cluster"
+)
+
+// Ensure small snapshots are uploaded with a single signed request.
+func TestS3SnapshotSink_PutSnapshot(t *testing.T) {
+	s := NewS3()
+	defer s.Close()
+
+	sink := s.Sink(16)
+	if n, err := sink.PutSnapshot("db0/rp0/1.tar", strings.NewReader("snapshot")); err != nil {
+		t.Fatal(err)
+	} else if n != 8 {
+		t.Fatalf("unexpected size: %d", n)
+	}
+
+	if got := s.objects["/bucket0/backups/db0/rp0/1.tar"]; got != "snapshot" {
+		t.Fatalf("unexpected object: %q", got)
+	} else if len(s.uploads) != 0 {
+		t.Fatalf("unexpected multipart uploads: %d", len(s.uploads))
+	}
+}
+
+// Ensure large snapshots are uploaded in parts.
+func TestS3SnapshotSink_PutSnapshot_Multipart(t *testing.T) {
+	s := NewS3()
+	defer s.Close()
+
+	data := strings.Repeat("0123456789", 10)
+	sink := s.Sink(16)
+	if n, err := sink.PutSnapshot("db0/rp0/1.tar", strings.NewReader(data)); err != nil {
+		t.Fatal(err)
+	} else if n != int64(len(data)) {
+		t.Fatalf("unexpected size: %d", n)
+	}
+
+	if got := s.objects["/bucket0/backups/db0/rp0/1.tar"]; got != data {
+		t.Fatalf("unexpected object: %q", got)
+	} else if parts := len(s.uploads["upload0"]); parts != 7 {
+		t.Fatalf("unexpected part count: %d", parts)
+	}
+}
+
+// S3 is a minimal S3 server that stores objects in memory.
+type S3 struct {
+	*httptest.Server
+
+	mu      sync.Mutex
+	objects map[string]string
+	uploads map[string]map[int][]byte
+}
+
+// NewS3 returns a new, running instance of S3.
+func NewS3() *S3 {
+	s := &S3{objects: make(map[string]string), uploads: make(map[string]map[int][]byte)}
+	s.Server = httptest.NewServer(s)
+	return s
+}
+
+// Sink returns a sink uploading to the server in parts of partSize bytes.
+func (s *S3) Sink(partSize int) *cluster.S3SnapshotSink {
+	sink := cluster.NewS3SnapshotSink(cluster.S3Config{
+		Endpoint:        s.URL,
+		Bucket:          "bucket0",
+		Prefix:          "backups",
+		AccessKeyID:     "key0",
+		SecretAccessKey: "secret0",
+	})
+	sink.PartSize = partSize
+	sink.Concurrency = 2
+	return sink
+}
+
+func (s *S3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=key0/") {
+		http.Error(w, "unsigned request", http.StatusForbidden)
+		return
+	}
+	body, _ := ioutil.ReadAll(r.Body)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	q := r.URL.Query()
+	_, initiate := q["uploads"]
+	uploadID := q.Get("uploadId")
+	switch {
+	case r.Method == "POST" && initiate:
+		id := fmt.Sprintf("upload%d", len(s.uploads))
+		s.uploads[id] = make(map[int][]byte)
+		fmt.Fprintf(w, "<InitiateMultipartUploadResult><UploadId>%s</UploadId></InitiateMultipartUploadResult>", id)
+	case r.Method == "PUT" && uploadID != "":
+		var n int
+		fmt.Sscan(q.Get("partNumber"), &n)
+		s.uploads[uploadID][n] = body
+		w.Header().Set("ETag", fmt.Sprintf(`"%d"`, n))
+	case r.Method == "POST" && uploadID != "":
+		var buf bytes.Buffer
+		for i := 1; i <= len(s.uploads[uploadID]); i++ {
+			if !bytes.Contains(body, []byte(fmt.Sprintf(`<PartNumber>%d</PartNumber><ETag>&#34;%d&#34;</ETag>`, i, i))) {
+				http.Error(w, fmt.Sprintf("missing part %d", i), http.StatusBadRequest)
+				return
+			}
+			buf.Write(s.uploads[uploadID][i])
+		}
+		s.objects[r.URL.Path] = buf.String()
+	case r.Method == "PUT":
+		s.objects[r.URL.Path] = string(body)
+	default:
+		http.Error(w, "unexpected request", http.StatusBadRequest)
+	}
+}
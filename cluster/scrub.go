@@ -0,0 +1,268 @@
+package cluster
+
+import (
+	"fmt"
+	"hash/crc32"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/influxdata/influxdb/tsdb/engine/tsm1"
+	"github.com/uber-go/zap"
+	"github.com/zhexuany/influxcloud/rpc"
+)
+
+// CorruptShard is a local shard with a TSM file that did not match its
+// checksums when it was last scrubbed.
+type CorruptShard struct {
+	ID              uint64    `json:"id"`
+	Database        string    `json:"database"`
+	RetentionPolicy string    `json:"retentionPolicy"`
+	Path            string    `json:"path"`
+	Reason          string    `json:"reason"`
+	Detected        time.Time `json:"detected"`
+
+	// Repaired is true once the shard was replaced by a copy from Source,
+	// another owner. Err is why the last repair failed, if it was attempted.
+	Repaired bool   `json:"repaired"`
+	Source   string `json:"source,omitempty"`
+	Err      string `json:"error,omitempty"`
+}
+
+// tsmCorruptError is returned for a TSM file that cannot be read or does not
+// match its checksums.
+type tsmCorruptError struct {
+	path   string
+	reason string
+}
+
+// Error returns the file and why it is corrupt.
+func (e *tsmCorruptError) Error() string {
+	return fmt.Sprintf("%s: %s", e.path, e.reason)
+}
+
+// shardScrubber verifies the TSM files of the local shards every interval,
+// reading them no faster than limiter allows.
+type shardScrubber struct {
+	interval time.Duration
+	limiter  *rateLimiter
+	repair   bool
+
+	mu      sync.Mutex
+	corrupt map[uint64]*CorruptShard
+}
+
+// newShardScrubber returns shard scrubbing configured by c.
+func newShardScrubber(c Config) *shardScrubber {
+	return &shardScrubber{
+		interval: time.Duration(c.ShardScrubInterval),
+		limiter:  newRateLimiter(c.ShardScrubRateLimit),
+		repair:   c.ShardScrubRepair,
+		corrupt:  make(map[uint64]*CorruptShard),
+	}
+}
+
+// report returns the shards found corrupt, in order of ID.
+func (sc *shardScrubber) report() []CorruptShard {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+
+	a := make([]CorruptShard, 0, len(sc.corrupt))
+	for _, sh := range sc.corrupt {
+		a = append(a, *sh)
+	}
+	sort.Sort(corruptShards(a))
+	return a
+}
+
+// reason returns why shard id was found corrupt, or an empty string if it
+// was not or was repaired since.
+func (sc *shardScrubber) reason(id uint64) string {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	if sh := sc.corrupt[id]; sh != nil && !sh.Repaired {
+		return sh.Reason
+	}
+	return ""
+}
+
+// runShardScrub scrubs the local shards every interval until the service is
+// closed.
+func (s *Service) runShardScrub() {
+	defer s.wg.Done()
+
+	t := time.NewTicker(s.scrubber.interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+			if err := s.scrubShards(time.Now()); err != nil {
+				s.Logger.Info("shard scrub failed", zap.Error(err))
+			}
+		case <-s.closing:
+			return
+		}
+	}
+}
+
+// scrubShards verifies the TSM files of each local shard, recording the
+// shards found corrupt and replacing them with a copy from another owner if
+// repair is enabled. Shards that verify again, or are no longer stored on
+// this node, are no longer reported.
+func (s *Service) scrubShards(now time.Time) error {
+	if s.ShardStore == nil || s.Node == nil {
+		return nil
+	}
+
+	infos, err := s.shardInfos()
+	if err != nil {
+		return err
+	}
+	byID := make(map[uint64]rpc.ShardInfo, len(infos))
+	for _, si := range infos {
+		byID[si.ID] = si
+	}
+
+	local := make(map[uint64]bool)
+	for _, id := range s.ShardStore.ShardIDs() {
+		local[id] = true
+		sh := s.ShardStore.Shard(id)
+		if sh == nil {
+			continue
+		}
+
+		err := verifyShard(sh.Path(), s.scrubber.limiter, s.closing)
+		if err == errThrottleClosed {
+			return nil
+		}
+		cerr, ok := err.(*tsmCorruptError)
+		if err != nil && !ok {
+			s.Logger.Info("shard scrub failed", zap.Uint64("shardID", id), zap.Error(err))
+			continue
+		} else if !ok {
+			s.scrubber.mu.Lock()
+			delete(s.scrubber.corrupt, id)
+			s.scrubber.mu.Unlock()
+			continue
+		}
+
+		// Shards still corrupt since they were last scrubbed are only
+		// reported once.
+		c := CorruptShard{ID: id, Path: cerr.path, Reason: cerr.reason, Detected: now}
+		c.RetentionPolicy = filepath.Base(filepath.Dir(sh.Path()))
+		c.Database = filepath.Base(filepath.Dir(filepath.Dir(sh.Path())))
+		s.scrubber.mu.Lock()
+		prev := s.scrubber.corrupt[id]
+		detected := prev == nil || prev.Repaired
+		if !detected {
+			c.Detected = prev.Detected
+		}
+		s.scrubber.corrupt[id] = &c
+		s.scrubber.mu.Unlock()
+
+		if detected {
+			s.Logger.Error("corrupt shard", zap.Uint64("shardID", id), zap.String("database", c.Database), zap.String("retentionPolicy", c.RetentionPolicy), zap.Error(cerr))
+			s.Events.Publish(Event{Type: EventShardCorrupt, NodeID: s.Node.ID, ShardID: id, Message: cerr.Error()})
+		}
+
+		si, ok := byID[id]
+		if !s.scrubber.repair || !ok || !containsUint64(si.Owners, s.Node.ID) {
+			continue
+		}
+		c.Source, err = s.replaceShard(id, si)
+		s.scrubber.mu.Lock()
+		if err != nil {
+			c.Err = err.Error()
+		} else {
+			c.Repaired = true
+		}
+		s.scrubber.corrupt[id] = &c
+		s.scrubber.mu.Unlock()
+	}
+
+	s.scrubber.mu.Lock()
+	for id := range s.scrubber.corrupt {
+		if !local[id] {
+			delete(s.scrubber.corrupt, id)
+		}
+	}
+	s.scrubber.mu.Unlock()
+	return nil
+}
+
+// replaceShard deletes the local copy of shard id and copies it again from
+// the first of its other owners it can be copied from. The local copy is
+// kept if the shard has no other owner.
+func (s *Service) replaceShard(id uint64, si rpc.ShardInfo) (string, error) {
+	sources, err := s.shardSources(si.Owners)
+	if err != nil {
+		return "", err
+	} else if len(sources) == 0 {
+		return "", fmt.Errorf("no other owner of shard %d", id)
+	}
+
+	if err := s.TSDBStore.DeleteShard(id); err != nil {
+		return "", fmt.Errorf("delete shard %d: %s", id, err)
+	}
+	return s.copyFromSources(id, si.Database, si.Policy, sources)
+}
+
+// verifyShard verifies each TSM file of the shard at path. Files removed
+// by a compaction while the shard is verified are skipped.
+func verifyShard(path string, limiter *rateLimiter, closing <-chan struct{}) error {
+	files, err := filepath.Glob(filepath.Join(path, "*."+tsm1.TSMFileExtension))
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		if err := verifyTSMFile(file, limiter, closing); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// verifyTSMFile reads each block of the TSM file at path and checks it
+// against its checksum. A file whose index cannot be read is corrupt too.
+func verifyTSMFile(path string, limiter *rateLimiter, closing <-chan struct{}) (err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	// A corrupt index may make the reader panic rather than fail.
+	defer func() {
+		if r := recover(); r != nil {
+			err = &tsmCorruptError{path: path, reason: fmt.Sprintf("read panicked: %v", r)}
+		}
+	}()
+
+	r, err := tsm1.NewTSMReader(f)
+	if err != nil {
+		return &tsmCorruptError{path: path, reason: err.Error()}
+	}
+	defer r.Close()
+
+	itr := r.BlockIterator()
+	for itr.Next() {
+		key, minTime, _, _, checksum, buf, err := itr.Read()
+		if err != nil {
+			return &tsmCorruptError{path: path, reason: fmt.Sprintf("block of %s: %s", key, err)}
+		} else if crc32.ChecksumIEEE(buf) != checksum {
+			return &tsmCorruptError{path: path, reason: fmt.Sprintf("block of %s at %d: checksum mismatch", key, minTime)}
+		}
+		if err := limiter.wait(len(buf), closing); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type corruptShards []CorruptShard
+
+func (a corruptShards) Len() int           { return len(a) }
+func (a corruptShards) Less(i, j int) bool { return a[i].ID < a[j].ID }
+func (a corruptShards) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
@@ -0,0 +1,323 @@
+package cluster
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/influxdata/influxdb/influxql"
+)
+
+// Remote iterators over top() and bottom() calls return candidate points
+// rather than raw series: each node selects the top or bottom points of
+// every window and group of its own shards, keeping their times, and the
+// querying node selects the result among the candidates of all nodes with
+// NewSelectorIterator. A point in the result over all nodes is always within
+// the candidates of the node storing it, so the result is exact.
+//
+// percentile() has no such candidate set short of every point of a window,
+// so its remote iterators still stream raw points.
+
+// selectorCall returns the top() or bottom() call of opt, if any.
+func selectorCall(opt influxql.IteratorOptions) (*influxql.Call, bool) {
+	call, ok := opt.Expr.(*influxql.Call)
+	if !ok || (call.Name != "top" && call.Name != "bottom") {
+		return nil, false
+	}
+	return call, true
+}
+
+// selectorRawOptions returns the options of the raw iterator the candidates
+// of call are selected from. Raw points are merged by name, tags and window,
+// so that the points of each window and group are read together.
+func selectorRawOptions(call *influxql.Call, opt influxql.IteratorOptions) (influxql.IteratorOptions, error) {
+	if _, _, err := selectorArgs(call, opt); err != nil {
+		return opt, err
+	}
+	ref, ok := call.Args[0].(*influxql.VarRef)
+	if !ok {
+		return opt, fmt.Errorf("%s() requires a field as its first argument", call.Name)
+	}
+	rawOpt := opt
+	rawOpt.Expr = &influxql.VarRef{Val: ref.Val, Type: ref.Type}
+	rawOpt.Ordered = false
+	rawOpt.Limit, rawOpt.Offset = 0, 0
+	return rawOpt, nil
+}
+
+// NewSelectorIterator returns an iterator over the points selected by the
+// top() or bottom() call of opt among the candidate points of inputs, which
+// are the remote iterators of each node for the call.
+func NewSelectorIterator(inputs influxql.Iterators, opt influxql.IteratorOptions) (influxql.Iterator, error) {
+	call, ok := selectorCall(opt)
+	if !ok {
+		return nil, fmt.Errorf("not a selector call: %s", opt.Expr)
+	}
+
+	mergeOpt := opt
+	mergeOpt.Expr = call.Args[0]
+	mergeOpt.Ordered = false
+	itr := influxql.NewMergeIterator(inputs, mergeOpt)
+	if itr == nil {
+		return nil, nil
+	}
+	return newSelectorIterator(itr, call, opt, opt.Interval)
+}
+
+// newSelectorIterator returns an iterator over the points selected by call
+// from each window and group of input. The times of the selected points are
+// kept unless interval is set, in which case they are set to the start of
+// their window. input is closed if the call is invalid.
+func newSelectorIterator(input influxql.Iterator, call *influxql.Call, opt influxql.IteratorOptions, interval influxql.Interval) (influxql.Iterator, error) {
+	n, tags, err := selectorArgs(call, opt)
+	if err != nil {
+		input.Close()
+		return nil, err
+	}
+
+	switch input := input.(type) {
+	case influxql.FloatIterator:
+		fn := influxql.NewFloatTopReduceSliceFunc(n, tags, interval)
+		if call.Name == "bottom" {
+			fn = influxql.NewFloatBottomReduceSliceFunc(n, tags, interval)
+		}
+		return &floatSelectorIterator{input: input, opt: opt, fn: fn}, nil
+	case influxql.IntegerIterator:
+		fn := influxql.NewIntegerTopReduceSliceFunc(n, tags, interval)
+		if call.Name == "bottom" {
+			fn = influxql.NewIntegerBottomReduceSliceFunc(n, tags, interval)
+		}
+		return &integerSelectorIterator{input: input, opt: opt, fn: fn}, nil
+	default:
+		input.Close()
+		return nil, fmt.Errorf("unsupported %s iterator type: %T", call.Name, input)
+	}
+}
+
+// selectorArgs returns the number of points selected by call, and the
+// indexes in opt.Aux of the tags the points are selected by.
+func selectorArgs(call *influxql.Call, opt influxql.IteratorOptions) (int, []int, error) {
+	if len(call.Args) < 2 {
+		return 0, nil, fmt.Errorf("%s() requires 2 or more arguments, got %d", call.Name, len(call.Args))
+	}
+	n, ok := call.Args[len(call.Args)-1].(*influxql.IntegerLiteral)
+	if !ok {
+		return 0, nil, fmt.Errorf("expected integer as last argument in %s(), found %s", call.Name, call.Args[len(call.Args)-1])
+	}
+
+	// The tags the points are selected by are stored in the aux fields.
+	var tags []int
+	for _, arg := range call.Args[1 : len(call.Args)-1] {
+		ref, ok := arg.(*influxql.VarRef)
+		if !ok {
+			return 0, nil, fmt.Errorf("only fields or tags are allowed in %s(), found %s", call.Name, arg)
+		}
+		for i, aux := range opt.Aux {
+			if aux.Val == ref.Val {
+				tags = append(tags, i)
+				break
+			}
+		}
+	}
+	return int(n.Val), tags, nil
+}
+
+// groupID returns the identifier of the group of the points of measurement
+// name with the given dimension tags.
+func groupID(name string, tags influxql.Tags) string {
+	if id := tags.ID(); id != "" {
+		return name + "\x00" + id
+	}
+	return name
+}
+
+// floatSelectorIterator selects the points of each run of points of input
+// within the same window, grouped by name and dimensions.
+type floatSelectorIterator struct {
+	input  influxql.FloatIterator
+	opt    influxql.IteratorOptions
+	fn     influxql.FloatReduceSliceFunc
+	peek   *influxql.FloatPoint
+	points []influxql.FloatPoint
+}
+
+// Stats returns stats from the input iterator.
+func (itr *floatSelectorIterator) Stats() influxql.IteratorStats { return itr.input.Stats() }
+
+// Close closes the input iterator.
+func (itr *floatSelectorIterator) Close() error { return itr.input.Close() }
+
+// Next returns the next selected point.
+func (itr *floatSelectorIterator) Next() (*influxql.FloatPoint, error) {
+	for len(itr.points) == 0 {
+		points, ok, err := itr.reduce()
+		if err != nil || !ok {
+			return nil, err
+		}
+		itr.points = points
+	}
+	p := &itr.points[0]
+	itr.points = itr.points[1:]
+	return p, nil
+}
+
+// next returns the next non-nil point of input.
+func (itr *floatSelectorIterator) next() (*influxql.FloatPoint, error) {
+	if p := itr.peek; p != nil {
+		itr.peek = nil
+		return p, nil
+	}
+	for {
+		p, err := itr.input.Next()
+		if err != nil || p == nil {
+			return nil, err
+		} else if !p.Nil {
+			return p.Clone(), nil
+		}
+	}
+}
+
+// reduce selects the points of the next window. It returns false once input
+// has no points left.
+func (itr *floatSelectorIterator) reduce() ([]influxql.FloatPoint, bool, error) {
+	p, err := itr.next()
+	if err != nil || p == nil {
+		return nil, false, err
+	}
+	start, end := itr.opt.Window(p.Time)
+
+	type group struct {
+		name   string
+		tags   influxql.Tags
+		points []influxql.FloatPoint
+	}
+	m := make(map[string]*group)
+	var ids []string
+	for ; p != nil; p, err = itr.next() {
+		if p.Time < start || p.Time >= end {
+			itr.peek = p
+			break
+		}
+		tags := p.Tags.Subset(itr.opt.Dimensions)
+		id := groupID(p.Name, tags)
+		g := m[id]
+		if g == nil {
+			g = &group{name: p.Name, tags: tags}
+			m[id] = g
+			ids = append(ids, id)
+		}
+		g.points = append(g.points, *p)
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	sort.Strings(ids)
+	var a []influxql.FloatPoint
+	for _, id := range ids {
+		g := m[id]
+		for _, p := range itr.fn(g.points) {
+			p.Name, p.Tags = g.name, g.tags
+			if p.Time == influxql.ZeroTime {
+				p.Time = start
+			}
+			a = append(a, p)
+		}
+	}
+	return a, true, nil
+}
+
+// integerSelectorIterator selects the points of each run of points of input
+// within the same window, grouped by name and dimensions.
+type integerSelectorIterator struct {
+	input  influxql.IntegerIterator
+	opt    influxql.IteratorOptions
+	fn     influxql.IntegerReduceSliceFunc
+	peek   *influxql.IntegerPoint
+	points []influxql.IntegerPoint
+}
+
+// Stats returns stats from the input iterator.
+func (itr *integerSelectorIterator) Stats() influxql.IteratorStats { return itr.input.Stats() }
+
+// Close closes the input iterator.
+func (itr *integerSelectorIterator) Close() error { return itr.input.Close() }
+
+// Next returns the next selected point.
+func (itr *integerSelectorIterator) Next() (*influxql.IntegerPoint, error) {
+	for len(itr.points) == 0 {
+		points, ok, err := itr.reduce()
+		if err != nil || !ok {
+			return nil, err
+		}
+		itr.points = points
+	}
+	p := &itr.points[0]
+	itr.points = itr.points[1:]
+	return p, nil
+}
+
+// next returns the next non-nil point of input.
+func (itr *integerSelectorIterator) next() (*influxql.IntegerPoint, error) {
+	if p := itr.peek; p != nil {
+		itr.peek = nil
+		return p, nil
+	}
+	for {
+		p, err := itr.input.Next()
+		if err != nil || p == nil {
+			return nil, err
+		} else if !p.Nil {
+			return p.Clone(), nil
+		}
+	}
+}
+
+// reduce selects the points of the next window. It returns false once input
+// has no points left.
+func (itr *integerSelectorIterator) reduce() ([]influxql.IntegerPoint, bool, error) {
+	p, err := itr.next()
+	if err != nil || p == nil {
+		return nil, false, err
+	}
+	start, end := itr.opt.Window(p.Time)
+
+	type group struct {
+		name   string
+		tags   influxql.Tags
+		points []influxql.IntegerPoint
+	}
+	m := make(map[string]*group)
+	var ids []string
+	for ; p != nil; p, err = itr.next() {
+		if p.Time < start || p.Time >= end {
+			itr.peek = p
+			break
+		}
+		tags := p.Tags.Subset(itr.opt.Dimensions)
+		id := groupID(p.Name, tags)
+		g := m[id]
+		if g == nil {
+			g = &group{name: p.Name, tags: tags}
+			m[id] = g
+			ids = append(ids, id)
+		}
+		g.points = append(g.points, *p)
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	sort.Strings(ids)
+	var a []influxql.IntegerPoint
+	for _, id := range ids {
+		g := m[id]
+		for _, p := range itr.fn(g.points) {
+			p.Name, p.Tags = g.name, g.tags
+			if p.Time == influxql.ZeroTime {
+				p.Time = start
+			}
+			a = append(a, p)
+		}
+	}
+	return a, true, nil
+}
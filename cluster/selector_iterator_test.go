@@ -0,0 +1,108 @@
+package cluster_test
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/influxdata/influxdb/influxql"
+	"github.com/influxdata/influxdb/models"
+	"github.com/zhexuany/influxcloud/cluster"
+)
+
+// Ensure top() and bottom() select the same points from the candidates of
+// each node as from all of their points, whether or not the nodes select
+// the candidates.
+func TestNewSelectorIterator(t *testing.T) {
+	// Each node stores the cpu points of one host.
+	var clients []*cluster.RemoteIteratorClient
+	for i, values := range [][]float64{{1, 5, 3, 7}, {6, 2, 8, 4}} {
+		id := uint64(10 + i)
+		store := MustOpenStore()
+		defer store.Close()
+		if err := store.CreateShard("db0", "rp0", id, true); err != nil {
+			t.Fatal(err)
+		}
+		var points []models.Point
+		for j, v := range values {
+			points = append(points, models.MustNewPoint("cpu", models.NewTags(map[string]string{"host": fmt.Sprintf("server%d", i)}), map[string]interface{}{"value": v}, time.Unix(int64(j), 0)))
+		}
+		if err := store.WriteToShard(id, points); err != nil {
+			t.Fatal(err)
+		}
+
+		s := MustOpenIteratorService(cluster.Config{}, store)
+		defer s.Close()
+		c := cluster.NewRemoteIteratorClient(time.Second)
+		c.MetaClient = &metaClient{host: s.Addr().String()}
+		clients = append(clients, c)
+	}
+
+	for _, tt := range []struct {
+		name     string
+		n        int64
+		interval time.Duration
+		exp      []string
+	}{
+		{name: "top", n: 3, exp: []string{"0 6", "2 8", "3 7"}},
+		{name: "bottom", n: 3, exp: []string{"0 1", "1 2", "2 3"}},
+		{name: "top", n: 2, interval: 2 * time.Second, exp: []string{"0 6", "0 5", "2 8", "2 7"}},
+	} {
+		for _, disabled := range []bool{false, true} {
+			opt := newIteratorOptions()
+			opt.Expr = &influxql.Call{Name: tt.name, Args: []influxql.Expr{
+				&influxql.VarRef{Val: "value", Type: influxql.Float},
+				&influxql.IntegerLiteral{Val: tt.n},
+			}}
+			opt.Interval = influxql.Interval{Duration: tt.interval}
+
+			var inputs influxql.Iterators
+			for i, c := range clients {
+				c.DisableAggregatePushdown = disabled
+				itr, err := c.CreateIterator(1, []uint64{uint64(10 + i)}, influxql.Float, opt)
+				if err != nil {
+					t.Fatal(err)
+				}
+				inputs = append(inputs, itr)
+			}
+			itr, err := cluster.NewSelectorIterator(inputs, opt)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var got []string
+			for {
+				p, err := itr.(influxql.FloatIterator).Next()
+				if err != nil {
+					t.Fatal(err)
+				} else if p == nil {
+					break
+				}
+				got = append(got, fmt.Sprintf("%d %v", p.Time/int64(time.Second), p.Value))
+			}
+			itr.Close()
+
+			if !reflect.DeepEqual(got, tt.exp) {
+				t.Fatalf("unexpected %s(%s) points (pushdown disabled=%v): %v, expected %v", tt.name, tt.interval, disabled, got, tt.exp)
+			}
+		}
+	}
+
+	// A node only streams its candidates.
+	clients[0].DisableAggregatePushdown = false
+	opt := newIteratorOptions()
+	opt.Expr = &influxql.Call{Name: "top", Args: []influxql.Expr{&influxql.VarRef{Val: "value", Type: influxql.Float}, &influxql.IntegerLiteral{Val: 1}}}
+	itr, err := clients[0].CreateIterator(1, []uint64{10}, influxql.Float, opt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer itr.Close()
+	if p, err := itr.(influxql.FloatIterator).Next(); err != nil {
+		t.Fatal(err)
+	} else if p == nil || p.Value != 7 || p.Time != int64(3*time.Second) {
+		t.Fatalf("unexpected candidate: %v", p)
+	} else if p, err := itr.(influxql.FloatIterator).Next(); err != nil || p != nil {
+		t.Fatalf("unexpected candidate: %v, %v", p, err)
+	}
+}
@@ -1,27 +1,47 @@
 package cluster
 
 import (
+	"bufio"
+	"crypto/tls"
+	"errors"
 	"expvar"
+	"fmt"
+	"io"
 	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
 	"strings"
 	"sync"
-	"fmt"
+	"time"
 
 	"github.com/influxdata/influxdb/coordinator"
 	"github.com/influxdata/influxdb/influxql"
+	"github.com/influxdata/influxdb/models"
 	"github.com/influxdata/influxdb/services/meta"
 	"github.com/influxdata/influxdb/tsdb"
+	"github.com/uber-go/zap"
+	"github.com/zhexuany/influxcloud"
+	cloudMeta "github.com/zhexuany/influxcloud/meta"
 	"github.com/zhexuany/influxcloud/rpc"
 	"github.com/zhexuany/influxcloud/tlv"
-	"github.com/uber-go/zap"
 )
 
-// MaxMessageSize defines how large a message can be before we reject it
-const MaxMessageSize = 1024 * 1024 * 1024 // 1GB
-
 // MuxHeader is the header byte used in the TCP mux.
 const MuxHeader = 2
 
+var (
+	// ErrClusterIDMismatch is returned when importing meta data exported by
+	// a node of another cluster.
+	ErrClusterIDMismatch = errors.New("cluster id mismatch")
+
+	// ErrStaleMetaData is returned when importing meta data that is older
+	// than the local meta data.
+	ErrStaleMetaData = errors.New("meta data older than local meta data")
+)
+
 // Service reprsents a cluster service
 type Service struct {
 	mu sync.RWMutex
@@ -31,47 +51,524 @@ type Service struct {
 
 	Listener net.Listener
 
-	MetaClient interface {
-		ShardOwner(shardID uint64) (string, string, meta.ShardInfo)
+	// HTTP status listener, only opened if enabled in the config.
+	httpAddr     string
+	httpListener net.Listener
+
+	// Open inbound connections, and the time they were accepted.
+	conns map[net.Conn]time.Time
+
+	// Handlers of the requests received, keyed by message type, and the
+	// number of requests on a connection, or on a multiplexed one, processed
+	// concurrently.
+	handlers           map[byte]registeredHandler
+	maxConnRequests    int
+	maxMuxConnRequests int
+
+	// The largest request accepted from other nodes.
+	maxMessageSize int64
+
+	// Set once Drain is called; active tracks inbound writes and statements
+	// still being processed.
+	draining     bool
+	active       sync.WaitGroup
+	drainTimeout time.Duration
+
+	// Set while serve accepts connections from other nodes.
+	accepting bool
+
+	// Set while the writes other nodes send are rejected.
+	readOnly bool
+
+	// The hinted handoff backlog above which the node is not ready.
+	readyMaxHHBacklog int64
+
+	// Batches inbound writes to the same shard if the window is non-zero.
+	coalesceWindow time.Duration
+	coalescer      *writeCoalescer
+
+	// Limits on the iterators other nodes open on this node. iterators
+	// holds a token for each open iterator and is nil if unlimited.
+	iterators          chan struct{}
+	maxIteratorBytes   int64
+	maxIteratorSeriesN int
+
+	// Time a querying node may stop reading an iterator stream for before
+	// the stream is aborted, or zero if unlimited.
+	iteratorStallTimeout time.Duration
+
+	// Leases granted to data nodes, e.g. to run continuous queries.
+	leases *meta.Leases
+
+	// Snapshots taken of local shards, the bases of incremental snapshots.
+	snapshots *shardSnapshots
+
+	// Limits on the rate shards are copied or restored to this node at, per
+	// copy and for all copies at once. copyLimiter is nil if unlimited.
+	copyRateLimit int64
+	copyLimiter   *rateLimiter
+
+	// Thresholds on the load of the local store above which shard writes
+	// are rejected as overloaded, and the last sample of the load.
+	loadLimits loadLimits
+	loadMu     sync.Mutex
+	load       rpc.NodeLoad
+	loadAt     time.Time
+
+	// Limits on the writes and iterators of each database, so that one
+	// database cannot monopolize the node.
+	quotas *databaseQuotas
+
+	// How the writes of each database are handled when some of their points
+	// are rejected.
+	partialWrites partialWritePolicies
+
+	// The hinted handoff writes other nodes replayed to this node, so that
+	// writes replayed again are skipped.
+	handoffs *handoffSequences
+
+	// Local shards no longer assigned to this node in the meta store.
+	orphans *orphanShards
+
+	// Shards assigned to this node in the meta store but missing locally,
+	// as found when the service opened.
+	startupCheck startupShardCheck
+
+	// Local shards whose TSM files did not match their checksums.
+	scrubber *shardScrubber
+
+	// The data nodes and shards last seen in the meta store, and the sink
+	// posting events to a webhook and the alerter, if configured.
+	nodes   nodeWatch
+	webhook *WebhookSink
+	alerter *Alerter
+
+	// The file destructive operations are audited to, if configured.
+	auditPath string
+	auditFile *os.File
+
+	// The tokens authorizing the RPCs that mutate the cluster.
+	adminTokens *adminTokens
+
+	Node *influxcloud.Node
+
+	// Version is the build version of this node, exchanged with other nodes
+	// when they connect so that version skew during upgrades is logged.
+	Version string
+
+	MetaClient MetaClient
+
+	// MetaStore exports the meta store snapshot to joining nodes, and
+	// imports a snapshot exported by another node.
+	MetaStore interface {
+		Data() *cloudMeta.Data
+		SetData(data *cloudMeta.Data) error
 	}
 
 	TSDBStore coordinator.TSDBStore
 
+	// ShardStore reports the state of the shards stored on this node.
+	ShardStore interface {
+		ShardIDs() []uint64
+		Shard(id uint64) *tsdb.Shard
+	}
+
 	ShardIteratorCreator coordinator.ShardIteratorCreator
 
+	// SnapshotSink stores the shard snapshots this node is asked to upload.
+	// Uploads are rejected if nil.
+	SnapshotSink SnapshotSink
+
 	Logger      zap.Logger
 	ShardWriter ShardWriter
 
+	// AuditLogger records the destructive operations other nodes request,
+	// separately from Logger. It writes to the audit log file if one is
+	// configured, and discards the records otherwise.
+	AuditLogger zap.Logger
+
+	// PointsWriter writes whole writes forwarded by other nodes. Forwarded
+	// writes are rejected as unsupported if nil.
+	PointsWriter interface {
+		WriteForwardedPoints(requestID string, deadline time.Time, database, retentionPolicy string, consistencyLevel models.ConsistencyLevel, points []models.Point) error
+	}
+
+	// Metrics is served in the Prometheus format on the /metrics endpoint.
+	// The statistics of the PointsWriter and HintedHandoff, and the sizes of
+	// the hinted handoff queues, are registered with it on open. Embedding
+	// servers may register other sources, e.g. a coordinator PointsWriter.
+	Metrics *Metrics
+
+	// Events publishes the changes of the cluster state seen by this node,
+	// so that operators and embedding servers can react to them. Other
+	// components may publish on it too, e.g. the PointsWriter and the
+	// hinted handoff service by its OnQueueFull hook. Alerts are raised
+	// from its events if alert webhooks are configured.
+	Events *EventBus
+
+	// Replicators send writes from this node to other nodes, e.g. the
+	// PointsWriter and the hinted handoff service. They are paused and
+	// resumed together for a target node.
+	Replicators []Replicator
+
+	// Drainers finish their outstanding work when the service is drained,
+	// in order. The PointsWriter should come before the hinted handoff
+	// service so that writes it queues are flushed as well.
+	Drainers []Drainer
+
+	// Reloaders apply the tunables of a reloaded configuration while the
+	// node runs, e.g. the PointsWriter and the ShardWriter.
+	Reloaders []Reloader
+
+	// SpanTracer creates a span for each request received. If the sender
+	// propagated a span context, the request's span is its child.
+	SpanTracer SpanTracer
+
+	// Writes reports in-flight shard writes and the slowest traced writes on
+	// the /debug/writes and /debug/traces endpoints if set.
+	Writes interface {
+		InflightWrites(threshold time.Duration) []InflightWrite
+		WriteTraces() []WriteTrace
+	}
+
+	// HintedHandoff is reported on the /hh endpoint and exported on the
+	// /metrics endpoint if set. Its queues are redirected to the node
+	// replacing the node they are for.
+	HintedHandoff interface {
+		QueueSizes() map[uint64]int64
+		RedirectQueue(from, to uint64) error
+	}
+
 	statMap *expvar.Map
+
+	// Timeout of the dials to other nodes, and the dialer connecting to
+	// them.
+	dialTimeout time.Duration
+	dialer      *Dialer
+
+	// tls secures the listener and the dialer if enabled.
+	tls TLSConfig
+}
+
+// Replicator sends writes to other nodes and can be paused per target node.
+type Replicator interface {
+	PauseReplication(nodeID uint64)
+	ResumeReplication(nodeID uint64)
+}
+
+// Drainer finishes outstanding work before the node is shut down.
+type Drainer interface {
+	Drain() error
+}
+
+// Reloader applies the tunables of a reloaded configuration.
+type Reloader interface {
+	Reload(c Config) error
 }
 
 // NewService returns a new instance of Service.
 func NewService(c Config) *Service {
-	return &Service{
-		closing: make(chan struct{}),
-		Logger:  zap.New(zap.NullEncoder()),
+	s := &Service{
+		closing:     make(chan struct{}),
+		conns:       make(map[net.Conn]time.Time),
+		snapshots:   newShardSnapshots(),
+		Metrics:     NewMetrics(),
+		Events:      NewEventBus(),
+		Logger:      zap.New(zap.NullEncoder()),
+		AuditLogger: zap.New(zap.NullEncoder()),
+		dialTimeout: time.Duration(c.DialTimeout),
+		dialer:      &Dialer{Resolver: NewResolver(time.Duration(c.DNSTTL))},
+		tls:         c.TLS,
+
+		drainTimeout: time.Duration(c.DrainTimeout),
+		readOnly:     c.ReadOnly,
+
+		coalesceWindow: time.Duration(c.WriteCoalesceWindow),
+
+		maxIteratorBytes:   c.MaxRemoteQueryBytes,
+		maxIteratorSeriesN: c.MaxRemoteSeriesN,
+
+		iteratorStallTimeout: time.Duration(c.IteratorStallTimeout),
+
+		copyRateLimit: c.ShardCopyRateLimit,
+		copyLimiter:   newRateLimiter(c.ShardCopyNodeRateLimit),
+
+		quotas:        newDatabaseQuotas(c),
+		partialWrites: newPartialWritePolicies(c),
+		orphans:       newOrphanShards(c),
+		startupCheck:  startupShardCheck{enabled: c.StartupShardCheck, repair: c.StartupShardRepair},
+		scrubber:      newShardScrubber(c),
+		nodes:         nodeWatch{interval: time.Duration(c.NodeCheckInterval)},
+		auditPath:     c.AuditLogPath,
+		adminTokens:   newAdminTokens(c),
+		handoffs:      newHandoffSequences(),
+
+		readyMaxHHBacklog: int64(c.ReadyMaxHHBacklog),
+
+		handlers:           make(map[byte]registeredHandler),
+		maxConnRequests:    c.MaxConnectionRequests,
+		maxMuxConnRequests: c.MaxMuxConnectionRequests,
+
+		loadLimits: loadLimits{
+			walBytes:       int64(c.MaxWALBacklog),
+			cacheBytes:     int64(c.MaxCacheSize),
+			compactionDebt: int64(c.MaxCompactionDebt),
+		},
+	}
+	s.registerHandlers()
+	s.dialer.Hello = s.helloRequest
+	s.dialer.AdminToken = s.adminTokens.admin
+	if c.EventWebhookURL != "" {
+		s.webhook = NewWebhookSink(c.EventWebhookURL, time.Duration(c.EventWebhookTimeout))
+	}
+	if len(c.AlertWebhookURLs) > 0 {
+		s.alerter = NewAlerter(c)
+	}
+	if c.MaxMessageSize > 0 && c.MaxMessageSize < tlv.MaxMessageSize {
+		s.maxMessageSize = int64(c.MaxMessageSize)
+	} else {
+		s.maxMessageSize = tlv.MaxMessageSize
+	}
+	if c.LeaseDuration > 0 {
+		s.leases = meta.NewLeases(time.Duration(c.LeaseDuration))
+	} else {
+		s.leases = meta.NewLeases(DefaultLeaseDuration)
+	}
+	if c.MaxConcurrentRemoteIterators > 0 {
+		s.iterators = make(chan struct{}, c.MaxConcurrentRemoteIterators)
+	}
+	if c.HTTPEnabled {
+		s.httpAddr = c.HTTPBindAddress
+	}
+	if c.SnapshotS3.Bucket != "" {
+		s.SnapshotSink = NewS3SnapshotSink(c.SnapshotS3)
 	}
+	return s
 }
 
 // Open opens the network listener and begins serving requests
 func (s *Service) Open() error {
 	s.Logger.Info("Starting cluster service")
+
+	if s.auditPath != "" {
+		if err := s.openAuditLog(s.auditPath); err != nil {
+			return err
+		}
+	}
+
+	if s.tls.Enabled {
+		server, err := s.tls.ServerConfig()
+		if err != nil {
+			return err
+		}
+		client, err := s.tls.ClientConfig()
+		if err != nil {
+			return err
+		}
+		s.Listener = tls.NewListener(s.Listener, server)
+		s.dialer.TLS = client
+	}
+
+	if src, ok := s.PointsWriter.(StatisticsSource); ok {
+		s.Metrics.Register(src)
+	}
+	if s.HintedHandoff != nil {
+		s.Metrics.RegisterQueues(s.HintedHandoff)
+		if src, ok := s.HintedHandoff.(StatisticsSource); ok {
+			s.Metrics.Register(src)
+		}
+	}
+
+	if s.coalesceWindow > 0 {
+		s.coalescer = newWriteCoalescer(s.coalesceWindow, s.TSDBStore.WriteToShard)
+	}
+
+	if s.httpAddr != "" {
+		ln, err := net.Listen("tcp", s.httpAddr)
+		if err != nil {
+			return err
+		}
+		s.httpListener = ln
+		s.Logger.Info("Listening on HTTP", zap.Stringer("addr", ln.Addr()))
+
+		s.wg.Add(1)
+		go s.serveHTTP()
+	}
+
 	s.wg.Add(1)
 	go s.serve()
 
+	if s.orphans.interval > 0 {
+		s.wg.Add(1)
+		go s.runOrphanShardCheck()
+	}
+
+	if s.startupCheck.enabled {
+		s.wg.Add(1)
+		go s.runStartupShardCheck()
+	}
+
+	if s.scrubber.interval > 0 {
+		s.wg.Add(1)
+		go s.runShardScrub()
+	}
+
+	if s.webhook != nil {
+		s.webhook.Logger = s.Logger
+		s.webhook.Open(s.Events)
+	}
+	if s.alerter != nil {
+		s.alerter.Logger = s.Logger
+		if s.HintedHandoff != nil {
+			s.alerter.HintedHandoff = s.HintedHandoff
+		}
+		s.alerter.Open(s.Events)
+	}
+	if s.nodes.interval > 0 {
+		s.wg.Add(1)
+		go s.runNodeCheck()
+	}
+
+	return nil
+}
+
+// Drain prepares the node to be shut down without dropping writes. New
+// inbound writes and statements are rejected so that peers queue them in
+// hinted handoff, and every peer is asked to pause replication to this node.
+// Drain then waits for in-flight requests to finish and drains each of the
+// Drainers.
+//
+// Peers keep queueing writes for this node until replication is resumed,
+// e.g. with "influxcloud-ctl resume-replication", once it has restarted.
+func (s *Service) Drain() error {
+	s.mu.Lock()
+	s.draining = true
+	s.mu.Unlock()
+	s.Logger.Info("draining cluster service")
+
+	if err := s.pausePeers(); err != nil {
+		s.Logger.Warn("unable to pause replication on peers", zap.Error(err))
+	}
+
+	done := make(chan struct{})
+	go func() {
+		s.active.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(s.drainTimeout):
+		return ErrTimeout
+	}
+
+	for _, d := range s.Drainers {
+		if err := d.Drain(); err != nil {
+			return err
+		}
+	}
+
+	s.Logger.Info("cluster service drained")
+	return nil
+}
+
+// startRequest registers an inbound write or statement. It returns false if
+// the service is draining and the request must be rejected.
+func (s *Service) startRequest() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.draining {
+		return false
+	}
+	s.active.Add(1)
+	return true
+}
+
+// pausePeers asks every other data node to pause replication to this node.
+func (s *Service) pausePeers() error {
+	if s.Node == nil {
+		return fmt.Errorf("node not set")
+	}
+
+	nodes, err := s.MetaClient.DataNodes()
+	if err != nil {
+		return err
+	}
+
+	var tcpHost string
+	for _, n := range nodes {
+		if n.ID == s.Node.ID {
+			tcpHost = n.TCPHost
+		}
+	}
+	if tcpHost == "" {
+		return fmt.Errorf("data node not found: %d", s.Node.ID)
+	}
+
+	for _, n := range nodes {
+		if n.ID == s.Node.ID {
+			continue
+		}
+		if err := s.pauseReplication(n.TCPHost, tcpHost); err != nil {
+			s.Logger.Warn("unable to pause replication", zap.Uint64("nodeID", n.ID), zap.Error(err))
+		}
+	}
+	return nil
+}
+
+// pauseReplication asks the node at addr to pause replication to tcpHost.
+func (s *Service) pauseReplication(addr, tcpHost string) error {
+	var resp rpc.PauseReplicationResponse
+	if err := s.dialer.dialRPC(addr, s.dialTimeout, tlv.PauseReplicationRequestMessage, &rpc.PauseReplicationRequest{
+		TCPHost: tcpHost,
+	}, &resp); err != nil {
+		return err
+	} else if resp.Err != "" {
+		return errors.New(resp.Err)
+	}
 	return nil
 }
 
+// HTTPAddr returns the address of the status HTTP listener, or nil if disabled.
+func (s *Service) HTTPAddr() net.Addr {
+	if s.httpListener == nil {
+		return nil
+	}
+	return s.httpListener.Addr()
+}
+
+// serveHTTP serves the status endpoints until the HTTP listener is closed.
+func (s *Service) serveHTTP() {
+	defer s.wg.Done()
+
+	err := http.Serve(s.httpListener, newHandler(s))
+	select {
+	case <-s.closing:
+	default:
+		s.Logger.Info("cluster http service error", zap.Error(err))
+	}
+}
+
 // WithLogger sets the internal logger to the logger passed in
 func (s *Service) WithLogger(log zap.Logger) {
 	s.Logger = log.With(zap.String("service", "cluster"))
 }
 
+// Dialer returns the Dialer the service connects to other nodes with, which
+// secures connections under the TLS configuration once opened, says hello
+// and sends the node's admin token. Clients sending admin RPCs on behalf of
+// the node, e.g. a MetaExecutor or a BackupCoordinator, should dial with it.
+func (s *Service) Dialer() *Dialer {
+	return s.dialer
+}
+
 // serve accepts connections from the listener and handles them
 
 func (s *Service) serve() {
 	defer s.wg.Done()
 
+	s.setAccepting(true)
+	defer s.setAccepting(false)
+
 	for {
 		// Check if the service is shutting down
 		select {
@@ -84,21 +581,43 @@ func (s *Service) serve() {
 		conn, err := s.Listener.Accept()
 		if err != nil {
 			if strings.Contains(err.Error(), "connection closed") {
-				s.Logger.Info(fmt.Sprint("cluster service accept error:", err))
+				s.Logger.Info("cluster service accept error", zap.Error(err))
 				return
 			}
-			s.Logger.Info(fmt.Sprint("accept error:", err))
+			s.Logger.Info("accept error", zap.Error(err))
 			continue
 		}
 
 		s.wg.Add(1)
 		go func() {
 			defer s.wg.Done()
+			if err := s.handshake(conn); err != nil {
+				s.Logger.Warn("tls handshake failed", zap.Stringer("peer", conn.RemoteAddr()), zap.Error(err))
+				conn.Close()
+				return
+			} else if err := s.identifyPeer(conn); err != nil {
+				s.Logger.Warn("peer identity refused", zap.Stringer("peer", conn.RemoteAddr()), zap.Error(err))
+				conn.Close()
+				return
+			}
 			s.handleConn(conn)
 		}()
 	}
 }
 
+// handshake completes the TLS handshake of conn within the dial timeout, if
+// it is a TLS connection, so that connections violating the TLS policy are
+// refused before any message is read.
+func (s *Service) handshake(conn net.Conn) error {
+	tlsConn, ok := conn.(*tls.Conn)
+	if !ok {
+		return nil
+	}
+	tlsConn.SetDeadline(time.Now().Add(s.dialTimeout))
+	defer tlsConn.SetDeadline(time.Time{})
+	return tlsConn.Handshake()
+}
+
 // Close close this service
 func (s *Service) Close() error {
 	if s.Listener != nil {
@@ -106,8 +625,22 @@ func (s *Service) Close() error {
 	}
 
 	close(s.closing)
+
+	if s.httpListener != nil {
+		s.httpListener.Close()
+	}
 	s.wg.Wait()
 
+	if s.webhook != nil {
+		s.webhook.Close()
+	}
+	if s.alerter != nil {
+		s.alerter.Close()
+	}
+	if s.auditFile != nil {
+		s.auditFile.Close()
+	}
+
 	return nil
 }
 
@@ -123,10 +656,52 @@ func (s *Service) handleConn(conn net.Conn) {
 		conn.Close()
 	}()
 
-	s.Logger.Info(fmt.Sprint("accept remote connection from", conn.RemoteAddr()))
+	log := s.Logger.With(zap.Stringer("peer", conn.RemoteAddr()))
+	log.Info("accepted remote connection")
+	s.trackConn(conn)
+	defer func() {
+		s.untrackConn(conn)
+		log.Info("closed remote connection")
+	}()
+
+	// The span of the request being processed. Requests that take over the
+	// connection return from handleConn, so the span is finished on return.
+	var span Span
 	defer func() {
-		s.Logger.Info(fmt.Sprint("close remote connection from", conn.RemoteAddr()))
+		if span != nil {
+			span.Finish()
+		}
 	}()
+
+	// The span context sent ahead of the next request, if any.
+	var carrier map[string]string
+
+	// The features negotiated with the remote node, if it said hello.
+	var features rpc.Feature
+
+	// The admin token sent on the connection, if any.
+	var token string
+
+	// Whether the remote node said hello, which it must do before anything
+	// else once the cluster of this node is known.
+	var helloed bool
+
+	// Requests are processed concurrently if more than one is allowed, and
+	// one at a time otherwise.
+	p := newPipeline(conn, s.maxConnRequests, log)
+	defer p.close()
+
+	// refuseFrame answers a frame larger than the maximum message size with
+	// rpc.CodeFrameTooLarge, once the requests before it are answered. The
+	// frame is left unread, so the connection is closed after.
+	refuseFrame := func(err error) {
+		if e, ok := err.(*tlv.FrameTooLargeError); ok {
+			p.flush()
+			if err := writeErrorResponse(conn, rpc.CodeFrameTooLarge, e); err != nil {
+				log.Warn("unable to write error response", zap.Error(err))
+			}
+		}
+	}
 	for {
 		// Read type-length-value.
 		typ, err := tlv.ReadType(conn)
@@ -134,234 +709,1526 @@ func (s *Service) handleConn(conn net.Conn) {
 			if strings.HasSuffix(err.Error(), "EOF") {
 				return
 			}
-			s.Logger.Warn("unable to read type:" + err.Error())
+			log.Warn("unable to read message type", zap.Error(err))
 			return
 		}
 
-		// Delegate message processing by type.
-		switch typ {
-		case tlv.WriteShardRequestMessage:
-			buf, err := tlv.ReadLV(conn)
-			if err != nil {
-				s.Logger.Warn("unable to read length-value: " + err.Error())
+		if typ == tlv.SpanContextMessage {
+			var sc rpc.SpanContext
+			if err := tlv.DecodeLVMax(conn, &sc, s.maxMessageSize); err != nil {
+				log.Warn("unable to read span context", zap.Error(err))
+				refuseFrame(err)
 				return
 			}
-
-			err = s.processWriteShardRequest(buf)
-			if err != nil {
-				s.Logger.Warn("process write shard error: " + err.Error())
-			}
-			s.writeShardResponse(conn, err)
-		case tlv.ExecuteStatementRequestMessage:
-			buf, err := tlv.ReadLV(conn)
-			if err != nil {
-				s.Logger.Warn("unable to read length-value: " + err.Error())
+			carrier = sc.Carrier
+			continue
+		} else if typ == tlv.AdminTokenMessage {
+			var t rpc.AdminToken
+			if err := tlv.DecodeLVMax(conn, &t, s.maxMessageSize); err != nil {
+				log.Warn("unable to read admin token", zap.Error(err))
+				refuseFrame(err)
 				return
 			}
+			token = t.Token
+			continue
+		}
 
-			err = s.processExecuteStatementRequest(buf)
-			if err != nil {
-				s.Logger.Warn("process execute statement error:" + err.Error())
+		if !helloed && typ != tlv.HelloRequestMessage {
+			if clusterID := clusterIDOf(s.MetaClient); clusterID != 0 {
+				err := fmt.Errorf("hello required by cluster %d before %s", clusterID, rpcName(typ))
+				log.Warn("rejecting request before hello", zap.String("type", rpcName(typ)), zap.Error(err))
+				p.flush()
+				if err := writeErrorResponse(conn, rpc.CodeAuthFailed, err); err != nil {
+					log.Warn("unable to write error response", zap.String("type", rpcName(typ)), zap.Error(err))
+				}
+				return
 			}
-			s.writeShardResponse(conn, err)
-		case tlv.CreateIteratorRequestMessage:
-			s.processCreateIteratorRequest(conn)
-			return
-		case tlv.FieldDimensionsRequestMessage:
-			s.processFieldDimensionsRequest(conn)
-			return
-		// case seriesKeysRequestMessage:
-		// s.processSeriesKeysRequest(conn)
-		// return
-		default:
-			s.Logger.Warn("cluster service message type not found:" + string(typ))
 		}
-	}
-
-}
-
-func (s *Service) executeStatement(stmt influxql.Statement, database string) error {
-	switch t := stmt.(type) {
-	case *influxql.DropDatabaseStatement:
-		return s.TSDBStore.DeleteDatabase(t.Name)
-	case *influxql.DropMeasurementStatement:
-		return s.TSDBStore.DeleteMeasurement(database, t.Name)
-	case *influxql.DropSeriesStatement:
-		return s.TSDBStore.DeleteSeries(database, t.Sources, t.Condition)
-	case *influxql.DropRetentionPolicyStatement:
-		return s.TSDBStore.DeleteRetentionPolicy(database, t.Name)
-	default:
-		return fmt.Errorf("%q should not be executed across a cluster", stmt.String())
-	}
-}
 
-func (s *Service) processWriteShardRequest(buf []byte) error {
-	// Build request
-	var req rpc.WriteShardRequest
-	if err := req.UnmarshalBinary(buf); err != nil {
-		return err
-	}
+		h, ok := s.handler(typ)
+		if ok {
+			// Unauthorized requests are left unread, so the connection is
+			// closed once the error is sent.
+			if err := s.adminTokens.authorize(typ, token); err != nil {
+				log.Warn("rejecting unauthorized request", zap.String("type", h.name), zap.Error(err))
+				s.audit(h.name, conn.RemoteAddr(), 0, err)
+				p.flush()
+				if err := writeErrorResponse(conn, rpc.CodeAuthFailed, err); err != nil {
+					log.Warn("unable to write error response", zap.String("type", h.name), zap.Error(err))
+				}
+				return
+			}
+		}
+		if ok && !h.serial {
+			buf, err := readRequest(conn, s.maxMessageSize)
+			if e, ok := err.(*tlv.FrameTooLargeError); ok {
+				// The request is left unread, so the connection is closed
+				// once the error is sent.
+				log.Warn("rejecting request larger than the maximum message size", zap.String("type", h.name), zap.Int64("size", e.Size), zap.Int64("max", e.Max))
+				p.flush()
+				if err := writeErrorResponse(conn, rpc.CodeFrameTooLarge, e); err != nil {
+					log.Warn("unable to write error response", zap.String("type", h.name), zap.Error(err))
+				}
+				return
+			} else if err != nil {
+				log.Warn("unable to read request", zap.String("type", h.name), zap.Error(err))
+				return
+			}
+			p.process(s, h, buf, carrier)
+			carrier = nil
+			if s.maxConnRequests <= 1 {
+				p.flush()
+			}
+			continue
+		}
 
-	points := req.Points()
-	// write points locally
-	err := s.TSDBStore.WriteToShard(req.ShardID(), points)
+		// The connection protocol is handled here; other requests are
+		// delegated to the handler registered for their type, once the
+		// requests before them have been answered.
+		p.flush()
+		start := time.Now()
+		switch typ {
+		case tlv.MultiplexRequestMessage:
+			if _, err := tlv.ReadLVMax(conn, s.maxMessageSize); err != nil {
+				log.Warn("unable to read request", zap.String("type", rpcName(typ)), zap.Error(err))
+				refuseFrame(err)
+				return
+			}
+			if err := tlv.WriteTLV(conn, tlv.MultiplexResponseMessage, nil); err != nil {
+				log.Warn("unable to write response", zap.String("type", rpcName(typ)), zap.Error(err))
+				return
+			}
 
-	// _, _, si := s.MetaClient.ShardOwner(req.ShardID())
-	// for _, node := range si.Owners {
-	// 	s.ShardWriter.WriteShard(req.ShardID(), node.NodeID, points)
-	// }
-	// We may have received a write for a shard that we don't have locally because the
-	// sending node may have just created the shard (via the metastore) and the write
-	// arrived before the local store could create the shard.  In this case, we need
-	// to check the metastore to determine what database and retention policy this
-	// shard should reside within.
-	if err == tsdb.ErrShardNotFound {
-		db, rp := req.Database(), req.RetentionPolicy()
-		if db == "" || rp == "" {
-			s.Logger.Warn("drop write request: shard" + string(req.ShardID()) + ". no database or rentention policy received")
-			return nil
+			// Requests on the connection are traced individually.
+			s.handleMuxConn(conn, features.Has(rpc.FeatureChecksum), log)
+			return
+		case tlv.HelloRequestMessage:
+			span = startRemoteSpan(s.SpanTracer, rpcName(typ), carrier)
+			carrier = nil
+			if features, err = s.processHelloRequest(conn); err != nil {
+				log.Warn("request failed", zap.String("type", rpcName(typ)), zap.Error(err))
+				return
+			}
+			helloed = true
+			s.Metrics.ObserveRPC(typ, time.Since(start))
+			span.Finish()
+			span = nil
+			continue
 		}
 
-		err = s.TSDBStore.CreateShard(req.Database(), req.RetentionPolicy(), req.ShardID(), true)
-		if err != nil {
-			return fmt.Errorf("create shard %d: %s", req.ShardID(), err)
+		if !ok {
+			log.Warn("cluster service message type not found", zap.Int("type", int(typ)))
+			continue
 		}
+		span = startRemoteSpan(s.SpanTracer, h.name, carrier)
+		carrier = nil
 
-		err = s.TSDBStore.WriteToShard(req.ShardID(), points)
-		if err != nil {
-			return fmt.Errorf("write shard %d: %s", req.ShardID(), err)
+		// The state of the connection is unknown once a handler streaming
+		// over it panicked, so it is closed once the error is sent.
+		if err := s.call(h, conn); err == ErrHijacked {
+			return
+		} else if e, ok := err.(*panicError); ok {
+			if err := writeErrorResponse(conn, rpc.CodeInternal, e); err != nil {
+				log.Warn("unable to write error response", zap.String("type", h.name), zap.Error(err))
+			}
+			return
+		} else if err != nil {
+			log.Warn("request failed", zap.String("type", h.name), zap.Error(err))
+			return
 		}
+		s.Metrics.observeRPC(h.name, time.Since(start))
+		span.Finish()
+		span = nil
 	}
+}
 
-	if err != nil {
-		return fmt.Errorf("write shard %d: %s", req.ShardID(), err)
+// handleMuxConn serves a multiplexed connection. Each WriteShard request
+// is processed in its own goroutine and its response is written, tagged
+// with the request's tag, as soon as it completes. Responses are checksummed
+// if checksum is true. Corrupted writes are rejected with
+// CodeChecksumMismatch, so that the sender can send them again.
+//
+// At most maxMuxConnRequests writes are processed at once, or
+// DefaultMaxMuxConnectionRequests if it is not set. The connection is not read while that many are, so that a sender
+// outpacing this node is held back by the connection rather than piling up
+// goroutines here.
+func (s *Service) handleMuxConn(conn net.Conn, checksum bool, log zap.Logger) {
+	var wmu sync.Mutex
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	n := s.maxMuxConnRequests
+	if n < 1 {
+		n = DefaultMaxMuxConnectionRequests
 	}
+	inflight := make(chan struct{}, n)
 
-	return nil
-}
+	writeTagged := tlv.WriteTaggedTLV
+	if checksum {
+		writeTagged = tlv.WriteTaggedTLVChecksum
+	}
+
+	// Span contexts sent ahead of requests, keyed by tag.
+	carriers := make(map[uint64]map[string]string)
+	for {
+		typ, tag, buf, err := tlv.ReadTaggedTLVMax(conn, s.maxMessageSize)
+		if e, ok := err.(*tlv.FrameTooLargeError); ok {
+			// The write is left unread, so the connection is closed once
+			// the error is sent.
+			log.Warn("rejecting write larger than the maximum message size", zap.Int64("size", e.Size), zap.Int64("max", e.Max))
+			if e.Type != tlv.WriteShardRequestMessage {
+				return
+			}
+			resp, err := marshalWriteShardResponse(&rpc.WriteShardError{Code: rpc.CodeFrameTooLarge, Message: e.Error()})
+			if err == nil {
+				wmu.Lock()
+				err = writeTagged(conn, tlv.WriteShardResponseMessage, tag, resp)
+				wmu.Unlock()
+			}
+			if err != nil {
+				log.Warn("unable to write response", zap.String("type", rpcName(tlv.WriteShardRequestMessage)), zap.Error(err))
+			}
+			return
+		} else if e, ok := err.(*tlv.ChecksumError); ok && e.Type == tlv.WriteShardRequestMessage {
+			log.Warn("rejecting corrupted write", zap.Error(err))
+			delete(carriers, tag)
+			resp, err := marshalWriteShardResponse(&rpc.WriteShardError{Code: rpc.CodeChecksumMismatch, Message: err.Error()})
+			if err == nil {
+				wmu.Lock()
+				err = writeTagged(conn, tlv.WriteShardResponseMessage, tag, resp)
+				wmu.Unlock()
+			}
+			if err != nil {
+				log.Warn("unable to write response", zap.String("type", rpcName(tlv.WriteShardRequestMessage)), zap.Error(err))
+				return
+			}
+			continue
+		} else if err != nil {
+			if !strings.HasSuffix(err.Error(), "EOF") {
+				log.Warn("unable to read tagged message", zap.Error(err))
+			}
+			return
+		}
+
+		switch typ {
+		case tlv.SpanContextMessage:
+			var sc rpc.SpanContext
+			if err := sc.UnmarshalBinary(buf); err != nil {
+				log.Warn("unable to read span context", zap.Error(err))
+				return
+			}
+			carriers[tag] = sc.Carrier
+			continue
+		case tlv.PingRequestMessage:
+			wmu.Lock()
+			err := writeTagged(conn, tlv.PingResponseMessage, tag, nil)
+			wmu.Unlock()
+			if err != nil {
+				log.Warn("unable to write response", zap.String("type", rpcName(typ)), zap.Error(err))
+				return
+			}
+			continue
+		case tlv.WriteShardRequestMessage:
+		default:
+			log.Warn("message type not supported on multiplexed connection", zap.Int("type", int(typ)))
+			return
+		}
+
+		carrier := carriers[tag]
+		delete(carriers, tag)
+
+		inflight <- struct{}{}
+		wg.Add(1)
+		go func(tag uint64, buf []byte, carrier map[string]string) {
+			defer wg.Done()
+			defer func() { <-inflight }()
+			span := startRemoteSpan(s.SpanTracer, rpcName(typ), carrier)
+			defer span.Finish()
+
+			start := time.Now()
+			resp, err := marshalWriteShardResponse(s.serveMuxWriteShard(buf, conn.RemoteAddr()))
+			if err != nil {
+				log.Warn("unable to marshal response", zap.String("type", rpcName(typ)), zap.Error(err))
+				return
+			}
+
+			wmu.Lock()
+			err = writeTagged(conn, tlv.WriteShardResponseMessage, tag, resp)
+			wmu.Unlock()
+			if err != nil {
+				log.Warn("unable to write response", zap.String("type", rpcName(tlv.WriteShardRequestMessage)), zap.Error(err))
+			}
+			s.Metrics.ObserveRPC(typ, time.Since(start))
+		}(tag, buf, carrier)
+	}
+}
+
+// trackConn records conn as an open inbound connection.
+func (s *Service) trackConn(conn net.Conn) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conns == nil {
+		s.conns = make(map[net.Conn]time.Time)
+	}
+	s.conns[conn] = time.Now().UTC()
+}
+
+// untrackConn removes conn from the set of open inbound connections.
+func (s *Service) untrackConn(conn net.Conn) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.conns, conn)
+}
+
+// executeStatement executes a statement sent to every data node on the
+// local store. seriesN is the number of series deleted by DROP SERIES and
+// DELETE statements.
+func (s *Service) executeStatement(stmt influxql.Statement, database string) (seriesN int64, err error) {
+	switch t := stmt.(type) {
+	case *influxql.DropDatabaseStatement:
+		return 0, s.TSDBStore.DeleteDatabase(t.Name)
+	case *influxql.DropMeasurementStatement:
+		return 0, s.TSDBStore.DeleteMeasurement(database, t.Name)
+	case *influxql.DropSeriesStatement:
+		return s.deleteSeries(database, t.Sources, t.Condition)
+	case *influxql.DeleteSeriesStatement:
+		return s.deleteSeries(database, t.Sources, t.Condition)
+	case *influxql.DropRetentionPolicyStatement:
+		return 0, s.TSDBStore.DeleteRetentionPolicy(database, t.Name)
+	default:
+		return 0, fmt.Errorf("%q should not be executed across a cluster", stmt.String())
+	}
+}
+
+// deleteSeries deletes the points of the series matching sources and the tag
+// predicates of condition, within the time range of condition, from every
+// local shard. It returns the number of series matched, if the store exposes
+// its series index.
+func (s *Service) deleteSeries(database string, sources influxql.Sources, condition influxql.Expr) (int64, error) {
+	var n int64
+	if idx, ok := s.TSDBStore.(seriesIndex); ok {
+		var err error
+		if n, err = matchingSeriesN(idx.DatabaseIndex(database), sources, condition); err != nil {
+			return 0, err
+		}
+	}
+
+	if err := s.TSDBStore.DeleteSeries(database, sources, condition); err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+// seriesIndex is implemented by stores that expose the series index of each
+// database, such as coordinator.LocalTSDBStore.
+type seriesIndex interface {
+	DatabaseIndex(name string) *tsdb.DatabaseIndex
+}
+
+// matchingSeriesN returns the number of series in db matching sources and
+// the tag predicates of condition. Every measurement matches if there are
+// no sources.
+func matchingSeriesN(db *tsdb.DatabaseIndex, sources influxql.Sources, condition influxql.Expr) (int64, error) {
+	if db == nil {
+		return 0, nil
+	}
+
+	var measurements tsdb.Measurements
+	if len(sources) == 0 {
+		measurements = db.Measurements()
+	}
+	for _, src := range sources {
+		switch src := src.(type) {
+		case *influxql.Measurement:
+			if src.Regex != nil {
+				measurements = append(measurements, db.MeasurementsByRegex(src.Regex.Val)...)
+			} else if m := db.Measurement(src.Name); m != nil {
+				measurements = append(measurements, m)
+			}
+		default:
+			return 0, fmt.Errorf("invalid source type: %#v", src)
+		}
+	}
+
+	var n int64
+	for _, m := range measurements {
+		ids, err := m.SeriesIDsAllOrByExpr(condition)
+		if err != nil {
+			return 0, err
+		}
+		n += int64(len(ids))
+	}
+	return n, nil
+}
+
+// serveWriteShard processes a WriteShard request unless the service is
+// draining or read-only.
+func (s *Service) serveWriteShard(buf []byte) error {
+	if !s.startRequest() {
+		return &rpc.WriteShardError{Code: rpc.CodeDraining, Message: ErrDraining.Error()}
+	}
+	defer s.active.Done()
+	if s.ReadOnly() {
+		return &rpc.WriteShardError{Code: rpc.CodeReadOnly, Message: ErrReadOnly.Error()}
+	}
+	if s.loadLimits.enabled() {
+		// Shed the write rather than let it time out, so that the sender
+		// can retry it or queue it in hinted handoff.
+		if reason := s.nodeLoad().Overloaded; reason != "" {
+			return &rpc.WriteShardError{Code: rpc.CodeOverloaded, Message: "node overloaded: " + reason}
+		}
+	}
+	return s.processWriteShardRequest(buf)
+}
+
+func (s *Service) processWriteShardRequest(buf []byte) error {
+	// Build request
+	var req rpc.WriteShardRequest
+	if err := req.UnmarshalBinary(buf); err != nil {
+		s.Logger.Warn("write shard failed", zap.Error(err))
+		return err
+	}
+
+	start := time.Now()
+	var deadline time.Time
+	if d := req.Timeout(); d > 0 {
+		deadline = start.Add(d)
+	}
+
+	if s.quotas.enabled() {
+		db := req.Database()
+		if db == "" {
+			db = s.shardDatabase(req.ShardID())
+		}
+		release, err := s.acquireWrite(db, len(buf), deadline)
+		if err != nil {
+			s.Logger.Warn("write shard failed", zap.String("requestID", req.RequestID()), zap.Uint64("originNodeID", req.OriginNodeID()), zap.Uint64("shardID", req.ShardID()), zap.Error(err))
+			return withRequestID(err, req.RequestID())
+		}
+		defer release()
+	}
+
+	if err := s.writeShard(&req, deadline); err != nil {
+		s.Logger.Warn("write shard failed", zap.String("requestID", req.RequestID()), zap.Uint64("originNodeID", req.OriginNodeID()), zap.Uint64("shardID", req.ShardID()), zap.Error(err))
+		return withRequestID(err, req.RequestID())
+	}
+
+	// The request ID is the sender's trace ID so this can be matched to the
+	// remote write stage of its trace.
+	s.Logger.Debug("wrote shard", zap.String("requestID", req.RequestID()), zap.Uint64("originNodeID", req.OriginNodeID()), zap.Uint64("shardID", req.ShardID()), zap.Duration("duration", time.Since(start)))
+	return nil
+}
+
+// withRequestID returns err, a failed shard write, as a *rpc.WriteShardError
+// reporting requestID to the sender. err is not modified, as it may be
+// shared.
+func withRequestID(err error, requestID string) error {
+	if requestID == "" {
+		return err
+	}
+	e := rpc.WriteShardError{Code: rpc.CodeUnknown, Message: err.Error()}
+	if other, ok := err.(*rpc.WriteShardError); ok {
+		e = *other
+	}
+	e.RequestID = requestID
+	return &e
+}
+
+// writeShard writes the points in req to the local store. Points are not
+// written once deadline has passed, unless it is zero.
+func (s *Service) writeShard(req *rpc.WriteShardRequest, deadline time.Time) error {
+	if deadlineExceeded(deadline) {
+		return errWriteShardDeadline
+	}
+
+	stream, seq := handoffStreamOf(req)
+	if seq != 0 && s.handoffs.applied(stream, seq) {
+		s.Logger.Debug("skipping replayed hinted handoff write", zap.Uint64("originNodeID", stream.originNodeID), zap.Uint64("queueID", stream.queueID), zap.Uint64("shardID", stream.shardID), zap.Uint64("sequence", seq))
+		return nil
+	}
+
+	points, err := req.DecodePoints()
+	if err != nil {
+		return err
+	}
+	if s.rejectAll(req) {
+		if rejected, reason, _ := conflictingPoints(s.ShardStore, req.ShardID(), points); len(rejected) > 0 {
+			return &rpc.WriteShardError{
+				Code:     rpc.CodeFieldTypeConflict,
+				Message:  fmt.Sprintf("write shard %d: write rejected by %s partial write policy: %s", req.ShardID(), PartialWriteRejectAll, reason),
+				Rejected: rejected,
+			}
+		}
+	}
+
+	// write points locally
+	err = s.writeToShard(req.ShardID(), points)
+
+	// _, _, si := s.MetaClient.ShardOwner(req.ShardID())
+	// for _, node := range si.Owners {
+	// 	s.ShardWriter.WriteShard(req.ShardID(), node.NodeID, points)
+	// }
+	// We may have received a write for a shard that we don't have locally because the
+	// sending node may have just created the shard (via the metastore) and the write
+	// arrived before the local store could create the shard.  In this case, we need
+	// to check the metastore to determine what database and retention policy this
+	// shard should reside within.
+	if err == tsdb.ErrShardNotFound {
+		db, rp := req.Database(), req.RetentionPolicy()
+		if db == "" || rp == "" {
+			s.Logger.Warn("dropping write to unknown shard without database or retention policy", zap.String("requestID", req.RequestID()), zap.Uint64("originNodeID", req.OriginNodeID()), zap.Uint64("shardID", req.ShardID()))
+			return nil
+		}
+
+		err = s.TSDBStore.CreateShard(req.Database(), req.RetentionPolicy(), req.ShardID(), true)
+		if err != nil {
+			return newWriteShardError(err, fmt.Sprintf("create shard %d: %s", req.ShardID(), err))
+		}
+
+		if deadlineExceeded(deadline) {
+			return errWriteShardDeadline
+		}
+
+		err = s.writeToShard(req.ShardID(), points)
+	}
+
+	if err != nil {
+		return s.writeShardError(req, err)
+	}
+	if seq != 0 {
+		s.handoffs.advance(stream, seq)
+	}
+
+	return nil
+}
+
+// rejectAll returns true if the points of req are written only if none is
+// rejected, as set by the partial write policy of its database.
+func (s *Service) rejectAll(req *rpc.WriteShardRequest) bool {
+	if s.partialWrites.policy != PartialWriteRejectAll && len(s.partialWrites.databases) == 0 {
+		return false
+	}
+	db := req.Database()
+	if db == "" {
+		db = s.shardDatabase(req.ShardID())
+	}
+	return s.partialWrites.get(db) == PartialWriteRejectAll
+}
+
+// writeShardError returns the error of req, a shard write that failed with
+// err. If the store dropped points with field type conflicts, the indices of
+// the points of req that were dropped are reported, so that the sender knows
+// which failed. None may be, if the points were coalesced with another write
+// that had the conflicts, and the write succeeded.
+func (s *Service) writeShardError(req *rpc.WriteShardRequest, err error) error {
+	e := &rpc.WriteShardError{Code: writeShardErrorCode(err), Message: fmt.Sprintf("write shard %d: %s", req.ShardID(), err)}
+	if _, ok := err.(tsdb.PartialWriteError); ok && e.Code == rpc.CodeFieldTypeConflict {
+		// The store removes the points it drops from the slice written, so
+		// the points are decoded again.
+		rejected, _, ok := conflictingPoints(s.ShardStore, req.ShardID(), req.Points())
+		if ok && len(rejected) == 0 {
+			return nil
+		}
+		e.Rejected = rejected
+	}
+	return e
+}
+
+// writeToShard writes points to the local store, batching them with other
+// writes to the same shard if write coalescing is enabled.
+func (s *Service) writeToShard(shardID uint64, points []models.Point) error {
+	if s.coalescer != nil {
+		return s.coalescer.WriteToShard(shardID, points)
+	}
+	return s.TSDBStore.WriteToShard(shardID, points)
+}
+
+// processWritePointsRequest writes a write forwarded by another node and
+// responds with its result.
+func (s *Service) processWritePointsRequest(conn net.Conn) error {
+	var req rpc.WritePointsRequest
+	if err := tlv.DecodeLV(conn, &req); err != nil {
+		return err
+	}
+
+	var resp rpc.WritePointsResponse
+	err := s.writePoints(&req)
+	if e, ok := err.(*rpc.WriteShardError); ok {
+		resp.Code, resp.Err = e.Code, e.Message
+	} else if err != nil {
+		resp.Code, resp.Err = rpc.CodeUnknown, err.Error()
+	}
+	if err != nil {
+		s.Logger.Warn("write points failed", zap.String("requestID", req.RequestID), zap.Error(err))
+	}
+
+	return tlv.EncodeTLV(conn, tlv.WritePointsResponseMessage, &resp)
+}
+
+// writePoints writes the points forwarded in req with the PointsWriter.
+// Writes the PointsWriter will not accept are failed with a code telling the
+// sender to write the points to the shard owners itself.
+func (s *Service) writePoints(req *rpc.WritePointsRequest) error {
+	if s.PointsWriter == nil {
+		return &rpc.WriteShardError{Code: rpc.CodeUnsupported, Message: "write forwarding not supported"}
+	}
+	if !s.startRequest() {
+		return &rpc.WriteShardError{Code: rpc.CodeDraining, Message: ErrDraining.Error()}
+	}
+	defer s.active.Done()
+	if s.ReadOnly() {
+		return &rpc.WriteShardError{Code: rpc.CodeReadOnly, Message: ErrReadOnly.Error()}
+	}
+
+	var deadline time.Time
+	if req.Timeout > 0 {
+		deadline = time.Now().Add(req.Timeout)
+	}
+
+	points, err := req.Points.Points()
+	if err != nil {
+		return err
+	}
+
+	err = s.PointsWriter.WriteForwardedPoints(req.RequestID, deadline, req.Database, req.RetentionPolicy, req.ConsistencyLevel, points)
+	if err == ErrDraining {
+		return &rpc.WriteShardError{Code: rpc.CodeDraining, Message: err.Error()}
+	}
+	return err
+}
+
+// errWriteShardDeadline is returned for writes whose sender has already given
+// up on them.
+var errWriteShardDeadline = &rpc.WriteShardError{Code: rpc.CodeDeadlineExceeded, Message: "write deadline exceeded"}
+
+// deadlineExceeded returns true if deadline is set and has passed.
+func deadlineExceeded(deadline time.Time) bool {
+	return !deadline.IsZero() && !time.Now().Before(deadline)
+}
+
+// newWriteShardError returns an error with msg and the code classifying err.
+func newWriteShardError(err error, msg string) error {
+	return &rpc.WriteShardError{Code: writeShardErrorCode(err), Message: msg}
+}
+
+// writeShardErrorCode classifies an error returned by the local store.
+func writeShardErrorCode(err error) rpc.ErrorCode {
+	switch err := err.(type) {
+	case *rpc.WriteShardError:
+		return err.Code
+	case tsdb.PartialWriteError:
+		if strings.HasPrefix(err.Reason, tsdb.ErrFieldTypeConflict.Error()) {
+			return rpc.CodeFieldTypeConflict
+		}
+		return rpc.CodeUnknown
+	}
+
+	switch {
+	case err == tsdb.ErrShardNotFound:
+		return rpc.CodeShardNotFound
+	case err == tsdb.ErrFieldTypeConflict:
+		return rpc.CodeFieldTypeConflict
+	case err == tsdb.ErrStoreClosed, err == tsdb.ErrEngineClosed:
+		return rpc.CodeStoreClosed
+	case strings.HasPrefix(err.Error(), "cache-max-memory-size exceeded"):
+		return rpc.CodeOverloaded
+	default:
+		return rpc.CodeUnknown
+	}
+}
 
 func (s *Service) writeShardResponse(conn net.Conn, err error) {
-	// Build response.
+	buf, err := marshalWriteShardResponse(err)
+	if err != nil {
+		s.Logger.Warn("unable to marshal response", zap.String("type", rpcName(tlv.WriteShardRequestMessage)), zap.Error(err))
+		return
+	}
+
+	// Write to connection.
+	if err := tlv.WriteTLV(conn, tlv.WriteShardResponseMessage, buf); err != nil {
+		s.Logger.Warn("unable to write response", zap.String("type", rpcName(tlv.WriteShardRequestMessage)), zap.Stringer("peer", conn.RemoteAddr()), zap.Error(err))
+	}
+}
+
+// executeStatementResponse writes the response to an ExecuteStatement request
+// that deleted seriesN series and failed with err, or succeeded if err is nil.
+func (s *Service) executeStatementResponse(conn net.Conn, seriesN int64, err error) {
+	var resp rpc.ExecuteStatementResponse
+	if e, ok := err.(*rpc.WriteShardError); ok {
+		resp.SetCode(int(e.Code))
+		resp.SetMessage(e.Message)
+	} else if err != nil {
+		resp.SetCode(int(rpc.CodeUnknown))
+		resp.SetMessage(err.Error())
+	} else {
+		resp.SetCode(int(rpc.CodeOK))
+		resp.SetSeriesN(seriesN)
+	}
+
+	if err := tlv.EncodeTLV(conn, tlv.ExecuteStatementResponseMessage, &resp); err != nil {
+		s.Logger.Warn("unable to write response", zap.String("type", rpcName(tlv.ExecuteStatementRequestMessage)), zap.Stringer("peer", conn.RemoteAddr()), zap.Error(err))
+	}
+}
+
+// marshalWriteShardResponse returns the response to a WriteShard request
+// that failed with err, or succeeded if err is nil.
+func marshalWriteShardResponse(err error) ([]byte, error) {
 	var resp rpc.WriteShardResponse
+	if e, ok := err.(*rpc.WriteShardError); ok {
+		resp.SetCode(int(e.Code))
+		resp.SetMessage(e.Message)
+		if e.RequestID != "" {
+			resp.SetRequestID(e.RequestID)
+		}
+		if len(e.Rejected) > 0 {
+			resp.SetRejected(e.Rejected)
+		}
+	} else if err != nil {
+		resp.SetCode(int(rpc.CodeUnknown))
+		resp.SetMessage(err.Error())
+	} else {
+		resp.SetCode(int(rpc.CodeOK))
+	}
+	return resp.MarshalBinary()
+}
+
+func (s *Service) processCreateIteratorRequest(conn net.Conn) {
+	defer conn.Close()
+
+	acquired := s.acquireIterator()
+	if acquired {
+		defer s.releaseIterator()
+	}
+
+	s.Metrics.openIteratorStream()
+	defer s.Metrics.closeIteratorStream()
+
+	var itr influxql.Iterator
+	var requestID, db, compression, encoding string
+	var disconnected <-chan struct{}
+	var acquiredDB bool
+	defer func() {
+		if acquiredDB {
+			s.quotas.release(s.quotas.iterators, s.quotas.maxIterators, db)
+		}
+	}()
+	if err := func() error {
+		// Parse request.
+		var req rpc.CreateIteratorRequest
+		if err := s.decodeRequest(conn, &req); err != nil {
+			return err
+		}
+		requestID, compression, encoding = req.RequestID, req.Compression, req.Encoding
+
+		if !acquired {
+			return &rpc.QueryLimitError{Limit: "max-concurrent-remote-iterators", Max: int64(cap(s.iterators))}
+		}
+
+		// The shards of a single request belong to the same database.
+		if len(req.ShardIDs) > 0 && s.quotas.enabled() {
+			db = s.shardDatabase(req.ShardIDs[0])
+		}
+		if !s.quotas.acquire(s.quotas.iterators, s.quotas.maxIterators, db) {
+			s.quotaHit(db, quotaIterators, int64(s.quotas.maxIterators))
+			return &rpc.QueryLimitError{Limit: quotaIterators, Max: int64(s.quotas.maxIterators)}
+		}
+		acquiredDB = true
+
+		// Interrupt the iterator if the querying node disconnects.
+		disconnected = monitorIteratorConn(conn)
+		req.Opt.InterruptCh = disconnected
+
+		// Generate a single iterator from all shards.
+		i, err := s.createIterator(req.ShardIDs, req.Opt)
+		if err != nil {
+			return err
+		}
+		itr = i
+
+		// Every series is read from its own iterator, so the series count
+		// is known before any points are read.
+		if itr != nil && s.maxIteratorSeriesN > 0 && itr.Stats().SeriesN > s.maxIteratorSeriesN {
+			return &rpc.QueryLimitError{Limit: "max-remote-series", Max: int64(s.maxIteratorSeriesN)}
+		}
+		return nil
+	}(); err != nil {
+		if itr != nil {
+			itr.Close()
+		}
+		s.Logger.Warn("create iterator failed", zap.String("requestID", requestID), zap.Stringer("peer", conn.RemoteAddr()), zap.Error(err))
+		if !isFrameTooLarge(err) {
+			tlv.EncodeTLV(conn, tlv.CreateIteratorResponseMessage, &rpc.CreateIteratorResponse{Err: err})
+		}
+		return
+	}
+
+	// Every write to the querying node must complete within the stall
+	// timeout, so that a node that stops reading cannot hold the iterator.
+	cw := &stallWriter{conn: conn, timeout: s.iteratorStallTimeout}
+
+	// Encode success response, compressing and encoding the stream as the
+	// querying node accepts.
+	var resp rpc.CreateIteratorResponse
+	if compression == rpc.CompressionSnappy {
+		resp.Compression = compression
+	}
+	if encoding == rpc.IteratorEncodingColumnar {
+		resp.Encoding = encoding
+	}
+	if err := tlv.EncodeTLV(cw, tlv.CreateIteratorResponseMessage, &resp); err != nil {
+		s.Logger.Warn("unable to write response", zap.String("type", rpcName(tlv.CreateIteratorRequestMessage)), zap.String("requestID", requestID), zap.Stringer("peer", conn.RemoteAddr()), zap.Error(err))
+		return
+	}
+
+	// Stream iterator to connection. The stream is ended with the error the
+	// iterator failed with, if any.
+	var err error
+	sw := &iteratorStreamWriter{w: cw, max: s.maxIteratorBytes, compress: resp.Compression != ""}
+	if l := s.quotas.limiter(db); l != nil {
+		sw.w = &throttledWriter{w: cw, limiters: []*rateLimiter{l}, closing: s.closing}
+	}
+	if itr != nil {
+		defer itr.Close()
+
+		w := bufio.NewWriter(sw)
+		if resp.Encoding == rpc.IteratorEncodingColumnar {
+			err = encodeColumnarIterator(w, itr, influxql.DefaultStatsInterval)
+		} else {
+			err = influxql.NewIteratorEncoder(w).EncodeIterator(itr)
+		}
+		if err == nil {
+			err = w.Flush()
+		}
+	}
+
+	// The local iterator is closed on return if the querying node stopped
+	// reading, or has gone, whether the monitor or a failed write noticed
+	// first.
+	if cw.stalled {
+		s.Metrics.evictIteratorStream()
+		s.Logger.Warn("aborting iterator stream the querying node stopped reading", zap.String("requestID", requestID), zap.Stringer("peer", conn.RemoteAddr()), zap.Duration("timeout", s.iteratorStallTimeout))
+		return
+	}
+	orphaned := sw.err != nil
+	select {
+	case <-disconnected:
+		orphaned = true
+	default:
+	}
+	if orphaned {
+		s.Metrics.orphanIteratorStream()
+		s.Logger.Info("closing orphaned CreateIterator iterator", zap.String("requestID", requestID), zap.Stringer("peer", conn.RemoteAddr()))
+		return
+	} else if err != nil {
+		s.Logger.Warn("iterator stream failed", zap.String("requestID", requestID), zap.Stringer("peer", conn.RemoteAddr()), zap.Error(err))
+	}
+
+	if err := tlv.EncodeTLV(cw, tlv.IteratorEndMessage, &rpc.IteratorEnd{Err: err}); err != nil {
+		s.Logger.Warn("unable to write iterator end", zap.String("requestID", requestID), zap.Stringer("peer", conn.RemoteAddr()), zap.Error(err))
+	}
+}
+
+// monitorIteratorConn returns a channel that is closed once conn is closed.
+// The querying node sends nothing after its CreateIterator request, so any
+// read returning means the connection has gone. conn is then closed as well
+// so that a write blocked on it fails.
+func monitorIteratorConn(conn net.Conn) <-chan struct{} {
+	ch := make(chan struct{})
+	go func() {
+		defer close(ch)
+		var buf [1]byte
+		conn.Read(buf[:])
+		conn.Close()
+	}()
+	return ch
+}
+
+// acquireIterator reserves one of the iterators other nodes may have open.
+// It returns false without blocking if they are all in use.
+func (s *Service) acquireIterator() bool {
+	if s.iterators == nil {
+		return true
+	}
+	select {
+	case s.iterators <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
+// releaseIterator releases an iterator reserved by acquireIterator.
+func (s *Service) releaseIterator() {
+	if s.iterators != nil {
+		<-s.iterators
+	}
+}
+
+// createIterator returns a single iterator over the sources of opt in the
+// local shards with the given IDs. Shards that are not stored on this node
+// are skipped. It returns nil if no shard has points for the sources.
+//
+// The iterator of a top() or bottom() call returns the candidate points of
+// the local shards.
+func (s *Service) createIterator(shardIDs []uint64, opt influxql.IteratorOptions) (influxql.Iterator, error) {
+	if s.ShardStore == nil {
+		return nil, fmt.Errorf("shard store not available")
+	}
+
+	if call, ok := selectorCall(opt); ok {
+		rawOpt, err := selectorRawOptions(call, opt)
+		if err != nil {
+			return nil, err
+		}
+		itr, err := s.createIterator(shardIDs, rawOpt)
+		if err != nil || itr == nil {
+			return nil, err
+		}
+		return newSelectorIterator(itr, call, opt, influxql.Interval{})
+	}
+
+	var itrs influxql.Iterators
+	for _, id := range shardIDs {
+		sh := s.ShardStore.Shard(id)
+		if sh == nil {
+			continue
+		}
+
+		for _, src := range opt.Sources {
+			m, ok := src.(*influxql.Measurement)
+			if !ok {
+				itrs.Close()
+				return nil, fmt.Errorf("invalid source type: %T", src)
+			}
+
+			names := []string{m.Name}
+			if m.Regex != nil {
+				names = sh.MeasurementsByRegex(m.Regex.Val)
+			}
+			for _, name := range names {
+				itr, err := sh.CreateIterator(name, opt)
+				if err != nil {
+					itrs.Close()
+					return nil, err
+				} else if itr != nil {
+					itrs = append(itrs, itr)
+				}
+			}
+		}
+	}
+	if len(itrs) == 0 {
+		return nil, nil
+	}
+	return itrs.Merge(opt)
+}
+
+func (s *Service) processFieldDimensionsRequest(conn net.Conn) {
+	var fields, dimensions map[string]struct{}
+	var requestID string
+	if err := func() error {
+		// Parse request.
+		var req rpc.FieldDimensionsRequest
+		if err := s.decodeRequest(conn, &req); err != nil {
+			return err
+		}
+		requestID = req.RequestID
+
+		// Collect iterator creators for each shard.
+		// ics := make(influxql.Iterators, 0, len(req.ShardIDs))
+		// for _, shardID := range req.ShardIDs {
+		// 	ic := s.ShardIteratorCreator.ShardIteratorCreator(shardID)
+		// 	if ic == nil {
+		// 		return nil
+		// 	}
+		// 	// ics = append(ics, ic.CreateIterator(nil))
+		// }
+
+		// // Generate a single iterator from all shards.
+		// i, _ := ics.Merge(nil)
+		// f, d, err := influxql.FieldMapper.FieldDimensions(nil)
+		// // f, d, err := influxql.IteratorCreators(ics).FieldDimensions(req.Sources)
+		// if err != nil {
+		// 	return err
+		// }
+		// fields, dimensions = f, d
+
+		return nil
+	}(); err != nil {
+		s.Logger.Warn("field dimensions failed", zap.String("requestID", requestID), zap.Stringer("peer", conn.RemoteAddr()), zap.Error(err))
+		if !isFrameTooLarge(err) {
+			tlv.EncodeTLV(conn, tlv.FieldDimensionsResponseMessage, nil)
+		}
+		return
+	}
+
+	// Encode success response.
+	if err := tlv.EncodeTLV(conn, tlv.FieldDimensionsResponseMessage, &rpc.FieldDimensionsResponse{
+		Fields:     fields,
+		Dimensions: dimensions,
+	}); err != nil {
+		s.Logger.Warn("unable to write response", zap.String("type", rpcName(tlv.FieldDimensionsRequestMessage)), zap.String("requestID", requestID), zap.Stringer("peer", conn.RemoteAddr()), zap.Error(err))
+		return
+	}
+}
+
+// processShowShardsRequest responds with every shard in the cluster and its owners.
+func (s *Service) processShowShardsRequest(conn net.Conn) error {
+	var req rpc.ShowShardsRequest
+	if err := tlv.DecodeLV(conn, &req); err != nil {
+		return err
+	}
+
+	var resp rpc.ShowShardsResponse
+	if shards, err := s.shardInfos(); err != nil {
+		resp.Err = err.Error()
+	} else {
+		resp.Shards = shards
+	}
+
+	return tlv.EncodeTLV(conn, tlv.ShowShardsResponseMessage, &resp)
+}
+
+// processShardStatusRequest responds with the status of the requested local
+// shards, or of every local shard if none are requested.
+func (s *Service) processShardStatusRequest(conn net.Conn) error {
+	var req rpc.ShardStatusRequest
+	if err := tlv.DecodeLV(conn, &req); err != nil {
+		return err
+	}
+
+	var resp rpc.ShardStatusResponse
+	if shards, err := s.shardStatuses(req.ShardIDs); err != nil {
+		resp.Err = err.Error()
+	} else {
+		resp.Shards = shards
+		resp.Load = s.nodeLoad()
+	}
+
+	return tlv.EncodeTLV(conn, tlv.ShardStatusResponseMessage, &resp)
+}
+
+// processShardBoundsRequest responds with the time range of the requested
+// measurements in the requested local shards.
+func (s *Service) processShardBoundsRequest(conn net.Conn) error {
+	var req rpc.ShardBoundsRequest
+	if err := tlv.DecodeLV(conn, &req); err != nil {
+		return err
+	}
+
+	var resp rpc.ShardBoundsResponse
+	if shards, err := s.shardBounds(req.ShardIDs, req.Measurements); err != nil {
+		resp.Err = err.Error()
+	} else {
+		resp.Shards = shards
+	}
+
+	return tlv.EncodeTLV(conn, tlv.ShardBoundsResponseMessage, &resp)
+}
+
+// shardBounds returns the time range of the given measurements, or of every
+// measurement if none are given, in the local shards with the given IDs.
+// Shards that are not stored on this node are skipped, and measurements
+// without points in a shard are left out of its bounds.
+func (s *Service) shardBounds(ids []uint64, measurements []string) ([]rpc.ShardBounds, error) {
+	if s.ShardStore == nil {
+		return nil, fmt.Errorf("shard store not available")
+	}
+
+	bounds := make([]rpc.ShardBounds, 0, len(ids))
+	for _, id := range ids {
+		sh := s.ShardStore.Shard(id)
+		if sh == nil {
+			continue
+		}
+
+		names := measurements
+		if len(names) == 0 {
+			names = sh.MeasurementsByRegex(regexp.MustCompile(``))
+			sort.Strings(names)
+		}
+
+		sb := rpc.ShardBounds{ID: id}
+		for _, name := range names {
+			mb, ok, err := measurementBounds(sh, name)
+			if err != nil {
+				return nil, fmt.Errorf("shard %d measurement %s: %s", id, name, err)
+			} else if ok {
+				sb.Measurements = append(sb.Measurements, mb)
+			}
+		}
+		bounds = append(bounds, sb)
+	}
+	return bounds, nil
+}
+
+// measurementBounds returns the time of the first and last point of the
+// measurement name in sh. ok is false if it has no points in sh.
+func measurementBounds(sh *tsdb.Shard, name string) (mb rpc.MeasurementBounds, ok bool, err error) {
+	fields, _, err := sh.FieldDimensions([]string{name})
+	if err != nil || len(fields) == 0 {
+		return mb, false, err
+	}
+
+	// Iterate over every field so that points are found whichever fields
+	// they have.
+	aux := make([]influxql.VarRef, 0, len(fields))
+	for k, typ := range fields {
+		aux = append(aux, influxql.VarRef{Val: k, Type: typ})
+	}
+	sort.Sort(influxql.VarRefs(aux))
+
+	mb.Name = name
+	if mb.MinTime, ok, err = firstPointTime(sh, name, aux, true); err != nil || !ok {
+		return mb, false, err
+	}
+	if mb.MaxTime, ok, err = firstPointTime(sh, name, aux, false); err != nil || !ok {
+		return mb, false, err
+	}
+	return mb, true, nil
+}
+
+// firstPointTime returns the time of the first point of the measurement name
+// in sh, or of the last point if ascending is false.
+func firstPointTime(sh *tsdb.Shard, name string, aux []influxql.VarRef, ascending bool) (time.Time, bool, error) {
+	itr, err := sh.CreateIterator(name, influxql.IteratorOptions{
+		Aux:       aux,
+		StartTime: influxql.MinTime,
+		EndTime:   influxql.MaxTime,
+		Ascending: ascending,
+		Ordered:   true,
+	})
+	if err != nil || itr == nil {
+		return time.Time{}, false, err
+	}
+	defer itr.Close()
+
+	// Iterators over auxiliary fields only always return float points.
+	fitr, ok := itr.(influxql.FloatIterator)
+	if !ok {
+		return time.Time{}, false, fmt.Errorf("unexpected iterator type: %T", itr)
+	}
+	p, err := fitr.Next()
+	if err != nil || p == nil {
+		return time.Time{}, false, err
+	}
+	return time.Unix(0, p.Time).UTC(), true, nil
+}
+
+// shardStatuses returns the status of the local shards with the given IDs.
+// Shards that are not stored on this node are skipped.
+//
+// LastWrite is the time the shard's WAL or data files last changed, while
+// LastModified only covers the data files: it is unset until the cache is
+// first snapshotted, and changes when the shard is compacted. A shard is cold
+// once its shard group has ended and it no longer receives new writes, and
+// corrupt if scrubbing found a TSM file not matching its checksums.
+func (s *Service) shardStatuses(ids []uint64) ([]rpc.ShardStatus, error) {
+	if s.ShardStore == nil {
+		return nil, fmt.Errorf("shard store not available")
+	}
+	if len(ids) == 0 {
+		ids = s.ShardStore.ShardIDs()
+	}
+
+	infos, err := s.shardInfos()
 	if err != nil {
-		resp.SetCode(1)
-		resp.SetMessage(err.Error())
-	} else {
-		resp.SetCode(0)
+		return nil, err
+	}
+	byID := make(map[uint64]rpc.ShardInfo, len(infos))
+	for _, si := range infos {
+		byID[si.ID] = si
+	}
+
+	now := time.Now()
+	statuses := make([]rpc.ShardStatus, 0, len(ids))
+	for _, id := range ids {
+		sh := s.ShardStore.Shard(id)
+		if sh == nil {
+			continue
+		}
+
+		size, err := sh.DiskSize()
+		if err != nil {
+			return nil, fmt.Errorf("shard %d size: %s", id, err)
+		}
+		seriesN, err := sh.SeriesCount()
+		if err != nil {
+			return nil, fmt.Errorf("shard %d series: %s", id, err)
+		}
+		modified, err := lastModified(sh.Path())
+		if err != nil {
+			return nil, fmt.Errorf("shard %d last modified: %s", id, err)
+		}
+
+		si := byID[id]
+		statuses = append(statuses, rpc.ShardStatus{
+			ID:           id,
+			Database:     si.Database,
+			Policy:       si.Policy,
+			Size:         size,
+			SeriesN:      int64(seriesN),
+			LastModified: modified,
+			LastWrite:    sh.LastModified().UTC(),
+			Cold:         !si.EndTime.IsZero() && si.EndTime.Before(now),
+			Corrupt:      s.scrubber.reason(id),
+		})
+	}
+	return statuses, nil
+}
+
+// lastModified returns the latest modification time of the files under path.
+func lastModified(path string) (time.Time, error) {
+	var t time.Time
+	err := filepath.Walk(path, func(_ string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !fi.IsDir() && fi.ModTime().After(t) {
+			t = fi.ModTime()
+		}
+		return nil
+	})
+	if t.IsZero() {
+		return t, err
 	}
+	return t.UTC(), err
+}
 
-	// Marshal response to binary.
-	buf, err := resp.MarshalBinary()
+// shardInfos flattens the meta data into a list of shards.
+func (s *Service) shardInfos() ([]rpc.ShardInfo, error) {
+	dbs, err := s.MetaClient.Databases()
 	if err != nil {
-		s.Logger.Warn("error marshalling shard response: " + err.Error())
-		return
+		return nil, err
 	}
 
-	// Write to connection.
-	if err := tlv.WriteTLV(conn, tlv.WriteShardResponseMessage, buf); err != nil {
-		s.Logger.Warn("write shard response error:" + err.Error())
+	var shards []rpc.ShardInfo
+	for _, dbi := range dbs {
+		for _, rpi := range dbi.RetentionPolicies {
+			for _, sgi := range rpi.ShardGroups {
+				if sgi.Deleted() {
+					continue
+				}
+
+				for _, si := range sgi.Shards {
+					owners := make([]uint64, len(si.Owners))
+					for i, o := range si.Owners {
+						owners[i] = o.NodeID
+					}
+
+					shards = append(shards, rpc.ShardInfo{
+						ID:           si.ID,
+						Database:     dbi.Name,
+						Policy:       rpi.Name,
+						ShardGroupID: sgi.ID,
+						StartTime:    sgi.StartTime,
+						EndTime:      sgi.EndTime,
+						Owners:       owners,
+					})
+				}
+			}
+		}
 	}
+	return shards, nil
 }
 
-func (s *Service) processCreateIteratorRequest(conn net.Conn) {
-	defer conn.Close()
+// processCopyShardRequest copies a shard from the source node onto this node
+// and registers this node as an owner of the shard.
+func (s *Service) processCopyShardRequest(conn net.Conn) error {
+	var req rpc.CopyShardRequest
+	if err := tlv.DecodeLV(conn, &req); err != nil {
+		return err
+	}
 
-	var itr influxql.Iterator
-	if err := func() error {
-		// Parse request.
-		var req rpc.CreateIteratorRequest
-		if err := tlv.DecodeLV(conn, &req); err != nil {
-			return err
+	var resp rpc.CopyShardResponse
+	if err := s.copyShard(&req); err != nil {
+		resp.Err = err.Error()
+	}
+
+	return tlv.EncodeTLV(conn, tlv.CopyShardResponseMessage, &resp)
+}
+
+func (s *Service) copyShard(req *rpc.CopyShardRequest) error {
+	if req.Database == "" || req.Policy == "" {
+		return fmt.Errorf("database and retention policy required to copy shard %d", req.ShardID)
+	}
+
+	// The shard is only owned by this node once a copy matches its
+	// checksums.
+	if err := s.copyVerifiedShard(req); err != nil {
+		return err
+	}
+
+	if err := s.MetaClient.AddShardOwner(req.ShardID, s.Node.ID); err != nil {
+		return err
+	}
+	s.Events.Publish(Event{Type: EventShardCopied, NodeID: s.Node.ID, ShardID: req.ShardID, Message: req.Source})
+	return nil
+}
+
+// copyVerifiedShard copies the shard of req from its source, copying it again
+// if it does not match its checksums.
+func (s *Service) copyVerifiedShard(req *rpc.CopyShardRequest) error {
+	var err error
+	for attempt := 1; attempt <= maxShardStreamAttempts; attempt++ {
+		if err = s.copyShardFrom(req); !isChecksumError(err) {
+			break
 		}
+		s.Logger.Warn("copied shard is corrupted", zap.Uint64("shardID", req.ShardID), zap.String("source", req.Source), zap.Int("attempt", attempt), zap.Error(err))
+		s.TSDBStore.DeleteShard(req.ShardID)
+	}
+	if err != nil {
+		return err
+	}
 
-		// Collect iterator creators for each shard.
-		// ics := make([]influxql.IteratorCreator, 0, len(req.ShardIDs))
-		// for _, shardID := range req.ShardIDs {
-		// 	ic := s.ShardIteratorCreator.ShardIteratorCreator(shardID)
-		// 	if ic == nil {
-		// 		return nil
-		// 	}
-		// 	ics = append(ics, ic)
-		// }
+	s.Logger.Info("copied shard", zap.Uint64("shardID", req.ShardID), zap.String("source", req.Source))
+	return nil
+}
 
-		// // Generate a single iterator from all shards.
-		// i, err := influxql.IteratorCreators(ics).CreateIterator(req.Opt)
-		// if err != nil {
-		// 	return err
-		// }
-		// itr = i
+// copyShardFrom restores a backup of the shard streamed from the source of
+// req, verifying it against its checksums.
+func (s *Service) copyShardFrom(req *rpc.CopyShardRequest) error {
+	conn, err := s.dialer.Dial(req.Source, s.dialTimeout)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
 
-		return nil
-	}(); err != nil {
-		itr.Close()
-		s.Logger.Warn("error reading CreateIterator request:" + err.Error())
-		// tlv.EncodeTLV(conn, tlv.CreateIteratorResponseMessage, &CreateIteratorResponse{Err: err})
+	if err := tlv.EncodeTLV(conn, tlv.BackupShardRequestMessage, &rpc.BackupShardRequest{
+		ShardID:   req.ShardID,
+		Checksums: true,
+	}); err != nil {
+		return err
+	}
+
+	var resp rpc.BackupShardResponse
+	if _, err := tlv.DecodeTLV(conn, &resp); err != nil {
+		return err
+	} else if resp.Err != "" {
+		return fmt.Errorf("backup shard %d on %s: %s", req.ShardID, req.Source, resp.Err)
+	}
+
+	if err := s.TSDBStore.CreateShard(req.Database, req.Policy, req.ShardID, true); err != nil {
+		return fmt.Errorf("create shard %d: %s", req.ShardID, err)
+	}
+
+	if err := s.restoreArchive(req.ShardID, s.throttleCopy(conn), func(w io.Writer, r io.Reader) error {
+		return verifyShardChecksums(w, r, req.ShardID, true)
+	}); err != nil {
+		if isChecksumError(err) {
+			return err
+		}
+		return fmt.Errorf("restore shard %d: %s", req.ShardID, err)
+	}
+	return nil
+}
+
+// processBackupShardRequest streams a backup of a local shard to the connection.
+func (s *Service) processBackupShardRequest(conn net.Conn) {
+	defer conn.Close()
 
-		tlv.EncodeTLV(conn, tlv.CreateIteratorResponseMessage, nil)
+	var req rpc.BackupShardRequest
+	if err := s.decodeRequest(conn, &req); err != nil {
+		s.Logger.Warn("backup shard failed", zap.Stringer("peer", conn.RemoteAddr()), zap.Error(err))
+		if !isFrameTooLarge(err) {
+			tlv.EncodeTLV(conn, tlv.BackupShardResponseMessage, &rpc.BackupShardResponse{Err: err.Error()})
+		}
 		return
 	}
 
+	// Only the files changed since the base snapshot are streamed if this
+	// node took it. Otherwise the snapshot is full.
+	id, base := s.snapshots.begin(req.ShardID, req.BaseSnapshotID)
+	since := req.Since
+	if base != 0 {
+		since = time.Unix(0, int64(base))
+	}
+
 	// Encode success response.
-	if err := tlv.EncodeTLV(conn, tlv.CreateIteratorResponseMessage, nil); err != nil {
-		s.Logger.Warn("error writing CreateIterator response: " + err.Error())
+	if err := tlv.EncodeTLV(conn, tlv.BackupShardResponseMessage, &rpc.BackupShardResponse{
+		SnapshotID:     id,
+		BaseSnapshotID: base,
+	}); err != nil {
+		s.Logger.Warn("unable to write response", zap.String("type", rpcName(tlv.BackupShardRequestMessage)), zap.Uint64("shardID", req.ShardID), zap.Stringer("peer", conn.RemoteAddr()), zap.Error(err))
 		return
 	}
 
-	// Exit if no iterator was produced.
-	if itr == nil {
+	// Stream shard to connection.
+	if err := s.backupShard(req.ShardID, since, conn, req.Checksums); err != nil {
+		s.Logger.Warn("shard backup stream failed", zap.Uint64("shardID", req.ShardID), zap.Stringer("peer", conn.RemoteAddr()), zap.Error(err))
 		return
 	}
+	s.snapshots.add(req.ShardID, id)
+}
+
+// processRemoveShardRequest deletes a local shard and removes this node from its owners.
+func (s *Service) processRemoveShardRequest(conn net.Conn) error {
+	var req rpc.RemoveShardRequest
+	if err := tlv.DecodeLV(conn, &req); err != nil {
+		return err
+	}
 
-	// Stream iterator to connection.
-	if err := influxql.NewIteratorEncoder(conn).EncodeIterator(itr); err != nil {
-		s.Logger.Warn("error encoding CreateIterator iterator: " + err.Error())
-		return
+	var resp rpc.RemoveShardResponse
+	err := func() error {
+		if _, _, si := s.MetaClient.ShardOwner(req.ShardID); len(si.Owners) == 1 && si.OwnedBy(s.Node.ID) {
+			return fmt.Errorf("shard %d is only owned by node %d", req.ShardID, s.Node.ID)
+		}
+		if err := s.MetaClient.RemoveShardOwner(req.ShardID, s.Node.ID); err != nil {
+			return err
+		}
+		s.snapshots.remove(req.ShardID)
+		if err := s.TSDBStore.DeleteShard(req.ShardID); err != nil {
+			return err
+		}
+		s.Events.Publish(Event{Type: EventShardDropped, NodeID: s.Node.ID, ShardID: req.ShardID})
+		return nil
+	}()
+	s.audit("remove shard", conn.RemoteAddr(), 0, err, zap.Uint64("shardID", req.ShardID))
+	if err != nil {
+		resp.Err = err.Error()
 	}
+
+	return tlv.EncodeTLV(conn, tlv.RemoveShardResponseMessage, &resp)
 }
 
-func (s *Service) processFieldDimensionsRequest(conn net.Conn) {
-	var fields, dimensions map[string]struct{}
-	if err := func() error {
-		// Parse request.
-		var req rpc.FieldDimensionsRequest
-		if err := tlv.DecodeLV(conn, &req); err != nil {
+// processTruncateShardsRequest ends all current shard groups at the requested
+// time, or after the requested delay if no time is given.
+func (s *Service) processTruncateShardsRequest(conn net.Conn) error {
+	var req rpc.TruncateShardsRequest
+	if err := tlv.DecodeLV(conn, &req); err != nil {
+		return err
+	}
+
+	t := req.Time
+	if t.IsZero() {
+		t = time.Now().Add(req.Delay)
+	}
+
+	var resp rpc.TruncateShardsResponse
+	if err := s.MetaClient.TruncateShardGroups(t.UTC()); err != nil {
+		resp.Err = err.Error()
+	}
+
+	return tlv.EncodeTLV(conn, tlv.TruncateShardsResponseMessage, &resp)
+}
+
+// processRemoveDataNodeRequest removes a data node from the cluster. Unless forced,
+// nodes holding the only copy of a shard are not removed.
+func (s *Service) processRemoveDataNodeRequest(conn net.Conn) error {
+	var req rpc.RemoveDataNodeRequest
+	if err := tlv.DecodeLV(conn, &req); err != nil {
+		return err
+	}
+
+	var resp rpc.RemoveDataNodeResponse
+	err := s.removeDataNode(req.TCPHost, req.Force)
+	s.audit("remove data node", conn.RemoteAddr(), 0, err,
+		zap.String("tcpHost", req.TCPHost),
+		zap.Bool("force", req.Force),
+	)
+	if err != nil {
+		resp.Err = err.Error()
+	}
+
+	return tlv.EncodeTLV(conn, tlv.RemoveDataNodeResponseMessage, &resp)
+}
+
+func (s *Service) removeDataNode(tcpHost string, force bool) error {
+	n, err := s.dataNodeByTCPHost(tcpHost)
+	if err != nil {
+		return err
+	}
+
+	if !force {
+		shards, err := s.shardInfos()
+		if err != nil {
 			return err
 		}
 
-		// Collect iterator creators for each shard.
-		// ics := make(influxql.Iterators, 0, len(req.ShardIDs))
-		// for _, shardID := range req.ShardIDs {
-		// 	ic := s.ShardIteratorCreator.ShardIteratorCreator(shardID)
-		// 	if ic == nil {
-		// 		return nil
-		// 	}
-		// 	// ics = append(ics, ic.CreateIterator(nil))
-		// }
+		for _, si := range shards {
+			if len(si.Owners) == 1 && si.Owners[0] == n.ID {
+				return fmt.Errorf("shard %d is only owned by node %d, use force to remove it", si.ID, n.ID)
+			}
+		}
+	}
 
-		// // Generate a single iterator from all shards.
-		// i, _ := ics.Merge(nil)
-		// f, d, err := influxql.FieldMapper.FieldDimensions(nil)
-		// // f, d, err := influxql.IteratorCreators(ics).FieldDimensions(req.Sources)
-		// if err != nil {
-		// 	return err
-		// }
-		// fields, dimensions = f, d
+	return s.MetaClient.DeleteDataNode(n.ID)
+}
 
-		return nil
+// processUpdateDataNodeRequest changes the TCP address of a data node.
+func (s *Service) processUpdateDataNodeRequest(conn net.Conn) error {
+	var req rpc.UpdateDataNodeRequest
+	if err := tlv.DecodeLV(conn, &req); err != nil {
+		return err
+	}
+
+	var resp rpc.UpdateDataNodeResponse
+	if err := func() error {
+		n, err := s.dataNodeByTCPHost(req.OldTCPHost)
+		if err != nil {
+			return err
+		}
+		return s.MetaClient.UpdateDataNode(n.ID, n.Host, req.NewTCPHost)
 	}(); err != nil {
-		s.Logger.Warn("error reading FieldDimensions request: " + err.Error())
-		tlv.EncodeTLV(conn, tlv.FieldDimensionsResponseMessage, nil)
-		return
+		resp.Err = err.Error()
 	}
 
-	// Encode success response.
-	if err := tlv.EncodeTLV(conn, tlv.FieldDimensionsResponseMessage, &rpc.FieldDimensionsResponse{
-		Fields:     fields,
-		Dimensions: dimensions,
-	}); err != nil {
-		s.Logger.Warn("error writing FieldDimensions response: " + err.Error())
-		return
+	return tlv.EncodeTLV(conn, tlv.UpdateDataNodeResponseMessage, &resp)
+}
+
+// processPauseReplicationRequest pauses or resumes writes from this node to another.
+func (s *Service) processPauseReplicationRequest(conn net.Conn) error {
+	var req rpc.PauseReplicationRequest
+	if err := tlv.DecodeLV(conn, &req); err != nil {
+		return err
+	}
+
+	var resp rpc.PauseReplicationResponse
+	if n, err := s.dataNodeByTCPHost(req.TCPHost); err != nil {
+		resp.Err = err.Error()
+	} else {
+		for _, r := range s.Replicators {
+			if req.Resume {
+				r.ResumeReplication(n.ID)
+			} else {
+				r.PauseReplication(n.ID)
+			}
+		}
+		if req.Resume {
+			s.Logger.Info("resumed replication", zap.Uint64("nodeID", n.ID))
+		} else {
+			s.Logger.Info("paused replication", zap.Uint64("nodeID", n.ID))
+		}
+	}
+
+	return tlv.EncodeTLV(conn, tlv.PauseReplicationResponseMessage, &resp)
+}
+
+func (s *Service) dataNodeByTCPHost(tcpHost string) (*meta.NodeInfo, error) {
+	nodes, err := s.MetaClient.DataNodes()
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range nodes {
+		if nodes[i].TCPHost == tcpHost {
+			return &nodes[i], nil
+		}
+	}
+	return nil, fmt.Errorf("data node not found: %s", tcpHost)
+}
+
+// processAuthStateRequest responds with a digest of the users cached on this node.
+func (s *Service) processAuthStateRequest(conn net.Conn) error {
+	var req rpc.AuthStateRequest
+	if err := tlv.DecodeLV(conn, &req); err != nil {
+		return err
 	}
+
+	users := s.MetaClient.Users()
+	return tlv.EncodeTLV(conn, tlv.AuthStateResponseMessage, &rpc.AuthStateResponse{
+		Hash:  UsersHash(users),
+		UserN: len(users),
+	})
 }
 
 func (s *Service) processJoinClusterRequest() {
@@ -370,9 +2237,84 @@ func (s *Service) processJoinClusterRequest() {
 func (s *Service) writeJoinClusterResponse() {
 
 }
-func (s *Service) importMetaData() {
 
+// processExportMetaDataRequest responds with a snapshot of the local meta store.
+func (s *Service) processExportMetaDataRequest(conn net.Conn) error {
+	var req rpc.ExportMetaDataRequest
+	if err := tlv.DecodeLV(conn, &req); err != nil {
+		return err
+	}
+
+	var resp rpc.ExportMetaDataResponse
+	if data, err := s.exportMetaData(); err != nil {
+		resp.Err = err.Error()
+	} else if buf, err := data.MarshalBinary(); err != nil {
+		resp.Err = err.Error()
+	} else {
+		resp.Data = buf
+		resp.ClusterID = data.Data.ClusterID
+		resp.Index = data.Data.Index
+	}
+
+	return tlv.EncodeTLV(conn, tlv.ExportMetaDataResponseMessage, &resp)
+}
+
+// exportMetaData returns a snapshot of the local meta store.
+func (s *Service) exportMetaData() (*cloudMeta.Data, error) {
+	if s.MetaStore == nil {
+		return nil, fmt.Errorf("meta store not available")
+	}
+
+	data := s.MetaStore.Data()
+	if data == nil || data.Data == nil {
+		return nil, fmt.Errorf("meta store has no data")
+	}
+	return data, nil
+}
+
+// ImportMetaData replaces the local meta store with a snapshot exported by
+// the data node at addr, so a joining node does not need a copy of the meta
+// directory. The snapshot must belong to the same cluster as the local meta
+// store, if it has one, and must be at least as recent.
+func (s *Service) ImportMetaData(addr string) error {
+	if s.MetaStore == nil {
+		return fmt.Errorf("meta store not available")
+	}
+
+	var resp rpc.ExportMetaDataResponse
+	if err := s.dialer.dialRPC(addr, s.dialTimeout, tlv.ExportMetaDataRequestMessage, &rpc.ExportMetaDataRequest{}, &resp); err != nil {
+		return err
+	} else if resp.Err != "" {
+		return fmt.Errorf("export meta data from %s: %s", addr, resp.Err)
+	}
+
+	data := &cloudMeta.Data{}
+	if err := data.UnmarshalBinary(resp.Data); err != nil {
+		return fmt.Errorf("unmarshal meta data from %s: %s", addr, err)
+	}
+
+	// Ensure the snapshot matches what the exporting node reported.
+	if data.Data.ClusterID != resp.ClusterID || data.Data.Index != resp.Index {
+		return fmt.Errorf("corrupt meta data from %s: cluster id %d index %d, expected cluster id %d index %d",
+			addr, data.Data.ClusterID, data.Data.Index, resp.ClusterID, resp.Index)
+	}
+
+	if local := s.MetaStore.Data(); local != nil && local.Data != nil {
+		if local.Data.ClusterID != 0 && local.Data.ClusterID != data.Data.ClusterID {
+			return ErrClusterIDMismatch
+		} else if data.Data.Index < local.Data.Index {
+			return ErrStaleMetaData
+		}
+	}
+
+	if err := s.MetaStore.SetData(data); err != nil {
+		return err
+	}
+
+	s.Logger.Info("imported meta data", zap.String("source", addr), zap.Uint64("index", data.Data.Index))
+	return nil
 }
+
 func (s *Service) processLeaveClusterRequest() {
 
 }
@@ -401,9 +2343,6 @@ func (s *Service) deleteSnapshot() {
 }
 func (s *Service) downloadShardSnapshot() {
 
-}
-func (s *Service) processShardStatusRequest() {
-
 }
 func (s *Service) processShowQueriesRequest() {
 
@@ -421,8 +2360,32 @@ func (s *Service) processShowTagValues() {
 
 }
 
-func (s *Service) processExecuteStatementRequest(buf []byte) error {
-	return nil
+// processExecuteStatementRequest executes the statement in an
+// ExecuteStatement request and returns the number of series it deleted.
+func (s *Service) processExecuteStatementRequest(buf []byte, peer net.Addr) (int64, error) {
+	var req rpc.ExecuteStatementRequest
+	if err := req.UnmarshalBinary(buf); err != nil {
+		return 0, err
+	}
+
+	stmt, err := influxql.ParseStatement(req.Statement())
+	if err != nil {
+		return 0, err
+	}
+
+	seriesN, err := s.executeStatement(stmt, req.Database())
+	if destructive(stmt) {
+		s.audit("execute statement", peer, req.NodeID(), err,
+			zap.String("statement", req.Statement()),
+			zap.String("database", req.Database()),
+			zap.String("requestID", req.RequestID()),
+		)
+	}
+	if err != nil {
+		return 0, err
+	}
+	s.Logger.Debug("executed statement", zap.String("requestID", req.RequestID()), zap.String("statement", req.Statement()), zap.Int64("seriesN", seriesN))
+	return seriesN, nil
 }
 
 // BufferedWriteCloser will
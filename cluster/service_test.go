@@ -1,17 +1,1945 @@
 package cluster_test
 
 import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding"
+	"encoding/binary"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
+	"math/big"
 	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
 	"time"
 
+	"github.com/influxdata/influxdb/coordinator"
+	"github.com/influxdata/influxdb/influxql"
 	"github.com/influxdata/influxdb/models"
 	"github.com/influxdata/influxdb/services/meta"
 	"github.com/influxdata/influxdb/tcp"
+	"github.com/influxdata/influxdb/toml"
+	"github.com/influxdata/influxdb/tsdb"
+	"github.com/zhexuany/influxcloud"
 	"github.com/zhexuany/influxcloud/cluster"
+	"github.com/zhexuany/influxcloud/hh"
+	cloudMeta "github.com/zhexuany/influxcloud/meta"
+	"github.com/zhexuany/influxcloud/rpc"
+	"github.com/zhexuany/influxcloud/tlv"
 )
 
+// Ensure the service lists every shard in the cluster with its owners.
+func TestService_ShowShards(t *testing.T) {
+	s := MustOpenService()
+	defer s.Close()
+
+	start := time.Unix(0, 0).UTC()
+	s.MetaClient.DatabasesFn = func() ([]meta.DatabaseInfo, error) {
+		return []meta.DatabaseInfo{{
+			Name: "db0",
+			RetentionPolicies: []meta.RetentionPolicyInfo{{
+				Name: "rp0",
+				ShardGroups: []meta.ShardGroupInfo{
+					{
+						ID:        1,
+						StartTime: start,
+						EndTime:   start.Add(time.Hour),
+						Shards: []meta.ShardInfo{
+							{ID: 10, Owners: []meta.ShardOwner{{NodeID: 1}, {NodeID: 2}}},
+						},
+					},
+					{
+						ID:        2,
+						DeletedAt: start,
+						Shards:    []meta.ShardInfo{{ID: 11}},
+					},
+				},
+			}},
+		}}, nil
+	}
+
+	var resp rpc.ShowShardsResponse
+	if err := s.Request(tlv.ShowShardsRequestMessage, &rpc.ShowShardsRequest{}, &resp); err != nil {
+		t.Fatal(err)
+	} else if resp.Err != "" {
+		t.Fatal(resp.Err)
+	}
+
+	exp := []rpc.ShardInfo{{
+		ID:           10,
+		Database:     "db0",
+		Policy:       "rp0",
+		ShardGroupID: 1,
+		StartTime:    start,
+		EndTime:      start.Add(time.Hour),
+		Owners:       []uint64{1, 2},
+	}}
+	if !reflect.DeepEqual(resp.Shards, exp) {
+		t.Fatalf("unexpected shards:\n\ngot=%+v\n\nexp=%+v", resp.Shards, exp)
+	}
+}
+
+// Ensure a shard can be copied from one node to another.
+func TestService_CopyShard(t *testing.T) {
+	src := MustOpenService()
+	defer src.Close()
+	src.TSDBStore.BackupShardFn = func(id uint64, since time.Time, w io.Writer) error {
+		if id != 10 {
+			t.Fatalf("unexpected shard id: %d", id)
+		}
+		_, err := w.Write(MustTarShardBackup("db0/rp0/10/000000001-000000001.tsm", "shard data"))
+		return err
+	}
+
+	dest := MustOpenService()
+	defer dest.Close()
+	dest.Node.ID = 2
+
+	var created bool
+	dest.TSDBStore.CreateShardFn = func(database, policy string, shardID uint64, enabled bool) error {
+		if database != "db0" || policy != "rp0" || shardID != 10 {
+			t.Fatalf("unexpected shard: %s %s %d", database, policy, shardID)
+		}
+		created = true
+		return nil
+	}
+	var restored string
+	dest.TSDBStore.RestoreShardFn = func(id uint64, r io.Reader) error {
+		buf, err := ioutil.ReadAll(r)
+		restored = string(buf)
+		return err
+	}
+	var owner uint64
+	dest.MetaClient.AddShardOwnerFn = func(shardID, nodeID uint64) error {
+		owner = nodeID
+		return nil
+	}
+
+	var resp rpc.CopyShardResponse
+	if err := dest.Request(tlv.CopyShardRequestMessage, &rpc.CopyShardRequest{
+		Source:   src.Addr().String(),
+		Dest:     dest.Addr().String(),
+		ShardID:  10,
+		Database: "db0",
+		Policy:   "rp0",
+	}, &resp); err != nil {
+		t.Fatal(err)
+	} else if resp.Err != "" {
+		t.Fatal(resp.Err)
+	}
+
+	if !created {
+		t.Fatal("expected shard to be created")
+	} else if data := MustReadShardBackup(restored); data != "shard data" {
+		t.Fatalf("unexpected restored data: %q", data)
+	} else if owner != 2 {
+		t.Fatalf("unexpected shard owner: %d", owner)
+	}
+}
+
+// Ensure a data node holding the only copy of a shard is not removed unless forced.
+func TestService_RemoveDataNode(t *testing.T) {
+	s := MustOpenService()
+	defer s.Close()
+
+	s.MetaClient.DataNodesFn = func() ([]meta.NodeInfo, error) {
+		return []meta.NodeInfo{{ID: 1, TCPHost: "host0:8088"}, {ID: 2, TCPHost: "host1:8088"}}, nil
+	}
+	s.MetaClient.DatabasesFn = func() ([]meta.DatabaseInfo, error) {
+		return []meta.DatabaseInfo{{
+			Name: "db0",
+			RetentionPolicies: []meta.RetentionPolicyInfo{{
+				Name: "rp0",
+				ShardGroups: []meta.ShardGroupInfo{{
+					ID:     1,
+					Shards: []meta.ShardInfo{{ID: 10, Owners: []meta.ShardOwner{{NodeID: 2}}}},
+				}},
+			}},
+		}}, nil
+	}
+	var deleted uint64
+	s.MetaClient.DeleteDataNodeFn = func(id uint64) error {
+		deleted = id
+		return nil
+	}
+
+	var resp rpc.RemoveDataNodeResponse
+	if err := s.Request(tlv.RemoveDataNodeRequestMessage, &rpc.RemoveDataNodeRequest{TCPHost: "host1:8088"}, &resp); err != nil {
+		t.Fatal(err)
+	} else if resp.Err == "" {
+		t.Fatal("expected error removing node with unreplicated shard")
+	} else if deleted != 0 {
+		t.Fatalf("unexpected node deleted: %d", deleted)
+	}
+
+	resp = rpc.RemoveDataNodeResponse{}
+	if err := s.Request(tlv.RemoveDataNodeRequestMessage, &rpc.RemoveDataNodeRequest{TCPHost: "host1:8088", Force: true}, &resp); err != nil {
+		t.Fatal(err)
+	} else if resp.Err != "" {
+		t.Fatal(resp.Err)
+	} else if deleted != 2 {
+		t.Fatalf("unexpected node deleted: %d", deleted)
+	}
+}
+
+// Ensure a dead data node is replaced by copying its shards to the new node
+// from their other owners, and redirecting the writes queued for it.
+func TestService_ReplaceDataNode(t *testing.T) {
+	s, src, dest := MustOpenService(), MustOpenService(), MustOpenService()
+	defer s.Close()
+	defer src.Close()
+	defer dest.Close()
+	src.Node.ID, dest.Node.ID = 2, 3
+
+	// Node 4 is dead. Shard 10 is copied from node 2, while shard 11 was
+	// not owned by node 4 and shard 12 is already owned by node 3.
+	s.MetaClient.DataNodesFn = func() ([]meta.NodeInfo, error) {
+		return []meta.NodeInfo{
+			{ID: 1, TCPHost: s.Addr().String()},
+			{ID: 2, TCPHost: src.Addr().String()},
+			{ID: 3, TCPHost: dest.Addr().String()},
+			{ID: 4, TCPHost: "127.0.0.1:0"},
+		}, nil
+	}
+	s.MetaClient.DatabasesFn = func() ([]meta.DatabaseInfo, error) {
+		return []meta.DatabaseInfo{{
+			Name: "db0",
+			RetentionPolicies: []meta.RetentionPolicyInfo{{
+				Name: "rp0",
+				ShardGroups: []meta.ShardGroupInfo{{
+					ID: 1,
+					Shards: []meta.ShardInfo{
+						{ID: 10, Owners: []meta.ShardOwner{{NodeID: 4}, {NodeID: 2}}},
+						{ID: 11, Owners: []meta.ShardOwner{{NodeID: 1}, {NodeID: 2}}},
+						{ID: 12, Owners: []meta.ShardOwner{{NodeID: 4}, {NodeID: 3}}},
+					},
+				}},
+			}},
+		}}, nil
+	}
+	var replaced [2]uint64
+	s.MetaClient.ReplaceDataNodeFn = func(oldID, newID uint64) error {
+		replaced = [2]uint64{oldID, newID}
+		return nil
+	}
+
+	src.TSDBStore.BackupShardFn = func(id uint64, since time.Time, w io.Writer) error {
+		_, err := w.Write(MustTarShardBackup(fmt.Sprintf("db0/rp0/%d/000000001-000000001.tsm", id), "shard data"))
+		return err
+	}
+	queued := queueSizes{4: 100}
+	src.Service.HintedHandoff = queued
+
+	dest.TSDBStore.CreateShardFn = func(database, policy string, shardID uint64, enabled bool) error { return nil }
+	dest.TSDBStore.RestoreShardFn = func(id uint64, r io.Reader) error {
+		_, err := io.Copy(ioutil.Discard, r)
+		return err
+	}
+	var owned []uint64
+	dest.MetaClient.AddShardOwnerFn = func(shardID, nodeID uint64) error {
+		owned = append(owned, shardID)
+		return nil
+	}
+
+	var resp rpc.ReplaceDataNodeResponse
+	if err := s.Request(tlv.ReplaceDataNodeRequestMessage, &rpc.ReplaceDataNodeRequest{
+		OldTCPHost: "127.0.0.1:0",
+		NewTCPHost: dest.Addr().String(),
+	}, &resp); err != nil {
+		t.Fatal(err)
+	} else if resp.Err != "" {
+		t.Fatal(resp.Err)
+	}
+
+	if !reflect.DeepEqual(resp.ShardIDs, []uint64{10}) {
+		t.Fatalf("unexpected shards copied: %v", resp.ShardIDs)
+	} else if !reflect.DeepEqual(owned, []uint64{10}) {
+		t.Fatalf("unexpected shards owned: %v", owned)
+	} else if !reflect.DeepEqual(queued, queueSizes{3: 100}) {
+		t.Fatalf("unexpected queues: %v", queued)
+	} else if replaced != [2]uint64{4, 3} {
+		t.Fatalf("unexpected replaced nodes: %v", replaced)
+	}
+}
+
+// Ensure connecting nodes are told the features both nodes support, and
+// nodes with an incompatible protocol version are refused.
+func TestService_Hello(t *testing.T) {
+	s := MustOpenService()
+	defer s.Close()
+	s.Node.ID, s.Version = 1, "1.1.0"
+
+	var resp rpc.HelloResponse
+	if err := s.Request(tlv.HelloRequestMessage, &rpc.HelloRequest{
+		NodeID:          2,
+		Version:         "1.0.0",
+		ProtocolVersion: rpc.ProtocolVersion,
+		Features:        rpc.FeatureCompression | rpc.FeaturePipelining,
+	}, &resp); err != nil {
+		t.Fatal(err)
+	} else if resp.Err != "" {
+		t.Fatal(resp.Err)
+	} else if resp.NodeID != 1 || resp.Version != "1.1.0" || resp.ProtocolVersion != rpc.ProtocolVersion {
+		t.Fatalf("unexpected response: %+v", resp)
+	} else if resp.Features != rpc.FeaturePipelining {
+		t.Fatalf("unexpected features: %s", resp.Features)
+	}
+
+	resp = rpc.HelloResponse{}
+	if err := s.Request(tlv.HelloRequestMessage, &rpc.HelloRequest{NodeID: 2, ProtocolVersion: rpc.MinProtocolVersion - 1}, &resp); err != nil {
+		t.Fatal(err)
+	} else if !strings.Contains(resp.Err, "incompatible peer 2") {
+		t.Fatalf("unexpected error: %q", resp.Err)
+	}
+}
+
+// Ensure connecting nodes of another cluster, or that do not send their
+// cluster ID, are refused once the cluster of the service is known, and that
+// they must say hello before anything else.
+func TestService_HelloClusterMismatch(t *testing.T) {
+	s := MustOpenService()
+	defer s.Close()
+
+	// Hello is optional until the cluster is known.
+	var resp rpc.ShowShardsResponse
+	s.MetaClient.DatabasesFn = func() ([]meta.DatabaseInfo, error) { return nil, nil }
+	if err := s.Request(tlv.ShowShardsRequestMessage, &rpc.ShowShardsRequest{}, &resp); err != nil {
+		t.Fatal(err)
+	}
+
+	s.MetaClient.ClusterIDFn = func() uint64 { return 100 }
+
+	events, cancel := s.Events.Subscribe(2)
+	defer cancel()
+
+	var hello rpc.HelloResponse
+	if err := s.Request(tlv.HelloRequestMessage, &rpc.HelloRequest{NodeID: 2, ProtocolVersion: rpc.ProtocolVersion, ClusterID: 100}, &hello); err != nil {
+		t.Fatal(err)
+	} else if hello.Err != "" {
+		t.Fatal(hello.Err)
+	} else if hello.ClusterID != 100 {
+		t.Fatalf("unexpected cluster ID: %d", hello.ClusterID)
+	}
+
+	for clusterID, exp := range map[uint64]string{
+		200: "node 2 belongs to cluster 200, not to cluster 100",
+		0:   "node 2 did not tell its cluster, cluster 100 required",
+	} {
+		var hello rpc.HelloResponse
+		if err := s.Request(tlv.HelloRequestMessage, &rpc.HelloRequest{NodeID: 2, ProtocolVersion: rpc.ProtocolVersion, ClusterID: clusterID}, &hello); err != nil {
+			t.Fatal(err)
+		} else if hello.Err != exp {
+			t.Fatalf("unexpected error: %q", hello.Err)
+		}
+		select {
+		case e := <-events:
+			if e.Type != cluster.EventClusterMismatch || e.NodeID != 2 {
+				t.Fatalf("unexpected event: %+v", e)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("expected cluster mismatch event")
+		}
+	}
+
+	// Requests sent before hello are refused.
+	conn, err := net.Dial("tcp", s.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if _, err := conn.Write([]byte{cluster.MuxHeader}); err != nil {
+		t.Fatal(err)
+	} else if err := tlv.EncodeTLV(conn, tlv.ShowShardsRequestMessage, &rpc.ShowShardsRequest{}); err != nil {
+		t.Fatal(err)
+	} else if _, err := tlv.DecodeTLV(conn, &resp); err == nil {
+		t.Fatal("expected error")
+	} else if e, ok := err.(*rpc.WriteShardError); !ok || e.Code != rpc.CodeAuthFailed || !strings.Contains(e.Message, "hello required") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+// Ensure requests of registered message types are dispatched to their
+// handler, and that builtin and reserved types cannot be registered.
+func TestService_RegisterHandler(t *testing.T) {
+	s := MustOpenService()
+	defer s.Close()
+
+	const echoRequestMessage, echoResponseMessage = 200, 201
+	if err := s.RegisterHandler(echoRequestMessage, "echo", func(conn net.Conn) error {
+		buf, err := tlv.ReadLV(conn)
+		if err != nil {
+			return err
+		}
+		return tlv.WriteTLV(conn, echoResponseMessage, buf)
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	var resp rpc.SpanContext
+	if err := s.Request(echoRequestMessage, &rpc.SpanContext{Carrier: map[string]string{"k": "v"}}, &resp); err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(resp.Carrier, map[string]string{"k": "v"}) {
+		t.Fatalf("unexpected response: %+v", resp)
+	}
+
+	noop := func(conn net.Conn) error { return nil }
+	if err := s.RegisterHandler(echoRequestMessage, "echo", noop); err == nil {
+		t.Fatal("expected error registering a type twice")
+	} else if err := s.RegisterHandler(tlv.WriteShardRequestMessage, "write", noop); err == nil {
+		t.Fatal("expected error registering a builtin type")
+	} else if err := s.RegisterHandler(tlv.HelloRequestMessage, "hello", noop); err == nil {
+		t.Fatal("expected error registering a reserved type")
+	}
+}
+
+// Ensure requests on a connection are processed concurrently, and that their
+// responses are written in the order the requests were received.
+func TestService_ConcurrentRequests(t *testing.T) {
+	s := NewService()
+	s.Service = cluster.NewService(cluster.Config{MaxConnectionRequests: 4})
+	s.Service.Node = &influxcloud.Node{ID: 1}
+	s.Service.TSDBStore = &s.TSDBStore
+	s.Service.MetaClient = &s.MetaClient
+	s.ln = MustListen("tcp", "127.0.0.1:0")
+	s.Listener = &muxListener{s.ln}
+	if err := s.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	// Dropping db0 blocks until db1 is dropped, which would never happen if
+	// the requests were processed one at a time.
+	db1 := make(chan struct{})
+	s.TSDBStore.DeleteDatabaseFn = func(name string) error {
+		switch name {
+		case "db0":
+			select {
+			case <-db1:
+				return errors.New("db0 failed")
+			case <-time.After(5 * time.Second):
+				return errors.New("timeout")
+			}
+		default:
+			close(db1)
+			return nil
+		}
+	}
+
+	conn, err := net.Dial("tcp", s.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if _, err := conn.Write([]byte{cluster.MuxHeader}); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"db0", "db1"} {
+		var req rpc.ExecuteStatementRequest
+		req.SetDatabase(name)
+		req.SetStatement("DROP DATABASE " + name)
+		req.SetRequestID(name)
+		if err := tlv.EncodeTLV(conn, tlv.ExecuteStatementRequestMessage, &req); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for i, msg := range []string{"db0 failed", ""} {
+		var resp rpc.ExecuteStatementResponse
+		if _, err := tlv.DecodeTLV(conn, &resp); err != nil {
+			t.Fatal(err)
+		} else if resp.Message() != msg {
+			t.Fatalf("unexpected response %d: %q", i, resp.Message())
+		}
+	}
+}
+
+// Ensure a request whose handler panics is answered with an internal error,
+// and that later requests on the connection are still processed.
+func TestService_HandlerPanic(t *testing.T) {
+	s := MustOpenService()
+	defer s.Close()
+
+	s.TSDBStore.DeleteDatabaseFn = func(name string) error {
+		panic("malformed request")
+	}
+
+	conn, err := net.Dial("tcp", s.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if _, err := conn.Write([]byte{cluster.MuxHeader}); err != nil {
+		t.Fatal(err)
+	}
+
+	var req rpc.ExecuteStatementRequest
+	req.SetDatabase("db0")
+	req.SetStatement("DROP DATABASE db0")
+	req.SetRequestID("0")
+	var resp rpc.ExecuteStatementResponse
+	if err := tlv.EncodeTLV(conn, tlv.ExecuteStatementRequestMessage, &req); err != nil {
+		t.Fatal(err)
+	} else if _, err := tlv.DecodeTLV(conn, &resp); err == nil {
+		t.Fatal("expected error")
+	} else if e, ok := err.(*rpc.WriteShardError); !ok || e.Code != rpc.CodeInternal || !strings.Contains(e.Message, "malformed request") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := tlv.WriteTLV(conn, tlv.PingRequestMessage, nil); err != nil {
+		t.Fatal(err)
+	} else if typ, _, err := tlv.ReadTLV(conn); err != nil {
+		t.Fatal(err)
+	} else if typ != tlv.PingResponseMessage {
+		t.Fatalf("unexpected response type: %d", typ)
+	}
+
+	var buf bytes.Buffer
+	if _, err := s.Metrics.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	} else if !strings.Contains(buf.String(), `influxcloud_rpc_panics_total{type="executeStatement"} 1`) {
+		t.Fatalf("unexpected metrics: %s", buf.String())
+	}
+}
+
+// Ensure requests and connection protocol messages larger than the maximum
+// message size are rejected before they are read, whether they are
+// processed concurrently or one at a time.
+func TestService_MaxMessageSize(t *testing.T) {
+	s := NewService()
+	s.Service = cluster.NewService(cluster.Config{MaxMessageSize: 1024})
+	s.Service.Node = &influxcloud.Node{ID: 1}
+	s.Service.TSDBStore = &s.TSDBStore
+	s.Service.MetaClient = &s.MetaClient
+	s.ln = MustListen("tcp", "127.0.0.1:0")
+	s.Listener = &muxListener{s.ln}
+	if err := s.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	for _, typ := range []byte{
+		tlv.ExecuteStatementRequestMessage,
+		tlv.SpanContextMessage,
+		tlv.AdminTokenMessage,
+		tlv.MultiplexRequestMessage,
+		tlv.HelloRequestMessage,
+		tlv.CreateIteratorRequestMessage,
+		tlv.FieldDimensionsRequestMessage,
+		tlv.BackupShardRequestMessage,
+		tlv.RestoreShardRequestMessage,
+	} {
+		func() {
+			conn, err := net.Dial("tcp", s.Addr().String())
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer conn.Close()
+
+			// Only the length prefix is sent.
+			if _, err := conn.Write([]byte{cluster.MuxHeader, typ}); err != nil {
+				t.Fatal(err)
+			} else if err := binary.Write(conn, binary.BigEndian, int64(2048)); err != nil {
+				t.Fatal(err)
+			}
+
+			var resp rpc.ErrorResponse
+			if _, err := tlv.DecodeTLV(conn, &resp); err == nil {
+				t.Fatalf("expected error for message type %d", typ)
+			} else if e, ok := err.(*rpc.WriteShardError); !ok || e.Code != rpc.CodeFrameTooLarge {
+				t.Fatalf("unexpected error for message type %d: %v", typ, err)
+			}
+		}()
+	}
+}
+
+// Ensure destructive operations requested by other nodes are recorded to the
+// audit log, along with the node they originate from and their outcome.
+func TestService_AuditLog(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cluster-audit-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "audit.log")
+
+	s := NewService()
+	s.Service = cluster.NewService(cluster.Config{AuditLogPath: path})
+	s.Service.Node = &influxcloud.Node{ID: 1}
+	s.Service.TSDBStore = &s.TSDBStore
+	s.Service.MetaClient = &s.MetaClient
+	s.ln = MustListen("tcp", "127.0.0.1:0")
+	s.Listener = &muxListener{s.ln}
+	if err := s.Open(); err != nil {
+		t.Fatal(err)
+	}
+
+	s.TSDBStore.DeleteDatabaseFn = func(name string) error { return nil }
+	s.MetaClient.ShardOwnerFn = func(shardID uint64) (string, string, meta.ShardInfo) { return "", "", meta.ShardInfo{} }
+	s.MetaClient.RemoveShardOwnerFn = func(shardID, nodeID uint64) error {
+		return errors.New("shard not found")
+	}
+
+	var req rpc.ExecuteStatementRequest
+	req.SetDatabase("db0")
+	req.SetStatement("DROP DATABASE db0")
+	req.SetRequestID("req0")
+	req.SetNodeID(2)
+	if err := s.Request(tlv.ExecuteStatementRequestMessage, &req, &rpc.ExecuteStatementResponse{}); err != nil {
+		t.Fatal(err)
+	}
+
+	var resp rpc.RemoveShardResponse
+	if err := s.Request(tlv.RemoveShardRequestMessage, &rpc.RemoveShardRequest{ShardID: 10}, &resp); err != nil {
+		t.Fatal(err)
+	} else if resp.Err == "" {
+		t.Fatal("expected error")
+	}
+	s.Close()
+
+	buf, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(buf)), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("unexpected audit log: %s", buf)
+	}
+
+	var records [2]map[string]interface{}
+	for i, line := range lines {
+		if err := json.Unmarshal([]byte(line), &records[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if r := records[0]; r["msg"] != "execute statement" || r["statement"] != "DROP DATABASE db0" || r["database"] != "db0" ||
+		r["requestID"] != "req0" || r["originNodeID"] != float64(2) || r["nodeID"] != float64(1) || r["result"] != "ok" {
+		t.Fatalf("unexpected statement record: %s", lines[0])
+	} else if r["peer"] == nil || r["time"] == nil {
+		t.Fatalf("expected peer and time: %s", lines[0])
+	}
+	if r := records[1]; r["msg"] != "remove shard" || r["shardID"] != float64(10) || r["result"] != "failed" || r["error"] != "shard not found" {
+		t.Fatalf("unexpected remove shard record: %s", lines[1])
+	}
+}
+
+// Ensure the RPCs that mutate the cluster are only processed after a token
+// with the admin role once admin tokens are configured.
+func TestService_AdminTokens(t *testing.T) {
+	s := NewService()
+	s.Service = cluster.NewService(cluster.Config{AdminTokens: []cluster.AdminTokenConfig{
+		{Token: "s3cr3t", Role: cluster.RoleAdmin},
+		{Token: "monitoring", Role: cluster.RoleReadOnly},
+	}})
+	s.Service.Node = &influxcloud.Node{ID: 1}
+	s.Service.TSDBStore = &s.TSDBStore
+	s.Service.MetaClient = &s.MetaClient
+	s.ln = MustListen("tcp", "127.0.0.1:0")
+	s.Listener = &muxListener{s.ln}
+	if err := s.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	var removed []uint64
+	s.MetaClient.ShardOwnerFn = func(shardID uint64) (string, string, meta.ShardInfo) { return "", "", meta.ShardInfo{} }
+	s.MetaClient.RemoveShardOwnerFn = func(shardID, nodeID uint64) error {
+		removed = append(removed, shardID)
+		return errors.New("shard not found")
+	}
+	s.MetaClient.DatabasesFn = func() ([]meta.DatabaseInfo, error) { return nil, nil }
+
+	// request sends req after token, if any, and returns the error code the
+	// service rejected it with, if any.
+	request := func(token string, typ byte, req encoding.BinaryMarshaler, resp encoding.BinaryUnmarshaler) rpc.ErrorCode {
+		conn, err := net.Dial("tcp", s.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+
+		if _, err := conn.Write([]byte{cluster.MuxHeader}); err != nil {
+			t.Fatal(err)
+		}
+		if token != "" {
+			if err := tlv.EncodeTLV(conn, tlv.AdminTokenMessage, &rpc.AdminToken{Token: token}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := tlv.EncodeTLV(conn, typ, req); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tlv.DecodeTLV(conn, resp); err != nil {
+			if e, ok := err.(*rpc.WriteShardError); ok {
+				return e.Code
+			}
+			t.Fatal(err)
+		}
+		return rpc.CodeOK
+	}
+
+	for _, token := range []string{"", "monitoring", "guess"} {
+		if code := request(token, tlv.RemoveShardRequestMessage, &rpc.RemoveShardRequest{ShardID: 10}, &rpc.RemoveShardResponse{}); code != rpc.CodeAuthFailed {
+			t.Fatalf("unexpected code with token %q: %s", token, code)
+		}
+	}
+	if len(removed) != 0 {
+		t.Fatalf("unexpected shards removed: %v", removed)
+	}
+	if code := request("s3cr3t", tlv.RemoveShardRequestMessage, &rpc.RemoveShardRequest{ShardID: 10}, &rpc.RemoveShardResponse{}); code != rpc.CodeOK {
+		t.Fatalf("unexpected code: %s", code)
+	} else if !reflect.DeepEqual(removed, []uint64{10}) {
+		t.Fatalf("unexpected shards removed: %v", removed)
+	}
+
+	// Statements deleting data are admin RPCs too.
+	var dropped []string
+	s.TSDBStore.DeleteDatabaseFn = func(name string) error {
+		dropped = append(dropped, name)
+		return nil
+	}
+	var drop rpc.ExecuteStatementRequest
+	drop.SetDatabase("db0")
+	drop.SetStatement("DROP DATABASE db0")
+	for _, token := range []string{"", "monitoring"} {
+		if code := request(token, tlv.ExecuteStatementRequestMessage, &drop, &rpc.ExecuteStatementResponse{}); code != rpc.CodeAuthFailed {
+			t.Fatalf("unexpected code with token %q: %s", token, code)
+		}
+	}
+	if len(dropped) != 0 {
+		t.Fatalf("unexpected databases dropped: %v", dropped)
+	}
+
+	// The node's own Dialer sends its admin token.
+	conn, err := s.Service.Dialer().Dial(s.Addr().String(), time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	var resp rpc.ExecuteStatementResponse
+	if err := tlv.EncodeTLV(conn, tlv.ExecuteStatementRequestMessage, &drop); err != nil {
+		t.Fatal(err)
+	} else if _, err := tlv.DecodeTLV(conn, &resp); err != nil {
+		t.Fatal(err)
+	} else if resp.Code() != int(rpc.CodeOK) || !reflect.DeepEqual(dropped, []string{"db0"}) {
+		t.Fatalf("unexpected response: %d %q, dropped %v", resp.Code(), resp.Message(), dropped)
+	}
+
+	// Status RPCs need no token, but an unknown token is refused.
+	for token, exp := range map[string]rpc.ErrorCode{"": rpc.CodeOK, "monitoring": rpc.CodeOK, "guess": rpc.CodeAuthFailed} {
+		if code := request(token, tlv.ShowShardsRequestMessage, &rpc.ShowShardsRequest{}, &rpc.ShowShardsResponse{}); code != exp {
+			t.Fatalf("unexpected code with token %q: %s", token, code)
+		}
+	}
+}
+
+// Ensure the service is served over TLS under its policy once enabled, and
+// that handshakes violating the policy are refused.
+func TestService_TLS(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cluster-tls-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	cert, key := MustWriteCertificate(dir, "127.0.0.1")
+
+	s := MustOpenTLSService(cluster.TLSConfig{
+		Enabled:     true,
+		Certificate: cert,
+		PrivateKey:  key,
+		CA:          cert,
+		Preset:      cluster.TLSPresetFIPS,
+	})
+	defer s.Close()
+
+	// ping pings the service over a connection dialed with c.
+	ping := func(c cluster.TLSConfig) error {
+		conn, err := s.DialTLS(c)
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+
+		if err := tlv.WriteTLV(conn, tlv.PingRequestMessage, nil); err != nil {
+			return err
+		} else if typ, _, err := tlv.ReadTLV(conn); err != nil {
+			return err
+		} else if typ != tlv.PingResponseMessage {
+			t.Fatalf("unexpected response type: %d", typ)
+		}
+		return nil
+	}
+
+	if err := ping(cluster.TLSConfig{Enabled: true, Certificate: cert, PrivateKey: key, CA: cert}); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, c := range map[string]cluster.TLSConfig{
+		"plaintext":      {},
+		"no certificate": {Enabled: true, CA: cert},
+		"tls1.1":         {Enabled: true, Certificate: cert, PrivateKey: key, CA: cert, MinVersion: "tls1.0", MaxVersion: "tls1.1"},
+		"tls1.3":         {Enabled: true, Certificate: cert, PrivateKey: key, CA: cert, MinVersion: "tls1.3"},
+		"cipher":         {Enabled: true, Certificate: cert, PrivateKey: key, CA: cert, MaxVersion: "tls1.2", Ciphers: []string{"TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305_SHA256"}},
+	} {
+		if err := ping(c); err == nil {
+			t.Fatalf("expected %s connection to be refused", name)
+		}
+	}
+}
+
+// Ensure mutual TLS connections are refused unless the certificate presented
+// is valid for a registered data node, and the node said hello as.
+func TestService_PeerIdentity(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cluster-tls-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	cert, key := MustWriteCertificate(dir, "127.0.0.1")
+	otherCert, otherKey := MustWriteCertificate(dir, "localhost")
+
+	// Both certificates are signed by a trusted authority.
+	ca := filepath.Join(dir, "ca.crt")
+	if a, err := ioutil.ReadFile(cert); err != nil {
+		t.Fatal(err)
+	} else if b, err := ioutil.ReadFile(otherCert); err != nil {
+		t.Fatal(err)
+	} else if err := ioutil.WriteFile(ca, append(a, b...), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	s := MustOpenTLSService(cluster.TLSConfig{Enabled: true, Certificate: cert, PrivateKey: key, CA: ca})
+	defer s.Close()
+
+	events, cancel := s.Events.Subscribe(4)
+	defer cancel()
+
+	// hello says hello as nodeID over a connection dialed with c.
+	hello := func(c cluster.TLSConfig, nodeID uint64) (string, error) {
+		conn, err := s.DialTLS(c)
+		if err != nil {
+			return "", err
+		}
+		defer conn.Close()
+
+		var resp rpc.HelloResponse
+		if err := tlv.EncodeTLV(conn, tlv.HelloRequestMessage, &rpc.HelloRequest{NodeID: nodeID, ProtocolVersion: rpc.ProtocolVersion}); err != nil {
+			return "", err
+		} else if _, err := tlv.DecodeTLV(conn, &resp); err != nil {
+			return "", err
+		}
+		return resp.Err, nil
+	}
+
+	// The certificate of 127.0.0.1 is valid for node 2 only.
+	c := cluster.TLSConfig{Enabled: true, Certificate: cert, PrivateKey: key, CA: ca}
+	if reason, err := hello(c, 2); err != nil {
+		t.Fatal(err)
+	} else if reason != "" {
+		t.Fatal(reason)
+	}
+	for _, nodeID := range []uint64{0, 1} {
+		if reason, err := hello(c, nodeID); err != nil {
+			t.Fatal(err)
+		} else if !strings.Contains(reason, "peer identity mismatch") {
+			t.Fatalf("unexpected reason for node %d: %q", nodeID, reason)
+		}
+	}
+
+	// The certificate of localhost is not valid for any node, and is refused
+	// before the hello is read.
+	if _, err := hello(cluster.TLSConfig{Enabled: true, Certificate: otherCert, PrivateKey: otherKey, CA: ca}, 2); err == nil {
+		t.Fatal("expected connection to be refused")
+	}
+
+	for i := 0; i < 3; i++ {
+		select {
+		case e := <-events:
+			if e.Type != cluster.EventPeerIdentityMismatch {
+				t.Fatalf("unexpected event: %+v", e)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("expected peer identity mismatch event")
+		}
+	}
+}
+
+// Ensure mutual TLS connections are refused rather than panicking when the
+// service has no meta client to verify peer identity against.
+func TestService_PeerIdentity_NoMetaClient(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cluster-tls-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	cert, key := MustWriteCertificate(dir, "127.0.0.1")
+	c := cluster.TLSConfig{Enabled: true, Certificate: cert, PrivateKey: key, CA: cert}
+
+	s := NewService()
+	s.Service = cluster.NewService(cluster.Config{DialTimeout: toml.Duration(time.Second), TLS: c})
+	s.Service.TSDBStore = &s.TSDBStore
+	s.ln = MustListen("tcp", "127.0.0.1:0")
+	s.Listener = &muxListener{s.ln}
+	if err := s.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	conn, err := s.DialTLS(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	if err := tlv.WriteTLV(conn, tlv.PingRequestMessage, nil); err != nil {
+		t.Fatal(err)
+	} else if _, _, err := tlv.ReadTLV(conn); err == nil {
+		t.Fatal("expected connection to be refused")
+	}
+}
+
+// Ensure writes corrupted in transit on a multiplexed connection are rejected
+// once both nodes negotiated checksums.
+func TestService_MuxChecksum(t *testing.T) {
+	s := MustOpenService()
+	defer s.Close()
+	var writeN int
+	s.TSDBStore.WriteToShardFn = func(shardID uint64, points []models.Point) error {
+		writeN++
+		return nil
+	}
+
+	conn, err := net.Dial("tcp", s.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	var hello rpc.HelloResponse
+	if _, err := conn.Write([]byte{cluster.MuxHeader}); err != nil {
+		t.Fatal(err)
+	} else if err := tlv.EncodeTLV(conn, tlv.HelloRequestMessage, &rpc.HelloRequest{NodeID: 2, ProtocolVersion: rpc.ProtocolVersion, Features: rpc.SupportedFeatures}); err != nil {
+		t.Fatal(err)
+	} else if _, err := tlv.DecodeTLV(conn, &hello); err != nil {
+		t.Fatal(err)
+	} else if !hello.Features.Has(rpc.FeatureChecksum) {
+		t.Fatalf("unexpected features: %s", hello.Features)
+	} else if err := tlv.WriteTLV(conn, tlv.MultiplexRequestMessage, nil); err != nil {
+		t.Fatal(err)
+	} else if _, _, err := tlv.ReadTLV(conn); err != nil {
+		t.Fatal(err)
+	}
+
+	points, err := rpc.EncodePoints([]models.Point{models.MustNewPoint("cpu", newTags(), newFields(), time.Now())})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var req rpc.WriteShardRequest
+	req.SetShardID(1)
+	req.SetEncodedPoints(points)
+	buf, err := req.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range []struct {
+		corrupt bool
+		code    rpc.ErrorCode
+	}{
+		{corrupt: true, code: rpc.CodeChecksumMismatch},
+		{corrupt: false, code: rpc.CodeOK},
+	} {
+		var frame bytes.Buffer
+		if err := tlv.WriteTaggedTLVChecksum(&frame, tlv.WriteShardRequestMessage, 1, buf); err != nil {
+			t.Fatal(err)
+		}
+		b := frame.Bytes()
+		if tt.corrupt {
+			b[len(b)-5] ^= 0xff // the last byte of the value
+		}
+		if _, err := conn.Write(b); err != nil {
+			t.Fatal(err)
+		}
+
+		var resp rpc.WriteShardResponse
+		if typ, tag, rbuf, err := tlv.ReadTaggedTLV(conn); err != nil {
+			t.Fatal(err)
+		} else if typ != tlv.WriteShardResponseMessage || tag != 1 {
+			t.Fatalf("unexpected response: type=%d tag=%d", typ, tag)
+		} else if err := resp.UnmarshalBinary(rbuf); err != nil {
+			t.Fatal(err)
+		} else if rpc.ErrorCode(resp.Code()) != tt.code {
+			t.Fatalf("unexpected response code: %s", rpc.ErrorCode(resp.Code()))
+		}
+	}
+	if writeN != 1 {
+		t.Fatalf("unexpected write count: %d", writeN)
+	}
+}
+
+// Ensure no more writes on a multiplexed connection are processed at once
+// than allowed, and that the others wait for one to complete.
+func TestService_MuxConnectionRequests(t *testing.T) {
+	s := NewService()
+	s.Service = cluster.NewService(cluster.Config{MaxMuxConnectionRequests: 2})
+	s.Service.Node = &influxcloud.Node{ID: 1}
+	s.Service.TSDBStore = &s.TSDBStore
+	s.Service.MetaClient = &s.MetaClient
+	s.ln = MustListen("tcp", "127.0.0.1:0")
+	s.Listener = &muxListener{s.ln}
+	if err := s.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	started, release := make(chan struct{}, 4), make(chan struct{})
+	s.TSDBStore.WriteToShardFn = func(shardID uint64, points []models.Point) error {
+		started <- struct{}{}
+		<-release
+		return nil
+	}
+
+	conn, err := net.Dial("tcp", s.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.Write([]byte{cluster.MuxHeader}); err != nil {
+		t.Fatal(err)
+	} else if err := tlv.EncodeTLV(conn, tlv.HelloRequestMessage, &rpc.HelloRequest{NodeID: 2, ProtocolVersion: rpc.ProtocolVersion}); err != nil {
+		t.Fatal(err)
+	} else if _, err := tlv.DecodeTLV(conn, &rpc.HelloResponse{}); err != nil {
+		t.Fatal(err)
+	} else if err := tlv.WriteTLV(conn, tlv.MultiplexRequestMessage, nil); err != nil {
+		t.Fatal(err)
+	} else if _, _, err := tlv.ReadTLV(conn); err != nil {
+		t.Fatal(err)
+	}
+
+	points, err := rpc.EncodePoints([]models.Point{models.MustNewPoint("cpu", newTags(), newFields(), time.Now())})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var req rpc.WriteShardRequest
+	req.SetShardID(1)
+	req.SetEncodedPoints(points)
+	buf, err := req.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for tag := uint64(1); tag <= 4; tag++ {
+		if err := tlv.WriteTaggedTLV(conn, tlv.WriteShardRequestMessage, tag, buf); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Two writes are processed, and the third waits for one of them.
+	for i := 0; i < 2; i++ {
+		select {
+		case <-started:
+		case <-time.After(5 * time.Second):
+			t.Fatal("writes were not processed concurrently")
+		}
+	}
+	select {
+	case <-started:
+		t.Fatal("more writes processed at once than allowed")
+	case <-time.After(100 * time.Millisecond):
+	}
+	release <- struct{}{}
+	select {
+	case <-started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("write not processed once another completed")
+	}
+	close(release)
+
+	for i := 0; i < 4; i++ {
+		var resp rpc.WriteShardResponse
+		if typ, _, rbuf, err := tlv.ReadTaggedTLV(conn); err != nil {
+			t.Fatal(err)
+		} else if typ != tlv.WriteShardResponseMessage {
+			t.Fatalf("unexpected response type: %d", typ)
+		} else if err := resp.UnmarshalBinary(rbuf); err != nil {
+			t.Fatal(err)
+		} else if rpc.ErrorCode(resp.Code()) != rpc.CodeOK {
+			t.Fatalf("unexpected response code: %s", rpc.ErrorCode(resp.Code()))
+		}
+	}
+}
+
+// Ensure replication to a single node can be paused and resumed.
+func TestService_PauseReplication(t *testing.T) {
+	s := MustOpenService()
+	defer s.Close()
+
+	s.MetaClient.DataNodesFn = func() ([]meta.NodeInfo, error) {
+		return []meta.NodeInfo{{ID: 1, TCPHost: "host0:8088"}, {ID: 2, TCPHost: "host1:8088"}}, nil
+	}
+	r := replicator{}
+	s.Service.Replicators = []cluster.Replicator{r}
+
+	var resp rpc.PauseReplicationResponse
+	if err := s.Request(tlv.PauseReplicationRequestMessage, &rpc.PauseReplicationRequest{TCPHost: "host1:8088"}, &resp); err != nil {
+		t.Fatal(err)
+	} else if resp.Err != "" {
+		t.Fatal(resp.Err)
+	} else if !reflect.DeepEqual(r, replicator{2: true}) {
+		t.Fatalf("unexpected paused nodes: %v", r)
+	}
+
+	resp = rpc.PauseReplicationResponse{}
+	if err := s.Request(tlv.PauseReplicationRequestMessage, &rpc.PauseReplicationRequest{TCPHost: "host1:8088", Resume: true}, &resp); err != nil {
+		t.Fatal(err)
+	} else if resp.Err != "" {
+		t.Fatal(resp.Err)
+	} else if !reflect.DeepEqual(r, replicator{2: false}) {
+		t.Fatalf("unexpected paused nodes: %v", r)
+	}
+
+	resp = rpc.PauseReplicationResponse{}
+	if err := s.Request(tlv.PauseReplicationRequestMessage, &rpc.PauseReplicationRequest{TCPHost: "host2:8088"}, &resp); err != nil {
+		t.Fatal(err)
+	} else if resp.Err == "" {
+		t.Fatal("expected error pausing unknown node")
+	}
+}
+
+// Ensure data nodes are put into maintenance mode and taken out of it.
+func TestService_SetMaintenance(t *testing.T) {
+	s := MustOpenService()
+	defer s.Close()
+
+	s.MetaClient.DataNodesFn = func() ([]meta.NodeInfo, error) {
+		return []meta.NodeInfo{{ID: 1, TCPHost: "host0:8088"}, {ID: 2, TCPHost: "host1:8088"}}, nil
+	}
+	maintenance := make(map[uint64]bool)
+	s.MetaClient.SetDataNodeMaintenanceFn = func(id uint64, v bool) error {
+		maintenance[id] = v
+		return nil
+	}
+
+	var resp rpc.SetMaintenanceResponse
+	if err := s.Request(tlv.SetMaintenanceRequestMessage, &rpc.SetMaintenanceRequest{TCPHost: "host1:8088", Maintenance: true}, &resp); err != nil {
+		t.Fatal(err)
+	} else if resp.Err != "" {
+		t.Fatal(resp.Err)
+	} else if !reflect.DeepEqual(maintenance, map[uint64]bool{2: true}) {
+		t.Fatalf("unexpected maintenance: %v", maintenance)
+	}
+
+	resp = rpc.SetMaintenanceResponse{}
+	if err := s.Request(tlv.SetMaintenanceRequestMessage, &rpc.SetMaintenanceRequest{TCPHost: "host1:8088"}, &resp); err != nil {
+		t.Fatal(err)
+	} else if resp.Err != "" {
+		t.Fatal(resp.Err)
+	} else if !reflect.DeepEqual(maintenance, map[uint64]bool{2: false}) {
+		t.Fatalf("unexpected maintenance: %v", maintenance)
+	}
+
+	resp = rpc.SetMaintenanceResponse{}
+	if err := s.Request(tlv.SetMaintenanceRequestMessage, &rpc.SetMaintenanceRequest{TCPHost: "host2:8088", Maintenance: true}, &resp); err != nil {
+		t.Fatal(err)
+	} else if resp.Err == "" {
+		t.Fatal("expected error for unknown node")
+	}
+}
+
+// replicator records whether replication to each node is paused.
+type replicator map[uint64]bool
+
+func (r replicator) PauseReplication(nodeID uint64)  { r[nodeID] = true }
+func (r replicator) ResumeReplication(nodeID uint64) { r[nodeID] = false }
+
+// Ensure draining rejects new writes, pauses replication on peers, and drains
+// the service's drainers.
+func TestService_Drain(t *testing.T) {
+	s0, s1 := MustOpenService(), MustOpenService()
+	defer s0.Close()
+	defer s1.Close()
+	s1.Service.Node = &influxcloud.Node{ID: 2}
+
+	dataNodes := func() ([]meta.NodeInfo, error) {
+		return []meta.NodeInfo{
+			{ID: 1, TCPHost: s0.Addr().String()},
+			{ID: 2, TCPHost: s1.Addr().String()},
+		}, nil
+	}
+	s0.MetaClient.DataNodesFn = dataNodes
+	s1.MetaClient.DataNodesFn = dataNodes
+
+	r := replicator{}
+	s1.Service.Replicators = []cluster.Replicator{r}
+
+	var drained int
+	s0.Service.Drainers = []cluster.Drainer{drainer(func() error { drained++; return nil })}
+
+	if err := s0.Drain(); err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(r, replicator{1: true}) {
+		t.Fatalf("unexpected paused nodes: %v", r)
+	} else if drained != 1 {
+		t.Fatalf("unexpected drain count: %d", drained)
+	}
+
+	req := &rpc.WriteShardRequest{}
+	req.SetShardID(1)
+	var resp rpc.WriteShardResponse
+	if err := s0.Request(tlv.WriteShardRequestMessage, req, &resp); err != nil {
+		t.Fatal(err)
+	} else if resp.Code() != int(rpc.CodeDraining) {
+		t.Fatalf("unexpected response code: %d", resp.Code())
+	}
+}
+
+// Ensure a read-only service rejects writes with a retryable code, still
+// serves other requests, and accepts writes once it is no longer read-only.
+func TestService_SetReadOnly(t *testing.T) {
+	s := MustOpenService()
+	defer s.Close()
+	s.MetaClient.DatabasesFn = func() ([]meta.DatabaseInfo, error) { return nil, nil }
+	s.TSDBStore.WriteToShardFn = func(shardID uint64, points []models.Point) error { return nil }
+
+	setReadOnly := func(v bool) {
+		var resp rpc.SetReadOnlyResponse
+		if err := s.Request(tlv.SetReadOnlyRequestMessage, &rpc.SetReadOnlyRequest{ReadOnly: v}, &resp); err != nil {
+			t.Fatal(err)
+		} else if resp.Err != "" {
+			t.Fatal(resp.Err)
+		} else if s.ReadOnly() != v {
+			t.Fatalf("unexpected read-only: %v", s.ReadOnly())
+		}
+	}
+
+	setReadOnly(true)
+	req := &rpc.WriteShardRequest{}
+	req.SetShardID(1)
+	var resp rpc.WriteShardResponse
+	if err := s.Request(tlv.WriteShardRequestMessage, req, &resp); err != nil {
+		t.Fatal(err)
+	} else if resp.Code() != int(rpc.CodeReadOnly) {
+		t.Fatalf("unexpected response code: %d", resp.Code())
+	} else if e := (&rpc.WriteShardError{Code: rpc.CodeReadOnly}); !e.Retryable() {
+		t.Fatal("expected read-only code to be retryable")
+	}
+
+	var shards rpc.ShowShardsResponse
+	if err := s.Request(tlv.ShowShardsRequestMessage, &rpc.ShowShardsRequest{}, &shards); err != nil {
+		t.Fatal(err)
+	} else if shards.Err != "" {
+		t.Fatal(shards.Err)
+	}
+
+	setReadOnly(false)
+	if err := s.Request(tlv.WriteShardRequestMessage, req, &resp); err != nil {
+		t.Fatal(err)
+	} else if resp.Code() != int(rpc.CodeOK) {
+		t.Fatalf("unexpected response code: %d", resp.Code())
+	}
+}
+
+// drainer is a function implementing cluster.Drainer.
+type drainer func() error
+
+func (fn drainer) Drain() error { return fn() }
+
+// Ensure a node can import the meta data exported by another node of the same cluster.
+func TestService_ImportMetaData(t *testing.T) {
+	s0, s1 := MustOpenService(), MustOpenService()
+	defer s0.Close()
+	defer s1.Close()
+
+	exported := &metaStore{data: &cloudMeta.Data{
+		Data: &meta.Data{
+			ClusterID: 100,
+			Index:     10,
+			Databases: []meta.DatabaseInfo{{Name: "db0"}},
+		},
+		DataNodes: cloudMeta.NodeInfos{{ID: 1, Host: "host0:8086", TCPHost: "host0:8088"}},
+	}}
+	s0.Service.MetaStore = exported
+
+	// Reject meta data from another cluster.
+	local := &metaStore{data: &cloudMeta.Data{Data: &meta.Data{ClusterID: 200, Index: 1}}}
+	s1.Service.MetaStore = local
+	if err := s1.ImportMetaData(s0.Addr().String()); err != cluster.ErrClusterIDMismatch {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Reject meta data older than the local meta data.
+	local.data = &cloudMeta.Data{Data: &meta.Data{ClusterID: 100, Index: 11}}
+	if err := s1.ImportMetaData(s0.Addr().String()); err != cluster.ErrStaleMetaData {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	local.data = &cloudMeta.Data{Data: &meta.Data{ClusterID: 100, Index: 1}}
+	if err := s1.ImportMetaData(s0.Addr().String()); err != nil {
+		t.Fatal(err)
+	} else if local.data.Data.Index != 10 || local.data.Database("db0") == nil {
+		t.Fatalf("unexpected imported data: %+v", local.data.Data)
+	} else if !reflect.DeepEqual(local.data.DataNodes, exported.data.DataNodes) {
+		t.Fatalf("unexpected data nodes: %+v", local.data.DataNodes)
+	}
+}
+
+// metaStore is an in-memory implementation of the service's MetaStore.
+type metaStore struct {
+	data *cloudMeta.Data
+}
+
+func (m *metaStore) Data() *cloudMeta.Data              { return m.data }
+func (m *metaStore) SetData(data *cloudMeta.Data) error { m.data = data; return nil }
+
+// Ensure time-bounded deletes are applied on every node, which report the
+// number of series they deleted.
+func TestMetaExecutor_DeleteSeries(t *testing.T) {
+	idx := tsdb.NewDatabaseIndex("db0")
+	for _, key := range []string{"cpu,host=a", "cpu,host=b", "mem,host=a"} {
+		name, tags, err := models.ParseKey([]byte(key))
+		if err != nil {
+			t.Fatal(err)
+		}
+		idx.CreateSeriesIndexIfNotExists(name, tsdb.NewSeries(key, tags), false)
+	}
+
+	var condition influxql.Expr
+	s := MustOpenService()
+	defer s.Close()
+	s.TSDBStore.DatabaseIndexFn = func(name string) *tsdb.DatabaseIndex {
+		if name != "db0" {
+			t.Fatalf("unexpected database: %s", name)
+		}
+		return idx
+	}
+	s.TSDBStore.DeleteSeriesFn = func(database string, sources []influxql.Source, cond influxql.Expr) error {
+		condition = cond
+		return nil
+	}
+
+	e := cluster.NewMetaExecutor()
+	e.MetaClient = &metaClient{host: s.Addr().String()}
+
+	stmt := influxql.MustParseStatement(`DELETE FROM cpu WHERE host = 'a' AND time < now() - 1h`)
+	seriesN, err := e.DeleteSeries(stmt, "db0")
+	if err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(seriesN, map[uint64]int64{1: 1}) {
+		t.Fatalf("unexpected series deleted: %v", seriesN)
+	}
+
+	// now() is evaluated by the executor so every node deletes the same range.
+	if condition == nil || strings.Contains(condition.String(), "now()") {
+		t.Fatalf("unexpected condition: %v", condition)
+	} else if min, max, err := influxql.TimeRange(condition); err != nil {
+		t.Fatal(err)
+	} else if !min.IsZero() || max.IsZero() || time.Since(max) < time.Hour {
+		t.Fatalf("unexpected time range: %s - %s", min, max)
+	}
+
+	seriesN, err = e.DeleteSeries(influxql.MustParseStatement(`DROP SERIES FROM /.*/`), "db0")
+	if err != nil {
+		t.Fatal(err)
+	} else if seriesN[1] != 3 {
+		t.Fatalf("unexpected series deleted: %v", seriesN)
+	}
+}
+
+// Ensure the HTTP listener reports node status, queues and connections.
+func TestService_HTTP(t *testing.T) {
+	s := NewService()
+	s.Service = cluster.NewService(cluster.Config{HTTPEnabled: true, HTTPBindAddress: "127.0.0.1:0"})
+	s.Service.Node = &influxcloud.Node{ID: 1}
+	s.Service.MetaClient = &s.MetaClient
+	s.Service.HintedHandoff = queueSizes{2: 100}
+	s.MetaClient.DataNodesFn = func() ([]meta.NodeInfo, error) {
+		return []meta.NodeInfo{{ID: 1, TCPHost: "host0:8088"}, {ID: 2, TCPHost: "host1:8088"}}, nil
+	}
+	s.MetaClient.DatabasesFn = func() ([]meta.DatabaseInfo, error) {
+		return []meta.DatabaseInfo{{
+			Name: "db0",
+			RetentionPolicies: []meta.RetentionPolicyInfo{{
+				Name: "rp0",
+				ShardGroups: []meta.ShardGroupInfo{{
+					ID: 1,
+					Shards: []meta.ShardInfo{
+						{ID: 10, Owners: []meta.ShardOwner{{NodeID: 1}, {NodeID: 2}}},
+						{ID: 11, Owners: []meta.ShardOwner{{NodeID: 2}}},
+					},
+				}},
+			}},
+		}}, nil
+	}
+	s.ln = MustListen("tcp", "127.0.0.1:0")
+	s.Listener = &muxListener{s.ln}
+	if err := s.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	get := func(path string, v interface{}) {
+		resp, err := http.Get("http://" + s.HTTPAddr().String() + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("unexpected status for %s: %d", path, resp.StatusCode)
+		} else if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var status cluster.Status
+	get("/status", &status)
+	if status.NodeID != 1 {
+		t.Fatalf("unexpected node id: %d", status.NodeID)
+	} else if !reflect.DeepEqual(status.Peers, []cluster.StatusPeer{
+		{ID: 1, TCPHost: "host0:8088", ShardN: 1},
+		{ID: 2, TCPHost: "host1:8088", ShardN: 2},
+	}) {
+		t.Fatalf("unexpected peers: %+v", status.Peers)
+	}
+
+	var sizes map[uint64]int64
+	get("/hh", &sizes)
+	if !reflect.DeepEqual(sizes, map[uint64]int64{2: 100}) {
+		t.Fatalf("unexpected queue sizes: %v", sizes)
+	}
+
+	// Hold a connection open to the cluster service.
+	conn, err := net.Dial("tcp", s.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if _, err := conn.Write([]byte{cluster.MuxHeader}); err != nil {
+		t.Fatal(err)
+	}
+
+	var conns []cluster.Connection
+	for i := 0; i < 100 && len(conns) == 0; i++ {
+		time.Sleep(10 * time.Millisecond)
+		get("/connections", &conns)
+	}
+	if len(conns) != 1 {
+		t.Fatalf("unexpected connection count: %d", len(conns))
+	} else if conns[0].Remote != conn.LocalAddr().String() {
+		t.Fatalf("unexpected remote address: %s", conns[0].Remote)
+	}
+
+	if resp, err := http.Get("http://" + s.HTTPAddr().String() + "/metrics"); err != nil {
+		t.Fatal(err)
+	} else if resp.Body.Close(); resp.StatusCode != http.StatusOK {
+		t.Fatalf("unexpected metrics status: %d", resp.StatusCode)
+	}
+
+	if resp, err := http.Get("http://" + s.HTTPAddr().String() + "/nope"); err != nil {
+		t.Fatal(err)
+	} else if resp.Body.Close(); resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("unexpected status: %d", resp.StatusCode)
+	}
+}
+
+// Ensure the statistics of the PointsWriter and the hinted handoff service,
+// and the sizes of the hinted handoff queues, are exported on /metrics.
+func TestService_HTTP_Metrics(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cluster-hh-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	c := hh.NewConfig()
+	c.Enabled = true
+	c.Dir = dir
+	c.RetryInterval, c.RetryMaxInterval = toml.Duration(time.Hour), toml.Duration(time.Hour)
+	handoff := hh.NewService(c, &fakeShardWriter{
+		ShardWriteFn: func(shardID, nodeID uint64, points []models.Point) error { return errors.New("node down") },
+	}, nodeHosts{})
+	if err := handoff.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer handoff.Close()
+	if err := handoff.WriteShard(1, 2, []models.Point{models.MustNewPoint("cpu", newTags(), newFields(), time.Unix(0, 0))}); err != nil {
+		t.Fatal(err)
+	}
+	size := handoff.QueueSizes()[2]
+	if size == 0 {
+		t.Fatal("expected write to be queued")
+	}
+
+	s := NewService()
+	s.Service = cluster.NewService(cluster.Config{HTTPEnabled: true, HTTPBindAddress: "127.0.0.1:0"})
+	s.Service.Node = &influxcloud.Node{ID: 1}
+	s.Service.PointsWriter = cluster.NewPointsWriter()
+	s.Service.HintedHandoff = handoff
+	s.ln = MustListen("tcp", "127.0.0.1:0")
+	s.Listener = &muxListener{s.ln}
+	if err := s.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	resp, err := http.Get("http://" + s.HTTPAddr().String() + "/metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	} else if resp.StatusCode != http.StatusOK {
+		t.Fatalf("unexpected status: %d", resp.StatusCode)
+	}
+
+	for _, line := range []string{
+		"influxcloud_write_write_ok 0",
+		fmt.Sprintf(`influxcloud_hh_node_processor_created{path=%q} 1`, dir),
+		"# TYPE influxcloud_hh_queue_bytes gauge",
+		fmt.Sprintf(`influxcloud_hh_queue_bytes{node="2"} %d`, size),
+	} {
+		if !strings.Contains(string(body), line+"\n") {
+			t.Errorf("missing line %q in:\n%s", line, body)
+		}
+	}
+}
+
+// Ensure local shards not assigned to the node are deleted once their grace
+// period has passed, and are reported on the /shards/orphans endpoint.
+func TestService_OrphanShards(t *testing.T) {
+	store := MustOpenStore()
+	defer store.Close()
+	for _, id := range []uint64{10, 11} {
+		if err := store.CreateShard("db0", "rp0", id, true); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	s := NewService()
+	s.Service = cluster.NewService(cluster.Config{
+		HTTPEnabled:              true,
+		HTTPBindAddress:          "127.0.0.1:0",
+		OrphanShardCheckInterval: toml.Duration(10 * time.Millisecond),
+		OrphanShardAction:        cluster.OrphanShardDelete,
+	})
+	s.Service.Node = &influxcloud.Node{ID: 1}
+	s.Service.MetaClient = &s.MetaClient
+	s.Service.ShardStore = store
+	s.Service.TSDBStore = coordinator.LocalTSDBStore{Store: store.Store}
+	s.MetaClient.DatabasesFn = func() ([]meta.DatabaseInfo, error) {
+		return []meta.DatabaseInfo{{
+			Name: "db0",
+			RetentionPolicies: []meta.RetentionPolicyInfo{{
+				Name: "rp0",
+				ShardGroups: []meta.ShardGroupInfo{{
+					ID: 1,
+					Shards: []meta.ShardInfo{
+						{ID: 10, Owners: []meta.ShardOwner{{NodeID: 1}}},
+						{ID: 11, Owners: []meta.ShardOwner{{NodeID: 2}}},
+					},
+				}},
+			}},
+		}}, nil
+	}
+	s.ln = MustListen("tcp", "127.0.0.1:0")
+	s.Listener = &muxListener{s.ln}
+	if err := s.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	var orphans []cluster.OrphanShard
+	for i := 0; i < 100 && (len(orphans) == 0 || orphans[0].Action == ""); i++ {
+		time.Sleep(10 * time.Millisecond)
+		resp, err := http.Get("http://" + s.HTTPAddr().String() + "/shards/orphans")
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = json.NewDecoder(resp.Body).Decode(&orphans)
+		resp.Body.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if len(orphans) != 1 {
+		t.Fatalf("unexpected orphan shards: %+v", orphans)
+	} else if sh := orphans[0]; sh.ID != 11 || sh.Database != "db0" || sh.RetentionPolicy != "rp0" || sh.Action != cluster.OrphanShardDelete {
+		t.Fatalf("unexpected orphan shard: %+v", sh)
+	} else if ids := store.ShardIDs(); len(ids) != 1 || ids[0] != 10 {
+		t.Fatalf("unexpected local shards: %v", ids)
+	}
+}
+
+// Ensure the shards a node owns but does not store are reported on /status
+// when it starts, and copied from their other owners if repair is enabled.
+func TestService_StartupShardCheck(t *testing.T) {
+	// Node 2 stores shard 12 but not shard 13, which was never written to.
+	srcStore := MustOpenStore()
+	defer srcStore.Close()
+	if err := srcStore.CreateShard("db0", "rp0", 12, true); err != nil {
+		t.Fatal(err)
+	}
+
+	src := MustOpenService()
+	defer src.Close()
+	src.Node.ID = 2
+	src.Service.ShardStore = srcStore
+	src.MetaClient.DatabasesFn = func() ([]meta.DatabaseInfo, error) { return nil, nil }
+	src.TSDBStore.BackupShardFn = func(id uint64, since time.Time, w io.Writer) error {
+		if id != 12 {
+			t.Fatalf("unexpected shard id: %d", id)
+		}
+		_, err := w.Write(MustTarShardBackup("db0/rp0/12/000000001-000000001.tsm", "shard data"))
+		return err
+	}
+
+	store := MustOpenStore()
+	defer store.Close()
+	if err := store.CreateShard("db0", "rp0", 10, true); err != nil {
+		t.Fatal(err)
+	}
+
+	s := NewService()
+	s.Service = cluster.NewService(cluster.Config{
+		DialTimeout:        toml.Duration(time.Second),
+		HTTPEnabled:        true,
+		HTTPBindAddress:    "127.0.0.1:0",
+		StartupShardCheck:  true,
+		StartupShardRepair: true,
+	})
+	s.Service.Node = &influxcloud.Node{ID: 1}
+	s.Service.MetaClient = &s.MetaClient
+	s.Service.ShardStore = store
+	s.Service.TSDBStore = &s.TSDBStore
+	s.MetaClient.DataNodesFn = func() ([]meta.NodeInfo, error) {
+		return []meta.NodeInfo{{ID: 1, TCPHost: "127.0.0.1:0"}, {ID: 2, TCPHost: src.Addr().String()}}, nil
+	}
+	s.MetaClient.DatabasesFn = func() ([]meta.DatabaseInfo, error) {
+		return []meta.DatabaseInfo{{
+			Name: "db0",
+			RetentionPolicies: []meta.RetentionPolicyInfo{{
+				Name: "rp0",
+				ShardGroups: []meta.ShardGroupInfo{{
+					ID: 1,
+					Shards: []meta.ShardInfo{
+						{ID: 10, Owners: []meta.ShardOwner{{NodeID: 1}}},
+						{ID: 11, Owners: []meta.ShardOwner{{NodeID: 2}}},
+						{ID: 12, Owners: []meta.ShardOwner{{NodeID: 1}, {NodeID: 2}}},
+						{ID: 13, Owners: []meta.ShardOwner{{NodeID: 1}, {NodeID: 2}}},
+					},
+				}},
+			}},
+		}}, nil
+	}
+	var restored string
+	s.TSDBStore.CreateShardFn = func(database, policy string, shardID uint64, enabled bool) error { return nil }
+	s.TSDBStore.RestoreShardFn = func(id uint64, r io.Reader) error {
+		buf, err := ioutil.ReadAll(r)
+		restored = string(buf)
+		return err
+	}
+	s.ln = MustListen("tcp", "127.0.0.1:0")
+	s.Listener = &muxListener{s.ln}
+	if err := s.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	var status cluster.Status
+	for i := 0; i < 100 && (status.ShardCheck == nil || status.ShardCheck.Finished.IsZero()); i++ {
+		time.Sleep(10 * time.Millisecond)
+		resp, err := http.Get("http://" + s.HTTPAddr().String() + "/status")
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = json.NewDecoder(resp.Body).Decode(&status)
+		resp.Body.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if c := status.ShardCheck; c == nil || c.Err != "" || c.OwnedN != 3 || c.LocalN != 1 || len(c.Missing) != 1 {
+		t.Fatalf("unexpected shard check: %+v", c)
+	} else if sh := c.Missing[0]; sh.ID != 12 || sh.Database != "db0" || sh.RetentionPolicy != "rp0" || !sh.Repaired || sh.Source != src.Addr().String() {
+		t.Fatalf("unexpected missing shard: %+v", sh)
+	} else if data := MustReadShardBackup(restored); data != "shard data" {
+		t.Fatalf("unexpected restored data: %q", data)
+	}
+}
+
+// Ensure scrubbing reports a shard whose TSM file does not match its
+// checksums, and replaces it with a copy from another owner if repair is
+// enabled.
+func TestService_ScrubShards(t *testing.T) {
+	// Both stores hold shard 10 with the same point in a TSM file.
+	mustOpenTSMStore := func() *Store {
+		store := MustOpenStore()
+		if err := store.CreateShard("db0", "rp0", 10, true); err != nil {
+			t.Fatal(err)
+		}
+		pt := models.MustNewPoint("cpu", models.NewTags(map[string]string{"host": "server0"}), map[string]interface{}{"value": 1.0}, time.Unix(0, 0))
+		if err := store.WriteToShard(10, []models.Point{pt}); err != nil {
+			t.Fatal(err)
+		} else if _, err := store.Shard(10).CreateSnapshot(); err != nil {
+			t.Fatal(err)
+		}
+		return store
+	}
+	healthy := mustOpenTSMStore()
+	defer healthy.Close()
+	store := mustOpenTSMStore()
+	defer store.Close()
+
+	// Flip a byte of the first block of the local copy.
+	files, err := filepath.Glob(filepath.Join(store.Shard(10).Path(), "*.tsm"))
+	if err != nil {
+		t.Fatal(err)
+	} else if len(files) != 1 {
+		t.Fatalf("unexpected TSM files: %v", files)
+	}
+	f, err := os.OpenFile(files[0], os.O_RDWR, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b [1]byte
+	if _, err := f.ReadAt(b[:], 10); err != nil {
+		t.Fatal(err)
+	}
+	b[0] ^= 0xff
+	if _, err := f.WriteAt(b[:], 10); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	src := MustOpenService()
+	defer src.Close()
+	src.TSDBStore.BackupShardFn = healthy.BackupShard
+
+	s := NewService()
+	s.Service = cluster.NewService(cluster.Config{
+		DialTimeout:        toml.Duration(time.Second),
+		HTTPEnabled:        true,
+		HTTPBindAddress:    "127.0.0.1:0",
+		ShardScrubInterval: toml.Duration(10 * time.Millisecond),
+		ShardScrubRepair:   true,
+	})
+	s.Service.Node = &influxcloud.Node{ID: 1}
+	s.Service.MetaClient = &s.MetaClient
+	s.Service.ShardStore = store
+	s.Service.TSDBStore = coordinator.LocalTSDBStore{Store: store.Store}
+	s.MetaClient.DataNodesFn = func() ([]meta.NodeInfo, error) {
+		return []meta.NodeInfo{{ID: 1, TCPHost: "127.0.0.1:0"}, {ID: 2, TCPHost: src.Addr().String()}}, nil
+	}
+	s.MetaClient.DatabasesFn = func() ([]meta.DatabaseInfo, error) {
+		return []meta.DatabaseInfo{{
+			Name: "db0",
+			RetentionPolicies: []meta.RetentionPolicyInfo{{
+				Name: "rp0",
+				ShardGroups: []meta.ShardGroupInfo{{
+					ID:     1,
+					Shards: []meta.ShardInfo{{ID: 10, Owners: []meta.ShardOwner{{NodeID: 1}, {NodeID: 2}}}},
+				}},
+			}},
+		}}, nil
+	}
+	events, cancel := s.Events.Subscribe(0)
+	defer cancel()
+	s.ln = MustListen("tcp", "127.0.0.1:0")
+	s.Listener = &muxListener{s.ln}
+	if err := s.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	next := func() cluster.Event {
+		select {
+		case e := <-events:
+			return e
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for event")
+		}
+		return cluster.Event{}
+	}
+	if e := next(); e.Type != cluster.EventShardCorrupt || e.NodeID != 1 || e.ShardID != 10 || !strings.Contains(e.Message, "checksum mismatch") {
+		t.Fatalf("unexpected event: %+v", e)
+	} else if e := next(); e.Type != cluster.EventShardCopied || e.ShardID != 10 || e.Message != src.Addr().String() {
+		t.Fatalf("unexpected event: %+v", e)
+	}
+
+	// The copy verifies on the next scrub, so the shard is no longer
+	// reported.
+	var corrupt []cluster.CorruptShard
+	for i := 0; i < 100; i++ {
+		resp, err := http.Get("http://" + s.HTTPAddr().String() + "/shards/corrupt")
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = json.NewDecoder(resp.Body).Decode(&corrupt)
+		resp.Body.Close()
+		if err != nil {
+			t.Fatal(err)
+		} else if len(corrupt) == 0 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if len(corrupt) != 0 {
+		t.Fatalf("unexpected corrupt shards: %+v", corrupt)
+	}
+}
+
+// Ensure the node reports itself ready on /ready only once its shards are
+// stored locally and its hinted handoff backlog is small, and alive on
+// /healthz regardless.
+func TestService_Ready(t *testing.T) {
+	store := MustOpenStore()
+	defer store.Close()
+
+	// Node 2 stores none of the shards it shares with the node.
+	peerStore := MustOpenStore()
+	defer peerStore.Close()
+	peer := NewService()
+	peer.Node.ID = 2
+	peer.Service.ShardStore = peerStore
+	peer.MetaClient.DatabasesFn = func() ([]meta.DatabaseInfo, error) { return nil, nil }
+	peer.ln = MustListen("tcp", "127.0.0.1:0")
+	peer.Listener = &muxListener{peer.ln}
+	if err := peer.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer peer.Close()
+
+	hh := queueSizes{2: 100}
+	s := NewService()
+	s.Service = cluster.NewService(cluster.Config{
+		DialTimeout:       toml.Duration(time.Second),
+		HTTPEnabled:       true,
+		HTTPBindAddress:   "127.0.0.1:0",
+		ReadyMaxHHBacklog: 1000,
+	})
+	s.Service.Node = &influxcloud.Node{ID: 1}
+	s.Service.MetaClient = &s.MetaClient
+	s.Service.ShardStore = store
+	s.Service.HintedHandoff = hh
+	s.MetaClient.DataNodesFn = func() ([]meta.NodeInfo, error) {
+		return []meta.NodeInfo{{ID: 1, TCPHost: "host0:8088"}, {ID: 2, TCPHost: peer.Addr().String()}}, nil
+	}
+	s.MetaClient.DatabasesFn = func() ([]meta.DatabaseInfo, error) {
+		return []meta.DatabaseInfo{{
+			Name: "db0",
+			RetentionPolicies: []meta.RetentionPolicyInfo{{
+				Name: "rp0",
+				ShardGroups: []meta.ShardGroupInfo{
+					{
+						ID: 1,
+						Shards: []meta.ShardInfo{
+							{ID: 10, Owners: []meta.ShardOwner{{NodeID: 1}}},
+							{ID: 11, Owners: []meta.ShardOwner{{NodeID: 2}}},
+							{ID: 12, Owners: []meta.ShardOwner{{NodeID: 1}, {NodeID: 2}}},
+						},
+					},
+					{
+						// Precreated, and not written to yet.
+						ID:        2,
+						StartTime: time.Now().Add(time.Hour),
+						EndTime:   time.Now().Add(2 * time.Hour),
+						Shards:    []meta.ShardInfo{{ID: 13, Owners: []meta.ShardOwner{{NodeID: 1}}}},
+					},
+				},
+			}},
+		}}, nil
+	}
+	s.ln = MustListen("tcp", "127.0.0.1:0")
+	s.Listener = &muxListener{s.ln}
+	if err := s.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	get := func(path string, v interface{}) int {
+		resp, err := http.Get("http://" + s.HTTPAddr().String() + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+			t.Fatal(err)
+		}
+		return resp.StatusCode
+	}
+	failed := func(r cluster.Readiness) []string {
+		var names []string
+		for _, c := range r.Checks {
+			if c.Err != "" {
+				names = append(names, c.Name)
+			}
+		}
+		return names
+	}
+
+	// Shard 10 is assigned to the node but not stored locally. Shard 12 is
+	// not stored by its other owner either, and shard 13 is precreated.
+	var r cluster.Readiness
+	if code := get("/ready", &r); code != http.StatusServiceUnavailable {
+		t.Fatalf("unexpected status: %d", code)
+	} else if r.Ready || !reflect.DeepEqual(failed(r), []string{"shards"}) {
+		t.Fatalf("unexpected readiness: %+v", r)
+	} else if r.Checks[3].Err != "missing shards: [10]" {
+		t.Fatalf("unexpected shards check: %+v", r.Checks[3])
+	}
+
+	var health map[string]string
+	if code := get("/healthz", &health); code != http.StatusOK {
+		t.Fatalf("unexpected status: %d", code)
+	}
+
+	if err := store.CreateShard("db0", "rp0", 10, true); err != nil {
+		t.Fatal(err)
+	}
+	r = cluster.Readiness{}
+	if code := get("/ready", &r); code != http.StatusOK {
+		t.Fatalf("unexpected status: %d", code)
+	} else if !r.Ready || len(r.Checks) != 4 {
+		t.Fatalf("unexpected readiness: %+v", r)
+	}
+
+	// Writes for node 2 pile up in hinted handoff.
+	hh[2] = 2000
+	r = cluster.Readiness{}
+	if code := get("/ready", &r); code != http.StatusServiceUnavailable {
+		t.Fatalf("unexpected status: %d", code)
+	} else if !reflect.DeepEqual(failed(r), []string{"hintedHandoff"}) {
+		t.Fatalf("unexpected readiness: %+v", r)
+	}
+}
+
+// queueSizes is a static implementation of cluster.Service.HintedHandoff.
+type queueSizes map[uint64]int64
+
+func (q queueSizes) QueueSizes() map[uint64]int64 { return q }
+
+func (q queueSizes) RedirectQueue(from, to uint64) error {
+	if n, ok := q[from]; ok {
+		q[to] += n
+		delete(q, from)
+	}
+	return nil
+}
+
 type metaClient struct {
 	host string
 }
@@ -23,6 +1951,10 @@ func (m *metaClient) DataNode(nodeID uint64) (*meta.NodeInfo, error) {
 	}, nil
 }
 
+func (m *metaClient) DataNodes() ([]meta.NodeInfo, error) {
+	return []meta.NodeInfo{{ID: 1, TCPHost: m.host}}, nil
+}
+
 func (m *metaClient) ShardOwner(shardID uint64) (db, rp string, si meta.ShardInfo) {
 	return "db", "rp", meta.ShardInfo{}
 }
@@ -104,16 +2036,42 @@ func (ts *testService) ResponseN(n int) ([]*serviceResponse, error) {
 type Service struct {
 	*cluster.Service
 
-	ln        net.Listener
-	TSDBStore TSDBStore
+	ln         net.Listener
+	TSDBStore  TSDBStore
+	MetaClient ServiceMetaClient
 }
 
 // NewService returns a new instance of Service.
 func NewService() *Service {
 	s := &Service{
-		Service: cluster.NewService(cluster.Config{}),
+		Service: cluster.NewService(cluster.Config{
+			DialTimeout:  toml.Duration(time.Second),
+			DrainTimeout: toml.Duration(time.Second),
+		}),
 	}
+	s.Service.Node = &influxcloud.Node{ID: 1}
+	s.Service.TSDBStore = &s.TSDBStore
+	s.Service.MetaClient = &s.MetaClient
+	return s
+}
+
+// MustOpenTLSService returns a new, open service on a random port served
+// over TLS with c. Nodes 1 and 2 are registered at 10.0.0.1 and 127.0.0.1.
+// Panic on error.
+func MustOpenTLSService(c cluster.TLSConfig) *Service {
+	s := NewService()
+	s.Service = cluster.NewService(cluster.Config{DialTimeout: toml.Duration(time.Second), TLS: c})
+	s.Service.Node = &influxcloud.Node{ID: 1}
 	s.Service.TSDBStore = &s.TSDBStore
+	s.Service.MetaClient = &s.MetaClient
+	s.MetaClient.DataNodesFn = func() ([]meta.NodeInfo, error) {
+		return []meta.NodeInfo{{ID: 1, TCPHost: "10.0.0.1:8088"}, {ID: 2, TCPHost: "127.0.0.1:8088"}}, nil
+	}
+	s.ln = MustListen("tcp", "127.0.0.1:0")
+	s.Listener = &muxListener{s.ln}
+	if err := s.Open(); err != nil {
+		panic(err)
+	}
 	return s
 }
 
@@ -139,6 +2097,118 @@ func (s *Service) Close() error {
 // Addr returns the network address of the service.
 func (s *Service) Addr() net.Addr { return s.ln.Addr() }
 
+// DialTLS dials the service, over TLS with c if enabled. The connection
+// times out after a second.
+func (s *Service) DialTLS(c cluster.TLSConfig) (net.Conn, error) {
+	d := &cluster.Dialer{}
+	if c.Enabled {
+		tc, err := c.ClientConfig()
+		if err != nil {
+			return nil, err
+		}
+		d.TLS = tc
+	}
+	conn, err := d.Dial(s.Addr().String(), time.Second)
+	if err != nil {
+		return nil, err
+	}
+	conn.SetDeadline(time.Now().Add(time.Second))
+	return conn, nil
+}
+
+// Request sends a single request to the service and decodes the response.
+func (s *Service) Request(typ byte, req encoding.BinaryMarshaler, resp encoding.BinaryUnmarshaler) error {
+	conn, err := net.Dial("tcp", s.Addr().String())
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err := conn.Write([]byte{cluster.MuxHeader}); err != nil {
+		return err
+	}
+
+	// Once the cluster of the service is known, hello is required first.
+	if c, ok := s.Service.MetaClient.(interface {
+		ClusterID() uint64
+	}); ok && c.ClusterID() != 0 && typ != tlv.HelloRequestMessage {
+		var hello rpc.HelloResponse
+		if err := tlv.EncodeTLV(conn, tlv.HelloRequestMessage, &rpc.HelloRequest{ProtocolVersion: rpc.ProtocolVersion, ClusterID: c.ClusterID()}); err != nil {
+			return err
+		} else if _, err := tlv.DecodeTLV(conn, &hello); err != nil {
+			return err
+		} else if hello.Err != "" {
+			return errors.New(hello.Err)
+		}
+	}
+
+	if err := tlv.EncodeTLV(conn, typ, req); err != nil {
+		return err
+	}
+	_, err = tlv.DecodeTLV(conn, resp)
+	return err
+}
+
+// ServiceMetaClient is a mockable implementation of cluster.Service.MetaClient.
+// The methods it does not mock panic.
+type ServiceMetaClient struct {
+	cluster.MetaClient
+	ShardOwnerFn             func(shardID uint64) (string, string, meta.ShardInfo)
+	DatabasesFn              func() ([]meta.DatabaseInfo, error)
+	DataNodesFn              func() ([]meta.NodeInfo, error)
+	AddShardOwnerFn          func(shardID, nodeID uint64) error
+	RemoveShardOwnerFn       func(shardID, nodeID uint64) error
+	DeleteDataNodeFn         func(id uint64) error
+	ReplaceDataNodeFn        func(oldID, newID uint64) error
+	SetDataNodeMaintenanceFn func(id uint64, maintenance bool) error
+	UpdateDataNodeFn         func(id uint64, host, tcpHost string) error
+	TruncateShardGroupsFn    func(t time.Time) error
+	UsersFn                  func() []meta.UserInfo
+	ClusterIDFn              func() uint64
+}
+
+func (m *ServiceMetaClient) ShardOwner(shardID uint64) (string, string, meta.ShardInfo) {
+	return m.ShardOwnerFn(shardID)
+}
+
+func (m *ServiceMetaClient) Databases() ([]meta.DatabaseInfo, error) { return m.DatabasesFn() }
+
+func (m *ServiceMetaClient) DataNodes() ([]meta.NodeInfo, error) { return m.DataNodesFn() }
+
+func (m *ServiceMetaClient) AddShardOwner(shardID, nodeID uint64) error {
+	return m.AddShardOwnerFn(shardID, nodeID)
+}
+
+func (m *ServiceMetaClient) RemoveShardOwner(shardID, nodeID uint64) error {
+	return m.RemoveShardOwnerFn(shardID, nodeID)
+}
+
+func (m *ServiceMetaClient) DeleteDataNode(id uint64) error { return m.DeleteDataNodeFn(id) }
+
+func (m *ServiceMetaClient) ReplaceDataNode(oldID, newID uint64) error {
+	return m.ReplaceDataNodeFn(oldID, newID)
+}
+
+func (m *ServiceMetaClient) SetDataNodeMaintenance(id uint64, maintenance bool) error {
+	return m.SetDataNodeMaintenanceFn(id, maintenance)
+}
+
+func (m *ServiceMetaClient) UpdateDataNode(id uint64, host, tcpHost string) error {
+	return m.UpdateDataNodeFn(id, host, tcpHost)
+}
+
+func (m *ServiceMetaClient) TruncateShardGroups(t time.Time) error {
+	return m.TruncateShardGroupsFn(t)
+}
+
+// ClusterID returns zero, an unknown cluster, unless ClusterIDFn is set.
+func (m *ServiceMetaClient) ClusterID() uint64 {
+	if m.ClusterIDFn == nil {
+		return 0
+	}
+	return m.ClusterIDFn()
+}
+
 // muxListener is a net.Listener implementation that strips off the first byte.
 // This is used to simulate the listener from pkg/mux.
 type muxListener struct {
@@ -172,3 +2242,47 @@ func MustListen(network, laddr string) net.Listener {
 	}
 	return ln
 }
+
+// MustWriteCertificate writes a self-signed certificate valid for hosts, and
+// its private key, to PEM files in dir. Panic on error.
+func MustWriteCertificate(dir string, hosts ...string) (certPath, keyPath string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		panic(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: hosts[0]},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth, x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	for _, h := range hosts {
+		if ip := net.ParseIP(h); ip != nil {
+			tmpl.IPAddresses = append(tmpl.IPAddresses, ip)
+		} else {
+			tmpl.DNSNames = append(tmpl.DNSNames, h)
+		}
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		panic(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		panic(err)
+	}
+
+	certPath, keyPath = filepath.Join(dir, hosts[0]+".crt"), filepath.Join(dir, hosts[0]+".key")
+	if err := ioutil.WriteFile(certPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600); err != nil {
+		panic(err)
+	} else if err := ioutil.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600); err != nil {
+		panic(err)
+	}
+	return certPath, keyPath
+}
+
+func (m *ServiceMetaClient) Users() []meta.UserInfo { return m.UsersFn() }
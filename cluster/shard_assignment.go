@@ -0,0 +1,34 @@
+package cluster
+
+import (
+	"github.com/influxdata/influxdb/services/meta"
+	cloudMeta "github.com/zhexuany/influxcloud/meta"
+)
+
+// shardAssigner is implemented by meta clients that store the mode points are
+// assigned to the shards of each shard group with.
+type shardAssigner interface {
+	ShardGroupAssignment(shardGroupID uint64) string
+}
+
+// shardFor returns the shard of sg that a point with hash is written to when
+// points are assigned with mode.
+func shardFor(sg *meta.ShardGroupInfo, mode string, hash uint64) meta.ShardInfo {
+	if mode == cloudMeta.ShardAssignmentJump {
+		return sg.Shards[jumpHash(hash, len(sg.Shards))]
+	}
+	return sg.ShardFor(hash)
+}
+
+// jumpHash returns the bucket in [0, n) for key using the jump consistent
+// hash of Lamping and Veach. Going from n to n+1 buckets only moves the keys
+// that land in the new bucket.
+func jumpHash(key uint64, n int) int {
+	var b, j int64 = -1, 0
+	for j < int64(n) {
+		b = j
+		key = key*2862933555777941143 + 1
+		j = int64(float64(b+1) * (float64(int64(1)<<31) / float64((key>>33)+1)))
+	}
+	return int(b)
+}
@@ -0,0 +1,45 @@
+package cluster
+
+import (
+	"testing"
+
+	"github.com/influxdata/influxdb/services/meta"
+	cloudMeta "github.com/zhexuany/influxcloud/meta"
+)
+
+// Ensure adding a shard only moves keys to the new shard.
+func TestJumpHash(t *testing.T) {
+	const keyN = 10000
+	for n := 1; n < 10; n++ {
+		var moved int
+		for key := uint64(0); key < keyN; key++ {
+			h := key * 0x9E3779B97F4A7C15
+			before, after := jumpHash(h, n), jumpHash(h, n+1)
+			if before < 0 || before >= n {
+				t.Fatalf("bucket %d out of range for %d buckets", before, n)
+			} else if before != after {
+				if after != n {
+					t.Fatalf("key %d moved from bucket %d to %d going to %d buckets", key, before, after, n+1)
+				}
+				moved++
+			}
+		}
+
+		// About 1/(n+1) of the keys move.
+		if exp := keyN / (n + 1); moved < exp/2 || moved > exp*2 {
+			t.Fatalf("%d of %d keys moved going to %d buckets", moved, keyN, n+1)
+		}
+	}
+}
+
+func TestShardFor(t *testing.T) {
+	sg := &meta.ShardGroupInfo{Shards: []meta.ShardInfo{{ID: 1}, {ID: 2}, {ID: 3}}}
+	for hash := uint64(0); hash < 100; hash++ {
+		if got, exp := shardFor(sg, cloudMeta.ShardAssignmentHash, hash), sg.ShardFor(hash); got.ID != exp.ID {
+			t.Fatalf("hash %d: got shard %d, expected %d", hash, got.ID, exp.ID)
+		}
+		if got, exp := shardFor(sg, cloudMeta.ShardAssignmentJump, hash), sg.Shards[jumpHash(hash, 3)]; got.ID != exp.ID {
+			t.Fatalf("hash %d: got jump shard %d, expected %d", hash, got.ID, exp.ID)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package cluster
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/influxdata/influxdb/services/meta"
+	"github.com/zhexuany/influxcloud/rpc"
+	"github.com/zhexuany/influxcloud/tlv"
+)
+
+// ShardBoundsClient asks data nodes for the time range of the data in their
+// shards, so that shards which cannot contain data for a query are not asked
+// for iterators.
+type ShardBoundsClient struct {
+	timeout time.Duration
+
+	// Dialer connects to the data nodes.
+	Dialer *Dialer
+
+	MetaClient interface {
+		DataNode(id uint64) (*meta.NodeInfo, error)
+	}
+}
+
+// NewShardBoundsClient returns a new instance of ShardBoundsClient.
+func NewShardBoundsClient(timeout time.Duration) *ShardBoundsClient {
+	return &ShardBoundsClient{timeout: timeout, Dialer: DefaultDialer}
+}
+
+// ShardBounds requests the time range of the given measurements, or of every
+// measurement if none are given, in the given shards from the node nodeID.
+// Shards the node does not store are left out of the response.
+func (c *ShardBoundsClient) ShardBounds(nodeID uint64, shardIDs []uint64, measurements []string) ([]rpc.ShardBounds, error) {
+	n, err := c.MetaClient.DataNode(nodeID)
+	if err != nil {
+		return nil, err
+	} else if n == nil {
+		return nil, fmt.Errorf("node %d does not exist", nodeID)
+	}
+
+	var resp rpc.ShardBoundsResponse
+	if err := c.Dialer.dialRPC(n.TCPHost, c.timeout, tlv.ShardBoundsRequestMessage, &rpc.ShardBoundsRequest{
+		ShardIDs:     shardIDs,
+		Measurements: measurements,
+	}, &resp); err != nil {
+		return nil, err
+	} else if resp.Err != "" {
+		return nil, errors.New(resp.Err)
+	}
+	return resp.Shards, nil
+}
+
+// PruneShards returns the shards of shardIDs on the node nodeID that hold
+// data for any of the given measurements, or for any measurement if none are
+// given, between min and max inclusive. Shards the node does not store are
+// pruned. On error, callers should query all of shardIDs.
+func (c *ShardBoundsClient) PruneShards(nodeID uint64, shardIDs []uint64, measurements []string, min, max time.Time) ([]uint64, error) {
+	bounds, err := c.ShardBounds(nodeID, shardIDs, measurements)
+	if err != nil {
+		return nil, err
+	}
+
+	var ids []uint64
+	for _, sb := range bounds {
+		for _, mb := range sb.Measurements {
+			if !mb.MaxTime.Before(min) && !mb.MinTime.After(max) {
+				ids = append(ids, sb.ID)
+				break
+			}
+		}
+	}
+	return ids, nil
+}
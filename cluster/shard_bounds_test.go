@@ -0,0 +1,75 @@
+package cluster_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/influxdata/influxdb/models"
+	"github.com/zhexuany/influxcloud/cluster"
+)
+
+// Ensure shards without data for a query's time range are pruned.
+func TestShardBoundsClient_PruneShards(t *testing.T) {
+	store := MustOpenStore()
+	defer store.Close()
+
+	for _, id := range []uint64{10, 11} {
+		if err := store.CreateShard("db0", "rp0", id, true); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write := func(shardID uint64, name string, times ...int64) {
+		var points []models.Point
+		for _, ts := range times {
+			points = append(points, models.MustNewPoint(name, models.NewTags(map[string]string{"host": "server0"}), map[string]interface{}{"value": 1.0}, time.Unix(ts, 0)))
+		}
+		if err := store.WriteToShard(shardID, points); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(10, "cpu", 10, 20, 30)
+	write(10, "mem", 100)
+	write(11, "cpu", 40, 50)
+
+	s := MustOpenService()
+	defer s.Close()
+	s.Service.ShardStore = store
+
+	c := cluster.NewShardBoundsClient(time.Second)
+	c.MetaClient = &metaClient{host: s.Addr().String()}
+
+	bounds, err := c.ShardBounds(1, []uint64{10, 12}, nil)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(bounds) != 1 || bounds[0].ID != 10 {
+		t.Fatalf("unexpected bounds: %+v", bounds)
+	} else if m := bounds[0].Measurements; len(m) != 2 {
+		t.Fatalf("unexpected measurements: %+v", m)
+	} else if m[0].Name != "cpu" || !m[0].MinTime.Equal(time.Unix(10, 0)) || !m[0].MaxTime.Equal(time.Unix(30, 0)) {
+		t.Fatalf("unexpected cpu bounds: %+v", m[0])
+	} else if m[1].Name != "mem" || !m[1].MinTime.Equal(time.Unix(100, 0)) || !m[1].MaxTime.Equal(time.Unix(100, 0)) {
+		t.Fatalf("unexpected mem bounds: %+v", m[1])
+	}
+
+	for _, tt := range []struct {
+		name         string
+		measurements []string
+		min, max     int64
+		exp          []uint64
+	}{
+		{name: "overlapping both", measurements: []string{"cpu"}, min: 25, max: 45, exp: []uint64{10, 11}},
+		{name: "later shard only", measurements: []string{"cpu"}, min: 31, max: 60, exp: []uint64{11}},
+		{name: "inclusive bounds", measurements: []string{"cpu"}, min: 0, max: 10, exp: []uint64{10}},
+		{name: "no data in range", measurements: []string{"cpu"}, min: 60, max: 90},
+		{name: "missing measurement", measurements: []string{"disk"}, min: 0, max: 100},
+		{name: "any measurement", min: 90, max: 110, exp: []uint64{10}},
+	} {
+		ids, err := c.PruneShards(1, []uint64{10, 11, 12}, tt.measurements, time.Unix(tt.min, 0), time.Unix(tt.max, 0))
+		if err != nil {
+			t.Fatalf("%s: %s", tt.name, err)
+		} else if !reflect.DeepEqual(ids, tt.exp) {
+			t.Errorf("%s: got shards %v, expected %v", tt.name, ids, tt.exp)
+		}
+	}
+}
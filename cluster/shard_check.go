@@ -0,0 +1,215 @@
+package cluster
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/uber-go/zap"
+	"github.com/zhexuany/influxcloud/rpc"
+)
+
+// ShardCheck is the result of comparing the shards a node owns in the meta
+// store against the shards stored on the node.
+type ShardCheck struct {
+	Started  time.Time `json:"started"`
+	Finished time.Time `json:"finished,omitempty"`
+
+	// OwnedN is the number of shards the meta store assigns to the node,
+	// and LocalN the number of shards stored on it.
+	OwnedN int `json:"ownedN"`
+	LocalN int `json:"localN"`
+
+	// Missing are the owned shards not stored on the node that should be,
+	// in order of ID. Shards of groups that just started, and shards no
+	// other owner stores, are not expected on the node yet.
+	Missing []MissingShard `json:"missing"`
+
+	// Err is why the check failed, e.g. because the meta store could not
+	// be read.
+	Err string `json:"error,omitempty"`
+}
+
+// MissingShard is a shard the meta store assigns to a node that is not
+// stored on the node.
+type MissingShard struct {
+	ID              uint64   `json:"id"`
+	Database        string   `json:"database"`
+	RetentionPolicy string   `json:"retentionPolicy"`
+	Owners          []uint64 `json:"owners"`
+
+	// Repaired is true once the shard was copied from Source, another
+	// owner. Err is why the last copy failed, if it was attempted.
+	Repaired bool   `json:"repaired"`
+	Source   string `json:"source,omitempty"`
+	Err      string `json:"error,omitempty"`
+
+	// sources are the other owners known to store the shard.
+	sources []string
+}
+
+// startupShardCheck checks the shards of a node when its cluster service
+// opens, copying the missing ones from their other owners if repair is set.
+type startupShardCheck struct {
+	enabled bool
+	repair  bool
+
+	mu     sync.Mutex
+	result *ShardCheck
+}
+
+// report returns the result of the check, or nil if it has not run.
+func (c *startupShardCheck) report() *ShardCheck {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.result == nil {
+		return nil
+	}
+	other := *c.result
+	other.Missing = append([]MissingShard(nil), c.result.Missing...)
+	return &other
+}
+
+// set records the result of the check.
+func (c *startupShardCheck) set(result *ShardCheck) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.result = result
+}
+
+// runStartupShardCheck checks the local shards once, logging a summary.
+func (s *Service) runStartupShardCheck() {
+	defer s.wg.Done()
+
+	result := s.checkShards(time.Now())
+	if result.Err != "" {
+		s.Logger.Warn("startup shard check failed", zap.String("error", result.Err))
+		return
+	}
+	if len(result.Missing) == 0 {
+		s.Logger.Info("startup shard check found no missing shards", zap.Int("owned", result.OwnedN), zap.Int("local", result.LocalN))
+		return
+	}
+
+	var repaired int
+	for _, sh := range result.Missing {
+		if sh.Repaired {
+			repaired++
+		}
+	}
+	s.Logger.Warn("startup shard check found missing shards", zap.Int("owned", result.OwnedN), zap.Int("local", result.LocalN), zap.Int("missing", len(result.Missing)), zap.Int("repaired", repaired))
+}
+
+// checkShards compares the shards owned by this node in the meta store with
+// its local shards, and copies the missing ones from their other owners if
+// repair is enabled. The result is recorded for the status endpoint once
+// the missing shards are known, and again once each is repaired.
+func (s *Service) checkShards(now time.Time) *ShardCheck {
+	result := &ShardCheck{Started: now}
+	if s.ShardStore == nil || s.Node == nil {
+		result.Err = "local shards unknown"
+		s.startupCheck.set(result)
+		return result
+	}
+
+	ownedN, missing, err := s.missingLocalShards(now)
+	if err != nil {
+		result.Err = err.Error()
+		s.startupCheck.set(result)
+		return result
+	}
+	result.OwnedN = ownedN
+	result.LocalN = len(s.ShardStore.ShardIDs())
+
+	result.Missing = []MissingShard{}
+	for _, si := range missing {
+		result.Missing = append(result.Missing, MissingShard{
+			ID:              si.ID,
+			Database:        si.Database,
+			RetentionPolicy: si.Policy,
+			Owners:          si.Owners,
+			sources:         si.sources,
+		})
+		s.Logger.Warn("owned shard missing locally", zap.Uint64("shardID", si.ID), zap.String("database", si.Database), zap.String("retentionPolicy", si.Policy))
+	}
+	s.startupCheck.set(result)
+
+	if s.startupCheck.repair {
+		for i := range result.Missing {
+			select {
+			case <-s.closing:
+				s.startupCheck.mu.Lock()
+				result.Err = "check interrupted by shutdown"
+				s.startupCheck.mu.Unlock()
+				return result
+			default:
+			}
+			sh := s.repairShard(result.Missing[i])
+			s.startupCheck.mu.Lock()
+			result.Missing[i] = sh
+			s.startupCheck.mu.Unlock()
+		}
+	}
+
+	s.startupCheck.mu.Lock()
+	result.Finished = time.Now()
+	s.startupCheck.mu.Unlock()
+	return result
+}
+
+// repairShard copies sh from the first of its other owners it can be copied
+// from, trying only those known to store it if any are.
+func (s *Service) repairShard(sh MissingShard) MissingShard {
+	sources := sh.sources
+	if len(sources) == 0 {
+		var err error
+		if sources, err = s.shardSources(sh.Owners); err != nil {
+			sh.Err = err.Error()
+			return sh
+		}
+	}
+	source, err := s.copyFromSources(sh.ID, sh.Database, sh.RetentionPolicy, sources)
+	if err != nil {
+		sh.Err = err.Error()
+		return sh
+	}
+	sh.Repaired, sh.Source, sh.Err = true, source, ""
+	return sh
+}
+
+// shardSources returns the addresses of the owners of a shard other than
+// this node.
+func (s *Service) shardSources(owners []uint64) ([]string, error) {
+	nodes, err := s.MetaClient.DataNodes()
+	if err != nil {
+		return nil, err
+	}
+	hosts := make(map[uint64]string)
+	for _, n := range nodes {
+		hosts[n.ID] = n.TCPHost
+	}
+
+	var a []string
+	for _, id := range owners {
+		if id != s.Node.ID && hosts[id] != "" {
+			a = append(a, hosts[id])
+		}
+	}
+	return a, nil
+}
+
+// copyFromSources copies shard id from the first of sources it can be copied
+// from, and returns its address.
+func (s *Service) copyFromSources(id uint64, database, policy string, sources []string) (string, error) {
+	err := fmt.Errorf("no other owner of shard %d", id)
+	for _, source := range sources {
+		req := &rpc.CopyShardRequest{Source: source, ShardID: id, Database: database, Policy: policy}
+		if err = s.copyVerifiedShard(req); err != nil {
+			s.Logger.Warn("shard repair failed", zap.Uint64("shardID", id), zap.String("source", source), zap.Error(err))
+			continue
+		}
+		s.Events.Publish(Event{Type: EventShardCopied, NodeID: s.Node.ID, ShardID: id, Message: source})
+		return source, nil
+	}
+	return "", err
+}
@@ -0,0 +1,187 @@
+package cluster
+
+import (
+	"sort"
+	"time"
+
+	"github.com/influxdata/influxdb/services/meta"
+)
+
+// ShardHealth is the replication health of a shard, merging its ownership in
+// the meta store with the live status of the nodes storing it.
+type ShardHealth struct {
+	ID              uint64
+	Database        string
+	RetentionPolicy string
+	ShardGroupID    uint64 // Zero if the shard is not in the meta store.
+
+	// Owners are the nodes the meta store assigns the shard to.
+	Owners []uint64
+
+	// Replicas are the owners of the shard and the other nodes storing it,
+	// sorted by node ID.
+	Replicas []ShardReplica
+
+	// Missing are the owners that were reached but do not store the shard.
+	// Orphans are the nodes storing the shard without owning it, which
+	// includes every node storing a shard the meta store does not have.
+	Missing []uint64
+	Orphans []uint64
+
+	// Corrupt are the nodes storing a copy of the shard that scrubbing
+	// found not to match its checksums.
+	Corrupt []uint64
+}
+
+// Healthy returns true if every owner stores an intact copy of the shard and
+// no other node stores it. Owners that could not be reached are not counted
+// as missing.
+func (h *ShardHealth) Healthy() bool {
+	return len(h.Missing) == 0 && len(h.Orphans) == 0 && len(h.Corrupt) == 0
+}
+
+// ShardReplica is the live status of a shard on a single node.
+type ShardReplica struct {
+	NodeID uint64
+	Owner  bool
+
+	// Exists is set if the node stores the shard, in which case its size,
+	// the time of its last write, and why it is corrupt, if it is, are set
+	// too.
+	Exists    bool
+	Size      int64
+	LastWrite time.Time
+	Corrupt   string
+
+	// Err is set if the node could not be queried, in which case whether it
+	// stores the shard is unknown.
+	Err error
+}
+
+// ShardHealth returns the replication health of every shard in the meta
+// store or stored by a data node, in order of shard ID. Deleted shard
+// groups are left out of the meta store's shards, so that nodes still
+// storing their shards report them as orphans.
+func (c *ShardStatusClient) ShardHealth() ([]ShardHealth, error) {
+	dbs, err := c.MetaClient.Databases()
+	if err != nil {
+		return nil, err
+	}
+	nodes, err := c.ShardStatus()
+	if err != nil {
+		return nil, err
+	}
+	return shardHealth(dbs, nodes), nil
+}
+
+// shardHealth merges the shards of dbs with the shards stored by nodes.
+func shardHealth(dbs []meta.DatabaseInfo, nodes []NodeShardStatus) []ShardHealth {
+	shards := make(map[uint64]*ShardHealth)
+	for _, db := range dbs {
+		for _, rp := range db.RetentionPolicies {
+			for _, sg := range rp.ShardGroups {
+				if sg.Deleted() {
+					continue
+				}
+				for _, sh := range sg.Shards {
+					h := &ShardHealth{
+						ID:              sh.ID,
+						Database:        db.Name,
+						RetentionPolicy: rp.Name,
+						ShardGroupID:    sg.ID,
+					}
+					for _, o := range sh.Owners {
+						h.Owners = append(h.Owners, o.NodeID)
+					}
+					shards[sh.ID] = h
+				}
+			}
+		}
+	}
+
+	unreachable := make(map[uint64]error)
+	for _, n := range nodes {
+		if n.Err != nil {
+			unreachable[n.NodeID] = n.Err
+			continue
+		}
+		for _, st := range n.Shards {
+			h := shards[st.ID]
+			if h == nil {
+				h = &ShardHealth{ID: st.ID, Database: st.Database, RetentionPolicy: st.Policy}
+				shards[st.ID] = h
+			}
+			owner := h.owns(n.NodeID)
+			h.Replicas = append(h.Replicas, ShardReplica{
+				NodeID:    n.NodeID,
+				Owner:     owner,
+				Exists:    true,
+				Size:      st.Size,
+				LastWrite: st.LastWrite,
+				Corrupt:   st.Corrupt,
+			})
+			if !owner {
+				h.Orphans = append(h.Orphans, n.NodeID)
+			}
+			if st.Corrupt != "" {
+				h.Corrupt = append(h.Corrupt, n.NodeID)
+			}
+		}
+	}
+
+	// Owners that did not report the shard are missing it, unless they
+	// could not be reached. Owners that are no longer data nodes are
+	// missing it too.
+	for _, h := range shards {
+		for _, id := range h.Owners {
+			if h.stored(id) {
+				continue
+			}
+			err := unreachable[id]
+			h.Replicas = append(h.Replicas, ShardReplica{NodeID: id, Owner: true, Err: err})
+			if err == nil {
+				h.Missing = append(h.Missing, id)
+			}
+		}
+	}
+
+	a := make([]ShardHealth, 0, len(shards))
+	for _, h := range shards {
+		sort.Sort(shardReplicas(h.Replicas))
+		a = append(a, *h)
+	}
+	sort.Sort(shardHealths(a))
+	return a
+}
+
+// owns returns true if the meta store assigns the shard to nodeID.
+func (h *ShardHealth) owns(nodeID uint64) bool {
+	for _, id := range h.Owners {
+		if id == nodeID {
+			return true
+		}
+	}
+	return false
+}
+
+// stored returns true if nodeID reported storing the shard.
+func (h *ShardHealth) stored(nodeID uint64) bool {
+	for _, r := range h.Replicas {
+		if r.NodeID == nodeID && r.Exists {
+			return true
+		}
+	}
+	return false
+}
+
+type shardHealths []ShardHealth
+
+func (a shardHealths) Len() int           { return len(a) }
+func (a shardHealths) Less(i, j int) bool { return a[i].ID < a[j].ID }
+func (a shardHealths) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
+
+type shardReplicas []ShardReplica
+
+func (a shardReplicas) Len() int           { return len(a) }
+func (a shardReplicas) Less(i, j int) bool { return a[i].NodeID < a[j].NodeID }
+func (a shardReplicas) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
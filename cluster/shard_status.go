@@ -0,0 +1,95 @@
+package cluster
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/influxdata/influxdb/services/meta"
+	"github.com/zhexuany/influxcloud/rpc"
+	"github.com/zhexuany/influxcloud/tlv"
+)
+
+// NodeShardStatus is the status of the shards stored on a single data node.
+type NodeShardStatus struct {
+	NodeID  uint64
+	TCPHost string
+	Shards  []rpc.ShardStatus
+
+	// Load is the backpressure on the node's local store.
+	Load rpc.NodeLoad
+
+	// Err is set if the node could not be queried.
+	Err error
+}
+
+// ShardStatusClient collects shard status from every data node in the cluster.
+type ShardStatusClient struct {
+	timeout time.Duration
+
+	// Dialer connects to the data nodes.
+	Dialer *Dialer
+
+	MetaClient interface {
+		DataNodes() ([]meta.NodeInfo, error)
+		Databases() ([]meta.DatabaseInfo, error)
+	}
+}
+
+// NewShardStatusClient returns a new instance of ShardStatusClient.
+func NewShardStatusClient(timeout time.Duration) *ShardStatusClient {
+	return &ShardStatusClient{timeout: timeout, Dialer: DefaultDialer}
+}
+
+// ShardStatus requests the status of the given shards, or of all shards if
+// none are given, from every data node. Nodes are queried concurrently and
+// returned in order of node ID. A node that cannot be reached is returned
+// with its Err set instead of failing the whole request.
+func (c *ShardStatusClient) ShardStatus(shardIDs ...uint64) ([]NodeShardStatus, error) {
+	nodes, err := c.MetaClient.DataNodes()
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]NodeShardStatus, len(nodes))
+	var wg sync.WaitGroup
+	for i, n := range nodes {
+		wg.Add(1)
+		go func(i int, n meta.NodeInfo) {
+			defer wg.Done()
+			resp, err := c.nodeShardStatus(n.TCPHost, shardIDs)
+			statuses[i] = NodeShardStatus{
+				NodeID:  n.ID,
+				TCPHost: n.TCPHost,
+				Shards:  resp.Shards,
+				Load:    resp.Load,
+				Err:     err,
+			}
+		}(i, n)
+	}
+	wg.Wait()
+
+	sort.Sort(nodeShardStatuses(statuses))
+	return statuses, nil
+}
+
+// nodeShardStatus requests the status of shards, and the load of its store,
+// from the node at addr.
+func (c *ShardStatusClient) nodeShardStatus(addr string, shardIDs []uint64) (rpc.ShardStatusResponse, error) {
+	var resp rpc.ShardStatusResponse
+	if err := c.Dialer.dialRPC(addr, c.timeout, tlv.ShardStatusRequestMessage, &rpc.ShardStatusRequest{
+		ShardIDs: shardIDs,
+	}, &resp); err != nil {
+		return rpc.ShardStatusResponse{}, err
+	} else if resp.Err != "" {
+		return rpc.ShardStatusResponse{}, errors.New(resp.Err)
+	}
+	return resp, nil
+}
+
+type nodeShardStatuses []NodeShardStatus
+
+func (a nodeShardStatuses) Len() int           { return len(a) }
+func (a nodeShardStatuses) Less(i, j int) bool { return a[i].NodeID < a[j].NodeID }
+func (a nodeShardStatuses) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
@@ -0,0 +1,188 @@
+package cluster_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxdb/services/meta"
+	"github.com/influxdata/influxdb/tsdb"
+	_ "github.com/influxdata/influxdb/tsdb/engine"
+	"github.com/zhexuany/influxcloud/cluster"
+)
+
+// Ensure the status of local shards is collected from every data node.
+func TestShardStatusClient_ShardStatus(t *testing.T) {
+	store := MustOpenStore()
+	defer store.Close()
+
+	if err := store.CreateShard("db0", "rp0", 10, true); err != nil {
+		t.Fatal(err)
+	}
+	pt := models.MustNewPoint("cpu", models.NewTags(map[string]string{"host": "server0"}), map[string]interface{}{"value": 1.0}, time.Unix(0, 0))
+	if err := store.WriteToShard(10, []models.Point{pt}); err != nil {
+		t.Fatal(err)
+	}
+
+	s := MustOpenService()
+	defer s.Close()
+	s.Service.ShardStore = store
+
+	start := time.Unix(0, 0).UTC()
+	s.MetaClient.DatabasesFn = func() ([]meta.DatabaseInfo, error) {
+		return []meta.DatabaseInfo{{
+			Name: "db0",
+			RetentionPolicies: []meta.RetentionPolicyInfo{{
+				Name: "rp0",
+				ShardGroups: []meta.ShardGroupInfo{{
+					ID:        1,
+					StartTime: start,
+					EndTime:   start.Add(time.Hour),
+					Shards:    []meta.ShardInfo{{ID: 10, Owners: []meta.ShardOwner{{NodeID: 1}}}},
+				}},
+			}},
+		}}, nil
+	}
+
+	c := cluster.NewShardStatusClient(time.Second)
+	c.MetaClient = &ServiceMetaClient{
+		DataNodesFn: func() ([]meta.NodeInfo, error) {
+			return []meta.NodeInfo{
+				{ID: 2, TCPHost: "127.0.0.1:0"},
+				{ID: 1, TCPHost: s.Addr().String()},
+			}, nil
+		},
+	}
+
+	nodes, err := c.ShardStatus()
+	if err != nil {
+		t.Fatal(err)
+	} else if len(nodes) != 2 {
+		t.Fatalf("unexpected node count: %d", len(nodes))
+	} else if nodes[0].NodeID != 1 || nodes[0].Err != nil {
+		t.Fatalf("unexpected node: %+v", nodes[0])
+	} else if nodes[1].NodeID != 2 || nodes[1].Err == nil {
+		t.Fatalf("expected error from unreachable node: %+v", nodes[1])
+	}
+
+	shards := nodes[0].Shards
+	if len(shards) != 1 {
+		t.Fatalf("unexpected shards: %+v", shards)
+	} else if sh := shards[0]; sh.ID != 10 || sh.Database != "db0" || sh.Policy != "rp0" {
+		t.Fatalf("unexpected shard: %+v", sh)
+	} else if sh.Size <= 0 || sh.SeriesN != 1 {
+		t.Fatalf("unexpected shard size or series: %+v", sh)
+	} else if sh.LastWrite.IsZero() {
+		t.Fatalf("expected last write time: %+v", sh)
+	} else if !sh.LastModified.IsZero() {
+		t.Fatalf("unexpected last modified time before the cache is snapshotted: %+v", sh)
+	} else if !sh.Cold {
+		t.Fatalf("expected shard from ended shard group to be cold: %+v", sh)
+	}
+
+	// Unknown shards are skipped.
+	if nodes, err := c.ShardStatus(11); err != nil {
+		t.Fatal(err)
+	} else if len(nodes[0].Shards) != 0 {
+		t.Fatalf("unexpected shards: %+v", nodes[0].Shards)
+	}
+}
+
+// Ensure shard ownership in the meta store is merged with the shards stored
+// by each data node, flagging missing replicas and orphan shards.
+func TestShardStatusClient_ShardHealth(t *testing.T) {
+	store := MustOpenStore()
+	defer store.Close()
+	for _, id := range []uint64{10, 11} {
+		if err := store.CreateShard("db0", "rp0", id, true); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	s := MustOpenService()
+	defer s.Close()
+	s.Service.ShardStore = store
+
+	start := time.Now().UTC()
+	dbs := []meta.DatabaseInfo{{
+		Name: "db0",
+		RetentionPolicies: []meta.RetentionPolicyInfo{{
+			Name: "rp0",
+			ShardGroups: []meta.ShardGroupInfo{{
+				ID:        1,
+				StartTime: start,
+				EndTime:   start.Add(time.Hour),
+				Shards: []meta.ShardInfo{
+					{ID: 10, Owners: []meta.ShardOwner{{NodeID: 1}}},
+					{ID: 12, Owners: []meta.ShardOwner{{NodeID: 1}, {NodeID: 2}}},
+				},
+			}},
+		}},
+	}}
+	s.MetaClient.DatabasesFn = func() ([]meta.DatabaseInfo, error) { return dbs, nil }
+
+	c := cluster.NewShardStatusClient(time.Second)
+	c.MetaClient = &ServiceMetaClient{
+		DatabasesFn: func() ([]meta.DatabaseInfo, error) { return dbs, nil },
+		DataNodesFn: func() ([]meta.NodeInfo, error) {
+			return []meta.NodeInfo{
+				{ID: 1, TCPHost: s.Addr().String()},
+				{ID: 2, TCPHost: "127.0.0.1:0"},
+			}, nil
+		},
+	}
+
+	shards, err := c.ShardHealth()
+	if err != nil {
+		t.Fatal(err)
+	} else if len(shards) != 3 {
+		t.Fatalf("unexpected shards: %+v", shards)
+	}
+
+	if sh := shards[0]; sh.ID != 10 || !sh.Healthy() || len(sh.Replicas) != 1 || !sh.Replicas[0].Exists || !sh.Replicas[0].Owner {
+		t.Fatalf("unexpected healthy shard: %+v", sh)
+	}
+
+	// The shard is stored by node 1 but not in the meta store.
+	if sh := shards[1]; sh.ID != 11 || sh.Healthy() || sh.ShardGroupID != 0 || !reflect.DeepEqual(sh.Orphans, []uint64{1}) {
+		t.Fatalf("unexpected orphan shard: %+v", sh)
+	}
+
+	// Node 1 is missing the shard, and whether unreachable node 2 has it is
+	// unknown.
+	if sh := shards[2]; sh.ID != 12 || sh.Healthy() || !reflect.DeepEqual(sh.Missing, []uint64{1}) {
+		t.Fatalf("unexpected shard with missing replica: %+v", sh)
+	} else if len(sh.Replicas) != 2 || sh.Replicas[0].Exists || sh.Replicas[1].Err == nil {
+		t.Fatalf("unexpected replicas: %+v", sh.Replicas)
+	}
+}
+
+// Store is a tsdb.Store in a temporary directory.
+type Store struct {
+	*tsdb.Store
+}
+
+// MustOpenStore returns a new, open Store at a temporary path. Panic on error.
+func MustOpenStore() *Store {
+	path, err := ioutil.TempDir("", "influxcloud-tsdb-")
+	if err != nil {
+		panic(err)
+	}
+
+	s := &Store{Store: tsdb.NewStore(path)}
+	s.EngineOptions.Config.WALDir = filepath.Join(path, "wal")
+	if err := s.Open(); err != nil {
+		panic(err)
+	}
+	return s
+}
+
+// Close closes the store and removes its data.
+func (s *Store) Close() error {
+	defer os.RemoveAll(s.Path())
+	return s.Store.Close()
+}
@@ -2,12 +2,17 @@ package cluster
 
 import (
 	"bufio"
+	"errors"
 	"fmt"
 	"net"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/influxdata/influxdb/models"
 	"github.com/influxdata/influxdb/services/meta"
+	cloudMeta "github.com/zhexuany/influxcloud/meta"
 	"github.com/zhexuany/influxcloud/rpc"
 	"github.com/zhexuany/influxcloud/tlv"
 )
@@ -15,56 +20,172 @@ import (
 // ShardWriter writes a set of points to a shard.
 type ShardWriter struct {
 	pool           *clientPool
-	timeout        time.Duration
+	timeout        time.Duration // accessed atomically, see Reload
+	pointCodec     uint32        // rpc.PointCodecID, accessed atomically, see Reload
 	maxConnections int
 
 	MetaClient interface {
 		ShardOwner(shardID uint64) (database, policy string, owners meta.ShardInfo)
 		DataNode(id uint64) (ni *meta.NodeInfo, err error)
 	}
+
+	// Multiplex sends all writes to a node over a single connection with
+	// many writes in flight at once, instead of one write per pooled
+	// connection. Nodes that do not support pipelining are written to over
+	// pooled connections instead.
+	Multiplex bool
+
+	// NodeID and Version identify this node in the hello message sent on
+	// multiplexed connections, so that the remote node can log version skew.
+	// The hello message also carries the cluster ID of MetaClient, if it
+	// has a ClusterID method, so that nodes of other clusters are refused.
+	// NodeID is also sent as the origin of each write, so that the remote
+	// node can log which node sent a failing write.
+	NodeID  uint64
+	Version string
+
+	// LateWrites is told about multiplexed writes that succeed after
+	// returning ErrTimeout, e.g. so that they are not also queued in hinted
+	// handoff. Writes on pooled connections cannot succeed late, as the
+	// connection is closed once they time out.
+	LateWrites interface {
+		LateWriteSucceeded(nodeID uint64, points *rpc.EncodedPoints)
+	}
+
+	// KeepAliveInterval is how often idle connections are pinged once the
+	// writer is opened. Connections that fail to answer within the writer's
+	// timeout are closed, as are connections to nodes removed from the
+	// cluster or whose hostname no longer resolves to the IP they were
+	// dialed at. A value of zero disables pings.
+	KeepAliveInterval time.Duration
+
+	// Dialer connects to the nodes written to.
+	Dialer *Dialer
+
+	muxMu    sync.Mutex
+	muxConns map[uint64]*muxConn
+	pooled   map[uint64]bool // nodes without pipelining, written to over pooled connections
+
+	wg      sync.WaitGroup
+	closing chan struct{}
 }
 
 // NewShardWriter returns a new instance of ShardWriter.
 func NewShardWriter(timeout time.Duration, maxConnections int) *ShardWriter {
 	return &ShardWriter{
-		pool:           newClientPool(),
-		timeout:        timeout,
-		maxConnections: maxConnections,
+		pool:              newClientPool(),
+		timeout:           timeout,
+		maxConnections:    maxConnections,
+		KeepAliveInterval: DefaultKeepAliveInterval,
+		Dialer:            DefaultDialer,
+	}
+}
+
+// Reload applies the shard writer timeout and the write point codec of c to
+// the writes and dials made from now on. It is safe to call while writes are
+// in flight.
+func (w *ShardWriter) Reload(c Config) error {
+	codec, ok := rpc.PointCodecByName(c.WritePointCodec)
+	if !ok && c.WritePointCodec != "" {
+		return fmt.Errorf("unknown write point codec: %q", c.WritePointCodec)
+	}
+	atomic.StoreInt64((*int64)(&w.timeout), int64(c.ShardWriterTimeout))
+	atomic.StoreUint32(&w.pointCodec, uint32(codec))
+	return nil
+}
+
+// writePointCodec returns the codec the points of writes are encoded with
+// for the nodes that support it.
+func (w *ShardWriter) writePointCodec() rpc.PointCodecID {
+	return rpc.PointCodecID(atomic.LoadUint32(&w.pointCodec))
+}
+
+// writeTimeout returns the timeout of writes and dials.
+func (w *ShardWriter) writeTimeout() time.Duration {
+	return time.Duration(atomic.LoadInt64((*int64)(&w.timeout)))
+}
+
+// Open starts pinging idle connections every KeepAliveInterval.
+func (w *ShardWriter) Open() error {
+	if w.KeepAliveInterval <= 0 || w.closing != nil {
+		return nil
 	}
+
+	w.closing = make(chan struct{})
+	w.wg.Add(1)
+	go w.keepAlive(w.KeepAliveInterval, w.closing)
+	return nil
 }
 
 // WriteShard writes time series points to a shard
 func (w *ShardWriter) WriteShard(shardID, ownerID uint64, points []models.Point) error {
-	buf := make([]byte, 0)
-	for _, p := range points {
-		b, err := p.MarshalBinary()
-		if err != nil {
-			return fmt.Errorf("failed to marshal point: %v", err)
-		}
-		buf = append(buf, b...)
-	}
-	return w.WriteShardBinary(shardID, ownerID, buf)
+	return w.WriteShardWithRequestID("", shardID, ownerID, points)
+}
 
+// WriteShardWithRequestID writes time series points to a shard on behalf of
+// the client request identified by requestID.
+func (w *ShardWriter) WriteShardWithRequestID(requestID string, shardID, ownerID uint64, points []models.Point) error {
+	return w.WriteShardWithSpan(noopSpan{}, requestID, shardID, ownerID, points)
 }
 
-// WriteShardBinary writes binary time series points to a shard
+// WriteShardWithSpan writes time series points to a shard on behalf of the
+// client request identified by requestID. The context of span is sent to the
+// remote node so that its handling of the write joins the same trace.
+func (w *ShardWriter) WriteShardWithSpan(span Span, requestID string, shardID, ownerID uint64, points []models.Point) error {
+	e, err := rpc.EncodePoints(points)
+	if err != nil {
+		return err
+	}
+	return w.WriteEncodedShard(span, requestID, time.Time{}, shardID, ownerID, e)
+}
+
+// WriteShardBinary writes a binary time series point to a shard
 func (w *ShardWriter) WriteShardBinary(shardID, ownerID uint64, buf []byte) error {
-	c, err := w.dial(ownerID)
+	return w.WriteEncodedShard(noopSpan{}, "", time.Time{}, shardID, ownerID, rpc.NewEncodedPoints([][]byte{buf}))
+}
+
+// deadlineGrace is how long past the deadline of a write the writer waits
+// for the remote node to report that it dropped the write, so that the write
+// is only in doubt if the node does not respond at all.
+const deadlineGrace = time.Second
+
+// WriteEncodedShard writes a batch of encoded points to a shard. The batch
+// is sent as is, so it can be shared with writes to the shard's other owners.
+// If deadline is not zero, the time left until it is sent with the write and
+// the write fails once it has passed, even if the timeout of the writer has
+// not.
+func (w *ShardWriter) WriteEncodedShard(span Span, requestID string, deadline time.Time, shardID, ownerID uint64, points *rpc.EncodedPoints) error {
+	return w.writeEncodedShard(span, requestID, deadline, shardID, ownerID, points, 0, 0)
+}
+
+// WriteHandoffShard writes the points of the hinted handoff writes up to seq
+// of the queue of node queueID to a shard. The owner skips the write if it
+// already applied the writes of the queue up to seq, so that writes replayed
+// after the queue failed to advance are not applied twice.
+func (w *ShardWriter) WriteHandoffShard(shardID, ownerID, queueID, seq uint64, points []models.Point) error {
+	e, err := rpc.EncodePoints(points)
 	if err != nil {
 		return err
 	}
+	return w.writeEncodedShard(noopSpan{}, "", time.Time{}, shardID, ownerID, e, queueID, seq)
+}
 
-	conn, ok := c.(*pooledConn)
-	if !ok {
-		panic("wrong connection type")
+// writeEncodedShard is WriteEncodedShard for the hinted handoff writes up to
+// seq of queue queueID, if seq is not zero.
+func (w *ShardWriter) writeEncodedShard(span Span, requestID string, deadline time.Time, shardID, ownerID uint64, points *rpc.EncodedPoints, queueID, seq uint64) error {
+	var timeout time.Duration
+	if !deadline.IsZero() {
+		if timeout = time.Until(deadline); timeout <= 0 {
+			return ErrTimeout
+		}
 	}
 
-	// Close is not literally closing such connects.
-	// Instead, Close will put connects back to pool
-	// which can imporve the memory usage
-	defer func(conn net.Conn) {
-		conn.Close() // return to pool
-	}(conn)
+	// Wait for the response no longer than the writer's timeout, nor much
+	// past the deadline.
+	wait := w.writeTimeout()
+	if timeout > 0 && timeout+deadlineGrace < wait {
+		wait = timeout + deadlineGrace
+	}
 
 	// Determine the location of this shard and whether it still exists
 	db, rp, _ := w.MetaClient.ShardOwner(shardID)
@@ -74,7 +195,23 @@ func (w *ShardWriter) WriteShardBinary(shardID, ownerID uint64, buf []byte) erro
 	request.SetShardID(shardID)
 	request.SetDatabase(db)
 	request.SetRetentionPolicy(rp)
-	request.SetBinaryPoints(buf)
+	request.SetEncodedPoints(points)
+	if requestID != "" {
+		request.SetRequestID(requestID)
+	}
+	if w.NodeID != 0 {
+		request.SetOriginNodeID(w.NodeID)
+	}
+	if timeout > 0 {
+		request.SetTimeout(timeout)
+	}
+	if seq != 0 {
+		request.SetHandoffSequence(queueID, seq)
+	}
+
+	if w.Multiplex {
+		return w.writeShardMux(span, ownerID, &request, points, wait)
+	}
 
 	// Marshal into protocol buffers.
 	reqB, err := request.MarshalBinary()
@@ -82,34 +219,259 @@ func (w *ShardWriter) WriteShardBinary(shardID, ownerID uint64, buf []byte) erro
 		return err
 	}
 
+	sent, err := w.writeShardConn(span, ownerID, reqB, wait)
+	if sent && err != nil && isClosedConnErr(err) {
+		// The remote node closed the pooled connection, e.g. after a network
+		// blip or a restart. Writing the same points again is harmless, so
+		// retry once on a new connection.
+		_, err = w.writeShardConn(span, ownerID, reqB, wait)
+	}
+	return err
+}
+
+// ForwardPoints sends a whole write to nodeID, which maps the points to shards
+// and writes them itself. If deadline is not zero, the time left until it is
+// sent with the write and the write fails with ErrTimeout once it has passed.
+// A write the remote node failed is returned as a *rpc.WriteShardError.
+func (w *ShardWriter) ForwardPoints(span Span, requestID string, deadline time.Time, nodeID uint64, database, retentionPolicy string, consistencyLevel models.ConsistencyLevel, points *rpc.EncodedPoints) error {
+	// The remote node writes each shard before responding, so wait for as
+	// long as it may take rather than for a single round trip.
+	timeout := w.writeTimeout()
+	if !deadline.IsZero() {
+		if timeout = time.Until(deadline); timeout <= 0 {
+			return ErrTimeout
+		}
+	}
+
+	reqB, err := (&rpc.WritePointsRequest{
+		Database:         database,
+		RetentionPolicy:  retentionPolicy,
+		ConsistencyLevel: consistencyLevel,
+		RequestID:        requestID,
+		Timeout:          timeout,
+		Points:           points,
+	}).MarshalBinary()
+	if err != nil {
+		return err
+	}
+
+	buf, sent, err := w.requestConn(span, nodeID, tlv.WritePointsRequestMessage, reqB, timeout)
+	if sent && err != nil && isClosedConnErr(err) {
+		// As with shard writes, retry once on a new connection.
+		buf, _, err = w.requestConn(span, nodeID, tlv.WritePointsRequestMessage, reqB, timeout)
+	}
+	if err != nil {
+		return err
+	}
+
+	var resp rpc.WritePointsResponse
+	if err := resp.UnmarshalBinary(buf); err != nil {
+		return err
+	}
+	if resp.Code != rpc.CodeOK {
+		return &rpc.WriteShardError{Code: resp.Code, Message: resp.Err}
+	}
+	return nil
+}
+
+// writeShardConn sends a marshaled write request to ownerID over a pooled
+// connection and waits up to timeout for the response. sent is true if the
+// request failed after a connection was obtained.
+func (w *ShardWriter) writeShardConn(span Span, ownerID uint64, req []byte, timeout time.Duration) (sent bool, err error) {
+	buf, sent, err := w.requestConn(span, ownerID, tlv.WriteShardRequestMessage, req, timeout)
+	if err != nil {
+		return sent, err
+	}
+	return false, decodeWriteShardResponse(buf)
+}
+
+// requestConn sends a marshaled request of type typ to nodeID over a pooled
+// connection and waits up to timeout for the response. sent is true if the
+// request failed after a connection was obtained.
+func (w *ShardWriter) requestConn(span Span, nodeID uint64, typ byte, req []byte, timeout time.Duration) (resp []byte, sent bool, err error) {
+	c, err := w.dial(nodeID)
+	if err != nil {
+		return nil, false, err
+	}
+
+	conn, ok := c.(*pooledConn)
+	if !ok {
+		panic("wrong connection type")
+	}
+
+	// Close is not literally closing such connects.
+	// Instead, Close will put connects back to pool
+	// which can imporve the memory usage
+	defer func(conn net.Conn) {
+		conn.Close() // return to pool
+	}(conn)
+
 	// Write request.
-	conn.SetWriteDeadline(time.Now().Add(w.timeout))
-	if err := tlv.WriteTLV(conn, tlv.WriteShardRequestMessage, reqB); err != nil {
+	conn.SetWriteDeadline(time.Now().Add(w.writeTimeout()))
+	if err := writeSpanContext(conn, span); err != nil {
 		conn.MarkUnusable()
-		return err
+		return nil, true, err
+	}
+	if err := tlv.WriteTLV(conn, typ, req); err != nil {
+		conn.MarkUnusable()
+		return nil, true, err
 	}
 
 	// Flush all buffered data
 	if err := bufio.NewWriter(conn).Flush(); err != nil {
-		return err
+		return nil, true, err
 	}
 
 	// Read the response.
-	conn.SetReadDeadline(time.Now().Add(w.timeout))
-	_, buf, err = tlv.ReadTLV(conn)
+	conn.SetReadDeadline(time.Now().Add(timeout))
+	_, buf, err := tlv.ReadTLV(conn)
 	if err != nil {
 		conn.MarkUnusable()
+		return nil, true, err
+	}
+
+	return buf, false, nil
+}
+
+// isClosedConnErr returns true if err shows that the remote end closed the
+// connection.
+func isClosedConnErr(err error) bool {
+	msg := err.Error()
+	return strings.HasSuffix(msg, "EOF") ||
+		strings.HasSuffix(msg, "connection reset by peer") ||
+		strings.HasSuffix(msg, "broken pipe")
+}
+
+// writeShardMux sends a write request of points to ownerID over its
+// multiplexed connection, with the points encoded with the write point codec
+// if the node supports it.
+func (w *ShardWriter) writeShardMux(span Span, ownerID uint64, request *rpc.WriteShardRequest, points *rpc.EncodedPoints, timeout time.Duration) error {
+	conn, err := w.muxConn(ownerID)
+	if err == errNoPipelining {
+		// Downgrade to the pooled connections every node supports.
+		req, err := request.MarshalBinary()
+		if err != nil {
+			return err
+		}
+		_, err = w.writeShardConn(span, ownerID, req, timeout)
+		return err
+	} else if err != nil {
+		return err
+	}
+
+	if codec := w.writePointCodec(); codec != rpc.BinaryPointCodec && conn.supportsPointCodec(codec) {
+		batch, err := points.Batch(codec)
+		if err != nil {
+			return err
+		}
+		request.SetPointBatch(codec, batch)
+	}
+	req, err := request.MarshalBinary()
+	if err != nil {
+		return err
+	}
+
+	var late func(typ byte, buf []byte)
+	if w.LateWrites != nil {
+		late = func(typ byte, buf []byte) {
+			if decodeWriteShardResponse(buf) == nil {
+				w.LateWrites.LateWriteSucceeded(ownerID, points)
+			}
+		}
+	}
+
+	_, buf, err := conn.Request(span, tlv.WriteShardRequestMessage, req, timeout, late)
+	if err != nil {
 		return err
 	}
+	err = decodeWriteShardResponse(buf)
+	if e, ok := err.(*rpc.WriteShardError); ok && e.Code == rpc.CodeChecksumMismatch {
+		// The write was corrupted in transit and rejected, so send it once
+		// more.
+		if _, buf, err = conn.Request(span, tlv.WriteShardRequestMessage, req, timeout, late); err != nil {
+			return err
+		}
+		err = decodeWriteShardResponse(buf)
+	}
+	return err
+}
+
+// muxConn returns the multiplexed connection to nodeID, dialing a new one if
+// there is none or the previous one has failed. errNoPipelining is returned
+// if the node does not support multiplexed connections.
+func (w *ShardWriter) muxConn(nodeID uint64) (*muxConn, error) {
+	w.muxMu.Lock()
+	conn, pooled := w.muxConns[nodeID], w.pooled[nodeID]
+	w.muxMu.Unlock()
+	if pooled {
+		return nil, errNoPipelining
+	} else if conn != nil && conn.error() == nil {
+		return conn, nil
+	}
+
+	ni, err := w.MetaClient.DataNode(nodeID)
+	if err != nil {
+		return nil, err
+	} else if ni == nil {
+		return nil, fmt.Errorf("node %d does not exist", nodeID)
+	}
+
+	conn, err = dialMuxConn(w.Dialer, ni.TCPHost, w.writeTimeout(), w.helloRequest())
+	if err == errNoPipelining {
+		w.muxMu.Lock()
+		if w.pooled == nil {
+			w.pooled = make(map[uint64]bool)
+		}
+		w.pooled[nodeID] = true
+		w.muxMu.Unlock()
+		return nil, err
+	} else if err != nil && isLegacyHelloErr(err) {
+		// Nodes older than the hello message do not answer it, but do
+		// support multiplexed connections.
+		conn, err = dialMuxConn(w.Dialer, ni.TCPHost, w.writeTimeout(), nil)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	w.muxMu.Lock()
+	defer w.muxMu.Unlock()
+
+	// Keep the connection dialed by another write in the meantime, if any.
+	if other := w.muxConns[nodeID]; other != nil && other.error() == nil {
+		conn.Close()
+		return other, nil
+	}
+	if w.muxConns == nil {
+		w.muxConns = make(map[uint64]*muxConn)
+	}
+	w.muxConns[nodeID] = conn
+	return conn, nil
+}
+
+// helloRequest returns the hello message said on the connections dialed.
+func (w *ShardWriter) helloRequest() *rpc.HelloRequest {
+	return newHelloRequest(w.NodeID, clusterIDOf(w.MetaClient), w.Version)
+}
+
+// isLegacyHelloErr returns true if err shows that the remote node did not
+// answer a hello message, as nodes older than the message do not.
+func isLegacyHelloErr(err error) bool {
+	if e, ok := err.(net.Error); ok && e.Timeout() {
+		return true
+	}
+	return isClosedConnErr(err)
+}
 
-	// Unmarshal response.
+// decodeWriteShardResponse returns the error reported in a WriteShard response.
+func decodeWriteShardResponse(buf []byte) error {
 	var response rpc.WriteShardResponse
 	if err := response.UnmarshalBinary(buf); err != nil {
 		return err
 	}
 
-	if response.Code() != 0 {
-		return fmt.Errorf("error code %d: %s", response.Code(), response.Message())
+	if code := rpc.ErrorCode(response.Code()); code != rpc.CodeOK {
+		return &rpc.WriteShardError{Code: code, Message: response.Message(), RequestID: response.RequestID(), Rejected: response.Rejected()}
 	}
 
 	return nil
@@ -119,10 +481,10 @@ func (w *ShardWriter) dial(nodeID uint64) (net.Conn, error) {
 	// If we don't have a connection pool for that addr yet, create one
 	_, ok := w.pool.getPool(nodeID)
 	if !ok {
-		factory := &connFactory{nodeID: nodeID, clientPool: w.pool, timeout: w.timeout}
+		factory := &connFactory{nodeID: nodeID, clientPool: w.pool, timeout: w.writeTimeout(), dialer: w.Dialer, hello: w.helloRequest}
 		factory.metaClient = w.MetaClient
 
-		p, err := NewBoundedPool(1, w.maxConnections, w.timeout, factory.dial)
+		p, err := NewBoundedPool(1, w.maxConnections, w.writeTimeout(), factory.dial)
 		if err != nil {
 			return nil, err
 		}
@@ -131,26 +493,146 @@ func (w *ShardWriter) dial(nodeID uint64) (net.Conn, error) {
 	return w.pool.conn(nodeID)
 }
 
+// keepAlive checks the writer's connections every interval until closing is
+// closed.
+func (w *ShardWriter) keepAlive(interval time.Duration, closing chan struct{}) {
+	defer w.wg.Done()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-closing:
+			return
+		case <-ticker.C:
+			w.checkConns()
+		}
+	}
+}
+
+// checkConns closes the connections to nodes that are no longer in the
+// cluster or that moved to another IP, and pings the idle connections to
+// the others.
+func (w *ShardWriter) checkConns() {
+	for _, nodeID := range w.pool.nodeIDs() {
+		addr, removed := w.nodeAddr(nodeID)
+		if removed {
+			w.pool.remove(nodeID)
+			continue
+		}
+
+		if p, ok := w.pool.getPool(nodeID); ok {
+			if p, ok := p.(*boundedPool); ok {
+				p.checkIdle(func(conn net.Conn) error {
+					if w.Dialer.moved(addr, conn.RemoteAddr()) {
+						return errNodeMoved
+					}
+					return ping(conn, w.writeTimeout())
+				})
+			}
+		}
+	}
+
+	w.muxMu.Lock()
+	muxConns := make(map[uint64]*muxConn, len(w.muxConns))
+	for nodeID, conn := range w.muxConns {
+		muxConns[nodeID] = conn
+	}
+	w.muxMu.Unlock()
+
+	for nodeID, conn := range muxConns {
+		if addr, removed := w.nodeAddr(nodeID); removed {
+			conn.Close()
+		} else if w.Dialer.moved(addr, conn.conn.RemoteAddr()) {
+			conn.fail(errNodeMoved)
+		} else if conn.error() == nil {
+			// Fail the connection if the ping does, so the next write redials.
+			if _, _, err := conn.Request(noopSpan{}, tlv.PingRequestMessage, nil, 0, nil); err != nil {
+				conn.fail(err)
+			}
+		}
+		if conn.error() == nil {
+			continue
+		}
+
+		w.muxMu.Lock()
+		if w.muxConns[nodeID] == conn {
+			delete(w.muxConns, nodeID)
+		}
+		w.muxMu.Unlock()
+	}
+}
+
+// nodeAddr returns the TCP address of nodeID, or true if the meta store no
+// longer has it. The address is empty if it could not be looked up.
+func (w *ShardWriter) nodeAddr(nodeID uint64) (string, bool) {
+	ni, err := w.MetaClient.DataNode(nodeID)
+	if err == cloudMeta.ErrNodeNotFound || (err == nil && ni == nil) {
+		return "", true
+	} else if err != nil {
+		return "", false
+	}
+	return ni.TCPHost, false
+}
+
+// ping checks that the remote end of conn answers within timeout.
+func ping(conn net.Conn, timeout time.Duration) error {
+	conn.SetDeadline(time.Now().Add(timeout))
+	if err := tlv.WriteTLV(conn, tlv.PingRequestMessage, nil); err != nil {
+		return err
+	}
+
+	if typ, _, err := tlv.ReadTLV(conn); err != nil {
+		return err
+	} else if typ != tlv.PingResponseMessage {
+		return fmt.Errorf("unexpected ping response type: %d", typ)
+	}
+
+	return conn.SetDeadline(time.Time{})
+}
+
 // Close closes ShardWriter's pool
 func (w *ShardWriter) Close() error {
 	if w.pool == nil {
 		return fmt.Errorf("client already closed")
 	}
+	if w.closing != nil {
+		close(w.closing)
+		w.wg.Wait()
+		w.closing = nil
+	}
 	w.pool.close()
 	w.pool = nil
+
+	w.muxMu.Lock()
+	defer w.muxMu.Unlock()
+	for _, conn := range w.muxConns {
+		conn.Close()
+	}
+	w.muxConns = nil
 	return nil
 }
 
 const (
 	maxConnections = 500
 	maxRetries     = 3
+
+	// reconnectBackoff is the wait before the first redial of a node that
+	// could not be reached. The wait doubles on each further attempt.
+	reconnectBackoff = 50 * time.Millisecond
 )
 
 var errMaxConnectionsExceeded = fmt.Errorf("can not exceed max connections of %d", maxConnections)
 
+// errNodeMoved fails the connections to a node whose hostname resolves to
+// another IP than the one they were dialed at.
+var errNodeMoved = errors.New("node moved to another address")
+
 type connFactory struct {
 	nodeID  uint64
 	timeout time.Duration
+	dialer  *Dialer
+	hello   func() *rpc.HelloRequest
 
 	clientPool interface {
 		size() int
@@ -175,17 +657,24 @@ func (c *connFactory) dial() (net.Conn, error) {
 		return nil, fmt.Errorf("node %d does not exist", c.nodeID)
 	}
 
-	conn, err := net.DialTimeout("tcp", ni.TCPHost, c.timeout)
-	if err != nil {
-		return nil, err
+	var d Dialer
+	if c.dialer != nil {
+		d = *c.dialer
 	}
-
-	// Write a marker byte for cluster messages.
-	_, err = conn.Write([]byte{MuxHeader})
-	if err != nil {
-		conn.Close()
-		return nil, err
+	if c.hello != nil {
+		d.Hello = c.hello
 	}
 
+	var conn net.Conn
+	backoff := reconnectBackoff
+	for i := 1; ; i++ {
+		if conn, err = d.Dial(ni.TCPHost, c.timeout); err == nil {
+			break
+		} else if i == maxRetries {
+			return nil, err
+		}
+		time.Sleep(backoff)
+		backoff *= 2
+	}
 	return conn, nil
 }
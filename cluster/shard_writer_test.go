@@ -2,13 +2,21 @@ package cluster_test
 
 import (
 	"net"
+	"reflect"
+	"strconv"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxdb/services/meta"
 	"github.com/influxdata/influxdb/toml"
+	"github.com/influxdata/influxdb/tsdb"
 	"github.com/zhexuany/influxcloud/cluster"
+	cloudMeta "github.com/zhexuany/influxcloud/meta"
+	"github.com/zhexuany/influxcloud/rpc"
+	"github.com/zhexuany/influxcloud/tlv"
 )
 
 func newTags() models.Tags {
@@ -82,6 +90,54 @@ func validatePoint(responses []*serviceResponse, t *testing.T, now time.Time) {
 
 }
 
+// Ensure hinted handoff writes replayed again are skipped by the owner.
+func TestShardWriter_WriteHandoffShard(t *testing.T) {
+	ts := newTestWriteService(nil)
+	var mu sync.Mutex
+	var written int
+	ts.TSDBStore.WriteToShardFn = func(shardID uint64, points []models.Point) error {
+		mu.Lock()
+		defer mu.Unlock()
+		written += len(points)
+		return nil
+	}
+	s := cluster.NewService(cluster.Config{})
+	s.Listener = ts.muxln
+	s.TSDBStore = &ts.TSDBStore
+	if err := s.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	defer ts.Close()
+
+	w := cluster.NewShardWriter(time.Minute, 1)
+	w.MetaClient = &metaClient{host: ts.ln.Addr().String()}
+	w.NodeID = 2
+	defer w.Close()
+
+	points := []models.Point{models.MustNewPoint("cpu", newTags(), newFields(), time.Now())}
+	for i, tt := range []struct {
+		queueID, seq uint64
+		written      int
+	}{
+		{queueID: 3, seq: 10, written: 1},
+		{queueID: 3, seq: 10, written: 1},
+		{queueID: 3, seq: 9, written: 1},
+		{queueID: 3, seq: 11, written: 2},
+		{queueID: 4, seq: 5, written: 3},
+	} {
+		if err := w.WriteHandoffShard(1, 2, tt.queueID, tt.seq, points); err != nil {
+			t.Fatal(err)
+		}
+		mu.Lock()
+		n := written
+		mu.Unlock()
+		if n != tt.written {
+			t.Fatalf("%d. unexpected points written: %d, expected %d", i, n, tt.written)
+		}
+	}
+}
+
 // Ensure the shard writer can successful write a multiple requests.
 func TestShardWriter_WriteShard_Multiple(t *testing.T) {
 	ts := newTestWriteService(nil)
@@ -124,6 +180,198 @@ func TestShardWriter_WriteShard_Multiple(t *testing.T) {
 	validatePoint(responses, t, now)
 }
 
+// Ensure the shard writer sends every point in a single write.
+func TestShardWriter_WriteShard_Points(t *testing.T) {
+	ts := newTestWriteService(nil)
+	ts.TSDBStore.WriteToShardFn = ts.writeShardSuccess
+	s := cluster.NewService(cluster.Config{})
+	s.Listener = ts.muxln
+	s.TSDBStore = &ts.TSDBStore
+	if err := s.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	defer ts.Close()
+
+	w := cluster.NewShardWriter(time.Minute, 1)
+	w.MetaClient = &metaClient{host: ts.ln.Addr().String()}
+	defer w.Close()
+
+	now := time.Now()
+	points := []models.Point{
+		models.MustNewPoint("cpu", newTags(), newFields(), now),
+		models.MustNewPoint("mem", newTags(), newFields(), now),
+	}
+	if err := w.WriteShard(1, 2, points); err != nil {
+		t.Fatal(err)
+	}
+
+	responses, err := ts.ResponseN(1)
+	if err != nil {
+		t.Fatal(err)
+	} else if n := len(responses[0].points); n != 2 {
+		t.Fatalf("unexpected point count: %d", n)
+	} else if name := responses[0].points[1].Name(); name != "mem" {
+		t.Fatalf("unexpected name: %s", name)
+	}
+}
+
+// Ensure writes on a multiplexed connection are answered out of order.
+func TestShardWriter_WriteShard_Multiplex(t *testing.T) {
+	ts := newTestWriteService(nil)
+	started, release := make(chan struct{}), make(chan struct{})
+	ts.TSDBStore.WriteToShardFn = func(shardID uint64, points []models.Point) error {
+		switch shardID {
+		case 1:
+			// Block until the write to shard 2, sent after this one, is done.
+			close(started)
+			<-release
+		case 2:
+			close(release)
+		case 3:
+			return tsdb.ErrShardNotFound
+		}
+		return nil
+	}
+	s := cluster.NewService(cluster.Config{})
+	s.Listener = ts.muxln
+	s.TSDBStore = &ts.TSDBStore
+	if err := s.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	defer ts.Close()
+
+	w := cluster.NewShardWriter(5*time.Second, 1)
+	w.MetaClient = &metaClient{host: ts.ln.Addr().String()}
+	w.Multiplex = true
+	defer w.Close()
+
+	points := []models.Point{models.MustNewPoint("cpu", newTags(), newFields(), time.Now())}
+	errC := make(chan error, 1)
+	go func() { errC <- w.WriteShard(1, 2, points) }()
+
+	// Wait for the first write to be in flight.
+	select {
+	case <-started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for first write")
+	}
+
+	if err := w.WriteShard(2, 2, points); err != nil {
+		t.Fatal(err)
+	} else if err := <-errC; err != nil {
+		t.Fatal(err)
+	}
+
+	// Errors are returned to the request they belong to.
+	if err := w.WriteShard(3, 2, points); err == nil {
+		t.Fatal("expected error")
+	} else if e, ok := err.(*rpc.WriteShardError); !ok || e.Code != rpc.CodeShardNotFound {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+// Ensure multiplexed writes are sent with the configured point codec.
+func TestShardWriter_WriteShard_PointCodec(t *testing.T) {
+	ts := newTestWriteService(nil)
+	var received []models.Point
+	ts.TSDBStore.WriteToShardFn = func(shardID uint64, points []models.Point) error {
+		received = points
+		return nil
+	}
+	s := cluster.NewService(cluster.Config{})
+	s.Listener = ts.muxln
+	s.TSDBStore = &ts.TSDBStore
+	if err := s.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	defer ts.Close()
+
+	c := cluster.NewConfig()
+	c.WritePointCodec = "columnar"
+	w := cluster.NewShardWriter(5*time.Second, 1)
+	w.MetaClient = &metaClient{host: ts.ln.Addr().String()}
+	w.Multiplex = true
+	if err := w.Reload(c); err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	now := time.Unix(0, 1500000000000000001)
+	points := []models.Point{
+		models.MustNewPoint("cpu", newTags(), models.Fields{"value": 1.5, "n": int64(2)}, now),
+		models.MustNewPoint("cpu", newTags(), models.Fields{"ok": true, "msg": "a b"}, now.Add(time.Second)),
+	}
+	if err := w.WriteShard(1, 2, points); err != nil {
+		t.Fatal(err)
+	} else if len(received) != len(points) {
+		t.Fatalf("got %d points, exp %d", len(received), len(points))
+	}
+	for i, p := range points {
+		if received[i].String() != p.String() {
+			t.Errorf("point %d: got %s, exp %s", i, received[i].String(), p.String())
+		}
+	}
+	if typ := reflect.TypeOf(received[0]).String(); typ != "*rpc.columnarPoint" {
+		t.Fatalf("points not decoded from a columnar batch: %s", typ)
+	}
+
+	c.WritePointCodec = "unknown"
+	if err := w.Reload(c); err == nil {
+		t.Fatal("expected error reloading an unknown codec")
+	}
+}
+
+// Ensure multiplexed writes that succeed after timing out are reported.
+func TestShardWriter_WriteShard_MultiplexLate(t *testing.T) {
+	ts := newTestWriteService(nil)
+	release := make(chan struct{})
+	ts.TSDBStore.WriteToShardFn = func(shardID uint64, points []models.Point) error {
+		<-release
+		return nil
+	}
+	s := cluster.NewService(cluster.Config{})
+	s.Listener = ts.muxln
+	s.TSDBStore = &ts.TSDBStore
+	if err := s.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	defer ts.Close()
+
+	late := make(lateWrites, 1)
+	w := cluster.NewShardWriter(100*time.Millisecond, 1)
+	w.MetaClient = &metaClient{host: ts.ln.Addr().String()}
+	w.Multiplex = true
+	w.LateWrites = late
+	defer w.Close()
+
+	points, err := rpc.EncodePoints([]models.Point{models.MustNewPoint("cpu", newTags(), newFields(), time.Now())})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.WriteEncodedShard(&testSpan{}, "", time.Time{}, 1, 2, points); err != cluster.ErrTimeout {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	close(release)
+
+	select {
+	case p := <-late:
+		if p != points {
+			t.Fatal("unexpected late write points")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for late write")
+	}
+}
+
+// lateWrites receives the points of late writes.
+type lateWrites chan *rpc.EncodedPoints
+
+func (l lateWrites) LateWriteSucceeded(nodeID uint64, points *rpc.EncodedPoints) { l <- points }
+
 // Ensure the shard writer returns an error when the server fails to accept the write.
 func TestShardWriter_WriteShard_Error(t *testing.T) {
 	ts := newTestWriteService(writeShardFail)
@@ -153,8 +401,512 @@ func TestShardWriter_WriteShard_Error(t *testing.T) {
 	if err := w.WriteShard(shardID, ownerID, points); err == nil || err.Error() != "error code 1: write shard 1: failed to write" {
 		t.Fatalf("unexpected error: %v", err)
 	}
+
+	// The remote node reports the request ID of the failed write.
+	w.NodeID = 3
+	if err := w.WriteShardWithRequestID("req0", shardID, ownerID, points); err == nil || err.Error() != "error code 1: write shard 1: failed to write (request req0)" {
+		t.Fatalf("unexpected error: %v", err)
+	} else if e, ok := err.(*rpc.WriteShardError); !ok || e.RequestID != "req0" {
+		t.Fatalf("unexpected error: %#v", err)
+	}
+}
+
+// Ensure the shard writer returns a typed error for known store failures.
+func TestShardWriter_WriteShard_ErrorCode(t *testing.T) {
+	ts := newTestWriteService(func(shardID uint64, points []models.Point) error {
+		return tsdb.PartialWriteError{Reason: tsdb.ErrFieldTypeConflict.Error() + ": input field \"value\" is type string", Dropped: 1}
+	})
+	s := cluster.NewService(cluster.Config{})
+	s.Listener = ts.muxln
+	s.TSDBStore = &ts.TSDBStore
+	if err := s.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	defer ts.Close()
+
+	w := cluster.NewShardWriter(time.Minute, 1)
+	w.MetaClient = &metaClient{host: ts.ln.Addr().String()}
+
+	points := []models.Point{models.MustNewPoint("cpu", newTags(), newFields(), time.Now())}
+	err := w.WriteShard(1, 2, points)
+	if e, ok := err.(*rpc.WriteShardError); !ok {
+		t.Fatalf("unexpected error: %#v", err)
+	} else if e.Code != rpc.CodeFieldTypeConflict {
+		t.Fatalf("unexpected code: %s", e.Code)
+	} else if e.Retryable() {
+		t.Fatal("expected field type conflict not to be retryable")
+	}
+}
+
+// Ensure the shard writer reports the points the remote node rejected for
+// field type conflicts, the others being written.
+func TestShardWriter_WriteShard_Rejected(t *testing.T) {
+	store := MustOpenIteratorStore()
+	defer store.Close()
+	s := MustOpenIteratorService(cluster.Config{}, store)
+	defer s.Close()
+	s.Service.TSDBStore = store
+
+	w := cluster.NewShardWriter(time.Minute, 1)
+	w.MetaClient = &metaClient{host: s.Addr().String()}
+
+	points := []models.Point{
+		models.MustNewPoint("cpu", newTags(), map[string]interface{}{"value": 2.0}, time.Unix(10, 0)),
+		models.MustNewPoint("cpu", newTags(), map[string]interface{}{"value": "bad"}, time.Unix(11, 0)),
+		models.MustNewPoint("mem", newTags(), map[string]interface{}{"value": "ok"}, time.Unix(12, 0)),
+		models.MustNewPoint("cpu", newTags(), map[string]interface{}{"value": int64(3)}, time.Unix(13, 0)),
+	}
+	err := w.WriteShard(10, 1, points)
+	if e, ok := err.(*rpc.WriteShardError); !ok || e.Code != rpc.CodeFieldTypeConflict {
+		t.Fatalf("unexpected error: %#v", err)
+	} else if !reflect.DeepEqual(e.Rejected, []int{1, 3}) {
+		t.Fatalf("unexpected rejected points: %v", e.Rejected)
+	}
+
+	// The points that were not rejected were written.
+	if err := w.WriteShard(10, 1, points[:1]); err != nil {
+		t.Fatal(err)
+	}
+	if fields, _, err := store.Shard(10).FieldDimensions([]string{"mem"}); err != nil {
+		t.Fatal(err)
+	} else if len(fields) != 1 {
+		t.Fatalf("unexpected fields: %v", fields)
+	}
+}
+
+// Ensure none of the points of a shard write are written if the partial
+// write policy of their database rejects partial writes.
+func TestShardWriter_WriteShard_RejectAll(t *testing.T) {
+	store := MustOpenIteratorStore()
+	defer store.Close()
+	s := MustOpenIteratorService(cluster.Config{PartialWritePolicy: cluster.PartialWriteRejectAll}, store)
+	defer s.Close()
+	s.Service.TSDBStore = store
+
+	w := cluster.NewShardWriter(time.Minute, 1)
+	w.MetaClient = &metaClient{host: s.Addr().String()}
+
+	points := []models.Point{
+		models.MustNewPoint("mem", newTags(), map[string]interface{}{"value": 1.0}, time.Unix(10, 0)),
+		models.MustNewPoint("cpu", newTags(), map[string]interface{}{"value": "bad"}, time.Unix(11, 0)),
+	}
+	err := w.WriteShard(10, 1, points)
+	if e, ok := err.(*rpc.WriteShardError); !ok || e.Code != rpc.CodeFieldTypeConflict {
+		t.Fatalf("unexpected error: %#v", err)
+	} else if !reflect.DeepEqual(e.Rejected, []int{1}) {
+		t.Fatalf("unexpected rejected points: %v", e.Rejected)
+	}
+	if fields, _, err := store.Shard(10).FieldDimensions([]string{"mem"}); err != nil {
+		t.Fatal(err)
+	} else if len(fields) != 0 {
+		t.Fatalf("unexpected fields: %v", fields)
+	}
+}
+
+// Ensure a whole write is forwarded to the remote node's points writer.
+func TestShardWriter_ForwardPoints(t *testing.T) {
+	var got struct {
+		requestID, database, retentionPolicy string
+		deadline                             time.Time
+		consistencyLevel                     models.ConsistencyLevel
+		points                               []models.Point
+	}
+
+	ts := newTestWriteService(nil)
+	s := cluster.NewService(cluster.Config{})
+	s.Listener = ts.muxln
+	s.TSDBStore = &ts.TSDBStore
+	s.PointsWriter = &fakeForwardedPointsWriter{
+		WriteFn: func(requestID string, deadline time.Time, database, retentionPolicy string, consistencyLevel models.ConsistencyLevel, points []models.Point) error {
+			if database == "fail" {
+				return cluster.ErrPartialWrite
+			}
+			got.requestID, got.deadline, got.database, got.retentionPolicy = requestID, deadline, database, retentionPolicy
+			got.consistencyLevel, got.points = consistencyLevel, points
+			return nil
+		},
+	}
+	if err := s.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	defer ts.Close()
+
+	w := cluster.NewShardWriter(time.Minute, 1)
+	w.MetaClient = &metaClient{host: ts.ln.Addr().String()}
+
+	points := []models.Point{models.MustNewPoint("cpu", newTags(), newFields(), time.Now())}
+	e, err := rpc.EncodePoints(points)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(time.Minute)
+	if err := w.ForwardPoints(&testSpan{}, "req", deadline, 2, "db", "rp", models.ConsistencyLevelQuorum, e); err != nil {
+		t.Fatal(err)
+	} else if got.requestID != "req" || got.database != "db" || got.retentionPolicy != "rp" || got.consistencyLevel != models.ConsistencyLevelQuorum {
+		t.Fatalf("unexpected forwarded write: %+v", got)
+	} else if len(got.points) != 1 || got.points[0].String() != points[0].String() {
+		t.Fatalf("unexpected points: %v", got.points)
+	} else if got.deadline.IsZero() || got.deadline.After(deadline.Add(time.Second)) {
+		t.Fatalf("unexpected deadline: %s", got.deadline)
+	}
+
+	// Errors from the remote write are returned.
+	err = w.ForwardPoints(&testSpan{}, "req", time.Time{}, 2, "fail", "rp", models.ConsistencyLevelQuorum, e)
+	if e, ok := err.(*rpc.WriteShardError); !ok || e.Code != rpc.CodeUnknown || e.Message != cluster.ErrPartialWrite.Error() {
+		t.Fatalf("unexpected error: %#v", err)
+	}
+}
+
+type fakeForwardedPointsWriter struct {
+	WriteFn func(requestID string, deadline time.Time, database, retentionPolicy string, consistencyLevel models.ConsistencyLevel, points []models.Point) error
+}
+
+func (w *fakeForwardedPointsWriter) WriteForwardedPoints(requestID string, deadline time.Time, database, retentionPolicy string, consistencyLevel models.ConsistencyLevel, points []models.Point) error {
+	return w.WriteFn(requestID, deadline, database, retentionPolicy, consistencyLevel, points)
+}
+
+// Ensure a write is not applied by the remote node once its deadline passes.
+func TestShardWriter_WriteEncodedShard_Deadline(t *testing.T) {
+	ts := newTestWriteService(func(shardID uint64, points []models.Point) error {
+		return tsdb.ErrShardNotFound
+	})
+	ts.TSDBStore.CreateShardFn = func(database, policy string, shardID uint64, enabled bool) error {
+		time.Sleep(100 * time.Millisecond)
+		return nil
+	}
+	s := cluster.NewService(cluster.Config{})
+	s.Listener = ts.muxln
+	s.TSDBStore = &ts.TSDBStore
+	if err := s.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	defer ts.Close()
+
+	w := cluster.NewShardWriter(time.Minute, 1)
+	w.MetaClient = &metaClient{host: ts.ln.Addr().String()}
+	defer w.Close()
+
+	points, err := rpc.EncodePoints([]models.Point{models.MustNewPoint("cpu", newTags(), newFields(), time.Now())})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The shard is created after the deadline so the points are not written.
+	err = w.WriteEncodedShard(&testSpan{}, "", time.Now().Add(50*time.Millisecond), 1, 2, points)
+	if e, ok := err.(*rpc.WriteShardError); !ok || e.Code != rpc.CodeDeadlineExceeded {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// A write past its deadline is not sent.
+	if err := w.WriteEncodedShard(&testSpan{}, "", time.Now().Add(-time.Second), 1, 2, points); err != cluster.ErrTimeout {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+// Ensure concurrent writes to the same shard are batched into one store write
+// when write coalescing is enabled.
+func TestShardWriter_WriteShard_Coalesce(t *testing.T) {
+	ts := newTestWriteService(nil)
+	ts.TSDBStore.WriteToShardFn = ts.writeShardSuccess
+	s := cluster.NewService(cluster.Config{WriteCoalesceWindow: toml.Duration(100 * time.Millisecond)})
+	s.Listener = ts.muxln
+	s.TSDBStore = &ts.TSDBStore
+	if err := s.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	defer ts.Close()
+
+	w := cluster.NewShardWriter(time.Minute, 3)
+	w.MetaClient = &metaClient{host: ts.ln.Addr().String()}
+	defer w.Close()
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 3)
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			points := []models.Point{models.MustNewPoint("cpu", newTags(), newFields(), time.Now())}
+			errs <- w.WriteShard(1, 2, points)
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	responses, err := ts.ResponseN(1)
+	if err != nil {
+		t.Fatal(err)
+	} else if n := len(responses[0].points); n != 3 {
+		t.Fatalf("unexpected batched point count: %d", n)
+	}
+	select {
+	case r := <-ts.responses:
+		t.Fatalf("unexpected write: %+v", r)
+	default:
+	}
+}
+
+// Ensure the span context of a write is propagated to the remote node.
+func TestShardWriter_WriteShardWithSpan(t *testing.T) {
+	ts := newTestWriteService(nil)
+	ts.TSDBStore.WriteToShardFn = ts.writeShardSuccess
+	tracer := &spanTracer{}
+	s := cluster.NewService(cluster.Config{})
+	s.Listener = ts.muxln
+	s.TSDBStore = &ts.TSDBStore
+	s.SpanTracer = tracer
+	if err := s.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	defer ts.Close()
+
+	w := cluster.NewShardWriter(time.Minute, 1)
+	w.MetaClient = &metaClient{host: ts.ln.Addr().String()}
+	defer w.Close()
+
+	points := []models.Point{models.MustNewPoint("cpu", newTags(), newFields(), time.Now())}
+	parent := tracer.StartSpan("write", nil)
+	if err := w.WriteShardWithSpan(parent, "req", 1, 2, points); err != nil {
+		t.Fatal(err)
+	}
+	parent.Finish()
+
+	// A write without a span does not inherit the previous span context.
+	if err := w.WriteShard(1, 2, points); err != nil {
+		t.Fatal(err)
+	}
+
+	// The remote span is finished after the response is sent so wait for it.
+	var spans []*testSpan
+	for i := 0; ; i++ {
+		if spans = tracer.Spans(); len(spans) == 3 && spans[2].Finished() {
+			break
+		} else if i == 100 {
+			t.Fatalf("unexpected spans: %+v", spans)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if sp := spans[1]; sp.operation != "cluster.writeShard" || sp.parent != parent.(*testSpan).id || !sp.Finished() {
+		t.Fatalf("unexpected remote span: %+v", sp)
+	} else if sp := spans[2]; sp.operation != "cluster.writeShard" || sp.parent != 0 {
+		t.Fatalf("unexpected span without parent: %+v", sp)
+	}
+}
+
+// spanTracer is a cluster.SpanTracer that records the spans it starts.
+type spanTracer struct {
+	mu    sync.Mutex
+	spans []*testSpan
+}
+
+func (t *spanTracer) StartSpan(operation string, parent cluster.Span) cluster.Span {
+	var parentID int
+	if parent != nil {
+		parentID = parent.(*testSpan).id
+	}
+	return t.start(operation, parentID)
+}
+
+func (t *spanTracer) StartRemoteSpan(operation string, carrier map[string]string) cluster.Span {
+	id, _ := strconv.Atoi(carrier["span-id"])
+	return t.start(operation, id)
+}
+
+func (t *spanTracer) start(operation string, parent int) *testSpan {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	sp := &testSpan{id: len(t.spans) + 1, parent: parent, operation: operation}
+	t.spans = append(t.spans, sp)
+	return sp
+}
+
+// Spans returns the spans started so far.
+func (t *spanTracer) Spans() []*testSpan {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]*testSpan(nil), t.spans...)
+}
+
+type testSpan struct {
+	mu        sync.Mutex
+	id        int
+	parent    int
+	operation string
+	finished  bool
+}
+
+func (sp *testSpan) SetTag(key string, value interface{}) {}
+
+func (sp *testSpan) Inject(carrier map[string]string) {
+	carrier["span-id"] = strconv.Itoa(sp.id)
+}
+
+func (sp *testSpan) Finish() {
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+	sp.finished = true
+}
+
+func (sp *testSpan) Finished() bool {
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+	return sp.finished
+}
+
+// Ensure a write is retried on a new connection when the remote node has
+// closed the pooled one.
+func TestShardWriter_WriteShard_Reconnect(t *testing.T) {
+	ts := newTestShardServer(t, 1)
+	defer ts.Close()
+
+	w := cluster.NewShardWriter(time.Minute, 1)
+	w.MetaClient = &metaClient{host: ts.ln.Addr().String()}
+	defer w.Close()
+
+	points := []models.Point{models.MustNewPoint("cpu", newTags(), newFields(), time.Now())}
+	for i := 0; i < 3; i++ {
+		if err := w.WriteShard(1, 2, points); err != nil {
+			t.Fatalf("write %d: %s", i, err)
+		}
+	}
+}
+
+// Ensure idle connections are pinged and connections to removed nodes are
+// closed.
+func TestShardWriter_KeepAlive(t *testing.T) {
+	ts := newTestShardServer(t, 0)
+	defer ts.Close()
+
+	mc := &removableMetaClient{metaClient: metaClient{host: ts.ln.Addr().String()}}
+	w := cluster.NewShardWriter(time.Second, 1)
+	w.MetaClient = mc
+	w.KeepAliveInterval = 10 * time.Millisecond
+	if err := w.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	points := []models.Point{models.MustNewPoint("cpu", newTags(), newFields(), time.Now())}
+	if err := w.WriteShard(1, 2, points); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case <-ts.pings:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for ping")
+	}
+
+	mc.Remove()
+	select {
+	case <-ts.closed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for connection to be closed")
+	}
 }
 
+// removableMetaClient is a metaClient whose node can be removed.
+type removableMetaClient struct {
+	metaClient
+	mu      sync.Mutex
+	removed bool
+}
+
+func (m *removableMetaClient) Remove() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.removed = true
+}
+
+func (m *removableMetaClient) DataNode(nodeID uint64) (*meta.NodeInfo, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.removed {
+		return nil, cloudMeta.ErrNodeNotFound
+	}
+	return m.metaClient.DataNode(nodeID)
+}
+
+// testShardServer answers shard writes and pings on raw cluster
+// connections, closing each connection after n writes if n is non-zero.
+type testShardServer struct {
+	ln     net.Listener
+	n      int
+	pings  chan struct{}
+	closed chan struct{}
+}
+
+func newTestShardServer(t *testing.T, n int) *testShardServer {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := &testShardServer{
+		ln:     ln,
+		n:      n,
+		pings:  make(chan struct{}, 100),
+		closed: make(chan struct{}, 100),
+	}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *testShardServer) serve(conn net.Conn) {
+	defer func() { s.closed <- struct{}{} }()
+	defer conn.Close()
+
+	var header [1]byte
+	if _, err := conn.Read(header[:]); err != nil {
+		return
+	}
+
+	for i := 0; s.n == 0 || i < s.n; {
+		typ, _, err := tlv.ReadTLV(conn)
+		if err != nil {
+			return
+		}
+
+		switch typ {
+		case tlv.PingRequestMessage:
+			s.pings <- struct{}{}
+			if err := tlv.WriteTLV(conn, tlv.PingResponseMessage, nil); err != nil {
+				return
+			}
+		case tlv.WriteShardRequestMessage:
+			var resp rpc.WriteShardResponse
+			resp.SetCode(int(rpc.CodeOK))
+			buf, _ := resp.MarshalBinary()
+			if err := tlv.WriteTLV(conn, tlv.WriteShardResponseMessage, buf); err != nil {
+				return
+			}
+			i++
+		}
+	}
+}
+
+func (s *testShardServer) Close() error { return s.ln.Close() }
+
 // Ensure the shard writer returns an error when dialing times out.
 func TestShardWriter_Write_ErrDialTimeout(t *testing.T) {
 	ts := newTestWriteService(nil)
@@ -215,6 +967,33 @@ func TestShardWriter_Write_ErrReadTimeout(t *testing.T) {
 	}
 }
 
+// Ensure the shard writer stops waiting for a response at the deadline of
+// the write, even if its own timeout has not passed.
+func TestShardWriter_WriteEncodedShard_DeadlineReadTimeout(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	w := cluster.NewShardWriter(time.Minute, 1)
+	w.MetaClient = &metaClient{host: ln.Addr().String()}
+	defer w.Close()
+
+	points, err := rpc.EncodePoints([]models.Point{models.MustNewPoint("cpu", newTags(), newFields(), time.Now())})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Now()
+	err = w.WriteEncodedShard(&testSpan{}, "", start.Add(50*time.Millisecond), 1, 2, points)
+	if err == nil || !strings.Contains(err.Error(), "i/o timeout") {
+		t.Fatalf("unexpected error: %v", err)
+	} else if d := time.Since(start); d > 5*time.Second {
+		t.Fatalf("write took %s", d)
+	}
+}
+
 // Ensure the shard writer returns an error when we can't get a connection.
 func TestShardWriter_Write_PoolMax(t *testing.T) {
 	ts := newTestWriteService(writeShardSlow)
@@ -0,0 +1,64 @@
+package cluster
+
+import (
+	"sync"
+	"time"
+)
+
+// maxShardSnapshots is the number of snapshots of each shard that are
+// remembered as bases for incremental snapshots.
+const maxShardSnapshots = 8
+
+// shardSnapshots remembers the snapshots a node has taken of its shards.
+// Incremental snapshots are only taken since a remembered snapshot, as the
+// files of a shard differ between its owners and are replaced when it is
+// restored. Snapshot IDs are the time the snapshot was started, in
+// nanoseconds.
+type shardSnapshots struct {
+	mu  sync.Mutex
+	ids map[uint64][]uint64
+}
+
+func newShardSnapshots() *shardSnapshots {
+	return &shardSnapshots{ids: make(map[uint64][]uint64)}
+}
+
+// begin returns the ID of a new snapshot of shardID, and the snapshot it is
+// taken since. The snapshot is taken since base if it is remembered, and is
+// full otherwise, in which case the returned base is zero.
+func (s *shardSnapshots) begin(shardID, base uint64) (id, since uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ids := s.ids[shardID]
+	id = uint64(time.Now().UnixNano())
+	if n := len(ids); n > 0 && id <= ids[n-1] {
+		id = ids[n-1] + 1
+	}
+
+	for _, prev := range ids {
+		if base != 0 && prev == base {
+			return id, base
+		}
+	}
+	return id, 0
+}
+
+// add remembers the snapshot id of shardID once it has been taken.
+func (s *shardSnapshots) add(shardID, id uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ids := append(s.ids[shardID], id)
+	if len(ids) > maxShardSnapshots {
+		ids = ids[len(ids)-maxShardSnapshots:]
+	}
+	s.ids[shardID] = ids
+}
+
+// remove forgets the snapshots of shardID, e.g. once it is deleted.
+func (s *shardSnapshots) remove(shardID uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.ids, shardID)
+}
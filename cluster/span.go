@@ -0,0 +1,73 @@
+package cluster
+
+import (
+	"net"
+
+	"github.com/zhexuany/influxcloud/rpc"
+	"github.com/zhexuany/influxcloud/tlv"
+)
+
+// SpanTracer creates spans for cluster requests so that a write or query is
+// reported as one trace across the coordinator and the data nodes. It is
+// implemented by adapters for OpenTracing or OpenTelemetry tracers, whose
+// text map propagators fill and read the carriers.
+type SpanTracer interface {
+	// StartSpan starts a span for operation. parent may be nil to start a
+	// root span.
+	StartSpan(operation string, parent Span) Span
+
+	// StartRemoteSpan starts a span for operation as a child of the remote
+	// span whose context was injected into carrier.
+	StartRemoteSpan(operation string, carrier map[string]string) Span
+}
+
+// Span is a single traced operation.
+type Span interface {
+	// SetTag adds a tag to the span.
+	SetTag(key string, value interface{})
+
+	// Inject writes the span context to carrier to send it to a remote node.
+	Inject(carrier map[string]string)
+
+	// Finish ends the span.
+	Finish()
+}
+
+// noopSpan is the Span used when tracing is disabled.
+type noopSpan struct{}
+
+func (noopSpan) SetTag(key string, value interface{}) {}
+func (noopSpan) Inject(carrier map[string]string)     {}
+func (noopSpan) Finish()                              {}
+
+// startSpan starts a span for operation with t, or returns a no-op span if
+// t is nil.
+func startSpan(t SpanTracer, operation string, parent Span) Span {
+	if t == nil {
+		return noopSpan{}
+	}
+	if _, ok := parent.(noopSpan); ok {
+		parent = nil
+	}
+	return t.StartSpan(operation, parent)
+}
+
+// startRemoteSpan starts a span for an inbound request named name with t, or
+// returns a no-op span if t is nil.
+func startRemoteSpan(t SpanTracer, name string, carrier map[string]string) Span {
+	if t == nil {
+		return noopSpan{}
+	}
+	return t.StartRemoteSpan("cluster."+name, carrier)
+}
+
+// writeSpanContext sends the context of span to conn ahead of a request. It
+// writes nothing if span has no context to propagate.
+func writeSpanContext(conn net.Conn, span Span) error {
+	carrier := make(map[string]string)
+	span.Inject(carrier)
+	if len(carrier) == 0 {
+		return nil
+	}
+	return tlv.EncodeTLV(conn, tlv.SpanContextMessage, &rpc.SpanContext{Carrier: carrier})
+}
@@ -8,6 +8,7 @@ import (
 	"github.com/influxdata/influxdb/influxql"
 	"github.com/influxdata/influxdb/models"
 	"github.com/influxdata/influxdb/services/meta"
+	"github.com/zhexuany/influxcloud"
 	"github.com/zhexuany/influxcloud/rpc"
 	"github.com/zhexuany/influxcloud/tlv"
 )
@@ -18,10 +19,19 @@ type StatementExecutor struct {
 	timeout        time.Duration
 	maxConnections int
 
+	// Node is the node executing statements. Explain prefers reading the
+	// shards it owns locally.
+	Node *influxcloud.Node
+
 	MetaClient interface {
 		DataNodes() (ni meta.NodeInfos, err error)
+		Database(name string) (*meta.DatabaseInfo, error)
+		ShardsByTimeRange(sources influxql.Sources, tmin, tmax time.Time) (a []meta.ShardInfo, err error)
 	}
 
+	// RemoteIteratorClient creates the iterators read from other nodes.
+	RemoteIteratorClient *RemoteIteratorClient
+
 	// This reprsents local StatementExecutor
 	StatementExecutor coordinator.StatementExecutor
 }
@@ -43,6 +53,7 @@ func (e *StatementExecutor) executeShowQueriesStatement(stmt *influxql.ShowQueri
 		return err
 	}
 
+	requestID := NewRequestID()
 	for _, data := range dataNodes {
 		go func(data meta.NodeInfo) error {
 			conn, err := e.pool.conn(data.ID)
@@ -52,6 +63,7 @@ func (e *StatementExecutor) executeShowQueriesStatement(stmt *influxql.ShowQueri
 			req := rpc.ExecuteStatementRequest{}
 			req.SetDatabase(ctx.Database)
 			req.SetStatement(stmt.String())
+			req.SetRequestID(requestID)
 			err = tlv.EncodeTLV(conn, tlv.ExecuteStatementRequestMessage, &req)
 			if err != nil {
 				return fmt.Errorf("failed to encode tlv: %v", err)
@@ -75,6 +87,7 @@ func (e *StatementExecutor) executeKillQueryStatement(stmt *influxql.KillQuerySt
 		return err
 	}
 
+	requestID := NewRequestID()
 	for _, data := range dataNodes {
 		go func(data meta.NodeInfo) error {
 			conn, err := e.pool.conn(data.ID)
@@ -84,6 +97,7 @@ func (e *StatementExecutor) executeKillQueryStatement(stmt *influxql.KillQuerySt
 			req := rpc.ExecuteStatementRequest{}
 			req.SetDatabase(ctx.Database)
 			req.SetStatement(stmt.String())
+			req.SetRequestID(requestID)
 			err = tlv.EncodeTLV(conn, tlv.ExecuteStatementRequestMessage, &req)
 			if err != nil {
 				return fmt.Errorf("failed to encode tlv: %v", err)
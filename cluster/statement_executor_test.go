@@ -214,6 +214,7 @@ type TSDBStore struct {
 	MeasurementsFn          func(databse string, cond influxql.Expr) ([]string, error)
 	RestoreShardFn          func(id uint64, r io.Reader) error
 	TagValuesFn             func(database string, cond influxql.Expr) ([]tsdb.TagValues, error)
+	DatabaseIndexFn         func(name string) *tsdb.DatabaseIndex
 }
 
 func (s *TSDBStore) CreateShard(database, policy string, shardID uint64, enabled bool) error {
@@ -232,7 +233,7 @@ func (s *TSDBStore) RestoreShard(id uint64, r io.Reader) error {
 		return nil
 	}
 
-	return s.RestoreShard(id, r)
+	return s.RestoreShardFn(id, r)
 }
 
 func (s *TSDBStore) BackupShard(id uint64, since time.Time, w io.Writer) error {
@@ -258,6 +259,13 @@ func (s *TSDBStore) DeleteSeries(database string, sources []influxql.Source, con
 	return s.DeleteSeriesFn(database, sources, condition)
 }
 
+func (s *TSDBStore) DatabaseIndex(name string) *tsdb.DatabaseIndex {
+	if s.DatabaseIndexFn == nil {
+		return nil
+	}
+	return s.DatabaseIndexFn(name)
+}
+
 func (s *TSDBStore) DeleteShard(id uint64) error {
 	return s.DeleteShardFn(id)
 }
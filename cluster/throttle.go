@@ -0,0 +1,118 @@
+package cluster
+
+import (
+	"errors"
+	"io"
+	"sync"
+	"time"
+)
+
+// maxThrottledRead is the most bytes a throttled stream reads at once, so
+// that it waits in short steps rather than for whole large reads.
+const maxThrottledRead = 32 * 1024
+
+// errThrottleClosed is returned by a throttled stream once the service is
+// closing.
+var errThrottleClosed = errors.New("service closing")
+
+// rateLimiter limits the rate bytes are transferred at. A limiter shared by
+// several streams limits their aggregate rate.
+type rateLimiter struct {
+	mu   sync.Mutex
+	rate float64   // bytes per second
+	next time.Time // when the next byte may be transferred
+}
+
+// newRateLimiter returns a limiter of rate bytes per second, or nil, which
+// does not limit, if rate is not positive.
+func newRateLimiter(rate int64) *rateLimiter {
+	if rate <= 0 {
+		return nil
+	}
+	return &rateLimiter{rate: float64(rate)}
+}
+
+// reserve takes n bytes from the limiter and returns how long to wait before
+// transferring them.
+func (l *rateLimiter) reserve(n int) time.Duration {
+	if l == nil {
+		return 0
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	if l.next.Before(now) {
+		l.next = now
+	}
+	d := l.next.Sub(now)
+	l.next = l.next.Add(time.Duration(float64(n) / l.rate * float64(time.Second)))
+	return d
+}
+
+// wait takes n bytes from the limiter and waits until they may be
+// transferred, or returns errThrottleClosed if closing is closed first.
+func (l *rateLimiter) wait(n int, closing <-chan struct{}) error {
+	d := l.reserve(n)
+	if d <= 0 {
+		return nil
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-closing:
+		return errThrottleClosed
+	}
+}
+
+// throttledReader reads from r no faster than each of limiters allows.
+type throttledReader struct {
+	r        io.Reader
+	limiters []*rateLimiter
+	closing  <-chan struct{}
+}
+
+// Read reads from r, then waits until the limiters allow the bytes read.
+func (r *throttledReader) Read(p []byte) (int, error) {
+	if len(p) > maxThrottledRead {
+		p = p[:maxThrottledRead]
+	}
+	n, err := r.r.Read(p)
+
+	var wait time.Duration
+	for _, l := range r.limiters {
+		if d := l.reserve(n); d > wait {
+			wait = d
+		}
+	}
+	if wait > 0 {
+		t := time.NewTimer(wait)
+		defer t.Stop()
+		select {
+		case <-t.C:
+		case <-r.closing:
+			return n, errThrottleClosed
+		}
+	}
+	return n, err
+}
+
+// throttleCopy returns r limited by the per-copy and per-node rate limits on
+// shards copied or restored to this node.
+func (s *Service) throttleCopy(r io.Reader) io.Reader {
+	s.mu.RLock()
+	rate, limiter := s.copyRateLimit, s.copyLimiter
+	s.mu.RUnlock()
+
+	if rate <= 0 && limiter == nil {
+		return r
+	}
+	return &throttledReader{
+		r:        r,
+		limiters: []*rateLimiter{newRateLimiter(rate), limiter},
+		closing:  s.closing,
+	}
+}
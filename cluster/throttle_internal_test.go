@@ -0,0 +1,49 @@
+package cluster
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Ensure throttled streams are read no faster than their limits, and that a
+// shared limiter limits the streams together.
+func TestThrottledReader(t *testing.T) {
+	data := make([]byte, 100*1024)
+
+	// 100KB at 1MB/s takes around 100ms.
+	start := time.Now()
+	r := &throttledReader{r: bytes.NewReader(data), limiters: []*rateLimiter{newRateLimiter(1024 * 1024)}}
+	if n, err := io.Copy(ioutil.Discard, r); err != nil {
+		t.Fatal(err)
+	} else if n != int64(len(data)) {
+		t.Fatalf("unexpected bytes read: %d", n)
+	} else if d := time.Since(start); d < 60*time.Millisecond {
+		t.Fatalf("read too fast: %s", d)
+	}
+
+	// Two streams of 100KB sharing 2MB/s also take around 100ms, even
+	// though each may be read at 4MB/s.
+	start = time.Now()
+	shared := newRateLimiter(2 * 1024 * 1024)
+	var wg sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			io.Copy(ioutil.Discard, &throttledReader{r: bytes.NewReader(data), limiters: []*rateLimiter{newRateLimiter(4 * 1024 * 1024), shared}})
+		}()
+	}
+	wg.Wait()
+	if d := time.Since(start); d < 60*time.Millisecond {
+		t.Fatalf("read too fast: %s", d)
+	}
+
+	// A stream with no limits is not throttled.
+	if n, err := io.Copy(ioutil.Discard, &throttledReader{r: bytes.NewReader(data), limiters: []*rateLimiter{newRateLimiter(0)}}); err != nil || n != int64(len(data)) {
+		t.Fatalf("unexpected read: %d, %v", n, err)
+	}
+}
@@ -0,0 +1,243 @@
+package cluster
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+)
+
+// Presets of the TLS policy of inter-node connections.
+const (
+	// TLSPresetDefault leaves the settings that are not configured to the
+	// defaults of crypto/tls.
+	TLSPresetDefault = "default"
+
+	// TLSPresetFIPS only allows TLS 1.2 with the ECDHE AES-GCM cipher suites
+	// and the NIST curves approved by FIPS 140-2. TLS 1.3 is not allowed, as
+	// its cipher suites cannot be restricted in crypto/tls.
+	TLSPresetFIPS = "fips"
+)
+
+// tlsVersions are the TLS versions by their names in the configuration.
+var tlsVersions = map[string]uint16{
+	"tls1.0": tls.VersionTLS10,
+	"tls1.1": tls.VersionTLS11,
+	"tls1.2": tls.VersionTLS12,
+	"tls1.3": tls.VersionTLS13,
+}
+
+// tlsCurves are the elliptic curves by their names in the configuration.
+var tlsCurves = map[string]tls.CurveID{
+	"P256":   tls.CurveP256,
+	"P384":   tls.CurveP384,
+	"P521":   tls.CurveP521,
+	"X25519": tls.X25519,
+}
+
+// fipsTLSConfig is the policy of TLSPresetFIPS.
+var fipsTLSConfig = TLSConfig{
+	MinVersion: "tls1.2",
+	MaxVersion: "tls1.2",
+	Ciphers: []string{
+		"TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256",
+		"TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256",
+		"TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384",
+		"TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384",
+	},
+	Curves: []string{"P256", "P384", "P521"},
+}
+
+// TLSConfig configures inter-node TLS connections, and the policy they are
+// negotiated with: the TLS versions, cipher suites and elliptic curves
+// allowed. Settings that are not set are taken from Preset. Under
+// TLSPresetFIPS, settings may only narrow the preset, so that a policy cannot
+// silently leave FIPS mode. Ciphers only restrict TLS 1.2 and earlier, as
+// crypto/tls chooses the cipher suites of TLS 1.3 itself.
+//
+// The policy applies to the tls.Config of the listener and the dialers of
+// inter-node TLS connections, see ServerConfig and ClientConfig.
+type TLSConfig struct {
+	// Enabled serves the cluster service over TLS, and dials other nodes
+	// over TLS.
+	Enabled bool `toml:"enabled"`
+
+	// Certificate and PrivateKey are the PEM files of the certificate the
+	// node presents, both as a server and as a client.
+	Certificate string `toml:"certificate"`
+	PrivateKey  string `toml:"private-key"`
+
+	// CA is the PEM file of the certificate authorities the certificates of
+	// other nodes are verified against. If set, connecting nodes must
+	// present a certificate, making connections mutual TLS. The system
+	// roots verify the certificates of the nodes dialed if not set.
+	CA string `toml:"ca"`
+
+	Preset     string   `toml:"preset"`
+	MinVersion string   `toml:"min-version"`
+	MaxVersion string   `toml:"max-version"`
+	Ciphers    []string `toml:"ciphers"`
+	Curves     []string `toml:"curves"`
+}
+
+// Apply sets the TLS versions, cipher suites and curve preferences of tc to
+// those of the policy, or returns an error if the policy is invalid.
+func (c TLSConfig) Apply(tc *tls.Config) error {
+	fips := false
+	switch c.Preset {
+	case "", TLSPresetDefault:
+	case TLSPresetFIPS:
+		fips = true
+		if c.MinVersion == "" {
+			c.MinVersion = fipsTLSConfig.MinVersion
+		}
+		if c.MaxVersion == "" {
+			c.MaxVersion = fipsTLSConfig.MaxVersion
+		}
+		if len(c.Ciphers) == 0 {
+			c.Ciphers = fipsTLSConfig.Ciphers
+		}
+		if len(c.Curves) == 0 {
+			c.Curves = fipsTLSConfig.Curves
+		}
+	default:
+		return fmt.Errorf("unknown tls preset: %q", c.Preset)
+	}
+
+	min, err := parseTLSVersion(c.MinVersion)
+	if err != nil {
+		return err
+	}
+	max, err := parseTLSVersion(c.MaxVersion)
+	if err != nil {
+		return err
+	}
+	if min != 0 && max != 0 && min > max {
+		return fmt.Errorf("tls min-version %s is above max-version %s", c.MinVersion, c.MaxVersion)
+	}
+	if fips && (min < tls.VersionTLS12 || max > tls.VersionTLS12) {
+		return fmt.Errorf("tls preset %q only allows tls1.2", TLSPresetFIPS)
+	}
+
+	var ciphers []uint16
+	for _, name := range c.Ciphers {
+		id, ok := cipherSuiteByName(name)
+		if !ok {
+			return fmt.Errorf("unknown tls cipher: %q", name)
+		} else if fips && !contains(fipsTLSConfig.Ciphers, name) {
+			return fmt.Errorf("tls cipher %s not allowed by preset %q", name, TLSPresetFIPS)
+		}
+		ciphers = append(ciphers, id)
+	}
+
+	var curves []tls.CurveID
+	for _, name := range c.Curves {
+		id, ok := tlsCurves[name]
+		if !ok {
+			return fmt.Errorf("unknown tls curve: %q", name)
+		} else if fips && !contains(fipsTLSConfig.Curves, name) {
+			return fmt.Errorf("tls curve %s not allowed by preset %q", name, TLSPresetFIPS)
+		}
+		curves = append(curves, id)
+	}
+
+	tc.MinVersion, tc.MaxVersion = min, max
+	tc.CipherSuites, tc.CurvePreferences = ciphers, curves
+	return nil
+}
+
+// ServerConfig returns the tls.Config the cluster service is served with.
+func (c TLSConfig) ServerConfig() (*tls.Config, error) {
+	tc := &tls.Config{}
+	if err := c.Apply(tc); err != nil {
+		return nil, err
+	}
+
+	cert, err := tls.LoadX509KeyPair(c.Certificate, c.PrivateKey)
+	if err != nil {
+		return nil, fmt.Errorf("load tls certificate: %s", err)
+	}
+	tc.Certificates = []tls.Certificate{cert}
+
+	if c.CA != "" {
+		pool, err := loadCertPool(c.CA)
+		if err != nil {
+			return nil, err
+		}
+		tc.ClientCAs = pool
+		tc.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return tc, nil
+}
+
+// ClientConfig returns the tls.Config other nodes are dialed with. The
+// certificate is presented to the nodes dialed if set.
+func (c TLSConfig) ClientConfig() (*tls.Config, error) {
+	tc := &tls.Config{}
+	if err := c.Apply(tc); err != nil {
+		return nil, err
+	}
+
+	if c.Certificate != "" || c.PrivateKey != "" {
+		cert, err := tls.LoadX509KeyPair(c.Certificate, c.PrivateKey)
+		if err != nil {
+			return nil, fmt.Errorf("load tls certificate: %s", err)
+		}
+		tc.Certificates = []tls.Certificate{cert}
+	}
+
+	if c.CA != "" {
+		pool, err := loadCertPool(c.CA)
+		if err != nil {
+			return nil, err
+		}
+		tc.RootCAs = pool
+	}
+	return tc, nil
+}
+
+// loadCertPool returns the pool of the PEM certificates in the file at path.
+func loadCertPool(path string) (*x509.CertPool, error) {
+	buf, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("load tls ca: %s", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(buf) {
+		return nil, fmt.Errorf("load tls ca: no certificate found in %s", path)
+	}
+	return pool, nil
+}
+
+// parseTLSVersion returns the TLS version named s, or 0 if s is empty.
+func parseTLSVersion(s string) (uint16, error) {
+	if s == "" {
+		return 0, nil
+	}
+	v, ok := tlsVersions[s]
+	if !ok {
+		return 0, fmt.Errorf("unknown tls version: %q", s)
+	}
+	return v, nil
+}
+
+// cipherSuiteByName returns the ID of the cipher suite named name, e.g.
+// "TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256".
+func cipherSuiteByName(name string) (uint16, bool) {
+	for _, s := range append(tls.CipherSuites(), tls.InsecureCipherSuites()...) {
+		if s.Name == name {
+			return s.ID, true
+		}
+	}
+	return 0, false
+}
+
+// contains returns true if a holds s.
+func contains(a []string, s string) bool {
+	for _, v := range a {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,134 @@
+package cluster
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// DefaultMaxWriteTraces is the default number of slowest write traces kept
+// when write tracing is enabled.
+const DefaultMaxWriteTraces = 10
+
+// Stages of the write path recorded in a WriteTrace.
+const (
+	StageMapShards     = "mapShards"
+	StageLocalWrite    = "localWrite"
+	StageRemoteWrite   = "remoteWrite"
+	StageHintedHandoff = "hhEnqueue"
+	StageForward       = "forward"
+)
+
+// WriteStage is the time spent in a single stage of a write.
+type WriteStage struct {
+	Name     string        `json:"name"`
+	ShardID  uint64        `json:"shardID,omitempty"`
+	NodeID   uint64        `json:"nodeID,omitempty"`
+	Started  time.Time     `json:"started"`
+	Duration time.Duration `json:"duration"`
+	Err      string        `json:"err,omitempty"`
+}
+
+// WriteTrace records the stages of a single call to WritePoints. The trace ID
+// is the request ID, which is sent to remote nodes with each shard write.
+type WriteTrace struct {
+	TraceID         string        `json:"traceID"`
+	Database        string        `json:"database"`
+	RetentionPolicy string        `json:"retentionPolicy"`
+	PointN          int           `json:"pointN"`
+	Started         time.Time     `json:"started"`
+	Duration        time.Duration `json:"duration"`
+	Err             string        `json:"err,omitempty"`
+	Stages          []WriteStage  `json:"stages"`
+}
+
+// writeTrace is the live state of a trace. A nil *writeTrace records nothing
+// so that callers do not need to check whether tracing is enabled.
+type writeTrace struct {
+	mu sync.Mutex
+	WriteTrace
+}
+
+// stage records a stage of the write that started at start and has just ended.
+func (t *writeTrace) stage(name string, shardID, nodeID uint64, start time.Time, err error) {
+	if t == nil {
+		return
+	}
+
+	s := WriteStage{
+		Name:     name,
+		ShardID:  shardID,
+		NodeID:   nodeID,
+		Started:  start,
+		Duration: time.Since(start),
+	}
+	if err != nil {
+		s.Err = err.Error()
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.Stages = append(t.Stages, s)
+}
+
+// WriteTracer keeps the slowest write traces.
+type WriteTracer struct {
+	mu     sync.Mutex
+	n      int
+	traces []WriteTrace // slowest first
+}
+
+// NewWriteTracer returns a WriteTracer that keeps the n slowest traces.
+func NewWriteTracer(n int) *WriteTracer {
+	return &WriteTracer{n: n}
+}
+
+// start begins a new trace. It returns nil if t is nil.
+func (t *WriteTracer) start(traceID, database, retentionPolicy string, pointN int) *writeTrace {
+	if t == nil {
+		return nil
+	}
+	return &writeTrace{WriteTrace: WriteTrace{
+		TraceID:         traceID,
+		Database:        database,
+		RetentionPolicy: retentionPolicy,
+		PointN:          pointN,
+		Started:         time.Now(),
+	}}
+}
+
+// finish completes tr and keeps it if it is among the n slowest. Stages still
+// running when the write returns, e.g. hinted handoff for a write that already
+// met its consistency level, are not included.
+func (t *WriteTracer) finish(tr *writeTrace, err error) WriteTrace {
+	tr.mu.Lock()
+	tr.Duration = time.Since(tr.Started)
+	if err != nil {
+		tr.Err = err.Error()
+	}
+	trace := tr.WriteTrace
+	trace.Stages = append([]WriteStage(nil), tr.Stages...)
+	tr.mu.Unlock()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if len(t.traces) == t.n && (t.n == 0 || trace.Duration <= t.traces[t.n-1].Duration) {
+		return trace
+	}
+
+	i := sort.Search(len(t.traces), func(i int) bool { return t.traces[i].Duration < trace.Duration })
+	t.traces = append(t.traces, WriteTrace{})
+	copy(t.traces[i+1:], t.traces[i:])
+	t.traces[i] = trace
+	if len(t.traces) > t.n {
+		t.traces = t.traces[:t.n]
+	}
+	return trace
+}
+
+// Traces returns the slowest traces recorded, slowest first.
+func (t *WriteTracer) Traces() []WriteTrace {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]WriteTrace(nil), t.traces...)
+}
@@ -0,0 +1,53 @@
+package cluster
+
+import (
+	"github.com/zhexuany/influxcloud/rpc"
+)
+
+// forwardTarget returns the remote node that owns the shards of the most
+// points in m, for a write to be forwarded to it as a whole rather than split
+// into one write per shard owner. A node is only chosen if its shards hold at
+// least threshold of the points, and more points than the local node's
+// shards, so that forwarding saves hops. Ties go to the lowest node ID.
+// Nodes for which skip returns true are not chosen.
+func forwardTarget(m *ShardMapping, localID uint64, threshold float64, skip func(nodeID uint64) bool) (uint64, bool) {
+	var total int
+	counts := make(map[uint64]int)
+	for shardID, points := range m.Points {
+		total += len(points)
+		for _, owner := range m.Shards[shardID].Owners {
+			counts[owner.NodeID] += len(points)
+		}
+	}
+	if total == 0 {
+		return 0, false
+	}
+
+	var target uint64
+	var best int
+	for nodeID, n := range counts {
+		if nodeID == localID || skip(nodeID) {
+			continue
+		}
+		if n > best || (n == best && nodeID < target) {
+			target, best = nodeID, n
+		}
+	}
+
+	if best == 0 || best <= counts[localID] || float64(best) < threshold*float64(total) {
+		return 0, false
+	}
+	return target, true
+}
+
+// forwardFailed returns true if a forwarded write failed before the remote
+// node could write any of its points, so that the write should be split and
+// sent to the shard owners instead. Errors the remote node returned for the
+// write itself are returned to the caller.
+func forwardFailed(err error) bool {
+	e, ok := err.(*rpc.WriteShardError)
+	if !ok {
+		return err != ErrTimeout
+	}
+	return e.Code == rpc.CodeDraining || e.Code == rpc.CodeReadOnly || e.Code == rpc.CodeUnsupported
+}
@@ -0,0 +1,46 @@
+package cluster
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxdb/services/meta"
+)
+
+// Ensure writes are forwarded to the node owning the shards of most points.
+func TestForwardTarget(t *testing.T) {
+	m := NewShardMapping(0)
+	mapShard := func(id uint64, pointN int, owners ...uint64) {
+		sh := &meta.ShardInfo{ID: id}
+		for _, nodeID := range owners {
+			sh.Owners = append(sh.Owners, meta.ShardOwner{NodeID: nodeID})
+		}
+		for i := 0; i < pointN; i++ {
+			m.MapPoint(sh, models.MustNewPoint("cpu", nil, models.Fields{"value": 1.0}, time.Unix(0, 0)))
+		}
+	}
+	mapShard(1, 6, 2, 3)
+	mapShard(2, 3, 3, 4)
+	mapShard(3, 1, 1)
+
+	none := func(uint64) bool { return false }
+	for _, tt := range []struct {
+		name      string
+		localID   uint64
+		threshold float64
+		skip      func(uint64) bool
+		target    uint64
+		ok        bool
+	}{
+		{name: "most points", localID: 1, threshold: 0.5, skip: none, target: 3, ok: true},
+		{name: "below threshold", localID: 1, threshold: 0.95, skip: none},
+		{name: "local owns more", localID: 3, threshold: 0.5, skip: none},
+		{name: "skipped", localID: 1, threshold: 0.5, skip: func(id uint64) bool { return id == 3 }, target: 2, ok: true},
+	} {
+		target, ok := forwardTarget(m, tt.localID, tt.threshold, tt.skip)
+		if target != tt.target || ok != tt.ok {
+			t.Errorf("%s: got node %d (%v), expected %d (%v)", tt.name, target, ok, tt.target, tt.ok)
+		}
+	}
+}
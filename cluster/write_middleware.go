@@ -0,0 +1,51 @@
+package cluster
+
+import (
+	"time"
+
+	"github.com/influxdata/influxdb/models"
+)
+
+// Write is a write passed through the middleware of a PointsWriter before
+// its points are mapped to shards. Middleware may change any field, e.g. to
+// add tags to the points or to route the write to another database.
+type Write struct {
+	RequestID        string
+	Deadline         time.Time
+	Database         string
+	RetentionPolicy  string
+	ConsistencyLevel models.ConsistencyLevel
+	Points           []models.Point
+
+	// DryRun is set for writes of WritePointsDryRun, which are not
+	// written.
+	DryRun bool
+}
+
+// WriteFunc writes w.
+type WriteFunc func(w *Write) error
+
+// WriteMiddleware wraps a WriteFunc, e.g. to validate, enrich or audit
+// writes before calling next, or to reject them by returning an error
+// without calling it.
+type WriteMiddleware func(next WriteFunc) WriteFunc
+
+// Use adds middleware to the writes of the PointsWriter. Middleware wraps
+// the middleware added before it, so the first middleware added sees each
+// write first. Writes forwarded by other nodes have already been through
+// their middleware and do not go through it again.
+func (w *PointsWriter) Use(middleware ...WriteMiddleware) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.middleware = append(w.middleware, middleware...)
+}
+
+// chain returns fn wrapped in the middleware of the PointsWriter.
+func (w *PointsWriter) chain(fn WriteFunc) WriteFunc {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	for i := len(w.middleware) - 1; i >= 0; i-- {
+		fn = w.middleware[i](fn)
+	}
+	return fn
+}
@@ -0,0 +1,514 @@
+// Command influxcloud-ctl manages the shards and data nodes of a cluster.
+package main
+
+import (
+	"encoding"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/influxdata/influxdb/cmd"
+	"github.com/zhexuany/influxcloud/cluster"
+	"github.com/zhexuany/influxcloud/rpc"
+	"github.com/zhexuany/influxcloud/tlv"
+)
+
+// DefaultBindAddress is the default address of the data node to contact.
+const DefaultBindAddress = "localhost:8088"
+
+func main() {
+	m := NewMain()
+	if err := m.Run(os.Args[1:]...); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+// Main represents the program execution.
+type Main struct {
+	// Address of the data node that requests are sent to.
+	Bind string
+
+	// Timeout for dialing and each round trip.
+	Timeout time.Duration
+
+	// Token authorizes the requests that mutate the cluster, if the data
+	// nodes have admin tokens configured.
+	Token string
+
+	// TLS dials the data nodes over TLS if enabled, presenting the
+	// certificate if set.
+	TLS cluster.TLSConfig
+
+	// NodeID is the data node the certificate presented belongs to, which
+	// the data nodes require over mutual TLS.
+	NodeID uint64
+
+	// clusterID is the cluster of the data nodes, told by the first one
+	// asked, once known.
+	clusterID uint64
+
+	Stdout io.Writer
+	Stderr io.Writer
+}
+
+// NewMain return a new instance of Main.
+func NewMain() *Main {
+	return &Main{
+		Bind:    DefaultBindAddress,
+		Timeout: 10 * time.Second,
+		Token:   os.Getenv("INFLUXCLOUD_ADMIN_TOKEN"),
+		Stdout:  os.Stdout,
+		Stderr:  os.Stderr,
+	}
+}
+
+// Run determines and runs the command specified by the CLI args.
+func (m *Main) Run(args ...string) error {
+	fs := flag.NewFlagSet("influxcloud-ctl", flag.ContinueOnError)
+	fs.StringVar(&m.Bind, "bind", m.Bind, "")
+	fs.StringVar(&m.Token, "token", m.Token, "")
+	fs.BoolVar(&m.TLS.Enabled, "tls", m.TLS.Enabled, "")
+	fs.StringVar(&m.TLS.CA, "tls-ca", m.TLS.CA, "")
+	fs.StringVar(&m.TLS.Certificate, "tls-cert", m.TLS.Certificate, "")
+	fs.StringVar(&m.TLS.PrivateKey, "tls-key", m.TLS.PrivateKey, "")
+	fs.Uint64Var(&m.NodeID, "node-id", m.NodeID, "")
+	fs.SetOutput(m.Stderr)
+	fs.Usage = func() { fmt.Fprintln(m.Stderr, usage) }
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	name, args := cmd.ParseCommandName(fs.Args())
+	switch name {
+	case "show-shards":
+		return m.showShards()
+	case "copy-shard":
+		return m.copyShard(args)
+	case "remove-shard":
+		return m.removeShard(args)
+	case "truncate-shards":
+		return m.truncateShards(args)
+	case "remove-data":
+		return m.removeData(args)
+	case "update-data":
+		return m.updateData(args)
+	case "replace-data":
+		return m.replaceData(args)
+	case "pause-replication":
+		return m.pauseReplication(args, false)
+	case "resume-replication":
+		return m.pauseReplication(args, true)
+	case "maintenance":
+		return m.maintenance(args)
+	case "read-only":
+		return m.readOnly(args)
+	case "quarantine":
+		return m.quarantine(args)
+	case "", "help":
+		fmt.Fprintln(m.Stdout, usage)
+		return nil
+	default:
+		return fmt.Errorf(`unknown command "%s"`+"\n"+`Run 'influxcloud-ctl help' for usage`+"\n\n", name)
+	}
+}
+
+func (m *Main) showShards() error {
+	shards, err := m.listShards(m.Bind)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(m.Stdout, 0, 8, 1, '\t', 0)
+	fmt.Fprintln(w, "ID\tDatabase\tRetention Policy\tShard Group\tStart\tEnd\tOwners")
+	for _, si := range shards {
+		owners := make([]string, len(si.Owners))
+		for i, id := range si.Owners {
+			owners[i] = strconv.FormatUint(id, 10)
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%d\t%s\t%s\t[%s]\n", si.ID, si.Database, si.Policy, si.ShardGroupID,
+			si.StartTime.Format(time.RFC3339), si.EndTime.Format(time.RFC3339), strings.Join(owners, ","))
+	}
+	return w.Flush()
+}
+
+func (m *Main) listShards(addr string) ([]rpc.ShardInfo, error) {
+	var resp rpc.ShowShardsResponse
+	if err := m.request(addr, tlv.ShowShardsRequestMessage, &rpc.ShowShardsRequest{}, &resp); err != nil {
+		return nil, err
+	} else if resp.Err != "" {
+		return nil, errors.New(resp.Err)
+	}
+	return resp.Shards, nil
+}
+
+func (m *Main) copyShard(args []string) error {
+	if len(args) != 3 {
+		return errors.New("usage: copy-shard <source-tcp-addr> <dest-tcp-addr> <shard-id>")
+	}
+	src, dest := args[0], args[1]
+	id, err := strconv.ParseUint(args[2], 10, 64)
+	if err != nil {
+		return err
+	}
+
+	// Look up which database and retention policy the shard belongs to.
+	shards, err := m.listShards(dest)
+	if err != nil {
+		return err
+	}
+	req := &rpc.CopyShardRequest{
+		Source:  src,
+		Dest:    dest,
+		ShardID: id,
+	}
+	for _, si := range shards {
+		if si.ID == id {
+			req.Database, req.Policy = si.Database, si.Policy
+		}
+	}
+	if req.Database == "" {
+		return fmt.Errorf("shard %d not found", id)
+	}
+
+	// The destination node pulls the shard from the source.
+	var resp rpc.CopyShardResponse
+	if err := m.request(dest, tlv.CopyShardRequestMessage, req, &resp); err != nil {
+		return err
+	} else if resp.Err != "" {
+		return errors.New(resp.Err)
+	}
+
+	fmt.Fprintf(m.Stdout, "Copied shard %d from %s to %s\n", id, src, dest)
+	return nil
+}
+
+func (m *Main) removeShard(args []string) error {
+	if len(args) != 2 {
+		return errors.New("usage: remove-shard <tcp-addr> <shard-id>")
+	}
+	id, err := strconv.ParseUint(args[1], 10, 64)
+	if err != nil {
+		return err
+	}
+
+	var resp rpc.RemoveShardResponse
+	if err := m.request(args[0], tlv.RemoveShardRequestMessage, &rpc.RemoveShardRequest{ShardID: id}, &resp); err != nil {
+		return err
+	} else if resp.Err != "" {
+		return errors.New(resp.Err)
+	}
+
+	fmt.Fprintf(m.Stdout, "Removed shard %d from %s\n", id, args[0])
+	return nil
+}
+
+func (m *Main) truncateShards(args []string) error {
+	fs := flag.NewFlagSet("truncate-shards", flag.ContinueOnError)
+	delay := fs.Duration("delay", time.Minute, "")
+	at := fs.String("time", "", "")
+	fs.SetOutput(m.Stderr)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	req := &rpc.TruncateShardsRequest{Delay: *delay}
+	if *at != "" {
+		t, err := time.Parse(time.RFC3339, *at)
+		if err != nil {
+			return fmt.Errorf("invalid time: %s", err)
+		}
+		req.Time = t
+	}
+
+	var resp rpc.TruncateShardsResponse
+	if err := m.request(m.Bind, tlv.TruncateShardsRequestMessage, req, &resp); err != nil {
+		return err
+	} else if resp.Err != "" {
+		return errors.New(resp.Err)
+	}
+
+	fmt.Fprintln(m.Stdout, "Truncated shards.")
+	return nil
+}
+
+func (m *Main) removeData(args []string) error {
+	fs := flag.NewFlagSet("remove-data", flag.ContinueOnError)
+	force := fs.Bool("force", false, "")
+	fs.SetOutput(m.Stderr)
+	if err := fs.Parse(args); err != nil {
+		return err
+	} else if fs.NArg() != 1 {
+		return errors.New("usage: remove-data [-force] <tcp-addr>")
+	}
+
+	var resp rpc.RemoveDataNodeResponse
+	if err := m.request(m.Bind, tlv.RemoveDataNodeRequestMessage, &rpc.RemoveDataNodeRequest{
+		TCPHost: fs.Arg(0),
+		Force:   *force,
+	}, &resp); err != nil {
+		return err
+	} else if resp.Err != "" {
+		return errors.New(resp.Err)
+	}
+
+	fmt.Fprintf(m.Stdout, "Removed data node %s\n", fs.Arg(0))
+	return nil
+}
+
+func (m *Main) updateData(args []string) error {
+	if len(args) != 2 {
+		return errors.New("usage: update-data <old-tcp-addr> <new-tcp-addr>")
+	}
+
+	var resp rpc.UpdateDataNodeResponse
+	if err := m.request(m.Bind, tlv.UpdateDataNodeRequestMessage, &rpc.UpdateDataNodeRequest{
+		OldTCPHost: args[0],
+		NewTCPHost: args[1],
+	}, &resp); err != nil {
+		return err
+	} else if resp.Err != "" {
+		return errors.New(resp.Err)
+	}
+
+	fmt.Fprintf(m.Stdout, "Updated data node %s to %s\n", args[0], args[1])
+	return nil
+}
+
+func (m *Main) replaceData(args []string) error {
+	if len(args) != 2 {
+		return errors.New("usage: replace-data <old-tcp-addr> <new-tcp-addr>")
+	}
+
+	var resp rpc.ReplaceDataNodeResponse
+	if err := m.request(m.Bind, tlv.ReplaceDataNodeRequestMessage, &rpc.ReplaceDataNodeRequest{
+		OldTCPHost: args[0],
+		NewTCPHost: args[1],
+	}, &resp); err != nil {
+		return err
+	} else if resp.Err != "" {
+		if len(resp.ShardIDs) > 0 {
+			fmt.Fprintf(m.Stderr, "Copied %d shards to %s before failing\n", len(resp.ShardIDs), args[1])
+		}
+		return errors.New(resp.Err)
+	}
+
+	fmt.Fprintf(m.Stdout, "Replaced data node %s with %s, copied %d shards\n", args[0], args[1], len(resp.ShardIDs))
+	return nil
+}
+
+func (m *Main) maintenance(args []string) error {
+	if len(args) != 2 || (args[1] != "on" && args[1] != "off") {
+		return errors.New("usage: maintenance <tcp-addr> on|off")
+	}
+
+	var resp rpc.SetMaintenanceResponse
+	if err := m.request(m.Bind, tlv.SetMaintenanceRequestMessage, &rpc.SetMaintenanceRequest{
+		TCPHost:     args[0],
+		Maintenance: args[1] == "on",
+	}, &resp); err != nil {
+		return err
+	} else if resp.Err != "" {
+		return errors.New(resp.Err)
+	}
+
+	if args[1] == "on" {
+		fmt.Fprintf(m.Stdout, "Data node %s is in maintenance\n", args[0])
+	} else {
+		fmt.Fprintf(m.Stdout, "Data node %s is out of maintenance\n", args[0])
+	}
+	return nil
+}
+
+func (m *Main) readOnly(args []string) error {
+	if len(args) != 2 || (args[1] != "on" && args[1] != "off") {
+		return errors.New("usage: read-only <tcp-addr> on|off")
+	}
+
+	var resp rpc.SetReadOnlyResponse
+	if err := m.request(args[0], tlv.SetReadOnlyRequestMessage, &rpc.SetReadOnlyRequest{
+		ReadOnly: args[1] == "on",
+	}, &resp); err != nil {
+		return err
+	} else if resp.Err != "" {
+		return errors.New(resp.Err)
+	}
+
+	if args[1] == "on" {
+		fmt.Fprintf(m.Stdout, "Data node %s is read-only\n", args[0])
+	} else {
+		fmt.Fprintf(m.Stdout, "Data node %s accepts writes\n", args[0])
+	}
+	return nil
+}
+
+func (m *Main) quarantine(args []string) error {
+	req := &rpc.QuarantineRequest{Action: rpc.QuarantineList}
+	switch {
+	case len(args) == 1:
+	case len(args) == 4 && (args[1] == rpc.QuarantineRequeue || args[1] == rpc.QuarantineDrop):
+		nodeID, err := strconv.ParseUint(args[2], 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid node ID: %s", args[2])
+		}
+		req.Action, req.NodeID, req.ID = args[1], nodeID, args[3]
+	default:
+		return errors.New("usage: quarantine <tcp-addr> [requeue|drop <node-id> <id>]")
+	}
+
+	var resp rpc.QuarantineResponse
+	if err := m.request(args[0], tlv.QuarantineRequestMessage, req, &resp); err != nil {
+		return err
+	} else if resp.Err != "" {
+		return errors.New(resp.Err)
+	}
+
+	switch req.Action {
+	case rpc.QuarantineRequeue:
+		fmt.Fprintf(m.Stdout, "Requeued write %s for node %d on %s\n", req.ID, req.NodeID, args[0])
+		return nil
+	case rpc.QuarantineDrop:
+		fmt.Fprintf(m.Stdout, "Dropped write %s for node %d on %s\n", req.ID, req.NodeID, args[0])
+		return nil
+	}
+
+	w := tabwriter.NewWriter(m.Stdout, 0, 8, 1, '\t', 0)
+	fmt.Fprintln(w, "Node\tID\tShard\tPoints\tSize\tAttempts\tQuarantined\tError")
+	for _, qw := range resp.Writes {
+		fmt.Fprintf(w, "%d\t%s\t%d\t%d\t%d\t%d\t%s\t%s\n", qw.NodeID, qw.ID, qw.ShardID, qw.PointN, qw.Size,
+			qw.Attempts, qw.Time.Format(time.RFC3339), qw.Err)
+	}
+	return w.Flush()
+}
+
+func (m *Main) pauseReplication(args []string, resume bool) error {
+	name := "pause-replication"
+	if resume {
+		name = "resume-replication"
+	}
+	if len(args) != 2 {
+		return fmt.Errorf("usage: %s <src-tcp-addr> <dest-tcp-addr>", name)
+	}
+
+	// The source node stops sending writes to the destination.
+	var resp rpc.PauseReplicationResponse
+	if err := m.request(args[0], tlv.PauseReplicationRequestMessage, &rpc.PauseReplicationRequest{
+		TCPHost: args[1],
+		Resume:  resume,
+	}, &resp); err != nil {
+		return err
+	} else if resp.Err != "" {
+		return errors.New(resp.Err)
+	}
+
+	if resume {
+		fmt.Fprintf(m.Stdout, "Resumed replication from %s to %s\n", args[0], args[1])
+	} else {
+		fmt.Fprintf(m.Stdout, "Paused replication from %s to %s\n", args[0], args[1])
+	}
+	return nil
+}
+
+// request sends a single request to the cluster service at addr and decodes the response.
+func (m *Main) request(addr string, typ byte, req encoding.BinaryMarshaler, resp encoding.BinaryUnmarshaler) error {
+	d := &cluster.Dialer{Resolver: cluster.DefaultResolver, Hello: m.hello}
+	if m.TLS.Enabled {
+		tc, err := m.TLS.ClientConfig()
+		if err != nil {
+			return err
+		}
+		d.TLS = tc
+	}
+
+	// The data nodes require a hello with their cluster ID once they know
+	// it, which they tell in their answer to a hello without.
+	if m.clusterID == 0 {
+		id, err := m.askClusterID(d, addr)
+		if err != nil {
+			return err
+		}
+		m.clusterID = id
+	}
+
+	conn, err := d.Dial(addr, m.Timeout)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if m.Token != "" {
+		if err := tlv.EncodeTLV(conn, tlv.AdminTokenMessage, &rpc.AdminToken{Token: m.Token}); err != nil {
+			return err
+		}
+	}
+	if err := tlv.EncodeTLV(conn, typ, req); err != nil {
+		return err
+	}
+
+	// Copying shards may take longer than a regular round trip.
+	if typ != tlv.CopyShardRequestMessage && typ != tlv.ReplaceDataNodeRequestMessage {
+		conn.SetReadDeadline(time.Now().Add(m.Timeout))
+	}
+
+	if _, err := tlv.DecodeTLV(conn, resp); err != nil {
+		return err
+	}
+	return nil
+}
+
+// hello returns the hello message said to the data nodes.
+func (m *Main) hello() *rpc.HelloRequest {
+	return &rpc.HelloRequest{NodeID: m.NodeID, ProtocolVersion: rpc.ProtocolVersion, ClusterID: m.clusterID}
+}
+
+// askClusterID returns the ID of the cluster of the data node at addr, or
+// zero if the data node does not know it yet.
+func (m *Main) askClusterID(d *cluster.Dialer, addr string) (uint64, error) {
+	conn, err := d.Dial(addr, m.Timeout)
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+
+	var resp rpc.HelloResponse
+	conn.SetDeadline(time.Now().Add(m.Timeout))
+	if err := tlv.EncodeTLV(conn, tlv.HelloRequestMessage, m.hello()); err != nil {
+		return 0, err
+	} else if _, err := tlv.DecodeTLV(conn, &resp); err != nil {
+		return 0, err
+	}
+	return resp.ClusterID, nil
+}
+
+const usage = `Usage: influxcloud-ctl [-bind <host:port>] [-token <token>] [-tls ...] <command> [arguments]
+
+Commands:
+
+    show-shards                                  list shards and their owners
+    copy-shard <src> <dest> <shard-id>           copy a shard from src to dest
+    remove-shard <addr> <shard-id>               remove a shard from a data node
+    truncate-shards [-delay <d>] [-time <t>]     end current shard groups at time t, or after delay
+    remove-data [-force] <addr>                  remove a data node from the cluster
+    update-data <old-addr> <new-addr>            change the TCP address of a data node
+    replace-data <old-addr> <new-addr>           hand the shards of a dead data node over to a new one
+    pause-replication <src> <dest>               queue writes from src to dest in hinted handoff
+    resume-replication <src> <dest>              resume writes from src to dest
+    maintenance <addr> on|off                    route writes to a data node through hinted handoff
+    read-only <addr> on|off                      reject the writes other nodes send to a data node
+    quarantine <addr> [requeue|drop <node> <id>] list, requeue or drop the quarantined hinted handoff writes
+
+Options:
+
+    -bind <host:port>  data node to send requests to (default localhost:8088)
+    -token <token>     admin token authorizing the requests (default $INFLUXCLOUD_ADMIN_TOKEN)
+    -tls               dial the data node over TLS
+    -tls-ca <path>     certificate authorities verifying the data node (default system roots)
+    -tls-cert <path>   certificate of a data node, if the data node requires mutual TLS
+    -tls-key <path>    private key of the client certificate
+    -node-id <id>      data node the client certificate belongs to`
@@ -2,15 +2,18 @@
 package run
 
 import (
+	"errors"
 	"flag"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"log"
 	"os"
+	"os/signal"
 	"path/filepath"
 	"runtime"
 	"strconv"
+	"syscall"
 	"time"
 
 	"github.com/uber-go/zap"
@@ -43,7 +46,14 @@ type Command struct {
 	Stderr io.Writer
 	Logger zap.Logger
 
+	// LogLevel is the level of Logger, set from the config. The level is
+	// left as is if nil.
+	LogLevel *zap.AtomicLevel
+
 	Server *Server
+
+	// The config file parsed again on reload.
+	configPath string
 }
 
 // NewCommand return a new instance of Command.
@@ -80,19 +90,10 @@ func (cmd *Command) Run(args ...string) error {
 	}
 
 	// Parse config
-	config, err := cmd.ParseConfig(options.GetConfigPath())
+	cmd.configPath = options.GetConfigPath()
+	config, err := cmd.loadConfig()
 	if err != nil {
-		return fmt.Errorf("parse config: %s", err)
-	}
-
-	// Apply any environment variables on top of the parsed config
-	if err := config.ApplyEnvOverrides(); err != nil {
-		return fmt.Errorf("apply env config: %v", err)
-	}
-
-	// Validate the configuration.
-	if err := config.Validate(); err != nil {
-		return fmt.Errorf("%s. To generate a valid configuration file run `influxd config > influxdb.generated.conf`", err)
+		return err
 	}
 
 	if config.HTTPD.PprofEnabled {
@@ -112,6 +113,7 @@ func (cmd *Command) Run(args ...string) error {
 		return fmt.Errorf("create server: %s", err)
 	}
 	s.Logger = cmd.Logger
+	s.LogLevel = cmd.LogLevel
 	s.CPUProfile = options.CPUProfile
 	s.MemProfile = options.MemProfile
 	if err := s.Open(); err != nil {
@@ -122,9 +124,46 @@ func (cmd *Command) Run(args ...string) error {
 	// Begin monitoring the server's error channel.
 	go cmd.monitorServerErrors()
 
+	// Reload the config on SIGHUP.
+	go cmd.reloadOnSignal()
+
 	return nil
 }
 
+// Reload parses the config file again and applies the settings that can
+// change while the server runs. The running server is left as is if the
+// config is invalid.
+func (cmd *Command) Reload() error {
+	if cmd.Server == nil {
+		return errors.New("server not running")
+	}
+	config, err := cmd.loadConfig()
+	if err != nil {
+		return err
+	}
+	return cmd.Server.Reload(config)
+}
+
+// loadConfig parses the config file with the environment variables applied
+// on top of it, and validates it.
+func (cmd *Command) loadConfig() (*Config, error) {
+	config, err := cmd.ParseConfig(cmd.configPath)
+	if err != nil {
+		return nil, fmt.Errorf("parse config: %s", err)
+	}
+
+	// Apply any environment variables on top of the parsed config
+	if err := config.ApplyEnvOverrides(); err != nil {
+		return nil, fmt.Errorf("apply env config: %v", err)
+	}
+
+	// Validate the configuration.
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("%s. To generate a valid configuration file run `influxd config > influxdb.generated.conf`", err)
+	}
+	return config, nil
+}
+
 // Close shuts down the server.
 func (cmd *Command) Close() error {
 	defer close(cmd.Closed)
@@ -147,6 +186,26 @@ func (cmd *Command) monitorServerErrors() {
 	}
 }
 
+// reloadOnSignal reloads the config each time the process receives SIGHUP,
+// until the command is closed.
+func (cmd *Command) reloadOnSignal() {
+	signalCh := make(chan os.Signal, 1)
+	signal.Notify(signalCh, syscall.SIGHUP)
+	defer signal.Stop(signalCh)
+
+	for {
+		select {
+		case <-signalCh:
+			cmd.Logger.Info("SIGHUP received, reloading configuration")
+			if err := cmd.Reload(); err != nil {
+				cmd.Logger.Error("unable to reload configuration", zap.Error(err))
+			}
+		case <-cmd.closing:
+			return
+		}
+	}
+}
+
 // ParseFlags parses the command line flags from args and returns an options set.
 func (cmd *Command) ParseFlags(args ...string) (Options, error) {
 	var options Options
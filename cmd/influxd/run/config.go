@@ -29,6 +29,7 @@ import (
 	"github.com/influxdata/influxdb/services/subscriber"
 	"github.com/influxdata/influxdb/services/udp"
 	"github.com/influxdata/influxdb/tsdb"
+	"github.com/uber-go/zap"
 	"github.com/zhexuany/influxcloud/cluster"
 	"github.com/zhexuany/influxcloud/hh"
 )
@@ -36,6 +37,9 @@ import (
 const (
 	// DefaultBindAddress is the default address for various RPC services.
 	DefaultBindAddress = ":8088"
+
+	// DefaultLogLevel is the default level of the messages logged.
+	DefaultLogLevel = "info"
 )
 
 // Config represents the configuration format for the influxd binary.
@@ -65,6 +69,17 @@ type Config struct {
 
 	// BindAddress is the address that all TCP services use (Raft, Snapshot, Cluster, etc.)
 	BindAddress string `toml:"bind-address"`
+
+	// AdvertiseAddress and HTTPAdvertiseAddress are the addresses other nodes
+	// reach the TCP services and the HTTP API at, when they differ from the
+	// bind addresses, e.g. behind NAT in Docker or Kubernetes. The bind
+	// addresses are used if empty.
+	AdvertiseAddress     string `toml:"advertise-address"`
+	HTTPAdvertiseAddress string `toml:"http-advertise-address"`
+
+	// LogLevel is the least severe level of the messages logged, e.g.
+	// "debug", "info" or "warn". It can be changed without a restart.
+	LogLevel string `toml:"log-level"`
 }
 
 // NewConfig returns an instance of Config with reasonable defaults.
@@ -88,6 +103,7 @@ func NewConfig() *Config {
 	c.ContinuousQuery = continuous_querier.NewConfig()
 	c.Retention = retention.NewConfig()
 	c.BindAddress = DefaultBindAddress
+	c.LogLevel = DefaultLogLevel
 
 	return c
 }
@@ -176,6 +192,14 @@ func (c *Config) Validate() error {
 		return err
 	}
 
+	if err := c.Cluster.Validate(); err != nil {
+		return err
+	}
+
+	if _, err := c.logLevel(); err != nil {
+		return err
+	}
+
 	for _, graphite := range c.GraphiteInputs {
 		if err := graphite.Validate(); err != nil {
 			return fmt.Errorf("invalid graphite config: %v", err)
@@ -191,6 +215,17 @@ func (c *Config) Validate() error {
 	return nil
 }
 
+// logLevel returns the parsed LogLevel, or the default level if it is empty.
+func (c *Config) logLevel() (zap.Level, error) {
+	var l zap.Level
+	if c.LogLevel == "" {
+		return zap.InfoLevel, nil
+	} else if err := l.UnmarshalText([]byte(c.LogLevel)); err != nil {
+		return l, fmt.Errorf("invalid log-level: %s", err)
+	}
+	return l, nil
+}
+
 // ApplyEnvOverrides apply the environment configuration on top of the config.
 func (c *Config) ApplyEnvOverrides() error {
 	return c.applyEnvOverrides("INFLUXDB", reflect.ValueOf(c), "")
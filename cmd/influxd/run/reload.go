@@ -0,0 +1,138 @@
+package run
+
+import (
+	"encoding"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/uber-go/zap"
+)
+
+// reloadable are the settings Reload applies while the server runs, named
+// by the path of their toml keys.
+var reloadable = map[string]bool{
+	"log-level":                          true,
+	"cluster.write-timeout":              true,
+	"cluster.owner-write-timeout":        true,
+	"cluster.shard-writer-timeout":       true,
+	"cluster.write-retry-policy":         true,
+	"cluster.max-write-retries":          true,
+	"cluster.write-hedge-delay":          true,
+	"cluster.write-point-codec":          true,
+	"cluster.shard-copy-rate-limit":      true,
+	"cluster.shard-copy-node-rate-limit": true,
+	"cluster.ready-max-hh-backlog":       true,
+	"cluster.admin-token":                true,
+}
+
+// Reload applies the reloadable settings of c while the server runs, and
+// records each one that changed in the audit log of the cluster service.
+// Changes to other settings are logged, by name only, and take effect once
+// the server is restarted. Nothing is applied if c is invalid.
+func (s *Server) Reload(c *Config) error {
+	if err := c.Validate(); err != nil {
+		return err
+	}
+
+	if s.ClusterServerice != nil {
+		if err := s.ClusterServerice.Reload(c.Cluster); err != nil {
+			return err
+		}
+	}
+	if err := s.setLogLevel(c); err != nil {
+		return err
+	}
+
+	for _, ch := range diffConfig(s.config, c) {
+		if !reloadable[ch.name] {
+			s.Logger.Warn("setting changed, restart required", zap.String("setting", ch.name))
+		}
+	}
+	for _, ch := range diffConfig(s.applied, c) {
+		if !reloadable[ch.name] {
+			continue
+		}
+		s.Logger.Info("setting reloaded", zap.String("setting", ch.name), zap.String("old", ch.old), zap.String("new", ch.new))
+		if s.ClusterServerice != nil {
+			s.ClusterServerice.AuditReload(ch.name, ch.old, ch.new)
+		}
+	}
+	s.applied = c
+	return nil
+}
+
+// setLogLevel sets LogLevel to the log level of c, if LogLevel is set.
+func (s *Server) setLogLevel(c *Config) error {
+	if s.LogLevel == nil {
+		return nil
+	}
+	l, err := c.logLevel()
+	if err != nil {
+		return err
+	}
+	s.LogLevel.SetLevel(l)
+	return nil
+}
+
+// configChange is a setting that differs between two configs.
+type configChange struct {
+	name     string // path of the toml keys, e.g. "cluster.write-timeout"
+	old, new string
+}
+
+// diffConfig returns the settings that differ between old and new.
+func diffConfig(old, new *Config) []configChange {
+	var changes []configChange
+	diffSetting(&changes, "", reflect.ValueOf(old).Elem(), reflect.ValueOf(new).Elem())
+	return changes
+}
+
+// diffSetting appends the settings that differ between old and new, named
+// below name, to changes. Sections are compared setting by setting, while
+// lists such as the graphite inputs are compared as a whole.
+func diffSetting(changes *[]configChange, name string, old, new reflect.Value) {
+	if old.Kind() == reflect.Ptr {
+		if old.IsNil() || new.IsNil() {
+			if old.IsNil() != new.IsNil() {
+				*changes = append(*changes, configChange{name: name})
+			}
+			return
+		}
+		old, new = old.Elem(), new.Elem()
+	}
+
+	if _, ok := old.Interface().(encoding.TextMarshaler); !ok && old.Kind() == reflect.Struct {
+		for i := 0; i < old.NumField(); i++ {
+			f := old.Type().Field(i)
+			if f.PkgPath != "" {
+				continue
+			}
+			key := strings.Split(f.Tag.Get("toml"), ",")[0]
+			if key == "-" {
+				continue
+			} else if key == "" {
+				key = strings.ToLower(f.Name)
+			}
+			if name != "" {
+				key = name + "." + key
+			}
+			diffSetting(changes, key, old.Field(i), new.Field(i))
+		}
+		return
+	}
+
+	if !reflect.DeepEqual(old.Interface(), new.Interface()) {
+		*changes = append(*changes, configChange{name: name, old: formatSetting(old), new: formatSetting(new)})
+	}
+}
+
+// formatSetting returns v as it would be written in the config file.
+func formatSetting(v reflect.Value) string {
+	if m, ok := v.Interface().(encoding.TextMarshaler); ok {
+		if b, err := m.MarshalText(); err == nil {
+			return string(b)
+		}
+	}
+	return fmt.Sprint(v.Interface())
+}
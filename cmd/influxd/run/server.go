@@ -65,6 +65,10 @@ type Server struct {
 
 	Logger zap.Logger
 
+	// LogLevel is set to the log level of the config when the server is
+	// opened and reloaded, if set. It should be the level of Logger.
+	LogLevel *zap.AtomicLevel
+
 	MetaClient *meta.Client
 
 	TSDBStore     *tsdb.Store
@@ -87,16 +91,19 @@ type Server struct {
 	CPUProfile string
 	MemProfile string
 
-	// httpAPIAddr is the host:port combination for the main HTTP API for querying and writing data
+	// httpAPIAddr is the host:port combination other nodes reach the main HTTP API for querying and writing data at
 	httpAPIAddr string
 
 	// httpUseTLS specifies if we should use a TLS connection to the http servers
 	httpUseTLS bool
 
-	// tcpAddr is the host:port combination for the TCP listener that services mux onto
+	// tcpAddr is the host:port combination other nodes reach the TCP listener that services mux onto at
 	tcpAddr string
 
 	config *Config
+
+	// applied is the config the reloadable settings were last applied from.
+	applied *Config
 }
 
 // NewServer returns a new instance of Server built from a config.
@@ -135,6 +142,15 @@ func NewServer(c *Config, buildInfo *BuildInfo) (*Server, error) {
 	// The old location to keep things backwards compatible
 	bind := c.BindAddress
 
+	// Other nodes reach this node at the advertised addresses, if any.
+	tcpAddr, httpAPIAddr := bind, c.HTTPD.BindAddress
+	if c.AdvertiseAddress != "" {
+		tcpAddr = c.AdvertiseAddress
+	}
+	if c.HTTPAdvertiseAddress != "" {
+		httpAPIAddr = c.HTTPAdvertiseAddress
+	}
+
 	s := &Server{
 		buildInfo: *buildInfo,
 		err:       make(chan error),
@@ -151,11 +167,12 @@ func NewServer(c *Config, buildInfo *BuildInfo) (*Server, error) {
 
 		reportingDisabled: c.ReportingDisabled,
 
-		httpAPIAddr: c.HTTPD.BindAddress,
+		httpAPIAddr: httpAPIAddr,
 		httpUseTLS:  c.HTTPD.HTTPSEnabled,
-		tcpAddr:     bind,
+		tcpAddr:     tcpAddr,
 
-		config: c,
+		config:  c,
+		applied: c,
 	}
 	s.Monitor = monitor.New(s, c.Monitor)
 
@@ -352,6 +369,9 @@ func (s *Server) appendContinuousQueryService(c continuous_querier.Config) {
 func (s *Server) appendClusterService(c cluster.Config) {
 	srv := cluster.NewService(c)
 	srv.TSDBStore = s.TSDBStore
+	srv.ShardStore = s.TSDBStore
+	srv.Version = s.buildInfo.Version
+	srv.Metrics.Register(s.PointsWriter)
 	s.Services = append(s.Services, srv)
 	s.ClusterServerice = srv
 }
@@ -364,6 +384,10 @@ func (s *Server) Open() error {
 	// Start profiling, if set.
 	startProfile(s.CPUProfile, s.MemProfile)
 
+	if err := s.setLogLevel(s.config); err != nil {
+		return err
+	}
+
 	// Open shared TCP connection.
 	ln, err := net.Listen("tcp", s.BindAddress)
 	if err != nil {
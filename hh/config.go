@@ -2,25 +2,32 @@ package hh
 
 import (
 	"errors"
+	"fmt"
 	"time"
 
 	"github.com/influxdata/influxdb/toml"
 )
 
 const (
-	// DefaultMaxSize is the default maximum size of all hinted handoff queues in bytes.
+	// DefaultMaxSize is the default maximum size of the hinted handoff queue of
+	// each node in bytes.
 	DefaultMaxSize = 1024 * 1024 * 1024
 
 	// DefaultMaxAge is the default maximum amount of time that a hinted handoff write
 	// can stay in the queue.  After this time, the write will be purged.
 	DefaultMaxAge = 7 * 24 * time.Hour
 
+	// DefaultOverflowPolicy is the default policy for writes to a node's
+	// queue once it has reached its maximum size.
+	DefaultOverflowPolicy = OverflowDropNew
+
 	// DefaultConcurrency is the default number of queued writes to process at a time .
 	DefaultRetryConcurrency = 20
 
-	// DefaultRetryRateLimit is the default rate that hinted handoffs will be retried.
-	// The rate is in bytes per second and applies across all nodes when retried.   A
-	// value of 0 disables the rate limit.
+	// DefaultRetryRateLimit is the default maximum rate that hinted handoffs will be
+	// retried at. The rate is in bytes per second and applies to each node; below it,
+	// the rate adapts to how quickly the node accepts writes. A value of 0 disables
+	// the rate limit.
 	DefaultRetryRateLimit = 0
 
 	// DefaultRetryInterval is the default amount of time the system waits before
@@ -33,11 +40,37 @@ const (
 	// will ever be.
 	DefaultRetryMaxInterval = time.Minute
 
+	// DefaultReplayBatchSize is the default number of bytes of queued writes
+	// sent to a node at once.
+	DefaultReplayBatchSize = 1024 * 1024
+
+	// DefaultReplayTargetLatency is the default longest time a batch of queued
+	// writes may take to send before the rate they are sent at is reduced.
+	DefaultReplayTargetLatency = time.Second
+
+	// DefaultQuarantineAfter is the default number of times in a row a batch
+	// of queued writes may fail to be stored by a node before the writes
+	// that fail again on their own are quarantined. A value of 0 disables
+	// quarantine, so that failing writes are retried until they expire.
+	DefaultQuarantineAfter = 10
+
 	// DefaultPurgeInterval is the amount of time the system waits before attempting
 	// to purge hinted handoff data due to age or inactive nodes.
 	DefaultPurgeInterval = time.Hour
 )
 
+// Policies for writes to a node's queue once it has reached its maximum size.
+const (
+	// OverflowDropOldest drops the oldest queued writes to make room.
+	OverflowDropOldest = "drop-oldest"
+
+	// OverflowDropNew rejects new writes with ErrQueueFull.
+	OverflowDropNew = "drop-new"
+
+	// OverflowBlock blocks new writes until queued writes have been sent.
+	OverflowBlock = "block"
+)
+
 // Config is a hinted handoff configuration.
 type Config struct {
 	Enabled          bool          `toml:"enabled"`
@@ -49,6 +82,33 @@ type Config struct {
 	RetryInterval    toml.Duration `toml:"retry-interval"`
 	RetryMaxInterval toml.Duration `toml:"retry-max-interval"`
 	PurgeInterval    toml.Duration `toml:"purge-interval"`
+	OverflowPolicy   string        `toml:"overflow-policy"`
+
+	ReplayBatchSize     int64         `toml:"replay-batch-size"`
+	ReplayTargetLatency toml.Duration `toml:"replay-target-latency"`
+
+	QuarantineAfter int `toml:"quarantine-after"`
+
+	// EncryptionKeyFile names a file, and EncryptionKeyEnv an environment
+	// variable, holding the hex-encoded AES key the queued writes are
+	// encrypted with on disk. Queues are not encrypted if neither is set.
+	// Writes already queued are still read once either is set, but writes
+	// queued encrypted cannot be sent without the key. Quarantined writes
+	// are not encrypted.
+	EncryptionKeyFile string `toml:"encryption-key-file"`
+	EncryptionKeyEnv  string `toml:"encryption-key-env"`
+
+	// Nodes overrides the queue limits for individual nodes.
+	Nodes []NodeConfig `toml:"node"`
+}
+
+// NodeConfig overrides the queue limits for the node with the given ID.
+// Limits that are not set are taken from the Config.
+type NodeConfig struct {
+	ID             uint64        `toml:"id"`
+	MaxSize        int64         `toml:"max-size"`
+	MaxAge         toml.Duration `toml:"max-age"`
+	OverflowPolicy string        `toml:"overflow-policy"`
 }
 
 // NewConfig returns a new Config.
@@ -62,12 +122,68 @@ func NewConfig() Config {
 		RetryInterval:    toml.Duration(DefaultRetryInterval),
 		RetryMaxInterval: toml.Duration(DefaultRetryMaxInterval),
 		PurgeInterval:    toml.Duration(DefaultPurgeInterval),
+		OverflowPolicy:   DefaultOverflowPolicy,
+
+		ReplayBatchSize:     DefaultReplayBatchSize,
+		ReplayTargetLatency: toml.Duration(DefaultReplayTargetLatency),
+
+		QuarantineAfter: DefaultQuarantineAfter,
+	}
+}
+
+// NodeConfig returns the queue limits for the node nodeID.
+func (c *Config) NodeConfig(nodeID uint64) NodeConfig {
+	nc := NodeConfig{
+		ID:             nodeID,
+		MaxSize:        c.MaxSize,
+		MaxAge:         c.MaxAge,
+		OverflowPolicy: c.OverflowPolicy,
+	}
+	for _, o := range c.Nodes {
+		if o.ID != nodeID {
+			continue
+		}
+		if o.MaxSize != 0 {
+			nc.MaxSize = o.MaxSize
+		}
+		if o.MaxAge != 0 {
+			nc.MaxAge = o.MaxAge
+		}
+		if o.OverflowPolicy != "" {
+			nc.OverflowPolicy = o.OverflowPolicy
+		}
 	}
+	return nc
 }
 
 func (c *Config) Validate() error {
 	if c.Enabled && c.Dir == "" {
 		return errors.New("HintedHandoff.Dir must be specified")
 	}
+	if err := validateOverflowPolicy(c.OverflowPolicy); err != nil {
+		return err
+	}
+	if c.QuarantineAfter < 0 {
+		return fmt.Errorf("HintedHandoff.QuarantineAfter must not be negative: %d", c.QuarantineAfter)
+	}
+	if c.EncryptionKeyFile != "" && c.EncryptionKeyEnv != "" {
+		return errors.New("HintedHandoff.EncryptionKeyFile and HintedHandoff.EncryptionKeyEnv are mutually exclusive")
+	}
+	for _, n := range c.Nodes {
+		if n.OverflowPolicy == "" {
+			continue
+		}
+		if err := validateOverflowPolicy(n.OverflowPolicy); err != nil {
+			return fmt.Errorf("node %d: %s", n.ID, err)
+		}
+	}
 	return nil
 }
+
+func validateOverflowPolicy(policy string) error {
+	switch policy {
+	case "", OverflowDropOldest, OverflowDropNew, OverflowBlock:
+		return nil
+	}
+	return fmt.Errorf("unknown HintedHandoff.OverflowPolicy: %q", policy)
+}
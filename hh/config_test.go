@@ -1,6 +1,8 @@
 package hh_test
 
 import (
+	"io/ioutil"
+	"os"
 	"testing"
 	"time"
 
@@ -20,6 +22,13 @@ max-size=2048
 max-age="20m"
 retry-rate-limit=1000
 purge-interval = "1h"
+overflow-policy = "drop-oldest"
+quarantine-after = 3
+
+[[node]]
+id = 2
+max-size = 4096
+overflow-policy = "block"
 `, &c); err != nil {
 		t.Fatal(err)
 	}
@@ -57,6 +66,22 @@ purge-interval = "1h"
 		t.Fatalf("unexpected purge interval: got %v, exp %v", c.PurgeInterval, exp)
 	}
 
+	if exp := hh.OverflowDropOldest; c.OverflowPolicy != exp {
+		t.Fatalf("unexpected overflow policy: got %v, exp %v", c.OverflowPolicy, exp)
+	}
+
+	if exp := 3; c.QuarantineAfter != exp {
+		t.Fatalf("unexpected quarantine after: got %v, exp %v", c.QuarantineAfter, exp)
+	}
+
+	// Limits not overridden for a node are inherited.
+	if exp := (hh.NodeConfig{ID: 2, MaxSize: 4096, MaxAge: c.MaxAge, OverflowPolicy: hh.OverflowBlock}); c.NodeConfig(2) != exp {
+		t.Fatalf("unexpected node config: got %+v, exp %+v", c.NodeConfig(2), exp)
+	}
+	if exp := (hh.NodeConfig{ID: 3, MaxSize: 2048, MaxAge: c.MaxAge, OverflowPolicy: hh.OverflowDropOldest}); c.NodeConfig(3) != exp {
+		t.Fatalf("unexpected node config: got %+v, exp %+v", c.NodeConfig(3), exp)
+	}
+
 }
 
 func TestDefaultDisabled(t *testing.T) {
@@ -76,3 +101,45 @@ func TestDefaultDisabled(t *testing.T) {
 		t.Fatalf("unexpected default enabled value: got %v, exp %v", c.Enabled, exp)
 	}
 }
+
+// Ensure the encryption key is read from a file or the environment.
+func TestConfig_EncryptionKey(t *testing.T) {
+	const key = "000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f"
+
+	f, err := ioutil.TempFile("", "hh_key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.WriteString(key + "\n"); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	os.Setenv("HH_TEST_KEY", key)
+	defer os.Unsetenv("HH_TEST_KEY")
+	os.Setenv("HH_TEST_SHORT_KEY", "0001")
+	defer os.Unsetenv("HH_TEST_SHORT_KEY")
+
+	c := hh.NewConfig()
+	if b, err := c.EncryptionKey(); err != nil || b != nil {
+		t.Fatalf("unexpected key: %x, %v", b, err)
+	}
+	for _, c := range []hh.Config{{EncryptionKeyFile: f.Name()}, {EncryptionKeyEnv: "HH_TEST_KEY"}} {
+		if b, err := c.EncryptionKey(); err != nil {
+			t.Fatal(err)
+		} else if len(b) != 32 || b[31] != 0x1f {
+			t.Fatalf("unexpected key: %x", b)
+		}
+	}
+
+	for _, c := range []hh.Config{
+		{EncryptionKeyFile: f.Name(), EncryptionKeyEnv: "HH_TEST_KEY"},
+		{EncryptionKeyEnv: "HH_TEST_SHORT_KEY"},
+		{EncryptionKeyEnv: "HH_TEST_UNSET_KEY"},
+	} {
+		if _, err := c.EncryptionKey(); err == nil {
+			t.Fatalf("expected error for %+v", c)
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package hh
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+)
+
+// keyIDSize is the size of the key ID an encrypted block starts with: the
+// start of the SHA-256 hash of the key it was encrypted with, so that a
+// block read with another key is told apart from a corrupt one.
+const keyIDSize = 4
+
+// ErrNoEncryptionKey is returned for an encrypted block read from a queue
+// that was opened without an encryption key.
+var ErrNoEncryptionKey = errors.New("block is encrypted, but no encryption key is configured")
+
+// blockCipher encrypts and decrypts the blocks of segmentV2 files with
+// AES-GCM.
+//
+// The stored body of an encrypted block is the key ID, a random nonce, and
+// the sealed body, itself compressed if blockCompressed is set. The flags of
+// the block are authenticated along with it, so that they cannot be changed
+// to have a block read as something else.
+type blockCipher struct {
+	aead  cipher.AEAD
+	keyID [keyIDSize]byte
+}
+
+// newBlockCipher returns a blockCipher encrypting with key, an AES key of 16,
+// 24 or 32 bytes.
+func newBlockCipher(key []byte) (*blockCipher, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	c := &blockCipher{aead: aead}
+	sum := sha256.Sum256(key)
+	copy(c.keyID[:], sum[:])
+	return c, nil
+}
+
+// optionalBlockCipher returns a blockCipher encrypting with key, or nil if
+// key is empty.
+func optionalBlockCipher(key []byte) (*blockCipher, error) {
+	if len(key) == 0 {
+		return nil, nil
+	}
+	return newBlockCipher(key)
+}
+
+// seal returns body encrypted as the stored body of a block with flags.
+func (c *blockCipher) seal(body []byte, flags byte) ([]byte, error) {
+	n := c.aead.NonceSize()
+	b := make([]byte, keyIDSize+n, keyIDSize+n+len(body)+c.aead.Overhead())
+	copy(b, c.keyID[:])
+	if _, err := io.ReadFull(rand.Reader, b[keyIDSize:]); err != nil {
+		return nil, err
+	}
+	return c.aead.Seal(b, b[keyIDSize:], body, []byte{flags}), nil
+}
+
+// open returns the body of a block with flags that seal encrypted as b. A
+// nil blockCipher returns ErrNoEncryptionKey.
+func (c *blockCipher) open(b []byte, flags byte) ([]byte, error) {
+	if c == nil {
+		return nil, ErrNoEncryptionKey
+	}
+	n := c.aead.NonceSize()
+	if len(b) < keyIDSize+n+c.aead.Overhead() {
+		return nil, errors.New("encrypted block too short")
+	}
+	if !bytes.Equal(b[:keyIDSize], c.keyID[:]) {
+		return nil, errors.New("block is encrypted with another key")
+	}
+	return c.aead.Open(nil, b[keyIDSize:keyIDSize+n], b[keyIDSize+n:], []byte{flags})
+}
+
+// EncryptionKey returns the key the queues are encrypted with, read from
+// EncryptionKeyFile or from the environment variable EncryptionKeyEnv, or
+// nil if neither is set. The key is hex encoded, and must decode to an AES
+// key of 16, 24 or 32 bytes.
+func (c *Config) EncryptionKey() ([]byte, error) {
+	var s string
+	switch {
+	case c.EncryptionKeyFile != "" && c.EncryptionKeyEnv != "":
+		return nil, errors.New("HintedHandoff.EncryptionKeyFile and HintedHandoff.EncryptionKeyEnv are mutually exclusive")
+	case c.EncryptionKeyFile != "":
+		b, err := ioutil.ReadFile(c.EncryptionKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("read HintedHandoff.EncryptionKeyFile: %s", err)
+		}
+		s = string(b)
+	case c.EncryptionKeyEnv != "":
+		v, ok := os.LookupEnv(c.EncryptionKeyEnv)
+		if !ok {
+			return nil, fmt.Errorf("HintedHandoff.EncryptionKeyEnv: %s is not set", c.EncryptionKeyEnv)
+		}
+		s = v
+	default:
+		return nil, nil
+	}
+
+	key, err := hex.DecodeString(strings.TrimSpace(s))
+	if err != nil {
+		return nil, fmt.Errorf("invalid hinted handoff encryption key: %s", err)
+	}
+	switch len(key) {
+	case 16, 24, 32:
+		return key, nil
+	}
+	return nil, fmt.Errorf("invalid hinted handoff encryption key: %d bytes, must be 16, 24 or 32", len(key))
+}
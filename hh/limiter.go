@@ -1,6 +1,9 @@
 package hh
 
-import "time"
+import (
+	"math"
+	"time"
+)
 
 type limiter struct {
 	count int64
@@ -59,3 +62,68 @@ func (t *limiter) clamp(value, min, max float64) float64 {
 	}
 	return value
 }
+
+// aimdLimiter adapts the rate data is sent to a node at to how the node
+// copes with it. The rate grows by step bytes per second after each batch
+// the node accepts within the target latency, and is halved after a batch
+// that fails or is slower, so that a recovering node is not overwhelmed.
+type aimdLimiter struct {
+	rate   float64 // bytes per second
+	min    float64
+	max    float64 // unlimited if zero
+	step   float64
+	target time.Duration
+}
+
+// newAIMDLimiter returns a limiter starting at min bytes per second, and
+// never exceeding max unless max is zero.
+func newAIMDLimiter(min, max, step int64, target time.Duration) *aimdLimiter {
+	l := &aimdLimiter{
+		rate:   float64(min),
+		min:    float64(min),
+		max:    float64(max),
+		step:   float64(step),
+		target: target,
+	}
+	if l.max > 0 && l.min > l.max {
+		l.rate, l.min = l.max, l.max
+	}
+	return l
+}
+
+// setLimits changes the maximum rate, the step the rate grows by and the
+// target latency, keeping the current rate within max.
+func (l *aimdLimiter) setLimits(max, step int64, target time.Duration) {
+	l.max, l.step, l.target = float64(max), float64(step), target
+	if l.max > 0 && l.min > l.max {
+		l.min = l.max
+	}
+	if l.max > 0 && l.rate > l.max {
+		l.rate = l.max
+	}
+}
+
+// Update adjusts the rate for a batch of n bytes that took latency to send
+// and failed if err is not nil. It returns how long to wait before sending
+// the next batch to keep to the rate.
+func (l *aimdLimiter) Update(n int, latency time.Duration, err error) time.Duration {
+	if err != nil || (l.target > 0 && latency > l.target) {
+		l.rate = math.Max(l.rate/2, l.min)
+	} else {
+		l.rate += l.step
+		if l.max > 0 && l.rate > l.max {
+			l.rate = l.max
+		}
+	}
+
+	if err != nil || l.rate <= 0 {
+		return 0
+	}
+	if delay := time.Duration(float64(n)/l.rate*float64(time.Second)) - latency; delay > 0 {
+		return delay
+	}
+	return 0
+}
+
+// Rate returns the current rate in bytes per second.
+func (l *aimdLimiter) Rate() int64 { return int64(l.rate) }
@@ -1,6 +1,7 @@
 package hh
 
 import (
+	"errors"
 	"testing"
 	"time"
 )
@@ -45,3 +46,33 @@ func TestLimiterExceeded(t *testing.T) {
 		t.Errorf("limiter rate mismatch. expected non-zero delay")
 	}
 }
+
+func TestAIMDLimiter(t *testing.T) {
+	l := newAIMDLimiter(1000, 4000, 1000, time.Second)
+
+	// The rate grows additively while batches are accepted in time.
+	if delay := l.Update(2000, 0, nil); l.Rate() != 2000 || delay != time.Second {
+		t.Fatalf("unexpected rate %d and delay %v", l.Rate(), delay)
+	}
+	l.Update(0, 0, nil)
+	l.Update(0, 0, nil)
+	if exp := int64(4000); l.Rate() != exp {
+		t.Fatalf("unexpected rate: got %d, exp %d", l.Rate(), exp)
+	}
+
+	// Time spent sending counts towards the delay.
+	if delay := l.Update(4000, 250*time.Millisecond, nil); delay != 750*time.Millisecond {
+		t.Fatalf("unexpected delay: %v", delay)
+	}
+
+	// The rate is halved after slow or failed batches, down to the minimum.
+	l.Update(0, 2*time.Second, nil)
+	if exp := int64(2000); l.Rate() != exp {
+		t.Fatalf("unexpected rate: got %d, exp %d", l.Rate(), exp)
+	}
+	l.Update(0, 0, errors.New("write failed"))
+	l.Update(0, 0, errors.New("write failed"))
+	if exp := int64(1000); l.Rate() != exp {
+		t.Fatalf("unexpected rate: got %d, exp %d", l.Rate(), exp)
+	}
+}
@@ -5,12 +5,14 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/influxdata/influxdb/models"
 	"github.com/uber-go/zap"
+	"github.com/zhexuany/influxcloud/rpc"
 	"sync/atomic"
 )
 
@@ -24,8 +26,19 @@ const (
 	statWriteConcurrencyReq       = "writeConcurrencyReq"
 	statWriteConcurrencyReqFail   = "writeConcurrencyReqFail"
 	statWriteConcurrencyReqPoints = "writeConcurrencyReqPoints"
+	statQueueAge                  = "queueAgeNs"
+	statQueueFull                 = "queueFullReq"
+	statQueueBlocked              = "queueBlockedReq"
+	statQueueDroppedBytes         = "queueDroppedBytes"
+	statQueuePurgedBytes          = "queuePurgedBytes"
+	statReplayRate                = "replayRateBytes"
+	statQueueQuarantined          = "queueQuarantinedReq"
 )
 
+// minReplayRate is the rate in bytes per second queued data is sent at
+// after the target node fails or slows down.
+const minReplayRate = 64 * 1024
+
 // NodeProcessor encapsulates a queue of hinted-handoff data for a node, and the
 // transmission of the data to the node.
 type NodeProcessor struct {
@@ -35,20 +48,61 @@ type NodeProcessor struct {
 	MaxSize          int64         // Maximum size an underlying queue can get.
 	MaxAge           time.Duration // Maximum age queue data can get before purging.
 	RetryRateLimit   int64         // Limits the rate data is sent to node.
+	OverflowPolicy   string        // What to do with writes once the queue is full.
 	nodeID           uint64
 	dir              string
 
+	// ReplayBatchSize is the number of bytes of queued writes read at once
+	// and sent as one write per shard. ReplayTargetLatency is the longest a
+	// batch may take before the rate data is sent at is reduced.
+	ReplayBatchSize     int64
+	ReplayTargetLatency time.Duration
+
+	// QuarantineAfter is the number of times in a row a batch may fail to be
+	// stored by the node before the writes of the batch that fail again on
+	// their own are moved to QuarantineDir, rather than retried until they
+	// expire. Writes are not quarantined if either is unset.
+	QuarantineAfter int
+	QuarantineDir   string
+
+	// EncryptionKey is the AES key the queue is encrypted with on disk, if
+	// set. It must be set before Open.
+	EncryptionKey []byte
+
+	// settingsMu guards the settings above once the processor is open, so
+	// that Reconfigure can change them while it runs. reconfigured wakes run
+	// to pick up the new settings.
+	settingsMu   sync.RWMutex
+	reconfigured chan struct{}
+
 	mu   sync.RWMutex
 	wg   sync.WaitGroup
 	done chan struct{}
 
+	// space is closed and replaced whenever queued data is sent or purged,
+	// waking writes blocked on a full queue.
+	spaceMu sync.Mutex
+	space   chan struct{}
+	full    int32 // non-zero while the queue is full
+
 	queue  *queue
 	meta   metaClient
 	writer shardWriter
 
+	// appendMu orders the sequence numbers of writes as they are queued.
+	appendMu sync.Mutex
+	seq      *sequencer
+
 	stats       *Statistics
 	defaultTags models.StatisticTags
 	Logger      zap.Logger
+
+	// OnQueueFull is called each time the queue fills up, if set.
+	OnQueueFull func(nodeID uint64)
+
+	paused   int32  // non-zero while sending to the node is paused
+	target   uint64 // node queued data is sent to instead of nodeID, if non-zero
+	failures int32  // times in a row the batch at the head failed to be stored
 }
 
 // NewNodeProcessor returns a new NodeProcessor for the given node, using dir for
@@ -59,6 +113,9 @@ func NewNodeProcessor(nodeID uint64, dir string, w shardWriter, m metaClient) *N
 		dir:    dir,
 		writer: w,
 		meta:   m,
+		space:  make(chan struct{}),
+
+		reconfigured: make(chan struct{}, 1),
 
 		stats: &Statistics{},
 		defaultTags: models.StatisticTags{
@@ -91,15 +148,34 @@ func (n *NodeProcessor) Open() error {
 		return fmt.Errorf("mkdir all: %s", err)
 	}
 
+	seq, err := openSequencer(filepath.Join(n.dir, sequenceFile))
+	if err != nil {
+		return err
+	}
+	n.seq = seq
+
 	// Create the queue of hinted-handoff data.
 	queue, err := newQueue(n.dir, n.MaxSize)
 	if err != nil {
 		return err
 	}
+	if len(n.EncryptionKey) > 0 {
+		if err := queue.SetEncryptionKey(n.EncryptionKey); err != nil {
+			return err
+		}
+	}
 
 	if err := queue.Open(); err != nil {
 		return err
 	}
+
+	// Keep several segments within MaxSize, as space is only freed once a
+	// whole segment has been sent or dropped.
+	if size := n.MaxSize / 4; size > 0 && size < defaultSegmentSize {
+		if err := queue.SetMaxSegmentSize(size); err != nil {
+			return err
+		}
+	}
 	n.queue = queue
 
 	atomic.StoreInt64(&n.stats.WriteDiskBytes, queue.TotalBytes())
@@ -124,6 +200,7 @@ func (n *NodeProcessor) Close() error {
 	close(n.done)
 	n.wg.Wait()
 	n.done = nil
+	n.notifySpace()
 
 	return n.queue.Close()
 }
@@ -157,6 +234,12 @@ type Statistics struct {
 	WriteShardConcurrentlyPoints int64
 	WriteDiskBytes               int64
 	WriteDiskSegments            int64
+	ReplayRate                   int64
+	QueueFull                    int64
+	QueueBlocked                 int64
+	QueueDroppedBytes            int64
+	QueuePurgedBytes             int64
+	QueueQuarantined             int64
 }
 
 // Statistics returns statistics for periodic monitoring.
@@ -176,10 +259,34 @@ func (n *NodeProcessor) Statistics(tags map[string]string) []models.Statistic {
 			statWriteConcurrencyReqPoints: atomic.LoadInt64(&n.stats.WriteShardConcurrentlyPoints),
 			"diskBytes":                   atomic.LoadInt64(&n.stats.WriteDiskBytes),
 			"totalSegments":               atomic.LoadInt64(&n.stats.WriteDiskSegments),
+			statQueueAge:                  int64(n.QueueAge()),
+			statQueueFull:                 atomic.LoadInt64(&n.stats.QueueFull),
+			statQueueBlocked:              atomic.LoadInt64(&n.stats.QueueBlocked),
+			statQueueDroppedBytes:         atomic.LoadInt64(&n.stats.QueueDroppedBytes),
+			statQueuePurgedBytes:          atomic.LoadInt64(&n.stats.QueuePurgedBytes),
+			statReplayRate:                atomic.LoadInt64(&n.stats.ReplayRate),
+			statQueueQuarantined:          atomic.LoadInt64(&n.stats.QueueQuarantined),
 		},
 	}}
 }
 
+// QueueAge returns the age of the oldest data in the hinted-handoff queue. It is
+// measured from the last write to the head segment, so it underestimates the
+// age while the queue fits in a single segment.
+func (n *NodeProcessor) QueueAge() time.Duration {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+
+	if n.queue == nil {
+		return 0
+	}
+	mod, err := n.queue.HeadModified()
+	if err != nil || mod.IsZero() {
+		return 0
+	}
+	return time.Since(mod)
+}
+
 // Purge deletes all hinted-handoff data under management by a NodeProcessor.
 // The NodeProcessor should be in the closed state before calling this function.
 func (n *NodeProcessor) Purge() error {
@@ -196,28 +303,90 @@ func (n *NodeProcessor) Purge() error {
 // WriteShard writes hinted-handoff data for the given shard and node. Since it may manipulate
 // hinted-handoff queues, and be called concurrently, it takes a lock during queue access.
 func (n *NodeProcessor) WriteShard(shardID uint64, points []models.Point) error {
+	return n.append(len(points), marshalWrite(shardID, points))
+}
+
+// WriteEncodedShard writes hinted-handoff data for the given shard and node
+// from points that are already encoded.
+func (n *NodeProcessor) WriteEncodedShard(shardID uint64, points *rpc.EncodedPoints) error {
+	return n.append(points.Len(), marshalEncodedWrite(shardID, points))
+}
+
+// append adds a marshaled write of pointN points to the queue. If the queue
+// is full, the write is handled according to the OverflowPolicy.
+func (n *NodeProcessor) append(pointN int, b []byte) error {
+	for retry := false; ; retry = true {
+		space, err := n.tryAppend(pointN, b, retry)
+		if space == nil {
+			return err
+		}
+
+		// Wait for queued data to be sent, or the processor to be closed.
+		<-space
+	}
+}
+
+// tryAppend adds a marshaled write to the queue. If the queue is full and
+// writes block, it returns a channel that is closed once there may be space.
+func (n *NodeProcessor) tryAppend(pointN int, b []byte, retry bool) (<-chan struct{}, error) {
 	n.mu.RLock()
 	defer n.mu.RUnlock()
 
 	if n.done == nil {
-		return fmt.Errorf("node processor is closed")
+		return nil, fmt.Errorf("node processor is closed")
 	}
 
-	atomic.AddInt64(&n.stats.WriteShardReq, 1)
-	atomic.AddInt64(&n.stats.WriteShardReqPoints, int64(len(points)))
+	if !retry {
+		atomic.AddInt64(&n.stats.WriteShardReq, 1)
+		atomic.AddInt64(&n.stats.WriteShardReqPoints, int64(pointN))
+	}
 
-	go func() {
+	if len(b) == 0 {
+		return nil, nil
+	}
+	defer func() {
+		// Updated while the processor is known to be open, as the queue's
+		// segments are gone once it is closed.
 		atomic.StoreInt64(&n.stats.WriteDiskSegments, n.queue.totalSegments())
 		atomic.StoreInt64(&n.stats.WriteDiskBytes, n.queue.TotalBytes())
-
 	}()
 
-	b := marshalWrite(shardID, points)
-	if len(b) == 0 {
-		return nil
+	// Writes are queued in the order of their sequence numbers, so that
+	// replaying them sends increasing numbers.
+	n.appendMu.Lock()
+	defer n.appendMu.Unlock()
+	seq, err := n.seq.next()
+	if err != nil {
+		return nil, err
 	}
-
-	if err := n.queue.Append(b); err != nil {
+	b = sequencedWrite(b, seq)
+
+	space := n.spaceC()
+	err = n.queue.Append(b)
+	if err == ErrQueueFull {
+		n.queueFull(retry)
+		n.settingsMu.RLock()
+		policy := n.OverflowPolicy
+		n.settingsMu.RUnlock()
+		switch policy {
+		case OverflowDropOldest:
+			dropped, derr := n.queue.DropOldest(int64(len(b)))
+			if dropped > 0 {
+				atomic.AddInt64(&n.stats.QueueDroppedBytes, dropped)
+				n.Logger.Warn("hinted handoff queue full, dropped oldest writes", zap.Uint64("nodeID", n.nodeID), zap.Int64("bytes", dropped))
+			}
+			if derr != nil {
+				return nil, derr
+			}
+			err = n.queue.Append(b)
+		case OverflowBlock:
+			if !retry {
+				atomic.AddInt64(&n.stats.QueueBlocked, 1)
+			}
+			return space, nil
+		}
+	}
+	if err != nil {
 		//
 		if err == ErrNotOpen {
 			select {
@@ -225,13 +394,81 @@ func (n *NodeProcessor) WriteShard(shardID uint64, points []models.Point) error
 				//do nothing, wait queue closed
 			}
 		}
-		return err
+		return nil, err
+	}
+
+	if atomic.CompareAndSwapInt32(&n.full, 1, 0) {
+		n.Logger.Info("hinted handoff queue accepting writes again", zap.Uint64("nodeID", n.nodeID))
 	}
 
 	//
 	atomic.AddInt64(&n.stats.WriteShardReq, 1)
 
-	return nil
+	return nil, nil
+}
+
+// queueFull records that a write found the queue full, logging it once each
+// time the queue fills up.
+func (n *NodeProcessor) queueFull(retry bool) {
+	if !retry {
+		atomic.AddInt64(&n.stats.QueueFull, 1)
+	}
+	if atomic.CompareAndSwapInt32(&n.full, 0, 1) {
+		n.settingsMu.RLock()
+		maxSize, policy := n.MaxSize, n.OverflowPolicy
+		n.settingsMu.RUnlock()
+		n.Logger.Warn("hinted handoff queue full", zap.Uint64("nodeID", n.nodeID), zap.Int64("maxSize", maxSize), zap.String("overflowPolicy", policy))
+		if n.OnQueueFull != nil {
+			n.OnQueueFull(n.nodeID)
+		}
+	}
+}
+
+// spaceC returns the channel closed once queued data is next sent or purged.
+func (n *NodeProcessor) spaceC() <-chan struct{} {
+	n.spaceMu.Lock()
+	defer n.spaceMu.Unlock()
+	return n.space
+}
+
+// notifySpace wakes the writes waiting for space in the queue.
+func (n *NodeProcessor) notifySpace() {
+	n.spaceMu.Lock()
+	defer n.spaceMu.Unlock()
+	close(n.space)
+	n.space = make(chan struct{})
+}
+
+// Pause stops sending queued data to the node. Data is still accepted and queued.
+func (n *NodeProcessor) Pause() { atomic.StoreInt32(&n.paused, 1) }
+
+// Resume restarts sending queued data to the node.
+func (n *NodeProcessor) Resume() { atomic.StoreInt32(&n.paused, 0) }
+
+// Paused returns true if sending to the node is paused.
+func (n *NodeProcessor) Paused() bool { return atomic.LoadInt32(&n.paused) != 0 }
+
+// SetTarget sends queued data to nodeID instead of the node the data was
+// queued for, such as a node that replaced it.
+func (n *NodeProcessor) SetTarget(nodeID uint64) { atomic.StoreUint64(&n.target, nodeID) }
+
+// Target returns the ID of the node queued data is sent to.
+func (n *NodeProcessor) Target() uint64 {
+	if id := atomic.LoadUint64(&n.target); id != 0 {
+		return id
+	}
+	return n.nodeID
+}
+
+// QueueSize returns the number of bytes in the hinted-handoff queue.
+func (n *NodeProcessor) QueueSize() int64 {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+
+	if n.queue == nil {
+		return 0
+	}
+	return n.queue.TotalBytes()
 }
 
 // LastModified returns the time the NodeProcessor last receieved hinted-handoff data.
@@ -244,55 +481,141 @@ func (n *NodeProcessor) LastModified() (time.Time, error) {
 }
 
 // run attempts to send any existing hinted handoff data to the target node. It also purges
-// any hinted handoff data older than the configured time.
+// any hinted handoff data older than the configured time. The waits restart
+// with the new settings whenever Reconfigure is called.
 func (n *NodeProcessor) run() {
 	defer n.wg.Done()
 
-	currInterval := time.Duration(n.RetryInterval)
-	if currInterval > time.Duration(n.RetryMaxInterval) {
-		currInterval = time.Duration(n.RetryMaxInterval)
+	l := n.replayLimits()
+	currInterval := l.retryInterval
+	if currInterval > l.retryMaxInterval {
+		currInterval = l.retryMaxInterval
 	}
 
+	// The send rate adapts to the target node, never exceeding the
+	// configured limit.
+	limiter := newAIMDLimiter(minReplayRate, l.rateLimit, l.batchSize, l.targetLatency)
+	atomic.StoreInt64(&n.stats.ReplayRate, limiter.Rate())
+
 	for {
+		if nl := n.replayLimits(); nl != l {
+			// Restart the backoff from the new retry interval.
+			l = nl
+			limiter.setLimits(l.rateLimit, l.batchSize, l.targetLatency)
+			currInterval = l.retryInterval
+			if currInterval > l.retryMaxInterval {
+				currInterval = l.retryMaxInterval
+			}
+		}
+
 		select {
 		case <-n.done:
 			return
 
-		case <-time.After(n.PurgeInterval):
-			if err := n.queue.PurgeOlderThan(time.Now().Add(-n.MaxAge)); err != nil {
+		case <-n.reconfigured:
+
+		case <-time.After(l.purgeInterval):
+			before := n.queue.TotalBytes()
+			if err := n.queue.PurgeOlderThan(time.Now().Add(-l.maxAge)); err != nil {
 				// n.Logger.Info("failed to purge for node %d: %s", n.nodeID, err.Error())
 			}
+			if purged := before - n.queue.TotalBytes(); purged > 0 {
+				atomic.AddInt64(&n.stats.QueuePurgedBytes, purged)
+				n.Logger.Warn("purged hinted handoff writes older than max age", zap.Uint64("nodeID", n.nodeID), zap.Int64("bytes", purged), zap.Duration("maxAge", l.maxAge))
+				n.notifySpace()
+			}
 
 		case <-time.After(currInterval):
-			limiter := NewRateLimiter(n.RetryRateLimit)
 			for {
-				c, err := n.SendWrite()
+				start := time.Now()
+				c, err := n.sendBatch()
+				if err != io.EOF {
+					delay := limiter.Update(c, time.Since(start), err)
+					atomic.StoreInt64(&n.stats.ReplayRate, limiter.Rate())
+					if err == nil {
+						// Block to maintain the throughput rate
+						time.Sleep(delay)
+					}
+				}
 				if err != nil {
 					if err == io.EOF {
 						// No more data, return to configured interval
-						currInterval = time.Duration(n.RetryInterval)
+						currInterval = l.retryInterval
 					} else {
 						currInterval = currInterval * 2
-						if currInterval > time.Duration(n.RetryMaxInterval) {
-							currInterval = time.Duration(n.RetryMaxInterval)
+						if currInterval > l.retryMaxInterval {
+							currInterval = l.retryMaxInterval
 						}
 					}
 					break
 				}
 
 				// Success! Ensure backoff is cancelled.
-				currInterval = time.Duration(n.RetryInterval)
-
-				// Update how many bytes we've sent
-				limiter.Update(c)
-
-				// Block to maintain the throughput rate
-				time.Sleep(limiter.Delay())
+				currInterval = l.retryInterval
 			}
 		}
 	}
 }
 
+// replayLimits are the settings run sends and purges queued data with.
+type replayLimits struct {
+	purgeInterval    time.Duration
+	retryInterval    time.Duration
+	retryMaxInterval time.Duration
+	maxAge           time.Duration
+	targetLatency    time.Duration
+	rateLimit        int64
+	batchSize        int64
+}
+
+// replayLimits returns the current settings of run.
+func (n *NodeProcessor) replayLimits() replayLimits {
+	n.settingsMu.RLock()
+	defer n.settingsMu.RUnlock()
+	return replayLimits{
+		purgeInterval:    n.PurgeInterval,
+		retryInterval:    n.RetryInterval,
+		retryMaxInterval: n.RetryMaxInterval,
+		maxAge:           n.MaxAge,
+		targetLatency:    n.ReplayTargetLatency,
+		rateLimit:        n.RetryRateLimit,
+		batchSize:        n.batchSize(),
+	}
+}
+
+// Reconfigure applies the retry, replay and queue limits of c for the node
+// of the processor, while it runs. A smaller MaxSize does not drop queued
+// data; new writes are handled by the OverflowPolicy until the queue fits.
+func (n *NodeProcessor) Reconfigure(c Config) {
+	nc := c.NodeConfig(n.nodeID)
+
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+
+	n.settingsMu.Lock()
+	n.RetryInterval = time.Duration(c.RetryInterval)
+	n.RetryMaxInterval = time.Duration(c.RetryMaxInterval)
+	n.RetryRateLimit = c.RetryRateLimit
+	n.ReplayBatchSize = c.ReplayBatchSize
+	n.ReplayTargetLatency = time.Duration(c.ReplayTargetLatency)
+	n.MaxSize = nc.MaxSize
+	n.MaxAge = time.Duration(nc.MaxAge)
+	n.OverflowPolicy = nc.OverflowPolicy
+	n.QuarantineAfter = c.QuarantineAfter
+	n.settingsMu.Unlock()
+
+	select {
+	case n.reconfigured <- struct{}{}:
+	default:
+	}
+
+	if n.queue != nil {
+		n.queue.SetMaxSize(nc.MaxSize)
+		// Writes blocked on a full queue may fit now.
+		n.notifySpace()
+	}
+}
+
 // SendWrite attempts to sent the current block of hinted data to the target node. If successful,
 // it returns the number of bytes it sent and advances to the next block. Otherwise returns EOF
 // when there is no more data or the node is inactive.
@@ -300,6 +623,10 @@ func (n *NodeProcessor) SendWrite() (int, error) {
 	n.mu.RLock()
 	defer n.mu.RUnlock()
 
+	if n.Paused() {
+		return 0, io.EOF
+	}
+
 	active, err := n.Active()
 	if err != nil {
 		return 0, err
@@ -316,7 +643,7 @@ func (n *NodeProcessor) SendWrite() (int, error) {
 	// ch := make(chan error)
 
 	// unmarshal the byte slice back to shard ID and points
-	shardID, points, err := unmarshalWrite(buf)
+	shardID, seq, points, err := unmarshalSequencedWrite(buf)
 	if err != nil {
 		atomic.AddInt64(&n.stats.WriteNodeReqFail, 1)
 		// n.Logger.Info("unmarshal write failed: %v", err)
@@ -326,7 +653,7 @@ func (n *NodeProcessor) SendWrite() (int, error) {
 		return 0, err
 	}
 
-	if err := n.writer.WriteShard(shardID, n.nodeID, points); err != nil {
+	if err := n.writeShard(shardID, seq, points); err != nil {
 		// ch <- err
 		return 0, err
 	}
@@ -348,11 +675,141 @@ func (n *NodeProcessor) SendWrite() (int, error) {
 	if err := n.queue.Advance(); err != nil {
 		// n.Logger.Info("failed to advance queue for node %d: %s", n.nodeID, err.Error())
 	}
+	n.notifySpace()
 
 	// return how much length already wroten into node
 	return len(buf), nil
 }
 
+// SendBatch sends the queued writes at the head of the queue, up to
+// ReplayBatchSize bytes across segments, as one write per shard. If every
+// write succeeds, it returns the number of bytes sent and advances past them.
+// Otherwise the batch is sent again later; the node skips the writes to
+// shards that succeeded, unless some of their queued writes have no sequence
+// number. It returns EOF when there is no more data or the node is inactive.
+func (n *NodeProcessor) SendBatch() (int, error) {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+	return n.sendBatch()
+}
+
+// sendBatch sends a batch without taking the lock. It is called directly by
+// run, which Close waits for while holding the lock.
+func (n *NodeProcessor) sendBatch() (int, error) {
+	if n.Paused() {
+		return 0, io.EOF
+	}
+
+	active, err := n.Active()
+	if err != nil {
+		return 0, err
+	}
+	if !active {
+		return 0, io.EOF
+	}
+
+	blocks, err := n.queue.PeekBlocks(n.replayBatchSize())
+	if err != nil {
+		return 0, err
+	}
+	if dir, ok := n.isolating(); ok {
+		return n.sendIsolated(blocks, dir)
+	}
+
+	// Coalesce the points of each shard, in the order the shards were
+	// first written to.
+	var shardIDs []uint64
+	points := make(map[uint64][]models.Point)
+	var size int
+
+	// The write of a shard is sent with the sequence number of its last
+	// queued write, or none if any was queued without one.
+	seqs := make(map[uint64]uint64)
+	unsequenced := make(map[uint64]bool)
+	for _, buf := range blocks {
+		shardID, seq, p, err := unmarshalSequencedWrite(buf)
+		if err != nil {
+			atomic.AddInt64(&n.stats.WriteNodeReqFail, 1)
+			n.failed()
+			return 0, err
+		}
+		if _, ok := points[shardID]; !ok {
+			shardIDs = append(shardIDs, shardID)
+		}
+		points[shardID] = append(points[shardID], p...)
+		size += len(buf)
+		if seq == 0 {
+			unsequenced[shardID] = true
+		} else if seq > seqs[shardID] {
+			seqs[shardID] = seq
+		}
+	}
+
+	for _, shardID := range shardIDs {
+		seq := seqs[shardID]
+		if unsequenced[shardID] {
+			seq = 0
+		}
+		if err := n.writeShard(shardID, seq, points[shardID]); err != nil {
+			atomic.AddInt64(&n.stats.WriteNodeReqFail, 1)
+			if isStoreError(err) {
+				n.failed()
+			}
+			return 0, err
+		}
+		atomic.AddInt64(&n.stats.WriteShardReq, 1)
+		atomic.AddInt64(&n.stats.WriteNodeReq, 1)
+		atomic.AddInt64(&n.stats.WriteNodeReqPoints, int64(len(points[shardID])))
+	}
+
+	atomic.StoreInt32(&n.failures, 0)
+	for range blocks {
+		if err := n.queue.Advance(); err != nil {
+			return size, err
+		}
+	}
+	n.notifySpace()
+
+	return size, nil
+}
+
+// writeShard sends points, the queued writes of shardID up to seq, to the
+// target node. They are sent without a sequence number if seq is zero or the
+// writer cannot send one.
+func (n *NodeProcessor) writeShard(shardID, seq uint64, points []models.Point) error {
+	if w, ok := n.writer.(handoffShardWriter); ok && seq != 0 {
+		return w.WriteHandoffShard(shardID, n.Target(), n.nodeID, seq, points)
+	}
+	return n.writer.WriteShard(shardID, n.Target(), points)
+}
+
+// replayBatchSize returns ReplayBatchSize, or the default if it is not set.
+func (n *NodeProcessor) replayBatchSize() int64 {
+	n.settingsMu.RLock()
+	defer n.settingsMu.RUnlock()
+	return n.batchSize()
+}
+
+// batchSize is replayBatchSize without taking the settings lock.
+func (n *NodeProcessor) batchSize() int64 {
+	if n.ReplayBatchSize <= 0 {
+		return DefaultReplayBatchSize
+	}
+	return n.ReplayBatchSize
+}
+
+// Flush sends queued writes to the target node until the queue is empty, the
+// processor is paused or the node is inactive, or a write fails.
+func (n *NodeProcessor) Flush() error {
+	for {
+		if _, err := n.SendBatch(); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+	}
+}
+
 // Head returns the head of the processor's queue.
 func (n *NodeProcessor) Head() string {
 	qp, err := n.queue.Position()
@@ -373,7 +830,7 @@ func (n *NodeProcessor) Tail() string {
 
 // Active returns whether this node processor is for a currently active node.
 func (n *NodeProcessor) Active() (bool, error) {
-	nio, err := n.meta.DataNode(n.nodeID)
+	nio, err := n.meta.DataNode(n.Target())
 	if err != nil {
 		return false, err
 	}
@@ -397,11 +854,86 @@ func marshalWrite(shardID uint64, points []models.Point) []byte {
 	return b
 }
 
+// encodedWriteMarker follows the shard ID of a write marshaled from encoded
+// points. Line protocol never starts with a zero byte, so writes queued as
+// line protocol are still read back correctly.
+const encodedWriteMarker = 0
+
+// marshalEncodedWrite marshals encoded points as a sequence of length-prefixed
+// points, without decoding them.
+func marshalEncodedWrite(shardID uint64, points *rpc.EncodedPoints) []byte {
+	n := 9
+	for _, p := range points.Bytes() {
+		n += 4 + len(p)
+	}
+
+	b := make([]byte, 9, n)
+	binary.BigEndian.PutUint64(b, shardID)
+	b[8] = encodedWriteMarker
+	for _, p := range points.Bytes() {
+		var sz [4]byte
+		binary.BigEndian.PutUint32(sz[:], uint32(len(p)))
+		b = append(b, sz[:]...)
+		b = append(b, p...)
+	}
+	return b
+}
+
+// sequencedWriteMarker follows the shard ID of a write with a sequence
+// number. The number follows it, then the write as marshaled without one.
+const sequencedWriteMarker = 1
+
+// sequencedWrite returns b, a marshaled write, with the sequence number seq.
+func sequencedWrite(b []byte, seq uint64) []byte {
+	out := make([]byte, 17, len(b)+9)
+	copy(out, b[:8])
+	out[8] = sequencedWriteMarker
+	binary.BigEndian.PutUint64(out[9:17], seq)
+	return append(out, b[8:]...)
+}
+
 func unmarshalWrite(b []byte) (uint64, []models.Point, error) {
+	shardID, _, points, err := unmarshalSequencedWrite(b)
+	return shardID, points, err
+}
+
+// unmarshalSequencedWrite returns the shard ID, sequence number and points of
+// a marshaled write. The sequence number is zero if the write has none.
+func unmarshalSequencedWrite(b []byte) (shardID, seq uint64, points []models.Point, err error) {
 	if len(b) < 8 {
-		return 0, nil, fmt.Errorf("too short: len = %d", len(b))
+		return 0, 0, nil, fmt.Errorf("too short: len = %d", len(b))
+	}
+	shardID = binary.BigEndian.Uint64(b[:8])
+	b = b[8:]
+	if len(b) > 0 && b[0] == sequencedWriteMarker {
+		if len(b) < 9 {
+			return 0, 0, nil, fmt.Errorf("sequence too short: len = %d", len(b))
+		}
+		seq = binary.BigEndian.Uint64(b[1:9])
+		b = b[9:]
+	}
+	if len(b) > 0 && b[0] == encodedWriteMarker {
+		points, err = unmarshalEncodedPoints(b[1:])
+		return shardID, seq, points, err
+	}
+	points, err = models.ParsePoints(b)
+	return shardID, seq, points, err
+}
+
+// unmarshalEncodedPoints decodes the points written by marshalEncodedWrite.
+func unmarshalEncodedPoints(b []byte) ([]models.Point, error) {
+	var bufs [][]byte
+	for len(b) > 0 {
+		if len(b) < 4 {
+			return nil, fmt.Errorf("too short: len = %d", len(b))
+		}
+		sz := int(binary.BigEndian.Uint32(b[:4]))
+		b = b[4:]
+		if len(b) < sz {
+			return nil, fmt.Errorf("point too short: len = %d, expected %d", len(b), sz)
+		}
+		bufs = append(bufs, b[:sz])
+		b = b[sz:]
 	}
-	ownerID := binary.BigEndian.Uint64(b[:8])
-	points, err := models.ParsePoints(b[8:])
-	return ownerID, points, err
+	return rpc.NewEncodedPoints(bufs).Points()
 }
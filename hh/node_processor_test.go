@@ -1,14 +1,20 @@
 package hh
 
 import (
+	"bytes"
+	"errors"
 	"io"
 	"io/ioutil"
 	"os"
+	"path/filepath"
+	"reflect"
 	"testing"
 	"time"
 
 	"github.com/influxdata/influxdb/models"
 	"github.com/influxdata/influxdb/services/meta"
+	"github.com/influxdata/influxdb/toml"
+	"github.com/zhexuany/influxcloud/rpc"
 )
 
 type fakeShardWriter struct {
@@ -19,6 +25,17 @@ func (f *fakeShardWriter) WriteShard(shardID, nodeID uint64, points []models.Poi
 	return f.ShardWriteFn(shardID, nodeID, points)
 }
 
+// fakeHandoffShardWriter is a fakeShardWriter that also sends the sequence
+// numbers of queued writes.
+type fakeHandoffShardWriter struct {
+	fakeShardWriter
+	HandoffWriteFn func(shardID, nodeID, queueID, seq uint64, points []models.Point) error
+}
+
+func (f *fakeHandoffShardWriter) WriteHandoffShard(shardID, nodeID, queueID, seq uint64, points []models.Point) error {
+	return f.HandoffWriteFn(shardID, nodeID, queueID, seq, points)
+}
+
 type fakeMetaStore struct {
 	NodeFn func(nodeID uint64) (*meta.NodeInfo, error)
 }
@@ -164,3 +181,597 @@ func TestNodeProcessorSendBlock(t *testing.T) {
 		t.Fatalf("Node processor directory still present after purge")
 	}
 }
+
+func TestUnmarshalWrite_Encoded(t *testing.T) {
+	points := []models.Point{
+		models.MustNewPoint("cpu", models.NewTags(map[string]string{"host": "a"}), models.Fields{"value": 1.0}, time.Unix(1, 0)),
+		models.MustNewPoint("cpu", models.NewTags(map[string]string{"host": "b"}), models.Fields{"value": 2.0}, time.Unix(2, 0)),
+	}
+	e, err := rpc.EncodePoints(points)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Writes queued from encoded points and as line protocol are both read
+	// back, with or without a sequence number.
+	for i, b := range [][]byte{
+		marshalEncodedWrite(5, e),
+		marshalWrite(5, points),
+		sequencedWrite(marshalEncodedWrite(5, e), 7),
+		sequencedWrite(marshalWrite(5, points), 7),
+	} {
+		shardID, seq, got, err := unmarshalSequencedWrite(b)
+		if err != nil {
+			t.Fatal(err)
+		} else if shardID != 5 {
+			t.Fatalf("unexpected shard id: %d", shardID)
+		} else if exp := uint64(i / 2 * 7); seq != exp {
+			t.Fatalf("unexpected sequence number: %d, expected %d", seq, exp)
+		} else if len(got) != len(points) {
+			t.Fatalf("unexpected point count: %d", len(got))
+		}
+		for i := range points {
+			if got[i].String() != points[i].String() {
+				t.Fatalf("unexpected point %d: got %s, expected %s", i, got[i], points[i])
+			}
+		}
+	}
+}
+
+// Ensure writes to a full queue are handled according to the overflow policy.
+func TestNodeProcessor_OverflowPolicy(t *testing.T) {
+	var sent []uint64
+	sh := &fakeShardWriter{
+		ShardWriteFn: func(shardID, nodeID uint64, points []models.Point) error {
+			sent = append(sent, shardID)
+			return nil
+		},
+	}
+	metastore := &fakeMetaStore{
+		NodeFn: func(nodeID uint64) (*meta.NodeInfo, error) { return &meta.NodeInfo{}, nil },
+	}
+	pt := models.MustNewPoint("cpu", nil, models.Fields{"value": 1.0}, time.Unix(0, 0))
+	var full []uint64
+
+	open := func(policy string) (*NodeProcessor, func()) {
+		dir, err := ioutil.TempDir("", "node_processor_test")
+		if err != nil {
+			t.Fatalf("failed to create temp dir: %v", err)
+		}
+		n := NewNodeProcessor(1, dir, sh, metastore)
+		n.MaxSize = 1024
+		n.OverflowPolicy = policy
+		n.OnQueueFull = func(nodeID uint64) { full = append(full, nodeID) }
+		// Only send writes when the test does.
+		n.PurgeInterval, n.RetryInterval, n.RetryMaxInterval = time.Hour, time.Hour, time.Hour
+		if err := n.Open(); err != nil {
+			t.Fatalf("Failed to open node processor: %v", err)
+		}
+		return n, func() {
+			n.Close()
+			os.RemoveAll(dir)
+		}
+	}
+
+	// fill writes to n until the queue is full, and returns the number of
+	// writes queued.
+	fill := func(n *NodeProcessor) int {
+		for i := 0; ; i++ {
+			if err := n.WriteShard(uint64(i), []models.Point{pt}); err == ErrQueueFull {
+				return i
+			} else if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		}
+	}
+
+	// New writes are rejected.
+	n, closeFn := open(OverflowDropNew)
+	fill(n)
+	if n.stats.QueueFull != 1 {
+		t.Fatalf("unexpected queue full count: %d", n.stats.QueueFull)
+	} else if len(full) != 1 || full[0] != 1 {
+		t.Fatalf("unexpected queue full notifications: %v", full)
+	}
+	closeFn()
+
+	// The oldest writes make room for new ones.
+	n, closeFn = open(OverflowDropOldest)
+	for i := 0; i < 100; i++ {
+		if err := n.WriteShard(uint64(i), []models.Point{pt}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if n.stats.QueueDroppedBytes == 0 {
+		t.Fatal("expected dropped bytes")
+	}
+	sent = nil
+	if err := n.Flush(); err != nil {
+		t.Fatal(err)
+	} else if len(sent) == 0 || sent[0] == 0 || sent[len(sent)-1] != 99 {
+		t.Fatalf("unexpected writes sent: %v", sent)
+	}
+	closeFn()
+
+	// New writes wait for queued writes to be sent.
+	n, closeFn = open(OverflowBlock)
+	defer closeFn()
+	n.OverflowPolicy = OverflowDropNew
+	queued := fill(n)
+	n.OverflowPolicy = OverflowBlock
+
+	errC := make(chan error, 1)
+	go func() { errC <- n.WriteShard(uint64(queued), []models.Point{pt}) }()
+	select {
+	case err := <-errC:
+		t.Fatalf("write did not block: %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	sent = nil
+	for len(sent) < queued {
+		if _, err := n.SendWrite(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	select {
+	case err := <-errC:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("write still blocked")
+	}
+	if n.stats.QueueBlocked != 1 {
+		t.Fatalf("unexpected queue blocked count: %d", n.stats.QueueBlocked)
+	}
+}
+
+// Ensure queued writes are sent in batches across segments, with one write
+// per shard.
+func TestNodeProcessor_SendBatch(t *testing.T) {
+	dir, err := ioutil.TempDir("", "node_processor_test")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	var fail bool
+	sent := make(map[uint64]int)
+	sh := &fakeShardWriter{
+		ShardWriteFn: func(shardID, nodeID uint64, points []models.Point) error {
+			if fail && shardID == 2 {
+				return errors.New("write failed")
+			}
+			sent[shardID] += len(points)
+			return nil
+		},
+	}
+	metastore := &fakeMetaStore{
+		NodeFn: func(nodeID uint64) (*meta.NodeInfo, error) { return &meta.NodeInfo{}, nil },
+	}
+
+	n := NewNodeProcessor(1, dir, sh, metastore)
+	n.MaxSize = 4096
+	n.PurgeInterval, n.RetryInterval, n.RetryMaxInterval = time.Hour, time.Hour, time.Hour
+	if err := n.Open(); err != nil {
+		t.Fatalf("Failed to open node processor: %v", err)
+	}
+	defer n.Close()
+
+	// Queue enough writes for them to span several segments.
+	pt := models.MustNewPoint("cpu", nil, models.Fields{"value": 1.0}, time.Unix(0, 0))
+	for i := 0; i < 100; i++ {
+		if err := n.WriteShard(uint64(i%2+1), []models.Point{pt}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if segments := n.queue.totalSegments(); segments < 2 {
+		t.Fatalf("unexpected segment count: %d", segments)
+	}
+
+	// A failed batch is not advanced past.
+	fail = true
+	if _, err := n.SendBatch(); err == nil {
+		t.Fatal("expected error")
+	}
+	fail = false
+	sent = make(map[uint64]int)
+	n.stats.WriteNodeReq = 0
+
+	if _, err := n.SendBatch(); err != nil {
+		t.Fatal(err)
+	} else if exp := map[uint64]int{1: 50, 2: 50}; !reflect.DeepEqual(sent, exp) {
+		t.Fatalf("unexpected points sent: got %v, exp %v", sent, exp)
+	} else if n.stats.WriteNodeReq != 2 {
+		t.Fatalf("unexpected write count: %d", n.stats.WriteNodeReq)
+	}
+
+	if _, err := n.SendBatch(); err != io.EOF {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+// Ensure queued writes are replayed with sequence numbers that increase
+// across reopens of the processor.
+func TestNodeProcessor_SendBatch_Sequence(t *testing.T) {
+	dir, err := ioutil.TempDir("", "node_processor_test")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	type write struct{ shardID, queueID, seq uint64 }
+	var writes []write
+	sh := &fakeHandoffShardWriter{
+		HandoffWriteFn: func(shardID, nodeID, queueID, seq uint64, points []models.Point) error {
+			writes = append(writes, write{shardID, queueID, seq})
+			return nil
+		},
+	}
+	metastore := &fakeMetaStore{
+		NodeFn: func(nodeID uint64) (*meta.NodeInfo, error) { return &meta.NodeInfo{}, nil },
+	}
+
+	n := NewNodeProcessor(1, dir, sh, metastore)
+	n.MaxSize = 4096
+	n.PurgeInterval, n.RetryInterval, n.RetryMaxInterval = time.Hour, time.Hour, time.Hour
+
+	pt := models.MustNewPoint("cpu", nil, models.Fields{"value": 1.0}, time.Unix(0, 0))
+	var last uint64
+	for i := 0; i < 2; i++ {
+		if err := n.Open(); err != nil {
+			t.Fatalf("Failed to open node processor: %v", err)
+		}
+		for _, shardID := range []uint64{1, 2, 1} {
+			if err := n.WriteShard(shardID, []models.Point{pt}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		writes = nil
+		if err := n.Flush(); err != nil {
+			t.Fatal(err)
+		}
+		n.Close()
+
+		// Shard 1 is sent with the number of its last write.
+		if len(writes) != 2 || writes[0].shardID != 1 || writes[1].shardID != 2 {
+			t.Fatalf("unexpected writes: %v", writes)
+		} else if writes[0].queueID != 1 || writes[0].seq <= writes[1].seq || writes[1].seq <= last {
+			t.Fatalf("unexpected sequence numbers: %v, last %d", writes, last)
+		}
+		last = writes[0].seq
+	}
+}
+
+func TestNodeProcessor_SetTarget(t *testing.T) {
+	dir, err := ioutil.TempDir("", "node_processor_test")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	sent := make(map[uint64]int)
+	sh := &fakeShardWriter{
+		ShardWriteFn: func(shardID, nodeID uint64, points []models.Point) error {
+			sent[nodeID] += len(points)
+			return nil
+		},
+	}
+	// Node 1 is gone, and replaced by node 2.
+	metastore := &fakeMetaStore{
+		NodeFn: func(nodeID uint64) (*meta.NodeInfo, error) {
+			if nodeID == 1 {
+				return nil, nil
+			}
+			return &meta.NodeInfo{ID: nodeID}, nil
+		},
+	}
+
+	n := NewNodeProcessor(1, dir, sh, metastore)
+	n.MaxSize = 4096
+	n.PurgeInterval, n.RetryInterval, n.RetryMaxInterval = time.Hour, time.Hour, time.Hour
+	if err := n.Open(); err != nil {
+		t.Fatalf("Failed to open node processor: %v", err)
+	}
+	defer n.Close()
+
+	pt := models.MustNewPoint("cpu", nil, models.Fields{"value": 1.0}, time.Unix(0, 0))
+	for i := 0; i < 3; i++ {
+		if err := n.WriteShard(1, []models.Point{pt}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Nothing is sent while the queue targets the inactive node.
+	if err := n.Flush(); err != nil {
+		t.Fatal(err)
+	} else if len(sent) != 0 {
+		t.Fatalf("unexpected points sent: %v", sent)
+	}
+
+	n.SetTarget(2)
+	if err := n.Flush(); err != nil {
+		t.Fatal(err)
+	} else if exp := map[uint64]int{2: 3}; !reflect.DeepEqual(sent, exp) {
+		t.Fatalf("unexpected points sent: got %v, exp %v", sent, exp)
+	} else if _, err := n.SendBatch(); err != io.EOF {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+// Ensure the limits of an open processor can be changed while it runs.
+func TestNodeProcessor_Reconfigure(t *testing.T) {
+	sentC := make(chan uint64, 100)
+	sh := &fakeShardWriter{
+		ShardWriteFn: func(shardID, nodeID uint64, points []models.Point) error {
+			sentC <- shardID
+			return nil
+		},
+	}
+	metastore := &fakeMetaStore{
+		NodeFn: func(nodeID uint64) (*meta.NodeInfo, error) { return &meta.NodeInfo{}, nil },
+	}
+	pt := models.MustNewPoint("cpu", nil, models.Fields{"value": 1.0}, time.Unix(0, 0))
+
+	dir, err := ioutil.TempDir("", "node_processor_test")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	c := NewConfig()
+	c.MaxSize = 1024
+	c.OverflowPolicy = OverflowDropNew
+	c.RetryInterval = toml.Duration(time.Hour)
+	c.RetryMaxInterval = toml.Duration(time.Hour)
+
+	n := NewNodeProcessor(1, dir, sh, metastore)
+	n.PurgeInterval = time.Hour
+	n.Reconfigure(c)
+	if err := n.Open(); err != nil {
+		t.Fatalf("Failed to open node processor: %v", err)
+	}
+	defer n.Close()
+
+	var queued int
+	for ; ; queued++ {
+		if err := n.WriteShard(uint64(queued), []models.Point{pt}); err == ErrQueueFull {
+			break
+		} else if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	// A larger queue accepts more writes, and a shorter interval sends
+	// them without waiting for the previous one to pass.
+	c.MaxSize = 4096
+	c.RetryInterval = toml.Duration(10 * time.Millisecond)
+	n.Reconfigure(c)
+	if err := n.WriteShard(uint64(queued), []models.Point{pt}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for i := 0; i <= queued; i++ {
+		select {
+		case id := <-sentC:
+			if id != uint64(i) {
+				t.Fatalf("unexpected shard sent: %d", id)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("writes not sent after %d", i)
+		}
+	}
+}
+
+// Ensure a write the node keeps failing to store is quarantined once its
+// batch failed QuarantineAfter times, without holding back the other writes,
+// and that it can be requeued and dropped.
+func TestService_Quarantine(t *testing.T) {
+	dir, err := ioutil.TempDir("", "node_processor_test")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	fail := true
+	storeErr := &rpc.WriteShardError{Code: rpc.CodeFieldTypeConflict, Message: "field type conflict"}
+	sent := make(map[uint64]int)
+	sh := &fakeShardWriter{
+		ShardWriteFn: func(shardID, nodeID uint64, points []models.Point) error {
+			if fail && shardID == 2 {
+				return storeErr
+			}
+			sent[shardID] += len(points)
+			return nil
+		},
+	}
+	metastore := &fakeMetaStore{
+		NodeFn: func(nodeID uint64) (*meta.NodeInfo, error) { return &meta.NodeInfo{}, nil },
+	}
+
+	c := NewConfig()
+	c.Enabled = true
+	c.Dir = dir
+	c.QuarantineAfter = 2
+	c.RetryInterval, c.RetryMaxInterval = toml.Duration(time.Hour), toml.Duration(time.Hour)
+	s := NewService(c, sh, metastore)
+	if err := s.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	pt := models.MustNewPoint("cpu", nil, models.Fields{"value": 1.0}, time.Unix(0, 0))
+	for _, shardID := range []uint64{1, 2, 1} {
+		if err := s.WriteShard(shardID, 1, []models.Point{pt}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	n, err := s.nodeProcessor(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Failed batches are retried as usual.
+	for i := 0; i < 2; i++ {
+		if _, err := n.SendBatch(); err == nil {
+			t.Fatal("expected error")
+		}
+	}
+	if writes, err := s.QuarantinedWrites(); err != nil {
+		t.Fatal(err)
+	} else if len(writes) != 0 {
+		t.Fatalf("unexpected quarantined writes: %v", writes)
+	}
+
+	// The next batch sends the writes one at a time and quarantines the one
+	// failing again.
+	sent = make(map[uint64]int)
+	if _, err := n.SendBatch(); err != nil {
+		t.Fatal(err)
+	} else if exp := map[uint64]int{1: 2}; !reflect.DeepEqual(sent, exp) {
+		t.Fatalf("unexpected points sent: got %v, exp %v", sent, exp)
+	} else if _, err := n.SendBatch(); err != io.EOF {
+		t.Fatalf("unexpected error: %v", err)
+	} else if n.stats.QueueQuarantined != 1 {
+		t.Fatalf("unexpected quarantined count: %d", n.stats.QueueQuarantined)
+	}
+
+	writes, err := s.QuarantinedWrites()
+	if err != nil {
+		t.Fatal(err)
+	} else if len(writes) != 1 {
+		t.Fatalf("unexpected quarantined writes: %v", writes)
+	}
+	w := writes[0]
+	if w.NodeID != 1 || w.ShardID != 2 || w.PointN != 1 || w.Attempts != 3 || w.Sequence == 0 {
+		t.Fatalf("unexpected quarantined write: %+v", w)
+	} else if w.Err != storeErr.Error() {
+		t.Fatalf("unexpected error: %s", w.Err)
+	}
+
+	// A requeued write is sent again, without its old sequence number.
+	fail = false
+	sent = make(map[uint64]int)
+	if err := s.RequeueQuarantined(1, w.ID); err != nil {
+		t.Fatal(err)
+	} else if _, err := n.SendBatch(); err != nil {
+		t.Fatal(err)
+	} else if exp := map[uint64]int{2: 1}; !reflect.DeepEqual(sent, exp) {
+		t.Fatalf("unexpected points sent: got %v, exp %v", sent, exp)
+	} else if writes, err := s.QuarantinedWrites(); err != nil {
+		t.Fatal(err)
+	} else if len(writes) != 0 {
+		t.Fatalf("unexpected quarantined writes: %v", writes)
+	}
+	if err := s.RequeueQuarantined(1, w.ID); err != ErrQuarantinedWriteNotFound {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// A dropped write is deleted.
+	fail = true
+	if err := s.WriteShard(2, 1, []models.Point{pt}); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		n.SendBatch()
+	}
+	writes, err = s.QuarantinedWrites()
+	if err != nil {
+		t.Fatal(err)
+	} else if len(writes) != 1 {
+		t.Fatalf("unexpected quarantined writes: %v", writes)
+	}
+	if err := s.DropQuarantined(1, writes[0].ID); err != nil {
+		t.Fatal(err)
+	} else if writes, err := s.QuarantinedWrites(); err != nil {
+		t.Fatal(err)
+	} else if len(writes) != 0 {
+		t.Fatalf("unexpected quarantined writes: %v", writes)
+	}
+	if err := s.DropQuarantined(1, "../1"); err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+// Ensure writes quarantined from an encrypted queue are sealed with its key,
+// and opened with it when requeued.
+func TestService_Quarantine_Encrypted(t *testing.T) {
+	dir, err := ioutil.TempDir("", "node_processor_test")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	os.Setenv("HH_TEST_QUARANTINE_KEY", "000102030405060708090a0b0c0d0e0f")
+	defer os.Unsetenv("HH_TEST_QUARANTINE_KEY")
+
+	fail := true
+	sent := make(map[uint64]int)
+	sh := &fakeShardWriter{
+		ShardWriteFn: func(shardID, nodeID uint64, points []models.Point) error {
+			if fail {
+				return &rpc.WriteShardError{Code: rpc.CodeFieldTypeConflict, Message: "field type conflict"}
+			}
+			sent[shardID] += len(points)
+			return nil
+		},
+	}
+	metastore := &fakeMetaStore{
+		NodeFn: func(nodeID uint64) (*meta.NodeInfo, error) { return &meta.NodeInfo{}, nil },
+	}
+
+	c := NewConfig()
+	c.Enabled = true
+	c.Dir = dir
+	c.QuarantineAfter = 1
+	c.EncryptionKeyEnv = "HH_TEST_QUARANTINE_KEY"
+	c.RetryInterval, c.RetryMaxInterval = toml.Duration(time.Hour), toml.Duration(time.Hour)
+	s := NewService(c, sh, metastore)
+	if err := s.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	pt := models.MustNewPoint("secret_measurement", nil, models.Fields{"value": 1.0}, time.Unix(0, 0))
+	if err := s.WriteShard(2, 1, []models.Point{pt}); err != nil {
+		t.Fatal(err)
+	}
+	n, err := s.nodeProcessor(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		n.SendBatch()
+	}
+
+	writes, err := s.QuarantinedWrites()
+	if err != nil {
+		t.Fatal(err)
+	} else if len(writes) != 1 {
+		t.Fatalf("unexpected quarantined writes: %v", writes)
+	}
+	id, qdir := writes[0].ID, s.quarantinePath(1)
+
+	// Only the sealed write is on disk, and the points cannot be read from it.
+	if _, err := os.Stat(filepath.Join(qdir, id+quarantineWriteExt)); !os.IsNotExist(err) {
+		t.Fatalf("unexpected plaintext write: %v", err)
+	} else if b, err := ioutil.ReadFile(filepath.Join(qdir, id+quarantineSealedExt)); err != nil {
+		t.Fatal(err)
+	} else if bytes.Contains(b, []byte("secret_measurement")) {
+		t.Fatal("quarantined write stored in plaintext")
+	}
+	if _, _, err := readQuarantined(qdir, id, nil); err != ErrNoEncryptionKey {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	fail = false
+	if err := s.RequeueQuarantined(1, id); err != nil {
+		t.Fatal(err)
+	} else if _, err := n.SendBatch(); err != nil {
+		t.Fatal(err)
+	} else if exp := map[uint64]int{2: 1}; !reflect.DeepEqual(sent, exp) {
+		t.Fatalf("unexpected points sent: got %v, exp %v", sent, exp)
+	} else if _, err := os.Stat(filepath.Join(qdir, id+quarantineSealedExt)); !os.IsNotExist(err) {
+		t.Fatalf("requeued write not removed: %v", err)
+	}
+}
@@ -0,0 +1,360 @@
+package hh
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/uber-go/zap"
+	"github.com/zhexuany/influxcloud/rpc"
+)
+
+// quarantineDir is the directory under the hinted handoff directory that
+// writes are quarantined in, with a directory for the writes of each node.
+const quarantineDir = "quarantine"
+
+// A quarantined write is kept as two files named by its ID: the write as it
+// was queued, without its sequence number, and its description as JSON. The
+// write is sealed with the encryption key of the queue if it has one, and
+// kept with quarantineSealedExt instead, so that it is no more readable on
+// disk in quarantine than it was in the queue.
+const (
+	quarantineWriteExt  = ".write"
+	quarantineSealedExt = ".sealed"
+	quarantineInfoExt   = ".json"
+)
+
+// ErrQuarantinedWriteNotFound is returned for a quarantined write that does
+// not exist.
+var ErrQuarantinedWriteNotFound = errors.New("quarantined write not found")
+
+// isStoreError returns true if err shows that the node a write was sent to
+// received it but failed to store it, e.g. because the write panicked its
+// handler or conflicts with the shard, rather than being unavailable or not
+// accepting writes for now.
+func isStoreError(err error) bool {
+	e, ok := err.(*rpc.WriteShardError)
+	if !ok {
+		return false
+	}
+	switch e.Code {
+	case rpc.CodeStoreClosed, rpc.CodeOverloaded, rpc.CodeAuthFailed, rpc.CodeDraining,
+		rpc.CodeDeadlineExceeded, rpc.CodeChecksumMismatch, rpc.CodeReadOnly:
+		return false
+	}
+	return true
+}
+
+// failed records that the batch at the head of the queue failed again,
+// because the node failed to store it or it could not be decoded.
+func (n *NodeProcessor) failed() {
+	atomic.AddInt32(&n.failures, 1)
+}
+
+// isolating returns the directory writes are quarantined in if the batch at
+// the head of the queue failed QuarantineAfter times in a row, so that its
+// writes are sent one at a time.
+func (n *NodeProcessor) isolating() (string, bool) {
+	n.settingsMu.RLock()
+	dir, after := n.QuarantineDir, n.QuarantineAfter
+	n.settingsMu.RUnlock()
+	if dir == "" || after <= 0 || int(atomic.LoadInt32(&n.failures)) < after {
+		return "", false
+	}
+	return dir, true
+}
+
+// sendIsolated sends each of blocks on its own, quarantining in dir those the
+// node fails to store again or that cannot be decoded, and advances past each
+// block sent or quarantined. It stops at the first write failing otherwise,
+// which is sent on its own again by the next batch.
+func (n *NodeProcessor) sendIsolated(blocks [][]byte, dir string) (int, error) {
+	attempts := int(atomic.LoadInt32(&n.failures)) + 1
+	var size int
+	for _, buf := range blocks {
+		shardID, seq, points, err := unmarshalSequencedWrite(buf)
+		if err == nil {
+			if err = n.writeShard(shardID, seq, points); err == nil {
+				atomic.AddInt64(&n.stats.WriteShardReq, 1)
+				atomic.AddInt64(&n.stats.WriteNodeReq, 1)
+				atomic.AddInt64(&n.stats.WriteNodeReqPoints, int64(len(points)))
+			} else if !isStoreError(err) {
+				atomic.AddInt64(&n.stats.WriteNodeReqFail, 1)
+				return size, err
+			}
+		}
+		if err != nil {
+			if qerr := n.quarantine(dir, buf, attempts, err); qerr != nil {
+				return size, qerr
+			}
+		}
+
+		if err := n.queue.Advance(); err != nil {
+			return size, err
+		}
+		size += len(buf)
+	}
+	atomic.StoreInt32(&n.failures, 0)
+	n.notifySpace()
+	return size, nil
+}
+
+// quarantine moves b, the queued write that failed with cause, into dir.
+func (n *NodeProcessor) quarantine(dir string, b []byte, attempts int, cause error) error {
+	w := rpc.QuarantinedWrite{
+		NodeID:   n.nodeID,
+		Size:     len(b),
+		Attempts: attempts,
+		Err:      cause.Error(),
+		Time:     time.Now().UTC(),
+	}
+	shardID, seq, points, _ := unmarshalSequencedWrite(b)
+	w.ShardID, w.Sequence, w.PointN = shardID, seq, len(points)
+
+	c, err := optionalBlockCipher(n.EncryptionKey)
+	if err != nil {
+		return fmt.Errorf("quarantine write: %s", err)
+	}
+	if err := writeQuarantined(dir, &w, unsequencedWrite(b), c); err != nil {
+		return fmt.Errorf("quarantine write: %s", err)
+	}
+	atomic.AddInt64(&n.stats.QueueQuarantined, 1)
+	n.Logger.Warn("quarantined hinted handoff write", zap.Uint64("nodeID", n.nodeID), zap.Uint64("shardID", w.ShardID), zap.String("id", w.ID), zap.Int("attempts", attempts), zap.Error(cause))
+	return nil
+}
+
+// unsequencedWrite returns b, a marshaled write, without its sequence number.
+func unsequencedWrite(b []byte) []byte {
+	if len(b) < 17 || b[8] != sequencedWriteMarker {
+		return b
+	}
+	out := make([]byte, 8, len(b)-9)
+	copy(out, b[:8])
+	return append(out, b[17:]...)
+}
+
+// writeQuarantined stores the write b described by w in dir, setting its ID.
+// The write is sealed with c if set. The description is written last, so
+// that a write is only listed once both files are complete.
+func writeQuarantined(dir string, w *rpc.QuarantinedWrite, b []byte, c *blockCipher) error {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+
+	ext, other := quarantineWriteExt, quarantineSealedExt
+	if c != nil {
+		sealed, err := c.seal(b, blockEncrypted)
+		if err != nil {
+			return err
+		}
+		b, ext, other = sealed, quarantineSealedExt, quarantineWriteExt
+	}
+
+	// Writes are named by the time they were quarantined, so that they are
+	// listed in that order.
+	var f *os.File
+	for id := w.Time.UnixNano(); ; id++ {
+		w.ID = fmt.Sprintf("%019d", id)
+		if _, err := os.Stat(filepath.Join(dir, w.ID+other)); err == nil {
+			continue
+		}
+		var err error
+		f, err = os.OpenFile(filepath.Join(dir, w.ID+ext), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+		if os.IsExist(err) {
+			continue
+		} else if err != nil {
+			return err
+		}
+		break
+	}
+	if _, err := f.Write(b); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	info, err := json.Marshal(w)
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(dir, w.ID+quarantineInfoExt)
+	if err := ioutil.WriteFile(path+"tmp", info, 0600); err != nil {
+		return err
+	}
+	return os.Rename(path+"tmp", path)
+}
+
+// listQuarantined returns the writes quarantined in dir, oldest first.
+func listQuarantined(dir string) ([]rpc.QuarantinedWrite, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*"+quarantineInfoExt))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+
+	var a []rpc.QuarantinedWrite
+	for _, file := range files {
+		w, err := readQuarantinedInfo(file)
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		a = append(a, w)
+	}
+	return a, nil
+}
+
+// readQuarantined returns the description and the marshaled write of the
+// write id quarantined in dir, opened with c if it was sealed. A sealed write
+// read with a nil c returns ErrNoEncryptionKey.
+func readQuarantined(dir, id string, c *blockCipher) (rpc.QuarantinedWrite, []byte, error) {
+	if err := validateQuarantineID(id); err != nil {
+		return rpc.QuarantinedWrite{}, nil, err
+	}
+	w, err := readQuarantinedInfo(filepath.Join(dir, id+quarantineInfoExt))
+	if os.IsNotExist(err) {
+		return w, nil, ErrQuarantinedWriteNotFound
+	} else if err != nil {
+		return w, nil, err
+	}
+	b, err := ioutil.ReadFile(filepath.Join(dir, id+quarantineWriteExt))
+	if os.IsNotExist(err) {
+		if b, err = ioutil.ReadFile(filepath.Join(dir, id+quarantineSealedExt)); err == nil {
+			b, err = c.open(b, blockEncrypted)
+		}
+	}
+	if err != nil {
+		return w, nil, err
+	}
+	return w, b, nil
+}
+
+// readQuarantinedInfo reads the description of a quarantined write.
+func readQuarantinedInfo(path string) (rpc.QuarantinedWrite, error) {
+	var w rpc.QuarantinedWrite
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return w, err
+	}
+	if err := json.Unmarshal(b, &w); err != nil {
+		return w, fmt.Errorf("%s: %s", path, err)
+	}
+	return w, nil
+}
+
+// removeQuarantined deletes the write id quarantined in dir. The description
+// is removed first, so that a write is no longer listed even if removing it
+// fails halfway.
+func removeQuarantined(dir, id string) error {
+	if err := validateQuarantineID(id); err != nil {
+		return err
+	}
+	if err := os.Remove(filepath.Join(dir, id+quarantineInfoExt)); os.IsNotExist(err) {
+		return ErrQuarantinedWriteNotFound
+	} else if err != nil {
+		return err
+	}
+	if err := os.Remove(filepath.Join(dir, id+quarantineWriteExt)); !os.IsNotExist(err) {
+		return err
+	}
+	return os.Remove(filepath.Join(dir, id+quarantineSealedExt))
+}
+
+// validateQuarantineID returns an error if id cannot be the ID of a
+// quarantined write, so that IDs sent by other nodes only name files in the
+// quarantine directory.
+func validateQuarantineID(id string) error {
+	if _, err := strconv.ParseUint(id, 10, 64); err != nil {
+		return fmt.Errorf("invalid quarantined write ID: %q", id)
+	}
+	return nil
+}
+
+// quarantinePath returns the directory the writes quarantined from the queue
+// of nodeID are kept in.
+func (s *Service) quarantinePath(nodeID uint64) string {
+	return filepath.Join(s.cfg.Dir, quarantineDir, fmt.Sprintf("%d", nodeID))
+}
+
+// QuarantinedWrites returns the writes quarantined from the queue of every
+// node, by node and oldest first.
+func (s *Service) QuarantinedWrites() ([]rpc.QuarantinedWrite, error) {
+	files, err := ioutil.ReadDir(filepath.Join(s.cfg.Dir, quarantineDir))
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var nodeIDs []uint64
+	for _, file := range files {
+		if id, err := strconv.ParseUint(file.Name(), 10, 64); err == nil && file.IsDir() {
+			nodeIDs = append(nodeIDs, id)
+		}
+	}
+	sort.Sort(uint64Slice(nodeIDs))
+
+	var a []rpc.QuarantinedWrite
+	for _, id := range nodeIDs {
+		writes, err := listQuarantined(s.quarantinePath(id))
+		if err != nil {
+			return nil, err
+		}
+		a = append(a, writes...)
+	}
+	return a, nil
+}
+
+// RequeueQuarantined queues the write id quarantined from the queue of
+// nodeID again, then removes it from quarantine. It is queued as a new write,
+// with a new sequence number, so that the node does not skip it as a replay.
+func (s *Service) RequeueQuarantined(nodeID uint64, id string) error {
+	c, err := optionalBlockCipher(s.encryptionKey)
+	if err != nil {
+		return err
+	}
+	dir := s.quarantinePath(nodeID)
+	w, b, err := readQuarantined(dir, id, c)
+	if err != nil {
+		return err
+	}
+
+	np, err := s.nodeProcessor(nodeID)
+	if err != nil {
+		return err
+	}
+	if err := np.append(w.PointN, b); err != nil {
+		return err
+	}
+	s.Logger.Info("requeued quarantined hinted handoff write", zap.Uint64("nodeID", nodeID), zap.String("id", id))
+	return removeQuarantined(dir, id)
+}
+
+// DropQuarantined deletes the write id quarantined from the queue of nodeID.
+func (s *Service) DropQuarantined(nodeID uint64, id string) error {
+	if err := removeQuarantined(s.quarantinePath(nodeID), id); err != nil {
+		return err
+	}
+	s.Logger.Info("dropped quarantined hinted handoff write", zap.Uint64("nodeID", nodeID), zap.String("id", id))
+	return nil
+}
+
+type uint64Slice []uint64
+
+func (a uint64Slice) Len() int           { return len(a) }
+func (a uint64Slice) Less(i, j int) bool { return a[i] < a[j] }
+func (a uint64Slice) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
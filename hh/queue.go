@@ -76,6 +76,9 @@ type queue struct {
 	// The segments that exist on disk
 	segments segments
 
+	// The cipher blocks are encrypted with, if set
+	cipher *blockCipher
+
 	// Logger print userful logs
 	Logger zap.Logger
 }
@@ -105,6 +108,20 @@ func newQueue(dir string, maxSize int64) (*queue, error) {
 	return q, nil
 }
 
+// SetEncryptionKey encrypts the blocks appended to the queue with key, an AES
+// key of 16, 24 or 32 bytes, and decrypts the blocks read from it. Blocks
+// written before are still read. It must be called before Open.
+func (l *queue) SetEncryptionKey(key []byte) error {
+	c, err := newBlockCipher(key)
+	if err != nil {
+		return err
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.cipher = c
+	return nil
+}
+
 // WithLogger sets the internal logger to the logger passed in
 func (l *queue) WithLogger(log zap.Logger) {
 	l.Logger = log.With(zap.String("service", "cluster"))
@@ -130,6 +147,14 @@ func (l *queue) Open() error {
 	l.head = l.segments[0]
 	l.tail = l.segments[len(l.segments)-1]
 
+	// Segments written before segmentV2 are only read until they are sent,
+	// so writes go to a new segment.
+	if l.tail.version == segmentV1 {
+		if err := l.addSegment(); err != nil {
+			return err
+		}
+	}
+
 	// If the head has been fully advanced and the segment size is modified,
 	// existing segments an get stuck and never allow clients to advance further.
 	// This advances the segment if the current head is already at the end.
@@ -234,6 +259,18 @@ func (l *queue) LastModified() (time.Time, error) {
 	return time.Time{}.UTC(), nil
 }
 
+// HeadModified returns the last time the oldest segment in the queue was
+// modified, or the zero time if the queue is empty.
+func (l *queue) HeadModified() (time.Time, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	if l.head != nil && !l.head.empty() {
+		return l.head.lastModified()
+	}
+	return time.Time{}.UTC(), nil
+}
+
 func (l *queue) Position() (*queuePos, error) {
 	l.mu.RLock()
 	defer l.mu.RUnlock()
@@ -281,7 +318,7 @@ func (l *queue) addSegment() error {
 		return err
 	}
 
-	segment, err := newSegment(filepath.Join(l.dir, strconv.FormatUint(nextID, 10)), l.maxSegmentSize)
+	segment, err := newSegment(filepath.Join(l.dir, strconv.FormatUint(nextID, 10)), l.maxSegmentSize, l.cipher)
 	if err != nil {
 		return err
 	}
@@ -314,7 +351,7 @@ func (l *queue) loadSegments() (segments, error) {
 
 		path := filepath.Join(l.dir, segment.Name())
 		l.Logger.Info("creating segement: " + path)
-		segment, err := newSegment(path, l.maxSegmentSize)
+		segment, err := newSegment(path, l.maxSegmentSize, l.cipher)
 		if err != nil {
 			return segments, err
 		}
@@ -380,6 +417,14 @@ func (l *queue) totalSegments() int64 {
 	return int64(num)
 }
 
+// SetMaxSize changes the maximum size of the queue. Data already queued
+// beyond a smaller size is kept until it is sent or purged.
+func (l *queue) SetMaxSize(size int64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.maxSize = size
+}
+
 // Append appends a byte slice to the end of the queue
 func (l *queue) Append(b []byte) error {
 	l.mu.Lock()
@@ -405,6 +450,39 @@ func (l *queue) Append(b []byte) error {
 	return nil
 }
 
+// DropOldest removes the oldest segments until n more bytes fit in the
+// queue, or until only an empty segment is left. It returns the number of
+// bytes removed.
+func (l *queue) DropOldest(n int64) (int64, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.tail == nil {
+		return 0, ErrNotOpen
+	}
+
+	var dropped int64
+	for l.diskUsage()+n > l.maxSize {
+		// If this is the last segment, first append a new one allowing
+		// trimming to proceed.
+		if len(l.segments) == 1 {
+			if l.head.totalBytes() <= 0 {
+				break
+			}
+			if err := l.addSegment(); err != nil {
+				return dropped, err
+			}
+		}
+
+		size := l.head.diskUsage()
+		if err := l.trimHead(); err != nil {
+			return dropped, err
+		}
+		dropped += size
+	}
+	return dropped, nil
+}
+
 // Current returns the current byte slice at the head of the queue
 func (l *queue) Current() ([]byte, error) {
 	l.mu.RLock()
@@ -416,6 +494,48 @@ func (l *queue) Current() ([]byte, error) {
 	return l.head.current()
 }
 
+// PeekBlocks returns the blocks from the head of the queue on, continuing
+// into the following segments, until their total size reaches maxBytes. At
+// least one block is returned, or io.EOF if there is none. The head is not
+// advanced.
+func (l *queue) PeekBlocks(maxBytes int64) ([][]byte, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.head == nil {
+		return nil, ErrNotOpen
+	}
+
+	// Remove segments that have been read to the end but are still the
+	// head, so that advancing past the returned blocks stays in step.
+	for len(l.segments) > 1 && l.head.exhausted() {
+		if err := l.trimHead(); err != nil {
+			return nil, err
+		}
+	}
+
+	var blocks [][]byte
+	var size int64
+	for _, seg := range l.segments {
+		b, err := seg.blocks(maxBytes - size)
+		if err != nil {
+			return nil, err
+		}
+		for _, block := range b {
+			size += int64(len(block))
+		}
+		blocks = append(blocks, b...)
+		if size >= maxBytes {
+			break
+		}
+	}
+
+	if len(blocks) == 0 {
+		return nil, io.EOF
+	}
+	return blocks, nil
+}
+
 // PeekN returns the next n bytes without advancing the head segement
 func (l *queue) PeekN(n int64) ([]byte, error) {
 	l.mu.RLock()
@@ -488,6 +608,9 @@ func (l *queue) trimHead() error {
 //
 // Segments store arbitrary byte slices and leave the serialization to the caller.  Segments
 // are created with a max size and will block writes when the segment is full.
+//
+// This is the layout of segmentV1 files. New segments are written as segmentV2, which
+// adds checksums, compression and an index block; see segment_v2.go.
 type segment struct {
 	mu sync.RWMutex
 
@@ -500,11 +623,17 @@ type segment struct {
 	maxSize     int64
 
 	segmentID uint64
+
+	// The format of the file, segmentV1 or segmentV2.
+	version int
+
+	// The cipher segmentV2 blocks are encrypted with, if set.
+	cipher *blockCipher
 }
 
 var mutex sync.RWMutex
 
-func newSegment(path string, maxSize int64) (*segment, error) {
+func newSegment(path string, maxSize int64, c *blockCipher) (*segment, error) {
 	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0600)
 	if err != nil {
 		return nil, err
@@ -520,6 +649,27 @@ func newSegment(path string, maxSize int64) (*segment, error) {
 		return nil, err
 	}
 
+	// New segments are written as segmentV2, existing ones are read in the
+	// format they were written in.
+	v2, err := isSegmentV2(f)
+	if err != nil {
+		return nil, err
+	}
+	if stats.Size() == 0 || v2 {
+		s := &segment{file: f, path: path, size: stats.Size(), maxSize: maxSize, segmentID: id, version: segmentV2, cipher: c}
+		mutex.Lock()
+		defer mutex.Unlock()
+		if s.size == 0 {
+			err = s.createV2()
+		} else {
+			err = s.openV2()
+		}
+		if err != nil {
+			return nil, err
+		}
+		return s, nil
+	}
+
 	// check whether file size is less than maxSize passed as parameter
 	var size int64
 	if maxSize >= stats.Size() {
@@ -532,7 +682,7 @@ func newSegment(path string, maxSize int64) (*segment, error) {
 		// with maxSize
 		size = maxSize
 	}
-	s := &segment{file: f, path: path, size: size, maxSize: maxSize, segmentID: id}
+	s := &segment{file: f, path: path, size: size, maxSize: maxSize, segmentID: id, version: segmentV1}
 
 	// after segment creation, open it with mutex protection
 	mutex.Lock()
@@ -753,6 +903,10 @@ func (l *segment) append(b []byte) error {
 		return ErrNotOpen
 	}
 
+	if l.version == segmentV2 {
+		return l.appendV2(b)
+	}
+
 	if l.size+int64(len(b)) > l.maxSize {
 		return ErrSegmentFull
 	}
@@ -807,6 +961,10 @@ func (l *segment) current() ([]byte, error) {
 	l.mu.Lock()
 	defer l.mu.Unlock()
 
+	if l.version == segmentV2 {
+		return l.currentV2()
+	}
+
 	if int64(l.pos) == l.size-footerSize {
 		return nil, io.EOF
 	}
@@ -834,6 +992,57 @@ func (l *segment) current() ([]byte, error) {
 	return b, nil
 }
 
+// exhausted returns true if every block in the segment has been read.
+func (l *segment) exhausted() bool {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	if l.version == segmentV2 {
+		return l.pos == l.size-indexSize
+	}
+	return int64(l.pos) == l.size-footerSize
+}
+
+// blocks returns the blocks from the current position on, without advancing
+// it, until their total size reaches maxBytes or the end of the segment.
+func (l *segment) blocks(maxBytes int64) ([][]byte, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.file == nil {
+		return nil, ErrNotOpen
+	}
+
+	if l.version == segmentV2 {
+		return l.blocksV2(maxBytes)
+	}
+
+	if err := l.seekToCurrent(); err != nil {
+		return nil, err
+	}
+
+	var blocks [][]byte
+	var size int64
+	for pos := int64(l.pos); pos < l.size-footerSize && size < maxBytes; {
+		sz, err := l.readUint64()
+		if err != nil {
+			return nil, err
+		}
+
+		if int64(sz) > l.maxSize {
+			return nil, fmt.Errorf("record size out of range: max %d: got %d", l.maxSize, sz)
+		}
+
+		b := make([]byte, sz)
+		if err := l.readBytes(b); err != nil {
+			return nil, err
+		}
+		blocks = append(blocks, b)
+		size += int64(sz)
+		pos += int64(sz) + 8
+	}
+	return blocks, nil
+}
+
 // TODO zhexuany finished this when alpha is ready
 // peek returns the next n blocks in segement without advancing the pos.
 func (l *segment) peek(n int64) ([]byte, error) {
@@ -844,6 +1053,10 @@ func (l *segment) peek(n int64) ([]byte, error) {
 		return nil, nil
 	}
 
+	if l.version == segmentV2 {
+		return l.peekV2(n)
+	}
+
 	if err := l.seekToCurrent(); err != nil {
 		return nil, err
 	}
@@ -892,6 +1105,10 @@ func (l *segment) advance() error {
 		return ErrNotOpen
 	}
 
+	if l.version == segmentV2 {
+		return l.advanceV2()
+	}
+
 	// If we're at the end of the file, can't advance
 	if int64(l.pos) == l.size-footerSize {
 		l.currentSize = 0
@@ -932,9 +1149,11 @@ func (l *segment) advance() error {
 
 func (l *segment) totalBytes() int64 {
 	l.mu.RLock()
-	totalB := l.size - footerSize
-	l.mu.RUnlock()
-	return totalB
+	defer l.mu.RUnlock()
+	if l.version == segmentV2 {
+		return l.size - segmentHeaderSize - indexSize
+	}
+	return l.size - footerSize
 }
 
 func (l *segment) close() error {
@@ -1,11 +1,13 @@
 package hh
 
 import (
+	"bytes"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 )
@@ -60,8 +62,8 @@ func TestQueueAppendOne(t *testing.T) {
 		t.Fatalf("Queue.Append file not exists. exp %v to exist", exp)
 	}
 
-	// 8 byte header ptr + 8 byte record len + record len
-	if exp := int64(8 + 8 + 4); stats.Size() != exp {
+	// 8 byte header + 9 byte block header + record len + 16 byte index
+	if exp := int64(segmentHeaderSize + blockHeaderSize + 4 + indexSize); stats.Size() != exp {
 		t.Fatalf("Queue.Append file size mismatch. got %v, exp %v", stats.Size(), exp)
 	}
 
@@ -138,7 +140,7 @@ func TestQueueAdvancePastEnd(t *testing.T) {
 	}
 
 	// set the segment size low to force a new segment to be created
-	q.SetMaxSegmentSize(12)
+	q.SetMaxSegmentSize(segmentHeaderSize + blockHeaderSize + 3 + indexSize + 4)
 
 	// Should go into a new segment
 	if err := q.Append([]byte("two")); err != nil {
@@ -325,3 +327,247 @@ func TestPurgeQueue(t *testing.T) {
 	}
 
 }
+
+// Ensure segments written in the segmentV1 format are still read, and that
+// writes go to a new segment.
+func TestQueue_SegmentV1(t *testing.T) {
+	dir, err := ioutil.TempDir("", "hh_queue")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	// A segmentV1 file of a single block, with the head at the block.
+	var b []byte
+	b = append(b, 0, 0, 0, 0, 0, 0, 0, 3)
+	b = append(b, "one"...)
+	b = append(b, 0, 0, 0, 0, 0, 0, 0, 0)
+	if err := ioutil.WriteFile(filepath.Join(dir, "1"), b, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	q, err := newQueue(dir, 1024)
+	if err != nil {
+		t.Fatalf("failed to create queue: %v", err)
+	}
+	if err := q.Open(); err != nil {
+		t.Fatalf("failed to open queue: %v", err)
+	}
+	defer q.Close()
+
+	if err := q.Append([]byte("two")); err != nil {
+		t.Fatalf("Queue.Append failed: %v", err)
+	} else if q.tail.version != segmentV2 || q.head.version != segmentV1 {
+		t.Fatalf("unexpected segment versions: head %d, tail %d", q.head.version, q.tail.version)
+	}
+
+	for _, exp := range []string{"one", "two"} {
+		if cur, err := q.Current(); err != nil {
+			t.Fatalf("Queue.Current failed: %v", err)
+		} else if string(cur) != exp {
+			t.Errorf("Queue.Current mismatch: got %v, exp %v", string(cur), exp)
+		}
+		if err := q.Advance(); err != nil {
+			t.Fatalf("Queue.Advance failed: %v", err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "1")); !os.IsNotExist(err) {
+		t.Fatalf("segmentV1 file not removed once read")
+	}
+}
+
+// Ensure a segment torn or corrupted by a crash is truncated after its last
+// good block when it is opened.
+func TestQueue_SegmentV2Repair(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		corrupt func(b []byte) []byte
+		exp     []string
+	}{
+		{
+			name:    "torn index",
+			corrupt: func(b []byte) []byte { return append(b[:len(b)-5], 0xff, 0xff) },
+			exp:     []string{"one", "two"},
+		},
+		{
+			name: "torn block",
+			corrupt: func(b []byte) []byte {
+				return append(b[:len(b)-indexSize], 0, 0, 0, 9, 0xff)
+			},
+			exp: []string{"one", "two"},
+		},
+		{
+			name: "corrupt block",
+			corrupt: func(b []byte) []byte {
+				b[segmentHeaderSize+blockHeaderSize+3+blockHeaderSize]++
+				return b
+			},
+			exp: []string{"one"},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "hh_queue")
+			if err != nil {
+				t.Fatalf("failed to create temp dir: %v", err)
+			}
+			defer os.RemoveAll(dir)
+
+			q, err := newQueue(dir, 1024)
+			if err != nil {
+				t.Fatalf("failed to create queue: %v", err)
+			}
+			if err := q.Open(); err != nil {
+				t.Fatalf("failed to open queue: %v", err)
+			}
+			for _, s := range []string{"one", "two"} {
+				if err := q.Append([]byte(s)); err != nil {
+					t.Fatalf("Queue.Append failed: %v", err)
+				}
+			}
+			if err := q.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			path := filepath.Join(dir, "1")
+			b, err := ioutil.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			} else if err := ioutil.WriteFile(path, tt.corrupt(b), 0600); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := q.Open(); err != nil {
+				t.Fatalf("failed to re-open queue: %v", err)
+			}
+			defer q.Close()
+			for _, exp := range tt.exp {
+				if cur, err := q.Current(); err != nil {
+					t.Fatalf("Queue.Current failed: %v", err)
+				} else if string(cur) != exp {
+					t.Errorf("Queue.Current mismatch: got %v, exp %v", string(cur), exp)
+				}
+				if err := q.Advance(); err != nil {
+					t.Fatalf("Queue.Advance failed: %v", err)
+				}
+			}
+			if _, err := q.Current(); err != io.EOF {
+				t.Fatalf("Queue.Current expected io.EOF, got: %v", err)
+			}
+		})
+	}
+}
+
+// Ensure large blocks are stored compressed and read back whole.
+func TestQueue_SegmentV2Compression(t *testing.T) {
+	dir, err := ioutil.TempDir("", "hh_queue")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	q, err := newQueue(dir, 1024*1024)
+	if err != nil {
+		t.Fatalf("failed to create queue: %v", err)
+	}
+	if err := q.Open(); err != nil {
+		t.Fatalf("failed to open queue: %v", err)
+	}
+	defer q.Close()
+
+	block := []byte(strings.Repeat("cpu,host=server01 value=1 0\n", 100))
+	if err := q.Append(block); err != nil {
+		t.Fatalf("Queue.Append failed: %v", err)
+	} else if n := q.TotalBytes(); n >= int64(len(block)) {
+		t.Fatalf("block not compressed: %d bytes stored", n)
+	}
+	if cur, err := q.Current(); err != nil {
+		t.Fatalf("Queue.Current failed: %v", err)
+	} else if !bytes.Equal(cur, block) {
+		t.Fatalf("Queue.Current mismatch: got %d bytes, exp %d", len(cur), len(block))
+	}
+}
+
+// Ensure blocks are stored encrypted when the queue has an encryption key,
+// and that they are neither read nor dropped without it.
+func TestQueue_SegmentV2Encryption(t *testing.T) {
+	dir, err := ioutil.TempDir("", "hh_queue")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	key := bytes.Repeat([]byte{0x42}, 32)
+	open := func(key []byte) *queue {
+		q, err := newQueue(dir, 1024*1024)
+		if err != nil {
+			t.Fatalf("failed to create queue: %v", err)
+		}
+		if key != nil {
+			if err := q.SetEncryptionKey(key); err != nil {
+				t.Fatalf("Queue.SetEncryptionKey failed: %v", err)
+			}
+		}
+		if err := q.Open(); err != nil {
+			t.Fatalf("failed to open queue: %v", err)
+		}
+		return q
+	}
+
+	// A block written before the key was set is still read.
+	q := open(nil)
+	if err := q.Append([]byte("plain")); err != nil {
+		t.Fatalf("Queue.Append failed: %v", err)
+	}
+	q.Close()
+
+	q = open(key)
+	small := []byte("cpu,host=secret value=1 0")
+	large := []byte(strings.Repeat("cpu,host=secret value=1 0\n", 100))
+	for _, b := range [][]byte{small, large} {
+		if err := q.Append(b); err != nil {
+			t.Fatalf("Queue.Append failed: %v", err)
+		}
+	}
+	size := q.TotalBytes()
+	q.Close()
+
+	files, err := filepath.Glob(filepath.Join(dir, "*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range files {
+		b, err := ioutil.ReadFile(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if bytes.Contains(b, []byte("secret")) {
+			t.Fatalf("segment %s holds the plaintext", f)
+		}
+	}
+
+	// Without the key, or with another one, the encrypted blocks are kept
+	// but cannot be read.
+	for _, k := range [][]byte{nil, bytes.Repeat([]byte{0x24}, 32)} {
+		q = open(k)
+		if n := q.TotalBytes(); n != size {
+			t.Fatalf("segment truncated: %d bytes, exp %d", n, size)
+		}
+		if cur, err := q.Current(); err != nil || string(cur) != "plain" {
+			t.Fatalf("Queue.Current mismatch: got %q, %v", cur, err)
+		}
+		if _, err := q.PeekBlocks(1024 * 1024); err == nil {
+			t.Fatalf("expected error reading encrypted blocks")
+		}
+		q.Close()
+	}
+
+	q = open(key)
+	defer q.Close()
+	blocks, err := q.PeekBlocks(1024 * 1024)
+	if err != nil {
+		t.Fatalf("Queue.PeekBlocks failed: %v", err)
+	}
+	if len(blocks) != 3 || string(blocks[0]) != "plain" || !bytes.Equal(blocks[1], small) || !bytes.Equal(blocks[2], large) {
+		t.Fatalf("Queue.PeekBlocks mismatch: got %d blocks", len(blocks))
+	}
+}
@@ -0,0 +1,377 @@
+package hh
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+
+	"github.com/golang/snappy"
+)
+
+// Versions of the segment file format.
+const (
+	// segmentV1 segments are a series of length-prefixed blocks followed by
+	// a footer holding the offset of the head block. They are still read,
+	// but new segments are always written as segmentV2.
+	segmentV1 = 1
+
+	// segmentV2 segments start with a header, checksum and may compress
+	// each block, and end with an index block locating the head block.
+	segmentV2 = 2
+)
+
+// segmentV2Magic starts the header of a segmentV2 file. A segmentV1 file
+// starts with the big-endian length of its first block, or with its footer,
+// so its first byte is zero for any block that fits in a segment.
+var segmentV2Magic = []byte("HHQ2")
+
+// indexMagic ends the index block of a segmentV2 file.
+var indexMagic = []byte("HHIX")
+
+const (
+	// segmentHeaderSize is the size of the header of a segmentV2 file: the
+	// magic and the version.
+	segmentHeaderSize = 8
+
+	// blockHeaderSize is the size of the header of each block of a
+	// segmentV2 file: the length of the stored block, its checksum, and its
+	// flags.
+	blockHeaderSize = 9
+
+	// indexSize is the size of the index block of a segmentV2 file: the
+	// offset of the head block, its checksum, and indexMagic.
+	indexSize = 16
+
+	// minCompressSize is the smallest block compressed. Compressing
+	// smaller blocks rarely saves more than the time it takes.
+	minCompressSize = 256
+)
+
+// Flags of the blocks of a segmentV2 file.
+const (
+	// blockCompressed is set in the flags of a block stored compressed with
+	// snappy.
+	blockCompressed = 1
+
+	// blockEncrypted is set in the flags of a block stored encrypted by a
+	// blockCipher, after it was compressed.
+	blockEncrypted = 2
+)
+
+// castagnoli is the table of the checksums of blocks and index blocks.
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+// segmentV2 layout:
+//
+// ┌────────────┐ ┌──────────────────────────┐ ┌──────────────────────────┐ ┌─────────────┐
+// │   Header   │ │         Block 1          │ │         Block 2          │ │ Index Block │
+// └────────────┘ └──────────────────────────┘ └──────────────────────────┘ └─────────────┘
+// ┌────────────┐ ┌────────┐┌───┐┌─────┐┌────┐                             ┌────┐┌───┐┌────┐
+// │Magic|Vers. │ │Length  ││CRC││Flags││Body│                             │Head││CRC││HHIX│
+// │  8 bytes   │ │4 bytes ││ 4 ││  1  ││ N  │                             │ 8  ││ 4 ││ 4  │
+// └────────────┘ └────────┘└───┘└─────┘└────┘                             └────┘└───┘└────┘
+//
+// The checksum of a block covers its flags and stored body, encrypted if
+// blockEncrypted is set, so that torn blocks are found without the key.
+// The index block
+// holds the offset of the head block, the first that was not advanced past,
+// so that replay seeks straight to it however much of the segment was sent.
+//
+// Appends overwrite the index block with the new block followed by a new
+// index block. If a crash leaves a torn block or index block, opening the
+// segment keeps the blocks whose checksums match up to the first that does
+// not, and truncates the rest. If the index block itself is lost, the head is
+// reset to the first block, so the blocks already sent are sent again; the
+// node they are sent to skips them by their sequence numbers.
+
+// isSegmentV2 returns true if f starts with the header of a segmentV2 file.
+func isSegmentV2(f *os.File) (bool, error) {
+	var b [segmentHeaderSize]byte
+	if _, err := f.ReadAt(b[:], 0); err == io.EOF {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	return bytes.Equal(b[:4], segmentV2Magic), nil
+}
+
+// createV2 writes the header and index block of a new segmentV2 file.
+func (l *segment) createV2() error {
+	var b [segmentHeaderSize]byte
+	copy(b[:], segmentV2Magic)
+	binary.BigEndian.PutUint32(b[4:], segmentV2)
+	if _, err := l.file.WriteAt(b[:], 0); err != nil {
+		return err
+	}
+
+	l.pos = segmentHeaderSize
+	l.currentSize = 0
+	if err := l.writeIndex(segmentHeaderSize + indexSize); err != nil {
+		return err
+	}
+	return l.file.Sync()
+}
+
+// openV2 reads the head offset of an existing segmentV2 file from its index
+// block, and checks the blocks from the head on, truncating the file after
+// the last good block if any is torn or corrupt.
+func (l *segment) openV2() error {
+	if l.size < segmentHeaderSize {
+		return fmt.Errorf("segment %s too short: len = %d", l.path, l.size)
+	}
+
+	// Scan from the head if the index block is intact, or from the first
+	// block otherwise, until the end of the blocks or the first bad block.
+	end := l.size - indexSize
+	pos, ok := l.readIndex()
+	if !ok {
+		pos, end = segmentHeaderSize, l.size
+	}
+	off := pos
+	for off < end {
+		n, err := l.recordSize(off, end)
+		if err != nil {
+			break
+		}
+		if _, _, err := l.readBlock(off, n); err != nil {
+			break
+		}
+		off += n
+	}
+
+	l.pos = pos
+	l.currentSize = 0
+	if ok && off == end {
+		return nil
+	}
+
+	// Drop everything from the first bad block, then write a new index.
+	if err := l.file.Truncate(off); err != nil {
+		return err
+	}
+	if err := l.writeIndex(off + indexSize); err != nil {
+		return err
+	}
+	return l.file.Sync()
+}
+
+// readIndex returns the head offset of the index block at the end of the
+// file, and false if the index block is missing or corrupt.
+func (l *segment) readIndex() (int64, bool) {
+	if l.size < segmentHeaderSize+indexSize {
+		return 0, false
+	}
+	var b [indexSize]byte
+	if _, err := l.file.ReadAt(b[:], l.size-indexSize); err != nil {
+		return 0, false
+	}
+	if !bytes.Equal(b[12:], indexMagic) || crc32.Checksum(b[:8], castagnoli) != binary.BigEndian.Uint32(b[8:12]) {
+		return 0, false
+	}
+	pos := int64(binary.BigEndian.Uint64(b[:8]))
+	if pos < segmentHeaderSize || pos > l.size-indexSize {
+		return 0, false
+	}
+	return pos, true
+}
+
+// writeIndex writes an index block holding the head offset so that the file
+// ends at size.
+func (l *segment) writeIndex(size int64) error {
+	var b [indexSize]byte
+	binary.BigEndian.PutUint64(b[:8], uint64(l.pos))
+	binary.BigEndian.PutUint32(b[8:12], crc32.Checksum(b[:8], castagnoli))
+	copy(b[12:], indexMagic)
+	if _, err := l.file.WriteAt(b[:], size-indexSize); err != nil {
+		return err
+	}
+	l.size = size
+	return nil
+}
+
+// encodeRecord returns b as a block of a segmentV2 file, compressed if that
+// makes it smaller, and encrypted with c if set.
+func encodeRecord(b []byte, c *blockCipher) ([]byte, error) {
+	body, flags := b, byte(0)
+	if len(b) >= minCompressSize {
+		if z := snappy.Encode(nil, b); len(z) < len(b) {
+			body, flags = z, blockCompressed
+		}
+	}
+	if c != nil {
+		flags |= blockEncrypted
+		var err error
+		if body, err = c.seal(body, flags); err != nil {
+			return nil, err
+		}
+	}
+
+	rec := make([]byte, blockHeaderSize+len(body))
+	binary.BigEndian.PutUint32(rec[0:4], uint32(len(body)))
+	rec[8] = flags
+	copy(rec[blockHeaderSize:], body)
+	binary.BigEndian.PutUint32(rec[4:8], crc32.Checksum(rec[8:], castagnoli))
+	return rec, nil
+}
+
+// recordSize returns the size of the block at off, which must end by end.
+func (l *segment) recordSize(off, end int64) (int64, error) {
+	if off+blockHeaderSize > end {
+		return 0, fmt.Errorf("block header at %d past end of segment", off)
+	}
+	var b [4]byte
+	if _, err := l.file.ReadAt(b[:], off); err != nil {
+		return 0, err
+	}
+	n := blockHeaderSize + int64(binary.BigEndian.Uint32(b[:]))
+	if off+n > end {
+		return 0, fmt.Errorf("block at %d past end of segment: len = %d", off, n)
+	}
+	return n, nil
+}
+
+// readBlock returns the flags and stored body of the block of n bytes at
+// off, checking its checksum.
+func (l *segment) readBlock(off, n int64) (byte, []byte, error) {
+	rec := make([]byte, n)
+	if _, err := l.file.ReadAt(rec, off); err != nil {
+		return 0, nil, err
+	}
+	if crc32.Checksum(rec[8:], castagnoli) != binary.BigEndian.Uint32(rec[4:8]) {
+		return 0, nil, fmt.Errorf("block at %d of segment %s: checksum mismatch", off, l.path)
+	}
+	return rec[8], rec[blockHeaderSize:], nil
+}
+
+// readRecord returns the body of the block of n bytes at off, checking its
+// checksum, decrypting and decompressing it.
+func (l *segment) readRecord(off, n int64) ([]byte, error) {
+	flags, body, err := l.readBlock(off, n)
+	if err != nil {
+		return nil, err
+	}
+
+	if flags&blockEncrypted != 0 {
+		if body, err = l.cipher.open(body, flags); err != nil {
+			return nil, fmt.Errorf("block at %d of segment %s: %s", off, l.path, err)
+		}
+	}
+	if flags&blockCompressed == 0 {
+		return body, nil
+	}
+	b, err := snappy.Decode(nil, body)
+	if err != nil {
+		return nil, fmt.Errorf("block at %d of segment %s: %s", off, l.path, err)
+	}
+	return b, nil
+}
+
+// appendV2 is append for a segmentV2 file.
+func (l *segment) appendV2(b []byte) error {
+	rec, err := encodeRecord(b, l.cipher)
+	if err != nil {
+		return err
+	}
+	if l.size+int64(len(rec)) > l.maxSize {
+		return ErrSegmentFull
+	}
+
+	// The block and the new index block are written at once, over the old
+	// index block.
+	end := l.size - indexSize
+	if _, err := l.file.WriteAt(rec, end); err != nil {
+		return err
+	}
+	if err := l.writeIndex(end + int64(len(rec)) + indexSize); err != nil {
+		return err
+	}
+	return l.file.Sync()
+}
+
+// currentV2 is current for a segmentV2 file.
+func (l *segment) currentV2() ([]byte, error) {
+	end := l.size - indexSize
+	if l.pos == end {
+		return nil, io.EOF
+	}
+	n, err := l.recordSize(l.pos, end)
+	if err != nil {
+		return nil, err
+	}
+	l.currentSize = n
+	return l.readRecord(l.pos, n)
+}
+
+// blocksV2 is blocks for a segmentV2 file.
+func (l *segment) blocksV2(maxBytes int64) ([][]byte, error) {
+	var blocks [][]byte
+	var size int64
+	end := l.size - indexSize
+	for off := l.pos; off < end && size < maxBytes; {
+		n, err := l.recordSize(off, end)
+		if err != nil {
+			return nil, err
+		}
+		b, err := l.readRecord(off, n)
+		if err != nil {
+			return nil, err
+		}
+		blocks = append(blocks, b)
+		size += int64(len(b))
+		off += n
+	}
+	return blocks, nil
+}
+
+// advanceV2 is advance for a segmentV2 file.
+func (l *segment) advanceV2() error {
+	end := l.size - indexSize
+	if l.pos == end {
+		l.currentSize = 0
+		return io.EOF
+	}
+	n, err := l.recordSize(l.pos, end)
+	if err != nil {
+		return err
+	}
+
+	l.pos += n
+	if err := l.writeIndex(l.size); err != nil {
+		return err
+	}
+	if err := l.file.Sync(); err != nil {
+		return err
+	}
+
+	l.currentSize = 0
+	if l.pos == end {
+		return io.EOF
+	}
+	return nil
+}
+
+// peekV2 is peek for a segmentV2 file.
+func (l *segment) peekV2(n int64) ([]byte, error) {
+	var buf []byte
+	end := l.size - indexSize
+	off := l.pos
+	for i := 0; i < int(n); i++ {
+		if off == end {
+			return nil, io.EOF
+		}
+		sz, err := l.recordSize(off, end)
+		if err != nil {
+			return buf, err
+		}
+		b, err := l.readRecord(off, sz)
+		if err != nil {
+			return nil, err
+		}
+		buf = append(buf, b...)
+		off += sz
+	}
+	return buf, nil
+}
@@ -0,0 +1,89 @@
+package hh
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"time"
+)
+
+// sequenceFile is the file in the directory of a queue that the sequence
+// numbers of its writes are reserved in.
+const sequenceFile = "sequence"
+
+// sequenceLease is how far past the last sequence number the reserved
+// ceiling is moved, so that the file is only rewritten once in a while.
+const sequenceLease = uint64(time.Minute)
+
+// sequencer assigns monotonically increasing sequence numbers to the writes
+// of a queue, so that the node they are replayed to can skip those it
+// already applied. Numbers start from the time in nanoseconds and never go
+// below the ceiling reserved in its file, so that they keep increasing
+// across restarts even if the clock goes back, or if the file is lost with
+// the rest of the queue. A sequencer is not safe for concurrent use.
+type sequencer struct {
+	path    string
+	last    uint64
+	ceiling uint64
+}
+
+// openSequencer returns the sequencer reserving numbers in the file at path.
+func openSequencer(path string) (*sequencer, error) {
+	s := &sequencer{path: path}
+	b, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return s, nil
+	} else if err != nil {
+		return nil, err
+	} else if len(b) != 8 {
+		return nil, fmt.Errorf("invalid sequence file %s: len = %d", path, len(b))
+	}
+	s.ceiling = binary.BigEndian.Uint64(b)
+	s.last = s.ceiling
+	return s, nil
+}
+
+// next returns the next sequence number, reserving more first if needed.
+func (s *sequencer) next() (uint64, error) {
+	seq := s.last + 1
+	if now := uint64(time.Now().UnixNano()); now > seq {
+		seq = now
+	}
+	if seq > s.ceiling {
+		if err := s.reserve(seq + sequenceLease); err != nil {
+			return 0, err
+		}
+	}
+	s.last = seq
+	return seq, nil
+}
+
+// reserve writes ceiling to the file of the sequencer, replacing it only
+// once the new value is synced.
+func (s *sequencer) reserve(ceiling uint64) error {
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], ceiling)
+
+	tmpFile := s.path + "tmp"
+	f, err := os.Create(tmpFile)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(b[:]); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpFile, s.path); err != nil {
+		return err
+	}
+	s.ceiling = ceiling
+	return nil
+}
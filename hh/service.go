@@ -14,6 +14,7 @@ import (
 	"github.com/influxdata/influxdb/monitor/diagnostics"
 	"github.com/influxdata/influxdb/services/meta"
 	"github.com/uber-go/zap"
+	"github.com/zhexuany/influxcloud/rpc"
 )
 
 // ErrHintedHandoffDisabled is returned when attempting to use a
@@ -33,6 +34,7 @@ type Service struct {
 	closing chan struct{}
 
 	processors map[uint64]*NodeProcessor
+	paused     map[uint64]struct{} // nodes that processors must not send to
 
 	defaultTags models.StatisticTags
 	stats       *HHStatistics
@@ -40,6 +42,14 @@ type Service struct {
 	Logger zap.Logger
 	cfg    Config
 
+	// encryptionKey is the key the queues are encrypted with, loaded from
+	// the configuration on Open.
+	encryptionKey []byte
+
+	// OnQueueFull is called each time the queue of a node fills up, if set,
+	// e.g. to publish it as a cluster event. It must be set before Open.
+	OnQueueFull func(nodeID uint64)
+
 	shardWriter shardWriter
 	MetaClient  metaClient
 
@@ -55,6 +65,12 @@ type shardWriter interface {
 	WriteShard(shardID, ownerID uint64, points []models.Point) error
 }
 
+// handoffShardWriter is implemented by shard writers that send the sequence
+// numbers of queued writes, so that the node skips replays it already applied.
+type handoffShardWriter interface {
+	WriteHandoffShard(shardID, ownerID, queueID, seq uint64, points []models.Point) error
+}
+
 type metaClient interface {
 	DataNode(id uint64) (ni *meta.NodeInfo, err error)
 }
@@ -69,6 +85,7 @@ func NewService(c Config, w shardWriter, m metaClient) *Service {
 		cfg:         c,
 		closing:     make(chan struct{}),
 		processors:  make(map[uint64]*NodeProcessor),
+		paused:      make(map[uint64]struct{}),
 		stats:       &HHStatistics{},
 		Logger:      zap.New(zap.NullEncoder()),
 		shardWriter: w,
@@ -96,6 +113,12 @@ func (s *Service) Open() error {
 		s.Monitor.RegisterDiagnosticsClient("hh", s)
 	}
 
+	key, err := s.cfg.EncryptionKey()
+	if err != nil {
+		return err
+	}
+	s.encryptionKey = key
+
 	// Create the root directory if it doesn't already exist.
 	// s.Logger.Info("Using data dir: %v", s.cfg.Dir)
 	if err := os.MkdirAll(s.cfg.Dir, 0700); err != nil {
@@ -115,7 +138,10 @@ func (s *Service) Open() error {
 			continue
 		}
 
-		n := NewNodeProcessor(nodeID, s.pathforNode(nodeID), s.shardWriter, s.MetaClient)
+		n := s.newNodeProcessor(nodeID)
+		if _, ok := s.paused[nodeID]; ok {
+			n.Pause()
+		}
 		//Open newly created NodeProcessor
 		if err := n.Open(); err != nil {
 			return err
@@ -194,41 +220,167 @@ func (s *Service) Empty(id uint64) bool {
 	return np.Empty()
 }
 
+// QueueSizes returns the number of bytes queued for each node.
+func (s *Service) QueueSizes() map[uint64]int64 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	sizes := make(map[uint64]int64, len(s.processors))
+	for id, np := range s.processors {
+		sizes[id] = np.QueueSize()
+	}
+	return sizes
+}
+
+// PauseReplication stops sending queued data to nodeID. Writes for the node
+// continue to be queued until ResumeReplication is called.
+func (s *Service) PauseReplication(nodeID uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.paused[nodeID] = struct{}{}
+	if p, ok := s.processors[nodeID]; ok {
+		p.Pause()
+	}
+}
+
+// ResumeReplication restarts sending queued data to nodeID.
+func (s *Service) ResumeReplication(nodeID uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.paused, nodeID)
+	if p, ok := s.processors[nodeID]; ok {
+		p.Resume()
+	}
+}
+
+// RedirectQueue sends the writes queued for the node from to the node to, and
+// flushes them. Writes queued for from later are sent to to as well. It is
+// used when to replaces from.
+func (s *Service) RedirectQueue(from, to uint64) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	np, ok := s.processors[from]
+	if !ok {
+		return nil
+	}
+	np.SetTarget(to)
+	return np.Flush()
+}
+
+// Drain flushes the queue of every node processor so that no writes are left
+// on this node when it shuts down. Writes for paused or inactive nodes remain
+// queued. Returns the first error encountered.
+func (s *Service) Drain() error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var firstErr error
+	for id, np := range s.processors {
+		if err := np.Flush(); err != nil {
+			s.Logger.Warn(fmt.Sprintf("unable to flush hinted handoff queue for node %d: %s", id, err))
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	return firstErr
+}
+
 // WriteShard queues the points write for shardID to node ownerID to handoff queue
 func (s *Service) WriteShard(shardID, ownerID uint64, points []models.Point) error {
-	if !s.cfg.Enabled {
-		return ErrHintedHandoffDisabled
+	processor, err := s.nodeProcessor(ownerID)
+	if err != nil {
+		return err
+	}
+	return processor.WriteShard(shardID, points)
+}
+
+// WriteEncodedShard queues encoded points for shardID to node ownerID to
+// handoff queue without decoding them.
+func (s *Service) WriteEncodedShard(shardID, ownerID uint64, points *rpc.EncodedPoints) error {
+	processor, err := s.nodeProcessor(ownerID)
+	if err != nil {
+		return err
+	}
+	return processor.WriteEncodedShard(shardID, points)
+}
+
+// nodeProcessor returns the processor for ownerID, creating and opening it if
+// it does not exist yet.
+func (s *Service) nodeProcessor(ownerID uint64) (*NodeProcessor, error) {
+	if !s.enabled {
+		return nil, ErrHintedHandoffDisabled
 	}
 
 	s.mu.RLock()
 	processor, ok := s.processors[ownerID]
 	s.mu.RUnlock()
+	if ok {
+		return processor, nil
+	}
+
+	// Check again under write-lock.
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	processor, ok = s.processors[ownerID]
 	if !ok {
-		if err := func() error {
-			// Check again under write-lock.
-			s.mu.Lock()
-			defer s.mu.Unlock()
-
-			processor, ok = s.processors[ownerID]
-			if !ok {
-				processor = NewNodeProcessor(ownerID, s.pathforNode(ownerID), s.shardWriter, s.MetaClient)
-				if err := processor.Open(); err != nil {
-					return err
-				}
-				s.processors[ownerID] = processor
-				atomic.AddInt64(&s.stats.NodeProcessorCreated, 1)
-				atomic.AddInt64(&s.stats.NodeProcessorOpened, 1)
-			}
-			return nil
-		}(); err != nil {
-			return err
+		processor = s.newNodeProcessor(ownerID)
+		if _, ok := s.paused[ownerID]; ok {
+			processor.Pause()
 		}
+		if err := processor.Open(); err != nil {
+			return nil, err
+		}
+		s.processors[ownerID] = processor
+		atomic.AddInt64(&s.stats.NodeProcessorCreated, 1)
+		atomic.AddInt64(&s.stats.NodeProcessorOpened, 1)
 	}
+	return processor, nil
+}
+
+// newNodeProcessor returns a processor for the queue of nodeID, configured
+// with the limits for that node.
+func (s *Service) newNodeProcessor(nodeID uint64) *NodeProcessor {
+	n := NewNodeProcessor(nodeID, s.pathforNode(nodeID), s.shardWriter, s.MetaClient)
+	n.PurgeInterval = time.Duration(s.cfg.PurgeInterval)
+	n.QuarantineDir = s.quarantinePath(nodeID)
+	n.EncryptionKey = s.encryptionKey
+	n.Reconfigure(s.cfg)
+	n.Logger = s.Logger
+	n.OnQueueFull = s.OnQueueFull
+	return n
+}
 
-	if err := processor.WriteShard(shardID, points); err != nil {
+// Reload applies the retry, replay, quarantine and queue limits of c to the
+// service and to the queues already open. Enabling hinted handoff, its
+// directory and its purge interval take effect once the node is restarted.
+// Nothing is applied if c is invalid.
+func (s *Service) Reload(c Config) error {
+	if err := c.Validate(); err != nil {
 		return err
 	}
 
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.cfg.MaxSize = c.MaxSize
+	s.cfg.MaxAge = c.MaxAge
+	s.cfg.RetryRateLimit = c.RetryRateLimit
+	s.cfg.RetryInterval = c.RetryInterval
+	s.cfg.RetryMaxInterval = c.RetryMaxInterval
+	s.cfg.OverflowPolicy = c.OverflowPolicy
+	s.cfg.ReplayBatchSize = c.ReplayBatchSize
+	s.cfg.ReplayTargetLatency = c.ReplayTargetLatency
+	s.cfg.QuarantineAfter = c.QuarantineAfter
+	s.cfg.Nodes = c.Nodes
+
+	for _, n := range s.processors {
+		n.Reconfigure(s.cfg)
+	}
 	return nil
 }
 
@@ -0,0 +1,162 @@
+package meta
+
+import (
+	"github.com/gogo/protobuf/proto"
+	"github.com/influxdata/influxdb/services/meta"
+	"github.com/zhexuany/influxcloud/meta/internal"
+)
+
+// Batch accumulates DDL commands so they can be applied to the meta store
+// in a single raft round trip with Client.ExecuteBatch. Commands are applied
+// in the order they were added and the batch is all or nothing: if any
+// command fails, none of them take effect.
+//
+// The first error encountered while building the batch is returned by
+// ExecuteBatch.
+type Batch struct {
+	cmds []*internal.Command
+	err  error
+}
+
+// NewBatch returns an empty batch.
+func NewBatch() *Batch {
+	return &Batch{}
+}
+
+// Len returns the number of commands in the batch.
+func (b *Batch) Len() int { return len(b.cmds) }
+
+// CreateDatabase adds a command creating the named database.
+func (b *Batch) CreateDatabase(name string) {
+	b.add(internal.Command_CreateDatabaseCommand, internal.E_CreateDatabaseCommand_Command,
+		&internal.CreateDatabaseCommand{
+			Name: proto.String(name),
+		},
+	)
+}
+
+// CreateDatabaseWithRetentionPolicy adds a command creating the named database
+// with spec as its default retention policy.
+func (b *Batch) CreateDatabaseWithRetentionPolicy(name string, spec *meta.RetentionPolicySpec) {
+	rpiB, err := marshalRetentionPolicySpec(spec)
+	if err != nil {
+		b.setErr(err)
+		return
+	}
+
+	b.add(internal.Command_CreateDatabaseCommand, internal.E_CreateDatabaseCommand_Command,
+		&internal.CreateDatabaseCommand{
+			Name:            proto.String(name),
+			RetentionPolicy: rpiB,
+		},
+	)
+}
+
+// DropDatabase adds a command dropping the named database.
+func (b *Batch) DropDatabase(name string) {
+	b.add(internal.Command_DropDatabaseCommand, internal.E_DropDatabaseCommand_Command,
+		&internal.DropDatabaseCommand{
+			Name: proto.String(name),
+		},
+	)
+}
+
+// CreateRetentionPolicy adds a command creating a retention policy on database.
+func (b *Batch) CreateRetentionPolicy(database string, spec *meta.RetentionPolicySpec) {
+	rpiB, err := marshalRetentionPolicySpec(spec)
+	if err != nil {
+		b.setErr(err)
+		return
+	}
+
+	b.add(internal.Command_CreateRetentionPolicyCommand, internal.E_CreateRetentionPolicyCommand_Command,
+		&internal.CreateRetentionPolicyCommand{
+			Database:        proto.String(database),
+			RetentionPolicy: rpiB,
+		},
+	)
+}
+
+// DropRetentionPolicy adds a command dropping a retention policy from database.
+func (b *Batch) DropRetentionPolicy(database, name string) {
+	b.add(internal.Command_DropRetentionPolicyCommand, internal.E_DropRetentionPolicyCommand_Command,
+		&internal.DropRetentionPolicyCommand{
+			Database: proto.String(database),
+			Name:     proto.String(name),
+		},
+	)
+}
+
+// SetDefaultRetentionPolicy adds a command setting the default retention policy of database.
+func (b *Batch) SetDefaultRetentionPolicy(database, name string) {
+	b.add(internal.Command_SetDefaultRetentionPolicyCommand, internal.E_SetDefaultRetentionPolicyCommand_Command,
+		&internal.SetDefaultRetentionPolicyCommand{
+			Database: proto.String(database),
+			Name:     proto.String(name),
+		},
+	)
+}
+
+// CreateContinuousQuery adds a command creating a continuous query on database.
+func (b *Batch) CreateContinuousQuery(database, name, query string) {
+	b.add(internal.Command_CreateContinuousQueryCommand, internal.E_CreateContinuousQueryCommand_Command,
+		&internal.CreateContinuousQueryCommand{
+			Database: proto.String(database),
+			Name:     proto.String(name),
+			Query:    proto.String(query),
+		},
+	)
+}
+
+// DropContinuousQuery adds a command dropping a continuous query from database.
+func (b *Batch) DropContinuousQuery(database, name string) {
+	b.add(internal.Command_DropContinuousQueryCommand, internal.E_DropContinuousQueryCommand_Command,
+		&internal.DropContinuousQueryCommand{
+			Database: proto.String(database),
+			Name:     proto.String(name),
+		},
+	)
+}
+
+func (b *Batch) add(typ internal.Command_Type, desc *proto.ExtensionDesc, value interface{}) {
+	if b.err != nil {
+		return
+	}
+
+	cmd := &internal.Command{Type: &typ}
+	if err := proto.SetExtension(cmd, desc, value); err != nil {
+		b.setErr(err)
+		return
+	}
+	b.cmds = append(b.cmds, cmd)
+}
+
+func (b *Batch) setErr(err error) {
+	if b.err == nil {
+		b.err = err
+	}
+}
+
+// marshalRetentionPolicySpec validates spec and returns the encoded retention policy.
+func marshalRetentionPolicySpec(spec *meta.RetentionPolicySpec) ([]byte, error) {
+	if spec.Duration != nil && *spec.Duration < MinRetentionPolicyDuration && *spec.Duration != 0 {
+		return nil, ErrRetentionPolicyDurationTooLow
+	}
+	return spec.NewRetentionPolicyInfo().MarshalBinary()
+}
+
+// ExecuteBatch applies all commands in b atomically in a single raft round trip.
+func (c *Client) ExecuteBatch(b *Batch) error {
+	if b.err != nil {
+		return b.err
+	}
+	if len(b.cmds) == 0 {
+		return ErrBatchEmpty
+	}
+
+	cmd := &internal.BatchCommand{
+		Commands: b.cmds,
+	}
+
+	return c.retryUntilExec(internal.Command_BatchCommand, internal.E_BatchCommand_Command, cmd)
+}
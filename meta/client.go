@@ -54,6 +54,7 @@ type Client struct {
 	changed   chan struct{}
 	closing   chan struct{}
 	cacheData *Data
+	watchers  watchers
 
 	HTTPClient  *http.Client
 	metaServers []string
@@ -67,6 +68,11 @@ type Client struct {
 	nodeID uint64
 
 	config *Config
+
+	// ShardAssignment is the mode points are assigned to shards with in the
+	// shard groups created by this client. The default ShardAssignmentHash
+	// is used if empty.
+	ShardAssignment string
 }
 
 // NewClient returns a new *Client.
@@ -429,7 +435,16 @@ func (c *Client) RemoveShardOwner(id, nodeid uint64) error {
 		NodeID: proto.Uint64(nodeid),
 	}
 
-	return c.retryUntilExec(internal.Command_RemoveShardOwnerCommand, internal.E_AddShardOwnerCommand_Command, cmd)
+	return c.retryUntilExec(internal.Command_RemoveShardOwnerCommand, internal.E_RemoveShardOwnerCommand_Command, cmd)
+}
+
+// TruncateShardGroups ends all current shard groups at t.
+func (c *Client) TruncateShardGroups(t time.Time) error {
+	cmd := &internal.TruncateShardGroupCommand{
+		TruncateAt: proto.Int64(t.UnixNano()),
+	}
+
+	return c.retryUntilExec(internal.Command_TruncateShardGroupsCommand, internal.E_TruncateShardGroupCommand_Command, cmd)
 }
 
 // UpdateDataNode updates data node info according nodeID.
@@ -487,6 +502,39 @@ func (c *Client) DeleteDataNode(id uint64) error {
 	return c.retryUntilExec(internal.Command_DeleteDataNodeCommand, internal.E_DeleteDataNodeCommand_Command, cmd)
 }
 
+// SetDataNodeMaintenance puts the data node id into maintenance mode, or takes
+// it out of it.
+func (c *Client) SetDataNodeMaintenance(id uint64, maintenance bool) error {
+	cmd := &internal.SetDataNodeMaintenanceCommand{
+		ID:          proto.Uint64(id),
+		Maintenance: proto.Bool(maintenance),
+	}
+
+	return c.retryUntilExec(internal.Command_SetDataNodeMaintenanceCommand, internal.E_SetDataNodeMaintenanceCommand_Command, cmd)
+}
+
+// MaintenanceNodes returns the IDs of the data nodes in maintenance mode.
+func (c *Client) MaintenanceNodes() []uint64 {
+	var ids []uint64
+	for _, n := range c.data().DataNodes {
+		if n.Maintenance {
+			ids = append(ids, n.ID)
+		}
+	}
+	return ids
+}
+
+// ReplaceDataNode hands the shards owned by the data node oldID over to the
+// data node newID, and removes oldID, in a single command.
+func (c *Client) ReplaceDataNode(oldID, newID uint64) error {
+	cmd := &internal.ReplaceDataNodeCommand{
+		OldID: proto.Uint64(oldID),
+		NewID: proto.Uint64(newID),
+	}
+
+	return c.retryUntilExec(internal.Command_ReplaceDataNodeCommand, internal.E_ReplaceDataNodeCommand_Command, cmd)
+}
+
 // MetaNodes returns the meta nodes' info.
 func (c *Client) MetaNodes() (NodeInfos, error) {
 	return c.data().MetaNodes, nil
@@ -765,6 +813,14 @@ func (c *Client) ShardsByTimeRange(sources influxql.Sources, tmin, tmax time.Tim
 
 // CreateShardGroup creates a shard group on a database and policy for a given timestamp.
 func (c *Client) CreateShardGroup(database, policy string, timestamp time.Time) (*meta.ShardGroupInfo, error) {
+	return c.CreateShardGroupWithShards(database, policy, timestamp, 0)
+}
+
+// CreateShardGroupWithShards creates a shard group on a database and policy
+// for a given timestamp with shardN shards, e.g. to restore the shards of a
+// backup. If shardN is zero, the shard count is determined by the data nodes.
+// An existing shard group is returned as is.
+func (c *Client) CreateShardGroupWithShards(database, policy string, timestamp time.Time, shardN int) (*meta.ShardGroupInfo, error) {
 	if sg, _ := c.data().Data.ShardGroupByTimestamp(database, policy, timestamp); sg != nil {
 		return sg, nil
 	}
@@ -774,6 +830,12 @@ func (c *Client) CreateShardGroup(database, policy string, timestamp time.Time)
 		Policy:    proto.String(policy),
 		Timestamp: proto.Int64(timestamp.UnixNano()),
 	}
+	if c.ShardAssignment != "" {
+		cmd.ShardAssignment = proto.String(c.ShardAssignment)
+	}
+	if shardN > 0 {
+		cmd.ShardN = proto.Uint32(uint32(shardN))
+	}
 
 	if err := c.retryUntilExec(internal.Command_CreateShardGroupCommand, internal.E_CreateShardGroupCommand_Command, cmd); err != nil {
 		return nil, err
@@ -789,6 +851,12 @@ func (c *Client) CreateShardGroup(database, policy string, timestamp time.Time)
 	return rpi.ShardGroupByTimestamp(timestamp), nil
 }
 
+// ShardGroupAssignment returns the mode points are assigned to the shards of
+// a shard group with.
+func (c *Client) ShardGroupAssignment(shardGroupID uint64) string {
+	return c.data().ShardAssignment(shardGroupID)
+}
+
 // DeleteShardGroup removes a shard group from a database and retention policy by id.
 func (c *Client) DeleteShardGroup(database, policy string, id uint64) error {
 	cmd := &internal.DeleteShardGroupCommand{
@@ -1020,6 +1088,14 @@ func (c *Client) WaitForDataChanged() chan struct{} {
 	return c.changed
 }
 
+// Watch returns a channel receiving the changes to the meta data from now on,
+// buffering up to n of them, and a function ending the watch. Changes are
+// sent once the data the client returns includes them, so that caches
+// invalidated by a change are not filled again from older data.
+func (c *Client) Watch(n int) (<-chan Change, func()) {
+	return c.watchers.subscribe(n)
+}
+
 // MarshalBinary marshals data into a bianry form.
 func (c *Client) MarshalBinary() ([]byte, error) {
 	c.mu.RLock()
@@ -1179,13 +1255,18 @@ func (c *Client) pollForUpdates() {
 
 		// update the data and notify of the change
 		c.mu.Lock()
-		idx := c.cacheData.Data.Index
+		prev := c.cacheData
+		idx := prev.Data.Index
 		c.cacheData = data
 		if idx < data.Data.Index {
 			close(c.changed)
 			c.changed = make(chan struct{})
 		}
 		c.mu.Unlock()
+
+		if idx < data.Data.Index {
+			c.watchers.publish(data.Data.Index, Diff(prev, data))
+		}
 	}
 }
 
@@ -62,7 +62,16 @@ type Config struct {
 	BindAddress string `toml:"bind-address"`
 
 	// HTTPBindAddress is the bind address for the metaservice HTTP API
-	HTTPBindAddress  string `toml:"http-bind-address"`
+	HTTPBindAddress string `toml:"http-bind-address"`
+
+	// AdvertiseAddress and HTTPAdvertiseAddress are the addresses other nodes
+	// reach the raft and HTTP listeners at, when they differ from the bind
+	// addresses, e.g. behind NAT in Docker or Kubernetes. They are the
+	// addresses registered in the meta store and sent when joining. The
+	// hostname and the port bound are used if empty.
+	AdvertiseAddress     string `toml:"advertise-address"`
+	HTTPAdvertiseAddress string `toml:"http-advertise-address"`
+
 	HTTPSEnabled     bool   `toml:"https-enabled"`
 	HTTPSCertificate string `toml:"https-certificate"`
 	// JoinPeers if specified gives other metastore servers to join this server to the cluster
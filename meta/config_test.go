@@ -21,6 +21,8 @@ leader-lease-timeout = "30h"
 commit-timeout = "40m"
 raft-promotion-enabled = false
 logging-enabled = false
+advertise-address = "meta0.example.com:8088"
+http-advertise-address = "meta0.example.com:8091"
 `, &c); err != nil {
 		t.Fatal(err)
 	}
@@ -42,5 +44,9 @@ logging-enabled = false
 		t.Fatalf("unexpected raft promotion enabled: %v", c.RaftPromotionEnabled)
 	} else if c.LoggingEnabled {
 		t.Fatalf("unexpected logging enabled: %v", c.LoggingEnabled)
+	} else if c.AdvertiseAddress != "meta0.example.com:8088" {
+		t.Fatalf("unexpected advertise address: %s", c.AdvertiseAddress)
+	} else if c.HTTPAdvertiseAddress != "meta0.example.com:8091" {
+		t.Fatalf("unexpected http advertise address: %s", c.HTTPAdvertiseAddress)
 	}
 }
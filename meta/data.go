@@ -27,6 +27,18 @@ const (
 	MinRetentionPolicyDuration = time.Hour
 )
 
+// Modes of assigning points to the shards of a shard group.
+const (
+	// ShardAssignmentHash assigns a point to the shard at its series hash
+	// modulo the number of shards. Shard groups without a mode use it.
+	ShardAssignmentHash = "hash"
+
+	// ShardAssignmentJump assigns a point with a jump consistent hash of its
+	// series, so that few series move to a different shard when later shard
+	// groups have more shards.
+	ShardAssignmentJump = "jump"
+)
+
 // Data represents the top level collection of all metadata.
 type Data struct {
 	// This is coupled with influxdb's implementation, but the structure is pretty
@@ -36,6 +48,10 @@ type Data struct {
 	DataNodes NodeInfos
 	MaxNodeID uint64
 	ClusterID uint64
+
+	// ShardAssignments holds the mode of shard groups created with a mode
+	// other than ShardAssignmentHash, keyed by shard group ID.
+	ShardAssignments map[uint64]string
 }
 
 // Clone returns a copy of data with a new version.
@@ -61,6 +77,13 @@ func (data *Data) Clone() *Data {
 		}
 	}
 
+	if data.ShardAssignments != nil {
+		other.ShardAssignments = make(map[uint64]string, len(data.ShardAssignments))
+		for id, mode := range data.ShardAssignments {
+			other.ShardAssignments[id] = mode
+		}
+	}
+
 	return &other
 }
 
@@ -77,6 +100,11 @@ type NodeInfo struct {
 	Host               string
 	TCPHost            string
 	PendingShardOwners uint64arr
+
+	// Maintenance is set on data nodes taken down for planned maintenance.
+	// Writes to them are queued in hinted handoff instead of being sent,
+	// and queries read from their shards' other owners.
+	Maintenance bool
 }
 
 // clone returns a deep copy of ni.
@@ -92,6 +120,9 @@ func (ni NodeInfo) marshal() *internal.NodeInfo {
 	for _, pso := range ni.PendingShardOwners {
 		pb.PendingShardOwners = append(pb.PendingShardOwners, *proto.Uint64(pso))
 	}
+	if ni.Maintenance {
+		pb.Maintenance = proto.Bool(true)
+	}
 	return pb
 }
 
@@ -101,6 +132,7 @@ func (ni *NodeInfo) unmarshal(pb *internal.NodeInfo) {
 	ni.Host = pb.GetHost()
 	ni.TCPHost = pb.GetTCPHost()
 	ni.PendingShardOwners = pb.GetPendingShardOwners()
+	ni.Maintenance = pb.GetMaintenance()
 }
 
 // MetaNode return meta node info according to nodeID
@@ -410,6 +442,74 @@ func (data *Data) DeleteDataNode(id uint64) error {
 	return nil
 }
 
+// SetDataNodeMaintenance puts the data node id into maintenance mode, or takes
+// it out of it.
+func (data *Data) SetDataNodeMaintenance(id uint64, maintenance bool) error {
+	n := data.DataNode(id)
+	if n == nil {
+		return ErrNodeNotFound
+	}
+	n.Maintenance = maintenance
+	return nil
+}
+
+// ReplaceDataNode hands every shard owned by the data node oldID over to the
+// data node newID, and removes oldID from the cluster. Shards already owned by
+// newID are left with one fewer owner.
+func (data *Data) ReplaceDataNode(oldID, newID uint64) error {
+	if oldID == newID {
+		return fmt.Errorf("data node %d cannot replace itself", oldID)
+	} else if data.DataNode(newID) == nil {
+		return ErrNodeNotFound
+	}
+
+	var nodes []NodeInfo
+	for _, n := range data.DataNodes {
+		if n.ID != oldID {
+			nodes = append(nodes, n)
+		}
+	}
+	if len(nodes) == len(data.DataNodes) {
+		return ErrNodeNotFound
+	}
+	data.DataNodes = nodes
+
+	for di := range data.Databases {
+		rps := data.Databases[di].RetentionPolicies
+		for ri := range rps {
+			sgs := rps[ri].ShardGroups
+			for sgi := range sgs {
+				shards := sgs[sgi].Shards
+				for si := range shards {
+					shards[si].Owners = replaceShardOwner(shards[si].Owners, oldID, newID)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// replaceShardOwner returns owners with oldID replaced by newID, or removed
+// if newID is already an owner.
+func replaceShardOwner(owners []meta.ShardOwner, oldID, newID uint64) []meta.ShardOwner {
+	idx, owned := -1, false
+	for i, o := range owners {
+		if o.NodeID == oldID {
+			idx = i
+		} else if o.NodeID == newID {
+			owned = true
+		}
+	}
+
+	if idx == -1 {
+		return owners
+	} else if owned {
+		return append(owners[:idx:idx], owners[idx+1:]...)
+	}
+	owners[idx].NodeID = newID
+	return owners
+}
+
 // MarshalBinary marshals data into a binary form.
 func (data *Data) MarshalBinary() ([]byte, error) {
 	return proto.Marshal(data.marshal())
@@ -433,6 +533,18 @@ func (data *Data) marshal() *internal.ClusterData {
 		pb.DataNodes[i] = data.DataNodes[i].marshal()
 	}
 
+	ids := make([]uint64, 0, len(data.ShardAssignments))
+	for id := range data.ShardAssignments {
+		ids = append(ids, id)
+	}
+	sort.Sort(uint64Slice(ids))
+	for _, id := range ids {
+		pb.ShardAssignments = append(pb.ShardAssignments, &internal.ShardGroupAssignment{
+			ShardGroupID: proto.Uint64(id),
+			Mode:         proto.String(data.ShardAssignments[id]),
+		})
+	}
+
 	return pb
 }
 
@@ -461,10 +573,32 @@ func (data *Data) unmarshal(pb *internal.ClusterData) {
 		data.DataNodes[i].unmarshal(d)
 	}
 
+	data.ShardAssignments = nil
+	for _, a := range pb.GetShardAssignments() {
+		if data.ShardAssignments == nil {
+			data.ShardAssignments = make(map[uint64]string)
+		}
+		data.ShardAssignments[a.GetShardGroupID()] = a.GetMode()
+	}
+}
+
+// CreateShardGroup creates a shard group on a database and policy for a given
+// timestamp. Points are assigned to the shards of the group with the given
+// mode, or ShardAssignmentHash if it is empty.
+func (data *Data) CreateShardGroup(database, policy string, timestamp time.Time, assignment string) error {
+	return data.CreateShardGroupWithShards(database, policy, timestamp, assignment, 0)
 }
 
-// CreateShardGroup creates a shard group on a database and policy for a given timestamp.
-func (data *Data) CreateShardGroup(database, policy string, timestamp time.Time) error {
+// CreateShardGroupWithShards creates a shard group like CreateShardGroup, but
+// with shardN shards spread across the data nodes instead of one shard per
+// replica set. If shardN is zero, the shard count is determined by the nodes.
+func (data *Data) CreateShardGroupWithShards(database, policy string, timestamp time.Time, assignment string, shardN int) error {
+	switch assignment {
+	case "", ShardAssignmentHash, ShardAssignmentJump:
+	default:
+		return fmt.Errorf("unknown shard assignment: %q", assignment)
+	}
+
 	// Ensure there are nodes in the metadata.
 	if len(data.DataNodes) == 0 {
 		return nil
@@ -494,7 +628,9 @@ func (data *Data) CreateShardGroup(database, policy string, timestamp time.Time)
 	// Determine shard count by node count divided by replication factor.
 	// This will ensure nodes will get distributed across nodes evenly and
 	// replicated the correct number of times.
-	shardN := len(data.DataNodes) / replicaN
+	if shardN <= 0 {
+		shardN = len(data.DataNodes) / replicaN
+	}
 
 	// Create the shard group.
 	data.Data.MaxShardGroupID++
@@ -512,9 +648,25 @@ func (data *Data) CreateShardGroup(database, policy string, timestamp time.Time)
 	rpi.ShardGroups = append(rpi.ShardGroups, sgi)
 	sort.Sort(meta.ShardGroupInfos(rpi.ShardGroups))
 
+	if assignment != "" && assignment != ShardAssignmentHash {
+		if data.ShardAssignments == nil {
+			data.ShardAssignments = make(map[uint64]string)
+		}
+		data.ShardAssignments[sgi.ID] = assignment
+	}
+
 	return nil
 }
 
+// ShardAssignment returns the mode points are assigned to the shards of
+// shard group id with.
+func (data *Data) ShardAssignment(id uint64) string {
+	if mode, ok := data.ShardAssignments[id]; ok {
+		return mode
+	}
+	return ShardAssignmentHash
+}
+
 func (data *Data) gcd() {
 
 }
@@ -537,9 +689,30 @@ func (data *Data) generatedShards(sgi *meta.ShardGroupInfo, shardN, replicaN int
 	}
 }
 
-// TruncateShardsGrops truncates shardsGroup according to shardsGroupID
-func (data *Data) TruncateShardsGrops(sg *meta.ShardGroupInfo) error {
-	return nil
+// TruncateShardGroups ends every shard group that is still open at t so that
+// writes after t are placed in newly created shard groups. Shard groups that
+// have not started by t are truncated at their start time.
+func (data *Data) TruncateShardGroups(t time.Time) {
+	for i := range data.Data.Databases {
+		dbi := &data.Data.Databases[i]
+		for j := range dbi.RetentionPolicies {
+			rpi := &dbi.RetentionPolicies[j]
+			for k := range rpi.ShardGroups {
+				sgi := &rpi.ShardGroups[k]
+				if !t.Before(sgi.EndTime) || sgi.Deleted() || (sgi.Truncated() && sgi.TruncatedAt.Before(t)) {
+					continue
+				}
+
+				if !t.After(sgi.StartTime) {
+					// This shard group hasn't started yet, so it is truncated
+					// at its start and no writes will ever land in it.
+					sgi.TruncatedAt = sgi.StartTime
+				} else {
+					sgi.TruncatedAt = t
+				}
+			}
+		}
+	}
 }
 
 // AddPendingShardOwner adds a pending shardOwner according to nodeID.
@@ -595,56 +768,80 @@ func (data *Data) ShardLocation(shardID uint64) (*meta.ShardInfo, error) {
 	return nil, fmt.Errorf("failed to find shards assoicated with %d", shardID)
 }
 
+// shard returns the shard shardID, or nil if it does not exist. The shard
+// is returned in place so that it can be updated.
+func (data *Data) shard(shardID uint64) *meta.ShardInfo {
+	for i := range data.Data.Databases {
+		for j := range data.Data.Databases[i].RetentionPolicies {
+			rpi := &data.Data.Databases[i].RetentionPolicies[j]
+			for k := range rpi.ShardGroups {
+				for l := range rpi.ShardGroups[k].Shards {
+					if si := &rpi.ShardGroups[k].Shards[l]; si.ID == shardID {
+						return si
+					}
+				}
+			}
+		}
+	}
+	return nil
+}
+
 // UpdateShard will update ShardOwner of a Shard according to ShardID
 func (data *Data) UpdateShard(shardID uint64, newOwners []meta.ShardOwner) error {
-	return fmt.Errorf("Failed to find Shard assoicated with shard ID %d", shardID)
+	si := data.shard(shardID)
+	if si == nil {
+		return ErrShardNotFound
+	}
+	si.Owners = newOwners
+	return nil
 }
 
-// AddShardOwner will update a shards labelled by shardID in this node if such shards ownby this newly adding node
+// AddShardOwner adds the data node nodeID to the owners of the shard shardID.
+// Adding an existing owner is a no-op.
 func (data *Data) AddShardOwner(shardID, nodeID uint64) error {
-	si, err := data.ShardLocation(shardID)
-	if err == nil {
-		if !si.OwnedBy(nodeID) {
-			if nodeID > data.MaxNodeID {
-				return nil
-			}
-			o := ShardOwners{}
-			o = append(o, meta.ShardOwner{NodeID: nodeID})
-			sort.Sort(o)
-			return data.UpdateShard(shardID, o)
-		}
+	si := data.shard(shardID)
+	if si == nil {
+		return ErrShardNotFound
+	} else if data.DataNode(nodeID) == nil {
+		return ErrNodeNotFound
+	} else if si.OwnedBy(nodeID) {
+		return nil
 	}
-	return err
+
+	o := append(ShardOwners{}, si.Owners...)
+	o = append(o, meta.ShardOwner{NodeID: nodeID})
+	sort.Sort(o)
+	return data.UpdateShard(shardID, o)
 }
 
-// RemoveShardOwner will remove all shards in this node if such shard owned by this node
+// RemoveShardOwner removes the data node nodeID from the owners of the shard
+// shardID. The last owner of a shard is never removed.
 func (data *Data) RemoveShardOwner(shardID, nodeID uint64) error {
-	si, err := data.ShardLocation(shardID)
+	si := data.shard(shardID)
+	if si == nil {
+		return ErrShardNotFound
+	} else if !si.OwnedBy(nodeID) {
+		return nil
+	} else if len(si.Owners) == 1 {
+		return ErrShardNotReplicated
+	}
+
+	o, err := data.PruneShard(si, nodeID)
 	if err != nil {
-		if si.OwnedBy(nodeID) {
-			o, _ := data.PruneShard(si, nodeID)
-			data.UpdateShard(shardID, o)
-		}
+		return err
 	}
-	return err
+	return data.UpdateShard(shardID, o)
 }
 
-// PruneShard prunes shard according to nodeID.
+// PruneShard returns the owners of si without nodeID. si is left unchanged.
 func (data *Data) PruneShard(si *meta.ShardInfo, nodeID uint64) ([]meta.ShardOwner, error) {
-	found := -1
 	for i, o := range si.Owners {
 		if o.NodeID == nodeID {
-			found = i
-			break
+			owners := make([]meta.ShardOwner, 0, len(si.Owners)-1)
+			owners = append(owners, si.Owners[:i]...)
+			return append(owners, si.Owners[i+1:]...), nil
 		}
 	}
-
-	if found != -1 {
-		copy(si.Owners[found:], si.Owners[found+1:])
-		// si.Owners[len(si.Owners)-1] = nil
-		// si.Owners = si.Owners[:len(si.Owners)-1]
-		return si.Owners, nil
-	}
 	return nil, fmt.Errorf("failed to find shard owner %d", nodeID)
 }
 
@@ -3,6 +3,7 @@ package meta
 import (
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/influxdata/influxdb/services/meta"
 )
@@ -30,3 +31,201 @@ func testnewShardOwner(t *testing.T) {
 		t.Errorf("got owner frequencies %v, expected %v", got, exp)
 	}
 }
+
+func TestData_TruncateShardGroups(t *testing.T) {
+	start := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+	data := &Data{Data: &meta.Data{
+		Databases: []meta.DatabaseInfo{{
+			Name: "db0",
+			RetentionPolicies: []meta.RetentionPolicyInfo{{
+				Name: "rp0",
+				ShardGroups: []meta.ShardGroupInfo{
+					{ID: 1, StartTime: start, EndTime: start.Add(time.Hour)},
+					{ID: 2, StartTime: start.Add(time.Hour), EndTime: start.Add(2 * time.Hour)},
+					{ID: 3, StartTime: start.Add(2 * time.Hour), EndTime: start.Add(3 * time.Hour)},
+				},
+			}},
+		}},
+	}}
+
+	// Truncate in the middle of the second shard group.
+	at := start.Add(90 * time.Minute)
+	data.TruncateShardGroups(at)
+
+	sgs := data.Data.Databases[0].RetentionPolicies[0].ShardGroups
+	if sgs[0].Truncated() {
+		t.Errorf("shard group 1 truncated at %s, expected it to be left alone", sgs[0].TruncatedAt)
+	}
+	if got, exp := sgs[1].TruncatedAt, at; !got.Equal(exp) {
+		t.Errorf("got shard group 2 truncated at %s, expected %s", got, exp)
+	}
+	if got, exp := sgs[2].TruncatedAt, sgs[2].StartTime; !got.Equal(exp) {
+		t.Errorf("got shard group 3 truncated at %s, expected %s", got, exp)
+	}
+
+	// A later truncation does not extend an earlier one.
+	data.TruncateShardGroups(start.Add(100 * time.Minute))
+	if got, exp := sgs[1].TruncatedAt, at; !got.Equal(exp) {
+		t.Errorf("got shard group 2 truncated at %s, expected %s", got, exp)
+	}
+}
+
+func TestData_CreateShardGroup_Assignment(t *testing.T) {
+	data := &Data{
+		Data: &meta.Data{
+			Databases: []meta.DatabaseInfo{{
+				Name: "db0",
+				RetentionPolicies: []meta.RetentionPolicyInfo{{
+					Name:               "rp0",
+					ReplicaN:           1,
+					ShardGroupDuration: time.Hour,
+				}},
+			}},
+		},
+		DataNodes: NodeInfos{{ID: 1}, {ID: 2}},
+	}
+
+	start := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+	if err := data.CreateShardGroup("db0", "rp0", start, ""); err != nil {
+		t.Fatal(err)
+	} else if err := data.CreateShardGroup("db0", "rp0", start.Add(time.Hour), ShardAssignmentJump); err != nil {
+		t.Fatal(err)
+	} else if err := data.CreateShardGroup("db0", "rp0", start.Add(2*time.Hour), "round-robin"); err == nil {
+		t.Fatal("expected unknown shard assignment to be rejected")
+	}
+
+	// The mode of each shard group survives a round trip through the store.
+	buf, err := data.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var other Data
+	if err := other.UnmarshalBinary(buf); err != nil {
+		t.Fatal(err)
+	}
+
+	sgs := other.Data.Databases[0].RetentionPolicies[0].ShardGroups
+	if len(sgs) != 2 {
+		t.Fatalf("got %d shard groups, expected 2", len(sgs))
+	}
+	if got, exp := other.ShardAssignment(sgs[0].ID), ShardAssignmentHash; got != exp {
+		t.Errorf("got shard group 1 assignment %q, expected %q", got, exp)
+	}
+	if got, exp := other.ShardAssignment(sgs[1].ID), ShardAssignmentJump; got != exp {
+		t.Errorf("got shard group 2 assignment %q, expected %q", got, exp)
+	}
+}
+
+func TestData_CreateShardGroupWithShards(t *testing.T) {
+	data := &Data{
+		Data: &meta.Data{
+			Databases: []meta.DatabaseInfo{{
+				Name: "db0",
+				RetentionPolicies: []meta.RetentionPolicyInfo{{
+					Name:               "rp0",
+					ReplicaN:           3,
+					ShardGroupDuration: time.Hour,
+				}},
+			}},
+		},
+		DataNodes: NodeInfos{{ID: 1}, {ID: 2}},
+	}
+
+	// The shards are spread across the nodes, with no more replicas than
+	// nodes.
+	start := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+	if err := data.CreateShardGroupWithShards("db0", "rp0", start, "", 3); err != nil {
+		t.Fatal(err)
+	}
+
+	sgs := data.Data.Databases[0].RetentionPolicies[0].ShardGroups
+	if len(sgs) != 1 {
+		t.Fatalf("got %d shard groups, expected 1", len(sgs))
+	} else if len(sgs[0].Shards) != 3 {
+		t.Fatalf("got %d shards, expected 3", len(sgs[0].Shards))
+	}
+	for _, sh := range sgs[0].Shards {
+		if len(sh.Owners) != 2 || sh.Owners[0].NodeID == sh.Owners[1].NodeID {
+			t.Errorf("unexpected owners of shard %d: %v", sh.ID, sh.Owners)
+		}
+	}
+}
+
+func TestData_ReplaceDataNode(t *testing.T) {
+	data := &Data{
+		Data: &meta.Data{
+			Databases: []meta.DatabaseInfo{{
+				Name: "db0",
+				RetentionPolicies: []meta.RetentionPolicyInfo{{
+					Name: "rp0",
+					ShardGroups: []meta.ShardGroupInfo{{
+						ID: 1,
+						Shards: []meta.ShardInfo{
+							{ID: 1, Owners: []meta.ShardOwner{{NodeID: 1}, {NodeID: 2}}},
+							{ID: 2, Owners: []meta.ShardOwner{{NodeID: 1}, {NodeID: 3}}},
+							{ID: 3, Owners: []meta.ShardOwner{{NodeID: 2}, {NodeID: 3}}},
+						},
+					}},
+				}},
+			}},
+		},
+		DataNodes: NodeInfos{{ID: 1}, {ID: 2}, {ID: 3}},
+	}
+
+	if err := data.ReplaceDataNode(1, 4); err != ErrNodeNotFound {
+		t.Fatalf("unexpected error: %v", err)
+	} else if err := data.ReplaceDataNode(4, 3); err != ErrNodeNotFound {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Node 3 takes over the shards of node 1, and node 1 is removed.
+	if err := data.ReplaceDataNode(1, 3); err != nil {
+		t.Fatal(err)
+	}
+	if got, exp := data.DataNodes, (NodeInfos{{ID: 2}, {ID: 3}}); !reflect.DeepEqual(got, exp) {
+		t.Errorf("got data nodes %v, expected %v", got, exp)
+	}
+
+	shards := data.Data.Databases[0].RetentionPolicies[0].ShardGroups[0].Shards
+	exp := [][]meta.ShardOwner{
+		{{NodeID: 3}, {NodeID: 2}},
+		{{NodeID: 3}},
+		{{NodeID: 2}, {NodeID: 3}},
+	}
+	for i, sh := range shards {
+		if !reflect.DeepEqual(sh.Owners, exp[i]) {
+			t.Errorf("got owners of shard %d %v, expected %v", sh.ID, sh.Owners, exp[i])
+		}
+	}
+}
+
+func TestData_SetDataNodeMaintenance(t *testing.T) {
+	data := &Data{
+		Data:      &meta.Data{},
+		DataNodes: NodeInfos{{ID: 1}, {ID: 2}},
+	}
+
+	if err := data.SetDataNodeMaintenance(3, true); err != ErrNodeNotFound {
+		t.Fatalf("unexpected error: %v", err)
+	} else if err := data.SetDataNodeMaintenance(2, true); err != nil {
+		t.Fatal(err)
+	}
+
+	// The flag survives a round trip through the store's snapshot format.
+	buf, err := data.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var other Data
+	if err := other.UnmarshalBinary(buf); err != nil {
+		t.Fatal(err)
+	} else if other.DataNodes[0].Maintenance || !other.DataNodes[1].Maintenance {
+		t.Fatalf("unexpected data nodes: %+v", other.DataNodes)
+	}
+
+	if err := other.SetDataNodeMaintenance(2, false); err != nil {
+		t.Fatal(err)
+	} else if other.DataNodes[1].Maintenance {
+		t.Fatal("expected node 2 to be out of maintenance")
+	}
+}
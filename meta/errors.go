@@ -79,6 +79,9 @@ var (
 	// ErrShardGroupNotFound is returned when mutating a shard group that doesn't exist.
 	ErrShardGroupNotFound = errors.New("shard group not found")
 
+	// ErrShardNotFound is returned when mutating a shard that doesn't exist.
+	ErrShardNotFound = errors.New("shard not found")
+
 	// ErrShardNotReplicated is returned if the node requested to be dropped has
 	// the last copy of a shard present and the force keyword was not used
 	ErrShardNotReplicated = errors.New("shard not replicated")
@@ -113,3 +116,11 @@ var (
 	// ErrAuthenticate is returned when authentication fails.
 	ErrAuthenticate = errors.New("authentication failed")
 )
+
+var (
+	// ErrBatchEmpty is returned when executing a batch with no commands.
+	ErrBatchEmpty = errors.New("batch contains no commands")
+
+	// ErrNestedBatch is returned when a batch contains another batch.
+	ErrNestedBatch = errors.New("batch commands cannot be nested")
+)
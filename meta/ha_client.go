@@ -0,0 +1,559 @@
+package meta
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/influxdata/influxdb/services/meta"
+	"github.com/zhexuany/influxcloud/meta/internal"
+)
+
+const (
+	// DefaultHACacheTTL is the default time an HAClient answers read-only
+	// calls from a snapshot before fetching a new one.
+	DefaultHACacheTTL = time.Second
+
+	// DefaultHAMaxStale is the default time an HAClient answers read-only
+	// calls from the last snapshot fetched while no meta node can be reached.
+	DefaultHAMaxStale = time.Minute
+
+	// maxRedirects is the number of redirects to the leader followed for a
+	// single command.
+	maxRedirects = 3
+
+	// watchRetryInterval is the time a watch waits before streaming changes
+	// from the meta nodes again once none of them could be streamed from.
+	watchRetryInterval = time.Second
+)
+
+// HAClient is a client of the meta service for data nodes that is aware of
+// every meta node. Commands are sent to the last known leader first, which is
+// relearned from the redirects of the other meta nodes once it changes. A
+// command that fails is retried on another meta node if it was never sent, or
+// if applying it twice is harmless.
+//
+// Read-only calls are answered from a snapshot fetched at most CacheTTL ago.
+// While no meta node can be reached, the last snapshot fetched is used for up
+// to MaxStale so that data nodes ride out brief meta outages.
+type HAClient struct {
+	mu       sync.RWMutex
+	servers  []string  // HTTP addresses of the meta nodes
+	leader   string    // HTTP address of the last known leader
+	data     *Data     // last snapshot fetched
+	fetched  time.Time // when data was fetched
+	minIndex uint64    // index of the last command applied or watched by this client
+
+	CacheTTL time.Duration
+	MaxStale time.Duration
+	TLS      bool
+
+	HTTPClient *http.Client
+	Logger     *log.Logger
+}
+
+// NewHAClient returns a new HAClient of the meta nodes at the HTTP addresses
+// servers.
+func NewHAClient(servers []string) *HAClient {
+	return &HAClient{
+		servers:  append([]string(nil), servers...),
+		CacheTTL: DefaultHACacheTTL,
+		MaxStale: DefaultHAMaxStale,
+		HTTPClient: &http.Client{
+			Timeout: 10 * time.Second,
+			// Redirects are followed by the client itself, so that it
+			// learns the leader from them.
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		Logger: log.New(os.Stderr, "[metaclient] ", log.LstdFlags),
+	}
+}
+
+// MetaServers returns the HTTP addresses of the meta nodes.
+func (c *HAClient) MetaServers() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return append([]string(nil), c.servers...)
+}
+
+// SetMetaServers sets the HTTP addresses of the meta nodes.
+func (c *HAClient) SetMetaServers(a []string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.servers = append([]string(nil), a...)
+}
+
+// Leader returns the HTTP address of the last known leader, or an empty
+// string if it is not known.
+func (c *HAClient) Leader() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.leader
+}
+
+// Data returns a snapshot of the meta data. It is the last snapshot fetched
+// if no meta node can be reached and the snapshot is at most MaxStale old.
+func (c *HAClient) Data() (*Data, error) {
+	c.mu.RLock()
+	data, fetched, minIndex := c.data, c.fetched, c.minIndex
+	c.mu.RUnlock()
+
+	if data != nil && time.Since(fetched) < c.CacheTTL && data.Data.Index >= minIndex {
+		return data, nil
+	}
+
+	fresh, err := c.fetch(minIndex)
+	if err == nil {
+		return fresh, nil
+	} else if data != nil && time.Since(fetched) < c.MaxStale {
+		c.Logger.Printf("using meta data fetched %s ago: %s", time.Since(fetched), err)
+		return data, nil
+	}
+	return nil, err
+}
+
+// fetch fetches a snapshot, preferring meta nodes that have applied the
+// commands at least up to minIndex, and caches it.
+func (c *HAClient) fetch(minIndex uint64) (*Data, error) {
+	var data *Data
+	err := ErrServiceUnavailable
+	for _, server := range c.order() {
+		d, e := c.snapshot(server)
+		if e != nil {
+			err = e
+			continue
+		}
+		if data == nil || d.Data.Index > data.Data.Index {
+			data = d
+		}
+		if d.Data.Index >= minIndex {
+			break
+		}
+	}
+	if data == nil {
+		return nil, fmt.Errorf("%s: %s", ErrServiceUnavailable, err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.data, c.fetched = data, time.Now()
+
+	// Meta nodes that joined or left are tracked from the meta data.
+	if len(data.MetaNodes) > 0 {
+		servers := make([]string, 0, len(data.MetaNodes))
+		for _, n := range data.MetaNodes {
+			servers = append(servers, n.Host)
+		}
+		c.servers = servers
+	}
+	return data, nil
+}
+
+// snapshot fetches a snapshot from the meta node at server.
+func (c *HAClient) snapshot(server string) (*Data, error) {
+	resp, err := c.HTTPClient.Get(c.url(server) + "?index=0")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("meta server %s returned %s", server, resp.Status)
+	}
+
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	data := &Data{}
+	if err := data.UnmarshalBinary(b); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// Watch returns a channel receiving the changes to the meta data from now on,
+// pushed by the meta nodes, buffering up to n of them, and a function ending
+// the watch. Each change also makes the next read-only call fetch a snapshot
+// including it, rather than answer from a snapshot fetched less than
+// CacheTTL ago.
+func (c *HAClient) Watch(n int) (<-chan Change, func()) {
+	var ws watchers
+	ch, unsubscribe := ws.subscribe(n)
+
+	done := make(chan struct{})
+	go c.watch(&ws, done)
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			close(done)
+			unsubscribe()
+		})
+	}
+}
+
+// watch streams the changes to the meta data to ws until done is closed,
+// moving on to the next meta node whenever streaming from one fails.
+func (c *HAClient) watch(ws *watchers, done chan struct{}) {
+	var index uint64
+	c.mu.RLock()
+	if c.data != nil {
+		index = c.data.Data.Index
+	}
+	c.mu.RUnlock()
+
+	for {
+		for _, server := range c.order() {
+			var err error
+			index, err = c.streamChanges(server, index, ws, done)
+			select {
+			case <-done:
+				return
+			default:
+			}
+			c.Logger.Printf("unable to watch meta server %s: %s", server, err)
+		}
+
+		select {
+		case <-done:
+			return
+		case <-time.After(watchRetryInterval):
+		}
+	}
+}
+
+// streamChanges publishes the changes made after index that the meta node at
+// server streams, until the stream fails or done is closed. It returns the
+// index of the last change published.
+func (c *HAClient) streamChanges(server string, index uint64, ws *watchers, done chan struct{}) (uint64, error) {
+	req, err := http.NewRequest("GET", fmt.Sprintf("%s/watch?index=%d", c.url(server), index), nil)
+	if err != nil {
+		return index, err
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-done:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	// The stream lasts until the watch ends, so it is not subject to the
+	// timeout of HTTPClient.
+	client := &http.Client{Transport: c.HTTPClient.Transport}
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return index, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return index, fmt.Errorf("meta server %s returned %s", server, resp.Status)
+	}
+
+	dec := json.NewDecoder(resp.Body)
+	for {
+		var ch Change
+		if err := dec.Decode(&ch); err != nil {
+			return index, err
+		}
+
+		c.mu.Lock()
+		if ch.Index > c.minIndex {
+			c.minIndex = ch.Index
+		}
+		c.mu.Unlock()
+
+		ws.publish(ch.Index, []Change{ch})
+		index = ch.Index
+	}
+}
+
+// order returns the meta nodes in the order they are tried, the last known
+// leader first.
+func (c *HAClient) order() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	servers := make([]string, 0, len(c.servers)+1)
+	if c.leader != "" {
+		servers = append(servers, c.leader)
+	}
+	for _, s := range c.servers {
+		if s != c.leader {
+			servers = append(servers, s)
+		}
+	}
+	return servers
+}
+
+// exec applies a command, retrying it on the other meta nodes if it was never
+// sent. Commands that may have been applied are only retried if idempotent.
+func (c *HAClient) exec(typ internal.Command_Type, desc *proto.ExtensionDesc, value interface{}, idempotent bool) error {
+	cmd := &internal.Command{Type: &typ}
+	if err := proto.SetExtension(cmd, desc, value); err != nil {
+		panic(err)
+	}
+	b, err := proto.Marshal(cmd)
+	if err != nil {
+		return err
+	}
+
+	err = ErrServiceUnavailable
+	for _, server := range c.order() {
+		var index uint64
+		if server, index, err = c.execOn(server, b); err == nil {
+			c.mu.Lock()
+			c.leader = server
+			if index > c.minIndex {
+				c.minIndex = index
+			}
+			c.mu.Unlock()
+			return nil
+		} else if _, ok := err.(errCommand); ok {
+			return err
+		}
+
+		// The leader is relearned from the next meta node.
+		c.mu.Lock()
+		if c.leader == server {
+			c.leader = ""
+		}
+		c.mu.Unlock()
+
+		if !idempotent && !unsent(err) {
+			return err
+		}
+		c.Logger.Printf("unable to apply command on meta server %s: %s", server, err)
+	}
+	return err
+}
+
+// execOn sends a command to the meta node at server, following redirects to
+// the leader. It returns the meta node that applied the command and the index
+// it was applied at.
+func (c *HAClient) execOn(server string, b []byte) (string, uint64, error) {
+	for i := 0; ; i++ {
+		index, err := c.post(server, b)
+		if e, ok := err.(errRedirect); ok && i < maxRedirects {
+			u, perr := url.Parse(e.host)
+			if perr != nil {
+				return server, 0, perr
+			}
+			server = u.Host
+			continue
+		}
+		return server, index, err
+	}
+}
+
+// post sends a command to the meta node at server.
+func (c *HAClient) post(server string, b []byte) (uint64, error) {
+	resp, err := c.HTTPClient.Post(c.url(server)+"/execute", "application/octet-stream", bytes.NewReader(b))
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusTemporaryRedirect:
+		return 0, errRedirect{host: resp.Header.Get("Location")}
+	case http.StatusServiceUnavailable:
+		// The meta node has no leader, so the command was not applied.
+		return 0, errUnavailable{server: server}
+	default:
+		return 0, fmt.Errorf("meta server %s returned %s", server, resp.Status)
+	}
+
+	b, err = ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return 0, err
+	}
+	res := &internal.Response{}
+	if err := proto.Unmarshal(b, res); err != nil {
+		return 0, err
+	}
+	if es := res.GetError(); es != "" {
+		return 0, errCommand{msg: es}
+	}
+	return res.GetIndex(), nil
+}
+
+func (c *HAClient) url(server string) string {
+	if c.TLS {
+		return "https://" + server
+	}
+	return "http://" + server
+}
+
+// errUnavailable is returned by a meta node that cannot apply commands.
+type errUnavailable struct {
+	server string
+}
+
+func (e errUnavailable) Error() string {
+	return fmt.Sprintf("meta server %s has no leader", e.server)
+}
+
+// unsent returns true if err shows that a command never reached a meta node,
+// or was refused, so that it is safe to send to another meta node.
+func unsent(err error) bool {
+	switch err.(type) {
+	case errUnavailable, errRedirect:
+		return true
+	}
+	if e, ok := err.(*url.Error); ok {
+		err = e.Err
+	}
+	e, ok := err.(*net.OpError)
+	return ok && e.Op == "dial"
+}
+
+// DataNode returns a data node by id.
+func (c *HAClient) DataNode(id uint64) (*NodeInfo, error) {
+	data, err := c.Data()
+	if err != nil {
+		return nil, err
+	}
+	for _, n := range data.DataNodes {
+		if n.ID == id {
+			return &n, nil
+		}
+	}
+	return nil, ErrNodeNotFound
+}
+
+// DataNodes returns the data nodes' info.
+func (c *HAClient) DataNodes() (NodeInfos, error) {
+	data, err := c.Data()
+	if err != nil {
+		return nil, err
+	}
+	return data.DataNodes, nil
+}
+
+// DataNodeByTCPHost returns the data node with the given TCP address.
+func (c *HAClient) DataNodeByTCPHost(tcpAddr string) (*NodeInfo, error) {
+	nodes, err := c.DataNodes()
+	if err != nil {
+		return nil, err
+	}
+	for _, n := range nodes {
+		if n.TCPHost == tcpAddr {
+			return &n, nil
+		}
+	}
+	return nil, ErrNodeNotFound
+}
+
+// MetaNodes returns the meta nodes' info.
+func (c *HAClient) MetaNodes() (NodeInfos, error) {
+	data, err := c.Data()
+	if err != nil {
+		return nil, err
+	}
+	return data.MetaNodes, nil
+}
+
+// Database returns info for the requested database, or nil if it does not
+// exist.
+func (c *HAClient) Database(name string) (*meta.DatabaseInfo, error) {
+	data, err := c.Data()
+	if err != nil {
+		return nil, err
+	}
+	return data.Data.Database(name), nil
+}
+
+// Databases returns a list of all database infos.
+func (c *HAClient) Databases() ([]meta.DatabaseInfo, error) {
+	data, err := c.Data()
+	if err != nil {
+		return nil, err
+	}
+	if data.Data.Databases == nil {
+		return []meta.DatabaseInfo{}, nil
+	}
+	return data.Data.Databases, nil
+}
+
+// ShardOwner returns the database, retention policy and info of a shard. si
+// is nil if the shard does not exist.
+func (c *HAClient) ShardOwner(shardID uint64) (database, policy string, si *meta.ShardInfo, err error) {
+	data, err := c.Data()
+	if err != nil {
+		return "", "", nil, err
+	}
+	for _, dbi := range data.Data.Databases {
+		for _, rpi := range dbi.RetentionPolicies {
+			for _, g := range rpi.ShardGroups {
+				if g.Deleted() {
+					continue
+				}
+				for _, sh := range g.Shards {
+					if sh.ID == shardID {
+						return dbi.Name, rpi.Name, &sh, nil
+					}
+				}
+			}
+		}
+	}
+	return "", "", nil, nil
+}
+
+// TruncateShardGroups ends all current shard groups at t. Truncating again
+// at the same time changes nothing.
+func (c *HAClient) TruncateShardGroups(t time.Time) error {
+	cmd := &internal.TruncateShardGroupCommand{
+		TruncateAt: proto.Int64(t.UnixNano()),
+	}
+
+	return c.exec(internal.Command_TruncateShardGroupsCommand, internal.E_TruncateShardGroupCommand_Command, cmd, true)
+}
+
+// UpdateDataNode changes the addresses of a data node.
+func (c *HAClient) UpdateDataNode(id uint64, host, tcpHost string) error {
+	cmd := &internal.UpdateDataNodeCommand{
+		ID:      proto.Uint64(id),
+		Host:    proto.String(host),
+		TCPHost: proto.String(tcpHost),
+	}
+
+	return c.exec(internal.Command_UpdateDataNodeCommand, internal.E_UpdateDataNodeCommand_Command, cmd, true)
+}
+
+// DeleteDataNode deletes a data node from the cluster. It is not retried once
+// sent, as a second attempt fails if the first one was applied.
+func (c *HAClient) DeleteDataNode(id uint64) error {
+	cmd := &internal.DeleteDataNodeCommand{
+		ID: proto.Uint64(id),
+	}
+
+	return c.exec(internal.Command_DeleteDataNodeCommand, internal.E_DeleteDataNodeCommand_Command, cmd, false)
+}
+
+// ReplaceDataNode hands the shards owned by the data node oldID over to the
+// data node newID, and removes oldID. It is not retried once sent.
+func (c *HAClient) ReplaceDataNode(oldID, newID uint64) error {
+	cmd := &internal.ReplaceDataNodeCommand{
+		OldID: proto.Uint64(oldID),
+		NewID: proto.Uint64(newID),
+	}
+
+	return c.exec(internal.Command_ReplaceDataNodeCommand, internal.E_ReplaceDataNodeCommand_Command, cmd, false)
+}
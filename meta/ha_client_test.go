@@ -0,0 +1,190 @@
+package meta
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/influxdata/influxdb/services/meta"
+	"github.com/zhexuany/influxcloud/meta/internal"
+)
+
+// Ensure changes pushed by a meta node reach the watchers, and make the next
+// read fetch a snapshot including them.
+func TestHAClient_Watch(t *testing.T) {
+	node := newFakeMetaNode()
+	defer node.Close()
+	node.changes = []Change{{Type: ChangeDatabaseCreated, Index: 5, Database: "db0"}}
+
+	c := NewHAClient([]string{node.host()})
+	c.Logger = log.New(ioutil.Discard, "", 0)
+
+	ch, cancel := c.Watch(0)
+	select {
+	case got := <-ch:
+		if got != node.changes[0] {
+			t.Fatalf("unexpected change: %+v", got)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for change")
+	}
+
+	c.mu.RLock()
+	minIndex := c.minIndex
+	c.mu.RUnlock()
+	if minIndex != 5 {
+		t.Fatalf("got min index %d, expected 5", minIndex)
+	}
+
+	cancel()
+	if _, ok := <-ch; ok {
+		t.Fatal("expected channel to be closed")
+	}
+}
+
+func TestHAClient(t *testing.T) {
+	leader, follower := newFakeMetaNode(), newFakeMetaNode()
+	defer follower.Close()
+	follower.setRedirect(leader)
+
+	c := NewHAClient([]string{follower.host(), leader.host()})
+	c.Logger = log.New(ioutil.Discard, "", 0)
+
+	// The leader is learned from the redirect of the follower.
+	if err := c.UpdateDataNode(1, "host0:8086", "host0:8088"); err != nil {
+		t.Fatal(err)
+	} else if got, exp := c.Leader(), leader.host(); got != exp {
+		t.Fatalf("got leader %s, expected %s", got, exp)
+	} else if follower.execN() != 1 || leader.execN() != 1 {
+		t.Fatalf("unexpected commands: follower=%d leader=%d", follower.execN(), leader.execN())
+	}
+
+	// Reads see the command applied.
+	if data, err := c.Data(); err != nil {
+		t.Fatal(err)
+	} else if data.Data.Index != 1 {
+		t.Fatalf("got index %d, expected 1", data.Data.Index)
+	}
+
+	// A command that may have been applied is not sent again.
+	leader.setStatus(http.StatusInternalServerError)
+	if err := c.DeleteDataNode(3); err == nil {
+		t.Fatal("expected error")
+	} else if follower.execN() != 1 {
+		t.Fatalf("unexpected commands on follower: %d", follower.execN())
+	}
+
+	// The leader goes away and the follower takes over.
+	leader.Close()
+	follower.setRedirect(nil)
+	if err := c.TruncateShardGroups(time.Unix(0, 0)); err != nil {
+		t.Fatal(err)
+	} else if got, exp := c.Leader(), follower.host(); got != exp {
+		t.Fatalf("got leader %s, expected %s", got, exp)
+	}
+
+	// The last snapshot is used while no meta node can be reached, until it
+	// is too old.
+	follower.Close()
+	c.CacheTTL = 0
+	if nodes, err := c.DataNodes(); err != nil {
+		t.Fatal(err)
+	} else if len(nodes) != 1 {
+		t.Fatalf("unexpected data nodes: %v", nodes)
+	}
+	c.MaxStale = 0
+	if _, err := c.DataNodes(); err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+// fakeMetaNode serves snapshots and applies commands like a meta node.
+type fakeMetaNode struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	redirect *fakeMetaNode // the leader, if not this node
+	status   int           // returned for commands if non-zero
+	index    uint64
+	execs    int
+	changes  []Change // streamed to watchers
+}
+
+func newFakeMetaNode() *fakeMetaNode {
+	n := &fakeMetaNode{}
+	n.Server = httptest.NewServer(n)
+	return n
+}
+
+func (n *fakeMetaNode) host() string { return n.Listener.Addr().String() }
+
+func (n *fakeMetaNode) setRedirect(leader *fakeMetaNode) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.redirect = leader
+}
+
+func (n *fakeMetaNode) setStatus(status int) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.status = status
+}
+
+func (n *fakeMetaNode) execN() int {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.execs
+}
+
+func (n *fakeMetaNode) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == "/watch" {
+		n.serveWatch(w, r)
+		return
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if r.Method == "GET" {
+		data := &Data{Data: &meta.Data{Index: n.index}, DataNodes: NodeInfos{{ID: 1}}}
+		b, err := data.MarshalBinary()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Write(b)
+		return
+	}
+
+	n.execs++
+	if n.status != 0 {
+		http.Error(w, "", n.status)
+		return
+	} else if n.redirect != nil {
+		http.Redirect(w, r, "http://"+n.redirect.host()+"/execute", http.StatusTemporaryRedirect)
+		return
+	}
+
+	n.index++
+	b, _ := proto.Marshal(&internal.Response{OK: proto.Bool(true), Index: proto.Uint64(n.index)})
+	w.Write(b)
+}
+
+// serveWatch streams the changes of the node and holds the stream open until
+// the watcher goes away.
+func (n *fakeMetaNode) serveWatch(w http.ResponseWriter, r *http.Request) {
+	n.mu.Lock()
+	enc := json.NewEncoder(w)
+	for _, c := range n.changes {
+		enc.Encode(c)
+	}
+	n.mu.Unlock()
+	w.(http.Flusher).Flush()
+	<-r.Context().Done()
+}
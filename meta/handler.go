@@ -86,6 +86,8 @@ func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 			h.WrapHandler("lease", h.serveLease).ServeHTTP(w, r)
 		case "/peers":
 			h.WrapHandler("peers", h.servePeers).ServeHTTP(w, r)
+		case "/watch":
+			h.WrapHandler("watch", h.serveWatch).ServeHTTP(w, r)
 		default:
 			h.WrapHandler("snapshot", h.serveSnapshot).ServeHTTP(w, r)
 		}
@@ -277,6 +279,66 @@ func (h *handler) serveSnapshot(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// serveWatch streams the changes to the meta data made after the index the
+// client has, as a JSON object per line, until the client goes away. As the
+// data at the index of the client is not kept, a ChangeReset is sent first
+// if the data changed since.
+func (h *handler) serveWatch(w http.ResponseWriter, r *http.Request) {
+	if h.isClosed() {
+		h.httpError(fmt.Errorf("server closed"), w, http.StatusInternalServerError)
+		return
+	}
+
+	index, err := strconv.ParseUint(r.URL.Query().Get("index"), 10, 64)
+	if err != nil {
+		http.Error(w, "error parsing index", http.StatusBadRequest)
+		return
+	}
+	prev, err := h.store.snapshot()
+	if err != nil {
+		h.httpError(err, w, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Add("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	flush := func() {
+		if f, ok := w.(http.Flusher); ok {
+			f.Flush()
+		}
+	}
+	if prev.Data.Index > index {
+		if err := enc.Encode(Change{Type: ChangeReset, Index: prev.Data.Index}); err != nil {
+			return
+		}
+	}
+	flush()
+
+	closed := w.(http.CloseNotifier).CloseNotify()
+	for {
+		select {
+		case <-h.store.afterIndex(prev.Data.Index):
+		case <-closed:
+			return
+		case <-h.closing:
+			return
+		}
+
+		next, err := h.store.snapshot()
+		if err != nil {
+			h.logger.Info("watch snapshot failed", zap.Error(err))
+			return
+		}
+		for _, c := range Diff(prev, next) {
+			if err := enc.Encode(c); err != nil {
+				return
+			}
+		}
+		flush()
+		prev = next
+	}
+}
+
 // servePing will return if the server is up, or if specified will check the status
 // of the other metaservers as well
 func (h *handler) servePing(w http.ResponseWriter, r *http.Request) {
@@ -409,6 +471,9 @@ func (w gzipResponseWriter) Write(b []byte) (int, error) {
 
 func (w gzipResponseWriter) Flush() {
 	w.Writer.(*gzip.Writer).Flush()
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
 }
 
 func (w gzipResponseWriter) CloseNotify() <-chan bool {
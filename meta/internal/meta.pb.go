@@ -10,6 +10,7 @@ It is generated from these files:
 
 It has these top-level messages:
 	ClusterData
+	ShardGroupAssignment
 	NodeInfo
 	RoleInfo
 	UserInfo
@@ -61,6 +62,9 @@ It has these top-level messages:
 	ChangeRoleNameCommand
 	ImportDataCommand
 	CreateBalancedShardGroupCommand
+	BatchCommand
+	ReplaceDataNodeCommand
+	SetDataNodeMaintenanceCommand
 */
 package internal
 
@@ -126,6 +130,9 @@ const (
 	Command_TruncateShardGroupsCommand       Command_Type = 42
 	Command_ChangeRoleNameCommand            Command_Type = 43
 	Command_CreateBalancedShardGroupCommand  Command_Type = 44
+	Command_BatchCommand                     Command_Type = 45
+	Command_ReplaceDataNodeCommand           Command_Type = 46
+	Command_SetDataNodeMaintenanceCommand    Command_Type = 47
 )
 
 var Command_Type_name = map[int32]string{
@@ -173,6 +180,9 @@ var Command_Type_name = map[int32]string{
 	42: "TruncateShardGroupsCommand",
 	43: "ChangeRoleNameCommand",
 	44: "CreateBalancedShardGroupCommand",
+	45: "BatchCommand",
+	46: "ReplaceDataNodeCommand",
+	47: "SetDataNodeMaintenanceCommand",
 }
 var Command_Type_value = map[string]int32{
 	"CreateDatabaseCommand":            1,
@@ -219,6 +229,9 @@ var Command_Type_value = map[string]int32{
 	"TruncateShardGroupsCommand":       42,
 	"ChangeRoleNameCommand":            43,
 	"CreateBalancedShardGroupCommand":  44,
+	"BatchCommand":                     45,
+	"ReplaceDataNodeCommand":           46,
+	"SetDataNodeMaintenanceCommand":    47,
 }
 
 func (x Command_Type) Enum() *Command_Type {
@@ -237,16 +250,17 @@ func (x *Command_Type) UnmarshalJSON(data []byte) error {
 	*x = Command_Type(value)
 	return nil
 }
-func (Command_Type) EnumDescriptor() ([]byte, []int) { return fileDescriptorMeta, []int{7, 0} }
+func (Command_Type) EnumDescriptor() ([]byte, []int) { return fileDescriptorMeta, []int{8, 0} }
 
 type ClusterData struct {
-	Data             []byte      `protobuf:"bytes,1,req,name=Data" json:"Data,omitempty"`
-	MaxNodeID        *uint64     `protobuf:"varint,2,req,name=MaxNodeID" json:"MaxNodeID,omitempty"`
-	DataNodes        []*NodeInfo `protobuf:"bytes,3,rep,name=DataNodes" json:"DataNodes,omitempty"`
-	MetaNodes        []*NodeInfo `protobuf:"bytes,4,rep,name=MetaNodes" json:"MetaNodes,omitempty"`
-	Roles            []*RoleInfo `protobuf:"bytes,5,rep,name=Roles" json:"Roles,omitempty"`
-	Users            []*UserInfo `protobuf:"bytes,6,rep,name=Users" json:"Users,omitempty"`
-	XXX_unrecognized []byte      `json:"-"`
+	Data             []byte                  `protobuf:"bytes,1,req,name=Data" json:"Data,omitempty"`
+	MaxNodeID        *uint64                 `protobuf:"varint,2,req,name=MaxNodeID" json:"MaxNodeID,omitempty"`
+	DataNodes        []*NodeInfo             `protobuf:"bytes,3,rep,name=DataNodes" json:"DataNodes,omitempty"`
+	MetaNodes        []*NodeInfo             `protobuf:"bytes,4,rep,name=MetaNodes" json:"MetaNodes,omitempty"`
+	Roles            []*RoleInfo             `protobuf:"bytes,5,rep,name=Roles" json:"Roles,omitempty"`
+	Users            []*UserInfo             `protobuf:"bytes,6,rep,name=Users" json:"Users,omitempty"`
+	ShardAssignments []*ShardGroupAssignment `protobuf:"bytes,7,rep,name=ShardAssignments" json:"ShardAssignments,omitempty"`
+	XXX_unrecognized []byte                  `json:"-"`
 }
 
 func (m *ClusterData) Reset()                    { *m = ClusterData{} }
@@ -296,18 +310,51 @@ func (m *ClusterData) GetUsers() []*UserInfo {
 	return nil
 }
 
+func (m *ClusterData) GetShardAssignments() []*ShardGroupAssignment {
+	if m != nil {
+		return m.ShardAssignments
+	}
+	return nil
+}
+
+type ShardGroupAssignment struct {
+	ShardGroupID     *uint64 `protobuf:"varint,1,req,name=ShardGroupID" json:"ShardGroupID,omitempty"`
+	Mode             *string `protobuf:"bytes,2,req,name=Mode" json:"Mode,omitempty"`
+	XXX_unrecognized []byte  `json:"-"`
+}
+
+func (m *ShardGroupAssignment) Reset()                    { *m = ShardGroupAssignment{} }
+func (m *ShardGroupAssignment) String() string            { return proto.CompactTextString(m) }
+func (*ShardGroupAssignment) ProtoMessage()               {}
+func (*ShardGroupAssignment) Descriptor() ([]byte, []int) { return fileDescriptorMeta, []int{1} }
+
+func (m *ShardGroupAssignment) GetShardGroupID() uint64 {
+	if m != nil && m.ShardGroupID != nil {
+		return *m.ShardGroupID
+	}
+	return 0
+}
+
+func (m *ShardGroupAssignment) GetMode() string {
+	if m != nil && m.Mode != nil {
+		return *m.Mode
+	}
+	return ""
+}
+
 type NodeInfo struct {
 	ID                 *uint64  `protobuf:"varint,1,req,name=ID" json:"ID,omitempty"`
 	Host               *string  `protobuf:"bytes,2,req,name=Host" json:"Host,omitempty"`
 	TCPHost            *string  `protobuf:"bytes,3,opt,name=TCPHost" json:"TCPHost,omitempty"`
 	PendingShardOwners []uint64 `protobuf:"varint,4,rep,name=PendingShardOwners" json:"PendingShardOwners,omitempty"`
+	Maintenance        *bool    `protobuf:"varint,5,opt,name=Maintenance" json:"Maintenance,omitempty"`
 	XXX_unrecognized   []byte   `json:"-"`
 }
 
 func (m *NodeInfo) Reset()                    { *m = NodeInfo{} }
 func (m *NodeInfo) String() string            { return proto.CompactTextString(m) }
 func (*NodeInfo) ProtoMessage()               {}
-func (*NodeInfo) Descriptor() ([]byte, []int) { return fileDescriptorMeta, []int{1} }
+func (*NodeInfo) Descriptor() ([]byte, []int) { return fileDescriptorMeta, []int{2} }
 
 func (m *NodeInfo) GetID() uint64 {
 	if m != nil && m.ID != nil {
@@ -337,6 +384,13 @@ func (m *NodeInfo) GetPendingShardOwners() []uint64 {
 	return nil
 }
 
+func (m *NodeInfo) GetMaintenance() bool {
+	if m != nil && m.Maintenance != nil {
+		return *m.Maintenance
+	}
+	return false
+}
+
 type RoleInfo struct {
 	Name             *string        `protobuf:"bytes,1,req,name=Name" json:"Name,omitempty"`
 	Permissions      *UserPrivilege `protobuf:"bytes,2,req,name=Permissions" json:"Permissions,omitempty"`
@@ -347,7 +401,7 @@ type RoleInfo struct {
 func (m *RoleInfo) Reset()                    { *m = RoleInfo{} }
 func (m *RoleInfo) String() string            { return proto.CompactTextString(m) }
 func (*RoleInfo) ProtoMessage()               {}
-func (*RoleInfo) Descriptor() ([]byte, []int) { return fileDescriptorMeta, []int{2} }
+func (*RoleInfo) Descriptor() ([]byte, []int) { return fileDescriptorMeta, []int{3} }
 
 func (m *RoleInfo) GetName() string {
 	if m != nil && m.Name != nil {
@@ -380,7 +434,7 @@ type UserInfo struct {
 func (m *UserInfo) Reset()                    { *m = UserInfo{} }
 func (m *UserInfo) String() string            { return proto.CompactTextString(m) }
 func (*UserInfo) ProtoMessage()               {}
-func (*UserInfo) Descriptor() ([]byte, []int) { return fileDescriptorMeta, []int{3} }
+func (*UserInfo) Descriptor() ([]byte, []int) { return fileDescriptorMeta, []int{4} }
 
 func (m *UserInfo) GetName() string {
 	if m != nil && m.Name != nil {
@@ -412,7 +466,7 @@ type UserPrivilege struct {
 func (m *UserPrivilege) Reset()                    { *m = UserPrivilege{} }
 func (m *UserPrivilege) String() string            { return proto.CompactTextString(m) }
 func (*UserPrivilege) ProtoMessage()               {}
-func (*UserPrivilege) Descriptor() ([]byte, []int) { return fileDescriptorMeta, []int{4} }
+func (*UserPrivilege) Descriptor() ([]byte, []int) { return fileDescriptorMeta, []int{5} }
 
 func (m *UserPrivilege) GetDatabase() string {
 	if m != nil && m.Database != nil {
@@ -437,7 +491,7 @@ type ScopedPermission struct {
 func (m *ScopedPermission) Reset()                    { *m = ScopedPermission{} }
 func (m *ScopedPermission) String() string            { return proto.CompactTextString(m) }
 func (*ScopedPermission) ProtoMessage()               {}
-func (*ScopedPermission) Descriptor() ([]byte, []int) { return fileDescriptorMeta, []int{5} }
+func (*ScopedPermission) Descriptor() ([]byte, []int) { return fileDescriptorMeta, []int{6} }
 
 func (m *ScopedPermission) GetResources() []byte {
 	if m != nil {
@@ -463,7 +517,7 @@ type Response struct {
 func (m *Response) Reset()                    { *m = Response{} }
 func (m *Response) String() string            { return proto.CompactTextString(m) }
 func (*Response) ProtoMessage()               {}
-func (*Response) Descriptor() ([]byte, []int) { return fileDescriptorMeta, []int{6} }
+func (*Response) Descriptor() ([]byte, []int) { return fileDescriptorMeta, []int{7} }
 
 func (m *Response) GetOK() bool {
 	if m != nil && m.OK != nil {
@@ -495,7 +549,7 @@ type Command struct {
 func (m *Command) Reset()                    { *m = Command{} }
 func (m *Command) String() string            { return proto.CompactTextString(m) }
 func (*Command) ProtoMessage()               {}
-func (*Command) Descriptor() ([]byte, []int) { return fileDescriptorMeta, []int{7} }
+func (*Command) Descriptor() ([]byte, []int) { return fileDescriptorMeta, []int{8} }
 
 var extRange_Command = []proto.ExtensionRange{
 	{Start: 100, End: 536870911},
@@ -521,7 +575,7 @@ type CreateDatabaseCommand struct {
 func (m *CreateDatabaseCommand) Reset()                    { *m = CreateDatabaseCommand{} }
 func (m *CreateDatabaseCommand) String() string            { return proto.CompactTextString(m) }
 func (*CreateDatabaseCommand) ProtoMessage()               {}
-func (*CreateDatabaseCommand) Descriptor() ([]byte, []int) { return fileDescriptorMeta, []int{8} }
+func (*CreateDatabaseCommand) Descriptor() ([]byte, []int) { return fileDescriptorMeta, []int{9} }
 
 func (m *CreateDatabaseCommand) GetName() string {
 	if m != nil && m.Name != nil {
@@ -553,7 +607,7 @@ type DropDatabaseCommand struct {
 func (m *DropDatabaseCommand) Reset()                    { *m = DropDatabaseCommand{} }
 func (m *DropDatabaseCommand) String() string            { return proto.CompactTextString(m) }
 func (*DropDatabaseCommand) ProtoMessage()               {}
-func (*DropDatabaseCommand) Descriptor() ([]byte, []int) { return fileDescriptorMeta, []int{9} }
+func (*DropDatabaseCommand) Descriptor() ([]byte, []int) { return fileDescriptorMeta, []int{10} }
 
 func (m *DropDatabaseCommand) GetName() string {
 	if m != nil && m.Name != nil {
@@ -580,7 +634,7 @@ func (m *CreateRetentionPolicyCommand) Reset()         { *m = CreateRetentionPol
 func (m *CreateRetentionPolicyCommand) String() string { return proto.CompactTextString(m) }
 func (*CreateRetentionPolicyCommand) ProtoMessage()    {}
 func (*CreateRetentionPolicyCommand) Descriptor() ([]byte, []int) {
-	return fileDescriptorMeta, []int{10}
+	return fileDescriptorMeta, []int{11}
 }
 
 func (m *CreateRetentionPolicyCommand) GetDatabase() string {
@@ -614,7 +668,7 @@ type DropRetentionPolicyCommand struct {
 func (m *DropRetentionPolicyCommand) Reset()                    { *m = DropRetentionPolicyCommand{} }
 func (m *DropRetentionPolicyCommand) String() string            { return proto.CompactTextString(m) }
 func (*DropRetentionPolicyCommand) ProtoMessage()               {}
-func (*DropRetentionPolicyCommand) Descriptor() ([]byte, []int) { return fileDescriptorMeta, []int{11} }
+func (*DropRetentionPolicyCommand) Descriptor() ([]byte, []int) { return fileDescriptorMeta, []int{12} }
 
 func (m *DropRetentionPolicyCommand) GetDatabase() string {
 	if m != nil && m.Database != nil {
@@ -648,7 +702,7 @@ func (m *SetDefaultRetentionPolicyCommand) Reset()         { *m = SetDefaultRete
 func (m *SetDefaultRetentionPolicyCommand) String() string { return proto.CompactTextString(m) }
 func (*SetDefaultRetentionPolicyCommand) ProtoMessage()    {}
 func (*SetDefaultRetentionPolicyCommand) Descriptor() ([]byte, []int) {
-	return fileDescriptorMeta, []int{12}
+	return fileDescriptorMeta, []int{13}
 }
 
 func (m *SetDefaultRetentionPolicyCommand) GetDatabase() string {
@@ -686,7 +740,7 @@ func (m *UpdateRetentionPolicyCommand) Reset()         { *m = UpdateRetentionPol
 func (m *UpdateRetentionPolicyCommand) String() string { return proto.CompactTextString(m) }
 func (*UpdateRetentionPolicyCommand) ProtoMessage()    {}
 func (*UpdateRetentionPolicyCommand) Descriptor() ([]byte, []int) {
-	return fileDescriptorMeta, []int{13}
+	return fileDescriptorMeta, []int{14}
 }
 
 func (m *UpdateRetentionPolicyCommand) GetDatabase() string {
@@ -736,13 +790,15 @@ type CreateShardGroupCommand struct {
 	Database         *string `protobuf:"bytes,1,req,name=Database" json:"Database,omitempty"`
 	Policy           *string `protobuf:"bytes,2,req,name=Policy" json:"Policy,omitempty"`
 	Timestamp        *int64  `protobuf:"varint,3,req,name=Timestamp" json:"Timestamp,omitempty"`
+	ShardAssignment  *string `protobuf:"bytes,4,opt,name=ShardAssignment" json:"ShardAssignment,omitempty"`
+	ShardN           *uint32 `protobuf:"varint,5,opt,name=ShardN" json:"ShardN,omitempty"`
 	XXX_unrecognized []byte  `json:"-"`
 }
 
 func (m *CreateShardGroupCommand) Reset()                    { *m = CreateShardGroupCommand{} }
 func (m *CreateShardGroupCommand) String() string            { return proto.CompactTextString(m) }
 func (*CreateShardGroupCommand) ProtoMessage()               {}
-func (*CreateShardGroupCommand) Descriptor() ([]byte, []int) { return fileDescriptorMeta, []int{14} }
+func (*CreateShardGroupCommand) Descriptor() ([]byte, []int) { return fileDescriptorMeta, []int{15} }
 
 func (m *CreateShardGroupCommand) GetDatabase() string {
 	if m != nil && m.Database != nil {
@@ -765,6 +821,20 @@ func (m *CreateShardGroupCommand) GetTimestamp() int64 {
 	return 0
 }
 
+func (m *CreateShardGroupCommand) GetShardAssignment() string {
+	if m != nil && m.ShardAssignment != nil {
+		return *m.ShardAssignment
+	}
+	return ""
+}
+
+func (m *CreateShardGroupCommand) GetShardN() uint32 {
+	if m != nil && m.ShardN != nil {
+		return *m.ShardN
+	}
+	return 0
+}
+
 var E_CreateShardGroupCommand_Command = &proto.ExtensionDesc{
 	ExtendedType:  (*Command)(nil),
 	ExtensionType: (*CreateShardGroupCommand)(nil),
@@ -783,7 +853,7 @@ type DeleteShardGroupCommand struct {
 func (m *DeleteShardGroupCommand) Reset()                    { *m = DeleteShardGroupCommand{} }
 func (m *DeleteShardGroupCommand) String() string            { return proto.CompactTextString(m) }
 func (*DeleteShardGroupCommand) ProtoMessage()               {}
-func (*DeleteShardGroupCommand) Descriptor() ([]byte, []int) { return fileDescriptorMeta, []int{15} }
+func (*DeleteShardGroupCommand) Descriptor() ([]byte, []int) { return fileDescriptorMeta, []int{16} }
 
 func (m *DeleteShardGroupCommand) GetDatabase() string {
 	if m != nil && m.Database != nil {
@@ -825,7 +895,7 @@ func (m *CreateContinuousQueryCommand) Reset()         { *m = CreateContinuousQu
 func (m *CreateContinuousQueryCommand) String() string { return proto.CompactTextString(m) }
 func (*CreateContinuousQueryCommand) ProtoMessage()    {}
 func (*CreateContinuousQueryCommand) Descriptor() ([]byte, []int) {
-	return fileDescriptorMeta, []int{16}
+	return fileDescriptorMeta, []int{17}
 }
 
 func (m *CreateContinuousQueryCommand) GetDatabase() string {
@@ -866,7 +936,7 @@ type DropContinuousQueryCommand struct {
 func (m *DropContinuousQueryCommand) Reset()                    { *m = DropContinuousQueryCommand{} }
 func (m *DropContinuousQueryCommand) String() string            { return proto.CompactTextString(m) }
 func (*DropContinuousQueryCommand) ProtoMessage()               {}
-func (*DropContinuousQueryCommand) Descriptor() ([]byte, []int) { return fileDescriptorMeta, []int{17} }
+func (*DropContinuousQueryCommand) Descriptor() ([]byte, []int) { return fileDescriptorMeta, []int{18} }
 
 func (m *DropContinuousQueryCommand) GetDatabase() string {
 	if m != nil && m.Database != nil {
@@ -900,7 +970,7 @@ type CreateUserCommand struct {
 func (m *CreateUserCommand) Reset()                    { *m = CreateUserCommand{} }
 func (m *CreateUserCommand) String() string            { return proto.CompactTextString(m) }
 func (*CreateUserCommand) ProtoMessage()               {}
-func (*CreateUserCommand) Descriptor() ([]byte, []int) { return fileDescriptorMeta, []int{18} }
+func (*CreateUserCommand) Descriptor() ([]byte, []int) { return fileDescriptorMeta, []int{19} }
 
 func (m *CreateUserCommand) GetName() string {
 	if m != nil && m.Name != nil {
@@ -939,7 +1009,7 @@ type DropUserCommand struct {
 func (m *DropUserCommand) Reset()                    { *m = DropUserCommand{} }
 func (m *DropUserCommand) String() string            { return proto.CompactTextString(m) }
 func (*DropUserCommand) ProtoMessage()               {}
-func (*DropUserCommand) Descriptor() ([]byte, []int) { return fileDescriptorMeta, []int{19} }
+func (*DropUserCommand) Descriptor() ([]byte, []int) { return fileDescriptorMeta, []int{20} }
 
 func (m *DropUserCommand) GetName() string {
 	if m != nil && m.Name != nil {
@@ -965,7 +1035,7 @@ type UpdateUserCommand struct {
 func (m *UpdateUserCommand) Reset()                    { *m = UpdateUserCommand{} }
 func (m *UpdateUserCommand) String() string            { return proto.CompactTextString(m) }
 func (*UpdateUserCommand) ProtoMessage()               {}
-func (*UpdateUserCommand) Descriptor() ([]byte, []int) { return fileDescriptorMeta, []int{20} }
+func (*UpdateUserCommand) Descriptor() ([]byte, []int) { return fileDescriptorMeta, []int{21} }
 
 func (m *UpdateUserCommand) GetName() string {
 	if m != nil && m.Name != nil {
@@ -999,7 +1069,7 @@ type SetPrivilegeCommand struct {
 func (m *SetPrivilegeCommand) Reset()                    { *m = SetPrivilegeCommand{} }
 func (m *SetPrivilegeCommand) String() string            { return proto.CompactTextString(m) }
 func (*SetPrivilegeCommand) ProtoMessage()               {}
-func (*SetPrivilegeCommand) Descriptor() ([]byte, []int) { return fileDescriptorMeta, []int{21} }
+func (*SetPrivilegeCommand) Descriptor() ([]byte, []int) { return fileDescriptorMeta, []int{22} }
 
 func (m *SetPrivilegeCommand) GetUsername() string {
 	if m != nil && m.Username != nil {
@@ -1037,7 +1107,7 @@ type CreateRoleCommand struct {
 func (m *CreateRoleCommand) Reset()                    { *m = CreateRoleCommand{} }
 func (m *CreateRoleCommand) String() string            { return proto.CompactTextString(m) }
 func (*CreateRoleCommand) ProtoMessage()               {}
-func (*CreateRoleCommand) Descriptor() ([]byte, []int) { return fileDescriptorMeta, []int{22} }
+func (*CreateRoleCommand) Descriptor() ([]byte, []int) { return fileDescriptorMeta, []int{23} }
 
 var E_CreateRoleCommand_Command = &proto.ExtensionDesc{
 	ExtendedType:  (*Command)(nil),
@@ -1054,7 +1124,7 @@ type DropRoleCommand struct {
 func (m *DropRoleCommand) Reset()                    { *m = DropRoleCommand{} }
 func (m *DropRoleCommand) String() string            { return proto.CompactTextString(m) }
 func (*DropRoleCommand) ProtoMessage()               {}
-func (*DropRoleCommand) Descriptor() ([]byte, []int) { return fileDescriptorMeta, []int{23} }
+func (*DropRoleCommand) Descriptor() ([]byte, []int) { return fileDescriptorMeta, []int{24} }
 
 var E_DropRoleCommand_Command = &proto.ExtensionDesc{
 	ExtendedType:  (*Command)(nil),
@@ -1071,7 +1141,7 @@ type AddRoleUsersCommand struct {
 func (m *AddRoleUsersCommand) Reset()                    { *m = AddRoleUsersCommand{} }
 func (m *AddRoleUsersCommand) String() string            { return proto.CompactTextString(m) }
 func (*AddRoleUsersCommand) ProtoMessage()               {}
-func (*AddRoleUsersCommand) Descriptor() ([]byte, []int) { return fileDescriptorMeta, []int{24} }
+func (*AddRoleUsersCommand) Descriptor() ([]byte, []int) { return fileDescriptorMeta, []int{25} }
 
 var E_AddRoleUsersCommand_Command = &proto.ExtensionDesc{
 	ExtendedType:  (*Command)(nil),
@@ -1088,7 +1158,7 @@ type RemoveRoleUsersCommand struct {
 func (m *RemoveRoleUsersCommand) Reset()                    { *m = RemoveRoleUsersCommand{} }
 func (m *RemoveRoleUsersCommand) String() string            { return proto.CompactTextString(m) }
 func (*RemoveRoleUsersCommand) ProtoMessage()               {}
-func (*RemoveRoleUsersCommand) Descriptor() ([]byte, []int) { return fileDescriptorMeta, []int{25} }
+func (*RemoveRoleUsersCommand) Descriptor() ([]byte, []int) { return fileDescriptorMeta, []int{26} }
 
 var E_RemoveRoleUsersCommand_Command = &proto.ExtensionDesc{
 	ExtendedType:  (*Command)(nil),
@@ -1105,7 +1175,7 @@ type AddRolePermissionsCommand struct {
 func (m *AddRolePermissionsCommand) Reset()                    { *m = AddRolePermissionsCommand{} }
 func (m *AddRolePermissionsCommand) String() string            { return proto.CompactTextString(m) }
 func (*AddRolePermissionsCommand) ProtoMessage()               {}
-func (*AddRolePermissionsCommand) Descriptor() ([]byte, []int) { return fileDescriptorMeta, []int{26} }
+func (*AddRolePermissionsCommand) Descriptor() ([]byte, []int) { return fileDescriptorMeta, []int{27} }
 
 var E_AddRolePermissionsCommand_Command = &proto.ExtensionDesc{
 	ExtendedType:  (*Command)(nil),
@@ -1123,7 +1193,7 @@ func (m *RemoveRolePermissionsCommand) Reset()         { *m = RemoveRolePermissi
 func (m *RemoveRolePermissionsCommand) String() string { return proto.CompactTextString(m) }
 func (*RemoveRolePermissionsCommand) ProtoMessage()    {}
 func (*RemoveRolePermissionsCommand) Descriptor() ([]byte, []int) {
-	return fileDescriptorMeta, []int{27}
+	return fileDescriptorMeta, []int{28}
 }
 
 var E_RemoveRolePermissionsCommand_Command = &proto.ExtensionDesc{
@@ -1142,7 +1212,7 @@ type SetDataCommand struct {
 func (m *SetDataCommand) Reset()                    { *m = SetDataCommand{} }
 func (m *SetDataCommand) String() string            { return proto.CompactTextString(m) }
 func (*SetDataCommand) ProtoMessage()               {}
-func (*SetDataCommand) Descriptor() ([]byte, []int) { return fileDescriptorMeta, []int{28} }
+func (*SetDataCommand) Descriptor() ([]byte, []int) { return fileDescriptorMeta, []int{29} }
 
 func (m *SetDataCommand) GetData() []byte {
 	if m != nil {
@@ -1168,7 +1238,7 @@ type SetAdminPrivilegeCommand struct {
 func (m *SetAdminPrivilegeCommand) Reset()                    { *m = SetAdminPrivilegeCommand{} }
 func (m *SetAdminPrivilegeCommand) String() string            { return proto.CompactTextString(m) }
 func (*SetAdminPrivilegeCommand) ProtoMessage()               {}
-func (*SetAdminPrivilegeCommand) Descriptor() ([]byte, []int) { return fileDescriptorMeta, []int{29} }
+func (*SetAdminPrivilegeCommand) Descriptor() ([]byte, []int) { return fileDescriptorMeta, []int{30} }
 
 func (m *SetAdminPrivilegeCommand) GetUsername() string {
 	if m != nil && m.Username != nil {
@@ -1204,7 +1274,7 @@ type CreateSubscriptionCommand struct {
 func (m *CreateSubscriptionCommand) Reset()                    { *m = CreateSubscriptionCommand{} }
 func (m *CreateSubscriptionCommand) String() string            { return proto.CompactTextString(m) }
 func (*CreateSubscriptionCommand) ProtoMessage()               {}
-func (*CreateSubscriptionCommand) Descriptor() ([]byte, []int) { return fileDescriptorMeta, []int{30} }
+func (*CreateSubscriptionCommand) Descriptor() ([]byte, []int) { return fileDescriptorMeta, []int{31} }
 
 func (m *CreateSubscriptionCommand) GetName() string {
 	if m != nil && m.Name != nil {
@@ -1259,7 +1329,7 @@ type DropSubscriptionCommand struct {
 func (m *DropSubscriptionCommand) Reset()                    { *m = DropSubscriptionCommand{} }
 func (m *DropSubscriptionCommand) String() string            { return proto.CompactTextString(m) }
 func (*DropSubscriptionCommand) ProtoMessage()               {}
-func (*DropSubscriptionCommand) Descriptor() ([]byte, []int) { return fileDescriptorMeta, []int{31} }
+func (*DropSubscriptionCommand) Descriptor() ([]byte, []int) { return fileDescriptorMeta, []int{32} }
 
 func (m *DropSubscriptionCommand) GetName() string {
 	if m != nil && m.Name != nil {
@@ -1299,7 +1369,7 @@ type RemovePeerCommand struct {
 func (m *RemovePeerCommand) Reset()                    { *m = RemovePeerCommand{} }
 func (m *RemovePeerCommand) String() string            { return proto.CompactTextString(m) }
 func (*RemovePeerCommand) ProtoMessage()               {}
-func (*RemovePeerCommand) Descriptor() ([]byte, []int) { return fileDescriptorMeta, []int{32} }
+func (*RemovePeerCommand) Descriptor() ([]byte, []int) { return fileDescriptorMeta, []int{33} }
 
 func (m *RemovePeerCommand) GetID() uint64 {
 	if m != nil && m.ID != nil {
@@ -1333,7 +1403,7 @@ type CreateMetaNodeCommand struct {
 func (m *CreateMetaNodeCommand) Reset()                    { *m = CreateMetaNodeCommand{} }
 func (m *CreateMetaNodeCommand) String() string            { return proto.CompactTextString(m) }
 func (*CreateMetaNodeCommand) ProtoMessage()               {}
-func (*CreateMetaNodeCommand) Descriptor() ([]byte, []int) { return fileDescriptorMeta, []int{33} }
+func (*CreateMetaNodeCommand) Descriptor() ([]byte, []int) { return fileDescriptorMeta, []int{34} }
 
 func (m *CreateMetaNodeCommand) GetHTTPAddr() string {
 	if m != nil && m.HTTPAddr != nil {
@@ -1373,7 +1443,7 @@ type CreateDataNodeCommand struct {
 func (m *CreateDataNodeCommand) Reset()                    { *m = CreateDataNodeCommand{} }
 func (m *CreateDataNodeCommand) String() string            { return proto.CompactTextString(m) }
 func (*CreateDataNodeCommand) ProtoMessage()               {}
-func (*CreateDataNodeCommand) Descriptor() ([]byte, []int) { return fileDescriptorMeta, []int{34} }
+func (*CreateDataNodeCommand) Descriptor() ([]byte, []int) { return fileDescriptorMeta, []int{35} }
 
 func (m *CreateDataNodeCommand) GetHTTPAddr() string {
 	if m != nil && m.HTTPAddr != nil {
@@ -1407,7 +1477,7 @@ type UpdateDataNodeCommand struct {
 func (m *UpdateDataNodeCommand) Reset()                    { *m = UpdateDataNodeCommand{} }
 func (m *UpdateDataNodeCommand) String() string            { return proto.CompactTextString(m) }
 func (*UpdateDataNodeCommand) ProtoMessage()               {}
-func (*UpdateDataNodeCommand) Descriptor() ([]byte, []int) { return fileDescriptorMeta, []int{35} }
+func (*UpdateDataNodeCommand) Descriptor() ([]byte, []int) { return fileDescriptorMeta, []int{36} }
 
 func (m *UpdateDataNodeCommand) GetID() uint64 {
 	if m != nil && m.ID != nil {
@@ -1446,7 +1516,7 @@ type DeleteMetaNodeCommand struct {
 func (m *DeleteMetaNodeCommand) Reset()                    { *m = DeleteMetaNodeCommand{} }
 func (m *DeleteMetaNodeCommand) String() string            { return proto.CompactTextString(m) }
 func (*DeleteMetaNodeCommand) ProtoMessage()               {}
-func (*DeleteMetaNodeCommand) Descriptor() ([]byte, []int) { return fileDescriptorMeta, []int{36} }
+func (*DeleteMetaNodeCommand) Descriptor() ([]byte, []int) { return fileDescriptorMeta, []int{37} }
 
 func (m *DeleteMetaNodeCommand) GetID() uint64 {
 	if m != nil && m.ID != nil {
@@ -1471,7 +1541,7 @@ type DeleteDataNodeCommand struct {
 func (m *DeleteDataNodeCommand) Reset()                    { *m = DeleteDataNodeCommand{} }
 func (m *DeleteDataNodeCommand) String() string            { return proto.CompactTextString(m) }
 func (*DeleteDataNodeCommand) ProtoMessage()               {}
-func (*DeleteDataNodeCommand) Descriptor() ([]byte, []int) { return fileDescriptorMeta, []int{37} }
+func (*DeleteDataNodeCommand) Descriptor() ([]byte, []int) { return fileDescriptorMeta, []int{38} }
 
 func (m *DeleteDataNodeCommand) GetID() uint64 {
 	if m != nil && m.ID != nil {
@@ -1500,7 +1570,7 @@ type SetMetaNodeCommand struct {
 func (m *SetMetaNodeCommand) Reset()                    { *m = SetMetaNodeCommand{} }
 func (m *SetMetaNodeCommand) String() string            { return proto.CompactTextString(m) }
 func (*SetMetaNodeCommand) ProtoMessage()               {}
-func (*SetMetaNodeCommand) Descriptor() ([]byte, []int) { return fileDescriptorMeta, []int{38} }
+func (*SetMetaNodeCommand) Descriptor() ([]byte, []int) { return fileDescriptorMeta, []int{39} }
 
 func (m *SetMetaNodeCommand) GetHTTPAddr() string {
 	if m != nil && m.HTTPAddr != nil {
@@ -1539,7 +1609,7 @@ type DropShardCommand struct {
 func (m *DropShardCommand) Reset()                    { *m = DropShardCommand{} }
 func (m *DropShardCommand) String() string            { return proto.CompactTextString(m) }
 func (*DropShardCommand) ProtoMessage()               {}
-func (*DropShardCommand) Descriptor() ([]byte, []int) { return fileDescriptorMeta, []int{39} }
+func (*DropShardCommand) Descriptor() ([]byte, []int) { return fileDescriptorMeta, []int{40} }
 
 func (m *DropShardCommand) GetID() uint64 {
 	if m != nil && m.ID != nil {
@@ -1565,7 +1635,7 @@ type SetUserPasswordCommand struct {
 func (m *SetUserPasswordCommand) Reset()                    { *m = SetUserPasswordCommand{} }
 func (m *SetUserPasswordCommand) String() string            { return proto.CompactTextString(m) }
 func (*SetUserPasswordCommand) ProtoMessage()               {}
-func (*SetUserPasswordCommand) Descriptor() ([]byte, []int) { return fileDescriptorMeta, []int{40} }
+func (*SetUserPasswordCommand) Descriptor() ([]byte, []int) { return fileDescriptorMeta, []int{41} }
 
 func (m *SetUserPasswordCommand) GetName() string {
 	if m != nil && m.Name != nil {
@@ -1598,7 +1668,7 @@ type AddUserPermissionsCommand struct {
 func (m *AddUserPermissionsCommand) Reset()                    { *m = AddUserPermissionsCommand{} }
 func (m *AddUserPermissionsCommand) String() string            { return proto.CompactTextString(m) }
 func (*AddUserPermissionsCommand) ProtoMessage()               {}
-func (*AddUserPermissionsCommand) Descriptor() ([]byte, []int) { return fileDescriptorMeta, []int{41} }
+func (*AddUserPermissionsCommand) Descriptor() ([]byte, []int) { return fileDescriptorMeta, []int{42} }
 
 func (m *AddUserPermissionsCommand) GetName() string {
 	if m != nil && m.Name != nil {
@@ -1632,7 +1702,7 @@ func (m *RemoveUserPermissionsCommand) Reset()         { *m = RemoveUserPermissi
 func (m *RemoveUserPermissionsCommand) String() string { return proto.CompactTextString(m) }
 func (*RemoveUserPermissionsCommand) ProtoMessage()    {}
 func (*RemoveUserPermissionsCommand) Descriptor() ([]byte, []int) {
-	return fileDescriptorMeta, []int{42}
+	return fileDescriptorMeta, []int{43}
 }
 
 func (m *RemoveUserPermissionsCommand) GetName() string {
@@ -1666,7 +1736,7 @@ type AddShardOwnerCommand struct {
 func (m *AddShardOwnerCommand) Reset()                    { *m = AddShardOwnerCommand{} }
 func (m *AddShardOwnerCommand) String() string            { return proto.CompactTextString(m) }
 func (*AddShardOwnerCommand) ProtoMessage()               {}
-func (*AddShardOwnerCommand) Descriptor() ([]byte, []int) { return fileDescriptorMeta, []int{43} }
+func (*AddShardOwnerCommand) Descriptor() ([]byte, []int) { return fileDescriptorMeta, []int{44} }
 
 func (m *AddShardOwnerCommand) GetID() uint64 {
 	if m != nil && m.ID != nil {
@@ -1684,7 +1754,7 @@ func (m *AddShardOwnerCommand) GetNodeID() uint64 {
 
 var E_AddShardOwnerCommand_Command = &proto.ExtensionDesc{
 	ExtendedType:  (*Command)(nil),
-	ExtensionType: (*SetMetaNodeCommand)(nil),
+	ExtensionType: (*AddShardOwnerCommand)(nil),
 	Field:         136,
 	Name:          "internal.AddShardOwnerCommand.command",
 	Tag:           "bytes,136,opt,name=command",
@@ -1699,7 +1769,7 @@ type RemoveShardOwnerCommand struct {
 func (m *RemoveShardOwnerCommand) Reset()                    { *m = RemoveShardOwnerCommand{} }
 func (m *RemoveShardOwnerCommand) String() string            { return proto.CompactTextString(m) }
 func (*RemoveShardOwnerCommand) ProtoMessage()               {}
-func (*RemoveShardOwnerCommand) Descriptor() ([]byte, []int) { return fileDescriptorMeta, []int{44} }
+func (*RemoveShardOwnerCommand) Descriptor() ([]byte, []int) { return fileDescriptorMeta, []int{45} }
 
 func (m *RemoveShardOwnerCommand) GetID() uint64 {
 	if m != nil && m.ID != nil {
@@ -1717,7 +1787,7 @@ func (m *RemoveShardOwnerCommand) GetNodeID() uint64 {
 
 var E_RemoveShardOwnerCommand_Command = &proto.ExtensionDesc{
 	ExtendedType:  (*Command)(nil),
-	ExtensionType: (*SetMetaNodeCommand)(nil),
+	ExtensionType: (*RemoveShardOwnerCommand)(nil),
 	Field:         137,
 	Name:          "internal.RemoveShardOwnerCommand.command",
 	Tag:           "bytes,137,opt,name=command",
@@ -1729,10 +1799,12 @@ type AddPendingShardOwnerCommand struct {
 	XXX_unrecognized []byte  `json:"-"`
 }
 
-func (m *AddPendingShardOwnerCommand) Reset()                    { *m = AddPendingShardOwnerCommand{} }
-func (m *AddPendingShardOwnerCommand) String() string            { return proto.CompactTextString(m) }
-func (*AddPendingShardOwnerCommand) ProtoMessage()               {}
-func (*AddPendingShardOwnerCommand) Descriptor() ([]byte, []int) { return fileDescriptorMeta, []int{45} }
+func (m *AddPendingShardOwnerCommand) Reset()         { *m = AddPendingShardOwnerCommand{} }
+func (m *AddPendingShardOwnerCommand) String() string { return proto.CompactTextString(m) }
+func (*AddPendingShardOwnerCommand) ProtoMessage()    {}
+func (*AddPendingShardOwnerCommand) Descriptor() ([]byte, []int) {
+	return fileDescriptorMeta, []int{46}
+}
 
 func (m *AddPendingShardOwnerCommand) GetID() uint64 {
 	if m != nil && m.ID != nil {
@@ -1766,7 +1838,7 @@ func (m *RemovePendingShardOwnerCommand) Reset()         { *m = RemovePendingSha
 func (m *RemovePendingShardOwnerCommand) String() string { return proto.CompactTextString(m) }
 func (*RemovePendingShardOwnerCommand) ProtoMessage()    {}
 func (*RemovePendingShardOwnerCommand) Descriptor() ([]byte, []int) {
-	return fileDescriptorMeta, []int{46}
+	return fileDescriptorMeta, []int{47}
 }
 
 func (m *RemovePendingShardOwnerCommand) GetID() uint64 {
@@ -1801,7 +1873,7 @@ func (m *CommitPendingShardOwnerCommand) Reset()         { *m = CommitPendingSha
 func (m *CommitPendingShardOwnerCommand) String() string { return proto.CompactTextString(m) }
 func (*CommitPendingShardOwnerCommand) ProtoMessage()    {}
 func (*CommitPendingShardOwnerCommand) Descriptor() ([]byte, []int) {
-	return fileDescriptorMeta, []int{47}
+	return fileDescriptorMeta, []int{48}
 }
 
 func (m *CommitPendingShardOwnerCommand) GetID() uint64 {
@@ -1827,16 +1899,16 @@ var E_CommitPendingShardOwnerCommand_Command = &proto.ExtensionDesc{
 }
 
 type TruncateShardGroupCommand struct {
-	TruncateAt       *uint64 `protobuf:"varint,1,req,name=TruncateAt" json:"TruncateAt,omitempty"`
-	XXX_unrecognized []byte  `json:"-"`
+	TruncateAt       *int64 `protobuf:"varint,1,req,name=TruncateAt" json:"TruncateAt,omitempty"`
+	XXX_unrecognized []byte `json:"-"`
 }
 
 func (m *TruncateShardGroupCommand) Reset()                    { *m = TruncateShardGroupCommand{} }
 func (m *TruncateShardGroupCommand) String() string            { return proto.CompactTextString(m) }
 func (*TruncateShardGroupCommand) ProtoMessage()               {}
-func (*TruncateShardGroupCommand) Descriptor() ([]byte, []int) { return fileDescriptorMeta, []int{48} }
+func (*TruncateShardGroupCommand) Descriptor() ([]byte, []int) { return fileDescriptorMeta, []int{49} }
 
-func (m *TruncateShardGroupCommand) GetTruncateAt() uint64 {
+func (m *TruncateShardGroupCommand) GetTruncateAt() int64 {
 	if m != nil && m.TruncateAt != nil {
 		return *m.TruncateAt
 	}
@@ -1845,7 +1917,7 @@ func (m *TruncateShardGroupCommand) GetTruncateAt() uint64 {
 
 var E_TruncateShardGroupCommand_Command = &proto.ExtensionDesc{
 	ExtendedType:  (*Command)(nil),
-	ExtensionType: (*SetMetaNodeCommand)(nil),
+	ExtensionType: (*TruncateShardGroupCommand)(nil),
 	Field:         141,
 	Name:          "internal.TruncateShardGroupCommand.command",
 	Tag:           "bytes,141,opt,name=command",
@@ -1860,7 +1932,7 @@ type ChangeRoleNameCommand struct {
 func (m *ChangeRoleNameCommand) Reset()                    { *m = ChangeRoleNameCommand{} }
 func (m *ChangeRoleNameCommand) String() string            { return proto.CompactTextString(m) }
 func (*ChangeRoleNameCommand) ProtoMessage()               {}
-func (*ChangeRoleNameCommand) Descriptor() ([]byte, []int) { return fileDescriptorMeta, []int{49} }
+func (*ChangeRoleNameCommand) Descriptor() ([]byte, []int) { return fileDescriptorMeta, []int{50} }
 
 func (m *ChangeRoleNameCommand) GetOldName() string {
 	if m != nil && m.OldName != nil {
@@ -1899,7 +1971,7 @@ type ImportDataCommand struct {
 func (m *ImportDataCommand) Reset()                    { *m = ImportDataCommand{} }
 func (m *ImportDataCommand) String() string            { return proto.CompactTextString(m) }
 func (*ImportDataCommand) ProtoMessage()               {}
-func (*ImportDataCommand) Descriptor() ([]byte, []int) { return fileDescriptorMeta, []int{50} }
+func (*ImportDataCommand) Descriptor() ([]byte, []int) { return fileDescriptorMeta, []int{51} }
 
 func (m *ImportDataCommand) GetData() []byte {
 	if m != nil {
@@ -1976,7 +2048,7 @@ func (m *CreateBalancedShardGroupCommand) Reset()         { *m = CreateBalancedS
 func (m *CreateBalancedShardGroupCommand) String() string { return proto.CompactTextString(m) }
 func (*CreateBalancedShardGroupCommand) ProtoMessage()    {}
 func (*CreateBalancedShardGroupCommand) Descriptor() ([]byte, []int) {
-	return fileDescriptorMeta, []int{51}
+	return fileDescriptorMeta, []int{52}
 }
 
 func (m *CreateBalancedShardGroupCommand) GetDatabase() string {
@@ -2008,8 +2080,107 @@ var E_CreateBalancedShardGroupCommand_Command = &proto.ExtensionDesc{
 	Tag:           "bytes,144,opt,name=command",
 }
 
+// BatchCommand applies a list of commands atomically in a single raft entry.
+type BatchCommand struct {
+	Commands         []*Command `protobuf:"bytes,1,rep,name=Commands" json:"Commands,omitempty"`
+	XXX_unrecognized []byte     `json:"-"`
+}
+
+func (m *BatchCommand) Reset()                    { *m = BatchCommand{} }
+func (m *BatchCommand) String() string            { return proto.CompactTextString(m) }
+func (*BatchCommand) ProtoMessage()               {}
+func (*BatchCommand) Descriptor() ([]byte, []int) { return fileDescriptorMeta, []int{53} }
+
+func (m *BatchCommand) GetCommands() []*Command {
+	if m != nil {
+		return m.Commands
+	}
+	return nil
+}
+
+var E_BatchCommand_Command = &proto.ExtensionDesc{
+	ExtendedType:  (*Command)(nil),
+	ExtensionType: (*BatchCommand)(nil),
+	Field:         145,
+	Name:          "internal.BatchCommand.command",
+	Tag:           "bytes,145,opt,name=command",
+}
+
+// ReplaceDataNodeCommand hands the shards of a data node over to another
+// data node and removes it.
+type ReplaceDataNodeCommand struct {
+	OldID            *uint64 `protobuf:"varint,1,req,name=OldID" json:"OldID,omitempty"`
+	NewID            *uint64 `protobuf:"varint,2,req,name=NewID" json:"NewID,omitempty"`
+	XXX_unrecognized []byte  `json:"-"`
+}
+
+func (m *ReplaceDataNodeCommand) Reset()                    { *m = ReplaceDataNodeCommand{} }
+func (m *ReplaceDataNodeCommand) String() string            { return proto.CompactTextString(m) }
+func (*ReplaceDataNodeCommand) ProtoMessage()               {}
+func (*ReplaceDataNodeCommand) Descriptor() ([]byte, []int) { return fileDescriptorMeta, []int{54} }
+
+func (m *ReplaceDataNodeCommand) GetOldID() uint64 {
+	if m != nil && m.OldID != nil {
+		return *m.OldID
+	}
+	return 0
+}
+
+func (m *ReplaceDataNodeCommand) GetNewID() uint64 {
+	if m != nil && m.NewID != nil {
+		return *m.NewID
+	}
+	return 0
+}
+
+var E_ReplaceDataNodeCommand_Command = &proto.ExtensionDesc{
+	ExtendedType:  (*Command)(nil),
+	ExtensionType: (*ReplaceDataNodeCommand)(nil),
+	Field:         146,
+	Name:          "internal.ReplaceDataNodeCommand.command",
+	Tag:           "bytes,146,opt,name=command",
+}
+
+// SetDataNodeMaintenanceCommand puts a data node into maintenance mode, or
+// takes it out of it.
+type SetDataNodeMaintenanceCommand struct {
+	ID               *uint64 `protobuf:"varint,1,req,name=ID" json:"ID,omitempty"`
+	Maintenance      *bool   `protobuf:"varint,2,req,name=Maintenance" json:"Maintenance,omitempty"`
+	XXX_unrecognized []byte  `json:"-"`
+}
+
+func (m *SetDataNodeMaintenanceCommand) Reset()         { *m = SetDataNodeMaintenanceCommand{} }
+func (m *SetDataNodeMaintenanceCommand) String() string { return proto.CompactTextString(m) }
+func (*SetDataNodeMaintenanceCommand) ProtoMessage()    {}
+func (*SetDataNodeMaintenanceCommand) Descriptor() ([]byte, []int) {
+	return fileDescriptorMeta, []int{55}
+}
+
+func (m *SetDataNodeMaintenanceCommand) GetID() uint64 {
+	if m != nil && m.ID != nil {
+		return *m.ID
+	}
+	return 0
+}
+
+func (m *SetDataNodeMaintenanceCommand) GetMaintenance() bool {
+	if m != nil && m.Maintenance != nil {
+		return *m.Maintenance
+	}
+	return false
+}
+
+var E_SetDataNodeMaintenanceCommand_Command = &proto.ExtensionDesc{
+	ExtendedType:  (*Command)(nil),
+	ExtensionType: (*SetDataNodeMaintenanceCommand)(nil),
+	Field:         147,
+	Name:          "internal.SetDataNodeMaintenanceCommand.command",
+	Tag:           "bytes,147,opt,name=command",
+}
+
 func init() {
 	proto.RegisterType((*ClusterData)(nil), "internal.ClusterData")
+	proto.RegisterType((*ShardGroupAssignment)(nil), "internal.ShardGroupAssignment")
 	proto.RegisterType((*NodeInfo)(nil), "internal.NodeInfo")
 	proto.RegisterType((*RoleInfo)(nil), "internal.RoleInfo")
 	proto.RegisterType((*UserInfo)(nil), "internal.UserInfo")
@@ -2061,6 +2232,9 @@ func init() {
 	proto.RegisterType((*ChangeRoleNameCommand)(nil), "internal.ChangeRoleNameCommand")
 	proto.RegisterType((*ImportDataCommand)(nil), "internal.ImportDataCommand")
 	proto.RegisterType((*CreateBalancedShardGroupCommand)(nil), "internal.CreateBalancedShardGroupCommand")
+	proto.RegisterType((*BatchCommand)(nil), "internal.BatchCommand")
+	proto.RegisterType((*ReplaceDataNodeCommand)(nil), "internal.ReplaceDataNodeCommand")
+	proto.RegisterType((*SetDataNodeMaintenanceCommand)(nil), "internal.SetDataNodeMaintenanceCommand")
 	proto.RegisterEnum("internal.Command_Type", Command_Type_name, Command_Type_value)
 	proto.RegisterExtension(E_CreateDatabaseCommand_Command)
 	proto.RegisterExtension(E_DropDatabaseCommand_Command)
@@ -2106,133 +2280,137 @@ func init() {
 	proto.RegisterExtension(E_ChangeRoleNameCommand_Command)
 	proto.RegisterExtension(E_ImportDataCommand_Command)
 	proto.RegisterExtension(E_CreateBalancedShardGroupCommand_Command)
+	proto.RegisterExtension(E_BatchCommand_Command)
+	proto.RegisterExtension(E_ReplaceDataNodeCommand_Command)
+	proto.RegisterExtension(E_SetDataNodeMaintenanceCommand_Command)
 }
 
 func init() { proto.RegisterFile("internal/meta.proto", fileDescriptorMeta) }
 
 var fileDescriptorMeta = []byte{
-	// 1964 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xbc, 0x5a, 0xcd, 0x6f, 0x1b, 0xc7,
-	0x15, 0xc7, 0xf0, 0x43, 0x22, 0x47, 0xb2, 0x2c, 0x8f, 0x68, 0x69, 0xf5, 0x61, 0x89, 0xa6, 0x5d,
-	0x97, 0x55, 0x0b, 0xb5, 0xe0, 0xad, 0x47, 0x55, 0xb4, 0x6a, 0xd6, 0x95, 0x44, 0x2f, 0x65, 0x14,
-	0x3d, 0xf4, 0xb0, 0xe6, 0x8e, 0xa5, 0xad, 0xc9, 0x5d, 0x76, 0x77, 0xa9, 0x8f, 0xb6, 0x49, 0xe4,
-	0x38, 0x8e, 0x13, 0xc7, 0xce, 0x07, 0x10, 0x20, 0x87, 0x20, 0xc8, 0x21, 0x39, 0x25, 0x40, 0x72,
-	0x4b, 0x10, 0xe4, 0x10, 0x04, 0xf9, 0x77, 0x02, 0xe4, 0x14, 0xe4, 0x98, 0x60, 0x66, 0x77, 0x38,
-	0xfb, 0x31, 0x3b, 0xab, 0x75, 0x94, 0x9c, 0xc4, 0x79, 0xef, 0xcd, 0x7b, 0xbf, 0xf7, 0xe6, 0xed,
-	0xdb, 0x37, 0x6f, 0x05, 0x67, 0x0c, 0xd3, 0xc5, 0xb6, 0xa9, 0xf5, 0xfe, 0xd8, 0xc7, 0xae, 0xb6,
-	0x36, 0xb0, 0x2d, 0xd7, 0x42, 0x25, 0x46, 0xac, 0xfd, 0x00, 0xe0, 0xc4, 0x46, 0x6f, 0xe8, 0xb8,
-	0xd8, 0x6e, 0x6a, 0xae, 0x86, 0x10, 0x2c, 0x90, 0xbf, 0x0a, 0xa8, 0xe6, 0xea, 0x93, 0x2a, 0xfd,
-	0x8d, 0x96, 0x60, 0x79, 0x4b, 0x3b, 0xda, 0xb6, 0x74, 0xdc, 0x6a, 0x2a, 0xb9, 0x6a, 0xae, 0x5e,
-	0x50, 0x39, 0x01, 0xfd, 0x09, 0x96, 0x89, 0x14, 0x59, 0x39, 0x4a, 0xbe, 0x9a, 0xaf, 0x4f, 0x34,
-	0xd0, 0x1a, 0xd3, 0xbf, 0x46, 0x85, 0xcc, 0xbb, 0x96, 0xca, 0x85, 0xc8, 0x8e, 0x2d, 0xcc, 0x76,
-	0x14, 0x92, 0x77, 0x8c, 0x84, 0x50, 0x1d, 0x16, 0x55, 0xab, 0x87, 0x1d, 0xa5, 0x18, 0x95, 0x26,
-	0x64, 0x2a, 0xed, 0x09, 0x10, 0xc9, 0xdb, 0x0e, 0xb6, 0x1d, 0x65, 0x2c, 0x2a, 0x49, 0xc8, 0x9e,
-	0x24, 0x15, 0xa8, 0x1d, 0xc1, 0x12, 0x33, 0x85, 0xa6, 0x60, 0xae, 0xd5, 0xa4, 0x3e, 0x17, 0xd4,
-	0x5c, 0xab, 0x49, 0xa2, 0x70, 0xc3, 0x72, 0x5c, 0xea, 0x6c, 0x59, 0xa5, 0xbf, 0x91, 0x02, 0xc7,
-	0x77, 0x37, 0xda, 0x94, 0x9c, 0xaf, 0x82, 0x7a, 0x59, 0x65, 0x4b, 0xb4, 0x06, 0x51, 0x1b, 0x9b,
-	0xba, 0x61, 0xee, 0x75, 0xf6, 0x35, 0x5b, 0xdf, 0x39, 0x34, 0x09, 0x00, 0xe2, 0x58, 0x41, 0x15,
-	0x70, 0x6a, 0x0f, 0x00, 0x2c, 0x31, 0xdc, 0xc4, 0xd4, 0xb6, 0xd6, 0xc7, 0xd4, 0x78, 0x59, 0xa5,
-	0xbf, 0xd1, 0x9f, 0xe1, 0x44, 0x1b, 0xdb, 0x7d, 0xc3, 0x71, 0x0c, 0xcb, 0x74, 0x28, 0x8a, 0x89,
-	0xc6, 0x5c, 0xd8, 0x95, 0xb6, 0x6d, 0x1c, 0x18, 0x3d, 0xbc, 0x87, 0xd5, 0xa0, 0x2c, 0xf7, 0x3f,
-	0x4f, 0x37, 0x49, 0xfc, 0xef, 0xc3, 0x12, 0x23, 0x09, 0x41, 0x90, 0x18, 0x68, 0xce, 0xfe, 0x28,
-	0x06, 0x9a, 0xb3, 0x1f, 0x05, 0xe6, 0x9d, 0xf6, 0xa9, 0x80, 0xd5, 0x5a, 0xf0, 0x5c, 0x88, 0x8b,
-	0x16, 0x60, 0x89, 0xa4, 0xc4, 0x1d, 0xcd, 0x61, 0x76, 0x47, 0x6b, 0x92, 0x71, 0x23, 0x41, 0x0a,
-	0xa0, 0xa8, 0x72, 0x42, 0xed, 0x1e, 0x9c, 0xee, 0x74, 0xad, 0x01, 0xd6, 0xb9, 0x7e, 0xb2, 0x43,
-	0xc5, 0x8e, 0x35, 0xb4, 0xbb, 0xd8, 0xf1, 0x93, 0x97, 0x13, 0x7e, 0x46, 0x40, 0x6b, 0x9b, 0xb0,
-	0xa4, 0x62, 0x67, 0x60, 0x99, 0x0e, 0x26, 0x69, 0xb2, 0x73, 0x93, 0x6a, 0x2f, 0xa9, 0xb9, 0x9d,
-	0x9b, 0xa8, 0x02, 0x8b, 0xd7, 0x6d, 0xdb, 0xb2, 0x95, 0x1c, 0x4d, 0x08, 0x6f, 0x41, 0xa8, 0x2d,
-	0x53, 0xc7, 0x47, 0x34, 0x4d, 0x0a, 0xaa, 0xb7, 0xa8, 0x7d, 0x02, 0xe1, 0xf8, 0x86, 0xd5, 0xef,
-	0x6b, 0xa6, 0x8e, 0x56, 0x61, 0xc1, 0x3d, 0x1e, 0x78, 0x6e, 0x4f, 0x35, 0x66, 0x39, 0x0e, 0x5f,
-	0x60, 0x6d, 0xf7, 0x78, 0x80, 0x55, 0x2a, 0x53, 0xfb, 0xb6, 0x0c, 0x0b, 0x64, 0x89, 0xe6, 0xe1,
-	0xc5, 0x0d, 0x1b, 0x6b, 0x2e, 0x66, 0x51, 0xf2, 0x85, 0xa7, 0x01, 0x9a, 0x83, 0x33, 0x4d, 0xdb,
-	0x1a, 0x44, 0x19, 0x39, 0x54, 0x85, 0x4b, 0xde, 0x1e, 0x15, 0xbb, 0xd8, 0x74, 0x0d, 0xcb, 0x6c,
-	0x5b, 0x3d, 0xa3, 0x7b, 0xcc, 0x24, 0xf2, 0x68, 0x19, 0x2e, 0x90, 0xad, 0x09, 0xfc, 0x02, 0xba,
-	0x0a, 0xab, 0x1d, 0xec, 0x36, 0xf1, 0x5d, 0x6d, 0xd8, 0x73, 0x13, 0xa4, 0x8a, 0xc4, 0xce, 0xed,
-	0x81, 0x9e, 0x6c, 0x67, 0x0c, 0x2d, 0xc2, 0x39, 0x0f, 0x09, 0x7d, 0x10, 0xfe, 0x6a, 0x5b, 0xc3,
-	0x01, 0x63, 0x8e, 0x13, 0x66, 0x13, 0xf7, 0xb0, 0x88, 0x59, 0xe2, 0x3e, 0x6c, 0x58, 0xa6, 0x6b,
-	0x98, 0x43, 0x6b, 0xe8, 0xdc, 0x1a, 0x62, 0x7b, 0xa4, 0xbb, 0xcc, 0x7c, 0x48, 0xe0, 0x43, 0x74,
-	0x11, 0x5e, 0xf0, 0x34, 0x90, 0x63, 0x66, 0xe4, 0x09, 0x34, 0x03, 0xcf, 0x93, 0x6d, 0x41, 0xe2,
-	0x24, 0x91, 0xf5, 0x3c, 0x09, 0x92, 0xcf, 0x91, 0x08, 0x77, 0xb0, 0x3b, 0x4a, 0x11, 0xc6, 0x98,
-	0xe2, 0xba, 0xc9, 0x03, 0xcd, 0xc8, 0xe7, 0x99, 0xee, 0x20, 0x71, 0x9a, 0x28, 0x59, 0xd7, 0x75,
-	0x42, 0xa3, 0x4f, 0x20, 0x63, 0x5c, 0x40, 0x0b, 0x70, 0x56, 0xc5, 0x7d, 0xeb, 0x00, 0xc7, 0x78,
-	0x08, 0x5d, 0x82, 0xf3, 0xfe, 0xa6, 0x40, 0x56, 0x32, 0xf6, 0x0c, 0x89, 0x0e, 0xdf, 0x2a, 0x90,
-	0xa8, 0x20, 0x04, 0xa7, 0xc8, 0x09, 0x6a, 0xae, 0xc6, 0x68, 0x17, 0xd1, 0x12, 0x54, 0x3a, 0xd8,
-	0x5d, 0xd7, 0xfb, 0x86, 0x19, 0xf3, 0x69, 0x96, 0x98, 0xf4, 0xcf, 0x6a, 0x78, 0xc7, 0xe9, 0xda,
-	0xc6, 0x80, 0x1c, 0x28, 0x63, 0xcf, 0xd1, 0xd3, 0xb2, 0xad, 0x81, 0x88, 0xa9, 0x90, 0x78, 0x78,
-	0x78, 0xda, 0x98, 0xc7, 0x6f, 0x9e, 0x27, 0x2f, 0xab, 0xe9, 0x8c, 0xb5, 0x10, 0xce, 0xeb, 0x20,
-	0x6b, 0x91, 0xb0, 0xbc, 0xc3, 0x88, 0xb2, 0x96, 0x08, 0xcb, 0x4b, 0x99, 0xa8, 0xc2, 0x4b, 0x9c,
-	0x15, 0xdd, 0xb5, 0x8c, 0x66, 0x21, 0xea, 0x60, 0x37, 0xba, 0x65, 0x05, 0x55, 0xe0, 0x34, 0x75,
-	0x89, 0xa4, 0x1f, 0xa3, 0x56, 0x89, 0x2f, 0xad, 0xfe, 0xc0, 0xb2, 0x43, 0xc1, 0xbb, 0x4c, 0x4e,
-	0xab, 0x83, 0x5d, 0x5a, 0x32, 0x34, 0xc7, 0x39, 0xb4, 0xf8, 0x96, 0x9a, 0x7f, 0x5a, 0x94, 0x17,
-	0x3f, 0x8b, 0x2b, 0xfc, 0xb4, 0x12, 0x24, 0xae, 0x22, 0x05, 0x56, 0xd6, 0x75, 0x9d, 0xbf, 0x2d,
-	0x18, 0xe7, 0x37, 0x24, 0xec, 0xde, 0xde, 0x38, 0xf3, 0x1a, 0x5a, 0x81, 0x8b, 0xeb, 0xba, 0x1e,
-	0x7b, 0xd7, 0x30, 0x81, 0xdf, 0xa2, 0x1a, 0x5c, 0x26, 0x0b, 0xc3, 0x4d, 0x94, 0xa9, 0x13, 0x19,
-	0x76, 0x76, 0x09, 0x32, 0xbf, 0x23, 0xcf, 0xda, 0xae, 0x3d, 0x34, 0xbb, 0xa1, 0x27, 0x79, 0x84,
-	0x7f, 0x95, 0x9e, 0xe6, 0xbe, 0x66, 0xee, 0xd1, 0x7c, 0x24, 0xef, 0x11, 0xc6, 0xfa, 0x3d, 0xba,
-	0x02, 0x57, 0xbc, 0x83, 0xfe, 0x8b, 0xd6, 0xd3, 0xcc, 0x2e, 0xd6, 0xe3, 0x4f, 0xfb, 0x1f, 0x56,
-	0x4b, 0x25, 0x7d, 0xfa, 0xe4, 0xe4, 0xe4, 0x24, 0x57, 0xfb, 0x00, 0x24, 0x14, 0x3c, 0xe1, 0xdb,
-	0xaa, 0x0e, 0xcf, 0x47, 0x6a, 0x0f, 0x2d, 0xca, 0x93, 0x6a, 0x94, 0xdc, 0xf8, 0x3b, 0x1c, 0xef,
-	0xfa, 0x8a, 0x2e, 0xc4, 0x2a, 0xaf, 0x82, 0xab, 0xa0, 0x3e, 0xd1, 0x58, 0x09, 0x30, 0x44, 0x10,
-	0x54, 0xa6, 0xa2, 0x36, 0x14, 0x96, 0x5e, 0x11, 0xc4, 0xc6, 0xdf, 0xa4, 0x86, 0xef, 0x52, 0xc3,
-	0x97, 0x38, 0x43, 0xa0, 0x96, 0x9b, 0xfd, 0x02, 0xc8, 0x2b, 0xbb, 0xf4, 0xed, 0x2a, 0x8c, 0x55,
-	0x4e, 0x14, 0xab, 0x8e, 0x14, 0xf2, 0x1e, 0x85, 0x7c, 0x2d, 0x1a, 0x2b, 0x31, 0x22, 0x8e, 0xfd,
-	0x7d, 0x20, 0x7b, 0xe7, 0x48, 0x91, 0xb3, 0xb0, 0xe6, 0x02, 0x61, 0xbd, 0x25, 0xc5, 0xb8, 0x4f,
-	0x31, 0x5e, 0x0d, 0x87, 0x35, 0x0d, 0xe1, 0xc7, 0x20, 0xfd, 0xad, 0x97, 0x19, 0xe7, 0x3f, 0xa4,
-	0x38, 0x0d, 0x8a, 0x73, 0x95, 0x33, 0xd2, 0xec, 0x73, 0xb4, 0xdf, 0x03, 0xf9, 0xdb, 0x37, 0x2b,
-	0x52, 0xd2, 0xe9, 0x6e, 0xe3, 0x43, 0x4a, 0xf6, 0x3b, 0x5d, 0x7f, 0x49, 0x35, 0x0d, 0x6d, 0x8d,
-	0x98, 0x50, 0x0a, 0x55, 0x50, 0xcf, 0xab, 0xa3, 0x35, 0xe1, 0xa9, 0x78, 0xd0, 0x33, 0xba, 0xda,
-	0xb6, 0x52, 0xac, 0x82, 0xfa, 0x39, 0x75, 0xb4, 0x4e, 0xc9, 0xa3, 0x7f, 0x47, 0xf3, 0x48, 0xe6,
-	0x0d, 0xf7, 0xfb, 0x4b, 0x90, 0xd8, 0x53, 0x48, 0x5d, 0x9e, 0x85, 0x63, 0x81, 0xac, 0x2f, 0xab,
-	0xfe, 0x8a, 0xb4, 0x90, 0xbb, 0x46, 0x1f, 0x3b, 0xae, 0xd6, 0x1f, 0xd0, 0xf6, 0x39, 0xaf, 0x72,
-	0x42, 0x63, 0x5b, 0xea, 0xc2, 0x3d, 0xea, 0xc2, 0xe5, 0xe8, 0xa3, 0x10, 0x03, 0xc6, 0xd1, 0x7f,
-	0x0d, 0x12, 0x9b, 0x9e, 0x67, 0x42, 0x5f, 0x83, 0x93, 0x5c, 0x51, 0xab, 0x49, 0x1d, 0x28, 0xa8,
-	0x21, 0x5a, 0x8a, 0x0f, 0xbd, 0xa8, 0x0f, 0x09, 0xf0, 0x44, 0x55, 0x48, 0xdc, 0x7b, 0x65, 0xce,
-	0xbc, 0x0a, 0x2c, 0xd2, 0xfd, 0x14, 0x7d, 0x59, 0xf5, 0x16, 0x29, 0xd9, 0xd3, 0x17, 0x57, 0x21,
-	0x31, 0xa2, 0x78, 0x15, 0x3a, 0x1b, 0xe4, 0x29, 0x55, 0xc8, 0x14, 0x55, 0xa1, 0x34, 0x84, 0xef,
-	0x02, 0x41, 0xdf, 0x7a, 0xea, 0xab, 0x5a, 0x05, 0x16, 0x69, 0x7f, 0x47, 0x43, 0x59, 0x52, 0xbd,
-	0x45, 0xe3, 0x86, 0x14, 0xa6, 0x45, 0x61, 0x2e, 0x46, 0x43, 0x19, 0x30, 0xcf, 0xd1, 0xf5, 0x63,
-	0xdd, 0xb3, 0xf0, 0xa5, 0xb7, 0x29, 0x35, 0x38, 0xa0, 0x06, 0xe7, 0xc3, 0x71, 0x11, 0x9a, 0x7b,
-	0x08, 0x04, 0x8d, 0xf9, 0x69, 0x83, 0x91, 0xe2, 0xf6, 0x7f, 0xa2, 0x6e, 0xc7, 0x0c, 0x71, 0x1c,
-	0x9f, 0x03, 0xe1, 0x4d, 0x80, 0xe4, 0x0b, 0x91, 0x37, 0x39, 0x9a, 0xd1, 0x3a, 0x94, 0x4b, 0x39,
-	0xd9, 0x4d, 0x37, 0x1f, 0xb9, 0xe9, 0xa6, 0xb4, 0x0c, 0x76, 0xb4, 0x65, 0x10, 0x00, 0xe3, 0xc8,
-	0xff, 0x25, 0xb8, 0xa9, 0xa4, 0x04, 0xc6, 0x11, 0xe7, 0x43, 0x40, 0x01, 0x57, 0xff, 0xcf, 0xd8,
-	0x8d, 0x27, 0xe5, 0xec, 0x5d, 0xd1, 0xd9, 0x0b, 0x55, 0x6b, 0xc2, 0x7b, 0x53, 0x4a, 0x70, 0x86,
-	0xd1, 0xe0, 0x08, 0x54, 0x70, 0x13, 0x7b, 0x49, 0x37, 0xb0, 0xc6, 0x96, 0xd4, 0xca, 0x01, 0xb5,
-	0x52, 0x0d, 0xcc, 0xa3, 0x84, 0x5a, 0x82, 0x8f, 0x4d, 0xf2, 0x75, 0xae, 0xd1, 0x96, 0xda, 0x3a,
-	0xa4, 0xb6, 0xae, 0xc4, 0x3c, 0x8a, 0x2b, 0xe2, 0xe6, 0x1c, 0xf9, 0xf5, 0x30, 0xa5, 0xb4, 0x1e,
-	0x45, 0x4b, 0xab, 0x4c, 0x17, 0x37, 0x7a, 0x2f, 0x7a, 0xe3, 0x14, 0x4d, 0x15, 0x1b, 0xd7, 0xa5,
-	0xa6, 0x8f, 0xa9, 0x69, 0x25, 0xdc, 0x0f, 0x71, 0x8d, 0xdc, 0xd8, 0x7b, 0x20, 0xf9, 0x2e, 0x2b,
-	0x7d, 0x2a, 0x47, 0x05, 0x32, 0x17, 0x2c, 0x90, 0x3b, 0x52, 0x54, 0xff, 0xa5, 0xa8, 0x6a, 0x21,
-	0x54, 0x42, 0xcb, 0x1c, 0xdf, 0x8f, 0x40, 0x72, 0x9b, 0x16, 0x16, 0x30, 0x59, 0xb9, 0x10, 0xb4,
-	0xee, 0xde, 0xab, 0x32, 0x4a, 0x26, 0x9a, 0xb7, 0x2c, 0x1d, 0x2b, 0x05, 0x4f, 0x33, 0xf9, 0x4d,
-	0x7a, 0x84, 0x26, 0x76, 0x5c, 0xc3, 0xa4, 0x1d, 0x9b, 0x37, 0x4d, 0x2d, 0xab, 0x21, 0x5a, 0x4a,
-	0x0e, 0xfe, 0x2f, 0x9a, 0x83, 0x89, 0xae, 0xf1, 0x08, 0x7c, 0x03, 0x12, 0x07, 0x06, 0xbf, 0x9c,
-	0xff, 0x29, 0xbd, 0xce, 0xff, 0x63, 0xbd, 0x8e, 0x18, 0x20, 0xf7, 0xe2, 0x3e, 0x10, 0x4c, 0x36,
-	0x46, 0x83, 0x63, 0xc0, 0x07, 0xc7, 0xeb, 0xba, 0x6e, 0xb3, 0x97, 0x0f, 0xf9, 0x9d, 0x52, 0x63,
-	0x9f, 0x8b, 0xd6, 0xd8, 0x98, 0x11, 0x8e, 0xe1, 0x53, 0x90, 0x30, 0x46, 0x21, 0x31, 0xbb, 0xb1,
-	0xbb, 0xdb, 0xa6, 0xb6, 0xfd, 0x44, 0x67, 0x6b, 0x7f, 0x70, 0x1d, 0x80, 0xc5, 0x96, 0x04, 0xad,
-	0x4a, 0x30, 0x78, 0xbd, 0x22, 0xfd, 0x9d, 0x72, 0x3d, 0x7e, 0x5e, 0x7c, 0x3d, 0x8e, 0xc0, 0x09,
-	0xf5, 0x30, 0xe2, 0xe9, 0xce, 0xb3, 0x21, 0x4e, 0x41, 0xf7, 0x42, 0xf2, 0xe5, 0x5d, 0x88, 0xee,
-	0x43, 0x90, 0x30, 0x60, 0xca, 0xfe, 0x41, 0x20, 0x17, 0xf8, 0x20, 0x90, 0xf2, 0xce, 0x38, 0x01,
-	0x51, 0x98, 0x42, 0x0c, 0x1c, 0xe6, 0x41, 0xc2, 0xac, 0x2b, 0x8a, 0x32, 0xc5, 0xee, 0xfd, 0x98,
-	0x5d, 0xa1, 0x56, 0x81, 0xdd, 0x94, 0xe8, 0xa4, 0xd8, 0x7d, 0x31, 0xc1, 0x6e, 0xa2, 0xbf, 0x1f,
-	0x01, 0xd1, 0x98, 0xee, 0x0c, 0x73, 0x5c, 0xde, 0x39, 0x3c, 0xf0, 0xf0, 0x2e, 0x85, 0xaa, 0x7c,
-	0x62, 0x90, 0xcc, 0xf8, 0xe8, 0x30, 0x16, 0x1f, 0xb9, 0xbd, 0x97, 0x32, 0xd9, 0x7b, 0x02, 0x92,
-	0xc6, 0x8f, 0xa7, 0xee, 0x86, 0xe5, 0x70, 0x1e, 0x66, 0x82, 0xf3, 0x19, 0x90, 0x4c, 0x3c, 0xcf,
-	0xf8, 0xe3, 0x56, 0x0a, 0xf0, 0x97, 0x33, 0x01, 0x27, 0x77, 0x57, 0xd9, 0x2c, 0xf6, 0xd7, 0xc5,
-	0xfe, 0x28, 0x13, 0xf6, 0xc7, 0x40, 0x3c, 0x25, 0x8e, 0x95, 0xad, 0x59, 0x38, 0x16, 0xfa, 0x6c,
-	0xeb, 0xaf, 0x52, 0xc0, 0xbc, 0x92, 0x09, 0xcc, 0x53, 0x90, 0x38, 0x98, 0x3e, 0x23, 0x3c, 0xaf,
-	0x66, 0xc2, 0xf3, 0x16, 0x90, 0xce, 0xc2, 0xcf, 0x08, 0xd3, 0xe3, 0x4c, 0x98, 0xde, 0x06, 0x69,
-	0xa3, 0xf5, 0x33, 0x82, 0xf5, 0x5a, 0x66, 0x58, 0xf2, 0xaf, 0x02, 0x67, 0x04, 0xeb, 0x49, 0x26,
-	0x58, 0x8f, 0x00, 0x9c, 0x8f, 0x7f, 0x64, 0x60, 0x88, 0x96, 0x21, 0x64, 0xcc, 0x75, 0xd7, 0x47,
-	0x16, 0xa0, 0xa4, 0x20, 0x79, 0x9a, 0x09, 0xc9, 0x3b, 0x20, 0xe1, 0x73, 0x06, 0x79, 0xe1, 0xec,
-	0xf4, 0xf4, 0x40, 0x81, 0x60, 0xcb, 0xe0, 0xf4, 0xd4, 0x7f, 0x15, 0xf9, 0xcb, 0x14, 0x64, 0xaf,
-	0x67, 0x42, 0xf6, 0x5d, 0x4e, 0xf0, 0x71, 0x4a, 0xf8, 0xdf, 0x1b, 0x15, 0x58, 0xdc, 0xb4, 0xec,
-	0x2e, 0x66, 0xf7, 0x1c, 0xba, 0x08, 0x35, 0xd9, 0xf9, 0xf4, 0x26, 0xbb, 0x20, 0xbe, 0x64, 0x28,
-	0x70, 0x9c, 0x1e, 0x50, 0x4b, 0x57, 0x8a, 0xf4, 0x20, 0xd8, 0x12, 0x55, 0xe1, 0xc4, 0x36, 0x3e,
-	0x1c, 0x99, 0x18, 0xa3, 0xfb, 0x83, 0x24, 0xb4, 0x06, 0xd1, 0x36, 0x3e, 0x8c, 0x1a, 0x1a, 0xa7,
-	0xc8, 0x05, 0x1c, 0xd4, 0x80, 0x15, 0x4a, 0xa5, 0x23, 0x65, 0x42, 0xdf, 0xd4, 0xba, 0xae, 0x65,
-	0x2b, 0x25, 0x6a, 0x58, 0xc8, 0x4b, 0x89, 0xf8, 0x1b, 0x99, 0x22, 0xfe, 0x15, 0x48, 0xfd, 0x7e,
-	0x95, 0x61, 0x70, 0x3b, 0x79, 0xca, 0xb1, 0xb3, 0xdc, 0x83, 0x37, 0xb3, 0x78, 0xf0, 0x53, 0x00,
-	0x00, 0x00, 0xff, 0xff, 0x6b, 0xb4, 0x5d, 0x43, 0x0b, 0x24, 0x00, 0x00,
+	// 1971 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xb4, 0x59, 0x6d, 0x6f, 0xdc, 0xc6,
+	0x11, 0x06, 0xef, 0x45, 0xba, 0x1b, 0xdd, 0xc9, 0xd2, 0x4a, 0xb6, 0x28, 0x59, 0x96, 0xcf, 0x6b,
+	0x27, 0xb9, 0xba, 0xa9, 0x02, 0x1c, 0xf2, 0xa1, 0xe8, 0x0b, 0x0a, 0x45, 0x17, 0xc7, 0x6a, 0x6b,
+	0x59, 0x95, 0x2e, 0x40, 0x3f, 0x14, 0x01, 0x98, 0xe3, 0x4a, 0x62, 0x7a, 0x47, 0xb2, 0x24, 0xcf,
+	0x67, 0xb5, 0x76, 0xad, 0x36, 0x6d, 0x9a, 0xbe, 0x24, 0x69, 0x53, 0x14, 0x68, 0x0a, 0xf4, 0xa7,
+	0xb4, 0x45, 0x7f, 0x56, 0x81, 0xa2, 0x28, 0x76, 0x79, 0x7b, 0x24, 0x97, 0xbb, 0x4b, 0xda, 0x46,
+	0x3e, 0x59, 0xde, 0x19, 0xce, 0xf3, 0xcc, 0xcc, 0xee, 0xec, 0xec, 0x1c, 0xac, 0x39, 0x6e, 0x44,
+	0x02, 0xd7, 0x1a, 0xbd, 0x31, 0x26, 0x91, 0xb5, 0xeb, 0x07, 0x5e, 0xe4, 0xa1, 0x06, 0x5f, 0xc4,
+	0xff, 0x35, 0x60, 0x69, 0x7f, 0x34, 0x09, 0x23, 0x12, 0xf4, 0xad, 0xc8, 0x42, 0x2d, 0xa8, 0xd1,
+	0x7f, 0x4d, 0xa3, 0x53, 0xe9, 0xb6, 0xd0, 0x2a, 0x34, 0x1f, 0x58, 0x8f, 0x0f, 0x3d, 0x9b, 0x1c,
+	0xf4, 0xcd, 0x4a, 0xa7, 0xd2, 0xad, 0xa1, 0x57, 0xa0, 0x49, 0x15, 0xe8, 0x5a, 0x68, 0x56, 0x3b,
+	0xd5, 0xee, 0x52, 0x0f, 0xed, 0x72, 0x73, 0xbb, 0x4c, 0xd5, 0x3d, 0xf5, 0xa8, 0xda, 0x03, 0xc2,
+	0xd5, 0x6a, 0x4a, 0xb5, 0x5b, 0x50, 0x3f, 0xf6, 0x46, 0x24, 0x34, 0xeb, 0xa2, 0x0a, 0x5d, 0xe6,
+	0x2a, 0xef, 0x86, 0x24, 0x08, 0xcd, 0x05, 0x51, 0x85, 0x2e, 0x33, 0x95, 0xaf, 0xc3, 0xca, 0xc9,
+	0xb9, 0x15, 0xd8, 0x7b, 0x61, 0xe8, 0x9c, 0xb9, 0x63, 0xe2, 0x46, 0xa1, 0xb9, 0xc8, 0xb4, 0x77,
+	0x12, 0x6d, 0xa6, 0xf1, 0x4e, 0xe0, 0x4d, 0xfc, 0x44, 0x0d, 0x7f, 0x03, 0xd6, 0x65, 0xeb, 0x68,
+	0x1d, 0x5a, 0xc9, 0xfa, 0x41, 0x9f, 0x85, 0xa3, 0x46, 0x83, 0xf3, 0xc0, 0xb3, 0x09, 0x8b, 0x44,
+	0x13, 0x9f, 0x42, 0x63, 0xee, 0x07, 0x40, 0x25, 0xad, 0x75, 0xdf, 0x0b, 0xa3, 0x58, 0x0b, 0x5d,
+	0x81, 0xc5, 0xc1, 0xfe, 0x11, 0x5b, 0xa8, 0x76, 0x8c, 0x6e, 0x13, 0x6d, 0x01, 0x3a, 0x22, 0xae,
+	0xed, 0xb8, 0x67, 0x0c, 0xe1, 0xe1, 0xd4, 0x25, 0x41, 0x1c, 0xa2, 0x1a, 0x5a, 0x83, 0xa5, 0x07,
+	0x16, 0x65, 0xec, 0x5a, 0xee, 0x90, 0x98, 0xf5, 0x8e, 0xd1, 0x6d, 0x60, 0x07, 0x1a, 0xf3, 0x60,
+	0xb4, 0xa0, 0x76, 0x68, 0x8d, 0x09, 0x43, 0x6a, 0xa2, 0xd7, 0x61, 0xe9, 0x88, 0x04, 0x63, 0x27,
+	0x0c, 0x1d, 0xcf, 0x0d, 0x19, 0xe0, 0x52, 0x6f, 0x23, 0x1b, 0xa0, 0xa3, 0xc0, 0x79, 0xe4, 0x8c,
+	0xc8, 0x19, 0x49, 0x02, 0x59, 0xed, 0x54, 0xe4, 0x81, 0xc4, 0x03, 0x68, 0xf0, 0xbf, 0x05, 0x28,
+	0xea, 0x94, 0x15, 0x9e, 0x9b, 0x15, 0x19, 0x70, 0xbc, 0x0d, 0x54, 0xc0, 0xf8, 0x4d, 0x68, 0x67,
+	0x99, 0xac, 0x40, 0x83, 0xee, 0xa1, 0xf7, 0xad, 0x90, 0x9b, 0x5f, 0x85, 0xe6, 0x5c, 0xcc, 0x30,
+	0xea, 0xf8, 0x04, 0x56, 0x4e, 0x86, 0x9e, 0x4f, 0xec, 0x04, 0x89, 0xaa, 0x1d, 0x93, 0xd0, 0x9b,
+	0x04, 0x43, 0x12, 0xce, 0xb6, 0xe8, 0x73, 0xc5, 0x00, 0xbf, 0x09, 0x8d, 0x63, 0x12, 0xfa, 0x9e,
+	0x1b, 0x12, 0x9a, 0xb3, 0x87, 0xdf, 0x63, 0x56, 0x1a, 0xa8, 0x0d, 0xf5, 0xb7, 0x83, 0xc0, 0x0b,
+	0xcc, 0x0a, 0xcb, 0x51, 0x1b, 0xea, 0x07, 0xae, 0x4d, 0x1e, 0xb3, 0x94, 0xd5, 0xf0, 0x7f, 0x00,
+	0x16, 0xf7, 0xbd, 0xf1, 0xd8, 0x72, 0x6d, 0x74, 0x07, 0x6a, 0xd1, 0x85, 0x1f, 0xf3, 0x5e, 0xee,
+	0x5d, 0x4b, 0x80, 0x66, 0x0a, 0xbb, 0x83, 0x0b, 0x9f, 0xe0, 0x7f, 0x00, 0xd4, 0xe8, 0x1f, 0x68,
+	0x13, 0xae, 0xee, 0x07, 0xc4, 0x8a, 0x08, 0x77, 0x78, 0xa6, 0xb6, 0x62, 0xa0, 0x0d, 0x58, 0xeb,
+	0x07, 0x9e, 0x2f, 0x0a, 0x2a, 0xa8, 0x03, 0xdb, 0xf1, 0x37, 0xc7, 0x24, 0x22, 0x6e, 0xe4, 0x78,
+	0xee, 0x91, 0x37, 0x72, 0x86, 0x17, 0x5c, 0xa3, 0x8a, 0x76, 0x60, 0x8b, 0x7e, 0xaa, 0x90, 0xd7,
+	0xd0, 0x1d, 0xe8, 0x9c, 0x90, 0xa8, 0x4f, 0x4e, 0xad, 0xc9, 0x28, 0x52, 0x68, 0xd5, 0x29, 0xce,
+	0xbb, 0xbe, 0xad, 0xc6, 0x59, 0x40, 0xd7, 0x61, 0x23, 0x66, 0x92, 0x1c, 0x06, 0x2e, 0x5c, 0xa4,
+	0xc2, 0x3e, 0x19, 0x11, 0x99, 0xb0, 0x91, 0xf8, 0xb0, 0xef, 0xb9, 0x91, 0xe3, 0x4e, 0xbc, 0x49,
+	0xf8, 0x83, 0x09, 0x09, 0xe6, 0xb6, 0x9b, 0xdc, 0x07, 0x85, 0x1c, 0xd0, 0x55, 0x58, 0x8d, 0x2d,
+	0xd0, 0x0c, 0xf2, 0xe5, 0x25, 0xb4, 0x06, 0x57, 0xe8, 0x67, 0xe9, 0xc5, 0x16, 0xd5, 0x8d, 0x3d,
+	0x49, 0x2f, 0xb7, 0x69, 0x84, 0x4f, 0x48, 0x34, 0xcf, 0x3e, 0x17, 0x2c, 0x27, 0xb6, 0xe9, 0xc1,
+	0xe2, 0xcb, 0x57, 0xb8, 0xed, 0xf4, 0xe2, 0x0a, 0x35, 0xb2, 0x67, 0xdb, 0x74, 0x8d, 0x9d, 0x1e,
+	0x2e, 0x58, 0x45, 0x5b, 0x70, 0xed, 0x98, 0x8c, 0xbd, 0x47, 0x24, 0x27, 0x43, 0xe8, 0x06, 0x6c,
+	0xce, 0x3e, 0x4a, 0x6d, 0x4e, 0x2e, 0x5e, 0xa3, 0xd1, 0x49, 0x3e, 0x95, 0x68, 0xac, 0x23, 0x04,
+	0xcb, 0x34, 0x83, 0x56, 0x64, 0xf1, 0xb5, 0xab, 0x68, 0x1b, 0xcc, 0x13, 0x12, 0xed, 0xd9, 0x63,
+	0xc7, 0xcd, 0xf9, 0x74, 0x8d, 0x42, 0xce, 0x72, 0x35, 0x79, 0x3f, 0x1c, 0x06, 0x8e, 0x4f, 0x13,
+	0xca, 0xc5, 0x1b, 0x2c, 0x5b, 0x81, 0xe7, 0xcb, 0x84, 0x26, 0x8d, 0x47, 0xcc, 0xe7, 0x88, 0x24,
+	0xf1, 0xdb, 0x4c, 0x36, 0x2f, 0x2f, 0xe5, 0x5c, 0xb4, 0x95, 0xdd, 0xd7, 0x69, 0xd1, 0x75, 0x2a,
+	0x8a, 0x93, 0x21, 0x8a, 0xb6, 0xa9, 0x28, 0xde, 0x32, 0xa2, 0xc1, 0x1b, 0x89, 0x48, 0xfc, 0x6a,
+	0x07, 0x5d, 0x03, 0x74, 0x42, 0x22, 0xf1, 0x93, 0x9b, 0x68, 0x1d, 0x56, 0x98, 0x4b, 0x74, 0xfb,
+	0xf1, 0xd5, 0x0e, 0xf5, 0xe5, 0x60, 0xec, 0x7b, 0x41, 0x26, 0x78, 0xb7, 0x68, 0xb6, 0x4e, 0x48,
+	0xc4, 0xaa, 0x81, 0x15, 0x86, 0x53, 0x2f, 0xf9, 0x04, 0xcf, 0xb2, 0xc5, 0x64, 0xf9, 0x5c, 0xdc,
+	0x4e, 0xb2, 0xa5, 0xd0, 0xb8, 0x83, 0x4c, 0x58, 0xdf, 0xb3, 0xed, 0xa4, 0x9e, 0x73, 0xc9, 0x2b,
+	0x34, 0xec, 0xf1, 0xb7, 0x79, 0xe1, 0xab, 0xe8, 0x26, 0x5c, 0xdf, 0xb3, 0xed, 0xdc, 0x6d, 0xc0,
+	0x15, 0x5e, 0x43, 0x18, 0x76, 0xe8, 0x7f, 0x9c, 0x48, 0xa9, 0xd3, 0xa5, 0x3a, 0x3c, 0x77, 0x0a,
+	0x9d, 0xaf, 0xd0, 0xb3, 0x36, 0x08, 0x26, 0xee, 0x30, 0x73, 0x92, 0xe7, 0xfc, 0xef, 0xb2, 0x6c,
+	0x9e, 0x5b, 0xee, 0x19, 0xdb, 0x8f, 0xb4, 0xea, 0x73, 0xd1, 0x57, 0xd1, 0x6d, 0xb8, 0x19, 0x27,
+	0xfa, 0x2d, 0x6b, 0x44, 0x2f, 0x25, 0x3b, 0x7f, 0xda, 0x5f, 0x47, 0x2b, 0xd0, 0x7a, 0xcb, 0x8a,
+	0x86, 0xe7, 0x7c, 0xe5, 0x6b, 0xf1, 0xe1, 0xf0, 0x47, 0xd6, 0x30, 0x97, 0xcf, 0x5d, 0x74, 0x0b,
+	0x6e, 0xcc, 0xf6, 0x36, 0x5d, 0x4f, 0x5d, 0x78, 0x5c, 0xe5, 0x8d, 0xbb, 0x8d, 0x86, 0xbd, 0x72,
+	0x79, 0x79, 0x79, 0x59, 0xc1, 0x1f, 0x1a, 0x8a, 0x0a, 0x2a, 0x5c, 0x50, 0x1b, 0x70, 0x45, 0x28,
+	0x63, 0xac, 0x96, 0xb7, 0x7a, 0xfb, 0xb0, 0x38, 0x9c, 0x7d, 0xb1, 0x9a, 0xab, 0xd6, 0x26, 0xe9,
+	0x18, 0xdd, 0xa5, 0xde, 0xcd, 0x94, 0x40, 0x86, 0x85, 0x4f, 0xa5, 0xb5, 0x3a, 0x4b, 0xa1, 0xb7,
+	0xa7, 0x45, 0x3a, 0x65, 0x48, 0x37, 0x12, 0x81, 0xc4, 0x20, 0xfe, 0x8b, 0xa1, 0xaf, 0xfd, 0x92,
+	0xab, 0x53, 0xea, 0x78, 0xa5, 0xdb, 0xea, 0x7d, 0x57, 0x4b, 0xe7, 0x8c, 0xd1, 0x79, 0x55, 0x74,
+	0x5c, 0x0e, 0x8b, 0x3f, 0x32, 0x74, 0x37, 0x8e, 0x84, 0x15, 0x8f, 0x0c, 0xeb, 0x17, 0x7a, 0xf7,
+	0xb5, 0x54, 0xce, 0x19, 0x95, 0x3b, 0xd9, 0xc8, 0x28, 0x88, 0x7c, 0x6e, 0x14, 0x5f, 0x6d, 0x85,
+	0x74, 0x0e, 0xb5, 0x74, 0x1c, 0x46, 0xe7, 0x6e, 0x22, 0x28, 0xc2, 0xc3, 0xff, 0x32, 0xf4, 0x37,
+	0x69, 0x11, 0x21, 0xda, 0x24, 0x1e, 0x92, 0x29, 0x5b, 0x88, 0x9b, 0x44, 0xfa, 0xc1, 0x24, 0xb0,
+	0xa8, 0x25, 0xb3, 0xd6, 0x31, 0xba, 0x55, 0xba, 0x42, 0x0f, 0x94, 0x33, 0xb4, 0x0e, 0x59, 0x5f,
+	0xd8, 0x2e, 0xc8, 0xef, 0x07, 0x62, 0x7e, 0x75, 0x04, 0xf1, 0x3f, 0x0d, 0xe5, 0x4d, 0x2f, 0x21,
+	0xbf, 0x0c, 0x0b, 0xa9, 0x9d, 0xc6, 0xba, 0xb7, 0x81, 0x33, 0x26, 0x61, 0x64, 0x8d, 0x7d, 0xd6,
+	0x5d, 0x56, 0xe9, 0xae, 0x14, 0x5a, 0x72, 0xe6, 0x07, 0xfb, 0x96, 0x09, 0xb8, 0x17, 0x6f, 0x6b,
+	0xbd, 0xf8, 0x31, 0xf3, 0xe2, 0x96, 0xb8, 0x4b, 0x73, 0x24, 0xf1, 0x5f, 0x0d, 0x65, 0x37, 0x52,
+	0xc2, 0x01, 0xb1, 0xdd, 0xa7, 0x3e, 0xd4, 0x0a, 0xa8, 0x8d, 0x44, 0x6a, 0x0a, 0x78, 0xfc, 0x85,
+	0xa1, 0xef, 0x85, 0x0a, 0x77, 0x47, 0x1b, 0xea, 0x4c, 0x9f, 0xd1, 0x6a, 0x16, 0xe4, 0x7d, 0x2c,
+	0x3f, 0xd7, 0x72, 0xe8, 0xf9, 0xb9, 0x7e, 0x31, 0x66, 0x05, 0xe7, 0xda, 0x95, 0x9d, 0x6b, 0x05,
+	0x91, 0x67, 0x92, 0x6e, 0x4f, 0xfb, 0x04, 0x69, 0x43, 0x9d, 0x75, 0x42, 0x2c, 0x28, 0x8d, 0xde,
+	0x77, 0xb4, 0x4c, 0x3c, 0xc6, 0xe4, 0xba, 0x18, 0x94, 0x14, 0x16, 0x7e, 0x2f, 0xd7, 0x57, 0x0a,
+	0xd5, 0xfd, 0xdb, 0x5a, 0x04, 0x9f, 0x21, 0x6c, 0x66, 0x7d, 0x4d, 0xdb, 0xf7, 0x25, 0x2d, 0xaa,
+	0xce, 0xc1, 0x02, 0x8f, 0x7e, 0x22, 0x7a, 0x94, 0x33, 0x8e, 0x3f, 0x33, 0xa4, 0xed, 0x2f, 0x4d,
+	0x2a, 0x55, 0x73, 0x13, 0xe0, 0x74, 0x9a, 0x2b, 0xf9, 0xf7, 0x18, 0x8d, 0x70, 0xbd, 0xe0, 0x76,
+	0x0b, 0xc4, 0xdb, 0x4d, 0x82, 0x8c, 0x07, 0x92, 0xb6, 0xbb, 0xc0, 0xcf, 0x50, 0x9e, 0xb9, 0x94,
+	0x01, 0x7c, 0x94, 0xeb, 0xda, 0x0b, 0x72, 0x15, 0xc9, 0x72, 0x95, 0xb6, 0xf8, 0x43, 0x69, 0xcb,
+	0x5f, 0x10, 0x81, 0x89, 0x18, 0x01, 0x89, 0x09, 0xfc, 0x9e, 0xea, 0xcd, 0xd0, 0xeb, 0x6b, 0x8d,
+	0x3f, 0x62, 0xc6, 0x3b, 0x89, 0x40, 0x6e, 0x05, 0xdb, 0x9a, 0x77, 0x47, 0xef, 0x1d, 0x2d, 0xc4,
+	0x94, 0x41, 0xdc, 0xce, 0xf1, 0xcf, 0x1b, 0xc2, 0x1f, 0xe8, 0x9f, 0x2f, 0x05, 0x15, 0xea, 0xb1,
+	0x58, 0xa1, 0x74, 0xb6, 0xf0, 0x8f, 0xc4, 0x87, 0x50, 0x76, 0x44, 0xd5, 0xfb, 0x96, 0x16, 0xeb,
+	0x82, 0x61, 0x99, 0xd9, 0xbb, 0x3c, 0xb1, 0x45, 0xbb, 0x4b, 0xe5, 0x9b, 0x4a, 0x72, 0x50, 0xe6,
+	0x45, 0xa7, 0xc2, 0x8a, 0xce, 0x3d, 0x2d, 0xf6, 0x4f, 0x19, 0x36, 0xce, 0x60, 0x4b, 0x81, 0xf0,
+	0xbf, 0x0d, 0xcd, 0xdb, 0x4d, 0x28, 0x12, 0xf9, 0xb3, 0x2a, 0x69, 0x00, 0xab, 0xbc, 0x9e, 0xb0,
+	0x71, 0x55, 0x8d, 0xdf, 0x71, 0x7d, 0x12, 0x46, 0x8e, 0xcb, 0xba, 0x8a, 0x78, 0xe2, 0xd6, 0x2c,
+	0xd8, 0x13, 0x3f, 0x13, 0xf7, 0x84, 0x92, 0x25, 0xbd, 0xe5, 0x54, 0x0f, 0xcc, 0x17, 0xf6, 0xa0,
+	0xe0, 0x06, 0x7e, 0x92, 0xbb, 0x81, 0xe5, 0xf8, 0xd8, 0x95, 0x3c, 0x6f, 0xe7, 0x23, 0x3b, 0x23,
+	0x1e, 0xd9, 0xed, 0xd9, 0x76, 0x50, 0xaa, 0xf2, 0x3e, 0x15, 0x2b, 0x52, 0xce, 0x34, 0xfe, 0xc4,
+	0x50, 0x3c, 0x9c, 0xa9, 0xef, 0xf7, 0x07, 0x83, 0x23, 0x06, 0x66, 0xa4, 0xe6, 0x83, 0x09, 0x3a,
+	0xe5, 0x72, 0x4c, 0x71, 0xe2, 0x1e, 0x44, 0xff, 0x7a, 0xf9, 0xb9, 0xfc, 0xf5, 0x22, 0xa0, 0xe2,
+	0x67, 0x8a, 0xc7, 0x7a, 0x09, 0x3a, 0x05, 0x04, 0x9e, 0xa9, 0x9f, 0x4f, 0x69, 0x02, 0x1f, 0x1b,
+	0x8a, 0x99, 0x40, 0xd9, 0xc1, 0x29, 0x65, 0xa2, 0xaf, 0x90, 0x97, 0x86, 0x48, 0x45, 0x0a, 0x88,
+	0x1d, 0xc5, 0x08, 0x22, 0xcd, 0xa4, 0x00, 0xea, 0x17, 0x39, 0x28, 0xa9, 0xc5, 0x04, 0xaa, 0x6f,
+	0xbd, 0x28, 0xd4, 0x2f, 0x15, 0x50, 0x92, 0x00, 0x4b, 0x66, 0x24, 0xcf, 0xbf, 0xdd, 0xf4, 0x57,
+	0xdc, 0x87, 0x31, 0x9b, 0xed, 0x4c, 0x49, 0x13, 0xbd, 0xb6, 0xf2, 0x53, 0x99, 0x8c, 0xc3, 0x7a,
+	0x88, 0x5f, 0x95, 0x81, 0x98, 0xaa, 0x66, 0x39, 0xda, 0x86, 0x4a, 0x0f, 0xfc, 0xeb, 0x32, 0xc0,
+	0x7f, 0x33, 0x34, 0x93, 0xa2, 0x97, 0x19, 0xce, 0x17, 0x90, 0xfb, 0xa8, 0x0c, 0xb9, 0xbf, 0x1b,
+	0xfa, 0x39, 0xd5, 0x97, 0xc8, 0xef, 0x37, 0xe5, 0xb2, 0x26, 0x1d, 0x92, 0x65, 0x4a, 0xc0, 0x32,
+	0x2c, 0xa4, 0x7f, 0x6d, 0x2a, 0xa8, 0x3e, 0x1f, 0xc7, 0xb0, 0x3b, 0x99, 0x9e, 0x25, 0x07, 0x80,
+	0x9f, 0x2a, 0x67, 0x70, 0x5a, 0x6c, 0xfd, 0xed, 0xfe, 0x5b, 0x43, 0xbc, 0x7d, 0x14, 0x18, 0xf8,
+	0x89, 0x76, 0xca, 0xa7, 0xa5, 0xa0, 0x8f, 0xfa, 0xef, 0xca, 0x44, 0xfd, 0x59, 0xd1, 0x78, 0xf0,
+	0x25, 0x08, 0xfc, 0xbe, 0x24, 0x01, 0xfd, 0x0c, 0xf3, 0x25, 0x08, 0xfc, 0xa1, 0x0c, 0x81, 0x0b,
+	0xd8, 0xcc, 0x0f, 0x3f, 0x39, 0x36, 0x02, 0xe0, 0xc2, 0xbd, 0x88, 0x71, 0xa8, 0x16, 0xbc, 0x6a,
+	0x3f, 0x31, 0xc4, 0xa6, 0x48, 0x69, 0x1d, 0x3f, 0x51, 0xcc, 0x55, 0x69, 0x19, 0x7e, 0x38, 0xb2,
+	0x53, 0xa7, 0x31, 0x35, 0x01, 0x2a, 0x53, 0xad, 0x3e, 0x2d, 0xe3, 0xf8, 0xff, 0x0c, 0xc9, 0x28,
+	0x5c, 0xf8, 0x85, 0xb7, 0x0d, 0xf5, 0x7b, 0x5e, 0x30, 0x8c, 0x51, 0x1b, 0x99, 0xde, 0xac, 0xaa,
+	0xea, 0xcd, 0x6a, 0x9c, 0x31, 0x73, 0xf8, 0xc0, 0x36, 0xeb, 0x2c, 0x75, 0x6b, 0xb0, 0x74, 0x48,
+	0xa6, 0xf3, 0xcf, 0x17, 0x98, 0xd6, 0x16, 0xa0, 0x43, 0x32, 0x15, 0x2d, 0x2c, 0x32, 0xec, 0x6d,
+	0x58, 0x67, 0x32, 0x36, 0xd5, 0xa2, 0xd2, 0x7b, 0xd6, 0x30, 0xf2, 0x02, 0xb3, 0x51, 0x22, 0xf3,
+	0x9f, 0x95, 0x09, 0xc0, 0x17, 0x46, 0xe1, 0xf0, 0xba, 0x70, 0x38, 0xd4, 0x92, 0x4c, 0xb7, 0x0a,
+	0xb8, 0xfd, 0xb1, 0x0c, 0x37, 0x3f, 0x3b, 0x32, 0x47, 0xb7, 0xa1, 0x31, 0xfb, 0x93, 0xfe, 0xb2,
+	0x49, 0x7f, 0x4f, 0xcd, 0x5b, 0xee, 0x7d, 0x53, 0x8b, 0xfb, 0xa7, 0x18, 0x37, 0xf5, 0x9b, 0x64,
+	0x1a, 0x01, 0x3f, 0x55, 0x8d, 0xe4, 0xe9, 0x26, 0x78, 0x38, 0xb2, 0xe7, 0x67, 0xb0, 0x0d, 0xf5,
+	0x43, 0x32, 0x9d, 0x1f, 0x41, 0x7d, 0x13, 0xfe, 0xb9, 0x91, 0x7f, 0x9a, 0xca, 0x40, 0xf0, 0xa7,
+	0x46, 0xc1, 0xd8, 0x3f, 0x53, 0x07, 0x84, 0x5f, 0xc2, 0xe3, 0xf7, 0xd6, 0xf7, 0xb5, 0x4c, 0xfe,
+	0x1c, 0x33, 0x79, 0x2d, 0xf7, 0xd8, 0x93, 0xc3, 0xfd, 0x7f, 0x00, 0xb9, 0x34, 0x87, 0x2a, 0x1a,
+	0x21, 0x00, 0x00,
 }
@@ -137,7 +137,7 @@ func (s *Service) Open() error {
 	}
 
 	// Open the store.  The addresses passed in are remotely accessible.
-	s.store = newStore(s.config, s.remoteAddr(s.httpAddr), s.remoteAddr(s.raftAddr))
+	s.store = newStore(s.config, s.RemoteHTTPAddr(s.httpAddr), s.RemoteRaftAddr())
 	s.store.node = s.Node
 
 	handler := newHandler(s.config, s)
@@ -203,6 +203,9 @@ func (s *Service) Close() error {
 
 // RemoteHTTPAddr returns a remote httpAddr according to a addr.
 func (s *Service) RemoteHTTPAddr(addr string) string {
+	if s.config.HTTPAdvertiseAddress != "" {
+		return s.config.HTTPAdvertiseAddress
+	}
 	return s.remoteAddr(s.httpAddr)
 }
 
@@ -213,6 +216,9 @@ func (s *Service) HTTPAddr() string {
 
 // RemoteRaftAddr returns a remote raft httpAddr.
 func (s *Service) RemoteRaftAddr() string {
+	if s.config.AdvertiseAddress != "" {
+		return s.config.AdvertiseAddress
+	}
 	return s.remoteAddr(s.raftAddr)
 }
 
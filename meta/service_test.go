@@ -240,6 +240,86 @@ func TestMetaService_CreateRetentionPolicy(t *testing.T) {
 	}
 }
 
+func TestMetaService_ExecuteBatch(t *testing.T) {
+	t.Parallel()
+
+	d, s, c := newServiceAndClient()
+	defer os.RemoveAll(d)
+	defer s.Close()
+	defer c.Close()
+
+	hour := time.Hour
+	one := 1
+	b := cloudMeta.NewBatch()
+	for i := 0; i < 3; i++ {
+		name := fmt.Sprintf("db%d", i)
+		b.CreateDatabaseWithRetentionPolicy(name, &meta.RetentionPolicySpec{
+			Name:     "rp0",
+			Duration: &hour,
+			ReplicaN: &one,
+		})
+		b.CreateRetentionPolicy(name, &meta.RetentionPolicySpec{
+			Name:     "rp1",
+			Duration: &hour,
+			ReplicaN: &one,
+		})
+	}
+
+	index := c.Data().Index
+	if err := c.ExecuteBatch(b); err != nil {
+		t.Fatal(err)
+	}
+
+	// The whole batch is applied as a single raft entry.
+	if got := c.Data().Index; got != index+1 {
+		t.Fatalf("unexpected index: got %d, exp %d", got, index+1)
+	}
+
+	for i := 0; i < 3; i++ {
+		db, err := c.Database(fmt.Sprintf("db%d", i))
+		if err != nil {
+			t.Fatal(err)
+		} else if db == nil {
+			t.Fatalf("db%d not created", i)
+		} else if db.DefaultRetentionPolicy != "rp0" {
+			t.Fatalf("db%d default rp wrong: %s", i, db.DefaultRetentionPolicy)
+		} else if db.RetentionPolicy("rp1") == nil {
+			t.Fatalf("db%d rp1 not created", i)
+		}
+	}
+
+	// Default retention policies are set in batches too.
+	b = cloudMeta.NewBatch()
+	b.SetDefaultRetentionPolicy("db0", "rp1")
+	if err := c.ExecuteBatch(b); err != nil {
+		t.Fatal(err)
+	} else if db, err := c.Database("db0"); err != nil {
+		t.Fatal(err)
+	} else if db.DefaultRetentionPolicy != "rp1" {
+		t.Fatalf("db0 default rp wrong: %s", db.DefaultRetentionPolicy)
+	}
+
+	// A failing command rolls back the whole batch.
+	b = cloudMeta.NewBatch()
+	b.CreateDatabase("db3")
+	b.CreateRetentionPolicy("db4", &meta.RetentionPolicySpec{
+		Name:     "rp0",
+		Duration: &hour,
+		ReplicaN: &one,
+	})
+	if err := c.ExecuteBatch(b); err == nil {
+		t.Fatal("expected error")
+	}
+
+	if db, _ := c.Database("db3"); db != nil {
+		t.Fatalf("expected database to not exist: %v", db)
+	}
+
+	if err := c.ExecuteBatch(cloudMeta.NewBatch()); err != cloudMeta.ErrBatchEmpty {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
 func TestMetaService_SetDefaultRetentionPolicy(t *testing.T) {
 	t.Parallel()
 
@@ -280,6 +360,28 @@ func TestMetaService_SetDefaultRetentionPolicy(t *testing.T) {
 	if db.DefaultRetentionPolicy != "rp0" {
 		t.Fatalf("rp name wrong: %s", db.DefaultRetentionPolicy)
 	}
+
+	if _, err := c.CreateRetentionPolicy("db0", &meta.RetentionPolicySpec{
+		Name:     "rp1",
+		Duration: &hour,
+		ReplicaN: &one,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.SetDefaultRetentionPolicy("db0", "rp1"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Make sure default retention policy is now rp1
+	if db, err := c.Database("db0"); err != nil {
+		t.Fatal(err)
+	} else if db.DefaultRetentionPolicy != "rp1" {
+		t.Fatalf("rp name wrong: %s", db.DefaultRetentionPolicy)
+	}
+
+	if err := c.SetDefaultRetentionPolicy("db0", "rp2"); err == nil {
+		t.Fatal("expected error setting a missing default retention policy")
+	}
 }
 
 func TestMetaService_DropRetentionPolicy(t *testing.T) {
@@ -1006,6 +1108,91 @@ func TestMetaService_DropDataNode_Reassign(t *testing.T) {
 	}
 }
 
+// Ensure shard owners are added and removed through the meta store, and the
+// last owner of a shard is never removed.
+func TestMetaService_ShardOwners(t *testing.T) {
+	t.Parallel()
+
+	d, s, c := newServiceAndClient()
+	defer os.RemoveAll(d)
+	defer s.Close()
+	defer c.Close()
+
+	n1, err := c.CreateDataNode("foo:8180", "bar:8181")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.CreateDatabase("foo"); err != nil {
+		t.Fatal(err)
+	}
+	sg, err := c.CreateShardGroup("foo", "default", time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	shardID := sg.Shards[0].ID
+
+	n2, err := c.CreateDataNode("foo:8280", "bar:8281")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Adding an owner twice is a no-op.
+	for i := 0; i < 2; i++ {
+		if err := c.AddShardOwner(shardID, n2.ID); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, _, si := c.ShardOwner(shardID); !reflect.DeepEqual(si.Owners, []meta.ShardOwner{{NodeID: n1.ID}, {NodeID: n2.ID}}) {
+		t.Fatalf("unexpected owners: %v", si.Owners)
+	}
+
+	if err := c.AddShardOwner(shardID, 100); err == nil || err.Error() != cloudMeta.ErrNodeNotFound.Error() {
+		t.Fatalf("unexpected error: %v", err)
+	} else if err := c.AddShardOwner(100, n1.ID); err == nil || err.Error() != cloudMeta.ErrShardNotFound.Error() {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := c.RemoveShardOwner(shardID, n1.ID); err != nil {
+		t.Fatal(err)
+	} else if _, _, si := c.ShardOwner(shardID); !reflect.DeepEqual(si.Owners, []meta.ShardOwner{{NodeID: n2.ID}}) {
+		t.Fatalf("unexpected owners: %v", si.Owners)
+	}
+
+	// The last owner is kept.
+	if err := c.RemoveShardOwner(shardID, n2.ID); err == nil || err.Error() != cloudMeta.ErrShardNotReplicated.Error() {
+		t.Fatalf("unexpected error: %v", err)
+	} else if _, _, si := c.ShardOwner(shardID); !si.OwnedBy(n2.ID) {
+		t.Fatalf("unexpected owners: %v", si.Owners)
+	}
+}
+
+// Ensure the addresses of a data node are updated through the meta store.
+func TestMetaService_UpdateDataNode(t *testing.T) {
+	t.Parallel()
+
+	d, s, c := newServiceAndClient()
+	defer os.RemoveAll(d)
+	defer s.Close()
+	defer c.Close()
+
+	n, err := c.CreateDataNode("foo:8180", "bar:8181")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.UpdateDataNode(n.ID, "foo2:8180", "bar2:8181"); err != nil {
+		t.Fatal(err)
+	} else if got, err := c.DataNode(n.ID); err != nil {
+		t.Fatal(err)
+	} else if got.Host != "foo2:8180" || got.TCPHost != "bar2:8181" {
+		t.Fatalf("unexpected data node: %+v", got)
+	}
+
+	if err := c.UpdateDataNode(100, "foo3:8180", "bar3:8181"); err == nil || err.Error() != cloudMeta.ErrNodeNotFound.Error() {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
 func TestMetaService_PersistClusterIDAfterRestart(t *testing.T) {
 	t.Skip("not enabled")
 	t.Parallel()
@@ -1014,6 +1201,84 @@ func TestMetaService_PersistClusterIDAfterRestart(t *testing.T) {
 	defer os.RemoveAll(cfg.Dir)
 }
 
+// Ensure a meta node registers its advertised addresses rather than the
+// addresses it binds to.
+func TestMetaService_AdvertiseAddress(t *testing.T) {
+	t.Parallel()
+
+	cfg := newConfig()
+	defer os.RemoveAll(cfg.Dir)
+	cfg.AdvertiseAddress = "localhost:18088"
+	cfg.HTTPAdvertiseAddress = "localhost:18091"
+	s := newService(cfg)
+	if err := s.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	if addr := s.RemoteRaftAddr(); addr != "localhost:18088" {
+		t.Fatalf("unexpected remote raft address: %s", addr)
+	} else if addr := s.RemoteHTTPAddr(s.HTTPAddr()); addr != "localhost:18091" {
+		t.Fatalf("unexpected remote http address: %s", addr)
+	}
+
+	c := newClient(s)
+	defer c.Close()
+
+	nodes, err := c.MetaNodes()
+	if err != nil {
+		t.Fatal(err)
+	} else if len(nodes) != 1 || nodes[0].Host != "localhost:18091" || nodes[0].TCPHost != "localhost:18088" {
+		t.Fatalf("unexpected meta nodes: %+v", nodes)
+	}
+}
+
+// Ensure watchers are told what changed in the meta data, both through the
+// client polling for snapshots and through the stream of a meta node.
+func TestMetaService_Watch(t *testing.T) {
+	t.Parallel()
+
+	d, s, c := newServiceAndClient()
+	defer os.RemoveAll(d)
+	defer s.Close()
+	defer c.Close()
+
+	changes, cancel := c.Watch(0)
+	defer cancel()
+
+	// The stream starts with a reset, as the client has no data yet.
+	ha := cloudMeta.NewHAClient([]string{s.HTTPAddr()})
+	streamed, cancelStream := ha.Watch(0)
+	defer cancelStream()
+	select {
+	case change := <-streamed:
+		if change.Type != cloudMeta.ChangeReset {
+			t.Fatalf("unexpected change: %+v", change)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for reset")
+	}
+
+	if _, err := c.CreateDatabase("db0"); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, ch := range []<-chan cloudMeta.Change{changes, streamed} {
+		timeout := time.After(5 * time.Second)
+	wait:
+		for {
+			select {
+			case change := <-ch:
+				if change.Type == cloudMeta.ChangeDatabaseCreated && change.Database == "db0" {
+					break wait
+				}
+			case <-timeout:
+				t.Fatal("timed out waiting for database creation")
+			}
+		}
+	}
+}
+
 func TestMetaService_Ping(t *testing.T) {
 	t.Parallel()
 	cfgs := make([]*cloudMeta.Config, 3)
@@ -27,61 +27,7 @@ func (fsm *storeFSM) Apply(l *raft.Log) interface{} {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	err := func() interface{} {
-		switch cmd.GetType() {
-		case internal.Command_CreateDatabaseCommand:
-			return fsm.applyCreateDatabaseCommand(&cmd)
-		case internal.Command_DropDatabaseCommand:
-			return fsm.applyDropDatabaseCommand(&cmd)
-		case internal.Command_CreateRetentionPolicyCommand:
-			return fsm.applyCreateRetentionPolicyCommand(&cmd)
-		case internal.Command_DropRetentionPolicyCommand:
-			return fsm.applyDropRetentionPolicyCommand(&cmd)
-		case internal.Command_SetDefaultRetentionPolicyCommand:
-			return fsm.applySetDefaultRetentionPolicyCommand(&cmd)
-		case internal.Command_UpdateRetentionPolicyCommand:
-			return fsm.applyUpdateRetentionPolicyCommand(&cmd)
-		case internal.Command_CreateShardGroupCommand:
-			return fsm.applyCreateShardGroupCommand(&cmd)
-		case internal.Command_DeleteShardGroupCommand:
-			return fsm.applyDeleteShardGroupCommand(&cmd)
-		case internal.Command_CreateContinuousQueryCommand:
-			return fsm.applyCreateContinuousQueryCommand(&cmd)
-		case internal.Command_DropContinuousQueryCommand:
-			return fsm.applyDropContinuousQueryCommand(&cmd)
-		case internal.Command_CreateSubscriptionCommand:
-			return fsm.applyCreateSubscriptionCommand(&cmd)
-		case internal.Command_DropSubscriptionCommand:
-			return fsm.applyDropSubscriptionCommand(&cmd)
-		case internal.Command_CreateUserCommand:
-			return fsm.applyCreateUserCommand(&cmd)
-		case internal.Command_DropUserCommand:
-			return fsm.applyDropUserCommand(&cmd)
-		case internal.Command_UpdateUserCommand:
-			return fsm.applyUpdateUserCommand(&cmd)
-		case internal.Command_SetPrivilegeCommand:
-			return fsm.applySetPrivilegeCommand(&cmd)
-		case internal.Command_SetAdminPrivilegeCommand:
-			return fsm.applySetAdminPrivilegeCommand(&cmd)
-		case internal.Command_SetDataCommand:
-			return fsm.applySetDataCommand(&cmd)
-		case internal.Command_CreateMetaNodeCommand:
-			return fsm.applyCreateMetaNodeCommand(&cmd)
-		case internal.Command_DeleteMetaNodeCommand:
-			return fsm.applyDeleteMetaNodeCommand(&cmd, s)
-		case internal.Command_SetMetaNodeCommand:
-			return fsm.applySetMetaNodeCommand(&cmd)
-		case internal.Command_CreateDataNodeCommand:
-			return fsm.applyCreateDataNodeCommand(&cmd)
-		case internal.Command_DeleteDataNodeCommand:
-			return fsm.applyDeleteDataNodeCommand(&cmd)
-		case internal.Command_AddShardOwnerCommand:
-			// return fsm.applyAddShardOwnerCommand(&cmd)
-		default:
-			panic(fmt.Errorf("cannot apply command: %x", l.Data))
-		}
-		return nil
-	}()
+	err := fsm.applyCommand(&cmd, s)
 
 	// Copy term and index to new metadata.
 	fsm.data.Data.Term = l.Term
@@ -94,18 +40,103 @@ func (fsm *storeFSM) Apply(l *raft.Log) interface{} {
 	return err
 }
 
-func (fsm *storeFSM) applyUpdateDataNodeCommand(cmd *internal.Command) interface{} {
-	ext, err := proto.GetExtension(cmd, internal.E_CreateDataNodeCommand_Command)
-	if err != nil {
-		return nil
+// applyCommand dispatches a single command to its apply function.
+func (fsm *storeFSM) applyCommand(cmd *internal.Command, s *store) interface{} {
+	switch cmd.GetType() {
+	case internal.Command_CreateDatabaseCommand:
+		return fsm.applyCreateDatabaseCommand(cmd)
+	case internal.Command_DropDatabaseCommand:
+		return fsm.applyDropDatabaseCommand(cmd)
+	case internal.Command_CreateRetentionPolicyCommand:
+		return fsm.applyCreateRetentionPolicyCommand(cmd)
+	case internal.Command_DropRetentionPolicyCommand:
+		return fsm.applyDropRetentionPolicyCommand(cmd)
+	case internal.Command_SetDefaultRetentionPolicyCommand:
+		return fsm.applySetDefaultRetentionPolicyCommand(cmd)
+	case internal.Command_UpdateRetentionPolicyCommand:
+		return fsm.applyUpdateRetentionPolicyCommand(cmd)
+	case internal.Command_CreateShardGroupCommand:
+		return fsm.applyCreateShardGroupCommand(cmd)
+	case internal.Command_DeleteShardGroupCommand:
+		return fsm.applyDeleteShardGroupCommand(cmd)
+	case internal.Command_CreateContinuousQueryCommand:
+		return fsm.applyCreateContinuousQueryCommand(cmd)
+	case internal.Command_DropContinuousQueryCommand:
+		return fsm.applyDropContinuousQueryCommand(cmd)
+	case internal.Command_CreateSubscriptionCommand:
+		return fsm.applyCreateSubscriptionCommand(cmd)
+	case internal.Command_DropSubscriptionCommand:
+		return fsm.applyDropSubscriptionCommand(cmd)
+	case internal.Command_CreateUserCommand:
+		return fsm.applyCreateUserCommand(cmd)
+	case internal.Command_DropUserCommand:
+		return fsm.applyDropUserCommand(cmd)
+	case internal.Command_UpdateUserCommand:
+		return fsm.applyUpdateUserCommand(cmd)
+	case internal.Command_SetPrivilegeCommand:
+		return fsm.applySetPrivilegeCommand(cmd)
+	case internal.Command_SetAdminPrivilegeCommand:
+		return fsm.applySetAdminPrivilegeCommand(cmd)
+	case internal.Command_SetDataCommand:
+		return fsm.applySetDataCommand(cmd)
+	case internal.Command_CreateMetaNodeCommand:
+		return fsm.applyCreateMetaNodeCommand(cmd)
+	case internal.Command_DeleteMetaNodeCommand:
+		return fsm.applyDeleteMetaNodeCommand(cmd, s)
+	case internal.Command_SetMetaNodeCommand:
+		return fsm.applySetMetaNodeCommand(cmd)
+	case internal.Command_CreateDataNodeCommand:
+		return fsm.applyCreateDataNodeCommand(cmd)
+	case internal.Command_DeleteDataNodeCommand:
+		return fsm.applyDeleteDataNodeCommand(cmd)
+	case internal.Command_BatchCommand:
+		return fsm.applyBatchCommand(cmd, s)
+	case internal.Command_ReplaceDataNodeCommand:
+		return fsm.applyReplaceDataNodeCommand(cmd)
+	case internal.Command_SetDataNodeMaintenanceCommand:
+		return fsm.applySetDataNodeMaintenanceCommand(cmd)
+	case internal.Command_TruncateShardGroupsCommand:
+		return fsm.applyTruncateShardGroupsCommand(cmd)
+	case internal.Command_AddShardOwnerCommand:
+		return fsm.applyAddShardOwnerCommand(cmd)
+	case internal.Command_RemoveShardOwnerCommand:
+		return fsm.applyRemoveShardOwnerCommand(cmd)
+	case internal.Command_UpdateDataNodeCommand:
+		return fsm.applyUpdateDataNodeCommand(cmd)
+	default:
+		panic(fmt.Errorf("cannot apply command: %s", cmd.GetType()))
 	}
+}
+
+// applyBatchCommand applies each command in the batch in order. If any
+// command fails, the data is rolled back so the batch is all or nothing.
+func (fsm *storeFSM) applyBatchCommand(cmd *internal.Command, s *store) interface{} {
+	ext, _ := proto.GetExtension(cmd, internal.E_BatchCommand_Command)
+	v := ext.(*internal.BatchCommand)
+
+	orig := fsm.data
+	for _, c := range v.GetCommands() {
+		if c.GetType() == internal.Command_BatchCommand {
+			fsm.data = orig
+			return ErrNestedBatch
+		}
+		if err := fsm.applyCommand(c, s); err != nil {
+			fsm.data = orig
+			return err
+		}
+	}
+	return nil
+}
+
+func (fsm *storeFSM) applyUpdateDataNodeCommand(cmd *internal.Command) interface{} {
+	ext, _ := proto.GetExtension(cmd, internal.E_UpdateDataNodeCommand_Command)
 	v := ext.(*internal.UpdateDataNodeCommand)
 
 	// Copy data and update.
 	other := fsm.data.Clone()
 	node := other.DataNode(v.GetID())
 	if node == nil {
-		return nil
+		return ErrNodeNotFound
 	}
 
 	node.Host = v.GetHost()
@@ -205,14 +236,15 @@ func (fsm *storeFSM) applyDropRetentionPolicyCommand(cmd *internal.Command) inte
 }
 
 func (fsm *storeFSM) applySetDefaultRetentionPolicyCommand(cmd *internal.Command) interface{} {
-	// ext, _ := proto.GetExtension(cmd, internal.E_SetDefaultRetentionPolicyCommand_Command)
-	// v := ext.(*internal.SetDefaultRetentionPolicyCommand)
+	ext, _ := proto.GetExtension(cmd, internal.E_SetDefaultRetentionPolicyCommand_Command)
+	v := ext.(*internal.SetDefaultRetentionPolicyCommand)
 
-	// Copy data and update.
+	// Copy data and update. The retention policy is made the default by an
+	// update that changes nothing else.
 	other := fsm.data.Clone()
-	// if err := other.Data.SetDefaultRetentionPolicY(v.GetDatabase(), v.GetName()); err != nil {
-	// return err
-	// }
+	if err := other.Data.UpdateRetentionPolicy(v.GetDatabase(), v.GetName(), &meta.RetentionPolicyUpdate{}, true); err != nil {
+		return err
+	}
 	fsm.data = other
 
 	return nil
@@ -250,7 +282,7 @@ func (fsm *storeFSM) applyCreateShardGroupCommand(cmd *internal.Command) interfa
 	// Copy data and update.
 	other := fsm.data.Clone()
 	//NOTE: here we override original CreateShardGroup. It has to call directly from data instead of data.Data
-	if err := other.CreateShardGroup(v.GetDatabase(), v.GetPolicy(), time.Unix(0, v.GetTimestamp())); err != nil {
+	if err := other.CreateShardGroupWithShards(v.GetDatabase(), v.GetPolicy(), time.Unix(0, v.GetTimestamp()), v.GetShardAssignment(), int(v.GetShardN())); err != nil {
 		return err
 	}
 	fsm.data = other
@@ -481,8 +513,65 @@ func (fsm *storeFSM) applyDeleteDataNodeCommand(cmd *internal.Command) interface
 	return nil
 }
 
+func (fsm *storeFSM) applyReplaceDataNodeCommand(cmd *internal.Command) interface{} {
+	ext, _ := proto.GetExtension(cmd, internal.E_ReplaceDataNodeCommand_Command)
+	v := ext.(*internal.ReplaceDataNodeCommand)
+
+	other := fsm.data.Clone()
+	if err := other.ReplaceDataNode(v.GetOldID(), v.GetNewID()); err != nil {
+		return err
+	}
+	fsm.data = other
+	return nil
+}
+
+func (fsm *storeFSM) applySetDataNodeMaintenanceCommand(cmd *internal.Command) interface{} {
+	ext, _ := proto.GetExtension(cmd, internal.E_SetDataNodeMaintenanceCommand_Command)
+	v := ext.(*internal.SetDataNodeMaintenanceCommand)
+
+	other := fsm.data.Clone()
+	if err := other.SetDataNodeMaintenance(v.GetID(), v.GetMaintenance()); err != nil {
+		return err
+	}
+	fsm.data = other
+	return nil
+}
+
+func (fsm *storeFSM) applyAddShardOwnerCommand(cmd *internal.Command) interface{} {
+	ext, _ := proto.GetExtension(cmd, internal.E_AddShardOwnerCommand_Command)
+	v := ext.(*internal.AddShardOwnerCommand)
+
+	other := fsm.data.Clone()
+	if err := other.AddShardOwner(v.GetID(), v.GetNodeID()); err != nil {
+		return err
+	}
+	fsm.data = other
+	return nil
+}
+
+func (fsm *storeFSM) applyRemoveShardOwnerCommand(cmd *internal.Command) interface{} {
+	ext, _ := proto.GetExtension(cmd, internal.E_RemoveShardOwnerCommand_Command)
+	v := ext.(*internal.RemoveShardOwnerCommand)
+
+	other := fsm.data.Clone()
+	if err := other.RemoveShardOwner(v.GetID(), v.GetNodeID()); err != nil {
+		return err
+	}
+	fsm.data = other
+	return nil
+}
+
+func (fsm *storeFSM) applyTruncateShardGroupsCommand(cmd *internal.Command) interface{} {
+	ext, _ := proto.GetExtension(cmd, internal.E_TruncateShardGroupCommand_Command)
+	v := ext.(*internal.TruncateShardGroupCommand)
+
+	other := fsm.data.Clone()
+	other.TruncateShardGroups(time.Unix(0, v.GetTruncateAt()).UTC())
+	fsm.data = other
+	return nil
+}
+
 //TODO finish these functions
-// func (fsm *storeFSM) applyUpdateDataNode(cmd *internal.Command) (interface{})            {}
 // func (fsm *storeFSM) applyCreateDatabase(cmd *internal.Command) interface{} {}
 
 // func (fsm *storeFSM) applyDropDatabase(cmd *internal.Command) (interface{})              {}
@@ -494,9 +583,6 @@ func (fsm *storeFSM) applyDeleteDataNodeCommand(cmd *internal.Command) interface
 // func (fsm *storeFSM) applyCreateShardGroup(cmd *internal.Command) (interface{})          {}
 // func (fsm *storeFSM) applyCreateBalancedShardGroup(cmd *internal.Command) (interface{})  {}
 // func (fsm *storeFSM) applyDeleteShardGroup(cmd *internal.Command) (interface{})          {}
-// func (fsm *storeFSM) applyTruncateShardGroups(cmd *internal.Command) (interface{})       {}
-// func (fsm *storeFSM) applyAddShardOwner(cmd *internal.Command) (interface{})             {}
-// func (fsm *storeFSM) applyRemoveShardOwner(cmd *internal.Command) (interface{})          {}
 // func (fsm *storeFSM) applyCreateContinuousQuery(cmd *internal.Command) (interface{})     {}
 // func (fsm *storeFSM) applyDropContinuousQuery(cmd *internal.Command) (interface{})       {}
 // func (fsm *storeFSM) applyCreateSubscription(cmd *internal.Command) (interface{})        {}
@@ -0,0 +1,236 @@
+package meta
+
+import (
+	"reflect"
+	"sync"
+
+	"github.com/influxdata/influxdb/services/meta"
+)
+
+// DefaultWatchBufferN is the number of changes buffered for a watcher that
+// does not ask for a buffer size.
+const DefaultWatchBufferN = 64
+
+// ChangeType is the kind of change to the meta data a Change reports.
+type ChangeType string
+
+// Types of the changes reported to the watchers of the meta data.
+const (
+	// ChangeReset is sent in place of changes that were not reported, e.g.
+	// because the watcher fell behind. Anything may have changed up to its
+	// index.
+	ChangeReset ChangeType = "reset"
+
+	// ChangeDatabaseCreated and ChangeDatabaseDropped report a database
+	// and its retention policies appearing or going away at once.
+	// ChangeDatabaseUpdated reports a change to its default retention
+	// policy or continuous queries.
+	ChangeDatabaseCreated ChangeType = "databaseCreated"
+	ChangeDatabaseDropped ChangeType = "databaseDropped"
+	ChangeDatabaseUpdated ChangeType = "databaseUpdated"
+
+	// ChangeRetentionPolicyCreated, ChangeRetentionPolicyDropped and
+	// ChangeRetentionPolicyUpdated report changes to the retention policies
+	// of an existing database, e.g. to their duration or subscriptions, other
+	// than to their shard groups.
+	ChangeRetentionPolicyCreated ChangeType = "retentionPolicyCreated"
+	ChangeRetentionPolicyDropped ChangeType = "retentionPolicyDropped"
+	ChangeRetentionPolicyUpdated ChangeType = "retentionPolicyUpdated"
+
+	// ChangeShardGroupCreated, ChangeShardGroupDeleted and
+	// ChangeShardGroupUpdated report changes to the shard groups of an
+	// existing retention policy. Updates include truncation and changes to
+	// the owners of its shards.
+	ChangeShardGroupCreated ChangeType = "shardGroupCreated"
+	ChangeShardGroupDeleted ChangeType = "shardGroupDeleted"
+	ChangeShardGroupUpdated ChangeType = "shardGroupUpdated"
+
+	// ChangeDataNodeAdded, ChangeDataNodeRemoved and ChangeDataNodeUpdated
+	// report changes to the data nodes, e.g. to their addresses or
+	// maintenance mode.
+	ChangeDataNodeAdded   ChangeType = "dataNodeAdded"
+	ChangeDataNodeRemoved ChangeType = "dataNodeRemoved"
+	ChangeDataNodeUpdated ChangeType = "dataNodeUpdated"
+)
+
+// Change is a change to the meta data, made by the command at Index.
+// Database, RetentionPolicy, ShardGroupID and NodeID are set to what the
+// change is about, if any.
+type Change struct {
+	Type            ChangeType `json:"type"`
+	Index           uint64     `json:"index"`
+	Database        string     `json:"database,omitempty"`
+	RetentionPolicy string     `json:"retentionPolicy,omitempty"`
+	ShardGroupID    uint64     `json:"shardGroupID,omitempty"`
+	NodeID          uint64     `json:"nodeID,omitempty"`
+}
+
+// Diff returns the changes from prev to next. A single ChangeReset is
+// returned if prev is empty.
+func Diff(prev, next *Data) []Change {
+	index := next.Data.Index
+	if prev == nil || prev.Data == nil {
+		return []Change{{Type: ChangeReset, Index: index}}
+	}
+
+	var changes []Change
+	for i := range next.Data.Databases {
+		db := &next.Data.Databases[i]
+		old := prev.Data.Database(db.Name)
+		if old == nil {
+			changes = append(changes, Change{Type: ChangeDatabaseCreated, Index: index, Database: db.Name})
+			continue
+		}
+		if old.DefaultRetentionPolicy != db.DefaultRetentionPolicy ||
+			!reflect.DeepEqual(old.ContinuousQueries, db.ContinuousQueries) {
+			changes = append(changes, Change{Type: ChangeDatabaseUpdated, Index: index, Database: db.Name})
+		}
+		changes = append(changes, diffRetentionPolicies(index, old, db)...)
+	}
+	for _, db := range prev.Data.Databases {
+		if next.Data.Database(db.Name) == nil {
+			changes = append(changes, Change{Type: ChangeDatabaseDropped, Index: index, Database: db.Name})
+		}
+	}
+
+	for _, n := range next.DataNodes {
+		old := prev.DataNode(n.ID)
+		if old == nil {
+			changes = append(changes, Change{Type: ChangeDataNodeAdded, Index: index, NodeID: n.ID})
+		} else if !reflect.DeepEqual(*old, n) {
+			changes = append(changes, Change{Type: ChangeDataNodeUpdated, Index: index, NodeID: n.ID})
+		}
+	}
+	for _, n := range prev.DataNodes {
+		if next.DataNode(n.ID) == nil {
+			changes = append(changes, Change{Type: ChangeDataNodeRemoved, Index: index, NodeID: n.ID})
+		}
+	}
+	return changes
+}
+
+// diffRetentionPolicies returns the changes to the retention policies of a
+// database from prev to next.
+func diffRetentionPolicies(index uint64, prev, next *meta.DatabaseInfo) []Change {
+	var changes []Change
+	for i := range next.RetentionPolicies {
+		rp := &next.RetentionPolicies[i]
+		old := prev.RetentionPolicy(rp.Name)
+		if old == nil {
+			changes = append(changes, Change{Type: ChangeRetentionPolicyCreated, Index: index, Database: next.Name, RetentionPolicy: rp.Name})
+			continue
+		}
+		if old.ReplicaN != rp.ReplicaN || old.Duration != rp.Duration || old.ShardGroupDuration != rp.ShardGroupDuration ||
+			!reflect.DeepEqual(old.Subscriptions, rp.Subscriptions) {
+			changes = append(changes, Change{Type: ChangeRetentionPolicyUpdated, Index: index, Database: next.Name, RetentionPolicy: rp.Name})
+		}
+
+		groups := make(map[uint64]*meta.ShardGroupInfo, len(old.ShardGroups))
+		for j := range old.ShardGroups {
+			groups[old.ShardGroups[j].ID] = &old.ShardGroups[j]
+		}
+		for _, sg := range rp.ShardGroups {
+			o := groups[sg.ID]
+			delete(groups, sg.ID)
+
+			c := Change{Index: index, Database: next.Name, RetentionPolicy: rp.Name, ShardGroupID: sg.ID}
+			switch {
+			case o == nil && sg.Deleted():
+				continue
+			case o == nil:
+				c.Type = ChangeShardGroupCreated
+			case sg.Deleted() && !o.Deleted():
+				c.Type = ChangeShardGroupDeleted
+			case !reflect.DeepEqual(*o, sg):
+				c.Type = ChangeShardGroupUpdated
+			default:
+				continue
+			}
+			changes = append(changes, c)
+		}
+
+		// Shard groups removed from the retention policy are deleted too,
+		// unless they were already reported deleted.
+		for _, sg := range old.ShardGroups {
+			if _, ok := groups[sg.ID]; ok && !sg.Deleted() {
+				changes = append(changes, Change{Type: ChangeShardGroupDeleted, Index: index, Database: next.Name, RetentionPolicy: rp.Name, ShardGroupID: sg.ID})
+			}
+		}
+	}
+	for _, rp := range prev.RetentionPolicies {
+		if next.RetentionPolicy(rp.Name) == nil {
+			changes = append(changes, Change{Type: ChangeRetentionPolicyDropped, Index: index, Database: next.Name, RetentionPolicy: rp.Name})
+		}
+	}
+	return changes
+}
+
+// watcher is a subscriber of the changes to the meta data. Changes are
+// dropped rather than block once its buffer is full, and a ChangeReset is
+// sent in their place as soon as there is room again.
+type watcher struct {
+	ch   chan Change
+	lost bool
+}
+
+// send sends the changes made up to index to w.
+func (w *watcher) send(index uint64, changes []Change) {
+	if w.lost {
+		select {
+		case w.ch <- Change{Type: ChangeReset, Index: index}:
+			w.lost = false
+		default:
+		}
+		return
+	}
+	for _, c := range changes {
+		select {
+		case w.ch <- c:
+		default:
+			w.lost = true
+			return
+		}
+	}
+}
+
+// watchers are the subscribers of the changes seen by a client. The zero
+// value has none.
+type watchers struct {
+	mu   sync.Mutex
+	subs map[*watcher]struct{}
+}
+
+// subscribe returns a channel receiving the changes published from now on,
+// buffering up to n of them, and a function ending the subscription. The
+// channel is closed once the subscription ends.
+func (ws *watchers) subscribe(n int) (<-chan Change, func()) {
+	if n <= 0 {
+		n = DefaultWatchBufferN
+	}
+	w := &watcher{ch: make(chan Change, n)}
+	ws.mu.Lock()
+	if ws.subs == nil {
+		ws.subs = make(map[*watcher]struct{})
+	}
+	ws.subs[w] = struct{}{}
+	ws.mu.Unlock()
+
+	var once sync.Once
+	return w.ch, func() {
+		once.Do(func() {
+			ws.mu.Lock()
+			delete(ws.subs, w)
+			ws.mu.Unlock()
+			close(w.ch)
+		})
+	}
+}
+
+// publish sends the changes made up to index to every subscriber.
+func (ws *watchers) publish(index uint64, changes []Change) {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	for w := range ws.subs {
+		w.send(index, changes)
+	}
+}
@@ -0,0 +1,115 @@
+package meta
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/influxdata/influxdb/services/meta"
+)
+
+// Ensure the changes between two snapshots are reported by type.
+func TestDiff(t *testing.T) {
+	start := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+	prev := &Data{
+		Data: &meta.Data{
+			Index: 3,
+			Databases: []meta.DatabaseInfo{
+				{
+					Name:                   "db0",
+					DefaultRetentionPolicy: "rp0",
+					RetentionPolicies: []meta.RetentionPolicyInfo{{
+						Name:        "rp0",
+						ShardGroups: []meta.ShardGroupInfo{{ID: 1, StartTime: start}},
+					}},
+				},
+				{Name: "db1"},
+			},
+		},
+		DataNodes: NodeInfos{{ID: 1, Host: "host1"}, {ID: 2, Host: "host2"}},
+	}
+	next := &Data{
+		Data: &meta.Data{
+			Index: 7,
+			Databases: []meta.DatabaseInfo{
+				{
+					Name:                   "db0",
+					DefaultRetentionPolicy: "rp1",
+					RetentionPolicies: []meta.RetentionPolicyInfo{
+						{
+							Name: "rp0",
+							ShardGroups: []meta.ShardGroupInfo{
+								{ID: 1, StartTime: start, DeletedAt: start},
+								{ID: 2, StartTime: start.Add(time.Hour)},
+							},
+						},
+						{Name: "rp1"},
+					},
+				},
+				{Name: "db2"},
+			},
+		},
+		DataNodes: NodeInfos{{ID: 1, Host: "host1b"}, {ID: 3, Host: "host3"}},
+	}
+
+	exp := []Change{
+		{Type: ChangeDatabaseUpdated, Index: 7, Database: "db0"},
+		{Type: ChangeShardGroupDeleted, Index: 7, Database: "db0", RetentionPolicy: "rp0", ShardGroupID: 1},
+		{Type: ChangeShardGroupCreated, Index: 7, Database: "db0", RetentionPolicy: "rp0", ShardGroupID: 2},
+		{Type: ChangeRetentionPolicyCreated, Index: 7, Database: "db0", RetentionPolicy: "rp1"},
+		{Type: ChangeDatabaseCreated, Index: 7, Database: "db2"},
+		{Type: ChangeDatabaseDropped, Index: 7, Database: "db1"},
+		{Type: ChangeDataNodeUpdated, Index: 7, NodeID: 1},
+		{Type: ChangeDataNodeAdded, Index: 7, NodeID: 3},
+		{Type: ChangeDataNodeRemoved, Index: 7, NodeID: 2},
+	}
+	if got := Diff(prev, next); !reflect.DeepEqual(got, exp) {
+		t.Fatalf("unexpected changes:\ngot %+v\nexp %+v", got, exp)
+	}
+
+	// Nothing changes between a snapshot and itself.
+	if got := Diff(next, next); len(got) != 0 {
+		t.Fatalf("unexpected changes: %+v", got)
+	}
+
+	// Without a previous snapshot, anything may have changed.
+	if got, exp := Diff(nil, next), []Change{{Type: ChangeReset, Index: 7}}; !reflect.DeepEqual(got, exp) {
+		t.Fatalf("unexpected changes: %+v", got)
+	}
+}
+
+// Ensure a watcher that falls behind is sent a reset in place of the changes
+// it missed, rather than blocking the others.
+func TestWatchers(t *testing.T) {
+	var ws watchers
+	slow, cancelSlow := ws.subscribe(1)
+	fast, cancelFast := ws.subscribe(4)
+	defer cancelFast()
+
+	a := Change{Type: ChangeDatabaseCreated, Index: 1, Database: "db0"}
+	b := Change{Type: ChangeDatabaseCreated, Index: 2, Database: "db1"}
+	ws.publish(1, []Change{a})
+	ws.publish(2, []Change{b})
+
+	if got := <-fast; got != a {
+		t.Fatalf("unexpected change: %+v", got)
+	} else if got := <-fast; got != b {
+		t.Fatalf("unexpected change: %+v", got)
+	}
+
+	// The slow watcher missed b, and is told so once it caught up.
+	if got := <-slow; got != a {
+		t.Fatalf("unexpected change: %+v", got)
+	}
+	ws.publish(3, []Change{{Type: ChangeDatabaseDropped, Index: 3, Database: "db0"}})
+	if got, exp := <-slow, (Change{Type: ChangeReset, Index: 3}); got != exp {
+		t.Fatalf("unexpected change: %+v", got)
+	}
+
+	// The channel is closed once the subscription ends.
+	cancelSlow()
+	if _, ok := <-slow; ok {
+		t.Fatal("expected channel to be closed")
+	}
+	ws.publish(4, []Change{a})
+}
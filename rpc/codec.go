@@ -0,0 +1,141 @@
+package rpc
+
+import (
+	"encoding/binary"
+	"fmt"
+	"sort"
+
+	"github.com/influxdata/influxdb/models"
+)
+
+// PointCodecID identifies the encoding of the points of a WriteShardRequest.
+// IDs are sent on the wire, so they must never be reused for another codec.
+type PointCodecID uint32
+
+const (
+	// BinaryPointCodec encodes each point as marshaled by
+	// models.Point.MarshalBinary. It is understood by every node, and is the
+	// encoding of writes from nodes that predate point codecs.
+	BinaryPointCodec PointCodecID = iota
+
+	// ColumnarPointCodec encodes a batch as columns of series keys, times,
+	// field keys and field values. See columnarCodec.
+	ColumnarPointCodec
+)
+
+// PointCodec encodes the points of a write sent to another node as a single
+// batch. A node only sends a batch encoded with a codec the receiving node
+// registered, as negotiated by their hello messages.
+type PointCodec interface {
+	// Name is the name of the codec in the configuration.
+	Name() string
+
+	// EncodePoints returns points as a batch.
+	EncodePoints(points []models.Point) ([]byte, error)
+
+	// DecodePoints returns the points of a batch. The points may refer to
+	// b, which must not be modified.
+	DecodePoints(b []byte) ([]models.Point, error)
+}
+
+// pointCodecs are the codecs registered by ID.
+var pointCodecs = make(map[PointCodecID]PointCodec)
+
+func init() {
+	RegisterPointCodec(BinaryPointCodec, binaryCodec{})
+	RegisterPointCodec(ColumnarPointCodec, columnarCodec{})
+}
+
+// RegisterPointCodec registers c as the codec of batches encoded as id. It
+// must be called from an init function, before any write is sent or received,
+// and panics if a codec is already registered as id or under the same name.
+func RegisterPointCodec(id PointCodecID, c PointCodec) {
+	if _, ok := pointCodecs[id]; ok {
+		panic(fmt.Sprintf("point codec %d registered twice", id))
+	}
+	if _, ok := PointCodecByName(c.Name()); ok {
+		panic(fmt.Sprintf("point codec %q registered twice", c.Name()))
+	}
+	pointCodecs[id] = c
+}
+
+// LookupPointCodec returns the codec registered as id.
+func LookupPointCodec(id PointCodecID) (PointCodec, bool) {
+	c, ok := pointCodecs[id]
+	return c, ok
+}
+
+// PointCodecByName returns the ID of the codec registered under name.
+func PointCodecByName(name string) (PointCodecID, bool) {
+	for id, c := range pointCodecs {
+		if c.Name() == name {
+			return id, true
+		}
+	}
+	return 0, false
+}
+
+// SupportedPointCodecs returns the IDs of the registered codecs, in order.
+func SupportedPointCodecs() []PointCodecID {
+	ids := make([]PointCodecID, 0, len(pointCodecs))
+	for id := range pointCodecs {
+		ids = append(ids, id)
+	}
+	sort.Sort(pointCodecIDs(ids))
+	return ids
+}
+
+// CommonPointCodecs returns the codecs of ids that are also registered.
+func CommonPointCodecs(ids []PointCodecID) []PointCodecID {
+	var a []PointCodecID
+	for _, id := range ids {
+		if _, ok := pointCodecs[id]; ok {
+			a = append(a, id)
+		}
+	}
+	return a
+}
+
+// binaryCodec is BinaryPointCodec. WriteShardRequest sends the points of a
+// binary batch in its repeated Points field rather than as a batch, so that
+// nodes without point codecs understand it, but a batch can be encoded as
+// the length-prefixed points.
+type binaryCodec struct{}
+
+func (binaryCodec) Name() string { return "binary" }
+
+func (binaryCodec) EncodePoints(points []models.Point) ([]byte, error) {
+	e, err := EncodePoints(points)
+	if err != nil {
+		return nil, err
+	}
+	var b []byte
+	for _, p := range e.points {
+		var sz [4]byte
+		binary.BigEndian.PutUint32(sz[:], uint32(len(p)))
+		b = append(b, sz[:]...)
+		b = append(b, p...)
+	}
+	return b, nil
+}
+
+func (binaryCodec) DecodePoints(b []byte) ([]models.Point, error) {
+	var points [][]byte
+	for len(b) > 0 {
+		if len(b) < 4 {
+			return nil, fmt.Errorf("binary batch: point length truncated")
+		}
+		n := binary.BigEndian.Uint32(b)
+		if uint64(len(b)-4) < uint64(n) {
+			return nil, fmt.Errorf("binary batch: point truncated")
+		}
+		points, b = append(points, b[4:4+n]), b[4+n:]
+	}
+	return NewEncodedPoints(points).Points()
+}
+
+type pointCodecIDs []PointCodecID
+
+func (a pointCodecIDs) Len() int           { return len(a) }
+func (a pointCodecIDs) Less(i, j int) bool { return a[i] < a[j] }
+func (a pointCodecIDs) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
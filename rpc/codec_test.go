@@ -0,0 +1,198 @@
+package rpc_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/influxdata/influxdb/models"
+	"github.com/zhexuany/influxcloud/rpc"
+)
+
+// codecTestPoints returns points with every field type, escaped keys and
+// repeated series.
+func codecTestPoints(t *testing.T) []models.Point {
+	now := time.Unix(0, 1500000000123456789)
+	var points []models.Point
+	for i, fields := range []models.Fields{
+		{"value": 1.5},
+		{"value": 2.25, "count": int64(-7)},
+		{"ok": true, "msg": "hello, \"world\""},
+		{"value": -0.5, "ok": false, "n": int64(1 << 40)},
+	} {
+		for j, tags := range []models.Tags{
+			nil,
+			models.NewTags(map[string]string{"host": "server A", "region": "us,west"}),
+		} {
+			name := "cpu"
+			if j == 1 {
+				name = "disk usage"
+			}
+			p, err := models.NewPoint(name, tags, fields, now.Add(time.Duration(i-j)*time.Second))
+			if err != nil {
+				t.Fatal(err)
+			}
+			points = append(points, p)
+		}
+	}
+	return points
+}
+
+func TestPointCodecs_RoundTrip(t *testing.T) {
+	points := codecTestPoints(t)
+	for _, id := range rpc.SupportedPointCodecs() {
+		c, _ := rpc.LookupPointCodec(id)
+		b, err := c.EncodePoints(points)
+		if err != nil {
+			t.Fatalf("%s: encode: %s", c.Name(), err)
+		}
+		got, err := c.DecodePoints(b)
+		if err != nil {
+			t.Fatalf("%s: decode: %s", c.Name(), err)
+		} else if len(got) != len(points) {
+			t.Fatalf("%s: got %d points, exp %d", c.Name(), len(got), len(points))
+		}
+
+		for i, p := range points {
+			g := got[i]
+			if string(g.Key()) != string(p.Key()) {
+				t.Errorf("%s: point %d key: got %q, exp %q", c.Name(), i, g.Key(), p.Key())
+			} else if g.Name() != p.Name() {
+				t.Errorf("%s: point %d name: got %q, exp %q", c.Name(), i, g.Name(), p.Name())
+			} else if !g.Time().Equal(p.Time()) {
+				t.Errorf("%s: point %d time: got %v, exp %v", c.Name(), i, g.Time(), p.Time())
+			} else if g.HashID() != p.HashID() {
+				t.Errorf("%s: point %d HashID mismatch", c.Name(), i)
+			} else if g.String() != p.String() {
+				t.Errorf("%s: point %d: got %s, exp %s", c.Name(), i, g.String(), p.String())
+			}
+			if !reflect.DeepEqual(g.Tags(), p.Tags()) && (len(g.Tags()) != 0 || len(p.Tags()) != 0) {
+				t.Errorf("%s: point %d tags: got %v, exp %v", c.Name(), i, g.Tags(), p.Tags())
+			}
+
+			pFields, _ := p.Fields()
+			gFields, err := g.Fields()
+			if err != nil {
+				t.Errorf("%s: point %d fields: %s", c.Name(), i, err)
+			} else if !reflect.DeepEqual(gFields, pFields) {
+				t.Errorf("%s: point %d fields: got %v, exp %v", c.Name(), i, gFields, pFields)
+			}
+
+			// Decoded points are stored as marshaled, so they must marshal as
+			// the originals do.
+			b, err := g.MarshalBinary()
+			if err != nil {
+				t.Fatalf("%s: point %d marshal: %s", c.Name(), i, err)
+			}
+			if other, err := models.NewPointFromBytes(b); err != nil {
+				t.Errorf("%s: point %d unmarshal: %s", c.Name(), i, err)
+			} else if other.String() != p.String() {
+				t.Errorf("%s: point %d marshaled: got %s, exp %s", c.Name(), i, other.String(), p.String())
+			}
+		}
+	}
+}
+
+func TestColumnarPointCodec_Truncated(t *testing.T) {
+	c, _ := rpc.LookupPointCodec(rpc.ColumnarPointCodec)
+	b, err := c.EncodePoints(codecTestPoints(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < len(b); i++ {
+		if _, err := c.DecodePoints(b[:i]); err == nil {
+			t.Fatalf("expected error decoding %d of %d bytes", i, len(b))
+		}
+	}
+}
+
+func TestColumnarPointCodec_Size(t *testing.T) {
+	tags := models.NewTags(map[string]string{"host": "serverA", "region": "uswest"})
+	var points []models.Point
+	for i := 0; i < 1000; i++ {
+		p, err := models.NewPoint("cpu", tags, models.Fields{"idle": float64(i), "user": int64(i)}, time.Unix(int64(i), 0))
+		if err != nil {
+			t.Fatal(err)
+		}
+		points = append(points, p)
+	}
+
+	binary, _ := rpc.LookupPointCodec(rpc.BinaryPointCodec)
+	columnar, _ := rpc.LookupPointCodec(rpc.ColumnarPointCodec)
+	bb, err := binary.EncodePoints(points)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cb, err := columnar.EncodePoints(points)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cb)*2 > len(bb) {
+		t.Fatalf("columnar batch of %d bytes not half of binary batch of %d bytes", len(cb), len(bb))
+	}
+}
+
+func TestWriteShardRequest_PointBatch(t *testing.T) {
+	points := codecTestPoints(t)
+	e, err := rpc.EncodePoints(points)
+	if err != nil {
+		t.Fatal(err)
+	}
+	batch, err := e.Batch(rpc.ColumnarPointCodec)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var req rpc.WriteShardRequest
+	req.SetShardID(1)
+	req.SetPointBatch(rpc.ColumnarPointCodec, batch)
+	b, err := req.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var other rpc.WriteShardRequest
+	if err := other.UnmarshalBinary(b); err != nil {
+		t.Fatal(err)
+	} else if other.PointCodec() != rpc.ColumnarPointCodec {
+		t.Fatalf("unexpected codec: %d", other.PointCodec())
+	}
+	got, err := other.DecodePoints()
+	if err != nil {
+		t.Fatal(err)
+	} else if len(got) != len(points) {
+		t.Fatalf("got %d points, exp %d", len(got), len(points))
+	}
+	for i, p := range points {
+		if got[i].String() != p.String() {
+			t.Errorf("point %d: got %s, exp %s", i, got[i].String(), p.String())
+		}
+	}
+
+	// Encoding points again with the codec returns the batch encoded first.
+	if again, err := e.Batch(rpc.ColumnarPointCodec); err != nil {
+		t.Fatal(err)
+	} else if &again[0] != &batch[0] {
+		t.Fatal("expected batch to be reused")
+	}
+}
+
+func TestWriteShardRequest_UnknownPointCodec(t *testing.T) {
+	var req rpc.WriteShardRequest
+	req.SetShardID(1)
+	req.SetPointBatch(rpc.PointCodecID(1000), []byte{1, 2, 3})
+	b, err := req.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var other rpc.WriteShardRequest
+	if err := other.UnmarshalBinary(b); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := other.DecodePoints(); err == nil {
+		t.Fatal("expected error")
+	} else if e, ok := err.(*rpc.WriteShardError); !ok || e.Code != rpc.CodeUnsupported {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
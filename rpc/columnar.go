@@ -0,0 +1,567 @@
+package rpc
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxdb/pkg/escape"
+)
+
+// columnarVersion is the version of the columnar batch format, its first
+// byte.
+const columnarVersion = 1
+
+// columnarCodec is ColumnarPointCodec. A batch stores each column in turn:
+//
+//	version          1 byte
+//	point count      uvarint
+//	series keys      uvarint count, then each key as uvarint length and bytes
+//	series column    index of the series key of each point, as uvarint
+//	time column      time of each point, as the zigzag varint nanoseconds since
+//	                 the time of the previous point, or since the epoch
+//	field keys       uvarint count, then each key as uvarint length and bytes
+//	count column     number of fields of each point, as uvarint
+//	key column       index of the key of each field, as uvarint
+//	type column      models.FieldType of each field, as a byte
+//	float column     each float field, as 8 bytes of big-endian IEEE 754
+//	integer column   each integer field, as zigzag varint
+//	boolean column   each boolean field, as a byte
+//	string column    each string field, as uvarint length and bytes
+//
+// Series keys and field keys are stored once per batch rather than once per
+// point, and times mostly take a byte or two. Values are stored typed, so
+// the points decoded do not parse their fields when they are iterated.
+type columnarCodec struct{}
+
+func (columnarCodec) Name() string { return "columnar" }
+
+func (columnarCodec) EncodePoints(points []models.Point) ([]byte, error) {
+	var (
+		seriesIdx = make(map[string]int)
+		seriesKey [][]byte
+		series    = make([]int, len(points))
+		fieldIdx  = make(map[string]int)
+		fieldKey  [][]byte
+		counts    = make([]int, len(points))
+		keys      []int
+		types     []byte
+		floats    []float64
+		integers  []int64
+		booleans  []byte
+		strs      []string
+	)
+
+	for i, p := range points {
+		key := p.Key()
+		idx, ok := seriesIdx[string(key)]
+		if !ok {
+			idx = len(seriesKey)
+			seriesIdx[string(key)] = idx
+			seriesKey = append(seriesKey, key)
+		}
+		series[i] = idx
+
+		iter := p.FieldIterator()
+		for iter.Next() {
+			if len(iter.FieldKey()) == 0 || iter.Type() == models.Empty {
+				continue
+			}
+
+			switch iter.Type() {
+			case models.Float:
+				v, err := iter.FloatValue()
+				if err != nil {
+					return nil, err
+				}
+				floats = append(floats, v)
+			case models.Integer:
+				v, err := iter.IntegerValue()
+				if err != nil {
+					return nil, err
+				}
+				integers = append(integers, v)
+			case models.Boolean:
+				v, err := iter.BooleanValue()
+				if err != nil {
+					return nil, err
+				}
+				if v {
+					booleans = append(booleans, 1)
+				} else {
+					booleans = append(booleans, 0)
+				}
+			case models.String:
+				strs = append(strs, iter.StringValue())
+			default:
+				return nil, fmt.Errorf("columnar batch: unsupported field type %d", iter.Type())
+			}
+
+			idx, ok := fieldIdx[string(iter.FieldKey())]
+			if !ok {
+				idx = len(fieldKey)
+				fieldIdx[string(iter.FieldKey())] = idx
+				fieldKey = append(fieldKey, append([]byte(nil), iter.FieldKey()...))
+			}
+			keys = append(keys, idx)
+			types = append(types, byte(iter.Type()))
+			counts[i]++
+		}
+		if counts[i] == 0 {
+			return nil, models.ErrPointMustHaveAField
+		}
+	}
+
+	b := []byte{columnarVersion}
+	b = appendUvarint(b, uint64(len(points)))
+
+	b = appendUvarint(b, uint64(len(seriesKey)))
+	for _, key := range seriesKey {
+		b = appendUvarint(b, uint64(len(key)))
+		b = append(b, key...)
+	}
+	for _, idx := range series {
+		b = appendUvarint(b, uint64(idx))
+	}
+
+	var prev int64
+	for _, p := range points {
+		t := p.UnixNano()
+		b = appendVarint(b, t-prev)
+		prev = t
+	}
+
+	b = appendUvarint(b, uint64(len(fieldKey)))
+	for _, key := range fieldKey {
+		b = appendUvarint(b, uint64(len(key)))
+		b = append(b, key...)
+	}
+	for _, n := range counts {
+		b = appendUvarint(b, uint64(n))
+	}
+	for _, idx := range keys {
+		b = appendUvarint(b, uint64(idx))
+	}
+	b = append(b, types...)
+
+	for _, v := range floats {
+		var buf [8]byte
+		binary.BigEndian.PutUint64(buf[:], math.Float64bits(v))
+		b = append(b, buf[:]...)
+	}
+	for _, v := range integers {
+		b = appendVarint(b, v)
+	}
+	b = append(b, booleans...)
+	for _, v := range strs {
+		b = appendUvarint(b, uint64(len(v)))
+		b = append(b, v...)
+	}
+	return b, nil
+}
+
+func (columnarCodec) DecodePoints(b []byte) ([]models.Point, error) {
+	r := columnarReader{b: b}
+	if v := r.byte(); r.err == nil && v != columnarVersion {
+		return nil, fmt.Errorf("columnar batch: unsupported version %d", v)
+	}
+
+	// Counts larger than the rest of the batch are corrupt, and are not
+	// allocated for.
+	pointN := r.count()
+	seriesKey := make([][]byte, r.count())
+	for i := range seriesKey {
+		seriesKey[i] = r.bytes(r.count())
+	}
+	points := make([]columnarPoint, pointN)
+	for i := range points {
+		idx := r.index()
+		if r.err == nil && idx >= len(seriesKey) {
+			r.err = fmt.Errorf("series key %d out of range", idx)
+		}
+		if r.err != nil {
+			break
+		}
+		points[i].key = seriesKey[idx]
+	}
+
+	var t int64
+	for i := range points {
+		t += r.varint()
+		points[i].time = t
+	}
+
+	fieldKey := make([][]byte, r.count())
+	for i := range fieldKey {
+		fieldKey[i] = r.bytes(r.count())
+	}
+	var fieldN int
+	counts := make([]int, pointN)
+	for i := range counts {
+		counts[i] = r.count()
+		if r.err == nil && counts[i] == 0 {
+			r.err = models.ErrPointMustHaveAField
+		}
+		fieldN += counts[i]
+	}
+	if r.err == nil && fieldN > len(r.b) {
+		r.err = errors.New("field count out of range")
+	}
+	if r.err != nil {
+		return nil, fmt.Errorf("columnar batch: %s", r.err)
+	}
+
+	fields := make([]columnarField, fieldN)
+	for i := range fields {
+		idx := r.index()
+		if r.err == nil && idx >= len(fieldKey) {
+			r.err = fmt.Errorf("field key %d out of range", idx)
+		}
+		if r.err != nil {
+			break
+		}
+		fields[i].key = fieldKey[idx]
+	}
+	for i, typ := range r.bytes(fieldN) {
+		fields[i].typ = models.FieldType(typ)
+	}
+	for i := range fields {
+		f := &fields[i]
+		if f.typ == models.Float {
+			bits := r.bytes(8)
+			if r.err != nil {
+				break
+			}
+			f.f = math.Float64frombits(binary.BigEndian.Uint64(bits))
+		}
+	}
+	for i := range fields {
+		if f := &fields[i]; f.typ == models.Integer {
+			f.i = r.varint()
+		}
+	}
+	for i := range fields {
+		if f := &fields[i]; f.typ == models.Boolean {
+			f.i = int64(r.byte())
+		}
+	}
+	for i := range fields {
+		f := &fields[i]
+		switch f.typ {
+		case models.String:
+			f.s = string(r.bytes(r.count()))
+		case models.Float, models.Integer, models.Boolean:
+		default:
+			if r.err == nil {
+				r.err = fmt.Errorf("unsupported field type %d", f.typ)
+			}
+		}
+	}
+	if r.err == nil && len(r.b) > 0 {
+		r.err = fmt.Errorf("%d trailing bytes", len(r.b))
+	}
+	if r.err != nil {
+		return nil, fmt.Errorf("columnar batch: %s", r.err)
+	}
+
+	a := make([]models.Point, pointN)
+	for i := range points {
+		points[i].fields, fields = fields[:counts[i]:counts[i]], fields[counts[i]:]
+		a[i] = &points[i]
+	}
+	return a, nil
+}
+
+// columnarReader reads the columns of a batch, recording the first error.
+type columnarReader struct {
+	b   []byte
+	err error
+}
+
+func (r *columnarReader) byte() byte {
+	if b := r.bytes(1); len(b) == 1 {
+		return b[0]
+	}
+	return 0
+}
+
+func (r *columnarReader) bytes(n int) []byte {
+	if r.err != nil {
+		return nil
+	} else if n > len(r.b) {
+		r.err = errColumnarTruncated
+		return nil
+	}
+	b := r.b[:n:n]
+	r.b = r.b[n:]
+	return b
+}
+
+func (r *columnarReader) uvarint() uint64 {
+	if r.err != nil {
+		return 0
+	}
+	v, n := binary.Uvarint(r.b)
+	if n <= 0 {
+		r.err = errColumnarTruncated
+		return 0
+	}
+	r.b = r.b[n:]
+	return v
+}
+
+func (r *columnarReader) varint() int64 {
+	if r.err != nil {
+		return 0
+	}
+	v, n := binary.Varint(r.b)
+	if n <= 0 {
+		r.err = errColumnarTruncated
+		return 0
+	}
+	r.b = r.b[n:]
+	return v
+}
+
+// index reads an index into a dictionary.
+func (r *columnarReader) index() int {
+	v := r.uvarint()
+	if v > math.MaxInt32 {
+		return math.MaxInt32
+	}
+	return int(v)
+}
+
+// count reads a count, which cannot exceed the bytes left as each of the
+// items counted takes at least a byte.
+func (r *columnarReader) count() int {
+	v := r.uvarint()
+	if r.err == nil && v > uint64(len(r.b)) {
+		r.err = fmt.Errorf("count %d out of range", v)
+	}
+	if r.err != nil {
+		return 0
+	}
+	return int(v)
+}
+
+// errColumnarTruncated is returned for a batch that ends within a column.
+var errColumnarTruncated = errors.New("truncated")
+
+func appendUvarint(b []byte, v uint64) []byte {
+	var buf [binary.MaxVarintLen64]byte
+	return append(b, buf[:binary.PutUvarint(buf[:], v)]...)
+}
+
+func appendVarint(b []byte, v int64) []byte {
+	var buf [binary.MaxVarintLen64]byte
+	return append(b, buf[:binary.PutVarint(buf[:], v)]...)
+}
+
+// columnarField is a field of a columnarPoint. Booleans are stored in i.
+type columnarField struct {
+	key []byte
+	typ models.FieldType
+	f   float64
+	i   int64
+	s   string
+}
+
+// columnarPoint is a point decoded from a columnar batch. It keeps its fields
+// typed, so that iterating them does not parse them, and otherwise behaves
+// as the points of the models package.
+type columnarPoint struct {
+	key    []byte
+	time   int64
+	fields []columnarField
+
+	cachedName string
+	cachedTags models.Tags
+
+	it int // the current field of FieldIterator, plus one
+}
+
+func (p *columnarPoint) Name() string {
+	if p.cachedName == "" {
+		name, _, _ := models.ParseKey(p.key)
+		p.cachedName = escape.UnescapeString(name)
+	}
+	return p.cachedName
+}
+
+func (p *columnarPoint) SetName(name string) {
+	p.key = models.MakeKey([]byte(name), p.Tags())
+	p.cachedName = ""
+}
+
+func (p *columnarPoint) Tags() models.Tags {
+	if p.cachedTags == nil {
+		_, p.cachedTags, _ = models.ParseKey(p.key)
+	}
+	return p.cachedTags
+}
+
+func (p *columnarPoint) AddTag(key, value string) {
+	tags := append(p.Tags(), models.Tag{Key: []byte(key), Value: []byte(value)})
+	sort.Sort(tags)
+	p.key = models.MakeKey([]byte(p.Name()), tags)
+	p.cachedTags = tags
+}
+
+func (p *columnarPoint) SetTags(tags models.Tags) {
+	p.key = models.MakeKey([]byte(p.Name()), tags)
+	p.cachedTags = tags
+}
+
+func (p *columnarPoint) Fields() (models.Fields, error) {
+	fields := make(models.Fields, len(p.fields))
+	for i := range p.fields {
+		f := &p.fields[i]
+		switch f.typ {
+		case models.Float:
+			fields[string(f.key)] = f.f
+		case models.Integer:
+			fields[string(f.key)] = f.i
+		case models.Boolean:
+			fields[string(f.key)] = f.i != 0
+		case models.String:
+			fields[string(f.key)] = f.s
+		}
+	}
+	return fields, nil
+}
+
+func (p *columnarPoint) Time() time.Time { return time.Unix(0, p.time).UTC() }
+
+func (p *columnarPoint) SetTime(t time.Time) { p.time = t.UnixNano() }
+
+func (p *columnarPoint) UnixNano() int64 { return p.time }
+
+func (p *columnarPoint) HashID() uint64 {
+	h := models.NewInlineFNV64a()
+	h.Write(p.key)
+	return h.Sum64()
+}
+
+func (p *columnarPoint) Key() []byte { return p.key }
+
+func (p *columnarPoint) String() string { return string(p.AppendString(nil)) }
+
+// appendFields appends the fields of the point as in line protocol.
+func (p *columnarPoint) appendFields(b []byte) []byte {
+	for i := range p.fields {
+		f := &p.fields[i]
+		if i > 0 {
+			b = append(b, ',')
+		}
+		b = append(b, escape.Bytes(f.key)...)
+		b = append(b, '=')
+		switch f.typ {
+		case models.Float:
+			b = strconv.AppendFloat(b, f.f, 'f', -1, 64)
+		case models.Integer:
+			b = strconv.AppendInt(b, f.i, 10)
+			b = append(b, 'i')
+		case models.Boolean:
+			b = strconv.AppendBool(b, f.i != 0)
+		case models.String:
+			b = append(b, '"')
+			b = append(b, models.EscapeStringField(f.s)...)
+			b = append(b, '"')
+		}
+	}
+	return b
+}
+
+func (p *columnarPoint) MarshalBinary() ([]byte, error) {
+	fields := p.appendFields(nil)
+	tb, err := p.Time().MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+
+	b := make([]byte, 8+len(p.key)+len(fields)+len(tb))
+	i := 0
+	binary.BigEndian.PutUint32(b[i:], uint32(len(p.key)))
+	i += 4
+	i += copy(b[i:], p.key)
+	binary.BigEndian.PutUint32(b[i:], uint32(len(fields)))
+	i += 4
+	i += copy(b[i:], fields)
+	copy(b[i:], tb)
+	return b, nil
+}
+
+// point returns the point as a point of the models package.
+func (p *columnarPoint) point() models.Point {
+	b, err := p.MarshalBinary()
+	if err != nil {
+		panic(fmt.Sprintf("failed to marshal point: %v", err))
+	}
+	pt, err := models.NewPointFromBytes(b)
+	if err != nil {
+		panic(fmt.Sprintf("failed to parse point: %v", err))
+	}
+	return pt
+}
+
+func (p *columnarPoint) PrecisionString(precision string) string {
+	return p.point().PrecisionString(precision)
+}
+
+func (p *columnarPoint) RoundedString(d time.Duration) string {
+	return p.point().RoundedString(d)
+}
+
+func (p *columnarPoint) Split(size int) []models.Point {
+	if len(p.String()) <= size {
+		return []models.Point{p}
+	}
+	return p.point().Split(size)
+}
+
+func (p *columnarPoint) Round(d time.Duration) { p.SetTime(p.Time().Round(d)) }
+
+func (p *columnarPoint) StringSize() int { return len(p.AppendString(nil)) }
+
+func (p *columnarPoint) AppendString(buf []byte) []byte {
+	buf = append(buf, p.key...)
+	buf = append(buf, ' ')
+	buf = p.appendFields(buf)
+	buf = append(buf, ' ')
+	return strconv.AppendInt(buf, p.time, 10)
+}
+
+func (p *columnarPoint) FieldIterator() models.FieldIterator {
+	p.Reset()
+	return p
+}
+
+func (p *columnarPoint) Next() bool {
+	if p.it >= len(p.fields) {
+		return false
+	}
+	p.it++
+	return true
+}
+
+func (p *columnarPoint) field() *columnarField { return &p.fields[p.it-1] }
+
+func (p *columnarPoint) FieldKey() []byte { return p.field().key }
+
+func (p *columnarPoint) Type() models.FieldType { return p.field().typ }
+
+func (p *columnarPoint) StringValue() string { return p.field().s }
+
+func (p *columnarPoint) IntegerValue() (int64, error) { return p.field().i, nil }
+
+func (p *columnarPoint) BooleanValue() (bool, error) { return p.field().i != 0, nil }
+
+func (p *columnarPoint) FloatValue() (float64, error) { return p.field().f, nil }
+
+func (p *columnarPoint) Reset() { p.it = 0 }
@@ -0,0 +1,136 @@
+package rpc
+
+import "fmt"
+
+// ErrorCode identifies why a remote node failed to process a request.
+type ErrorCode int
+
+// Error codes returned in a WriteShardResponse, and in the responses of
+// other requests that report why they failed.
+const (
+	CodeOK ErrorCode = iota
+	CodeUnknown
+	CodeShardNotFound
+	CodeFieldTypeConflict
+	CodeStoreClosed
+	CodeOverloaded
+	CodeAuthFailed
+	CodeDraining
+	CodeDeadlineExceeded
+	CodeUnsupported
+	CodeChecksumMismatch
+	CodeInternal
+	CodeFrameTooLarge
+	CodeReadOnly
+)
+
+// String returns the name of the error code.
+func (c ErrorCode) String() string {
+	switch c {
+	case CodeOK:
+		return "ok"
+	case CodeUnknown:
+		return "unknown"
+	case CodeShardNotFound:
+		return "shard not found"
+	case CodeFieldTypeConflict:
+		return "field type conflict"
+	case CodeStoreClosed:
+		return "store closed"
+	case CodeOverloaded:
+		return "overloaded"
+	case CodeAuthFailed:
+		return "auth failed"
+	case CodeDraining:
+		return "draining"
+	case CodeDeadlineExceeded:
+		return "deadline exceeded"
+	case CodeUnsupported:
+		return "unsupported"
+	case CodeChecksumMismatch:
+		return "checksum mismatch"
+	case CodeInternal:
+		return "internal error"
+	case CodeFrameTooLarge:
+		return "frame too large"
+	case CodeReadOnly:
+		return "read only"
+	default:
+		return fmt.Sprintf("code %d", int(c))
+	}
+}
+
+// WriteShardError is returned when a remote node fails to write to a shard.
+type WriteShardError struct {
+	Code    ErrorCode
+	Message string
+
+	// RequestID is the ID of the client request of the failed write, if the
+	// remote node reported it.
+	RequestID string
+
+	// Rejected are the indices of the points the remote node rejected, if
+	// it reported them. The other points of the write were written.
+	Rejected []int
+}
+
+// Error returns the error code and message from the remote node.
+func (e *WriteShardError) Error() string {
+	if e.RequestID != "" {
+		return fmt.Sprintf("error code %d: %s (request %s)", int(e.Code), e.Message, e.RequestID)
+	}
+	return fmt.Sprintf("error code %d: %s", int(e.Code), e.Message)
+}
+
+// Retryable returns true if the write may succeed later, either by retrying
+// it or by queueing it in hinted handoff. Field type conflicts and auth
+// failures are permanent and will fail again on every attempt, as will writes
+// that crashed the remote node's handler or exceed its maximum message size.
+func (e *WriteShardError) Retryable() bool {
+	switch e.Code {
+	case CodeFieldTypeConflict, CodeAuthFailed, CodeInternal, CodeFrameTooLarge:
+		return false
+	default:
+		return true
+	}
+}
+
+// QueryLimitError is returned when a remote query exceeds one of the limits a
+// data node places on the resources used by a single query.
+type QueryLimitError struct {
+	Limit string
+	Max   int64
+}
+
+// Error returns the limit that was exceeded.
+func (e *QueryLimitError) Error() string {
+	return fmt.Sprintf("query limit exceeded: %s (%d)", e.Limit, e.Max)
+}
+
+// IteratorOptionsVersionError is returned when a remote iterator is requested
+// with a newer encoding of its options than this node supports. The request
+// is rejected rather than run without the options this node cannot decode,
+// which could change the results of the query.
+type IteratorOptionsVersionError struct {
+	Version uint32
+}
+
+// Error returns the version of the request and the supported version.
+func (e *IteratorOptionsVersionError) Error() string {
+	return fmt.Sprintf("unsupported iterator options version %d, expected %d or lower", e.Version, IteratorOptionsVersion)
+}
+
+// ChecksumError is returned when streamed shard data does not match the
+// checksums sent with it. The stream may succeed if it is sent again.
+type ChecksumError struct {
+	ShardID uint64
+	File    string
+}
+
+// Error returns the shard and the file that failed verification, if known.
+func (e *ChecksumError) Error() string {
+	if e.File == "" {
+		return fmt.Sprintf("checksum mismatch in shard %d", e.ShardID)
+	}
+	return fmt.Sprintf("checksum mismatch in shard %d: %s", e.ShardID, e.File)
+}
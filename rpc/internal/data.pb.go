@@ -38,6 +38,8 @@ It has these top-level messages:
 	DownloadShardSnapshotResponse
 	ShardStatusRequest
 	ShardStatusResponse
+	NodeLoad
+	ShardStatus
 	CreateShardSnapshotRequest
 	CreateShardSnapshotResponse
 	DeleteShardSnapshotRequest
@@ -55,6 +57,57 @@ It has these top-level messages:
 	TagValues
 	ShowTagValuesRequest
 	ShowTagValuesResponse
+	ShardInfo
+	ShowShardsRequest
+	ShowShardsResponse
+	BackupShardRequest
+	BackupShardResponse
+	TruncateShardsRequest
+	TruncateShardsResponse
+	RemoveDataNodeRequest
+	RemoveDataNodeResponse
+	UpdateDataNodeRequest
+	UpdateDataNodeResponse
+	AuthStateRequest
+	AuthStateResponse
+	PauseReplicationRequest
+	PauseReplicationResponse
+	ExportMetaDataRequest
+	ExportMetaDataResponse
+	SpanContext
+	SpanContextEntry
+	WritePointsRequest
+	WritePointsResponse
+	ShardBoundsRequest
+	ShardBoundsResponse
+	ShardBounds
+	MeasurementBounds
+	IteratorEnd
+	AcquireLeaseRequest
+	AcquireLeaseResponse
+	UploadShardSnapshotRequest
+	UploadShardSnapshotResponse
+	ReplaceDataNodeRequest
+	ReplaceDataNodeResponse
+	RedirectHintedHandoffRequest
+	RedirectHintedHandoffResponse
+	HelloRequest
+	HelloResponse
+	DropShardsRequest
+	DropShardsResponse
+	ErrorResponse
+	SetMaintenanceRequest
+	SetMaintenanceResponse
+	SetReadOnlyRequest
+	SetReadOnlyResponse
+	CardinalityRequest
+	CardinalityResponse
+	DatabaseCardinality
+	ShardCardinality
+	QuarantineRequest
+	QuarantineResponse
+	QuarantinedWrite
+	AdminToken
 */
 package internal
 
@@ -77,7 +130,7 @@ type CopyShardRequest struct {
 	Source           *string `protobuf:"bytes,1,req,name=Source,json=source" json:"Source,omitempty"`
 	Dest             *string `protobuf:"bytes,2,req,name=Dest,json=dest" json:"Dest,omitempty"`
 	Database         *string `protobuf:"bytes,3,opt,name=Database,json=database" json:"Database,omitempty"`
-	Policy           *string `protobuf:"bytes,4,req,name=Policy,json=policy" json:"Policy,omitempty"`
+	Policy           *string `protobuf:"bytes,4,opt,name=Policy,json=policy" json:"Policy,omitempty"`
 	ShardID          *uint64 `protobuf:"varint,5,req,name=ShardID,json=shardID" json:"ShardID,omitempty"`
 	XXX_unrecognized []byte  `json:"-"`
 }
@@ -451,6 +504,13 @@ type WriteShardRequest struct {
 	Points           [][]byte `protobuf:"bytes,2,rep,name=Points,json=points" json:"Points,omitempty"`
 	Database         *string  `protobuf:"bytes,3,opt,name=Database,json=database" json:"Database,omitempty"`
 	RetentionPolicy  *string  `protobuf:"bytes,4,opt,name=RetentionPolicy,json=retentionPolicy" json:"RetentionPolicy,omitempty"`
+	RequestID        *string  `protobuf:"bytes,5,opt,name=RequestID,json=requestID" json:"RequestID,omitempty"`
+	Timeout          *int64   `protobuf:"varint,6,opt,name=Timeout,json=timeout" json:"Timeout,omitempty"`
+	OriginNodeID     *uint64  `protobuf:"varint,7,opt,name=OriginNodeID,json=originNodeID" json:"OriginNodeID,omitempty"`
+	HandoffQueueID   *uint64  `protobuf:"varint,8,opt,name=HandoffQueueID,json=handoffQueueID" json:"HandoffQueueID,omitempty"`
+	HandoffSequence  *uint64  `protobuf:"varint,9,opt,name=HandoffSequence,json=handoffSequence" json:"HandoffSequence,omitempty"`
+	PointCodec       *uint32  `protobuf:"varint,10,opt,name=PointCodec,json=pointCodec" json:"PointCodec,omitempty"`
+	PointBatch       []byte   `protobuf:"bytes,11,opt,name=PointBatch,json=pointBatch" json:"PointBatch,omitempty"`
 	XXX_unrecognized []byte   `json:"-"`
 }
 
@@ -487,10 +547,61 @@ func (m *WriteShardRequest) GetRetentionPolicy() string {
 	return ""
 }
 
+func (m *WriteShardRequest) GetRequestID() string {
+	if m != nil && m.RequestID != nil {
+		return *m.RequestID
+	}
+	return ""
+}
+
+func (m *WriteShardRequest) GetTimeout() int64 {
+	if m != nil && m.Timeout != nil {
+		return *m.Timeout
+	}
+	return 0
+}
+
+func (m *WriteShardRequest) GetOriginNodeID() uint64 {
+	if m != nil && m.OriginNodeID != nil {
+		return *m.OriginNodeID
+	}
+	return 0
+}
+
+func (m *WriteShardRequest) GetHandoffQueueID() uint64 {
+	if m != nil && m.HandoffQueueID != nil {
+		return *m.HandoffQueueID
+	}
+	return 0
+}
+
+func (m *WriteShardRequest) GetHandoffSequence() uint64 {
+	if m != nil && m.HandoffSequence != nil {
+		return *m.HandoffSequence
+	}
+	return 0
+}
+
+func (m *WriteShardRequest) GetPointCodec() uint32 {
+	if m != nil && m.PointCodec != nil {
+		return *m.PointCodec
+	}
+	return 0
+}
+
+func (m *WriteShardRequest) GetPointBatch() []byte {
+	if m != nil {
+		return m.PointBatch
+	}
+	return nil
+}
+
 type WriteShardResponse struct {
-	Code             *int32  `protobuf:"varint,1,req,name=Code,json=code" json:"Code,omitempty"`
-	Message          *string `protobuf:"bytes,2,opt,name=Message,json=message" json:"Message,omitempty"`
-	XXX_unrecognized []byte  `json:"-"`
+	Code             *int32   `protobuf:"varint,1,req,name=Code,json=code" json:"Code,omitempty"`
+	Message          *string  `protobuf:"bytes,2,opt,name=Message,json=message" json:"Message,omitempty"`
+	RequestID        *string  `protobuf:"bytes,3,opt,name=RequestID,json=requestID" json:"RequestID,omitempty"`
+	Rejected         []uint32 `protobuf:"varint,4,rep,packed,name=Rejected,json=rejected" json:"Rejected,omitempty"`
+	XXX_unrecognized []byte   `json:"-"`
 }
 
 func (m *WriteShardResponse) Reset()                    { *m = WriteShardResponse{} }
@@ -512,9 +623,25 @@ func (m *WriteShardResponse) GetMessage() string {
 	return ""
 }
 
+func (m *WriteShardResponse) GetRequestID() string {
+	if m != nil && m.RequestID != nil {
+		return *m.RequestID
+	}
+	return ""
+}
+
+func (m *WriteShardResponse) GetRejected() []uint32 {
+	if m != nil {
+		return m.Rejected
+	}
+	return nil
+}
+
 type ExecuteStatementRequest struct {
 	Statement        *string `protobuf:"bytes,1,req,name=Statement,json=statement" json:"Statement,omitempty"`
 	Database         *string `protobuf:"bytes,2,req,name=Database,json=database" json:"Database,omitempty"`
+	RequestID        *string `protobuf:"bytes,3,opt,name=RequestID,json=requestID" json:"RequestID,omitempty"`
+	NodeID           *uint64 `protobuf:"varint,4,opt,name=NodeID,json=nodeID" json:"NodeID,omitempty"`
 	XXX_unrecognized []byte  `json:"-"`
 }
 
@@ -537,9 +664,24 @@ func (m *ExecuteStatementRequest) GetDatabase() string {
 	return ""
 }
 
+func (m *ExecuteStatementRequest) GetRequestID() string {
+	if m != nil && m.RequestID != nil {
+		return *m.RequestID
+	}
+	return ""
+}
+
+func (m *ExecuteStatementRequest) GetNodeID() uint64 {
+	if m != nil && m.NodeID != nil {
+		return *m.NodeID
+	}
+	return 0
+}
+
 type ExecuteStatementResponse struct {
 	Code             *int32  `protobuf:"varint,1,req,name=Code,json=code" json:"Code,omitempty"`
 	Message          *string `protobuf:"bytes,2,opt,name=Message,json=message" json:"Message,omitempty"`
+	SeriesN          *int64  `protobuf:"varint,3,opt,name=SeriesN,json=seriesN" json:"SeriesN,omitempty"`
 	XXX_unrecognized []byte  `json:"-"`
 }
 
@@ -562,10 +704,26 @@ func (m *ExecuteStatementResponse) GetMessage() string {
 	return ""
 }
 
+func (m *ExecuteStatementResponse) GetSeriesN() int64 {
+	if m != nil && m.SeriesN != nil {
+		return *m.SeriesN
+	}
+	return 0
+}
+
 type CreateIteratorRequest struct {
-	ShardIDs         []uint64 `protobuf:"varint,1,rep,name=ShardIDs,json=shardIDs" json:"ShardIDs,omitempty"`
-	Opt              []byte   `protobuf:"bytes,2,req,name=Opt,json=opt" json:"Opt,omitempty"`
-	XXX_unrecognized []byte   `json:"-"`
+	ShardIDs  []uint64 `protobuf:"varint,1,rep,name=ShardIDs,json=shardIDs" json:"ShardIDs,omitempty"`
+	Opt       []byte   `protobuf:"bytes,2,req,name=Opt,json=opt" json:"Opt,omitempty"`
+	RequestID *string  `protobuf:"bytes,3,opt,name=RequestID,json=requestID" json:"RequestID,omitempty"`
+	// The GROUP BY tags of the query, which the encoding of Opt leaves out.
+	GroupBy []string `protobuf:"bytes,4,rep,name=GroupBy,json=groupBy" json:"GroupBy,omitempty"`
+	// The version of the encoding of the options, unset before versions.
+	Version *uint32 `protobuf:"varint,5,opt,name=Version,json=version" json:"Version,omitempty"`
+	// The compression of the stream of points the querying node accepts.
+	Compression *string `protobuf:"bytes,6,opt,name=Compression,json=compression" json:"Compression,omitempty"`
+	// The encoding of the stream of points the querying node accepts.
+	Encoding         *string `protobuf:"bytes,7,opt,name=Encoding,json=encoding" json:"Encoding,omitempty"`
+	XXX_unrecognized []byte  `json:"-"`
 }
 
 func (m *CreateIteratorRequest) Reset()                    { *m = CreateIteratorRequest{} }
@@ -587,8 +745,49 @@ func (m *CreateIteratorRequest) GetOpt() []byte {
 	return nil
 }
 
+func (m *CreateIteratorRequest) GetRequestID() string {
+	if m != nil && m.RequestID != nil {
+		return *m.RequestID
+	}
+	return ""
+}
+
+func (m *CreateIteratorRequest) GetGroupBy() []string {
+	if m != nil {
+		return m.GroupBy
+	}
+	return nil
+}
+
+func (m *CreateIteratorRequest) GetVersion() uint32 {
+	if m != nil && m.Version != nil {
+		return *m.Version
+	}
+	return 0
+}
+
+func (m *CreateIteratorRequest) GetCompression() string {
+	if m != nil && m.Compression != nil {
+		return *m.Compression
+	}
+	return ""
+}
+
+func (m *CreateIteratorRequest) GetEncoding() string {
+	if m != nil && m.Encoding != nil {
+		return *m.Encoding
+	}
+	return ""
+}
+
 type CreateIteratorResponse struct {
-	Err              *string `protobuf:"bytes,1,opt,name=Err,json=err" json:"Err,omitempty"`
+	Err      *string `protobuf:"bytes,1,opt,name=Err,json=err" json:"Err,omitempty"`
+	Limit    *string `protobuf:"bytes,2,opt,name=Limit,json=limit" json:"Limit,omitempty"`
+	LimitMax *int64  `protobuf:"varint,3,opt,name=LimitMax,json=limitMax" json:"LimitMax,omitempty"`
+	// The compression of the stream of points, if any.
+	Compression *string `protobuf:"bytes,4,opt,name=Compression,json=compression" json:"Compression,omitempty"`
+	// The encoding of the stream of points, if not the influxql encoding.
+	Encoding         *string `protobuf:"bytes,5,opt,name=Encoding,json=encoding" json:"Encoding,omitempty"`
 	XXX_unrecognized []byte  `json:"-"`
 }
 
@@ -604,6 +803,34 @@ func (m *CreateIteratorResponse) GetErr() string {
 	return ""
 }
 
+func (m *CreateIteratorResponse) GetLimit() string {
+	if m != nil && m.Limit != nil {
+		return *m.Limit
+	}
+	return ""
+}
+
+func (m *CreateIteratorResponse) GetLimitMax() int64 {
+	if m != nil && m.LimitMax != nil {
+		return *m.LimitMax
+	}
+	return 0
+}
+
+func (m *CreateIteratorResponse) GetCompression() string {
+	if m != nil && m.Compression != nil {
+		return *m.Compression
+	}
+	return ""
+}
+
+func (m *CreateIteratorResponse) GetEncoding() string {
+	if m != nil && m.Encoding != nil {
+		return *m.Encoding
+	}
+	return ""
+}
+
 type IteratorStats struct {
 	SeriesN          *uint64 `protobuf:"varint,1,req,name=SeriesN,json=seriesN" json:"SeriesN,omitempty"`
 	PointN           []byte  `protobuf:"bytes,2,req,name=PointN,json=pointN" json:"PointN,omitempty"`
@@ -632,6 +859,7 @@ func (m *IteratorStats) GetPointN() []byte {
 type FieldDimensionsRequest struct {
 	ShardIDs         []uint64 `protobuf:"varint,1,rep,name=ShardIDs,json=shardIDs" json:"ShardIDs,omitempty"`
 	Sources          []byte   `protobuf:"bytes,2,req,name=Sources,json=sources" json:"Sources,omitempty"`
+	RequestID        *string  `protobuf:"bytes,3,opt,name=RequestID,json=requestID" json:"RequestID,omitempty"`
 	XXX_unrecognized []byte   `json:"-"`
 }
 
@@ -654,6 +882,13 @@ func (m *FieldDimensionsRequest) GetSources() []byte {
 	return nil
 }
 
+func (m *FieldDimensionsRequest) GetRequestID() string {
+	if m != nil && m.RequestID != nil {
+		return *m.RequestID
+	}
+	return ""
+}
+
 type Field struct {
 	Name             *string `protobuf:"bytes,1,req,name=Name,json=name" json:"Name,omitempty"`
 	Type             *uint64 `protobuf:"varint,2,req,name=Type,json=type" json:"Type,omitempty"`
@@ -809,8 +1044,8 @@ func (m *DownloadShardSnapshotResponse) GetErr() string {
 }
 
 type ShardStatusRequest struct {
-	ShardID          *uint64 `protobuf:"varint,1,req,name=ShardID,json=shardID" json:"ShardID,omitempty"`
-	XXX_unrecognized []byte  `json:"-"`
+	ShardIDs         []uint64 `protobuf:"varint,1,rep,name=ShardIDs,json=shardIDs" json:"ShardIDs,omitempty"`
+	XXX_unrecognized []byte   `json:"-"`
 }
 
 func (m *ShardStatusRequest) Reset()                    { *m = ShardStatusRequest{} }
@@ -818,17 +1053,18 @@ func (m *ShardStatusRequest) String() string            { return proto.CompactTe
 func (*ShardStatusRequest) ProtoMessage()               {}
 func (*ShardStatusRequest) Descriptor() ([]byte, []int) { return fileDescriptorData, []int{27} }
 
-func (m *ShardStatusRequest) GetShardID() uint64 {
-	if m != nil && m.ShardID != nil {
-		return *m.ShardID
+func (m *ShardStatusRequest) GetShardIDs() []uint64 {
+	if m != nil {
+		return m.ShardIDs
 	}
-	return 0
+	return nil
 }
 
 type ShardStatusResponse struct {
-	Err              *string `protobuf:"bytes,1,req,name=Err,json=err" json:"Err,omitempty"`
-	Size_            *uint64 `protobuf:"varint,2,req,name=Size,json=size" json:"Size,omitempty"`
-	XXX_unrecognized []byte  `json:"-"`
+	Err              *string        `protobuf:"bytes,1,req,name=Err,json=err" json:"Err,omitempty"`
+	Shards           []*ShardStatus `protobuf:"bytes,2,rep,name=Shards,json=shards" json:"Shards,omitempty"`
+	Load             *NodeLoad      `protobuf:"bytes,3,opt,name=Load,json=load" json:"Load,omitempty"`
+	XXX_unrecognized []byte         `json:"-"`
 }
 
 func (m *ShardStatusResponse) Reset()                    { *m = ShardStatusResponse{} }
@@ -843,13 +1079,142 @@ func (m *ShardStatusResponse) GetErr() string {
 	return ""
 }
 
-func (m *ShardStatusResponse) GetSize_() uint64 {
+func (m *ShardStatusResponse) GetShards() []*ShardStatus {
+	if m != nil {
+		return m.Shards
+	}
+	return nil
+}
+
+func (m *ShardStatusResponse) GetLoad() *NodeLoad {
+	if m != nil {
+		return m.Load
+	}
+	return nil
+}
+
+type NodeLoad struct {
+	WALBytes         *int64  `protobuf:"varint,1,req,name=WALBytes,json=wALBytes" json:"WALBytes,omitempty"`
+	CacheBytes       *int64  `protobuf:"varint,2,req,name=CacheBytes,json=cacheBytes" json:"CacheBytes,omitempty"`
+	CompactionDebt   *int64  `protobuf:"varint,3,req,name=CompactionDebt,json=compactionDebt" json:"CompactionDebt,omitempty"`
+	Overloaded       *string `protobuf:"bytes,4,req,name=Overloaded,json=overloaded" json:"Overloaded,omitempty"`
+	XXX_unrecognized []byte  `json:"-"`
+}
+
+func (m *NodeLoad) Reset()                    { *m = NodeLoad{} }
+func (m *NodeLoad) String() string            { return proto.CompactTextString(m) }
+func (*NodeLoad) ProtoMessage()               {}
+func (*NodeLoad) Descriptor() ([]byte, []int) { return fileDescriptorData, []int{29} }
+
+func (m *NodeLoad) GetWALBytes() int64 {
+	if m != nil && m.WALBytes != nil {
+		return *m.WALBytes
+	}
+	return 0
+}
+
+func (m *NodeLoad) GetCacheBytes() int64 {
+	if m != nil && m.CacheBytes != nil {
+		return *m.CacheBytes
+	}
+	return 0
+}
+
+func (m *NodeLoad) GetCompactionDebt() int64 {
+	if m != nil && m.CompactionDebt != nil {
+		return *m.CompactionDebt
+	}
+	return 0
+}
+
+func (m *NodeLoad) GetOverloaded() string {
+	if m != nil && m.Overloaded != nil {
+		return *m.Overloaded
+	}
+	return ""
+}
+
+type ShardStatus struct {
+	ID               *uint64 `protobuf:"varint,1,req,name=ID,json=iD" json:"ID,omitempty"`
+	Database         *string `protobuf:"bytes,2,req,name=Database,json=database" json:"Database,omitempty"`
+	Policy           *string `protobuf:"bytes,3,req,name=Policy,json=policy" json:"Policy,omitempty"`
+	Size_            *int64  `protobuf:"varint,4,req,name=Size,json=size" json:"Size,omitempty"`
+	SeriesN          *int64  `protobuf:"varint,5,req,name=SeriesN,json=seriesN" json:"SeriesN,omitempty"`
+	LastModified     *int64  `protobuf:"varint,6,req,name=LastModified,json=lastModified" json:"LastModified,omitempty"`
+	LastWrite        *int64  `protobuf:"varint,7,req,name=LastWrite,json=lastWrite" json:"LastWrite,omitempty"`
+	Cold             *bool   `protobuf:"varint,8,req,name=Cold,json=cold" json:"Cold,omitempty"`
+	Corrupt          *string `protobuf:"bytes,9,opt,name=Corrupt,json=corrupt" json:"Corrupt,omitempty"`
+	XXX_unrecognized []byte  `json:"-"`
+}
+
+func (m *ShardStatus) Reset()                    { *m = ShardStatus{} }
+func (m *ShardStatus) String() string            { return proto.CompactTextString(m) }
+func (*ShardStatus) ProtoMessage()               {}
+func (*ShardStatus) Descriptor() ([]byte, []int) { return fileDescriptorData, []int{30} }
+
+func (m *ShardStatus) GetID() uint64 {
+	if m != nil && m.ID != nil {
+		return *m.ID
+	}
+	return 0
+}
+
+func (m *ShardStatus) GetDatabase() string {
+	if m != nil && m.Database != nil {
+		return *m.Database
+	}
+	return ""
+}
+
+func (m *ShardStatus) GetPolicy() string {
+	if m != nil && m.Policy != nil {
+		return *m.Policy
+	}
+	return ""
+}
+
+func (m *ShardStatus) GetSize_() int64 {
 	if m != nil && m.Size_ != nil {
 		return *m.Size_
 	}
 	return 0
 }
 
+func (m *ShardStatus) GetSeriesN() int64 {
+	if m != nil && m.SeriesN != nil {
+		return *m.SeriesN
+	}
+	return 0
+}
+
+func (m *ShardStatus) GetLastModified() int64 {
+	if m != nil && m.LastModified != nil {
+		return *m.LastModified
+	}
+	return 0
+}
+
+func (m *ShardStatus) GetLastWrite() int64 {
+	if m != nil && m.LastWrite != nil {
+		return *m.LastWrite
+	}
+	return 0
+}
+
+func (m *ShardStatus) GetCold() bool {
+	if m != nil && m.Cold != nil {
+		return *m.Cold
+	}
+	return false
+}
+
+func (m *ShardStatus) GetCorrupt() string {
+	if m != nil && m.Corrupt != nil {
+		return *m.Corrupt
+	}
+	return ""
+}
+
 type CreateShardSnapshotRequest struct {
 	ShardID          *uint64 `protobuf:"varint,1,req,name=ShardID,json=shardID" json:"ShardID,omitempty"`
 	XXX_unrecognized []byte  `json:"-"`
@@ -858,7 +1223,7 @@ type CreateShardSnapshotRequest struct {
 func (m *CreateShardSnapshotRequest) Reset()                    { *m = CreateShardSnapshotRequest{} }
 func (m *CreateShardSnapshotRequest) String() string            { return proto.CompactTextString(m) }
 func (*CreateShardSnapshotRequest) ProtoMessage()               {}
-func (*CreateShardSnapshotRequest) Descriptor() ([]byte, []int) { return fileDescriptorData, []int{29} }
+func (*CreateShardSnapshotRequest) Descriptor() ([]byte, []int) { return fileDescriptorData, []int{31} }
 
 func (m *CreateShardSnapshotRequest) GetShardID() uint64 {
 	if m != nil && m.ShardID != nil {
@@ -874,10 +1239,12 @@ type CreateShardSnapshotResponse struct {
 	XXX_unrecognized []byte  `json:"-"`
 }
 
-func (m *CreateShardSnapshotResponse) Reset()                    { *m = CreateShardSnapshotResponse{} }
-func (m *CreateShardSnapshotResponse) String() string            { return proto.CompactTextString(m) }
-func (*CreateShardSnapshotResponse) ProtoMessage()               {}
-func (*CreateShardSnapshotResponse) Descriptor() ([]byte, []int) { return fileDescriptorData, []int{30} }
+func (m *CreateShardSnapshotResponse) Reset()         { *m = CreateShardSnapshotResponse{} }
+func (m *CreateShardSnapshotResponse) String() string { return proto.CompactTextString(m) }
+func (*CreateShardSnapshotResponse) ProtoMessage()    {}
+func (*CreateShardSnapshotResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptorData, []int{32}
+}
 
 func (m *CreateShardSnapshotResponse) GetErr() string {
 	if m != nil && m.Err != nil {
@@ -909,7 +1276,7 @@ type DeleteShardSnapshotRequest struct {
 func (m *DeleteShardSnapshotRequest) Reset()                    { *m = DeleteShardSnapshotRequest{} }
 func (m *DeleteShardSnapshotRequest) String() string            { return proto.CompactTextString(m) }
 func (*DeleteShardSnapshotRequest) ProtoMessage()               {}
-func (*DeleteShardSnapshotRequest) Descriptor() ([]byte, []int) { return fileDescriptorData, []int{31} }
+func (*DeleteShardSnapshotRequest) Descriptor() ([]byte, []int) { return fileDescriptorData, []int{33} }
 
 func (m *DeleteShardSnapshotRequest) GetShardID() uint64 {
 	if m != nil && m.ShardID != nil {
@@ -930,10 +1297,12 @@ type DeleteShardSnapshotResponse struct {
 	XXX_unrecognized []byte  `json:"-"`
 }
 
-func (m *DeleteShardSnapshotResponse) Reset()                    { *m = DeleteShardSnapshotResponse{} }
-func (m *DeleteShardSnapshotResponse) String() string            { return proto.CompactTextString(m) }
-func (*DeleteShardSnapshotResponse) ProtoMessage()               {}
-func (*DeleteShardSnapshotResponse) Descriptor() ([]byte, []int) { return fileDescriptorData, []int{32} }
+func (m *DeleteShardSnapshotResponse) Reset()         { *m = DeleteShardSnapshotResponse{} }
+func (m *DeleteShardSnapshotResponse) String() string { return proto.CompactTextString(m) }
+func (*DeleteShardSnapshotResponse) ProtoMessage()    {}
+func (*DeleteShardSnapshotResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptorData, []int{34}
+}
 
 func (m *DeleteShardSnapshotResponse) GetErr() string {
 	if m != nil && m.Err != nil {
@@ -954,7 +1323,7 @@ type QueryInfo struct {
 func (m *QueryInfo) Reset()                    { *m = QueryInfo{} }
 func (m *QueryInfo) String() string            { return proto.CompactTextString(m) }
 func (*QueryInfo) ProtoMessage()               {}
-func (*QueryInfo) Descriptor() ([]byte, []int) { return fileDescriptorData, []int{33} }
+func (*QueryInfo) Descriptor() ([]byte, []int) { return fileDescriptorData, []int{35} }
 
 func (m *QueryInfo) GetID() uint64 {
 	if m != nil && m.ID != nil {
@@ -998,7 +1367,7 @@ type ShowQueriesRequest struct {
 func (m *ShowQueriesRequest) Reset()                    { *m = ShowQueriesRequest{} }
 func (m *ShowQueriesRequest) String() string            { return proto.CompactTextString(m) }
 func (*ShowQueriesRequest) ProtoMessage()               {}
-func (*ShowQueriesRequest) Descriptor() ([]byte, []int) { return fileDescriptorData, []int{34} }
+func (*ShowQueriesRequest) Descriptor() ([]byte, []int) { return fileDescriptorData, []int{36} }
 
 type ShowQueriesResponse struct {
 	Queries          *string `protobuf:"bytes,1,req,name=Queries,json=queries" json:"Queries,omitempty"`
@@ -1009,7 +1378,7 @@ type ShowQueriesResponse struct {
 func (m *ShowQueriesResponse) Reset()                    { *m = ShowQueriesResponse{} }
 func (m *ShowQueriesResponse) String() string            { return proto.CompactTextString(m) }
 func (*ShowQueriesResponse) ProtoMessage()               {}
-func (*ShowQueriesResponse) Descriptor() ([]byte, []int) { return fileDescriptorData, []int{35} }
+func (*ShowQueriesResponse) Descriptor() ([]byte, []int) { return fileDescriptorData, []int{37} }
 
 func (m *ShowQueriesResponse) GetQueries() string {
 	if m != nil && m.Queries != nil {
@@ -1033,7 +1402,7 @@ type KillQueryRequest struct {
 func (m *KillQueryRequest) Reset()                    { *m = KillQueryRequest{} }
 func (m *KillQueryRequest) String() string            { return proto.CompactTextString(m) }
 func (*KillQueryRequest) ProtoMessage()               {}
-func (*KillQueryRequest) Descriptor() ([]byte, []int) { return fileDescriptorData, []int{36} }
+func (*KillQueryRequest) Descriptor() ([]byte, []int) { return fileDescriptorData, []int{38} }
 
 func (m *KillQueryRequest) GetID() uint64 {
 	if m != nil && m.ID != nil {
@@ -1050,7 +1419,7 @@ type KillQueryResponse struct {
 func (m *KillQueryResponse) Reset()                    { *m = KillQueryResponse{} }
 func (m *KillQueryResponse) String() string            { return proto.CompactTextString(m) }
 func (*KillQueryResponse) ProtoMessage()               {}
-func (*KillQueryResponse) Descriptor() ([]byte, []int) { return fileDescriptorData, []int{37} }
+func (*KillQueryResponse) Descriptor() ([]byte, []int) { return fileDescriptorData, []int{39} }
 
 func (m *KillQueryResponse) GetErr() string {
 	if m != nil && m.Err != nil {
@@ -1062,13 +1431,16 @@ func (m *KillQueryResponse) GetErr() string {
 type RestoreShardRequest struct {
 	ShardID          *uint64 `protobuf:"varint,1,req,name=ShardID,json=shardID" json:"ShardID,omitempty"`
 	Size_            *uint64 `protobuf:"varint,2,req,name=Size,json=size" json:"Size,omitempty"`
+	Database         *string `protobuf:"bytes,3,opt,name=Database,json=database" json:"Database,omitempty"`
+	Policy           *string `protobuf:"bytes,4,opt,name=Policy,json=policy" json:"Policy,omitempty"`
+	SourceShardID    *uint64 `protobuf:"varint,5,opt,name=SourceShardID,json=sourceShardID" json:"SourceShardID,omitempty"`
 	XXX_unrecognized []byte  `json:"-"`
 }
 
 func (m *RestoreShardRequest) Reset()                    { *m = RestoreShardRequest{} }
 func (m *RestoreShardRequest) String() string            { return proto.CompactTextString(m) }
 func (*RestoreShardRequest) ProtoMessage()               {}
-func (*RestoreShardRequest) Descriptor() ([]byte, []int) { return fileDescriptorData, []int{38} }
+func (*RestoreShardRequest) Descriptor() ([]byte, []int) { return fileDescriptorData, []int{40} }
 
 func (m *RestoreShardRequest) GetShardID() uint64 {
 	if m != nil && m.ShardID != nil {
@@ -1084,15 +1456,37 @@ func (m *RestoreShardRequest) GetSize_() uint64 {
 	return 0
 }
 
+func (m *RestoreShardRequest) GetDatabase() string {
+	if m != nil && m.Database != nil {
+		return *m.Database
+	}
+	return ""
+}
+
+func (m *RestoreShardRequest) GetPolicy() string {
+	if m != nil && m.Policy != nil {
+		return *m.Policy
+	}
+	return ""
+}
+
+func (m *RestoreShardRequest) GetSourceShardID() uint64 {
+	if m != nil && m.SourceShardID != nil {
+		return *m.SourceShardID
+	}
+	return 0
+}
+
 type RestoreShardResponse struct {
 	Err              *string `protobuf:"bytes,1,req,name=Err,json=err" json:"Err,omitempty"`
+	Code             *int32  `protobuf:"varint,2,opt,name=Code,json=code" json:"Code,omitempty"`
 	XXX_unrecognized []byte  `json:"-"`
 }
 
 func (m *RestoreShardResponse) Reset()                    { *m = RestoreShardResponse{} }
 func (m *RestoreShardResponse) String() string            { return proto.CompactTextString(m) }
 func (*RestoreShardResponse) ProtoMessage()               {}
-func (*RestoreShardResponse) Descriptor() ([]byte, []int) { return fileDescriptorData, []int{39} }
+func (*RestoreShardResponse) Descriptor() ([]byte, []int) { return fileDescriptorData, []int{41} }
 
 func (m *RestoreShardResponse) GetErr() string {
 	if m != nil && m.Err != nil {
@@ -1101,6 +1495,13 @@ func (m *RestoreShardResponse) GetErr() string {
 	return ""
 }
 
+func (m *RestoreShardResponse) GetCode() int32 {
+	if m != nil && m.Code != nil {
+		return *m.Code
+	}
+	return 0
+}
+
 type ShowMeasurementsRequest struct {
 	Err *string `protobuf:"bytes,1,req,name=Err,json=err" json:"Err,omitempty"`
 	// Not sure what should be used for Condition, use string for now
@@ -1111,7 +1512,7 @@ type ShowMeasurementsRequest struct {
 func (m *ShowMeasurementsRequest) Reset()                    { *m = ShowMeasurementsRequest{} }
 func (m *ShowMeasurementsRequest) String() string            { return proto.CompactTextString(m) }
 func (*ShowMeasurementsRequest) ProtoMessage()               {}
-func (*ShowMeasurementsRequest) Descriptor() ([]byte, []int) { return fileDescriptorData, []int{40} }
+func (*ShowMeasurementsRequest) Descriptor() ([]byte, []int) { return fileDescriptorData, []int{42} }
 
 func (m *ShowMeasurementsRequest) GetErr() string {
 	if m != nil && m.Err != nil {
@@ -1136,7 +1537,7 @@ type ShowMeasurementsResponse struct {
 func (m *ShowMeasurementsResponse) Reset()                    { *m = ShowMeasurementsResponse{} }
 func (m *ShowMeasurementsResponse) String() string            { return proto.CompactTextString(m) }
 func (*ShowMeasurementsResponse) ProtoMessage()               {}
-func (*ShowMeasurementsResponse) Descriptor() ([]byte, []int) { return fileDescriptorData, []int{41} }
+func (*ShowMeasurementsResponse) Descriptor() ([]byte, []int) { return fileDescriptorData, []int{43} }
 
 func (m *ShowMeasurementsResponse) GetMeasurements() string {
 	if m != nil && m.Measurements != nil {
@@ -1161,7 +1562,7 @@ type KeyValue struct {
 func (m *KeyValue) Reset()                    { *m = KeyValue{} }
 func (m *KeyValue) String() string            { return proto.CompactTextString(m) }
 func (*KeyValue) ProtoMessage()               {}
-func (*KeyValue) Descriptor() ([]byte, []int) { return fileDescriptorData, []int{42} }
+func (*KeyValue) Descriptor() ([]byte, []int) { return fileDescriptorData, []int{44} }
 
 func (m *KeyValue) GetKey() string {
 	if m != nil && m.Key != nil {
@@ -1186,7 +1587,7 @@ type TagValues struct {
 func (m *TagValues) Reset()                    { *m = TagValues{} }
 func (m *TagValues) String() string            { return proto.CompactTextString(m) }
 func (*TagValues) ProtoMessage()               {}
-func (*TagValues) Descriptor() ([]byte, []int) { return fileDescriptorData, []int{43} }
+func (*TagValues) Descriptor() ([]byte, []int) { return fileDescriptorData, []int{45} }
 
 func (m *TagValues) GetMeasurement() string {
 	if m != nil && m.Measurement != nil {
@@ -1211,7 +1612,7 @@ type ShowTagValuesRequest struct {
 func (m *ShowTagValuesRequest) Reset()                    { *m = ShowTagValuesRequest{} }
 func (m *ShowTagValuesRequest) String() string            { return proto.CompactTextString(m) }
 func (*ShowTagValuesRequest) ProtoMessage()               {}
-func (*ShowTagValuesRequest) Descriptor() ([]byte, []int) { return fileDescriptorData, []int{44} }
+func (*ShowTagValuesRequest) Descriptor() ([]byte, []int) { return fileDescriptorData, []int{46} }
 
 func (m *ShowTagValuesRequest) GetDatabase() string {
 	if m != nil && m.Database != nil {
@@ -1236,7 +1637,7 @@ type ShowTagValuesResponse struct {
 func (m *ShowTagValuesResponse) Reset()                    { *m = ShowTagValuesResponse{} }
 func (m *ShowTagValuesResponse) String() string            { return proto.CompactTextString(m) }
 func (*ShowTagValuesResponse) ProtoMessage()               {}
-func (*ShowTagValuesResponse) Descriptor() ([]byte, []int) { return fileDescriptorData, []int{45} }
+func (*ShowTagValuesResponse) Descriptor() ([]byte, []int) { return fileDescriptorData, []int{47} }
 
 func (m *ShowTagValuesResponse) GetValues() []byte {
 	if m != nil {
@@ -1252,6 +1653,1463 @@ func (m *ShowTagValuesResponse) GetErr() string {
 	return ""
 }
 
+type ShardInfo struct {
+	ID               *uint64  `protobuf:"varint,1,req,name=ID,json=iD" json:"ID,omitempty"`
+	Database         *string  `protobuf:"bytes,2,req,name=Database,json=database" json:"Database,omitempty"`
+	Policy           *string  `protobuf:"bytes,3,req,name=Policy,json=policy" json:"Policy,omitempty"`
+	ShardGroupID     *uint64  `protobuf:"varint,4,req,name=ShardGroupID,json=shardGroupID" json:"ShardGroupID,omitempty"`
+	StartTime        *int64   `protobuf:"varint,5,req,name=StartTime,json=startTime" json:"StartTime,omitempty"`
+	EndTime          *int64   `protobuf:"varint,6,req,name=EndTime,json=endTime" json:"EndTime,omitempty"`
+	Owners           []uint64 `protobuf:"varint,7,rep,name=Owners,json=owners" json:"Owners,omitempty"`
+	XXX_unrecognized []byte   `json:"-"`
+}
+
+func (m *ShardInfo) Reset()                    { *m = ShardInfo{} }
+func (m *ShardInfo) String() string            { return proto.CompactTextString(m) }
+func (*ShardInfo) ProtoMessage()               {}
+func (*ShardInfo) Descriptor() ([]byte, []int) { return fileDescriptorData, []int{48} }
+
+func (m *ShardInfo) GetID() uint64 {
+	if m != nil && m.ID != nil {
+		return *m.ID
+	}
+	return 0
+}
+
+func (m *ShardInfo) GetDatabase() string {
+	if m != nil && m.Database != nil {
+		return *m.Database
+	}
+	return ""
+}
+
+func (m *ShardInfo) GetPolicy() string {
+	if m != nil && m.Policy != nil {
+		return *m.Policy
+	}
+	return ""
+}
+
+func (m *ShardInfo) GetShardGroupID() uint64 {
+	if m != nil && m.ShardGroupID != nil {
+		return *m.ShardGroupID
+	}
+	return 0
+}
+
+func (m *ShardInfo) GetStartTime() int64 {
+	if m != nil && m.StartTime != nil {
+		return *m.StartTime
+	}
+	return 0
+}
+
+func (m *ShardInfo) GetEndTime() int64 {
+	if m != nil && m.EndTime != nil {
+		return *m.EndTime
+	}
+	return 0
+}
+
+func (m *ShardInfo) GetOwners() []uint64 {
+	if m != nil {
+		return m.Owners
+	}
+	return nil
+}
+
+type ShowShardsRequest struct {
+	XXX_unrecognized []byte `json:"-"`
+}
+
+func (m *ShowShardsRequest) Reset()                    { *m = ShowShardsRequest{} }
+func (m *ShowShardsRequest) String() string            { return proto.CompactTextString(m) }
+func (*ShowShardsRequest) ProtoMessage()               {}
+func (*ShowShardsRequest) Descriptor() ([]byte, []int) { return fileDescriptorData, []int{49} }
+
+type ShowShardsResponse struct {
+	Shards           []*ShardInfo `protobuf:"bytes,1,rep,name=Shards,json=shards" json:"Shards,omitempty"`
+	Err              *string      `protobuf:"bytes,2,req,name=Err,json=err" json:"Err,omitempty"`
+	XXX_unrecognized []byte       `json:"-"`
+}
+
+func (m *ShowShardsResponse) Reset()                    { *m = ShowShardsResponse{} }
+func (m *ShowShardsResponse) String() string            { return proto.CompactTextString(m) }
+func (*ShowShardsResponse) ProtoMessage()               {}
+func (*ShowShardsResponse) Descriptor() ([]byte, []int) { return fileDescriptorData, []int{50} }
+
+func (m *ShowShardsResponse) GetShards() []*ShardInfo {
+	if m != nil {
+		return m.Shards
+	}
+	return nil
+}
+
+func (m *ShowShardsResponse) GetErr() string {
+	if m != nil && m.Err != nil {
+		return *m.Err
+	}
+	return ""
+}
+
+type BackupShardRequest struct {
+	ShardID          *uint64 `protobuf:"varint,1,req,name=ShardID,json=shardID" json:"ShardID,omitempty"`
+	Since            *int64  `protobuf:"varint,2,req,name=Since,json=since" json:"Since,omitempty"`
+	BaseSnapshotID   *uint64 `protobuf:"varint,3,opt,name=BaseSnapshotID,json=baseSnapshotID" json:"BaseSnapshotID,omitempty"`
+	Checksums        *bool   `protobuf:"varint,4,opt,name=Checksums,json=checksums" json:"Checksums,omitempty"`
+	XXX_unrecognized []byte  `json:"-"`
+}
+
+func (m *BackupShardRequest) Reset()                    { *m = BackupShardRequest{} }
+func (m *BackupShardRequest) String() string            { return proto.CompactTextString(m) }
+func (*BackupShardRequest) ProtoMessage()               {}
+func (*BackupShardRequest) Descriptor() ([]byte, []int) { return fileDescriptorData, []int{51} }
+
+func (m *BackupShardRequest) GetShardID() uint64 {
+	if m != nil && m.ShardID != nil {
+		return *m.ShardID
+	}
+	return 0
+}
+
+func (m *BackupShardRequest) GetSince() int64 {
+	if m != nil && m.Since != nil {
+		return *m.Since
+	}
+	return 0
+}
+
+func (m *BackupShardRequest) GetBaseSnapshotID() uint64 {
+	if m != nil && m.BaseSnapshotID != nil {
+		return *m.BaseSnapshotID
+	}
+	return 0
+}
+
+func (m *BackupShardRequest) GetChecksums() bool {
+	if m != nil && m.Checksums != nil {
+		return *m.Checksums
+	}
+	return false
+}
+
+type BackupShardResponse struct {
+	Err              *string `protobuf:"bytes,1,req,name=Err,json=err" json:"Err,omitempty"`
+	SnapshotID       *uint64 `protobuf:"varint,2,opt,name=SnapshotID,json=snapshotID" json:"SnapshotID,omitempty"`
+	BaseSnapshotID   *uint64 `protobuf:"varint,3,opt,name=BaseSnapshotID,json=baseSnapshotID" json:"BaseSnapshotID,omitempty"`
+	XXX_unrecognized []byte  `json:"-"`
+}
+
+func (m *BackupShardResponse) Reset()                    { *m = BackupShardResponse{} }
+func (m *BackupShardResponse) String() string            { return proto.CompactTextString(m) }
+func (*BackupShardResponse) ProtoMessage()               {}
+func (*BackupShardResponse) Descriptor() ([]byte, []int) { return fileDescriptorData, []int{52} }
+
+func (m *BackupShardResponse) GetErr() string {
+	if m != nil && m.Err != nil {
+		return *m.Err
+	}
+	return ""
+}
+
+func (m *BackupShardResponse) GetSnapshotID() uint64 {
+	if m != nil && m.SnapshotID != nil {
+		return *m.SnapshotID
+	}
+	return 0
+}
+
+func (m *BackupShardResponse) GetBaseSnapshotID() uint64 {
+	if m != nil && m.BaseSnapshotID != nil {
+		return *m.BaseSnapshotID
+	}
+	return 0
+}
+
+type TruncateShardsRequest struct {
+	Delay            *int64 `protobuf:"varint,1,req,name=Delay,json=delay" json:"Delay,omitempty"`
+	Time             *int64 `protobuf:"varint,2,opt,name=Time,json=time" json:"Time,omitempty"`
+	XXX_unrecognized []byte `json:"-"`
+}
+
+func (m *TruncateShardsRequest) Reset()                    { *m = TruncateShardsRequest{} }
+func (m *TruncateShardsRequest) String() string            { return proto.CompactTextString(m) }
+func (*TruncateShardsRequest) ProtoMessage()               {}
+func (*TruncateShardsRequest) Descriptor() ([]byte, []int) { return fileDescriptorData, []int{53} }
+
+func (m *TruncateShardsRequest) GetDelay() int64 {
+	if m != nil && m.Delay != nil {
+		return *m.Delay
+	}
+	return 0
+}
+
+func (m *TruncateShardsRequest) GetTime() int64 {
+	if m != nil && m.Time != nil {
+		return *m.Time
+	}
+	return 0
+}
+
+type TruncateShardsResponse struct {
+	Err              *string `protobuf:"bytes,1,req,name=Err,json=err" json:"Err,omitempty"`
+	XXX_unrecognized []byte  `json:"-"`
+}
+
+func (m *TruncateShardsResponse) Reset()                    { *m = TruncateShardsResponse{} }
+func (m *TruncateShardsResponse) String() string            { return proto.CompactTextString(m) }
+func (*TruncateShardsResponse) ProtoMessage()               {}
+func (*TruncateShardsResponse) Descriptor() ([]byte, []int) { return fileDescriptorData, []int{54} }
+
+func (m *TruncateShardsResponse) GetErr() string {
+	if m != nil && m.Err != nil {
+		return *m.Err
+	}
+	return ""
+}
+
+type RemoveDataNodeRequest struct {
+	TCPHost          *string `protobuf:"bytes,1,req,name=TCPHost,json=tCPHost" json:"TCPHost,omitempty"`
+	Force            *bool   `protobuf:"varint,2,req,name=Force,json=force" json:"Force,omitempty"`
+	XXX_unrecognized []byte  `json:"-"`
+}
+
+func (m *RemoveDataNodeRequest) Reset()                    { *m = RemoveDataNodeRequest{} }
+func (m *RemoveDataNodeRequest) String() string            { return proto.CompactTextString(m) }
+func (*RemoveDataNodeRequest) ProtoMessage()               {}
+func (*RemoveDataNodeRequest) Descriptor() ([]byte, []int) { return fileDescriptorData, []int{55} }
+
+func (m *RemoveDataNodeRequest) GetTCPHost() string {
+	if m != nil && m.TCPHost != nil {
+		return *m.TCPHost
+	}
+	return ""
+}
+
+func (m *RemoveDataNodeRequest) GetForce() bool {
+	if m != nil && m.Force != nil {
+		return *m.Force
+	}
+	return false
+}
+
+type RemoveDataNodeResponse struct {
+	Err              *string `protobuf:"bytes,1,req,name=Err,json=err" json:"Err,omitempty"`
+	XXX_unrecognized []byte  `json:"-"`
+}
+
+func (m *RemoveDataNodeResponse) Reset()                    { *m = RemoveDataNodeResponse{} }
+func (m *RemoveDataNodeResponse) String() string            { return proto.CompactTextString(m) }
+func (*RemoveDataNodeResponse) ProtoMessage()               {}
+func (*RemoveDataNodeResponse) Descriptor() ([]byte, []int) { return fileDescriptorData, []int{56} }
+
+func (m *RemoveDataNodeResponse) GetErr() string {
+	if m != nil && m.Err != nil {
+		return *m.Err
+	}
+	return ""
+}
+
+type UpdateDataNodeRequest struct {
+	OldTCPHost       *string `protobuf:"bytes,1,req,name=OldTCPHost,json=oldTCPHost" json:"OldTCPHost,omitempty"`
+	NewTCPHost       *string `protobuf:"bytes,2,req,name=NewTCPHost,json=newTCPHost" json:"NewTCPHost,omitempty"`
+	XXX_unrecognized []byte  `json:"-"`
+}
+
+func (m *UpdateDataNodeRequest) Reset()                    { *m = UpdateDataNodeRequest{} }
+func (m *UpdateDataNodeRequest) String() string            { return proto.CompactTextString(m) }
+func (*UpdateDataNodeRequest) ProtoMessage()               {}
+func (*UpdateDataNodeRequest) Descriptor() ([]byte, []int) { return fileDescriptorData, []int{57} }
+
+func (m *UpdateDataNodeRequest) GetOldTCPHost() string {
+	if m != nil && m.OldTCPHost != nil {
+		return *m.OldTCPHost
+	}
+	return ""
+}
+
+func (m *UpdateDataNodeRequest) GetNewTCPHost() string {
+	if m != nil && m.NewTCPHost != nil {
+		return *m.NewTCPHost
+	}
+	return ""
+}
+
+type UpdateDataNodeResponse struct {
+	Err              *string `protobuf:"bytes,1,req,name=Err,json=err" json:"Err,omitempty"`
+	XXX_unrecognized []byte  `json:"-"`
+}
+
+func (m *UpdateDataNodeResponse) Reset()                    { *m = UpdateDataNodeResponse{} }
+func (m *UpdateDataNodeResponse) String() string            { return proto.CompactTextString(m) }
+func (*UpdateDataNodeResponse) ProtoMessage()               {}
+func (*UpdateDataNodeResponse) Descriptor() ([]byte, []int) { return fileDescriptorData, []int{58} }
+
+func (m *UpdateDataNodeResponse) GetErr() string {
+	if m != nil && m.Err != nil {
+		return *m.Err
+	}
+	return ""
+}
+
+type AuthStateRequest struct {
+	XXX_unrecognized []byte `json:"-"`
+}
+
+func (m *AuthStateRequest) Reset()                    { *m = AuthStateRequest{} }
+func (m *AuthStateRequest) String() string            { return proto.CompactTextString(m) }
+func (*AuthStateRequest) ProtoMessage()               {}
+func (*AuthStateRequest) Descriptor() ([]byte, []int) { return fileDescriptorData, []int{59} }
+
+type AuthStateResponse struct {
+	Hash             *string `protobuf:"bytes,1,req,name=Hash,json=hash" json:"Hash,omitempty"`
+	UserN            *uint64 `protobuf:"varint,2,req,name=UserN,json=userN" json:"UserN,omitempty"`
+	Err              *string `protobuf:"bytes,3,req,name=Err,json=err" json:"Err,omitempty"`
+	XXX_unrecognized []byte  `json:"-"`
+}
+
+func (m *AuthStateResponse) Reset()                    { *m = AuthStateResponse{} }
+func (m *AuthStateResponse) String() string            { return proto.CompactTextString(m) }
+func (*AuthStateResponse) ProtoMessage()               {}
+func (*AuthStateResponse) Descriptor() ([]byte, []int) { return fileDescriptorData, []int{60} }
+
+func (m *AuthStateResponse) GetHash() string {
+	if m != nil && m.Hash != nil {
+		return *m.Hash
+	}
+	return ""
+}
+
+func (m *AuthStateResponse) GetUserN() uint64 {
+	if m != nil && m.UserN != nil {
+		return *m.UserN
+	}
+	return 0
+}
+
+func (m *AuthStateResponse) GetErr() string {
+	if m != nil && m.Err != nil {
+		return *m.Err
+	}
+	return ""
+}
+
+type PauseReplicationRequest struct {
+	TCPHost          *string `protobuf:"bytes,1,req,name=TCPHost,json=tCPHost" json:"TCPHost,omitempty"`
+	Resume           *bool   `protobuf:"varint,2,req,name=Resume,json=resume" json:"Resume,omitempty"`
+	XXX_unrecognized []byte  `json:"-"`
+}
+
+func (m *PauseReplicationRequest) Reset()                    { *m = PauseReplicationRequest{} }
+func (m *PauseReplicationRequest) String() string            { return proto.CompactTextString(m) }
+func (*PauseReplicationRequest) ProtoMessage()               {}
+func (*PauseReplicationRequest) Descriptor() ([]byte, []int) { return fileDescriptorData, []int{61} }
+
+func (m *PauseReplicationRequest) GetTCPHost() string {
+	if m != nil && m.TCPHost != nil {
+		return *m.TCPHost
+	}
+	return ""
+}
+
+func (m *PauseReplicationRequest) GetResume() bool {
+	if m != nil && m.Resume != nil {
+		return *m.Resume
+	}
+	return false
+}
+
+type PauseReplicationResponse struct {
+	Err              *string `protobuf:"bytes,1,req,name=Err,json=err" json:"Err,omitempty"`
+	XXX_unrecognized []byte  `json:"-"`
+}
+
+func (m *PauseReplicationResponse) Reset()                    { *m = PauseReplicationResponse{} }
+func (m *PauseReplicationResponse) String() string            { return proto.CompactTextString(m) }
+func (*PauseReplicationResponse) ProtoMessage()               {}
+func (*PauseReplicationResponse) Descriptor() ([]byte, []int) { return fileDescriptorData, []int{62} }
+
+func (m *PauseReplicationResponse) GetErr() string {
+	if m != nil && m.Err != nil {
+		return *m.Err
+	}
+	return ""
+}
+
+type ExportMetaDataRequest struct {
+	XXX_unrecognized []byte `json:"-"`
+}
+
+func (m *ExportMetaDataRequest) Reset()                    { *m = ExportMetaDataRequest{} }
+func (m *ExportMetaDataRequest) String() string            { return proto.CompactTextString(m) }
+func (*ExportMetaDataRequest) ProtoMessage()               {}
+func (*ExportMetaDataRequest) Descriptor() ([]byte, []int) { return fileDescriptorData, []int{63} }
+
+type ExportMetaDataResponse struct {
+	Data             []byte  `protobuf:"bytes,1,req,name=Data,json=data" json:"Data,omitempty"`
+	ClusterID        *uint64 `protobuf:"varint,2,req,name=ClusterID,json=clusterID" json:"ClusterID,omitempty"`
+	Index            *uint64 `protobuf:"varint,3,req,name=Index,json=index" json:"Index,omitempty"`
+	Err              *string `protobuf:"bytes,4,req,name=Err,json=err" json:"Err,omitempty"`
+	XXX_unrecognized []byte  `json:"-"`
+}
+
+func (m *ExportMetaDataResponse) Reset()                    { *m = ExportMetaDataResponse{} }
+func (m *ExportMetaDataResponse) String() string            { return proto.CompactTextString(m) }
+func (*ExportMetaDataResponse) ProtoMessage()               {}
+func (*ExportMetaDataResponse) Descriptor() ([]byte, []int) { return fileDescriptorData, []int{64} }
+
+func (m *ExportMetaDataResponse) GetData() []byte {
+	if m != nil {
+		return m.Data
+	}
+	return nil
+}
+
+func (m *ExportMetaDataResponse) GetClusterID() uint64 {
+	if m != nil && m.ClusterID != nil {
+		return *m.ClusterID
+	}
+	return 0
+}
+
+func (m *ExportMetaDataResponse) GetIndex() uint64 {
+	if m != nil && m.Index != nil {
+		return *m.Index
+	}
+	return 0
+}
+
+func (m *ExportMetaDataResponse) GetErr() string {
+	if m != nil && m.Err != nil {
+		return *m.Err
+	}
+	return ""
+}
+
+type SpanContext struct {
+	Entries          []*SpanContextEntry `protobuf:"bytes,1,rep,name=Entries,json=entries" json:"Entries,omitempty"`
+	XXX_unrecognized []byte              `json:"-"`
+}
+
+func (m *SpanContext) Reset()                    { *m = SpanContext{} }
+func (m *SpanContext) String() string            { return proto.CompactTextString(m) }
+func (*SpanContext) ProtoMessage()               {}
+func (*SpanContext) Descriptor() ([]byte, []int) { return fileDescriptorData, []int{65} }
+
+func (m *SpanContext) GetEntries() []*SpanContextEntry {
+	if m != nil {
+		return m.Entries
+	}
+	return nil
+}
+
+type SpanContextEntry struct {
+	Key              *string `protobuf:"bytes,1,req,name=Key,json=key" json:"Key,omitempty"`
+	Value            *string `protobuf:"bytes,2,req,name=Value,json=value" json:"Value,omitempty"`
+	XXX_unrecognized []byte  `json:"-"`
+}
+
+func (m *SpanContextEntry) Reset()                    { *m = SpanContextEntry{} }
+func (m *SpanContextEntry) String() string            { return proto.CompactTextString(m) }
+func (*SpanContextEntry) ProtoMessage()               {}
+func (*SpanContextEntry) Descriptor() ([]byte, []int) { return fileDescriptorData, []int{66} }
+
+func (m *SpanContextEntry) GetKey() string {
+	if m != nil && m.Key != nil {
+		return *m.Key
+	}
+	return ""
+}
+
+func (m *SpanContextEntry) GetValue() string {
+	if m != nil && m.Value != nil {
+		return *m.Value
+	}
+	return ""
+}
+
+type WritePointsRequest struct {
+	Database         *string  `protobuf:"bytes,1,req,name=Database,json=database" json:"Database,omitempty"`
+	RetentionPolicy  *string  `protobuf:"bytes,2,req,name=RetentionPolicy,json=retentionPolicy" json:"RetentionPolicy,omitempty"`
+	ConsistencyLevel *int32   `protobuf:"varint,3,req,name=ConsistencyLevel,json=consistencyLevel" json:"ConsistencyLevel,omitempty"`
+	RequestID        *string  `protobuf:"bytes,4,req,name=RequestID,json=requestID" json:"RequestID,omitempty"`
+	Timeout          *int64   `protobuf:"varint,5,req,name=Timeout,json=timeout" json:"Timeout,omitempty"`
+	Points           [][]byte `protobuf:"bytes,6,rep,name=Points,json=points" json:"Points,omitempty"`
+	XXX_unrecognized []byte   `json:"-"`
+}
+
+func (m *WritePointsRequest) Reset()                    { *m = WritePointsRequest{} }
+func (m *WritePointsRequest) String() string            { return proto.CompactTextString(m) }
+func (*WritePointsRequest) ProtoMessage()               {}
+func (*WritePointsRequest) Descriptor() ([]byte, []int) { return fileDescriptorData, []int{67} }
+
+func (m *WritePointsRequest) GetDatabase() string {
+	if m != nil && m.Database != nil {
+		return *m.Database
+	}
+	return ""
+}
+
+func (m *WritePointsRequest) GetRetentionPolicy() string {
+	if m != nil && m.RetentionPolicy != nil {
+		return *m.RetentionPolicy
+	}
+	return ""
+}
+
+func (m *WritePointsRequest) GetConsistencyLevel() int32 {
+	if m != nil && m.ConsistencyLevel != nil {
+		return *m.ConsistencyLevel
+	}
+	return 0
+}
+
+func (m *WritePointsRequest) GetRequestID() string {
+	if m != nil && m.RequestID != nil {
+		return *m.RequestID
+	}
+	return ""
+}
+
+func (m *WritePointsRequest) GetTimeout() int64 {
+	if m != nil && m.Timeout != nil {
+		return *m.Timeout
+	}
+	return 0
+}
+
+func (m *WritePointsRequest) GetPoints() [][]byte {
+	if m != nil {
+		return m.Points
+	}
+	return nil
+}
+
+type WritePointsResponse struct {
+	Code             *int32  `protobuf:"varint,1,req,name=Code,json=code" json:"Code,omitempty"`
+	Err              *string `protobuf:"bytes,2,req,name=Err,json=err" json:"Err,omitempty"`
+	XXX_unrecognized []byte  `json:"-"`
+}
+
+func (m *WritePointsResponse) Reset()                    { *m = WritePointsResponse{} }
+func (m *WritePointsResponse) String() string            { return proto.CompactTextString(m) }
+func (*WritePointsResponse) ProtoMessage()               {}
+func (*WritePointsResponse) Descriptor() ([]byte, []int) { return fileDescriptorData, []int{68} }
+
+func (m *WritePointsResponse) GetCode() int32 {
+	if m != nil && m.Code != nil {
+		return *m.Code
+	}
+	return 0
+}
+
+func (m *WritePointsResponse) GetErr() string {
+	if m != nil && m.Err != nil {
+		return *m.Err
+	}
+	return ""
+}
+
+type ShardBoundsRequest struct {
+	ShardIDs         []uint64 `protobuf:"varint,1,rep,name=ShardIDs,json=shardIDs" json:"ShardIDs,omitempty"`
+	Measurements     []string `protobuf:"bytes,2,rep,name=Measurements,json=measurements" json:"Measurements,omitempty"`
+	XXX_unrecognized []byte   `json:"-"`
+}
+
+func (m *ShardBoundsRequest) Reset()                    { *m = ShardBoundsRequest{} }
+func (m *ShardBoundsRequest) String() string            { return proto.CompactTextString(m) }
+func (*ShardBoundsRequest) ProtoMessage()               {}
+func (*ShardBoundsRequest) Descriptor() ([]byte, []int) { return fileDescriptorData, []int{69} }
+
+func (m *ShardBoundsRequest) GetShardIDs() []uint64 {
+	if m != nil {
+		return m.ShardIDs
+	}
+	return nil
+}
+
+func (m *ShardBoundsRequest) GetMeasurements() []string {
+	if m != nil {
+		return m.Measurements
+	}
+	return nil
+}
+
+type ShardBoundsResponse struct {
+	Err              *string        `protobuf:"bytes,1,req,name=Err,json=err" json:"Err,omitempty"`
+	Shards           []*ShardBounds `protobuf:"bytes,2,rep,name=Shards,json=shards" json:"Shards,omitempty"`
+	XXX_unrecognized []byte         `json:"-"`
+}
+
+func (m *ShardBoundsResponse) Reset()                    { *m = ShardBoundsResponse{} }
+func (m *ShardBoundsResponse) String() string            { return proto.CompactTextString(m) }
+func (*ShardBoundsResponse) ProtoMessage()               {}
+func (*ShardBoundsResponse) Descriptor() ([]byte, []int) { return fileDescriptorData, []int{70} }
+
+func (m *ShardBoundsResponse) GetErr() string {
+	if m != nil && m.Err != nil {
+		return *m.Err
+	}
+	return ""
+}
+
+func (m *ShardBoundsResponse) GetShards() []*ShardBounds {
+	if m != nil {
+		return m.Shards
+	}
+	return nil
+}
+
+type ShardBounds struct {
+	ID               *uint64              `protobuf:"varint,1,req,name=ID,json=iD" json:"ID,omitempty"`
+	Measurements     []*MeasurementBounds `protobuf:"bytes,2,rep,name=Measurements,json=measurements" json:"Measurements,omitempty"`
+	XXX_unrecognized []byte               `json:"-"`
+}
+
+func (m *ShardBounds) Reset()                    { *m = ShardBounds{} }
+func (m *ShardBounds) String() string            { return proto.CompactTextString(m) }
+func (*ShardBounds) ProtoMessage()               {}
+func (*ShardBounds) Descriptor() ([]byte, []int) { return fileDescriptorData, []int{71} }
+
+func (m *ShardBounds) GetID() uint64 {
+	if m != nil && m.ID != nil {
+		return *m.ID
+	}
+	return 0
+}
+
+func (m *ShardBounds) GetMeasurements() []*MeasurementBounds {
+	if m != nil {
+		return m.Measurements
+	}
+	return nil
+}
+
+type MeasurementBounds struct {
+	Name             *string `protobuf:"bytes,1,req,name=Name,json=name" json:"Name,omitempty"`
+	MinTime          *int64  `protobuf:"varint,2,req,name=MinTime,json=minTime" json:"MinTime,omitempty"`
+	MaxTime          *int64  `protobuf:"varint,3,req,name=MaxTime,json=maxTime" json:"MaxTime,omitempty"`
+	XXX_unrecognized []byte  `json:"-"`
+}
+
+func (m *MeasurementBounds) Reset()                    { *m = MeasurementBounds{} }
+func (m *MeasurementBounds) String() string            { return proto.CompactTextString(m) }
+func (*MeasurementBounds) ProtoMessage()               {}
+func (*MeasurementBounds) Descriptor() ([]byte, []int) { return fileDescriptorData, []int{72} }
+
+func (m *MeasurementBounds) GetName() string {
+	if m != nil && m.Name != nil {
+		return *m.Name
+	}
+	return ""
+}
+
+func (m *MeasurementBounds) GetMinTime() int64 {
+	if m != nil && m.MinTime != nil {
+		return *m.MinTime
+	}
+	return 0
+}
+
+func (m *MeasurementBounds) GetMaxTime() int64 {
+	if m != nil && m.MaxTime != nil {
+		return *m.MaxTime
+	}
+	return 0
+}
+
+type IteratorEnd struct {
+	Err              *string `protobuf:"bytes,1,opt,name=Err,json=err" json:"Err,omitempty"`
+	Limit            *string `protobuf:"bytes,2,opt,name=Limit,json=limit" json:"Limit,omitempty"`
+	LimitMax         *int64  `protobuf:"varint,3,opt,name=LimitMax,json=limitMax" json:"LimitMax,omitempty"`
+	XXX_unrecognized []byte  `json:"-"`
+}
+
+func (m *IteratorEnd) Reset()                    { *m = IteratorEnd{} }
+func (m *IteratorEnd) String() string            { return proto.CompactTextString(m) }
+func (*IteratorEnd) ProtoMessage()               {}
+func (*IteratorEnd) Descriptor() ([]byte, []int) { return fileDescriptorData, []int{73} }
+
+func (m *IteratorEnd) GetErr() string {
+	if m != nil && m.Err != nil {
+		return *m.Err
+	}
+	return ""
+}
+
+func (m *IteratorEnd) GetLimit() string {
+	if m != nil && m.Limit != nil {
+		return *m.Limit
+	}
+	return ""
+}
+
+func (m *IteratorEnd) GetLimitMax() int64 {
+	if m != nil && m.LimitMax != nil {
+		return *m.LimitMax
+	}
+	return 0
+}
+
+type AcquireLeaseRequest struct {
+	Name             *string `protobuf:"bytes,1,req,name=Name,json=name" json:"Name,omitempty"`
+	NodeID           *uint64 `protobuf:"varint,2,req,name=NodeID,json=nodeID" json:"NodeID,omitempty"`
+	XXX_unrecognized []byte  `json:"-"`
+}
+
+func (m *AcquireLeaseRequest) Reset()                    { *m = AcquireLeaseRequest{} }
+func (m *AcquireLeaseRequest) String() string            { return proto.CompactTextString(m) }
+func (*AcquireLeaseRequest) ProtoMessage()               {}
+func (*AcquireLeaseRequest) Descriptor() ([]byte, []int) { return fileDescriptorData, []int{74} }
+
+func (m *AcquireLeaseRequest) GetName() string {
+	if m != nil && m.Name != nil {
+		return *m.Name
+	}
+	return ""
+}
+
+func (m *AcquireLeaseRequest) GetNodeID() uint64 {
+	if m != nil && m.NodeID != nil {
+		return *m.NodeID
+	}
+	return 0
+}
+
+type AcquireLeaseResponse struct {
+	Err              *string `protobuf:"bytes,1,req,name=Err,json=err" json:"Err,omitempty"`
+	Owner            *uint64 `protobuf:"varint,2,opt,name=Owner,json=owner" json:"Owner,omitempty"`
+	Expiration       *int64  `protobuf:"varint,3,opt,name=Expiration,json=expiration" json:"Expiration,omitempty"`
+	XXX_unrecognized []byte  `json:"-"`
+}
+
+func (m *AcquireLeaseResponse) Reset()                    { *m = AcquireLeaseResponse{} }
+func (m *AcquireLeaseResponse) String() string            { return proto.CompactTextString(m) }
+func (*AcquireLeaseResponse) ProtoMessage()               {}
+func (*AcquireLeaseResponse) Descriptor() ([]byte, []int) { return fileDescriptorData, []int{75} }
+
+func (m *AcquireLeaseResponse) GetErr() string {
+	if m != nil && m.Err != nil {
+		return *m.Err
+	}
+	return ""
+}
+
+func (m *AcquireLeaseResponse) GetOwner() uint64 {
+	if m != nil && m.Owner != nil {
+		return *m.Owner
+	}
+	return 0
+}
+
+func (m *AcquireLeaseResponse) GetExpiration() int64 {
+	if m != nil && m.Expiration != nil {
+		return *m.Expiration
+	}
+	return 0
+}
+
+type UploadShardSnapshotRequest struct {
+	ShardID          *uint64 `protobuf:"varint,1,req,name=ShardID,json=shardID" json:"ShardID,omitempty"`
+	Key              *string `protobuf:"bytes,2,req,name=Key,json=key" json:"Key,omitempty"`
+	XXX_unrecognized []byte  `json:"-"`
+}
+
+func (m *UploadShardSnapshotRequest) Reset()                    { *m = UploadShardSnapshotRequest{} }
+func (m *UploadShardSnapshotRequest) String() string            { return proto.CompactTextString(m) }
+func (*UploadShardSnapshotRequest) ProtoMessage()               {}
+func (*UploadShardSnapshotRequest) Descriptor() ([]byte, []int) { return fileDescriptorData, []int{76} }
+
+func (m *UploadShardSnapshotRequest) GetShardID() uint64 {
+	if m != nil && m.ShardID != nil {
+		return *m.ShardID
+	}
+	return 0
+}
+
+func (m *UploadShardSnapshotRequest) GetKey() string {
+	if m != nil && m.Key != nil {
+		return *m.Key
+	}
+	return ""
+}
+
+type UploadShardSnapshotResponse struct {
+	Err              *string `protobuf:"bytes,1,req,name=Err,json=err" json:"Err,omitempty"`
+	Size_            *int64  `protobuf:"varint,2,opt,name=Size,json=size" json:"Size,omitempty"`
+	XXX_unrecognized []byte  `json:"-"`
+}
+
+func (m *UploadShardSnapshotResponse) Reset()         { *m = UploadShardSnapshotResponse{} }
+func (m *UploadShardSnapshotResponse) String() string { return proto.CompactTextString(m) }
+func (*UploadShardSnapshotResponse) ProtoMessage()    {}
+func (*UploadShardSnapshotResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptorData, []int{77}
+}
+
+func (m *UploadShardSnapshotResponse) GetErr() string {
+	if m != nil && m.Err != nil {
+		return *m.Err
+	}
+	return ""
+}
+
+func (m *UploadShardSnapshotResponse) GetSize_() int64 {
+	if m != nil && m.Size_ != nil {
+		return *m.Size_
+	}
+	return 0
+}
+
+type ReplaceDataNodeRequest struct {
+	OldTCPHost       *string `protobuf:"bytes,1,req,name=OldTCPHost,json=oldTCPHost" json:"OldTCPHost,omitempty"`
+	NewTCPHost       *string `protobuf:"bytes,2,req,name=NewTCPHost,json=newTCPHost" json:"NewTCPHost,omitempty"`
+	XXX_unrecognized []byte  `json:"-"`
+}
+
+func (m *ReplaceDataNodeRequest) Reset()                    { *m = ReplaceDataNodeRequest{} }
+func (m *ReplaceDataNodeRequest) String() string            { return proto.CompactTextString(m) }
+func (*ReplaceDataNodeRequest) ProtoMessage()               {}
+func (*ReplaceDataNodeRequest) Descriptor() ([]byte, []int) { return fileDescriptorData, []int{78} }
+
+func (m *ReplaceDataNodeRequest) GetOldTCPHost() string {
+	if m != nil && m.OldTCPHost != nil {
+		return *m.OldTCPHost
+	}
+	return ""
+}
+
+func (m *ReplaceDataNodeRequest) GetNewTCPHost() string {
+	if m != nil && m.NewTCPHost != nil {
+		return *m.NewTCPHost
+	}
+	return ""
+}
+
+type ReplaceDataNodeResponse struct {
+	Err              *string  `protobuf:"bytes,1,req,name=Err,json=err" json:"Err,omitempty"`
+	ShardIDs         []uint64 `protobuf:"varint,2,rep,name=ShardIDs,json=shardIDs" json:"ShardIDs,omitempty"`
+	XXX_unrecognized []byte   `json:"-"`
+}
+
+func (m *ReplaceDataNodeResponse) Reset()                    { *m = ReplaceDataNodeResponse{} }
+func (m *ReplaceDataNodeResponse) String() string            { return proto.CompactTextString(m) }
+func (*ReplaceDataNodeResponse) ProtoMessage()               {}
+func (*ReplaceDataNodeResponse) Descriptor() ([]byte, []int) { return fileDescriptorData, []int{79} }
+
+func (m *ReplaceDataNodeResponse) GetErr() string {
+	if m != nil && m.Err != nil {
+		return *m.Err
+	}
+	return ""
+}
+
+func (m *ReplaceDataNodeResponse) GetShardIDs() []uint64 {
+	if m != nil {
+		return m.ShardIDs
+	}
+	return nil
+}
+
+type RedirectHintedHandoffRequest struct {
+	FromNodeID       *uint64 `protobuf:"varint,1,req,name=FromNodeID,json=fromNodeID" json:"FromNodeID,omitempty"`
+	ToNodeID         *uint64 `protobuf:"varint,2,req,name=ToNodeID,json=toNodeID" json:"ToNodeID,omitempty"`
+	XXX_unrecognized []byte  `json:"-"`
+}
+
+func (m *RedirectHintedHandoffRequest) Reset()         { *m = RedirectHintedHandoffRequest{} }
+func (m *RedirectHintedHandoffRequest) String() string { return proto.CompactTextString(m) }
+func (*RedirectHintedHandoffRequest) ProtoMessage()    {}
+func (*RedirectHintedHandoffRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptorData, []int{80}
+}
+
+func (m *RedirectHintedHandoffRequest) GetFromNodeID() uint64 {
+	if m != nil && m.FromNodeID != nil {
+		return *m.FromNodeID
+	}
+	return 0
+}
+
+func (m *RedirectHintedHandoffRequest) GetToNodeID() uint64 {
+	if m != nil && m.ToNodeID != nil {
+		return *m.ToNodeID
+	}
+	return 0
+}
+
+type RedirectHintedHandoffResponse struct {
+	Err              *string `protobuf:"bytes,1,req,name=Err,json=err" json:"Err,omitempty"`
+	XXX_unrecognized []byte  `json:"-"`
+}
+
+func (m *RedirectHintedHandoffResponse) Reset()         { *m = RedirectHintedHandoffResponse{} }
+func (m *RedirectHintedHandoffResponse) String() string { return proto.CompactTextString(m) }
+func (*RedirectHintedHandoffResponse) ProtoMessage()    {}
+func (*RedirectHintedHandoffResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptorData, []int{81}
+}
+
+func (m *RedirectHintedHandoffResponse) GetErr() string {
+	if m != nil && m.Err != nil {
+		return *m.Err
+	}
+	return ""
+}
+
+type HelloRequest struct {
+	NodeID           *uint64  `protobuf:"varint,1,req,name=NodeID,json=nodeID" json:"NodeID,omitempty"`
+	Version          *string  `protobuf:"bytes,2,req,name=Version,json=version" json:"Version,omitempty"`
+	ProtocolVersion  *uint32  `protobuf:"varint,3,req,name=ProtocolVersion,json=protocolVersion" json:"ProtocolVersion,omitempty"`
+	Features         *uint64  `protobuf:"varint,4,req,name=Features,json=features" json:"Features,omitempty"`
+	PointCodecs      []uint32 `protobuf:"varint,5,rep,name=PointCodecs,json=pointCodecs" json:"PointCodecs,omitempty"`
+	ClusterID        *uint64  `protobuf:"varint,6,opt,name=ClusterID,json=clusterID" json:"ClusterID,omitempty"`
+	XXX_unrecognized []byte   `json:"-"`
+}
+
+func (m *HelloRequest) Reset()                    { *m = HelloRequest{} }
+func (m *HelloRequest) String() string            { return proto.CompactTextString(m) }
+func (*HelloRequest) ProtoMessage()               {}
+func (*HelloRequest) Descriptor() ([]byte, []int) { return fileDescriptorData, []int{82} }
+
+func (m *HelloRequest) GetNodeID() uint64 {
+	if m != nil && m.NodeID != nil {
+		return *m.NodeID
+	}
+	return 0
+}
+
+func (m *HelloRequest) GetVersion() string {
+	if m != nil && m.Version != nil {
+		return *m.Version
+	}
+	return ""
+}
+
+func (m *HelloRequest) GetProtocolVersion() uint32 {
+	if m != nil && m.ProtocolVersion != nil {
+		return *m.ProtocolVersion
+	}
+	return 0
+}
+
+func (m *HelloRequest) GetFeatures() uint64 {
+	if m != nil && m.Features != nil {
+		return *m.Features
+	}
+	return 0
+}
+
+func (m *HelloRequest) GetPointCodecs() []uint32 {
+	if m != nil {
+		return m.PointCodecs
+	}
+	return nil
+}
+
+func (m *HelloRequest) GetClusterID() uint64 {
+	if m != nil && m.ClusterID != nil {
+		return *m.ClusterID
+	}
+	return 0
+}
+
+type HelloResponse struct {
+	Err              *string  `protobuf:"bytes,1,req,name=Err,json=err" json:"Err,omitempty"`
+	NodeID           *uint64  `protobuf:"varint,2,opt,name=NodeID,json=nodeID" json:"NodeID,omitempty"`
+	Version          *string  `protobuf:"bytes,3,opt,name=Version,json=version" json:"Version,omitempty"`
+	ProtocolVersion  *uint32  `protobuf:"varint,4,opt,name=ProtocolVersion,json=protocolVersion" json:"ProtocolVersion,omitempty"`
+	Features         *uint64  `protobuf:"varint,5,opt,name=Features,json=features" json:"Features,omitempty"`
+	PointCodecs      []uint32 `protobuf:"varint,6,rep,name=PointCodecs,json=pointCodecs" json:"PointCodecs,omitempty"`
+	ClusterID        *uint64  `protobuf:"varint,7,opt,name=ClusterID,json=clusterID" json:"ClusterID,omitempty"`
+	XXX_unrecognized []byte   `json:"-"`
+}
+
+func (m *HelloResponse) Reset()                    { *m = HelloResponse{} }
+func (m *HelloResponse) String() string            { return proto.CompactTextString(m) }
+func (*HelloResponse) ProtoMessage()               {}
+func (*HelloResponse) Descriptor() ([]byte, []int) { return fileDescriptorData, []int{83} }
+
+func (m *HelloResponse) GetErr() string {
+	if m != nil && m.Err != nil {
+		return *m.Err
+	}
+	return ""
+}
+
+func (m *HelloResponse) GetNodeID() uint64 {
+	if m != nil && m.NodeID != nil {
+		return *m.NodeID
+	}
+	return 0
+}
+
+func (m *HelloResponse) GetVersion() string {
+	if m != nil && m.Version != nil {
+		return *m.Version
+	}
+	return ""
+}
+
+func (m *HelloResponse) GetProtocolVersion() uint32 {
+	if m != nil && m.ProtocolVersion != nil {
+		return *m.ProtocolVersion
+	}
+	return 0
+}
+
+func (m *HelloResponse) GetFeatures() uint64 {
+	if m != nil && m.Features != nil {
+		return *m.Features
+	}
+	return 0
+}
+
+func (m *HelloResponse) GetPointCodecs() []uint32 {
+	if m != nil {
+		return m.PointCodecs
+	}
+	return nil
+}
+
+func (m *HelloResponse) GetClusterID() uint64 {
+	if m != nil && m.ClusterID != nil {
+		return *m.ClusterID
+	}
+	return 0
+}
+
+type DropShardsRequest struct {
+	ShardIDs         []uint64 `protobuf:"varint,1,rep,name=ShardIDs,json=shardIDs" json:"ShardIDs,omitempty"`
+	XXX_unrecognized []byte   `json:"-"`
+}
+
+func (m *DropShardsRequest) Reset()                    { *m = DropShardsRequest{} }
+func (m *DropShardsRequest) String() string            { return proto.CompactTextString(m) }
+func (*DropShardsRequest) ProtoMessage()               {}
+func (*DropShardsRequest) Descriptor() ([]byte, []int) { return fileDescriptorData, []int{84} }
+
+func (m *DropShardsRequest) GetShardIDs() []uint64 {
+	if m != nil {
+		return m.ShardIDs
+	}
+	return nil
+}
+
+type DropShardsResponse struct {
+	Err              *string `protobuf:"bytes,1,req,name=Err,json=err" json:"Err,omitempty"`
+	XXX_unrecognized []byte  `json:"-"`
+}
+
+func (m *DropShardsResponse) Reset()                    { *m = DropShardsResponse{} }
+func (m *DropShardsResponse) String() string            { return proto.CompactTextString(m) }
+func (*DropShardsResponse) ProtoMessage()               {}
+func (*DropShardsResponse) Descriptor() ([]byte, []int) { return fileDescriptorData, []int{85} }
+
+func (m *DropShardsResponse) GetErr() string {
+	if m != nil && m.Err != nil {
+		return *m.Err
+	}
+	return ""
+}
+
+type ErrorResponse struct {
+	Code             *int32  `protobuf:"varint,1,req,name=Code,json=code" json:"Code,omitempty"`
+	Message          *string `protobuf:"bytes,2,req,name=Message,json=message" json:"Message,omitempty"`
+	XXX_unrecognized []byte  `json:"-"`
+}
+
+func (m *ErrorResponse) Reset()                    { *m = ErrorResponse{} }
+func (m *ErrorResponse) String() string            { return proto.CompactTextString(m) }
+func (*ErrorResponse) ProtoMessage()               {}
+func (*ErrorResponse) Descriptor() ([]byte, []int) { return fileDescriptorData, []int{86} }
+
+func (m *ErrorResponse) GetCode() int32 {
+	if m != nil && m.Code != nil {
+		return *m.Code
+	}
+	return 0
+}
+
+func (m *ErrorResponse) GetMessage() string {
+	if m != nil && m.Message != nil {
+		return *m.Message
+	}
+	return ""
+}
+
+type SetMaintenanceRequest struct {
+	TCPHost          *string `protobuf:"bytes,1,req,name=TCPHost,json=tCPHost" json:"TCPHost,omitempty"`
+	Maintenance      *bool   `protobuf:"varint,2,req,name=Maintenance,json=maintenance" json:"Maintenance,omitempty"`
+	XXX_unrecognized []byte  `json:"-"`
+}
+
+func (m *SetMaintenanceRequest) Reset()                    { *m = SetMaintenanceRequest{} }
+func (m *SetMaintenanceRequest) String() string            { return proto.CompactTextString(m) }
+func (*SetMaintenanceRequest) ProtoMessage()               {}
+func (*SetMaintenanceRequest) Descriptor() ([]byte, []int) { return fileDescriptorData, []int{87} }
+
+func (m *SetMaintenanceRequest) GetTCPHost() string {
+	if m != nil && m.TCPHost != nil {
+		return *m.TCPHost
+	}
+	return ""
+}
+
+func (m *SetMaintenanceRequest) GetMaintenance() bool {
+	if m != nil && m.Maintenance != nil {
+		return *m.Maintenance
+	}
+	return false
+}
+
+type SetMaintenanceResponse struct {
+	Err              *string `protobuf:"bytes,1,req,name=Err,json=err" json:"Err,omitempty"`
+	XXX_unrecognized []byte  `json:"-"`
+}
+
+func (m *SetMaintenanceResponse) Reset()                    { *m = SetMaintenanceResponse{} }
+func (m *SetMaintenanceResponse) String() string            { return proto.CompactTextString(m) }
+func (*SetMaintenanceResponse) ProtoMessage()               {}
+func (*SetMaintenanceResponse) Descriptor() ([]byte, []int) { return fileDescriptorData, []int{88} }
+
+func (m *SetMaintenanceResponse) GetErr() string {
+	if m != nil && m.Err != nil {
+		return *m.Err
+	}
+	return ""
+}
+
+type SetReadOnlyRequest struct {
+	ReadOnly         *bool  `protobuf:"varint,1,req,name=ReadOnly,json=readOnly" json:"ReadOnly,omitempty"`
+	XXX_unrecognized []byte `json:"-"`
+}
+
+func (m *SetReadOnlyRequest) Reset()                    { *m = SetReadOnlyRequest{} }
+func (m *SetReadOnlyRequest) String() string            { return proto.CompactTextString(m) }
+func (*SetReadOnlyRequest) ProtoMessage()               {}
+func (*SetReadOnlyRequest) Descriptor() ([]byte, []int) { return fileDescriptorData, []int{89} }
+
+func (m *SetReadOnlyRequest) GetReadOnly() bool {
+	if m != nil && m.ReadOnly != nil {
+		return *m.ReadOnly
+	}
+	return false
+}
+
+type SetReadOnlyResponse struct {
+	Err              *string `protobuf:"bytes,1,req,name=Err,json=err" json:"Err,omitempty"`
+	XXX_unrecognized []byte  `json:"-"`
+}
+
+func (m *SetReadOnlyResponse) Reset()                    { *m = SetReadOnlyResponse{} }
+func (m *SetReadOnlyResponse) String() string            { return proto.CompactTextString(m) }
+func (*SetReadOnlyResponse) ProtoMessage()               {}
+func (*SetReadOnlyResponse) Descriptor() ([]byte, []int) { return fileDescriptorData, []int{90} }
+
+func (m *SetReadOnlyResponse) GetErr() string {
+	if m != nil && m.Err != nil {
+		return *m.Err
+	}
+	return ""
+}
+
+type CardinalityRequest struct {
+	Database         *string `protobuf:"bytes,1,opt,name=Database,json=database" json:"Database,omitempty"`
+	Exact            *bool   `protobuf:"varint,2,req,name=Exact,json=exact" json:"Exact,omitempty"`
+	XXX_unrecognized []byte  `json:"-"`
+}
+
+func (m *CardinalityRequest) Reset()                    { *m = CardinalityRequest{} }
+func (m *CardinalityRequest) String() string            { return proto.CompactTextString(m) }
+func (*CardinalityRequest) ProtoMessage()               {}
+func (*CardinalityRequest) Descriptor() ([]byte, []int) { return fileDescriptorData, []int{91} }
+
+func (m *CardinalityRequest) GetDatabase() string {
+	if m != nil && m.Database != nil {
+		return *m.Database
+	}
+	return ""
+}
+
+func (m *CardinalityRequest) GetExact() bool {
+	if m != nil && m.Exact != nil {
+		return *m.Exact
+	}
+	return false
+}
+
+type CardinalityResponse struct {
+	Err              *string                `protobuf:"bytes,1,req,name=Err,json=err" json:"Err,omitempty"`
+	Databases        []*DatabaseCardinality `protobuf:"bytes,2,rep,name=Databases,json=databases" json:"Databases,omitempty"`
+	Shards           []*ShardCardinality    `protobuf:"bytes,3,rep,name=Shards,json=shards" json:"Shards,omitempty"`
+	XXX_unrecognized []byte                 `json:"-"`
+}
+
+func (m *CardinalityResponse) Reset()                    { *m = CardinalityResponse{} }
+func (m *CardinalityResponse) String() string            { return proto.CompactTextString(m) }
+func (*CardinalityResponse) ProtoMessage()               {}
+func (*CardinalityResponse) Descriptor() ([]byte, []int) { return fileDescriptorData, []int{92} }
+
+func (m *CardinalityResponse) GetErr() string {
+	if m != nil && m.Err != nil {
+		return *m.Err
+	}
+	return ""
+}
+
+func (m *CardinalityResponse) GetDatabases() []*DatabaseCardinality {
+	if m != nil {
+		return m.Databases
+	}
+	return nil
+}
+
+func (m *CardinalityResponse) GetShards() []*ShardCardinality {
+	if m != nil {
+		return m.Shards
+	}
+	return nil
+}
+
+type DatabaseCardinality struct {
+	Name             *string  `protobuf:"bytes,1,req,name=Name,json=name" json:"Name,omitempty"`
+	SeriesN          *int64   `protobuf:"varint,2,req,name=SeriesN,json=seriesN" json:"SeriesN,omitempty"`
+	Measurements     []string `protobuf:"bytes,3,rep,name=Measurements,json=measurements" json:"Measurements,omitempty"`
+	SeriesKeys       []string `protobuf:"bytes,4,rep,name=SeriesKeys,json=seriesKeys" json:"SeriesKeys,omitempty"`
+	Sketch           []byte   `protobuf:"bytes,5,opt,name=Sketch,json=sketch" json:"Sketch,omitempty"`
+	XXX_unrecognized []byte   `json:"-"`
+}
+
+func (m *DatabaseCardinality) Reset()                    { *m = DatabaseCardinality{} }
+func (m *DatabaseCardinality) String() string            { return proto.CompactTextString(m) }
+func (*DatabaseCardinality) ProtoMessage()               {}
+func (*DatabaseCardinality) Descriptor() ([]byte, []int) { return fileDescriptorData, []int{93} }
+
+func (m *DatabaseCardinality) GetName() string {
+	if m != nil && m.Name != nil {
+		return *m.Name
+	}
+	return ""
+}
+
+func (m *DatabaseCardinality) GetSeriesN() int64 {
+	if m != nil && m.SeriesN != nil {
+		return *m.SeriesN
+	}
+	return 0
+}
+
+func (m *DatabaseCardinality) GetMeasurements() []string {
+	if m != nil {
+		return m.Measurements
+	}
+	return nil
+}
+
+func (m *DatabaseCardinality) GetSeriesKeys() []string {
+	if m != nil {
+		return m.SeriesKeys
+	}
+	return nil
+}
+
+func (m *DatabaseCardinality) GetSketch() []byte {
+	if m != nil {
+		return m.Sketch
+	}
+	return nil
+}
+
+type ShardCardinality struct {
+	ID               *uint64 `protobuf:"varint,1,req,name=ID,json=iD" json:"ID,omitempty"`
+	Database         *string `protobuf:"bytes,2,req,name=Database,json=database" json:"Database,omitempty"`
+	SeriesN          *int64  `protobuf:"varint,3,req,name=SeriesN,json=seriesN" json:"SeriesN,omitempty"`
+	XXX_unrecognized []byte  `json:"-"`
+}
+
+func (m *ShardCardinality) Reset()                    { *m = ShardCardinality{} }
+func (m *ShardCardinality) String() string            { return proto.CompactTextString(m) }
+func (*ShardCardinality) ProtoMessage()               {}
+func (*ShardCardinality) Descriptor() ([]byte, []int) { return fileDescriptorData, []int{94} }
+
+func (m *ShardCardinality) GetID() uint64 {
+	if m != nil && m.ID != nil {
+		return *m.ID
+	}
+	return 0
+}
+
+func (m *ShardCardinality) GetDatabase() string {
+	if m != nil && m.Database != nil {
+		return *m.Database
+	}
+	return ""
+}
+
+func (m *ShardCardinality) GetSeriesN() int64 {
+	if m != nil && m.SeriesN != nil {
+		return *m.SeriesN
+	}
+	return 0
+}
+
+type QuarantineRequest struct {
+	Action           *string `protobuf:"bytes,1,req,name=Action,json=action" json:"Action,omitempty"`
+	NodeID           *uint64 `protobuf:"varint,2,opt,name=NodeID,json=nodeID" json:"NodeID,omitempty"`
+	ID               *string `protobuf:"bytes,3,opt,name=ID,json=iD" json:"ID,omitempty"`
+	XXX_unrecognized []byte  `json:"-"`
+}
+
+func (m *QuarantineRequest) Reset()                    { *m = QuarantineRequest{} }
+func (m *QuarantineRequest) String() string            { return proto.CompactTextString(m) }
+func (*QuarantineRequest) ProtoMessage()               {}
+func (*QuarantineRequest) Descriptor() ([]byte, []int) { return fileDescriptorData, []int{95} }
+
+func (m *QuarantineRequest) GetAction() string {
+	if m != nil && m.Action != nil {
+		return *m.Action
+	}
+	return ""
+}
+
+func (m *QuarantineRequest) GetNodeID() uint64 {
+	if m != nil && m.NodeID != nil {
+		return *m.NodeID
+	}
+	return 0
+}
+
+func (m *QuarantineRequest) GetID() string {
+	if m != nil && m.ID != nil {
+		return *m.ID
+	}
+	return ""
+}
+
+type QuarantineResponse struct {
+	Err              *string             `protobuf:"bytes,1,req,name=Err,json=err" json:"Err,omitempty"`
+	Writes           []*QuarantinedWrite `protobuf:"bytes,2,rep,name=Writes,json=writes" json:"Writes,omitempty"`
+	XXX_unrecognized []byte              `json:"-"`
+}
+
+func (m *QuarantineResponse) Reset()                    { *m = QuarantineResponse{} }
+func (m *QuarantineResponse) String() string            { return proto.CompactTextString(m) }
+func (*QuarantineResponse) ProtoMessage()               {}
+func (*QuarantineResponse) Descriptor() ([]byte, []int) { return fileDescriptorData, []int{96} }
+
+func (m *QuarantineResponse) GetErr() string {
+	if m != nil && m.Err != nil {
+		return *m.Err
+	}
+	return ""
+}
+
+func (m *QuarantineResponse) GetWrites() []*QuarantinedWrite {
+	if m != nil {
+		return m.Writes
+	}
+	return nil
+}
+
+type QuarantinedWrite struct {
+	ID               *string `protobuf:"bytes,1,req,name=ID,json=iD" json:"ID,omitempty"`
+	NodeID           *uint64 `protobuf:"varint,2,req,name=NodeID,json=nodeID" json:"NodeID,omitempty"`
+	ShardID          *uint64 `protobuf:"varint,3,req,name=ShardID,json=shardID" json:"ShardID,omitempty"`
+	Sequence         *uint64 `protobuf:"varint,4,req,name=Sequence,json=sequence" json:"Sequence,omitempty"`
+	PointN           *int64  `protobuf:"varint,5,req,name=PointN,json=pointN" json:"PointN,omitempty"`
+	Size_            *int64  `protobuf:"varint,6,req,name=Size,json=size" json:"Size,omitempty"`
+	Attempts         *int64  `protobuf:"varint,7,req,name=Attempts,json=attempts" json:"Attempts,omitempty"`
+	Err              *string `protobuf:"bytes,8,req,name=Err,json=err" json:"Err,omitempty"`
+	Time             *int64  `protobuf:"varint,9,req,name=Time,json=time" json:"Time,omitempty"`
+	XXX_unrecognized []byte  `json:"-"`
+}
+
+func (m *QuarantinedWrite) Reset()                    { *m = QuarantinedWrite{} }
+func (m *QuarantinedWrite) String() string            { return proto.CompactTextString(m) }
+func (*QuarantinedWrite) ProtoMessage()               {}
+func (*QuarantinedWrite) Descriptor() ([]byte, []int) { return fileDescriptorData, []int{97} }
+
+func (m *QuarantinedWrite) GetID() string {
+	if m != nil && m.ID != nil {
+		return *m.ID
+	}
+	return ""
+}
+
+func (m *QuarantinedWrite) GetNodeID() uint64 {
+	if m != nil && m.NodeID != nil {
+		return *m.NodeID
+	}
+	return 0
+}
+
+func (m *QuarantinedWrite) GetShardID() uint64 {
+	if m != nil && m.ShardID != nil {
+		return *m.ShardID
+	}
+	return 0
+}
+
+func (m *QuarantinedWrite) GetSequence() uint64 {
+	if m != nil && m.Sequence != nil {
+		return *m.Sequence
+	}
+	return 0
+}
+
+func (m *QuarantinedWrite) GetPointN() int64 {
+	if m != nil && m.PointN != nil {
+		return *m.PointN
+	}
+	return 0
+}
+
+func (m *QuarantinedWrite) GetSize_() int64 {
+	if m != nil && m.Size_ != nil {
+		return *m.Size_
+	}
+	return 0
+}
+
+func (m *QuarantinedWrite) GetAttempts() int64 {
+	if m != nil && m.Attempts != nil {
+		return *m.Attempts
+	}
+	return 0
+}
+
+func (m *QuarantinedWrite) GetErr() string {
+	if m != nil && m.Err != nil {
+		return *m.Err
+	}
+	return ""
+}
+
+func (m *QuarantinedWrite) GetTime() int64 {
+	if m != nil && m.Time != nil {
+		return *m.Time
+	}
+	return 0
+}
+
+type AdminToken struct {
+	Token            *string `protobuf:"bytes,1,req,name=Token,json=token" json:"Token,omitempty"`
+	XXX_unrecognized []byte  `json:"-"`
+}
+
+func (m *AdminToken) Reset()                    { *m = AdminToken{} }
+func (m *AdminToken) String() string            { return proto.CompactTextString(m) }
+func (*AdminToken) ProtoMessage()               {}
+func (*AdminToken) Descriptor() ([]byte, []int) { return fileDescriptorData, []int{98} }
+
+func (m *AdminToken) GetToken() string {
+	if m != nil && m.Token != nil {
+		return *m.Token
+	}
+	return ""
+}
+
 func init() {
 	proto.RegisterType((*CopyShardRequest)(nil), "internal.CopyShardRequest")
 	proto.RegisterType((*CopyShardResponse)(nil), "internal.CopyShardResponse")
@@ -1282,6 +3140,8 @@ func init() {
 	proto.RegisterType((*DownloadShardSnapshotResponse)(nil), "internal.DownloadShardSnapshotResponse")
 	proto.RegisterType((*ShardStatusRequest)(nil), "internal.ShardStatusRequest")
 	proto.RegisterType((*ShardStatusResponse)(nil), "internal.ShardStatusResponse")
+	proto.RegisterType((*NodeLoad)(nil), "internal.NodeLoad")
+	proto.RegisterType((*ShardStatus)(nil), "internal.ShardStatus")
 	proto.RegisterType((*CreateShardSnapshotRequest)(nil), "internal.CreateShardSnapshotRequest")
 	proto.RegisterType((*CreateShardSnapshotResponse)(nil), "internal.CreateShardSnapshotResponse")
 	proto.RegisterType((*DeleteShardSnapshotRequest)(nil), "internal.DeleteShardSnapshotRequest")
@@ -1299,80 +3159,239 @@ func init() {
 	proto.RegisterType((*TagValues)(nil), "internal.TagValues")
 	proto.RegisterType((*ShowTagValuesRequest)(nil), "internal.ShowTagValuesRequest")
 	proto.RegisterType((*ShowTagValuesResponse)(nil), "internal.ShowTagValuesResponse")
+	proto.RegisterType((*ShardInfo)(nil), "internal.ShardInfo")
+	proto.RegisterType((*ShowShardsRequest)(nil), "internal.ShowShardsRequest")
+	proto.RegisterType((*ShowShardsResponse)(nil), "internal.ShowShardsResponse")
+	proto.RegisterType((*BackupShardRequest)(nil), "internal.BackupShardRequest")
+	proto.RegisterType((*BackupShardResponse)(nil), "internal.BackupShardResponse")
+	proto.RegisterType((*TruncateShardsRequest)(nil), "internal.TruncateShardsRequest")
+	proto.RegisterType((*TruncateShardsResponse)(nil), "internal.TruncateShardsResponse")
+	proto.RegisterType((*RemoveDataNodeRequest)(nil), "internal.RemoveDataNodeRequest")
+	proto.RegisterType((*RemoveDataNodeResponse)(nil), "internal.RemoveDataNodeResponse")
+	proto.RegisterType((*UpdateDataNodeRequest)(nil), "internal.UpdateDataNodeRequest")
+	proto.RegisterType((*UpdateDataNodeResponse)(nil), "internal.UpdateDataNodeResponse")
+	proto.RegisterType((*AuthStateRequest)(nil), "internal.AuthStateRequest")
+	proto.RegisterType((*AuthStateResponse)(nil), "internal.AuthStateResponse")
+	proto.RegisterType((*PauseReplicationRequest)(nil), "internal.PauseReplicationRequest")
+	proto.RegisterType((*PauseReplicationResponse)(nil), "internal.PauseReplicationResponse")
+	proto.RegisterType((*ExportMetaDataRequest)(nil), "internal.ExportMetaDataRequest")
+	proto.RegisterType((*ExportMetaDataResponse)(nil), "internal.ExportMetaDataResponse")
+	proto.RegisterType((*SpanContext)(nil), "internal.SpanContext")
+	proto.RegisterType((*SpanContextEntry)(nil), "internal.SpanContextEntry")
+	proto.RegisterType((*WritePointsRequest)(nil), "internal.WritePointsRequest")
+	proto.RegisterType((*WritePointsResponse)(nil), "internal.WritePointsResponse")
+	proto.RegisterType((*ShardBoundsRequest)(nil), "internal.ShardBoundsRequest")
+	proto.RegisterType((*ShardBoundsResponse)(nil), "internal.ShardBoundsResponse")
+	proto.RegisterType((*ShardBounds)(nil), "internal.ShardBounds")
+	proto.RegisterType((*MeasurementBounds)(nil), "internal.MeasurementBounds")
+	proto.RegisterType((*IteratorEnd)(nil), "internal.IteratorEnd")
+	proto.RegisterType((*AcquireLeaseRequest)(nil), "internal.AcquireLeaseRequest")
+	proto.RegisterType((*AcquireLeaseResponse)(nil), "internal.AcquireLeaseResponse")
+	proto.RegisterType((*UploadShardSnapshotRequest)(nil), "internal.UploadShardSnapshotRequest")
+	proto.RegisterType((*UploadShardSnapshotResponse)(nil), "internal.UploadShardSnapshotResponse")
+	proto.RegisterType((*ReplaceDataNodeRequest)(nil), "internal.ReplaceDataNodeRequest")
+	proto.RegisterType((*ReplaceDataNodeResponse)(nil), "internal.ReplaceDataNodeResponse")
+	proto.RegisterType((*RedirectHintedHandoffRequest)(nil), "internal.RedirectHintedHandoffRequest")
+	proto.RegisterType((*RedirectHintedHandoffResponse)(nil), "internal.RedirectHintedHandoffResponse")
+	proto.RegisterType((*HelloRequest)(nil), "internal.HelloRequest")
+	proto.RegisterType((*HelloResponse)(nil), "internal.HelloResponse")
+	proto.RegisterType((*DropShardsRequest)(nil), "internal.DropShardsRequest")
+	proto.RegisterType((*DropShardsResponse)(nil), "internal.DropShardsResponse")
+	proto.RegisterType((*ErrorResponse)(nil), "internal.ErrorResponse")
+	proto.RegisterType((*SetMaintenanceRequest)(nil), "internal.SetMaintenanceRequest")
+	proto.RegisterType((*SetMaintenanceResponse)(nil), "internal.SetMaintenanceResponse")
+	proto.RegisterType((*SetReadOnlyRequest)(nil), "internal.SetReadOnlyRequest")
+	proto.RegisterType((*SetReadOnlyResponse)(nil), "internal.SetReadOnlyResponse")
+	proto.RegisterType((*CardinalityRequest)(nil), "internal.CardinalityRequest")
+	proto.RegisterType((*CardinalityResponse)(nil), "internal.CardinalityResponse")
+	proto.RegisterType((*DatabaseCardinality)(nil), "internal.DatabaseCardinality")
+	proto.RegisterType((*ShardCardinality)(nil), "internal.ShardCardinality")
+	proto.RegisterType((*QuarantineRequest)(nil), "internal.QuarantineRequest")
+	proto.RegisterType((*QuarantineResponse)(nil), "internal.QuarantineResponse")
+	proto.RegisterType((*QuarantinedWrite)(nil), "internal.QuarantinedWrite")
+	proto.RegisterType((*AdminToken)(nil), "internal.AdminToken")
 }
 
 func init() { proto.RegisterFile("internal/data.proto", fileDescriptorData) }
 
 var fileDescriptorData = []byte{
-	// 1117 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x09, 0x6e, 0x88, 0x02, 0xff, 0xa4, 0x56, 0xcf, 0x6e, 0xdb, 0xc6,
-	0x13, 0x06, 0x25, 0xca, 0x92, 0x26, 0xfe, 0x25, 0x0e, 0x25, 0xdb, 0x84, 0xe3, 0x5f, 0x21, 0x2c,
-	0xd0, 0x56, 0xed, 0x21, 0x46, 0x7a, 0xe8, 0xa5, 0x27, 0x57, 0x72, 0x11, 0xc5, 0xb1, 0xea, 0x52,
-	0x6e, 0x83, 0x02, 0xbd, 0x6c, 0xc4, 0x49, 0x4c, 0x44, 0xe2, 0xd2, 0xbb, 0x4b, 0x27, 0x0a, 0xd0,
-	0x27, 0x68, 0xd1, 0x57, 0xeb, 0x2b, 0x15, 0xfb, 0x87, 0x14, 0x49, 0x99, 0xae, 0x53, 0xdf, 0x38,
-	0xb3, 0xcb, 0x99, 0x6f, 0xbe, 0x6f, 0x76, 0x67, 0xa1, 0x17, 0xc5, 0x12, 0x79, 0x4c, 0x17, 0x47,
-	0x21, 0x95, 0xf4, 0x69, 0xc2, 0x99, 0x64, 0x5e, 0x27, 0x73, 0x92, 0x3f, 0x1d, 0xd8, 0x19, 0xb1,
-	0x64, 0x35, 0xbb, 0xa4, 0x3c, 0x0c, 0xf0, 0x2a, 0x45, 0x21, 0xbd, 0x3d, 0xd8, 0x9a, 0xb1, 0x94,
-	0xcf, 0xd1, 0x77, 0x06, 0x8d, 0x61, 0x37, 0xd8, 0x12, 0xda, 0xf2, 0x3c, 0x70, 0xc7, 0x28, 0xa4,
-	0xdf, 0xd0, 0x5e, 0x37, 0x54, 0x7b, 0x0f, 0xa0, 0x33, 0xa6, 0x92, 0xbe, 0xa6, 0x02, 0xfd, 0xe6,
-	0xc0, 0x19, 0x76, 0x83, 0x4e, 0x68, 0x6d, 0x15, 0xe7, 0x9c, 0x2d, 0xa2, 0xf9, 0xca, 0x77, 0x4d,
-	0x9c, 0x44, 0x5b, 0x9e, 0x0f, 0x6d, 0x9d, 0x6f, 0x32, 0xf6, 0x5b, 0x83, 0xc6, 0xd0, 0x0d, 0xda,
-	0xc2, 0x98, 0xe4, 0x73, 0x78, 0x5c, 0x40, 0x23, 0x12, 0x16, 0x0b, 0xf4, 0x76, 0xa0, 0x79, 0xc2,
-	0xb9, 0xc5, 0xd2, 0x44, 0xce, 0x89, 0x0f, 0x7b, 0xf9, 0xb6, 0x99, 0xa4, 0x32, 0x15, 0x16, 0x3a,
-	0x39, 0x86, 0xfd, 0x8d, 0x95, 0xba, 0x30, 0x5e, 0x1f, 0x5a, 0x17, 0x54, 0xbc, 0x13, 0x7e, 0x63,
-	0xd0, 0x1c, 0x76, 0x83, 0x96, 0x54, 0x06, 0xf9, 0xdb, 0x81, 0x47, 0x95, 0x18, 0xf7, 0x60, 0xa4,
-	0x71, 0x27, 0x46, 0x0e, 0xa1, 0x7b, 0xc1, 0x24, 0x5d, 0xcc, 0xa2, 0x8f, 0x68, 0x39, 0xe9, 0xca,
-	0xcc, 0xe1, 0x0d, 0xe0, 0xc1, 0x3c, 0xe5, 0x1c, 0x63, 0xa9, 0xd7, 0xb7, 0xf4, 0x7a, 0xd1, 0xa5,
-	0xfe, 0x9f, 0x49, 0xca, 0x25, 0x86, 0xc7, 0xd2, 0x6f, 0x9b, 0xff, 0x45, 0xe6, 0x20, 0xbf, 0x41,
-	0xff, 0x34, 0x5a, 0x2c, 0xee, 0xa5, 0x73, 0x41, 0xb3, 0x66, 0x59, 0xb3, 0xaf, 0x60, 0xb7, 0x12,
-	0xbd, 0x56, 0xb7, 0xd7, 0xe0, 0x05, 0xb8, 0x64, 0xd7, 0x58, 0x82, 0x51, 0x24, 0xcc, 0xa9, 0x25,
-	0xac, 0x51, 0xd7, 0x42, 0x15, 0x38, 0x5f, 0x42, 0xaf, 0x94, 0xa3, 0x16, 0xcc, 0x5f, 0x0e, 0x78,
-	0x2f, 0x58, 0x14, 0x8f, 0x16, 0xa9, 0x90, 0xc8, 0x0b, 0xa4, 0x4c, 0x59, 0x88, 0x93, 0xb1, 0xde,
-	0xeb, 0x06, 0x5b, 0xb1, 0xb6, 0x14, 0x4a, 0xe5, 0x3f, 0x0e, 0x43, 0x6e, 0xb1, 0x74, 0x62, 0x6b,
-	0x2b, 0xfa, 0xcf, 0x50, 0x52, 0xf5, 0x2d, 0xfc, 0xa6, 0x6e, 0xa6, 0xee, 0x32, 0x73, 0x78, 0x5f,
-	0xc0, 0xc3, 0xc9, 0x32, 0x61, 0x5c, 0xaa, 0x3d, 0xaa, 0x52, 0x2b, 0xfe, 0xc3, 0xa8, 0xe4, 0x25,
-	0xbf, 0x42, 0xaf, 0x84, 0xc7, 0x22, 0xaf, 0x03, 0xe4, 0x43, 0xfb, 0x62, 0x74, 0xfe, 0x9c, 0xe5,
-	0x42, 0xb5, 0xa5, 0x31, 0xb3, 0x5a, 0x9b, 0xeb, 0x5a, 0x9f, 0x41, 0xef, 0x25, 0xd2, 0x6b, 0xac,
-	0xd4, 0x5a, 0xac, 0xc9, 0x29, 0xd7, 0x44, 0x86, 0xd0, 0x2f, 0xff, 0x52, 0x4b, 0xe4, 0x1f, 0x0e,
-	0x3c, 0x7e, 0xc5, 0x23, 0x59, 0x56, 0xb5, 0xa0, 0x90, 0x53, 0x52, 0xc8, 0x68, 0x1a, 0xc5, 0xd2,
-	0x9c, 0xbb, 0x6d, 0xa5, 0xa9, 0xb2, 0x6e, 0xbd, 0x4a, 0x86, 0xf0, 0x28, 0x40, 0x89, 0xb1, 0x8c,
-	0x58, 0x9c, 0x9f, 0x20, 0xb5, 0xe5, 0x11, 0x2f, 0xbb, 0xc9, 0xf7, 0xe0, 0x15, 0xc1, 0x58, 0xd4,
-	0x1e, 0xb8, 0x23, 0x16, 0x9a, 0xfe, 0x6a, 0x05, 0xee, 0x9c, 0x85, 0xa8, 0x10, 0x9e, 0xa1, 0x10,
-	0xf4, 0x2d, 0xfa, 0x0d, 0x1d, 0xab, 0xbd, 0x34, 0x26, 0x99, 0xc1, 0xfe, 0xc9, 0x07, 0x9c, 0xa7,
-	0x12, 0xd5, 0xf9, 0xc7, 0x25, 0xc6, 0x32, 0x2b, 0xcb, 0x9c, 0x34, 0xe3, 0xb3, 0x24, 0xa8, 0x93,
-	0x66, 0x1c, 0xa5, 0x12, 0x1a, 0xe5, 0x56, 0x26, 0xcf, 0xc1, 0xdf, 0x0c, 0xfa, 0x9f, 0xe0, 0x9d,
-	0xc0, 0xee, 0x88, 0x23, 0x95, 0x38, 0x91, 0xc8, 0xa9, 0x64, 0x45, 0x3d, 0x2d, 0xe7, 0xc2, 0x77,
-	0x06, 0xcd, 0xa1, 0x1b, 0x74, 0x2c, 0xe9, 0x42, 0xe9, 0xf6, 0x63, 0x62, 0x5a, 0x65, 0x3b, 0x68,
-	0xb2, 0x44, 0x92, 0xaf, 0x61, 0xaf, 0x1a, 0xa6, 0xaa, 0xb1, 0x93, 0x69, 0x7c, 0x0c, 0xff, 0xcb,
-	0x76, 0x29, 0xf4, 0x42, 0xcb, 0x8b, 0x3c, 0x42, 0x31, 0xcd, 0xe5, 0x35, 0x66, 0x2e, 0xef, 0xd4,
-	0xe6, 0x32, 0xf2, 0x4e, 0xc9, 0x14, 0xf6, 0x7e, 0x88, 0x70, 0x11, 0x8e, 0xa3, 0x25, 0xc6, 0x22,
-	0x62, 0xb1, 0xb8, 0x0b, 0x6c, 0x95, 0x47, 0xdf, 0x4a, 0xc2, 0x86, 0x6b, 0x9b, 0x4b, 0x4a, 0x90,
-	0x23, 0x68, 0xe9, 0x78, 0x8a, 0xbc, 0x29, 0x5d, 0x66, 0x77, 0x87, 0x1b, 0xd3, 0xa5, 0x26, 0xf4,
-	0x62, 0x95, 0x18, 0x11, 0xdc, 0xc0, 0x95, 0xab, 0x04, 0xc9, 0x1c, 0xf6, 0x37, 0x00, 0xac, 0xcf,
-	0x98, 0x5e, 0x32, 0xf9, 0xbb, 0xc1, 0xd6, 0x1b, 0x6d, 0x79, 0x9f, 0x01, 0xac, 0x77, 0xdb, 0x31,
-	0x01, 0x61, 0xee, 0x59, 0x9f, 0xb4, 0x9c, 0xa8, 0x97, 0xd0, 0x3f, 0xf9, 0x90, 0xd0, 0x38, 0xb4,
-	0xa8, 0xef, 0x57, 0xe3, 0x08, 0x76, 0x2b, 0xd1, 0x2c, 0xe0, 0xc2, 0x2f, 0x4a, 0xa5, 0xf5, 0x2f,
-	0x19, 0xa4, 0x46, 0x11, 0xd2, 0xe1, 0x98, 0xbd, 0x8f, 0x17, 0x8c, 0x86, 0x66, 0xa6, 0xc5, 0x34,
-	0x11, 0x97, 0x4c, 0xfe, 0xfb, 0x49, 0xf5, 0xc0, 0x3d, 0xa7, 0xf2, 0x32, 0x1b, 0x04, 0x09, 0x95,
-	0x97, 0xe4, 0x19, 0xfc, 0xbf, 0x26, 0x5a, 0x6d, 0xf3, 0x3c, 0x05, 0x6f, 0x73, 0x54, 0xd7, 0xa7,
-	0x25, 0xdf, 0x41, 0xef, 0x6e, 0x03, 0xdc, 0x03, 0x57, 0x4f, 0x44, 0xab, 0xb2, 0x88, 0x3e, 0x22,
-	0xf9, 0x16, 0x0e, 0x4c, 0x57, 0x7f, 0x5a, 0xad, 0xe4, 0x15, 0x3c, 0xb9, 0xf1, 0xbf, 0xdb, 0x92,
-	0x57, 0xc9, 0xc9, 0x01, 0x35, 0x0b, 0x80, 0x5e, 0xc0, 0xc1, 0x18, 0x17, 0xf8, 0xa9, 0x80, 0x6e,
-	0x24, 0xff, 0x08, 0x9e, 0xdc, 0x18, 0xab, 0xf6, 0x6e, 0xfe, 0x1d, 0xba, 0x3f, 0xa5, 0xc8, 0x57,
-	0x93, 0xf8, 0x0d, 0xf3, 0x1e, 0x42, 0x23, 0x4f, 0xd3, 0x88, 0xc6, 0xea, 0xfd, 0xa3, 0x17, 0x6d,
-	0x8a, 0xd6, 0x95, 0x32, 0x54, 0xde, 0x9f, 0x05, 0x66, 0xe3, 0xc3, 0x4d, 0x05, 0xf2, 0xd2, 0xbd,
-	0xe6, 0x56, 0x46, 0xb4, 0x5a, 0x4b, 0x39, 0x55, 0x57, 0xb0, 0x7e, 0xba, 0x34, 0x83, 0x4e, 0x68,
-	0x6d, 0xd2, 0x57, 0xca, 0xb3, 0xf7, 0x2a, 0x4b, 0x84, 0x85, 0x47, 0x5a, 0xaf, 0xe4, 0x5d, 0xf7,
-	0xb4, 0x75, 0xd9, 0x0a, 0xda, 0x57, 0xc6, 0x5c, 0xf7, 0x74, 0x5e, 0x17, 0x81, 0x1d, 0xf5, 0xe8,
-	0xd0, 0xf0, 0x33, 0x2a, 0x2b, 0xe5, 0xa9, 0xc7, 0x64, 0x61, 0x4f, 0x2d, 0x45, 0x23, 0xf5, 0x60,
-	0x10, 0x92, 0xf1, 0xbb, 0xce, 0xaf, 0x9b, 0xba, 0x6e, 0x08, 0xfd, 0x72, 0x90, 0xda, 0x74, 0x13,
-	0xd8, 0x57, 0xc5, 0x9f, 0x21, 0x15, 0x29, 0xd7, 0x53, 0x20, 0x3f, 0x11, 0x9b, 0x3d, 0x76, 0x08,
-	0xdd, 0x11, 0x8b, 0xc3, 0x48, 0x93, 0x6b, 0xca, 0xef, 0xce, 0x33, 0x07, 0x39, 0x07, 0x7f, 0x33,
-	0x94, 0x4d, 0x4c, 0x60, 0xbb, 0xe8, 0xb7, 0x41, 0xb7, 0x97, 0x05, 0xdf, 0x0d, 0xb4, 0x7e, 0x03,
-	0x9d, 0x53, 0x5c, 0xfd, 0x42, 0x17, 0xa9, 0x86, 0x7e, 0x8a, 0xab, 0x0c, 0xcd, 0x3b, 0x5c, 0xa9,
-	0x7e, 0xd1, 0x4b, 0x59, 0xbf, 0x5c, 0x2b, 0x83, 0x9c, 0x40, 0xf7, 0x82, 0xbe, 0xd5, 0x0b, 0x42,
-	0x3d, 0x55, 0x0b, 0x69, 0xed, 0xcf, 0x0f, 0x0a, 0x59, 0xd5, 0x55, 0x6b, 0xf6, 0x66, 0x2f, 0x3a,
-	0x1d, 0x45, 0x90, 0x73, 0xe8, 0xab, 0x62, 0xf2, 0x50, 0x77, 0x79, 0x1d, 0xde, 0x4e, 0xcf, 0x31,
-	0xec, 0x56, 0x22, 0xae, 0x6f, 0x7b, 0x0b, 0xc1, 0x31, 0x13, 0xca, 0x40, 0xd8, 0xe4, 0xe3, 0x9f,
-	0x00, 0x00, 0x00, 0xff, 0xff, 0xd0, 0xb8, 0xbc, 0xbd, 0x3e, 0x0d, 0x00, 0x00,
+	// 2844 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xac, 0x5a, 0xdb, 0x6f, 0x1c, 0x49,
+	0xd5, 0x57, 0xcf, 0xf4, 0xdc, 0x8e, 0xed, 0xc4, 0x6e, 0xdf, 0x46, 0x49, 0x76, 0x35, 0x2a, 0x7d,
+	0xdf, 0x7e, 0xf3, 0x2d, 0xb0, 0x61, 0x23, 0xc4, 0x03, 0x0b, 0x42, 0xf6, 0xd8, 0xd9, 0x78, 0xe3,
+	0x38, 0x4e, 0xdb, 0xbb, 0x59, 0x16, 0xb4, 0x52, 0xa5, 0xbb, 0x1c, 0x37, 0xe9, 0xe9, 0x9e, 0x74,
+	0xd5, 0x24, 0x1e, 0x24, 0x10, 0xaf, 0x20, 0xc4, 0x33, 0x42, 0x42, 0xfc, 0x31, 0x48, 0xbc, 0xf2,
+	0x04, 0x12, 0x2f, 0xfc, 0x0b, 0x3c, 0xf3, 0x86, 0x4e, 0x5d, 0xba, 0xab, 0x7b, 0xa6, 0x27, 0xde,
+	0xec, 0xbe, 0xcd, 0x39, 0x55, 0x7d, 0xea, 0x5c, 0xaa, 0xce, 0xf9, 0xd5, 0xa9, 0x81, 0xcd, 0x28,
+	0x11, 0x2c, 0x4b, 0x68, 0x7c, 0x37, 0xa4, 0x82, 0x7e, 0x30, 0xc9, 0x52, 0x91, 0x7a, 0x5d, 0xc3,
+	0x24, 0xbf, 0x73, 0x60, 0x7d, 0x94, 0x4e, 0x66, 0x67, 0x97, 0x34, 0x0b, 0x7d, 0xf6, 0x72, 0xca,
+	0xb8, 0xf0, 0x76, 0xa0, 0x7d, 0x96, 0x4e, 0xb3, 0x80, 0xf5, 0x9d, 0x41, 0x63, 0xd8, 0xf3, 0xdb,
+	0x5c, 0x52, 0x9e, 0x07, 0xee, 0x01, 0xe3, 0xa2, 0xdf, 0x90, 0x5c, 0x37, 0xc4, 0xb9, 0xb7, 0xa0,
+	0x7b, 0x40, 0x05, 0x7d, 0x46, 0x39, 0xeb, 0x37, 0x07, 0xce, 0xb0, 0xe7, 0x77, 0x43, 0x4d, 0xa3,
+	0x9c, 0xd3, 0x34, 0x8e, 0x82, 0x59, 0xdf, 0x95, 0x23, 0xed, 0x89, 0xa4, 0xbc, 0x3e, 0x74, 0xe4,
+	0x7a, 0x47, 0x07, 0xfd, 0xd6, 0xa0, 0x31, 0x74, 0xfd, 0x0e, 0x57, 0x24, 0xf9, 0x5f, 0xd8, 0xb0,
+	0xb4, 0xe1, 0x93, 0x34, 0xe1, 0xcc, 0x5b, 0x87, 0xe6, 0x61, 0x96, 0x69, 0x5d, 0x9a, 0x2c, 0xcb,
+	0x48, 0x1f, 0x76, 0xf2, 0x69, 0x67, 0x82, 0x8a, 0x29, 0xd7, 0xaa, 0x93, 0x3d, 0xd8, 0x9d, 0x1b,
+	0xa9, 0x13, 0xe3, 0x6d, 0x41, 0xeb, 0x9c, 0xf2, 0x17, 0xbc, 0xdf, 0x18, 0x34, 0x87, 0x3d, 0xbf,
+	0x25, 0x90, 0x20, 0x7f, 0x73, 0xe0, 0x66, 0x45, 0xc6, 0xd7, 0xf0, 0x48, 0xa3, 0xd6, 0x23, 0x0d,
+	0xcb, 0x23, 0x77, 0xa0, 0x77, 0x9e, 0x0a, 0x1a, 0x9f, 0x45, 0xbf, 0x60, 0xda, 0x27, 0x3d, 0x61,
+	0x18, 0xde, 0x00, 0x56, 0x82, 0x69, 0x96, 0xb1, 0x44, 0xc8, 0xf1, 0xb6, 0x1c, 0xb7, 0x59, 0xf8,
+	0xfd, 0x99, 0xa0, 0x99, 0x60, 0xe1, 0x9e, 0xe8, 0x77, 0xd4, 0xf7, 0xdc, 0x30, 0xc8, 0xcf, 0x60,
+	0xeb, 0x61, 0x14, 0xc7, 0x5f, 0x2b, 0xce, 0x56, 0xcc, 0x9a, 0xe5, 0x98, 0xfd, 0x3f, 0x6c, 0x57,
+	0xa4, 0xd7, 0xc6, 0xed, 0x19, 0x78, 0x3e, 0x1b, 0xa7, 0xaf, 0x58, 0x49, 0x0d, 0xdb, 0x61, 0x4e,
+	0xad, 0xc3, 0x1a, 0x25, 0x87, 0xd5, 0xab, 0xf3, 0x7f, 0xb0, 0x59, 0x5a, 0xa3, 0x56, 0x99, 0xdf,
+	0x3b, 0xe0, 0x7d, 0x92, 0x46, 0xc9, 0x28, 0x9e, 0x72, 0xc1, 0x32, 0xcb, 0x29, 0x27, 0x69, 0xc8,
+	0x8e, 0x0e, 0xe4, 0x5c, 0xd7, 0x6f, 0x27, 0x92, 0x42, 0x2d, 0x91, 0xbf, 0x17, 0x86, 0x99, 0xd6,
+	0xa5, 0x9b, 0x68, 0x1a, 0xdd, 0xff, 0x88, 0x09, 0x8a, 0xbf, 0x79, 0xbf, 0x29, 0x37, 0x53, 0x6f,
+	0x6c, 0x18, 0xde, 0x7b, 0x70, 0xe3, 0x68, 0x3c, 0x49, 0x33, 0x81, 0x73, 0xd0, 0x52, 0x1d, 0xfc,
+	0x1b, 0x51, 0x89, 0x4b, 0x7e, 0x02, 0x9b, 0x25, 0x7d, 0xb4, 0xe6, 0x75, 0x0a, 0xf5, 0xa1, 0x73,
+	0x3e, 0x3a, 0x7d, 0x90, 0xe6, 0x81, 0xea, 0x08, 0x45, 0x1a, 0x5b, 0x9b, 0x85, 0xad, 0x1f, 0xc2,
+	0xe6, 0x31, 0xa3, 0xaf, 0x58, 0xc5, 0x56, 0xdb, 0x26, 0xa7, 0x6c, 0x13, 0x19, 0xc2, 0x56, 0xf9,
+	0x93, 0x5a, 0x47, 0xfe, 0xa7, 0x01, 0x1b, 0x4f, 0xb3, 0x48, 0x94, 0xa3, 0x6a, 0x45, 0xc8, 0x29,
+	0x45, 0x48, 0xc5, 0x34, 0x4a, 0x84, 0x3a, 0x77, 0xab, 0x18, 0x53, 0xa4, 0x96, 0xa6, 0x92, 0x21,
+	0xdc, 0xf4, 0x99, 0x60, 0x89, 0x88, 0xd2, 0xa4, 0x94, 0x53, 0x6e, 0x66, 0x65, 0x36, 0xc6, 0x42,
+	0xab, 0x20, 0xd3, 0x0b, 0xce, 0xe9, 0x65, 0x86, 0x21, 0x9d, 0x16, 0x8d, 0x59, 0x3a, 0x15, 0xfd,
+	0xf6, 0xc0, 0x19, 0x36, 0xfd, 0x8e, 0x50, 0xa4, 0x47, 0x60, 0xf5, 0x71, 0x16, 0x3d, 0x8f, 0x12,
+	0xed, 0xec, 0xce, 0xc0, 0x19, 0xba, 0xfe, 0x6a, 0x6a, 0xf1, 0x30, 0x92, 0x0f, 0x68, 0x12, 0xa6,
+	0x17, 0x17, 0x4f, 0xa6, 0x6c, 0x8a, 0xb3, 0xba, 0x72, 0xd6, 0x8d, 0xcb, 0x12, 0x17, 0xb5, 0xd5,
+	0xf3, 0xce, 0x70, 0xe5, 0x24, 0x60, 0xfd, 0x9e, 0x9c, 0x78, 0xf3, 0xb2, 0xcc, 0xf6, 0xde, 0x05,
+	0x90, 0xbe, 0x18, 0xa5, 0x21, 0x0b, 0xfa, 0x30, 0x70, 0x86, 0x6b, 0x3e, 0x4c, 0x72, 0x4e, 0x3e,
+	0xbe, 0x4f, 0x45, 0x70, 0xd9, 0x5f, 0x19, 0x38, 0xc3, 0x55, 0x3d, 0x2e, 0x39, 0xe4, 0xd7, 0x0e,
+	0x78, 0xb6, 0xef, 0x75, 0x90, 0x3c, 0x70, 0xf1, 0x7b, 0xe9, 0xf9, 0x96, 0xef, 0x06, 0x69, 0xc8,
+	0xd0, 0xf4, 0x47, 0x8c, 0x73, 0xfa, 0x9c, 0xf5, 0x1b, 0xd2, 0x2d, 0x9d, 0xb1, 0x22, 0xcb, 0x2e,
+	0x6b, 0x56, 0x5d, 0xf6, 0x2e, 0x74, 0x7d, 0xf6, 0x73, 0x16, 0x08, 0x16, 0xf6, 0xdd, 0x41, 0x73,
+	0xb8, 0xb6, 0xdf, 0x58, 0x77, 0xfc, 0x6e, 0xa6, 0x79, 0xe4, 0x37, 0x0e, 0xec, 0x1e, 0x5e, 0xb1,
+	0x60, 0x2a, 0x18, 0x66, 0x4b, 0x36, 0x66, 0x89, 0x30, 0x9b, 0x40, 0xe5, 0x25, 0xc5, 0xd3, 0x5b,
+	0xa6, 0xc7, 0x0d, 0xa3, 0x14, 0xf0, 0x46, 0xe5, 0xe0, 0x2f, 0xd7, 0xa9, 0x38, 0x13, 0xee, 0xc0,
+	0x29, 0xce, 0x04, 0x79, 0x06, 0xfd, 0x79, 0x55, 0xde, 0xca, 0x27, 0xb8, 0x7d, 0x59, 0x16, 0x31,
+	0x7e, 0x22, 0x57, 0x6f, 0xfa, 0x1d, 0xae, 0x48, 0xf2, 0x77, 0x07, 0xb6, 0x47, 0x19, 0xa3, 0x82,
+	0x1d, 0x09, 0x96, 0x51, 0x91, 0xda, 0xc7, 0x49, 0x6f, 0x79, 0xde, 0x77, 0x06, 0xcd, 0xa1, 0xeb,
+	0x77, 0xf5, 0x9e, 0xe7, 0x78, 0x6c, 0x1e, 0x4f, 0xd4, 0x49, 0x5d, 0xf5, 0x9b, 0xe9, 0x44, 0xbc,
+	0xc1, 0xc2, 0x3e, 0x74, 0x3e, 0xce, 0xd2, 0xe9, 0x64, 0x7f, 0x26, 0x9d, 0xde, 0xf3, 0x3b, 0xcf,
+	0x15, 0x89, 0x23, 0x9f, 0xb1, 0x8c, 0x47, 0x69, 0x22, 0xb7, 0xf7, 0x9a, 0xdf, 0x79, 0xa5, 0x48,
+	0xac, 0x13, 0xa3, 0x74, 0x3c, 0xc9, 0x18, 0x97, 0xa3, 0x6d, 0x29, 0x73, 0x25, 0x28, 0x58, 0xa8,
+	0xe1, 0x61, 0x12, 0xa4, 0x61, 0x94, 0x3c, 0x97, 0x1b, 0xbc, 0xe7, 0x77, 0x99, 0xa6, 0xc9, 0x1f,
+	0x1d, 0xd8, 0xa9, 0xda, 0x55, 0x3d, 0xf3, 0x8e, 0x55, 0x3a, 0x8f, 0xa3, 0x71, 0x24, 0xb4, 0xdb,
+	0x5a, 0x31, 0x12, 0x28, 0x5e, 0x72, 0x1f, 0xd1, 0x2b, 0xed, 0xb5, 0x6e, 0xac, 0xe9, 0xaa, 0x72,
+	0xee, 0x72, 0xe5, 0x5a, 0x15, 0xe5, 0xf6, 0x60, 0xcd, 0x68, 0x85, 0x91, 0xe5, 0x76, 0x7c, 0x4c,
+	0x7a, 0x51, 0x64, 0x9e, 0x5e, 0x4e, 0xb4, 0xb3, 0x55, 0x7a, 0x39, 0x21, 0x31, 0xec, 0xdc, 0x8f,
+	0x58, 0x1c, 0x1e, 0x44, 0x63, 0x96, 0xe0, 0x82, 0xfc, 0x3a, 0x71, 0xc3, 0x75, 0x64, 0x55, 0xe4,
+	0x5a, 0x5c, 0x47, 0x15, 0x49, 0xbe, 0x3c, 0x7e, 0xe4, 0x2e, 0xb4, 0xe4, 0x6a, 0xb8, 0xed, 0x4e,
+	0xe8, 0xd8, 0x54, 0x36, 0x37, 0xa1, 0x63, 0xb9, 0x15, 0xcf, 0x67, 0x13, 0xb5, 0xe9, 0x5d, 0xdf,
+	0x15, 0xb3, 0x09, 0x23, 0x01, 0xec, 0xce, 0xa9, 0x57, 0x54, 0x00, 0x39, 0xa4, 0xb4, 0xeb, 0xf9,
+	0xed, 0x0b, 0x49, 0x61, 0x72, 0x28, 0x66, 0x6b, 0x10, 0x03, 0x61, 0xce, 0x29, 0xea, 0x80, 0x09,
+	0x1b, 0x39, 0x86, 0xad, 0xc3, 0xab, 0x09, 0x4d, 0x42, 0x6d, 0xd3, 0xd7, 0xf2, 0x00, 0x19, 0xc1,
+	0x76, 0x45, 0x9a, 0x56, 0xd8, 0xfa, 0xc4, 0x19, 0x38, 0xd6, 0x27, 0x46, 0xa5, 0x86, 0xad, 0xd2,
+	0x9d, 0x83, 0xf4, 0x75, 0x12, 0xa7, 0x34, 0x54, 0x88, 0x2b, 0xa1, 0x13, 0x7e, 0x99, 0x8a, 0x37,
+	0xd7, 0x11, 0x0f, 0xdc, 0x53, 0x2a, 0x2e, 0x0d, 0x4c, 0x99, 0x50, 0x71, 0x49, 0x3e, 0x84, 0x77,
+	0x6a, 0xa4, 0xd5, 0x6d, 0x65, 0xf2, 0x5d, 0xf0, 0xe6, 0x81, 0xe4, 0x32, 0x8f, 0x90, 0x5f, 0xc1,
+	0xe6, 0xf5, 0x00, 0xe6, 0x77, 0xa0, 0x2d, 0x27, 0xaa, 0xe0, 0xac, 0xdc, 0xdb, 0xfe, 0xc0, 0x00,
+	0xef, 0x0f, 0x6c, 0x01, 0x6d, 0x29, 0x19, 0x81, 0x82, 0x7b, 0x9c, 0xd2, 0x50, 0x06, 0x6c, 0xe5,
+	0x9e, 0x57, 0x4c, 0xc6, 0x5c, 0x87, 0x23, 0xbe, 0x8b, 0x86, 0x21, 0x72, 0xe9, 0x1a, 0x16, 0x2a,
+	0xfa, 0x74, 0xef, 0x78, 0x7f, 0x26, 0xa4, 0xb3, 0x1b, 0x78, 0xe6, 0x5e, 0x6b, 0x1a, 0x37, 0xc8,
+	0x88, 0x06, 0x97, 0x4c, 0x8d, 0x36, 0xe4, 0x28, 0x04, 0x39, 0x07, 0xeb, 0x19, 0x9e, 0x49, 0x1a,
+	0x60, 0xfd, 0x3c, 0x60, 0xcf, 0x84, 0xc4, 0x0c, 0x4d, 0xff, 0x46, 0x50, 0xe2, 0xa2, 0x9c, 0xc7,
+	0xaf, 0x58, 0x86, 0x8b, 0xcb, 0x22, 0x80, 0x06, 0x42, 0x9a, 0x73, 0xc8, 0xbf, 0x1d, 0x58, 0xb1,
+	0xe1, 0xf2, 0x0d, 0x68, 0xe4, 0xe1, 0x6a, 0x44, 0x07, 0x4b, 0x13, 0x7d, 0x81, 0xf0, 0x9a, 0x25,
+	0x84, 0xe7, 0x81, 0x2b, 0xd1, 0xae, 0x2b, 0x35, 0x72, 0x39, 0xc2, 0x5c, 0xeb, 0xd0, 0xb7, 0x24,
+	0x3b, 0x3f, 0xf4, 0x04, 0x56, 0x8f, 0x29, 0x17, 0x8f, 0xd2, 0x30, 0xba, 0x88, 0x58, 0x28, 0x31,
+	0x72, 0xd3, 0x5f, 0x8d, 0x2d, 0x1e, 0x1e, 0x58, 0x9c, 0x23, 0xcb, 0xa5, 0x04, 0xc9, 0x4d, 0xbf,
+	0x17, 0x1b, 0x86, 0x2a, 0x0f, 0x71, 0xd8, 0xef, 0x0e, 0x1a, 0xc3, 0x2e, 0x96, 0x87, 0x38, 0xc4,
+	0xf5, 0x46, 0x69, 0x96, 0x4d, 0x27, 0x42, 0xd6, 0xef, 0x9e, 0xdf, 0x09, 0x14, 0x49, 0xbe, 0x0f,
+	0xb7, 0x54, 0xae, 0xfc, 0x6a, 0x7b, 0x96, 0x3c, 0x85, 0xdb, 0x0b, 0xbf, 0xab, 0xdd, 0x42, 0x0b,
+	0x36, 0x79, 0xee, 0x1a, 0x85, 0x7c, 0xa5, 0x6b, 0xc8, 0x27, 0x70, 0xeb, 0x80, 0xc5, 0xec, 0xab,
+	0x2a, 0xb4, 0xf0, 0x10, 0xdd, 0x85, 0xdb, 0x0b, 0x65, 0xd5, 0x22, 0xc0, 0x5f, 0x42, 0xef, 0xc9,
+	0x94, 0x65, 0xb3, 0xa3, 0xe4, 0x22, 0x9d, 0x0b, 0xfe, 0x16, 0xb4, 0xe4, 0xa0, 0x5e, 0xa2, 0xf5,
+	0x12, 0x09, 0x5c, 0xf7, 0x53, 0xce, 0x0c, 0x48, 0x75, 0xa7, 0x9c, 0x65, 0xa5, 0x6d, 0xe2, 0x56,
+	0xb6, 0x09, 0x8e, 0x4d, 0x33, 0x2a, 0x54, 0xd9, 0x93, 0xdb, 0x3c, 0xd4, 0x34, 0xd9, 0xc2, 0x13,
+	0x9c, 0xbe, 0xc6, 0x55, 0x22, 0x66, 0x5d, 0x05, 0x37, 0x4b, 0xdc, 0x22, 0x37, 0x69, 0x96, 0xb6,
+	0xa0, 0xf3, 0x52, 0x91, 0x45, 0x6e, 0xca, 0xed, 0x22, 0xb0, 0x8e, 0x57, 0x1b, 0xa9, 0xbe, 0x71,
+	0x65, 0xc5, 0x3c, 0xbc, 0xb2, 0x5a, 0x73, 0x6a, 0x5d, 0xf4, 0x27, 0x07, 0xef, 0x25, 0x5c, 0xa4,
+	0xd9, 0x75, 0x61, 0xb2, 0x89, 0x72, 0xa3, 0x88, 0xf2, 0x5b, 0xdd, 0xb6, 0xff, 0x07, 0xd6, 0x54,
+	0x32, 0x2e, 0xee, 0xdc, 0x08, 0x99, 0xd6, 0xb8, 0xcd, 0x24, 0x3f, 0x84, 0xad, 0xb2, 0x7a, 0xcb,
+	0x76, 0xa4, 0xc4, 0x51, 0x98, 0xc3, 0x35, 0x8e, 0x22, 0x47, 0xb0, 0x8b, 0xbe, 0x7e, 0xc4, 0x28,
+	0x9f, 0x66, 0x12, 0x76, 0xe5, 0x89, 0x74, 0x5e, 0xc0, 0x1d, 0xe8, 0x8d, 0xd2, 0x24, 0x8c, 0x64,
+	0x2c, 0x95, 0xb7, 0x7b, 0x81, 0x61, 0x90, 0x53, 0xe8, 0xcf, 0x8b, 0xd2, 0xca, 0x10, 0x58, 0xb5,
+	0xf9, 0x5a, 0xe8, 0xea, 0xd8, 0xe2, 0x2d, 0x88, 0xe2, 0x3d, 0xe8, 0x3e, 0x64, 0xb3, 0xcf, 0x68,
+	0x3c, 0x95, 0xe6, 0x3c, 0x64, 0x33, 0xa3, 0xcd, 0x0b, 0x36, 0xc3, 0xed, 0x29, 0x87, 0xcc, 0xf6,
+	0x7c, 0x85, 0x04, 0x39, 0x84, 0xde, 0x39, 0x7d, 0x2e, 0x07, 0x38, 0x42, 0x17, 0x6b, 0x59, 0xfd,
+	0xf1, 0x8a, 0xb5, 0x2a, 0xfa, 0x5e, 0xcd, 0x35, 0xd7, 0x54, 0x29, 0x85, 0x93, 0x53, 0xd8, 0x42,
+	0x63, 0x72, 0x51, 0xd7, 0xb9, 0xf2, 0x2e, 0x77, 0xcf, 0x1e, 0x6c, 0x57, 0x24, 0x16, 0x20, 0x41,
+	0xab, 0xe0, 0x28, 0xd8, 0xa3, 0x54, 0x58, 0xe0, 0x8f, 0xbf, 0x38, 0xd0, 0x53, 0x61, 0x5f, 0x74,
+	0x5c, 0xdf, 0x26, 0x57, 0x13, 0x58, 0x95, 0x02, 0x25, 0x62, 0x95, 0xa0, 0x1c, 0xa5, 0xad, 0x72,
+	0x8b, 0x97, 0xb7, 0x28, 0xf0, 0xfa, 0xa5, 0x4f, 0x70, 0x8f, 0x1b, 0x06, 0x1e, 0x83, 0xc3, 0x24,
+	0x94, 0x63, 0x2a, 0x75, 0x77, 0x98, 0x22, 0x71, 0xcd, 0xc7, 0xaf, 0x13, 0x96, 0xf1, 0x7e, 0x47,
+	0x96, 0xe1, 0x76, 0x2a, 0x29, 0xb2, 0x09, 0x1b, 0xe8, 0x08, 0xb9, 0x6e, 0x7e, 0xe6, 0xcf, 0xc0,
+	0xb3, 0x99, 0xda, 0x35, 0xdf, 0xca, 0xcb, 0xb0, 0x23, 0xcb, 0xf0, 0x66, 0xa5, 0x0c, 0xa3, 0x1f,
+	0xf2, 0x22, 0x3c, 0xef, 0xaf, 0xdf, 0x3a, 0xe0, 0xed, 0xd3, 0xe0, 0xc5, 0x74, 0x72, 0xcd, 0x93,
+	0xbb, 0x05, 0xad, 0xb3, 0x08, 0x2f, 0x7d, 0xaa, 0xe2, 0xb6, 0x38, 0x12, 0x58, 0x6c, 0xf7, 0x29,
+	0x67, 0x26, 0x9d, 0x6a, 0xd0, 0xe8, 0xfa, 0x37, 0x9e, 0x95, 0xb8, 0x32, 0xfe, 0x97, 0x2c, 0x78,
+	0xc1, 0xa7, 0x63, 0x2e, 0x8f, 0x72, 0xd7, 0xef, 0x05, 0x86, 0x41, 0x52, 0xd8, 0x2c, 0xe9, 0x52,
+	0x7b, 0x4c, 0xdf, 0x05, 0xb0, 0x96, 0x6a, 0xc8, 0xa5, 0x80, 0x17, 0xcb, 0x5c, 0x53, 0x1d, 0xdc,
+	0x70, 0xe7, 0xd9, 0x34, 0x09, 0x4c, 0xcd, 0xca, 0xf7, 0xf0, 0x16, 0xb4, 0x0e, 0x58, 0x4c, 0x67,
+	0x1a, 0x75, 0xb4, 0x42, 0x24, 0x24, 0xb4, 0xc5, 0x28, 0x36, 0x24, 0xfc, 0x77, 0xf1, 0x76, 0x4d,
+	0xde, 0x87, 0x9d, 0xaa, 0x88, 0xda, 0x3c, 0xf9, 0x31, 0x6c, 0xab, 0xf6, 0x0d, 0x6e, 0x42, 0x04,
+	0x39, 0x96, 0xbb, 0x4d, 0xbb, 0xc3, 0x29, 0xb7, 0x3b, 0xb6, 0xa0, 0x75, 0x3f, 0xcd, 0xb4, 0xbb,
+	0xbb, 0x7e, 0xeb, 0x02, 0x09, 0x5c, 0xb4, 0x2a, 0xa8, 0x76, 0xd1, 0xa7, 0xb0, 0xfd, 0xe9, 0x24,
+	0xa4, 0x62, 0x6e, 0x51, 0x04, 0x3e, 0x71, 0x58, 0x5e, 0x17, 0xd2, 0x9c, 0x83, 0xe3, 0x27, 0xec,
+	0x75, 0xb9, 0x0d, 0x03, 0x49, 0xce, 0x41, 0x25, 0xaa, 0x82, 0x6b, 0x95, 0xf0, 0x60, 0x7d, 0x6f,
+	0x2a, 0x2e, 0xe5, 0xc5, 0xd5, 0xec, 0xe7, 0xc7, 0xb0, 0x61, 0xf1, 0x8a, 0x8b, 0xec, 0x03, 0xca,
+	0x2f, 0xf5, 0xb7, 0xee, 0x25, 0xe5, 0x97, 0xe8, 0x03, 0x2c, 0xa7, 0x27, 0xba, 0x5a, 0xb4, 0xb0,
+	0x9e, 0x9e, 0x2c, 0x68, 0x04, 0x3d, 0x84, 0xdd, 0x53, 0x3a, 0xe5, 0xcc, 0x67, 0x93, 0x38, 0x0a,
+	0x64, 0xf9, 0x7c, 0xb3, 0x83, 0x77, 0xa0, 0xed, 0x33, 0x3e, 0x1d, 0x1b, 0x0f, 0xb7, 0x33, 0x49,
+	0x91, 0x6f, 0x43, 0x7f, 0x5e, 0x58, 0xad, 0x7d, 0xbb, 0xf2, 0xb6, 0x60, 0x35, 0xbc, 0x8c, 0x91,
+	0x19, 0xec, 0x54, 0x07, 0x0a, 0x4b, 0x91, 0xd6, 0x19, 0xcd, 0xc5, 0x3c, 0x24, 0x8f, 0x87, 0x6a,
+	0x49, 0x1d, 0x1d, 0x68, 0x6b, 0x7b, 0x81, 0x61, 0xa0, 0x1f, 0x8e, 0x92, 0x90, 0x5d, 0x69, 0x6c,
+	0xd4, 0x8a, 0x90, 0x30, 0xca, 0xb8, 0x85, 0x32, 0x23, 0x58, 0x39, 0x9b, 0xd0, 0x64, 0x94, 0x26,
+	0x82, 0x5d, 0x09, 0xef, 0x7b, 0x98, 0x7e, 0x84, 0x06, 0x05, 0x98, 0x22, 0x6e, 0x59, 0x29, 0xa2,
+	0x98, 0x87, 0x73, 0x66, 0x98, 0x9a, 0xe4, 0x54, 0xf2, 0x03, 0x58, 0xaf, 0x0e, 0x5e, 0xbb, 0xc0,
+	0xfc, 0xc3, 0x34, 0x6e, 0x54, 0x2b, 0xec, 0x3a, 0x85, 0x61, 0x41, 0x0f, 0x4c, 0x89, 0x9c, 0xeb,
+	0x81, 0xbd, 0x8f, 0x4d, 0xfd, 0x84, 0x47, 0x5c, 0xb0, 0x24, 0x98, 0x1d, 0xb3, 0x57, 0x2c, 0x96,
+	0x0e, 0x69, 0xf9, 0xeb, 0x41, 0x85, 0x5f, 0xbe, 0xc6, 0x2a, 0x0f, 0x2d, 0xee, 0x97, 0x69, 0xc4,
+	0x6d, 0xfa, 0x65, 0x45, 0x17, 0xaf, 0x6d, 0x77, 0xf1, 0xc8, 0x47, 0xb0, 0x59, 0xb2, 0x6b, 0x49,
+	0xf7, 0x65, 0x3e, 0xd5, 0x9e, 0xeb, 0xbb, 0xd8, 0x7e, 0x3a, 0x4d, 0xc2, 0x6b, 0xdd, 0x4e, 0xab,
+	0x90, 0x40, 0xdd, 0x82, 0x4b, 0x90, 0x80, 0x7c, 0x06, 0x9b, 0x25, 0xa9, 0x6f, 0x7d, 0x5f, 0xd3,
+	0x02, 0x74, 0xa9, 0x20, 0x5f, 0xc2, 0x8a, 0xc5, 0x9e, 0xab, 0xa4, 0x3f, 0x5e, 0xa0, 0xda, 0xca,
+	0xbd, 0xdb, 0x85, 0x4c, 0x6b, 0x54, 0x4b, 0x2e, 0xeb, 0xfd, 0x53, 0xd8, 0x98, 0x9b, 0xb2, 0xb0,
+	0x9f, 0x80, 0x6d, 0xac, 0x28, 0xd1, 0x79, 0x57, 0x46, 0x69, 0xac, 0x48, 0x39, 0x42, 0xaf, 0xe4,
+	0x48, 0x53, 0x8f, 0x28, 0x92, 0x3c, 0x81, 0x15, 0xd3, 0x51, 0x39, 0x4c, 0xc2, 0x6f, 0xa2, 0xc5,
+	0x83, 0x88, 0x7b, 0x2f, 0x78, 0x39, 0x8d, 0x32, 0x76, 0xcc, 0x28, 0xcf, 0x93, 0xe8, 0x22, 0x8d,
+	0x8b, 0x06, 0x5e, 0xc3, 0x6e, 0x6a, 0x93, 0x2f, 0x61, 0xab, 0x2c, 0x62, 0xd9, 0xe3, 0x8d, 0xc4,
+	0x05, 0xba, 0xb4, 0xb5, 0x24, 0x2c, 0xc0, 0x84, 0x7c, 0x78, 0x35, 0x89, 0xf4, 0x45, 0x41, 0x29,
+	0x08, 0x2c, 0xe7, 0x90, 0x07, 0x70, 0xeb, 0xd3, 0xc9, 0x5b, 0xf4, 0x1a, 0xf4, 0xb1, 0x6e, 0xe4,
+	0xc7, 0x9a, 0x8c, 0xe0, 0xf6, 0x42, 0x49, 0xcb, 0x70, 0xb3, 0xc6, 0xf3, 0x8e, 0xb9, 0xd0, 0x92,
+	0xcf, 0xb1, 0x48, 0x4d, 0x62, 0x1a, 0x7c, 0xe3, 0x95, 0xe7, 0x63, 0xd8, 0x9d, 0x93, 0x5c, 0xab,
+	0x9a, 0x7d, 0xc0, 0x1a, 0x95, 0x66, 0xc7, 0x17, 0x70, 0xc7, 0x67, 0x61, 0x94, 0xb1, 0x40, 0x3c,
+	0xc0, 0x9d, 0x1b, 0xea, 0xce, 0xb6, 0xa5, 0xe8, 0xfd, 0x2c, 0x1d, 0x97, 0x9e, 0x28, 0xe0, 0x22,
+	0xe7, 0xa0, 0xec, 0xf3, 0xb4, 0x14, 0xeb, 0xae, 0xd0, 0x34, 0x76, 0x6b, 0x6a, 0x64, 0xd7, 0x56,
+	0x91, 0xbf, 0x3a, 0xb0, 0xfa, 0x80, 0xc5, 0x71, 0xfa, 0xa6, 0xf7, 0x1a, 0xab, 0x4d, 0xaa, 0x9f,
+	0x47, 0x4c, 0x9b, 0x74, 0x08, 0x37, 0x4f, 0xf1, 0x19, 0x34, 0x48, 0x63, 0x33, 0x03, 0xcf, 0xc6,
+	0x9a, 0x7f, 0x73, 0x52, 0x66, 0xa3, 0xee, 0xf7, 0x19, 0x15, 0xd3, 0x8c, 0x71, 0x8d, 0x69, 0xbb,
+	0x17, 0x9a, 0xc6, 0x4b, 0x41, 0xd1, 0xb9, 0xe7, 0xfd, 0x16, 0x76, 0xc6, 0xfd, 0x95, 0xa2, 0x75,
+	0xcf, 0xcb, 0x95, 0xaa, 0x3d, 0x70, 0x4a, 0x95, 0x8a, 0xfc, 0xd3, 0x81, 0x35, 0x6d, 0x48, 0x6d,
+	0x5c, 0xec, 0x53, 0xe2, 0x2c, 0xb6, 0x4d, 0xdd, 0x02, 0x97, 0xd9, 0xe6, 0x0e, 0x9c, 0x37, 0xd9,
+	0xa6, 0x6e, 0x84, 0xb5, 0xb6, 0xb5, 0xdf, 0x60, 0x5b, 0xa7, 0x6a, 0xdb, 0x5d, 0xd8, 0x38, 0xc8,
+	0xd2, 0x49, 0x19, 0x2f, 0x2e, 0xeb, 0xa8, 0xbd, 0x07, 0x9e, 0xfd, 0x41, 0x6d, 0xf4, 0x7f, 0x04,
+	0x6b, 0x87, 0x59, 0x96, 0x66, 0x4b, 0xcb, 0x4a, 0xa9, 0xa9, 0xdf, 0xb0, 0x9a, 0xfa, 0xe4, 0x0c,
+	0xb6, 0xcf, 0x98, 0x78, 0x44, 0x71, 0xaf, 0x25, 0x34, 0x09, 0xae, 0x01, 0x2e, 0xf1, 0xee, 0x57,
+	0xcc, 0xd7, 0x00, 0x68, 0x65, 0x5c, 0xb0, 0x10, 0xe3, 0x55, 0x85, 0xd6, 0xea, 0x8f, 0xbd, 0x46,
+	0x26, 0x7c, 0x46, 0xc3, 0xc7, 0x49, 0x3c, 0xb3, 0x3c, 0x63, 0x58, 0x72, 0x72, 0x17, 0x5f, 0x57,
+	0x14, 0x8d, 0xcf, 0x99, 0xa5, 0x2f, 0x6a, 0x45, 0xdf, 0x07, 0x6f, 0x44, 0xb3, 0x30, 0x4a, 0x68,
+	0x1c, 0x89, 0xd9, 0x62, 0x3c, 0x51, 0x6e, 0x18, 0x6c, 0x41, 0xeb, 0xf0, 0x8a, 0x06, 0xc2, 0xe0,
+	0x66, 0x86, 0x04, 0xf9, 0x83, 0x03, 0x9b, 0x25, 0x41, 0xb5, 0xbb, 0xf3, 0x23, 0xe8, 0x19, 0xd9,
+	0xa6, 0xb8, 0xbd, 0x53, 0x14, 0x37, 0x33, 0x64, 0xcb, 0xea, 0x99, 0xb5, 0xb9, 0x77, 0x2f, 0x2f,
+	0xb5, 0xcd, 0x39, 0xc0, 0x85, 0x7c, 0xfb, 0x33, 0x53, 0x6f, 0xff, 0xec, 0xc0, 0xe6, 0x02, 0xb1,
+	0x75, 0x25, 0xd1, 0xb4, 0x0a, 0x1b, 0x73, 0xad, 0xc2, 0x52, 0x59, 0x6e, 0xce, 0x23, 0x06, 0x79,
+	0x79, 0x92, 0xd3, 0x1f, 0xb2, 0x19, 0xd7, 0x0f, 0x30, 0xc0, 0x73, 0x8e, 0x7c, 0x39, 0x7f, 0xc1,
+	0xf0, 0x49, 0xae, 0x25, 0xfb, 0xdb, 0x6d, 0x2e, 0x29, 0xf2, 0x39, 0xac, 0x57, 0xb5, 0xff, 0x4a,
+	0x17, 0xec, 0xd2, 0xab, 0x93, 0xad, 0x35, 0x39, 0x83, 0x8d, 0x27, 0x53, 0x9a, 0xd1, 0x44, 0x44,
+	0x09, 0xb3, 0x72, 0xdf, 0x9e, 0xec, 0xd2, 0x9a, 0x07, 0x7c, 0xd5, 0xb3, 0xad, 0xcd, 0x1b, 0x4a,
+	0x15, 0x95, 0x32, 0xb0, 0x77, 0xf5, 0x05, 0x78, 0xb6, 0xd0, 0xda, 0x48, 0xdf, 0x83, 0xb6, 0xc4,
+	0x74, 0x26, 0xcc, 0x56, 0xb0, 0x8a, 0xef, 0x43, 0x39, 0xc5, 0x6f, 0xbf, 0x96, 0x33, 0xc9, 0xbf,
+	0x1c, 0x58, 0xaf, 0x0e, 0x5a, 0xbe, 0x90, 0x0a, 0xd4, 0xc1, 0x80, 0xfa, 0xe7, 0x7d, 0x99, 0x45,
+	0xcc, 0x9b, 0xaa, 0x4e, 0xc9, 0x5c, 0xd3, 0xd6, 0xcb, 0x8f, 0xc2, 0xaa, 0xfa, 0xe5, 0x27, 0xaf,
+	0xbc, 0x6d, 0xab, 0x95, 0x7c, 0x0b, 0xba, 0x7b, 0x42, 0xb0, 0xf1, 0x44, 0x70, 0xdd, 0x0b, 0xee,
+	0x52, 0x4d, 0x1b, 0x07, 0x74, 0x4b, 0xb5, 0x5b, 0x62, 0xa8, 0x9e, 0x92, 0x20, 0x6f, 0xb5, 0x04,
+	0x60, 0x2f, 0x44, 0x9c, 0x95, 0xbe, 0x60, 0x89, 0xfc, 0x2f, 0x09, 0xfe, 0xd0, 0xc6, 0xb5, 0x04,
+	0x12, 0xff, 0x1d, 0x00, 0x40, 0x2b, 0x8e, 0x9a, 0x7e, 0x23, 0x00, 0x00,
 }
@@ -3,6 +3,9 @@ package rpc
 import (
 	"errors"
 	"fmt"
+	"sort"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/gogo/protobuf/proto"
@@ -37,6 +40,39 @@ func (w *WriteShardRequest) Database() string { return w.pb.GetDatabase() }
 
 func (w *WriteShardRequest) RetentionPolicy() string { return w.pb.GetRetentionPolicy() }
 
+// SetRequestID sets the ID of the client request that caused this write.
+func (w *WriteShardRequest) SetRequestID(id string) { w.pb.RequestID = &id }
+
+// RequestID returns the ID of the client request that caused this write.
+func (w *WriteShardRequest) RequestID() string { return w.pb.GetRequestID() }
+
+// SetOriginNodeID sets the ID of the node that sent this write.
+func (w *WriteShardRequest) SetOriginNodeID(id uint64) { w.pb.OriginNodeID = &id }
+
+// OriginNodeID returns the ID of the node that sent this write, or zero if
+// the sender did not set it.
+func (w *WriteShardRequest) OriginNodeID() uint64 { return w.pb.GetOriginNodeID() }
+
+// SetHandoffSequence marks this write as the replay of the hinted handoff
+// writes up to seq of the queue of node queueID on the origin node.
+func (w *WriteShardRequest) SetHandoffSequence(queueID, seq uint64) {
+	w.pb.HandoffQueueID = &queueID
+	w.pb.HandoffSequence = &seq
+}
+
+// HandoffSequence returns the hinted handoff queue and sequence number of
+// this write, or zeros if it is not sequenced.
+func (w *WriteShardRequest) HandoffSequence() (queueID, seq uint64) {
+	return w.pb.GetHandoffQueueID(), w.pb.GetHandoffSequence()
+}
+
+// SetTimeout sets the time left before the client gives up on this write.
+func (w *WriteShardRequest) SetTimeout(d time.Duration) { w.pb.Timeout = proto.Int64(int64(d)) }
+
+// Timeout returns the time left before the client gives up on this write, or
+// zero if the write has no deadline.
+func (w *WriteShardRequest) Timeout() time.Duration { return time.Duration(w.pb.GetTimeout()) }
+
 // Points returns the time series Points
 func (w *WriteShardRequest) Points() []models.Point { return w.unmarshalPoints() }
 
@@ -69,6 +105,27 @@ func (w *WriteShardRequest) SetBinaryPoints(buf []byte) {
 	w.pb.Points = append(w.pb.Points, buf)
 }
 
+// SetEncodedPoints sets the points of the request to an encoded batch. The
+// batch is shared, not copied.
+func (w *WriteShardRequest) SetEncodedPoints(e *EncodedPoints) {
+	w.pb.Points = e.points
+	w.pb.PointCodec, w.pb.PointBatch = nil, nil
+}
+
+// SetPointBatch sets the points of the request to b, a batch encoded with the
+// codec registered as id. The batch is shared, not copied.
+func (w *WriteShardRequest) SetPointBatch(id PointCodecID, b []byte) {
+	if id == BinaryPointCodec {
+		panic("binary points are set with SetEncodedPoints")
+	}
+	w.pb.Points = nil
+	w.pb.PointCodec = proto.Uint32(uint32(id))
+	w.pb.PointBatch = b
+}
+
+// PointCodec returns the codec the points of the request are encoded with.
+func (w *WriteShardRequest) PointCodec() PointCodecID { return PointCodecID(w.pb.GetPointCodec()) }
+
 // MarshalBinary encodes the object to a binary format.
 func (w *WriteShardRequest) MarshalBinary() ([]byte, error) {
 	return proto.Marshal(&w.pb)
@@ -82,7 +139,29 @@ func (w *WriteShardRequest) UnmarshalBinary(buf []byte) error {
 	return nil
 }
 
+// DecodePoints returns the points of the request. A *WriteShardError with
+// CodeUnsupported is returned if they are encoded with an unknown codec.
+func (w *WriteShardRequest) DecodePoints() ([]models.Point, error) {
+	id := w.PointCodec()
+	if id == BinaryPointCodec {
+		return NewEncodedPoints(w.pb.GetPoints()).Points()
+	}
+	c, ok := LookupPointCodec(id)
+	if !ok {
+		return nil, &WriteShardError{Code: CodeUnsupported, Message: fmt.Sprintf("unknown point codec %d", id)}
+	}
+	return c.DecodePoints(w.pb.GetPointBatch())
+}
+
 func (w *WriteShardRequest) unmarshalPoints() []models.Point {
+	if w.PointCodec() != BinaryPointCodec {
+		points, err := w.DecodePoints()
+		if err != nil {
+			panic(fmt.Sprintf("failed to decode points: %v", err))
+		}
+		return points
+	}
+
 	points := make([]models.Point, len(w.pb.GetPoints()))
 	for i, p := range w.pb.GetPoints() {
 		pt, err := models.NewPointFromBytes(p)
@@ -98,6 +177,89 @@ func (w *WriteShardRequest) unmarshalPoints() []models.Point {
 	return points
 }
 
+// EncodedPoints is a batch of points in the binary format sent in a
+// WriteShardRequest. A batch is encoded once per shard and then shared by the
+// writes to each of the shard's owners and by hinted handoff.
+type EncodedPoints struct {
+	points [][]byte
+
+	// src are the points encoded, if known, and batches the batches of
+	// other codecs encoded from them, by codec.
+	src     []models.Point
+	mu      sync.Mutex
+	batches map[PointCodecID][]byte
+}
+
+// EncodePoints returns points in the binary format.
+func EncodePoints(points []models.Point) (*EncodedPoints, error) {
+	e := &EncodedPoints{points: make([][]byte, len(points)), src: points}
+	for i, p := range points {
+		b, err := p.MarshalBinary()
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal point: %v", err)
+		}
+		e.points[i] = b
+	}
+	return e, nil
+}
+
+// NewEncodedPoints returns a batch of points that are already in the binary
+// format. The slices are not copied.
+func NewEncodedPoints(points [][]byte) *EncodedPoints {
+	return &EncodedPoints{points: points}
+}
+
+// Len returns the number of points in the batch.
+func (e *EncodedPoints) Len() int { return len(e.points) }
+
+// Bytes returns the encoding of each point. The slices must not be modified.
+func (e *EncodedPoints) Bytes() [][]byte { return e.points }
+
+// Batch returns the batch encoded with the codec registered as id. It is
+// encoded once, and then shared by the writes of the batch with that codec.
+func (e *EncodedPoints) Batch(id PointCodecID) ([]byte, error) {
+	c, ok := LookupPointCodec(id)
+	if !ok {
+		return nil, fmt.Errorf("unknown point codec %d", id)
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if b, ok := e.batches[id]; ok {
+		return b, nil
+	}
+
+	points := e.src
+	if points == nil {
+		var err error
+		if points, err = e.Points(); err != nil {
+			return nil, err
+		}
+	}
+	b, err := c.EncodePoints(points)
+	if err != nil {
+		return nil, err
+	}
+	if e.batches == nil {
+		e.batches = make(map[PointCodecID][]byte)
+	}
+	e.batches[id] = b
+	return b, nil
+}
+
+// Points decodes the batch.
+func (e *EncodedPoints) Points() ([]models.Point, error) {
+	points := make([]models.Point, len(e.points))
+	for i, b := range e.points {
+		p, err := models.NewPointFromBytes(b)
+		if err != nil {
+			return nil, err
+		}
+		points[i] = p
+	}
+	return points, nil
+}
+
 // SetCode sets the Code
 func (w *WriteShardResponse) SetCode(code int) { w.pb.Code = proto.Int32(int32(code)) }
 
@@ -110,6 +272,34 @@ func (w *WriteShardResponse) Code() int { return int(w.pb.GetCode()) }
 // Message returns the Message
 func (w *WriteShardResponse) Message() string { return w.pb.GetMessage() }
 
+// SetRequestID sets the ID of the client request of the write responded to.
+func (w *WriteShardResponse) SetRequestID(id string) { w.pb.RequestID = &id }
+
+// RequestID returns the ID of the client request of the write responded to.
+func (w *WriteShardResponse) RequestID() string { return w.pb.GetRequestID() }
+
+// SetRejected sets the indices of the points of the write that the remote
+// node rejected, having written the others.
+func (w *WriteShardResponse) SetRejected(a []int) {
+	w.pb.Rejected = make([]uint32, len(a))
+	for i, v := range a {
+		w.pb.Rejected[i] = uint32(v)
+	}
+}
+
+// Rejected returns the indices of the points of the write that the remote
+// node rejected, or nil if it did not report them.
+func (w *WriteShardResponse) Rejected() []int {
+	if len(w.pb.Rejected) == 0 {
+		return nil
+	}
+	a := make([]int, len(w.pb.Rejected))
+	for i, v := range w.pb.Rejected {
+		a[i] = int(v)
+	}
+	return a
+}
+
 // MarshalBinary encodes the object to a binary format.
 func (w *WriteShardResponse) MarshalBinary() ([]byte, error) {
 	return proto.Marshal(&w.pb)
@@ -142,6 +332,19 @@ func (r *ExecuteStatementRequest) Database() string { return r.pb.GetDatabase()
 // SetDatabase sets the database name.
 func (r *ExecuteStatementRequest) SetDatabase(database string) { r.pb.Database = proto.String(database) }
 
+// RequestID returns the ID of the client request that caused this statement.
+func (r *ExecuteStatementRequest) RequestID() string { return r.pb.GetRequestID() }
+
+// SetRequestID sets the ID of the client request that caused this statement.
+func (r *ExecuteStatementRequest) SetRequestID(id string) { r.pb.RequestID = proto.String(id) }
+
+// NodeID returns the ID of the node the statement originates from, or zero
+// if unknown.
+func (r *ExecuteStatementRequest) NodeID() uint64 { return r.pb.GetNodeID() }
+
+// SetNodeID sets the ID of the node the statement originates from.
+func (r *ExecuteStatementRequest) SetNodeID(id uint64) { r.pb.NodeID = proto.Uint64(id) }
+
 // MarshalBinary encodes the object to a binary format.
 func (r *ExecuteStatementRequest) MarshalBinary() ([]byte, error) {
 	return proto.Marshal(&r.pb)
@@ -157,7 +360,7 @@ func (r *ExecuteStatementRequest) UnmarshalBinary(buf []byte) error {
 
 // ExecuteStatementResponse represents the response returned from a remote ExecuteStatementRequest call.
 type ExecuteStatementResponse struct {
-	pb internal.WriteShardResponse
+	pb internal.ExecuteStatementResponse
 }
 
 // Code returns the response code.
@@ -172,6 +375,13 @@ func (w *ExecuteStatementResponse) Message() string { return w.pb.GetMessage() }
 // SetMessage sets the Message
 func (w *ExecuteStatementResponse) SetMessage(message string) { w.pb.Message = &message }
 
+// SeriesN returns the number of series deleted by a DROP SERIES or DELETE
+// statement.
+func (w *ExecuteStatementResponse) SeriesN() int64 { return w.pb.GetSeriesN() }
+
+// SetSeriesN sets the SeriesN
+func (w *ExecuteStatementResponse) SetSeriesN(n int64) { w.pb.SeriesN = proto.Int64(n) }
+
 // MarshalBinary encodes the object to a binary format.
 func (w *ExecuteStatementResponse) MarshalBinary() ([]byte, error) {
 	return proto.Marshal(&w.pb)
@@ -185,21 +395,72 @@ func (w *ExecuteStatementResponse) UnmarshalBinary(buf []byte) error {
 	return nil
 }
 
+// IteratorOptionsVersion is the version of the encoding of the options of
+// remote iterators. Version 1 adds the GROUP BY tags to the options encoded
+// by influxql, and rejects the sources influxql cannot encode.
+//
+// The influxql options have no time zone, so there is nothing to forward for
+// it yet, and subqueries are planned on the querying node, whose iterators
+// over the inner queries only have measurement sources. Fields added to the
+// encoding must bump the version, so that older nodes reject the requests
+// rather than return different results than a single node would.
+const IteratorOptionsVersion = 1
+
+// CompressionSnappy compresses each chunk of the stream of points of a
+// remote iterator with the snappy block format.
+const CompressionSnappy = "snappy"
+
+// IteratorEncodingColumnar streams the points of a remote iterator as
+// columnar record batches rather than one influxql encoded point at a time.
+const IteratorEncodingColumnar = "columnar"
+
 // CreateIteratorRequest represents a request to create a remote iterator.
 type CreateIteratorRequest struct {
-	ShardIDs []uint64
-	Opt      influxql.IteratorOptions
+	ShardIDs  []uint64
+	Opt       influxql.IteratorOptions
+	RequestID string
+
+	// Compression is the compression of the stream of points the querying
+	// node accepts, if any. Nodes that do not support it stream the points
+	// uncompressed.
+	Compression string
+
+	// Encoding is the encoding of the stream of points the querying node
+	// accepts, if any. Nodes that do not support it stream the points
+	// encoded by influxql.
+	Encoding string
 }
 
 // MarshalBinary encodes r to a binary format.
 func (r *CreateIteratorRequest) MarshalBinary() ([]byte, error) {
+	// influxql only encodes measurement sources, and panics on subqueries.
+	for _, src := range r.Opt.Sources {
+		if _, ok := src.(*influxql.Measurement); !ok {
+			return nil, fmt.Errorf("unable to forward source of type %T", src)
+		}
+	}
+
 	buf, err := r.Opt.MarshalBinary()
 	if err != nil {
 		return nil, err
 	}
+
+	// The GROUP BY tags are never encoded with the options, so the node
+	// would otherwise group the partial aggregates of the query differently.
+	var groupBy []string
+	for dim := range r.Opt.GroupBy {
+		groupBy = append(groupBy, dim)
+	}
+	sort.Strings(groupBy)
+
 	return proto.Marshal(&internal.CreateIteratorRequest{
-		ShardIDs: r.ShardIDs,
-		Opt:      buf,
+		ShardIDs:    r.ShardIDs,
+		Opt:         buf,
+		RequestID:   proto.String(r.RequestID),
+		GroupBy:     groupBy,
+		Version:     proto.Uint32(IteratorOptionsVersion),
+		Compression: proto.String(r.Compression),
+		Encoding:    proto.String(r.Encoding),
 	})
 }
 
@@ -210,23 +471,47 @@ func (r *CreateIteratorRequest) UnmarshalBinary(data []byte) error {
 		return err
 	}
 
+	if v := pb.GetVersion(); v > IteratorOptionsVersion {
+		return &IteratorOptionsVersionError{Version: v}
+	}
+
 	r.ShardIDs = pb.GetShardIDs()
+	r.RequestID = pb.GetRequestID()
+	r.Compression = pb.GetCompression()
+	r.Encoding = pb.GetEncoding()
 	if err := r.Opt.UnmarshalBinary(pb.GetOpt()); err != nil {
 		return err
 	}
+	if groupBy := pb.GetGroupBy(); len(groupBy) > 0 {
+		r.Opt.GroupBy = make(map[string]struct{}, len(groupBy))
+		for _, dim := range groupBy {
+			r.Opt.GroupBy[dim] = struct{}{}
+		}
+	}
 	return nil
 }
 
 // CreateIteratorResponse represents a response from remote iterator creation.
 type CreateIteratorResponse struct {
 	Err error
+
+	// Compression is the compression of the stream of points, if any.
+	Compression string
+
+	// Encoding is the encoding of the stream of points, if not the
+	// influxql encoding.
+	Encoding string
 }
 
 // MarshalBinary encodes r to a binary format.
 func (r *CreateIteratorResponse) MarshalBinary() ([]byte, error) {
 	var pb internal.CreateIteratorResponse
-	if r.Err != nil {
-		pb.Err = proto.String(r.Err.Error())
+	pb.Err, pb.Limit, pb.LimitMax = encodeIteratorError(r.Err)
+	if r.Compression != "" {
+		pb.Compression = proto.String(r.Compression)
+	}
+	if r.Encoding != "" {
+		pb.Encoding = proto.String(r.Encoding)
 	}
 	return proto.Marshal(&pb)
 }
@@ -237,16 +522,63 @@ func (r *CreateIteratorResponse) UnmarshalBinary(data []byte) error {
 	if err := proto.Unmarshal(data, &pb); err != nil {
 		return err
 	}
-	if pb.Err != nil {
-		r.Err = errors.New(pb.GetErr())
+	r.Err = decodeIteratorError(pb.Err, pb.Limit, pb.GetLimitMax())
+	r.Compression = pb.GetCompression()
+	r.Encoding = pb.GetEncoding()
+	return nil
+}
+
+// IteratorEnd ends the stream of points of a remote iterator. Err is set if
+// the iterator failed before all of its points were sent.
+type IteratorEnd struct {
+	Err error
+}
+
+// MarshalBinary encodes r to a binary format.
+func (r *IteratorEnd) MarshalBinary() ([]byte, error) {
+	var pb internal.IteratorEnd
+	pb.Err, pb.Limit, pb.LimitMax = encodeIteratorError(r.Err)
+	return proto.Marshal(&pb)
+}
+
+// UnmarshalBinary decodes data into r.
+func (r *IteratorEnd) UnmarshalBinary(data []byte) error {
+	var pb internal.IteratorEnd
+	if err := proto.Unmarshal(data, &pb); err != nil {
+		return err
+	}
+	r.Err = decodeIteratorError(pb.Err, pb.Limit, pb.GetLimitMax())
+	return nil
+}
+
+// encodeIteratorError returns the protobuf fields of an iterator error.
+// The limit fields are only set for a *QueryLimitError.
+func encodeIteratorError(err error) (msg, limit *string, max *int64) {
+	if err == nil {
+		return nil, nil, nil
+	}
+	if e, ok := err.(*QueryLimitError); ok {
+		return proto.String(e.Error()), proto.String(e.Limit), proto.Int64(e.Max)
+	}
+	return proto.String(err.Error()), nil, nil
+}
+
+// decodeIteratorError returns the iterator error encoded in the protobuf
+// fields, as a *QueryLimitError if limit is set.
+func decodeIteratorError(msg, limit *string, max int64) error {
+	if limit != nil {
+		return &QueryLimitError{Limit: *limit, Max: max}
+	} else if msg != nil {
+		return errors.New(*msg)
 	}
 	return nil
 }
 
 // FieldDimensionsRequest represents a request to retrieve unique fields & dimensions.
 type FieldDimensionsRequest struct {
-	ShardIDs []uint64
-	Sources  influxql.Sources
+	ShardIDs  []uint64
+	Sources   influxql.Sources
+	RequestID string
 }
 
 // MarshalBinary encodes r to a binary format.
@@ -256,8 +588,9 @@ func (r *FieldDimensionsRequest) MarshalBinary() ([]byte, error) {
 		return nil, err
 	}
 	return proto.Marshal(&internal.FieldDimensionsRequest{
-		ShardIDs: r.ShardIDs,
-		Sources:  buf,
+		ShardIDs:  r.ShardIDs,
+		Sources:   buf,
+		RequestID: proto.String(r.RequestID),
 	})
 }
 
@@ -269,6 +602,7 @@ func (r *FieldDimensionsRequest) UnmarshalBinary(data []byte) error {
 	}
 
 	r.ShardIDs = pb.GetShardIDs()
+	r.RequestID = pb.GetRequestID()
 	if err := r.Sources.UnmarshalBinary(pb.GetSources()); err != nil {
 		return err
 	}
@@ -469,9 +803,16 @@ func (csr *CreateShardSnapshotResponse) UnmarshalBinary(data []byte) error {
 	return nil
 }
 
+// RestoreShardRequest asks a data node to create the shard ShardID of
+// Database and Policy and restore the backup of SourceShardID into it. The
+// backup of Size bytes follows the request as RestoreShardDataMessage
+// records, ending with an empty record.
 type RestoreShardRequest struct {
-	Size    uint64
-	ShardID uint64
+	Size          uint64
+	ShardID       uint64
+	Database      string
+	Policy        string
+	SourceShardID uint64
 }
 
 func (m *RestoreShardRequest) MarshalBinary() ([]byte, error) {
@@ -479,6 +820,9 @@ func (m *RestoreShardRequest) MarshalBinary() ([]byte, error) {
 
 	pb.Size_ = proto.Uint64(m.Size)
 	pb.ShardID = proto.Uint64(m.ShardID)
+	pb.Database = proto.String(m.Database)
+	pb.Policy = proto.String(m.Policy)
+	pb.SourceShardID = proto.Uint64(m.SourceShardID)
 
 	return proto.Marshal(&pb)
 }
@@ -492,17 +836,24 @@ func (m *RestoreShardRequest) UnmarshalBinary(data []byte) error {
 
 	m.Size = pb.GetSize_()
 	m.ShardID = pb.GetShardID()
+	m.Database = pb.GetDatabase()
+	m.Policy = pb.GetPolicy()
+	m.SourceShardID = pb.GetSourceShardID()
 
 	return nil
 }
 
+// RestoreShardResponse reports whether a shard was restored. Code is
+// CodeChecksumMismatch if the streamed backup was corrupted.
 type RestoreShardResponse struct {
-	Err string
+	Err  string
+	Code ErrorCode
 }
 
 func (m *RestoreShardResponse) MarshalBinary() ([]byte, error) {
 	var pb internal.RestoreShardResponse
 	pb.Err = proto.String(m.Err)
+	pb.Code = proto.Int32(int32(m.Code))
 
 	return proto.Marshal(&pb)
 }
@@ -514,6 +865,7 @@ func (m *RestoreShardResponse) UnmarshalBinary(data []byte) error {
 	}
 
 	m.Err = pb.GetErr()
+	m.Code = ErrorCode(pb.GetCode())
 
 	return nil
 }
@@ -646,10 +998,12 @@ func (rsr *RemoveShardRequest) UnmarshalBinary(data []byte) error {
 }
 
 type RemoveShardResponse struct {
+	Err string
 }
 
 func (rsr *RemoveShardResponse) MarshalBinary() ([]byte, error) {
 	var pb internal.RemoveShardResponse
+	pb.Err = proto.String(rsr.Err)
 
 	return proto.Marshal(&pb)
 }
@@ -660,6 +1014,8 @@ func (rsr *RemoveShardResponse) UnmarshalBinary(data []byte) error {
 		return err
 	}
 
+	rsr.Err = pb.GetErr()
+
 	return nil
 }
 
@@ -712,10 +1068,11 @@ func (csr *CopyShardStatusResponse) UnmarshalBinary(data []byte) error {
 }
 
 type CopyShardRequest struct {
-	Source  string
-	Dest    string
-	ShardID uint64
-	Policy  string
+	Source   string
+	Dest     string
+	ShardID  uint64
+	Database string
+	Policy   string
 }
 
 func (m *CopyShardRequest) MarshalBinary() ([]byte, error) {
@@ -736,6 +1093,7 @@ func (m *CopyShardRequest) MarshalBinary() ([]byte, error) {
 	pb.Source = proto.String(m.Source)
 	pb.Dest = proto.String(m.Dest)
 	pb.ShardID = proto.Uint64(m.ShardID)
+	pb.Database = proto.String(m.Database)
 	pb.Policy = proto.String(m.Policy)
 
 	return proto.Marshal(&pb)
@@ -750,6 +1108,7 @@ func (m *CopyShardRequest) UnmarshalBinary(data []byte) error {
 	m.Source = pb.GetSource()
 	m.Dest = pb.GetDest()
 	m.ShardID = pb.GetShardID()
+	m.Database = pb.GetDatabase()
 	m.Policy = pb.GetPolicy()
 
 	return nil
@@ -826,3 +1185,1591 @@ func (ksr *KillCopyShardResponse) UnmarshalBinary(data []byte) error {
 
 	return nil
 }
+
+// ShardInfo describes a shard and the nodes that own it.
+type ShardInfo struct {
+	ID           uint64
+	Database     string
+	Policy       string
+	ShardGroupID uint64
+	StartTime    time.Time
+	EndTime      time.Time
+	Owners       []uint64
+}
+
+type ShowShardsRequest struct {
+}
+
+func (ssr *ShowShardsRequest) MarshalBinary() ([]byte, error) {
+	var pb internal.ShowShardsRequest
+
+	return proto.Marshal(&pb)
+}
+
+func (ssr *ShowShardsRequest) UnmarshalBinary(data []byte) error {
+	var pb internal.ShowShardsRequest
+	if err := proto.Unmarshal(data, &pb); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+type ShowShardsResponse struct {
+	Err    string
+	Shards []ShardInfo
+}
+
+func (ssr *ShowShardsResponse) MarshalBinary() ([]byte, error) {
+	var pb internal.ShowShardsResponse
+	pb.Err = proto.String(ssr.Err)
+	pb.Shards = make([]*internal.ShardInfo, len(ssr.Shards))
+	for i, si := range ssr.Shards {
+		pb.Shards[i] = &internal.ShardInfo{
+			ID:           proto.Uint64(si.ID),
+			Database:     proto.String(si.Database),
+			Policy:       proto.String(si.Policy),
+			ShardGroupID: proto.Uint64(si.ShardGroupID),
+			StartTime:    proto.Int64(si.StartTime.UnixNano()),
+			EndTime:      proto.Int64(si.EndTime.UnixNano()),
+			Owners:       si.Owners,
+		}
+	}
+
+	return proto.Marshal(&pb)
+}
+
+func (ssr *ShowShardsResponse) UnmarshalBinary(data []byte) error {
+	var pb internal.ShowShardsResponse
+	if err := proto.Unmarshal(data, &pb); err != nil {
+		return err
+	}
+
+	ssr.Err = pb.GetErr()
+	ssr.Shards = make([]ShardInfo, len(pb.GetShards()))
+	for i, si := range pb.GetShards() {
+		ssr.Shards[i] = ShardInfo{
+			ID:           si.GetID(),
+			Database:     si.GetDatabase(),
+			Policy:       si.GetPolicy(),
+			ShardGroupID: si.GetShardGroupID(),
+			StartTime:    time.Unix(0, si.GetStartTime()).UTC(),
+			EndTime:      time.Unix(0, si.GetEndTime()).UTC(),
+			Owners:       si.GetOwners(),
+		}
+	}
+
+	return nil
+}
+
+type BackupShardRequest struct {
+	ShardID uint64
+	Since   time.Time
+
+	// BaseSnapshotID requests an incremental snapshot of the files changed
+	// since the snapshot with this ID, if the node still knows it.
+	BaseSnapshotID uint64
+
+	// Checksums requests the checksums of the files of the snapshot to be
+	// streamed after them.
+	Checksums bool
+}
+
+func (bsr *BackupShardRequest) MarshalBinary() ([]byte, error) {
+	var pb internal.BackupShardRequest
+	pb.ShardID = proto.Uint64(bsr.ShardID)
+	pb.Since = proto.Int64(bsr.Since.UnixNano())
+	if bsr.BaseSnapshotID != 0 {
+		pb.BaseSnapshotID = proto.Uint64(bsr.BaseSnapshotID)
+	}
+	if bsr.Checksums {
+		pb.Checksums = proto.Bool(true)
+	}
+
+	return proto.Marshal(&pb)
+}
+
+func (bsr *BackupShardRequest) UnmarshalBinary(data []byte) error {
+	var pb internal.BackupShardRequest
+	if err := proto.Unmarshal(data, &pb); err != nil {
+		return err
+	}
+
+	bsr.ShardID = pb.GetShardID()
+	bsr.Since = time.Unix(0, pb.GetSince()).UTC()
+	bsr.BaseSnapshotID = pb.GetBaseSnapshotID()
+	bsr.Checksums = pb.GetChecksums()
+
+	return nil
+}
+
+// BackupShardResponse precedes the snapshot streamed by the node.
+// BaseSnapshotID is the snapshot an incremental snapshot was taken since, or
+// zero if the snapshot is full.
+type BackupShardResponse struct {
+	Err            string
+	SnapshotID     uint64
+	BaseSnapshotID uint64
+}
+
+func (bsr *BackupShardResponse) MarshalBinary() ([]byte, error) {
+	var pb internal.BackupShardResponse
+	pb.Err = proto.String(bsr.Err)
+	pb.SnapshotID = proto.Uint64(bsr.SnapshotID)
+	pb.BaseSnapshotID = proto.Uint64(bsr.BaseSnapshotID)
+
+	return proto.Marshal(&pb)
+}
+
+func (bsr *BackupShardResponse) UnmarshalBinary(data []byte) error {
+	var pb internal.BackupShardResponse
+	if err := proto.Unmarshal(data, &pb); err != nil {
+		return err
+	}
+
+	bsr.Err = pb.GetErr()
+	bsr.SnapshotID = pb.GetSnapshotID()
+	bsr.BaseSnapshotID = pb.GetBaseSnapshotID()
+
+	return nil
+}
+
+type TruncateShardsRequest struct {
+	Delay time.Duration
+
+	// Time is the time at which shard groups are truncated. If unset, shard
+	// groups are truncated Delay after the request is received.
+	Time time.Time
+}
+
+func (tsr *TruncateShardsRequest) MarshalBinary() ([]byte, error) {
+	var pb internal.TruncateShardsRequest
+	pb.Delay = proto.Int64(int64(tsr.Delay))
+	pb.Time = proto.Int64(marshalTime(tsr.Time))
+
+	return proto.Marshal(&pb)
+}
+
+func (tsr *TruncateShardsRequest) UnmarshalBinary(data []byte) error {
+	var pb internal.TruncateShardsRequest
+	if err := proto.Unmarshal(data, &pb); err != nil {
+		return err
+	}
+
+	tsr.Delay = time.Duration(pb.GetDelay())
+	tsr.Time = unmarshalTime(pb.GetTime())
+
+	return nil
+}
+
+type TruncateShardsResponse struct {
+	Err string
+}
+
+func (tsr *TruncateShardsResponse) MarshalBinary() ([]byte, error) {
+	var pb internal.TruncateShardsResponse
+	pb.Err = proto.String(tsr.Err)
+
+	return proto.Marshal(&pb)
+}
+
+func (tsr *TruncateShardsResponse) UnmarshalBinary(data []byte) error {
+	var pb internal.TruncateShardsResponse
+	if err := proto.Unmarshal(data, &pb); err != nil {
+		return err
+	}
+
+	tsr.Err = pb.GetErr()
+
+	return nil
+}
+
+type RemoveDataNodeRequest struct {
+	TCPHost string
+	Force   bool
+}
+
+func (rdr *RemoveDataNodeRequest) MarshalBinary() ([]byte, error) {
+	var pb internal.RemoveDataNodeRequest
+
+	if rdr.TCPHost == "" {
+		return nil, fmt.Errorf("TCPHost cannot be empty string")
+	}
+	pb.TCPHost = proto.String(rdr.TCPHost)
+	pb.Force = proto.Bool(rdr.Force)
+
+	return proto.Marshal(&pb)
+}
+
+func (rdr *RemoveDataNodeRequest) UnmarshalBinary(data []byte) error {
+	var pb internal.RemoveDataNodeRequest
+	if err := proto.Unmarshal(data, &pb); err != nil {
+		return err
+	}
+
+	rdr.TCPHost = pb.GetTCPHost()
+	rdr.Force = pb.GetForce()
+
+	return nil
+}
+
+type RemoveDataNodeResponse struct {
+	Err string
+}
+
+func (rdr *RemoveDataNodeResponse) MarshalBinary() ([]byte, error) {
+	var pb internal.RemoveDataNodeResponse
+	pb.Err = proto.String(rdr.Err)
+
+	return proto.Marshal(&pb)
+}
+
+func (rdr *RemoveDataNodeResponse) UnmarshalBinary(data []byte) error {
+	var pb internal.RemoveDataNodeResponse
+	if err := proto.Unmarshal(data, &pb); err != nil {
+		return err
+	}
+
+	rdr.Err = pb.GetErr()
+
+	return nil
+}
+
+type UpdateDataNodeRequest struct {
+	OldTCPHost string
+	NewTCPHost string
+}
+
+func (udr *UpdateDataNodeRequest) MarshalBinary() ([]byte, error) {
+	var pb internal.UpdateDataNodeRequest
+
+	if udr.OldTCPHost == "" {
+		return nil, fmt.Errorf("OldTCPHost cannot be empty string")
+	}
+	if udr.NewTCPHost == "" {
+		return nil, fmt.Errorf("NewTCPHost cannot be empty string")
+	}
+	pb.OldTCPHost = proto.String(udr.OldTCPHost)
+	pb.NewTCPHost = proto.String(udr.NewTCPHost)
+
+	return proto.Marshal(&pb)
+}
+
+func (udr *UpdateDataNodeRequest) UnmarshalBinary(data []byte) error {
+	var pb internal.UpdateDataNodeRequest
+	if err := proto.Unmarshal(data, &pb); err != nil {
+		return err
+	}
+
+	udr.OldTCPHost = pb.GetOldTCPHost()
+	udr.NewTCPHost = pb.GetNewTCPHost()
+
+	return nil
+}
+
+type UpdateDataNodeResponse struct {
+	Err string
+}
+
+func (udr *UpdateDataNodeResponse) MarshalBinary() ([]byte, error) {
+	var pb internal.UpdateDataNodeResponse
+	pb.Err = proto.String(udr.Err)
+
+	return proto.Marshal(&pb)
+}
+
+func (udr *UpdateDataNodeResponse) UnmarshalBinary(data []byte) error {
+	var pb internal.UpdateDataNodeResponse
+	if err := proto.Unmarshal(data, &pb); err != nil {
+		return err
+	}
+
+	udr.Err = pb.GetErr()
+
+	return nil
+}
+
+type AuthStateRequest struct {
+}
+
+func (asr *AuthStateRequest) MarshalBinary() ([]byte, error) {
+	var pb internal.AuthStateRequest
+
+	return proto.Marshal(&pb)
+}
+
+func (asr *AuthStateRequest) UnmarshalBinary(data []byte) error {
+	var pb internal.AuthStateRequest
+	if err := proto.Unmarshal(data, &pb); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+type AuthStateResponse struct {
+	Err   string
+	Hash  string
+	UserN int
+}
+
+func (asr *AuthStateResponse) MarshalBinary() ([]byte, error) {
+	var pb internal.AuthStateResponse
+	pb.Err = proto.String(asr.Err)
+	pb.Hash = proto.String(asr.Hash)
+	pb.UserN = proto.Uint64(uint64(asr.UserN))
+
+	return proto.Marshal(&pb)
+}
+
+func (asr *AuthStateResponse) UnmarshalBinary(data []byte) error {
+	var pb internal.AuthStateResponse
+	if err := proto.Unmarshal(data, &pb); err != nil {
+		return err
+	}
+
+	asr.Err = pb.GetErr()
+	asr.Hash = pb.GetHash()
+	asr.UserN = int(pb.GetUserN())
+
+	return nil
+}
+
+type PauseReplicationRequest struct {
+	TCPHost string
+	Resume  bool
+}
+
+func (prr *PauseReplicationRequest) MarshalBinary() ([]byte, error) {
+	var pb internal.PauseReplicationRequest
+
+	if prr.TCPHost == "" {
+		return nil, fmt.Errorf("TCPHost cannot be empty string")
+	}
+	pb.TCPHost = proto.String(prr.TCPHost)
+	pb.Resume = proto.Bool(prr.Resume)
+
+	return proto.Marshal(&pb)
+}
+
+func (prr *PauseReplicationRequest) UnmarshalBinary(data []byte) error {
+	var pb internal.PauseReplicationRequest
+	if err := proto.Unmarshal(data, &pb); err != nil {
+		return err
+	}
+
+	prr.TCPHost = pb.GetTCPHost()
+	prr.Resume = pb.GetResume()
+
+	return nil
+}
+
+type PauseReplicationResponse struct {
+	Err string
+}
+
+func (prr *PauseReplicationResponse) MarshalBinary() ([]byte, error) {
+	var pb internal.PauseReplicationResponse
+	pb.Err = proto.String(prr.Err)
+
+	return proto.Marshal(&pb)
+}
+
+func (prr *PauseReplicationResponse) UnmarshalBinary(data []byte) error {
+	var pb internal.PauseReplicationResponse
+	if err := proto.Unmarshal(data, &pb); err != nil {
+		return err
+	}
+
+	prr.Err = pb.GetErr()
+
+	return nil
+}
+
+type ExportMetaDataRequest struct {
+}
+
+func (emr *ExportMetaDataRequest) MarshalBinary() ([]byte, error) {
+	var pb internal.ExportMetaDataRequest
+
+	return proto.Marshal(&pb)
+}
+
+func (emr *ExportMetaDataRequest) UnmarshalBinary(data []byte) error {
+	var pb internal.ExportMetaDataRequest
+	if err := proto.Unmarshal(data, &pb); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+type ExportMetaDataResponse struct {
+	Data      []byte
+	ClusterID uint64
+	Index     uint64
+	Err       string
+}
+
+func (emr *ExportMetaDataResponse) MarshalBinary() ([]byte, error) {
+	var pb internal.ExportMetaDataResponse
+	pb.Data = emr.Data
+	if pb.Data == nil {
+		pb.Data = []byte{}
+	}
+	pb.ClusterID = proto.Uint64(emr.ClusterID)
+	pb.Index = proto.Uint64(emr.Index)
+	pb.Err = proto.String(emr.Err)
+
+	return proto.Marshal(&pb)
+}
+
+func (emr *ExportMetaDataResponse) UnmarshalBinary(data []byte) error {
+	var pb internal.ExportMetaDataResponse
+	if err := proto.Unmarshal(data, &pb); err != nil {
+		return err
+	}
+
+	emr.Data = pb.GetData()
+	emr.ClusterID = pb.GetClusterID()
+	emr.Index = pb.GetIndex()
+	emr.Err = pb.GetErr()
+
+	return nil
+}
+
+// ShardStatus describes the state of a shard stored on a data node.
+// Corrupt is why the shard's data files last failed verification, if they
+// did.
+type ShardStatus struct {
+	ID           uint64
+	Database     string
+	Policy       string
+	Size         int64
+	SeriesN      int64
+	LastModified time.Time
+	LastWrite    time.Time
+	Cold         bool
+	Corrupt      string
+}
+
+type ShardStatusRequest struct {
+	ShardIDs []uint64
+}
+
+func (ssr *ShardStatusRequest) MarshalBinary() ([]byte, error) {
+	var pb internal.ShardStatusRequest
+	pb.ShardIDs = ssr.ShardIDs
+
+	return proto.Marshal(&pb)
+}
+
+func (ssr *ShardStatusRequest) UnmarshalBinary(data []byte) error {
+	var pb internal.ShardStatusRequest
+	if err := proto.Unmarshal(data, &pb); err != nil {
+		return err
+	}
+
+	ssr.ShardIDs = pb.GetShardIDs()
+
+	return nil
+}
+
+// NodeLoad describes the backpressure on the local store of a data node.
+// Overloaded is the reason the node rejects writes, if it does.
+type NodeLoad struct {
+	WALBytes       int64 // size of the WAL segments not yet compacted
+	CacheBytes     int64 // size of the in-memory caches
+	CompactionDebt int64 // cache snapshots and TSM compactions in progress
+	Overloaded     string
+}
+
+type ShardStatusResponse struct {
+	Err    string
+	Shards []ShardStatus
+	Load   NodeLoad
+}
+
+func (ssr *ShardStatusResponse) MarshalBinary() ([]byte, error) {
+	var pb internal.ShardStatusResponse
+	pb.Err = proto.String(ssr.Err)
+	pb.Shards = make([]*internal.ShardStatus, len(ssr.Shards))
+	for i, ss := range ssr.Shards {
+		pb.Shards[i] = &internal.ShardStatus{
+			ID:           proto.Uint64(ss.ID),
+			Database:     proto.String(ss.Database),
+			Policy:       proto.String(ss.Policy),
+			Size_:        proto.Int64(ss.Size),
+			SeriesN:      proto.Int64(ss.SeriesN),
+			LastModified: proto.Int64(marshalTime(ss.LastModified)),
+			LastWrite:    proto.Int64(marshalTime(ss.LastWrite)),
+			Cold:         proto.Bool(ss.Cold),
+			Corrupt:      proto.String(ss.Corrupt),
+		}
+	}
+	pb.Load = &internal.NodeLoad{
+		WALBytes:       proto.Int64(ssr.Load.WALBytes),
+		CacheBytes:     proto.Int64(ssr.Load.CacheBytes),
+		CompactionDebt: proto.Int64(ssr.Load.CompactionDebt),
+		Overloaded:     proto.String(ssr.Load.Overloaded),
+	}
+
+	return proto.Marshal(&pb)
+}
+
+func (ssr *ShardStatusResponse) UnmarshalBinary(data []byte) error {
+	var pb internal.ShardStatusResponse
+	if err := proto.Unmarshal(data, &pb); err != nil {
+		return err
+	}
+
+	ssr.Err = pb.GetErr()
+	ssr.Shards = make([]ShardStatus, len(pb.GetShards()))
+	for i, ss := range pb.GetShards() {
+		ssr.Shards[i] = ShardStatus{
+			ID:           ss.GetID(),
+			Database:     ss.GetDatabase(),
+			Policy:       ss.GetPolicy(),
+			Size:         ss.GetSize_(),
+			SeriesN:      ss.GetSeriesN(),
+			LastModified: unmarshalTime(ss.GetLastModified()),
+			LastWrite:    unmarshalTime(ss.GetLastWrite()),
+			Cold:         ss.GetCold(),
+			Corrupt:      ss.GetCorrupt(),
+		}
+	}
+	if load := pb.GetLoad(); load != nil {
+		ssr.Load = NodeLoad{
+			WALBytes:       load.GetWALBytes(),
+			CacheBytes:     load.GetCacheBytes(),
+			CompactionDebt: load.GetCompactionDebt(),
+			Overloaded:     load.GetOverloaded(),
+		}
+	}
+
+	return nil
+}
+
+// ShardBounds describes the data stored in a shard on a data node, so that
+// queries can skip shards that cannot contain points they select.
+type ShardBounds struct {
+	ID uint64
+
+	// Measurements lists the requested measurements that have points in
+	// the shard, with the time of their first and last point.
+	Measurements []MeasurementBounds
+}
+
+// MeasurementBounds is the time range of a measurement's points in a shard.
+type MeasurementBounds struct {
+	Name    string
+	MinTime time.Time
+	MaxTime time.Time
+}
+
+type ShardBoundsRequest struct {
+	ShardIDs     []uint64
+	Measurements []string
+}
+
+func (sbr *ShardBoundsRequest) MarshalBinary() ([]byte, error) {
+	var pb internal.ShardBoundsRequest
+	pb.ShardIDs = sbr.ShardIDs
+	pb.Measurements = sbr.Measurements
+
+	return proto.Marshal(&pb)
+}
+
+func (sbr *ShardBoundsRequest) UnmarshalBinary(data []byte) error {
+	var pb internal.ShardBoundsRequest
+	if err := proto.Unmarshal(data, &pb); err != nil {
+		return err
+	}
+
+	sbr.ShardIDs = pb.GetShardIDs()
+	sbr.Measurements = pb.GetMeasurements()
+
+	return nil
+}
+
+type ShardBoundsResponse struct {
+	Err    string
+	Shards []ShardBounds
+}
+
+func (sbr *ShardBoundsResponse) MarshalBinary() ([]byte, error) {
+	var pb internal.ShardBoundsResponse
+	pb.Err = proto.String(sbr.Err)
+	pb.Shards = make([]*internal.ShardBounds, len(sbr.Shards))
+	for i, sb := range sbr.Shards {
+		pb.Shards[i] = &internal.ShardBounds{
+			ID:           proto.Uint64(sb.ID),
+			Measurements: make([]*internal.MeasurementBounds, len(sb.Measurements)),
+		}
+		for j, mb := range sb.Measurements {
+			pb.Shards[i].Measurements[j] = &internal.MeasurementBounds{
+				Name:    proto.String(mb.Name),
+				MinTime: proto.Int64(mb.MinTime.UnixNano()),
+				MaxTime: proto.Int64(mb.MaxTime.UnixNano()),
+			}
+		}
+	}
+
+	return proto.Marshal(&pb)
+}
+
+func (sbr *ShardBoundsResponse) UnmarshalBinary(data []byte) error {
+	var pb internal.ShardBoundsResponse
+	if err := proto.Unmarshal(data, &pb); err != nil {
+		return err
+	}
+
+	sbr.Err = pb.GetErr()
+	sbr.Shards = make([]ShardBounds, len(pb.GetShards()))
+	for i, sb := range pb.GetShards() {
+		sbr.Shards[i] = ShardBounds{
+			ID:           sb.GetID(),
+			Measurements: make([]MeasurementBounds, len(sb.GetMeasurements())),
+		}
+		for j, mb := range sb.GetMeasurements() {
+			sbr.Shards[i].Measurements[j] = MeasurementBounds{
+				Name:    mb.GetName(),
+				MinTime: time.Unix(0, mb.GetMinTime()).UTC(),
+				MaxTime: time.Unix(0, mb.GetMaxTime()).UTC(),
+			}
+		}
+	}
+
+	return nil
+}
+
+// WritePointsRequest forwards a whole write to another data node, which maps
+// the points to shards and writes them as if it had received the write from
+// a client.
+type WritePointsRequest struct {
+	Database         string
+	RetentionPolicy  string
+	ConsistencyLevel models.ConsistencyLevel
+	RequestID        string
+	Timeout          time.Duration
+	Points           *EncodedPoints
+}
+
+func (wpr *WritePointsRequest) MarshalBinary() ([]byte, error) {
+	var pb internal.WritePointsRequest
+	pb.Database = proto.String(wpr.Database)
+	pb.RetentionPolicy = proto.String(wpr.RetentionPolicy)
+	pb.ConsistencyLevel = proto.Int32(int32(wpr.ConsistencyLevel))
+	pb.RequestID = proto.String(wpr.RequestID)
+	pb.Timeout = proto.Int64(int64(wpr.Timeout))
+	if wpr.Points != nil {
+		pb.Points = wpr.Points.points
+	}
+
+	return proto.Marshal(&pb)
+}
+
+func (wpr *WritePointsRequest) UnmarshalBinary(data []byte) error {
+	var pb internal.WritePointsRequest
+	if err := proto.Unmarshal(data, &pb); err != nil {
+		return err
+	}
+
+	wpr.Database = pb.GetDatabase()
+	wpr.RetentionPolicy = pb.GetRetentionPolicy()
+	wpr.ConsistencyLevel = models.ConsistencyLevel(pb.GetConsistencyLevel())
+	wpr.RequestID = pb.GetRequestID()
+	wpr.Timeout = time.Duration(pb.GetTimeout())
+	wpr.Points = NewEncodedPoints(pb.GetPoints())
+
+	return nil
+}
+
+// WritePointsResponse reports whether a forwarded write succeeded. Code is
+// CodeOK if it did.
+type WritePointsResponse struct {
+	Code ErrorCode
+	Err  string
+}
+
+func (wpr *WritePointsResponse) MarshalBinary() ([]byte, error) {
+	var pb internal.WritePointsResponse
+	pb.Code = proto.Int32(int32(wpr.Code))
+	pb.Err = proto.String(wpr.Err)
+
+	return proto.Marshal(&pb)
+}
+
+func (wpr *WritePointsResponse) UnmarshalBinary(data []byte) error {
+	var pb internal.WritePointsResponse
+	if err := proto.Unmarshal(data, &pb); err != nil {
+		return err
+	}
+
+	wpr.Code = ErrorCode(pb.GetCode())
+	wpr.Err = pb.GetErr()
+
+	return nil
+}
+
+// AcquireLeaseRequest asks a data node for the lease Name on behalf of the
+// node NodeID.
+type AcquireLeaseRequest struct {
+	Name   string
+	NodeID uint64
+}
+
+func (alr *AcquireLeaseRequest) MarshalBinary() ([]byte, error) {
+	var pb internal.AcquireLeaseRequest
+	pb.Name = proto.String(alr.Name)
+	pb.NodeID = proto.Uint64(alr.NodeID)
+
+	return proto.Marshal(&pb)
+}
+
+func (alr *AcquireLeaseRequest) UnmarshalBinary(data []byte) error {
+	var pb internal.AcquireLeaseRequest
+	if err := proto.Unmarshal(data, &pb); err != nil {
+		return err
+	}
+
+	alr.Name = pb.GetName()
+	alr.NodeID = pb.GetNodeID()
+
+	return nil
+}
+
+// AcquireLeaseResponse returns the current holder of a lease and when the
+// lease expires. The lease was acquired if Owner is the requesting node.
+type AcquireLeaseResponse struct {
+	Err        string
+	Owner      uint64
+	Expiration time.Time
+}
+
+func (alr *AcquireLeaseResponse) MarshalBinary() ([]byte, error) {
+	var pb internal.AcquireLeaseResponse
+	pb.Err = proto.String(alr.Err)
+	pb.Owner = proto.Uint64(alr.Owner)
+	pb.Expiration = proto.Int64(marshalTime(alr.Expiration))
+
+	return proto.Marshal(&pb)
+}
+
+func (alr *AcquireLeaseResponse) UnmarshalBinary(data []byte) error {
+	var pb internal.AcquireLeaseResponse
+	if err := proto.Unmarshal(data, &pb); err != nil {
+		return err
+	}
+
+	alr.Err = pb.GetErr()
+	alr.Owner = pb.GetOwner()
+	alr.Expiration = unmarshalTime(pb.GetExpiration())
+
+	return nil
+}
+
+// UploadShardSnapshotRequest asks a data node to upload a snapshot of the
+// shard ShardID to its snapshot sink as Key.
+type UploadShardSnapshotRequest struct {
+	ShardID uint64
+	Key     string
+}
+
+func (usr *UploadShardSnapshotRequest) MarshalBinary() ([]byte, error) {
+	var pb internal.UploadShardSnapshotRequest
+	pb.ShardID = proto.Uint64(usr.ShardID)
+	pb.Key = proto.String(usr.Key)
+
+	return proto.Marshal(&pb)
+}
+
+func (usr *UploadShardSnapshotRequest) UnmarshalBinary(data []byte) error {
+	var pb internal.UploadShardSnapshotRequest
+	if err := proto.Unmarshal(data, &pb); err != nil {
+		return err
+	}
+
+	usr.ShardID = pb.GetShardID()
+	usr.Key = pb.GetKey()
+
+	return nil
+}
+
+// UploadShardSnapshotResponse returns the size of the uploaded snapshot.
+type UploadShardSnapshotResponse struct {
+	Err  string
+	Size int64
+}
+
+func (usr *UploadShardSnapshotResponse) MarshalBinary() ([]byte, error) {
+	var pb internal.UploadShardSnapshotResponse
+	pb.Err = proto.String(usr.Err)
+	pb.Size_ = proto.Int64(usr.Size)
+
+	return proto.Marshal(&pb)
+}
+
+func (usr *UploadShardSnapshotResponse) UnmarshalBinary(data []byte) error {
+	var pb internal.UploadShardSnapshotResponse
+	if err := proto.Unmarshal(data, &pb); err != nil {
+		return err
+	}
+
+	usr.Err = pb.GetErr()
+	usr.Size = pb.GetSize_()
+
+	return nil
+}
+
+// ReplaceDataNodeRequest asks a data node to hand the shards of the data node
+// at OldTCPHost over to the data node at NewTCPHost, and remove the old node.
+type ReplaceDataNodeRequest struct {
+	OldTCPHost string
+	NewTCPHost string
+}
+
+func (rdr *ReplaceDataNodeRequest) MarshalBinary() ([]byte, error) {
+	var pb internal.ReplaceDataNodeRequest
+
+	if rdr.OldTCPHost == "" {
+		return nil, fmt.Errorf("OldTCPHost cannot be empty string")
+	}
+	if rdr.NewTCPHost == "" {
+		return nil, fmt.Errorf("NewTCPHost cannot be empty string")
+	}
+	pb.OldTCPHost = proto.String(rdr.OldTCPHost)
+	pb.NewTCPHost = proto.String(rdr.NewTCPHost)
+
+	return proto.Marshal(&pb)
+}
+
+func (rdr *ReplaceDataNodeRequest) UnmarshalBinary(data []byte) error {
+	var pb internal.ReplaceDataNodeRequest
+	if err := proto.Unmarshal(data, &pb); err != nil {
+		return err
+	}
+
+	rdr.OldTCPHost = pb.GetOldTCPHost()
+	rdr.NewTCPHost = pb.GetNewTCPHost()
+
+	return nil
+}
+
+// ReplaceDataNodeResponse returns the IDs of the shards copied to the new
+// data node.
+type ReplaceDataNodeResponse struct {
+	Err      string
+	ShardIDs []uint64
+}
+
+func (rdr *ReplaceDataNodeResponse) MarshalBinary() ([]byte, error) {
+	var pb internal.ReplaceDataNodeResponse
+	pb.Err = proto.String(rdr.Err)
+	pb.ShardIDs = rdr.ShardIDs
+
+	return proto.Marshal(&pb)
+}
+
+func (rdr *ReplaceDataNodeResponse) UnmarshalBinary(data []byte) error {
+	var pb internal.ReplaceDataNodeResponse
+	if err := proto.Unmarshal(data, &pb); err != nil {
+		return err
+	}
+
+	rdr.Err = pb.GetErr()
+	rdr.ShardIDs = pb.GetShardIDs()
+
+	return nil
+}
+
+// RedirectHintedHandoffRequest asks a data node to send the writes it queued
+// for the node FromNodeID to the node ToNodeID instead.
+type RedirectHintedHandoffRequest struct {
+	FromNodeID uint64
+	ToNodeID   uint64
+}
+
+func (rhr *RedirectHintedHandoffRequest) MarshalBinary() ([]byte, error) {
+	var pb internal.RedirectHintedHandoffRequest
+	pb.FromNodeID = proto.Uint64(rhr.FromNodeID)
+	pb.ToNodeID = proto.Uint64(rhr.ToNodeID)
+
+	return proto.Marshal(&pb)
+}
+
+func (rhr *RedirectHintedHandoffRequest) UnmarshalBinary(data []byte) error {
+	var pb internal.RedirectHintedHandoffRequest
+	if err := proto.Unmarshal(data, &pb); err != nil {
+		return err
+	}
+
+	rhr.FromNodeID = pb.GetFromNodeID()
+	rhr.ToNodeID = pb.GetToNodeID()
+
+	return nil
+}
+
+type RedirectHintedHandoffResponse struct {
+	Err string
+}
+
+func (rhr *RedirectHintedHandoffResponse) MarshalBinary() ([]byte, error) {
+	var pb internal.RedirectHintedHandoffResponse
+	pb.Err = proto.String(rhr.Err)
+
+	return proto.Marshal(&pb)
+}
+
+func (rhr *RedirectHintedHandoffResponse) UnmarshalBinary(data []byte) error {
+	var pb internal.RedirectHintedHandoffResponse
+	if err := proto.Unmarshal(data, &pb); err != nil {
+		return err
+	}
+
+	rhr.Err = pb.GetErr()
+
+	return nil
+}
+
+// ProtocolVersion is the version of the cluster protocol spoken by this build.
+// Nodes refuse connections from peers whose version is below
+// MinProtocolVersion.
+const (
+	ProtocolVersion    = 1
+	MinProtocolVersion = 1
+)
+
+// Feature is a bitmask of optional parts of the cluster protocol. Peers only
+// use the features both of them support.
+type Feature uint64
+
+const (
+	// FeatureCompression compresses requests and responses. It is reserved
+	// and not yet supported by any build.
+	FeatureCompression Feature = 1 << iota
+
+	// FeaturePipelining carries many requests at once over one multiplexed
+	// connection.
+	FeaturePipelining
+
+	// FeatureProtobuf encodes requests and responses as protocol buffers.
+	FeatureProtobuf
+
+	// FeatureChecksum follows each record with its CRC32, so that records
+	// corrupted in transit are rejected.
+	FeatureChecksum
+)
+
+// SupportedFeatures are the features supported by this build.
+const SupportedFeatures = FeaturePipelining | FeatureProtobuf | FeatureChecksum
+
+// Has returns true if f includes every feature of other.
+func (f Feature) Has(other Feature) bool { return f&other == other }
+
+// String returns the names of the features in f.
+func (f Feature) String() string {
+	var names []string
+	for _, n := range []struct {
+		f    Feature
+		name string
+	}{
+		{FeatureCompression, "compression"},
+		{FeaturePipelining, "pipelining"},
+		{FeatureProtobuf, "protobuf"},
+		{FeatureChecksum, "checksum"},
+	} {
+		if f.Has(n.f) {
+			names = append(names, n.name)
+		}
+	}
+	return "[" + strings.Join(names, ",") + "]"
+}
+
+// HelloRequest is sent first on a connection to tell the remote node who is
+// connecting and which features it supports.
+type HelloRequest struct {
+	NodeID          uint64
+	Version         string
+	ProtocolVersion uint32
+	Features        Feature
+
+	// PointCodecs are the codecs the node decodes the points of writes
+	// with. Nodes that do not send them only decode BinaryPointCodec.
+	PointCodecs []PointCodecID
+
+	// ClusterID is the ID of the cluster the node belongs to, or zero if
+	// it is not known yet.
+	ClusterID uint64
+}
+
+func (hr *HelloRequest) MarshalBinary() ([]byte, error) {
+	var pb internal.HelloRequest
+	pb.NodeID = proto.Uint64(hr.NodeID)
+	pb.Version = proto.String(hr.Version)
+	pb.ProtocolVersion = proto.Uint32(hr.ProtocolVersion)
+	pb.Features = proto.Uint64(uint64(hr.Features))
+	pb.PointCodecs = marshalPointCodecs(hr.PointCodecs)
+	if hr.ClusterID != 0 {
+		pb.ClusterID = proto.Uint64(hr.ClusterID)
+	}
+
+	return proto.Marshal(&pb)
+}
+
+func (hr *HelloRequest) UnmarshalBinary(data []byte) error {
+	var pb internal.HelloRequest
+	if err := proto.Unmarshal(data, &pb); err != nil {
+		return err
+	}
+
+	hr.NodeID = pb.GetNodeID()
+	hr.Version = pb.GetVersion()
+	hr.ProtocolVersion = pb.GetProtocolVersion()
+	hr.Features = Feature(pb.GetFeatures())
+	hr.PointCodecs = unmarshalPointCodecs(pb.GetPointCodecs())
+	hr.ClusterID = pb.GetClusterID()
+
+	return nil
+}
+
+// HelloResponse describes the remote node, and the features negotiated for
+// the connection. Err is set if the remote node refused the connection.
+type HelloResponse struct {
+	Err             string
+	NodeID          uint64
+	Version         string
+	ProtocolVersion uint32
+	Features        Feature
+
+	// PointCodecs are the codecs of the request the remote node can decode
+	// the points of writes with.
+	PointCodecs []PointCodecID
+
+	// ClusterID is the ID of the cluster the remote node belongs to, or
+	// zero if it is not known yet.
+	ClusterID uint64
+}
+
+func (hr *HelloResponse) MarshalBinary() ([]byte, error) {
+	var pb internal.HelloResponse
+	pb.Err = proto.String(hr.Err)
+	pb.NodeID = proto.Uint64(hr.NodeID)
+	pb.Version = proto.String(hr.Version)
+	pb.ProtocolVersion = proto.Uint32(hr.ProtocolVersion)
+	pb.Features = proto.Uint64(uint64(hr.Features))
+	pb.PointCodecs = marshalPointCodecs(hr.PointCodecs)
+	if hr.ClusterID != 0 {
+		pb.ClusterID = proto.Uint64(hr.ClusterID)
+	}
+
+	return proto.Marshal(&pb)
+}
+
+func (hr *HelloResponse) UnmarshalBinary(data []byte) error {
+	var pb internal.HelloResponse
+	if err := proto.Unmarshal(data, &pb); err != nil {
+		return err
+	}
+
+	hr.Err = pb.GetErr()
+	hr.NodeID = pb.GetNodeID()
+	hr.Version = pb.GetVersion()
+	hr.ProtocolVersion = pb.GetProtocolVersion()
+	hr.Features = Feature(pb.GetFeatures())
+	hr.PointCodecs = unmarshalPointCodecs(pb.GetPointCodecs())
+	hr.ClusterID = pb.GetClusterID()
+
+	return nil
+}
+
+func marshalPointCodecs(ids []PointCodecID) []uint32 {
+	if len(ids) == 0 {
+		return nil
+	}
+	a := make([]uint32, len(ids))
+	for i, id := range ids {
+		a[i] = uint32(id)
+	}
+	return a
+}
+
+func unmarshalPointCodecs(a []uint32) []PointCodecID {
+	if len(a) == 0 {
+		return nil
+	}
+	ids := make([]PointCodecID, len(a))
+	for i, id := range a {
+		ids[i] = PointCodecID(id)
+	}
+	return ids
+}
+
+// DropShardsRequest asks a data node to delete its local copies of shards
+// whose shard groups were deleted from the meta store.
+type DropShardsRequest struct {
+	ShardIDs []uint64
+}
+
+func (dsr *DropShardsRequest) MarshalBinary() ([]byte, error) {
+	var pb internal.DropShardsRequest
+	pb.ShardIDs = dsr.ShardIDs
+
+	return proto.Marshal(&pb)
+}
+
+func (dsr *DropShardsRequest) UnmarshalBinary(data []byte) error {
+	var pb internal.DropShardsRequest
+	if err := proto.Unmarshal(data, &pb); err != nil {
+		return err
+	}
+
+	dsr.ShardIDs = pb.GetShardIDs()
+
+	return nil
+}
+
+type DropShardsResponse struct {
+	Err string
+}
+
+func (dsr *DropShardsResponse) MarshalBinary() ([]byte, error) {
+	var pb internal.DropShardsResponse
+	pb.Err = proto.String(dsr.Err)
+
+	return proto.Marshal(&pb)
+}
+
+func (dsr *DropShardsResponse) UnmarshalBinary(data []byte) error {
+	var pb internal.DropShardsResponse
+	if err := proto.Unmarshal(data, &pb); err != nil {
+		return err
+	}
+
+	dsr.Err = pb.GetErr()
+
+	return nil
+}
+
+// ErrorResponse is sent in place of the response to a request the remote
+// node failed to process, e.g. because processing it panicked.
+type ErrorResponse struct {
+	Code    ErrorCode
+	Message string
+}
+
+// Err returns the error reported by the remote node.
+func (er *ErrorResponse) Err() error {
+	return &WriteShardError{Code: er.Code, Message: er.Message}
+}
+
+func (er *ErrorResponse) MarshalBinary() ([]byte, error) {
+	var pb internal.ErrorResponse
+	pb.Code = proto.Int32(int32(er.Code))
+	pb.Message = proto.String(er.Message)
+
+	return proto.Marshal(&pb)
+}
+
+func (er *ErrorResponse) UnmarshalBinary(data []byte) error {
+	var pb internal.ErrorResponse
+	if err := proto.Unmarshal(data, &pb); err != nil {
+		return err
+	}
+
+	er.Code = ErrorCode(pb.GetCode())
+	er.Message = pb.GetMessage()
+
+	return nil
+}
+
+// SetMaintenanceRequest asks a data node to put the data node at TCPHost into
+// maintenance mode, or to take it out of it if Maintenance is false.
+type SetMaintenanceRequest struct {
+	TCPHost     string
+	Maintenance bool
+}
+
+func (smr *SetMaintenanceRequest) MarshalBinary() ([]byte, error) {
+	var pb internal.SetMaintenanceRequest
+
+	if smr.TCPHost == "" {
+		return nil, fmt.Errorf("TCPHost cannot be empty string")
+	}
+	pb.TCPHost = proto.String(smr.TCPHost)
+	pb.Maintenance = proto.Bool(smr.Maintenance)
+
+	return proto.Marshal(&pb)
+}
+
+func (smr *SetMaintenanceRequest) UnmarshalBinary(data []byte) error {
+	var pb internal.SetMaintenanceRequest
+	if err := proto.Unmarshal(data, &pb); err != nil {
+		return err
+	}
+
+	smr.TCPHost = pb.GetTCPHost()
+	smr.Maintenance = pb.GetMaintenance()
+
+	return nil
+}
+
+type SetMaintenanceResponse struct {
+	Err string
+}
+
+func (smr *SetMaintenanceResponse) MarshalBinary() ([]byte, error) {
+	var pb internal.SetMaintenanceResponse
+	pb.Err = proto.String(smr.Err)
+
+	return proto.Marshal(&pb)
+}
+
+func (smr *SetMaintenanceResponse) UnmarshalBinary(data []byte) error {
+	var pb internal.SetMaintenanceResponse
+	if err := proto.Unmarshal(data, &pb); err != nil {
+		return err
+	}
+
+	smr.Err = pb.GetErr()
+
+	return nil
+}
+
+// SetReadOnlyRequest asks a data node to reject the writes other nodes send
+// it, or to accept them again if ReadOnly is false.
+type SetReadOnlyRequest struct {
+	ReadOnly bool
+}
+
+func (srr *SetReadOnlyRequest) MarshalBinary() ([]byte, error) {
+	var pb internal.SetReadOnlyRequest
+	pb.ReadOnly = proto.Bool(srr.ReadOnly)
+
+	return proto.Marshal(&pb)
+}
+
+func (srr *SetReadOnlyRequest) UnmarshalBinary(data []byte) error {
+	var pb internal.SetReadOnlyRequest
+	if err := proto.Unmarshal(data, &pb); err != nil {
+		return err
+	}
+
+	srr.ReadOnly = pb.GetReadOnly()
+
+	return nil
+}
+
+type SetReadOnlyResponse struct {
+	Err string
+}
+
+func (srr *SetReadOnlyResponse) MarshalBinary() ([]byte, error) {
+	var pb internal.SetReadOnlyResponse
+	pb.Err = proto.String(srr.Err)
+
+	return proto.Marshal(&pb)
+}
+
+func (srr *SetReadOnlyResponse) UnmarshalBinary(data []byte) error {
+	var pb internal.SetReadOnlyResponse
+	if err := proto.Unmarshal(data, &pb); err != nil {
+		return err
+	}
+
+	srr.Err = pb.GetErr()
+
+	return nil
+}
+
+// CardinalityRequest asks a data node for the series cardinality of its local
+// shards, of Database or of every database if empty. The series keys of each
+// database are returned if Exact is set, and a HyperLogLog sketch of them
+// otherwise, so that the cardinality of a database across nodes can be
+// merged.
+type CardinalityRequest struct {
+	Database string
+	Exact    bool
+}
+
+func (cr *CardinalityRequest) MarshalBinary() ([]byte, error) {
+	var pb internal.CardinalityRequest
+	if cr.Database != "" {
+		pb.Database = proto.String(cr.Database)
+	}
+	pb.Exact = proto.Bool(cr.Exact)
+
+	return proto.Marshal(&pb)
+}
+
+func (cr *CardinalityRequest) UnmarshalBinary(data []byte) error {
+	var pb internal.CardinalityRequest
+	if err := proto.Unmarshal(data, &pb); err != nil {
+		return err
+	}
+
+	cr.Database = pb.GetDatabase()
+	cr.Exact = pb.GetExact()
+
+	return nil
+}
+
+// DatabaseCardinality is the series cardinality of a database on one data
+// node. SeriesKeys is only set for exact requests, and Sketch otherwise.
+type DatabaseCardinality struct {
+	Name         string
+	SeriesN      int64
+	Measurements []string
+	SeriesKeys   []string
+	Sketch       []byte
+}
+
+// ShardCardinality is the number of series in a single shard.
+type ShardCardinality struct {
+	ID       uint64
+	Database string
+	SeriesN  int64
+}
+
+type CardinalityResponse struct {
+	Err       string
+	Databases []DatabaseCardinality
+	Shards    []ShardCardinality
+}
+
+func (cr *CardinalityResponse) MarshalBinary() ([]byte, error) {
+	var pb internal.CardinalityResponse
+	pb.Err = proto.String(cr.Err)
+	pb.Databases = make([]*internal.DatabaseCardinality, len(cr.Databases))
+	for i, dc := range cr.Databases {
+		pb.Databases[i] = &internal.DatabaseCardinality{
+			Name:         proto.String(dc.Name),
+			SeriesN:      proto.Int64(dc.SeriesN),
+			Measurements: dc.Measurements,
+			SeriesKeys:   dc.SeriesKeys,
+			Sketch:       dc.Sketch,
+		}
+	}
+	pb.Shards = make([]*internal.ShardCardinality, len(cr.Shards))
+	for i, sc := range cr.Shards {
+		pb.Shards[i] = &internal.ShardCardinality{
+			ID:       proto.Uint64(sc.ID),
+			Database: proto.String(sc.Database),
+			SeriesN:  proto.Int64(sc.SeriesN),
+		}
+	}
+
+	return proto.Marshal(&pb)
+}
+
+func (cr *CardinalityResponse) UnmarshalBinary(data []byte) error {
+	var pb internal.CardinalityResponse
+	if err := proto.Unmarshal(data, &pb); err != nil {
+		return err
+	}
+
+	cr.Err = pb.GetErr()
+	cr.Databases = make([]DatabaseCardinality, len(pb.GetDatabases()))
+	for i, dc := range pb.GetDatabases() {
+		cr.Databases[i] = DatabaseCardinality{
+			Name:         dc.GetName(),
+			SeriesN:      dc.GetSeriesN(),
+			Measurements: dc.GetMeasurements(),
+			SeriesKeys:   dc.GetSeriesKeys(),
+			Sketch:       dc.GetSketch(),
+		}
+	}
+	cr.Shards = make([]ShardCardinality, len(pb.GetShards()))
+	for i, sc := range pb.GetShards() {
+		cr.Shards[i] = ShardCardinality{
+			ID:       sc.GetID(),
+			Database: sc.GetDatabase(),
+			SeriesN:  sc.GetSeriesN(),
+		}
+	}
+
+	return nil
+}
+
+// Actions of a QuarantineRequest.
+const (
+	// QuarantineList lists the quarantined writes.
+	QuarantineList = "list"
+
+	// QuarantineRequeue queues a quarantined write for its node again.
+	QuarantineRequeue = "requeue"
+
+	// QuarantineDrop deletes a quarantined write.
+	QuarantineDrop = "drop"
+)
+
+// QuarantineRequest asks a data node to list the hinted handoff writes it
+// quarantined, or to requeue or drop the write ID quarantined from the queue
+// of NodeID.
+type QuarantineRequest struct {
+	Action string
+	NodeID uint64
+	ID     string
+}
+
+func (qr *QuarantineRequest) MarshalBinary() ([]byte, error) {
+	var pb internal.QuarantineRequest
+	pb.Action = proto.String(qr.Action)
+	pb.NodeID = proto.Uint64(qr.NodeID)
+	pb.ID = proto.String(qr.ID)
+
+	return proto.Marshal(&pb)
+}
+
+func (qr *QuarantineRequest) UnmarshalBinary(data []byte) error {
+	var pb internal.QuarantineRequest
+	if err := proto.Unmarshal(data, &pb); err != nil {
+		return err
+	}
+
+	qr.Action = pb.GetAction()
+	qr.NodeID = pb.GetNodeID()
+	qr.ID = pb.GetID()
+
+	return nil
+}
+
+// QuarantinedWrite describes a write queued in hinted handoff that was moved
+// out of the queue of NodeID after the node repeatedly failed to store it.
+// Sequence is the sequence number it was queued with, if any.
+type QuarantinedWrite struct {
+	ID       string
+	NodeID   uint64
+	ShardID  uint64
+	Sequence uint64
+	PointN   int
+	Size     int
+	Attempts int
+	Err      string
+	Time     time.Time
+}
+
+type QuarantineResponse struct {
+	Err    string
+	Writes []QuarantinedWrite
+}
+
+func (qr *QuarantineResponse) MarshalBinary() ([]byte, error) {
+	var pb internal.QuarantineResponse
+	pb.Err = proto.String(qr.Err)
+	pb.Writes = make([]*internal.QuarantinedWrite, len(qr.Writes))
+	for i, w := range qr.Writes {
+		pb.Writes[i] = &internal.QuarantinedWrite{
+			ID:       proto.String(w.ID),
+			NodeID:   proto.Uint64(w.NodeID),
+			ShardID:  proto.Uint64(w.ShardID),
+			Sequence: proto.Uint64(w.Sequence),
+			PointN:   proto.Int64(int64(w.PointN)),
+			Size_:    proto.Int64(int64(w.Size)),
+			Attempts: proto.Int64(int64(w.Attempts)),
+			Err:      proto.String(w.Err),
+			Time:     proto.Int64(marshalTime(w.Time)),
+		}
+	}
+
+	return proto.Marshal(&pb)
+}
+
+func (qr *QuarantineResponse) UnmarshalBinary(data []byte) error {
+	var pb internal.QuarantineResponse
+	if err := proto.Unmarshal(data, &pb); err != nil {
+		return err
+	}
+
+	qr.Err = pb.GetErr()
+	qr.Writes = make([]QuarantinedWrite, len(pb.GetWrites()))
+	for i, w := range pb.GetWrites() {
+		qr.Writes[i] = QuarantinedWrite{
+			ID:       w.GetID(),
+			NodeID:   w.GetNodeID(),
+			ShardID:  w.GetShardID(),
+			Sequence: w.GetSequence(),
+			PointN:   int(w.GetPointN()),
+			Size:     int(w.GetSize_()),
+			Attempts: int(w.GetAttempts()),
+			Err:      w.GetErr(),
+			Time:     unmarshalTime(w.GetTime()),
+		}
+	}
+
+	return nil
+}
+
+// SpanContext carries the context of a tracing span to a remote node. It is
+// sent as its own record ahead of the request it belongs to.
+type SpanContext struct {
+	Carrier map[string]string
+}
+
+func (sc *SpanContext) MarshalBinary() ([]byte, error) {
+	keys := make([]string, 0, len(sc.Carrier))
+	for k := range sc.Carrier {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var pb internal.SpanContext
+	pb.Entries = make([]*internal.SpanContextEntry, len(keys))
+	for i, k := range keys {
+		pb.Entries[i] = &internal.SpanContextEntry{
+			Key:   proto.String(k),
+			Value: proto.String(sc.Carrier[k]),
+		}
+	}
+
+	return proto.Marshal(&pb)
+}
+
+func (sc *SpanContext) UnmarshalBinary(data []byte) error {
+	var pb internal.SpanContext
+	if err := proto.Unmarshal(data, &pb); err != nil {
+		return err
+	}
+
+	sc.Carrier = make(map[string]string, len(pb.GetEntries()))
+	for _, e := range pb.GetEntries() {
+		sc.Carrier[e.GetKey()] = e.GetValue()
+	}
+
+	return nil
+}
+
+// AdminToken carries the token authorizing the admin requests sent after
+// it on a connection. It is sent as its own record ahead of them.
+type AdminToken struct {
+	Token string
+}
+
+func (t *AdminToken) MarshalBinary() ([]byte, error) {
+	return proto.Marshal(&internal.AdminToken{Token: proto.String(t.Token)})
+}
+
+func (t *AdminToken) UnmarshalBinary(data []byte) error {
+	var pb internal.AdminToken
+	if err := proto.Unmarshal(data, &pb); err != nil {
+		return err
+	}
+	t.Token = pb.GetToken()
+	return nil
+}
+
+// marshalTime encodes t as nanoseconds since the epoch, or zero if t is unset.
+func marshalTime(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixNano()
+}
+
+// unmarshalTime decodes a time encoded by marshalTime.
+func unmarshalTime(n int64) time.Time {
+	if n == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, n).UTC()
+}
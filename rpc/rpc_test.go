@@ -2,8 +2,13 @@ package rpc_test
 
 import (
 	"bytes"
+	"github.com/gogo/protobuf/proto"
+	"github.com/influxdata/influxdb/influxql"
 	"github.com/influxdata/influxdb/models"
 	"github.com/zhexuany/influxcloud/rpc"
+	"github.com/zhexuany/influxcloud/rpc/internal"
+	"reflect"
+	"regexp"
 	"testing"
 	"time"
 )
@@ -16,6 +21,10 @@ func TestWriteShardRequestBinary(t *testing.T) {
 		t.Fatalf("ShardID mismatch: got %v, exp %v", sr.ShardID(), exp)
 	}
 
+	sr.SetRequestID("req0")
+	sr.SetOriginNodeID(2)
+	sr.SetHandoffSequence(3, 100)
+	sr.SetTimeout(3 * time.Second)
 	sr.AddPoint("cpu", 1.0, time.Now(), models.NewTags(map[string]string{"host": "serverA"}))
 	sr.AddPoint("cpu", 2.0, time.Now().Add(time.Hour), nil)
 	sr.AddPoint("cpu_load", 3.0, time.Unix(0, 0).Add(time.Hour+time.Second), nil)
@@ -37,6 +46,21 @@ func TestWriteShardRequestBinary(t *testing.T) {
 		t.Errorf("ShardID mismatch: got %v, exp %v", got.ShardID(), sr.ShardID())
 	}
 
+	if got.RequestID() != sr.RequestID() {
+		t.Errorf("RequestID mismatch: got %v, exp %v", got.RequestID(), sr.RequestID())
+	}
+
+	if got.OriginNodeID() != sr.OriginNodeID() {
+		t.Errorf("OriginNodeID mismatch: got %v, exp %v", got.OriginNodeID(), sr.OriginNodeID())
+	}
+	if queueID, seq := got.HandoffSequence(); queueID != 3 || seq != 100 {
+		t.Errorf("HandoffSequence mismatch: got %v %v, exp 3 100", queueID, seq)
+	}
+
+	if got.Timeout() != sr.Timeout() {
+		t.Errorf("Timeout mismatch: got %v, exp %v", got.Timeout(), sr.Timeout())
+	}
+
 	if len(got.Points()) != len(sr.Points()) {
 		t.Errorf("Points count mismatch: got %v, exp %v", len(got.Points()), len(sr.Points()))
 	}
@@ -82,6 +106,8 @@ func TestWriteShardResponseBinary(t *testing.T) {
 	sr := &rpc.WriteShardResponse{}
 	sr.SetCode(10)
 	sr.SetMessage("foo")
+	sr.SetRequestID("req0")
+	sr.SetRejected([]int{1, 3})
 	b, err := sr.MarshalBinary()
 
 	if exp := 10; sr.Code() != exp {
@@ -112,4 +138,113 @@ func TestWriteShardResponseBinary(t *testing.T) {
 		t.Errorf("Message mismatch: got %v, exp %v", got.Message(), sr.Message())
 	}
 
+	if got.RequestID() != sr.RequestID() {
+		t.Errorf("RequestID mismatch: got %v, exp %v", got.RequestID(), sr.RequestID())
+	}
+
+	if !reflect.DeepEqual(got.Rejected(), sr.Rejected()) {
+		t.Errorf("Rejected mismatch: got %v, exp %v", got.Rejected(), sr.Rejected())
+	}
+
+}
+
+// Ensure every option influxql queries use reaches the remote node, so that
+// distributed queries return the same results as a single node.
+func TestCreateIteratorRequestBinary(t *testing.T) {
+	req := &rpc.CreateIteratorRequest{
+		ShardIDs: []uint64{10, 11},
+		Opt: influxql.IteratorOptions{
+			Expr: &influxql.Call{Name: "mean", Args: []influxql.Expr{&influxql.VarRef{Val: "value", Type: influxql.Float}}},
+			Aux:  []influxql.VarRef{{Val: "host", Type: influxql.Tag}, {Val: "load", Type: influxql.Integer}},
+			Sources: []influxql.Source{
+				&influxql.Measurement{Database: "db0", RetentionPolicy: "rp0", Name: "cpu"},
+				&influxql.Measurement{Database: "db0", Regex: &influxql.RegexLiteral{Val: regexp.MustCompile(`^mem`)}},
+			},
+			Interval:   influxql.Interval{Duration: time.Minute, Offset: 10 * time.Second},
+			Dimensions: []string{"host", "region"},
+			GroupBy:    map[string]struct{}{"host": struct{}{}, "region": struct{}{}},
+			Fill:       influxql.NumberFill,
+			FillValue:  1.5,
+			Condition:  influxql.MustParseExpr(`region = 'west' AND value > 10`),
+			StartTime:  100,
+			EndTime:    200,
+			Ascending:  true,
+			Limit:      5,
+			Offset:     1,
+			SLimit:     3,
+			SOffset:    2,
+			Dedupe:     true,
+			Ordered:    true,
+			MaxSeriesN: 1000,
+		},
+		RequestID:   "req0",
+		Compression: rpc.CompressionSnappy,
+	}
+	b, err := req.MarshalBinary()
+	if err != nil {
+		t.Fatalf("CreateIteratorRequest.MarshalBinary() failed: %v", err)
+	}
+
+	got := &rpc.CreateIteratorRequest{}
+	if err := got.UnmarshalBinary(b); err != nil {
+		t.Fatalf("CreateIteratorRequest.UnmarshalBinary() failed: %v", err)
+	}
+	if !reflect.DeepEqual(got.ShardIDs, req.ShardIDs) || got.RequestID != req.RequestID || got.Compression != req.Compression {
+		t.Errorf("request mismatch: got %v %q %q, exp %v %q %q", got.ShardIDs, got.RequestID, got.Compression, req.ShardIDs, req.RequestID, req.Compression)
+	}
+
+	// Expressions are compared by their string, as parsing them back may
+	// build different, equivalent trees.
+	exp := req.Opt
+	if got.Opt.Expr.String() != exp.Expr.String() {
+		t.Errorf("Expr mismatch: got %v, exp %v", got.Opt.Expr, exp.Expr)
+	} else if got.Opt.Condition.String() != exp.Condition.String() {
+		t.Errorf("Condition mismatch: got %v, exp %v", got.Opt.Condition, exp.Condition)
+	} else if influxql.Sources(got.Opt.Sources).String() != influxql.Sources(exp.Sources).String() {
+		t.Errorf("Sources mismatch: got %v, exp %v", got.Opt.Sources, exp.Sources)
+	}
+	got.Opt.Expr, got.Opt.Condition, got.Opt.Sources = nil, nil, nil
+	exp.Expr, exp.Condition, exp.Sources = nil, nil, nil
+	if !reflect.DeepEqual(got.Opt, exp) {
+		t.Errorf("options mismatch:\ngot %#v\nexp %#v", got.Opt, exp)
+	}
+}
+
+// Ensure requests encoding their options in a newer version are rejected,
+// and that sources influxql cannot encode fail to encode.
+func TestCreateIteratorRequestBinary_Version(t *testing.T) {
+	b, err := proto.Marshal(&internal.CreateIteratorRequest{
+		Opt:     []byte{},
+		Version: proto.Uint32(rpc.IteratorOptionsVersion + 1),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var req rpc.CreateIteratorRequest
+	if err, ok := req.UnmarshalBinary(b).(*rpc.IteratorOptionsVersionError); !ok || err.Version != rpc.IteratorOptionsVersion+1 {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Requests from nodes without versions are decoded.
+	b, err = (&rpc.CreateIteratorRequest{}).MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var pb internal.CreateIteratorRequest
+	if err := proto.Unmarshal(b, &pb); err != nil {
+		t.Fatal(err)
+	} else if pb.GetVersion() != rpc.IteratorOptionsVersion {
+		t.Fatalf("unexpected version: %d", pb.GetVersion())
+	}
+	pb.Version = nil
+	if b, err = proto.Marshal(&pb); err != nil {
+		t.Fatal(err)
+	} else if err := req.UnmarshalBinary(b); err != nil {
+		t.Fatal(err)
+	}
+
+	req.Opt.Sources = []influxql.Source{&influxql.SubQuery{Statement: influxql.MustParseStatement(`SELECT value FROM cpu`).(*influxql.SelectStatement)}}
+	if _, err := req.MarshalBinary(); err == nil {
+		t.Fatal("expected error encoding a subquery source")
+	}
 }
@@ -1,15 +1,57 @@
 package tlv
 
 import (
+	"bytes"
 	"encoding"
 	"encoding/binary"
 	"fmt"
+	"hash/crc32"
 	"io"
+
+	"github.com/zhexuany/influxcloud/rpc"
 )
 
 // MaxMessageSize defines how large a message can be before we reject it
 const MaxMessageSize = 1024 * 1024 * 1024 // 1GB
 
+// maxPreallocSize is the largest value read into a buffer allocated up front.
+// Buffers for larger values grow as the value is read, so that a length
+// prefix alone cannot make the reader allocate them.
+const maxPreallocSize = 1024 * 1024 // 1MB
+
+// ChecksumFlag is set in the type of a record that is followed by the CRC32
+// of the whole record. Records are only checksummed on connections where both
+// nodes negotiated checksums, as older nodes do not know the flag.
+const ChecksumFlag byte = 0x80
+
+// crcTable is the table used to checksum records.
+var crcTable = crc32.MakeTable(crc32.Castagnoli)
+
+// FrameTooLargeError is returned when the length of a record exceeds the
+// maximum size accepted by the reader. The value is not read.
+type FrameTooLargeError struct {
+	Type byte
+	Tag  uint64
+	Size int64
+	Max  int64
+}
+
+func (e *FrameTooLargeError) Error() string {
+	return fmt.Sprintf("frame too large: %d bytes exceeds maximum of %d", e.Size, e.Max)
+}
+
+// ChecksumError is returned when a record does not match its checksum. The
+// type and tag are as read, and may be corrupted as well.
+type ChecksumError struct {
+	Type byte
+	Tag  uint64
+}
+
+// Error returns the type of the corrupted record.
+func (e *ChecksumError) Error() string {
+	return fmt.Sprintf("checksum mismatch in message type %d", e.Type)
+}
+
 // type for different requests and responses
 const (
 	WriteShardRequestMessage byte = iota + 1
@@ -41,9 +83,128 @@ const (
 
 	ShowQuriesStatementRequestMessage
 	ShowQuriesStatementResponseMessage
+
+	ShowShardsRequestMessage
+	ShowShardsResponseMessage
+
+	BackupShardRequestMessage
+	BackupShardResponseMessage
+
+	TruncateShardsRequestMessage
+	TruncateShardsResponseMessage
+
+	RemoveDataNodeRequestMessage
+	RemoveDataNodeResponseMessage
+
+	UpdateDataNodeRequestMessage
+	UpdateDataNodeResponseMessage
+
+	AuthStateRequestMessage
+	AuthStateResponseMessage
+
+	PauseReplicationRequestMessage
+	PauseReplicationResponseMessage
+
+	ExportMetaDataRequestMessage
+	ExportMetaDataResponseMessage
+
+	ShardStatusRequestMessage
+	ShardStatusResponseMessage
+
+	// SpanContextMessage precedes a request and carries the tracing span
+	// that the request belongs to.
+	SpanContextMessage
+
+	// MultiplexRequestMessage switches a connection to tagged records so
+	// that several requests can be in flight at once.
+	MultiplexRequestMessage
+	MultiplexResponseMessage
+
+	// PingRequestMessage checks that an idle connection is still alive.
+	PingRequestMessage
+	PingResponseMessage
+
+	// WritePointsRequestMessage forwards a whole write to the node that
+	// owns most of its shards.
+	WritePointsRequestMessage
+	WritePointsResponseMessage
+
+	// ShardBoundsRequestMessage asks for the time range of measurements in
+	// shards so that queries can skip shards without relevant points.
+	ShardBoundsRequestMessage
+	ShardBoundsResponseMessage
+
+	// IteratorPointsMessage carries a chunk of the points streamed by a
+	// remote iterator, and IteratorEndMessage ends the stream.
+	IteratorPointsMessage
+	IteratorEndMessage
+
+	// AcquireLeaseRequestMessage asks a data node for a lease, so that work
+	// such as continuous queries is only done by one node at a time.
+	AcquireLeaseRequestMessage
+	AcquireLeaseResponseMessage
+
+	// RestoreShardRequestMessage restores a shard from a backup, which is
+	// streamed as RestoreShardDataMessage records.
+	RestoreShardRequestMessage
+	RestoreShardResponseMessage
+	RestoreShardDataMessage
+
+	// UploadShardSnapshotRequestMessage asks a data node to upload a shard
+	// snapshot to object storage.
+	UploadShardSnapshotRequestMessage
+	UploadShardSnapshotResponseMessage
+
+	// ReplaceDataNodeRequestMessage hands the shards of a data node over to
+	// a new data node, and RedirectHintedHandoffRequestMessage sends the
+	// writes queued for the old node to the new one.
+	ReplaceDataNodeRequestMessage
+	ReplaceDataNodeResponseMessage
+	RedirectHintedHandoffRequestMessage
+	RedirectHintedHandoffResponseMessage
+
+	// HelloRequestMessage is sent first on a connection to negotiate the
+	// protocol version and features with the remote node.
+	HelloRequestMessage
+	HelloResponseMessage
+
+	// DropShardsRequestMessage asks a data node to delete the local copies
+	// of shards expired by retention enforcement.
+	DropShardsRequestMessage
+	DropShardsResponseMessage
+
+	// ErrorResponseMessage is sent in place of the response to a request
+	// the remote node failed to process. It carries an rpc.ErrorResponse.
+	ErrorResponseMessage
+
+	// SetMaintenanceRequestMessage puts a data node into maintenance mode,
+	// or takes it out of it.
+	SetMaintenanceRequestMessage
+	SetMaintenanceResponseMessage
+
+	// SetReadOnlyRequestMessage makes a data node reject the writes other
+	// nodes send it, or accept them again.
+	SetReadOnlyRequestMessage
+	SetReadOnlyResponseMessage
+
+	// CardinalityRequestMessage requests the series cardinality of the
+	// local shards of a data node.
+	CardinalityRequestMessage
+	CardinalityResponseMessage
+
+	// QuarantineRequestMessage lists, requeues or drops the hinted handoff
+	// writes a data node quarantined.
+	QuarantineRequestMessage
+	QuarantineResponseMessage
+
+	// AdminTokenMessage precedes admin requests and carries the token
+	// authorizing them. The token holds for the rest of the connection.
+	AdminTokenMessage
 )
 
-// ReadTLV reads a type-length-value record from r.
+// ReadTLV reads a type-length-value record from r. If the record is
+// checksummed it is verified, and a *ChecksumError is returned with the type
+// if it is corrupted.
 func ReadTLV(r io.Reader) (byte, []byte, error) {
 	typ, err := ReadType(r)
 	if err != nil {
@@ -51,10 +212,22 @@ func ReadTLV(r io.Reader) (byte, []byte, error) {
 	}
 
 	buf, err := ReadLV(r)
-	if err != nil {
+	if e, ok := err.(*FrameTooLargeError); ok {
+		e.Type = typ &^ ChecksumFlag
+		return e.Type, nil, e
+	} else if err != nil {
 		return 0, nil, err
 	}
-	return typ, buf, err
+
+	if typ&ChecksumFlag != 0 {
+		typ &^= ChecksumFlag
+		if ok, err := verifyChecksum(r, header(typ|ChecksumFlag, nil, len(buf)), buf); err != nil {
+			return 0, nil, err
+		} else if !ok {
+			return typ, nil, &ChecksumError{Type: typ}
+		}
+	}
+	return typ, buf, nil
 }
 
 // ReadType reads the type from a TLV record.
@@ -68,17 +241,32 @@ func ReadType(r io.Reader) (byte, error) {
 
 // ReadLV reads the length-value from a TLV record.
 func ReadLV(r io.Reader) ([]byte, error) {
+	return ReadLVMax(r, MaxMessageSize)
+}
+
+// ReadLVMax reads the length-value from a TLV record, and returns a
+// *FrameTooLargeError without reading the value if it is larger than max.
+func ReadLVMax(r io.Reader, max int64) ([]byte, error) {
 	// Read the size of the message.
 	var sz int64
 	if err := binary.Read(r, binary.BigEndian, &sz); err != nil {
 		return nil, fmt.Errorf("read message size: %s", err)
 	}
 
-	if sz >= MaxMessageSize {
-		return nil, fmt.Errorf("max message size of %d exceeded: %d", MaxMessageSize, sz)
+	if sz < 0 {
+		return nil, fmt.Errorf("invalid message size: %d", sz)
+	} else if sz > max || sz >= MaxMessageSize {
+		return nil, &FrameTooLargeError{Size: sz, Max: max}
 	}
 
 	// Read the value.
+	if sz > maxPreallocSize {
+		var buf bytes.Buffer
+		if _, err := io.CopyN(&buf, r, sz); err != nil {
+			return nil, fmt.Errorf("read message value: %s", err)
+		}
+		return buf.Bytes(), nil
+	}
 	buf := make([]byte, sz)
 	if _, err := io.ReadFull(r, buf); err != nil {
 		return nil, fmt.Errorf("read message value: %s", err)
@@ -98,6 +286,15 @@ func WriteTLV(w io.Writer, typ byte, buf []byte) error {
 	return nil
 }
 
+// WriteTLVChecksum writes a type-length-value record followed by its checksum
+// to w with a single call.
+func WriteTLVChecksum(w io.Writer, typ byte, buf []byte) error {
+	if _, err := w.Write(appendChecksum(append(header(typ|ChecksumFlag, nil, len(buf)), buf...))); err != nil {
+		return fmt.Errorf("write message: %s", err)
+	}
+	return nil
+}
+
 // WriteType writes the type in a TLV record to w.
 func WriteType(w io.Writer, typ byte) error {
 	if _, err := w.Write([]byte{typ}); err != nil {
@@ -120,6 +317,95 @@ func WriteLV(w io.Writer, buf []byte) error {
 	return nil
 }
 
+// ReadTaggedTLV reads a type-tag-length-value record from r. Tagged records
+// are used on multiplexed connections, where a response carries the tag of
+// the request it answers.
+func ReadTaggedTLV(r io.Reader) (byte, uint64, []byte, error) {
+	return ReadTaggedTLVMax(r, MaxMessageSize)
+}
+
+// ReadTaggedTLVMax reads a type-tag-length-value record from r. A
+// *FrameTooLargeError is returned with the type and tag, without reading the
+// value, if the value is larger than max.
+func ReadTaggedTLVMax(r io.Reader, max int64) (byte, uint64, []byte, error) {
+	typ, err := ReadType(r)
+	if err != nil {
+		return 0, 0, nil, err
+	}
+
+	var tag uint64
+	if err := binary.Read(r, binary.BigEndian, &tag); err != nil {
+		return 0, 0, nil, fmt.Errorf("read message tag: %s", err)
+	}
+
+	buf, err := ReadLVMax(r, max)
+	if e, ok := err.(*FrameTooLargeError); ok {
+		e.Type, e.Tag = typ&^ChecksumFlag, tag
+		return e.Type, tag, nil, e
+	} else if err != nil {
+		return 0, 0, nil, err
+	}
+
+	if typ&ChecksumFlag != 0 {
+		typ &^= ChecksumFlag
+		if ok, err := verifyChecksum(r, header(typ|ChecksumFlag, &tag, len(buf)), buf); err != nil {
+			return 0, 0, nil, err
+		} else if !ok {
+			return typ, tag, nil, &ChecksumError{Type: typ, Tag: tag}
+		}
+	}
+	return typ, tag, buf, nil
+}
+
+// WriteTaggedTLV writes a type-tag-length-value record to w. The record is
+// written with a single call to w.
+func WriteTaggedTLV(w io.Writer, typ byte, tag uint64, buf []byte) error {
+	if _, err := w.Write(append(header(typ, &tag, len(buf)), buf...)); err != nil {
+		return fmt.Errorf("write tagged message: %s", err)
+	}
+	return nil
+}
+
+// WriteTaggedTLVChecksum writes a type-tag-length-value record followed by its
+// checksum to w with a single call.
+func WriteTaggedTLVChecksum(w io.Writer, typ byte, tag uint64, buf []byte) error {
+	if _, err := w.Write(appendChecksum(append(header(typ|ChecksumFlag, &tag, len(buf)), buf...))); err != nil {
+		return fmt.Errorf("write tagged message: %s", err)
+	}
+	return nil
+}
+
+// header returns the type, tag if not nil, and length of a record, with room
+// for a value of n bytes and its checksum.
+func header(typ byte, tag *uint64, n int) []byte {
+	b := make([]byte, 1, 17+n+4)
+	b[0] = typ
+	if tag != nil {
+		b = b[:9]
+		binary.BigEndian.PutUint64(b[1:9], *tag)
+	}
+	b = b[:len(b)+8]
+	binary.BigEndian.PutUint64(b[len(b)-8:], uint64(n))
+	return b
+}
+
+// appendChecksum appends the checksum of record b to b.
+func appendChecksum(b []byte) []byte {
+	var sum [4]byte
+	binary.BigEndian.PutUint32(sum[:], crc32.Checksum(b, crcTable))
+	return append(b, sum[:]...)
+}
+
+// verifyChecksum reads the checksum of the record with header hdr and value
+// buf from r, and returns true if it matches.
+func verifyChecksum(r io.Reader, hdr, buf []byte) (bool, error) {
+	var sum uint32
+	if err := binary.Read(r, binary.BigEndian, &sum); err != nil {
+		return false, fmt.Errorf("read message checksum: %s", err)
+	}
+	return sum == crc32.Update(crc32.Checksum(hdr, crcTable), crcTable, buf), nil
+}
+
 // EncodeTLV encodes v to a binary format and writes the record-length-value record to w.
 func EncodeTLV(w io.Writer, typ byte, v encoding.BinaryMarshaler) error {
 	if err := WriteType(w, typ); err != nil {
@@ -145,11 +431,19 @@ func EncodeLV(w io.Writer, v encoding.BinaryMarshaler) error {
 }
 
 // DecodeTLV reads the type-length-value record from r and unmarshals it into v.
+// If the remote node sent an error in place of the record, it is returned.
 func DecodeTLV(r io.Reader, v encoding.BinaryUnmarshaler) (typ byte, err error) {
 	typ, err = ReadType(r)
 	if err != nil {
 		return 0, err
 	}
+	if typ == ErrorResponseMessage {
+		var resp rpc.ErrorResponse
+		if err := DecodeLV(r, &resp); err != nil {
+			return 0, err
+		}
+		return typ, resp.Err()
+	}
 	if err := DecodeLV(r, v); err != nil {
 		return 0, err
 	}
@@ -158,7 +452,14 @@ func DecodeTLV(r io.Reader, v encoding.BinaryUnmarshaler) (typ byte, err error)
 
 // DecodeLV reads the length-value record from r and unmarshals it into v.
 func DecodeLV(r io.Reader, v encoding.BinaryUnmarshaler) error {
-	buf, err := ReadLV(r)
+	return DecodeLVMax(r, v, MaxMessageSize)
+}
+
+// DecodeLVMax reads the length-value record from r and unmarshals it into v.
+// A *FrameTooLargeError is returned without reading the value if it is
+// larger than max.
+func DecodeLVMax(r io.Reader, v encoding.BinaryUnmarshaler, max int64) error {
+	buf, err := ReadLVMax(r, max)
 	if err != nil {
 		return err
 	}